@@ -1,6 +1,8 @@
 package run
 
 import (
+	"errors"
+
 	"github.com/alecthomas/kingpin"
 	"github.com/apex/log"
 	"github.com/fatih/color"
@@ -34,7 +36,7 @@ func init() {
 	})
 
 	functionalRun := func(runType model.RunType, pred func(name string, gr nettests.Group) bool) error {
-		for name, group := range nettests.All {
+		for name, group := range nettests.Groups(probe.Config()) {
 			if !pred(name, group) {
 				continue
 			}
@@ -79,6 +81,16 @@ func init() {
 		cmd.Command(name, "").Action(genRunWithGroupName(name))
 	}
 
+	groupCmd := cmd.Command("group", "Run a user-defined test group")
+	groupName := groupCmd.Arg("name", "Name of the group to run").Required().String()
+	groupCmd.Action(func(pc *kingpin.ParseContext) error {
+		if _, found := nettests.Groups(probe.Config())[*groupName]; !found {
+			log.Errorf("No test group named %s", *groupName)
+			return errors.New("invalid test group name")
+		}
+		return genRunWithGroupName(*groupName)(pc)
+	})
+
 	unattendedCmd := cmd.Command("unattended", "")
 	unattendedCmd.Action(func(_ *kingpin.ParseContext) error {
 		return functionalRun(model.RunTypeTimed, func(name string, gr nettests.Group) bool {
@@ -55,6 +55,9 @@ type Config struct {
 	Nettests Nettests `json:"nettests"`
 	Advanced Advanced `json:"advanced"`
 
+	// Groups contains user-defined nettest groups indexed by name.
+	Groups map[string]Group `json:"groups,omitempty"`
+
 	mutex sync.Mutex
 	path  string
 }
@@ -35,6 +35,47 @@ func TestParseConfig(t *testing.T) {
 	}
 }
 
+func TestParseConfigWithSubmitters(t *testing.T) {
+	config, err := ReadConfig("testdata/config-with-submitters.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subms := config.Sharing.Submitters
+	if len(subms) != 2 {
+		t.Fatal("not the expected number of submitters")
+	}
+	if subms[0].Type != "webhook" || subms[0].MaxRetries != 5 || subms[0].Headers["Authorization"] != "Bearer xo" {
+		t.Fatal("not the expected webhook submitter config")
+	}
+	if subms[1].Type != "unix" || subms[1].URL != "unix:///run/ooni/measurements.sock" {
+		t.Fatal("not the expected unix submitter config")
+	}
+}
+
+func TestParseConfigWithGroups(t *testing.T) {
+	config, err := ReadConfig("testdata/config-with-groups.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Groups) != 2 {
+		t.Fatal("not the expected number of groups")
+	}
+	team := config.Groups["team"]
+	if team.Label != "Team Nettests" || !team.UnattendedOK || len(team.Nettests) != 2 {
+		t.Fatal("not the expected team group")
+	}
+	if team.Nettests[0].TestName != "web_connectivity" || team.Nettests[0].Inputs[0] != "https://www.example.com/" {
+		t.Fatal("not the expected first nettest")
+	}
+	if team.Nettests[1].TestName != "dnscheck" || team.Nettests[1].Options["HTTP3Enabled"] != true {
+		t.Fatal("not the expected second nettest")
+	}
+	campaign := config.Groups["campaign"]
+	if campaign.OONIRunURL != "https://run.example.org/v2/campaign.json" || campaign.UnattendedOK {
+		t.Fatal("not the expected campaign group")
+	}
+}
+
 func TestUpdateConfig(t *testing.T) {
 	tmpFile, err := ioutil.TempFile(os.TempDir(), "ooniconfig-")
 	if err != nil {
@@ -1,12 +1,35 @@
 package config
 
+import "github.com/ooni/probe-cli/v3/internal/submitter"
+
 // Sharing settings
 type Sharing struct {
 	UploadResults bool `json:"upload_results"`
+
+	// Submitters contains extra submitters (e.g., a webhook) to which
+	// we submit measurements regardless of UploadResults.
+	Submitters []*submitter.Config `json:"submitters,omitempty"`
 }
 
 // Advanced settings
-type Advanced struct{}
+type Advanced struct {
+	// DataBudget limits the amount of data used for measuring.
+	DataBudget DataBudget `json:"data_budget"`
+}
+
+// DataBudget contains data usage limits in MiB. A zero
+// value means that there is no limit.
+type DataBudget struct {
+	// DailyMB is the maximum data usage per calendar day.
+	DailyMB int64 `json:"daily_mb,omitempty"`
+
+	// ExperimentMB is the maximum data usage of each experiment.
+	ExperimentMB int64 `json:"experiment_mb,omitempty"`
+
+	// SessionMB is the maximum data usage of each measurement
+	// session, i.e., of each nettest group we run.
+	SessionMB int64 `json:"session_mb,omitempty"`
+}
 
 // Nettests related settings
 type Nettests struct {
@@ -14,3 +37,36 @@ type Nettests struct {
 	WebsitesURLLimit             int64    `json:"websites_url_limit"`
 	WebsitesEnabledCategoryCodes []string `json:"websites_enabled_category_codes"`
 }
+
+// Group is a user-defined group of nettests
+type Group struct {
+	// Label is the OPTIONAL human readable name of the group.
+	Label string `json:"label,omitempty"`
+
+	// Nettests contains the OPTIONAL nettests to run.
+	Nettests []GroupNettest `json:"nettests,omitempty"`
+
+	// OONIRunURL is the OPTIONAL URL of an OONI Run v2 descriptor
+	// whose nettests we should run after Nettests.
+	OONIRunURL string `json:"oonirun_url,omitempty"`
+
+	// OONIRunAcceptChanges OPTIONALLY indicates that we should run the
+	// descriptor at OONIRunURL even if it is new or has changed.
+	OONIRunAcceptChanges bool `json:"oonirun_accept_changes,omitempty"`
+
+	// UnattendedOK indicates whether we should run this group
+	// when running in unattended mode (e.g., using autorun).
+	UnattendedOK bool `json:"unattended_ok"`
+}
+
+// GroupNettest is a nettest inside a user-defined group
+type GroupNettest struct {
+	// Inputs contains OPTIONAL inputs for the nettest.
+	Inputs []string `json:"inputs,omitempty"`
+
+	// Options contains OPTIONAL options for the nettest.
+	Options map[string]any `json:"options,omitempty"`
+
+	// TestName is the MANDATORY nettest name.
+	TestName string `json:"test_name"`
+}
@@ -85,8 +85,22 @@ var summarizers = map[string]func(uint64, uint64, string) []string{
 	},
 }
 
+// makeGenericSummary is the summarizer for groups without a specific summarizer,
+// e.g., the user-defined groups, which only reports the measurement counts.
+func makeGenericSummary(totalCount uint64, anomalyCount uint64, ss string) []string {
+	return []string{
+		fmt.Sprintf("%d measurements", totalCount),
+		fmt.Sprintf("%d anomalies", anomalyCount),
+		"",
+	}
+}
+
 func makeSummary(name string, totalCount uint64, anomalyCount uint64, ss string) []string {
-	return summarizers[name](totalCount, anomalyCount, ss)
+	fn, ok := summarizers[name]
+	if !ok {
+		fn = makeGenericSummary
+	}
+	return fn(totalCount, anomalyCount, ss)
 }
 
 func logResultItem(w io.Writer, f log.Fields) error {
@@ -0,0 +1,65 @@
+package nettests
+
+import (
+	"context"
+
+	engine "github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Custom is a nettest defined by the user, either in
+// a group inside the config or in an OONI Run v2 descriptor.
+type Custom struct {
+	// Inputs contains the inputs to measure.
+	Inputs []string
+
+	// Name is the name of the experiment to run.
+	Name string
+
+	// Options contains the experiment options.
+	Options map[string]any
+}
+
+func (n Custom) lookupInputs(ctl *Controller, policy model.InputPolicy) ([]string, error) {
+	inputloader := &engine.InputLoader{
+		CheckInConfig: &model.OOAPICheckInConfig{
+			// See the comment in web_connectivity.go
+			Charging: true,
+			OnWiFi:   true,
+			RunType:  ctl.RunType,
+			WebConnectivity: model.OOAPICheckInConfigWebConnectivity{
+				CategoryCodes: ctl.Probe.Config().Nettests.WebsitesEnabledCategoryCodes,
+			},
+		},
+		ExperimentName: n.Name,
+		InputPolicy:    policy,
+		Session:        ctl.Session,
+		StaticInputs:   n.Inputs,
+	}
+	testlist, err := inputloader.Load(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if len(testlist) == 1 && testlist[0].URL == "" {
+		// The experiment does not take any input, so there's
+		// no need to register inputs into the database.
+		return []string{""}, nil
+	}
+	return ctl.BuildAndSetInputIdxMap(testlist)
+}
+
+// Run starts the nettest.
+func (n Custom) Run(ctl *Controller) error {
+	builder, err := ctl.Session.NewExperimentBuilder(n.Name)
+	if err != nil {
+		return err
+	}
+	if err := builder.SetOptionsAny(n.Options); err != nil {
+		return err
+	}
+	inputs, err := n.lookupInputs(ctl, builder.InputPolicy())
+	if err != nil {
+		return err
+	}
+	return ctl.Run(builder, inputs)
+}
@@ -1,5 +1,12 @@
 package nettests
 
+import (
+	"sync"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+)
+
 // Group is a group of nettests
 type Group struct {
 	Label        string
@@ -62,3 +69,77 @@ var All = map[string]Group{
 		UnattendedOK: true,
 	},
 }
+
+// groupsLoader loads and validates the groups of a given config once.
+type groupsLoader struct {
+	once   sync.Once
+	groups map[string]Group
+}
+
+var (
+	// groupsLoadersMu protects groupsLoaders.
+	groupsLoadersMu sync.Mutex
+
+	// groupsLoaders maps each config to its groups loader.
+	groupsLoaders = map[*config.Config]*groupsLoader{}
+)
+
+// Groups returns all the nettest groups that can be run by the user, i.e.,
+// the ones in All plus the ones defined in the config. A group defined in the
+// config cannot replace a group with the same name inside All. We load and
+// validate the groups of a given config once, so we only warn once about the
+// invalid groups. The caller MUST NOT modify the returned map.
+func Groups(cfg *config.Config) map[string]Group {
+	groupsLoadersMu.Lock()
+	loader, found := groupsLoaders[cfg]
+	if !found {
+		loader = &groupsLoader{}
+		groupsLoaders[cfg] = loader
+	}
+	groupsLoadersMu.Unlock()
+	loader.once.Do(func() {
+		loader.groups = loadGroups(cfg)
+	})
+	return loader.groups
+}
+
+// loadGroups implements Groups.
+func loadGroups(cfg *config.Config) map[string]Group {
+	out := make(map[string]Group, len(All)+len(cfg.Groups))
+	for name, group := range All {
+		out[name] = group
+	}
+	for name, gc := range cfg.Groups {
+		if _, found := out[name]; found {
+			log.Warnf("ignoring user-defined group %s: this name is reserved", name)
+			continue
+		}
+		out[name] = newUserDefinedGroup(name, gc)
+	}
+	return out
+}
+
+// newUserDefinedGroup creates a Group from its config.
+func newUserDefinedGroup(name string, gc config.Group) Group {
+	group := Group{
+		Label:        gc.Label,
+		UnattendedOK: gc.UnattendedOK,
+	}
+	if group.Label == "" {
+		group.Label = name
+	}
+	for _, entry := range gc.Nettests {
+		group.Nettests = append(group.Nettests, Custom{
+			Inputs:  entry.Inputs,
+			Name:    entry.TestName,
+			Options: entry.Options,
+		})
+	}
+	if gc.OONIRunURL != "" {
+		group.Nettests = append(group.Nettests, OONIRunV2{
+			AcceptChanges: gc.OONIRunAcceptChanges,
+			URL:           gc.OONIRunURL,
+		})
+	}
+	return group
+}
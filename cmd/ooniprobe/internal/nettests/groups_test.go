@@ -0,0 +1,106 @@
+package nettests
+
+import (
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+)
+
+func TestGroups(t *testing.T) {
+	t.Run("without user-defined groups", func(t *testing.T) {
+		groups := Groups(&config.Config{})
+		if diff := cmp.Diff(All, groups); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with user-defined groups", func(t *testing.T) {
+		cfg := &config.Config{
+			Groups: map[string]config.Group{
+				"team": {
+					Label: "Team Nettests",
+					Nettests: []config.GroupNettest{{
+						Inputs:   []string{"https://www.example.com/"},
+						TestName: "web_connectivity",
+					}, {
+						Options:  map[string]any{"HTTP3Enabled": true},
+						TestName: "dnscheck",
+					}},
+					OONIRunURL:   "https://run.example.org/v2/team.json",
+					UnattendedOK: true,
+				},
+				"campaign": {
+					OONIRunURL:           "https://run.example.org/v2/campaign.json",
+					OONIRunAcceptChanges: true,
+				},
+				"websites": {
+					Label: "Shadowing",
+				},
+			},
+		}
+		groups := Groups(cfg)
+		if len(groups) != len(All)+2 {
+			t.Fatal("unexpected number of groups", len(groups))
+		}
+		if diff := cmp.Diff(All["websites"], groups["websites"]); diff != "" {
+			t.Fatal(diff)
+		}
+		expectTeam := Group{
+			Label: "Team Nettests",
+			Nettests: []Nettest{
+				Custom{
+					Inputs: []string{"https://www.example.com/"},
+					Name:   "web_connectivity",
+				},
+				Custom{
+					Name:    "dnscheck",
+					Options: map[string]any{"HTTP3Enabled": true},
+				},
+				OONIRunV2{
+					URL: "https://run.example.org/v2/team.json",
+				},
+			},
+			UnattendedOK: true,
+		}
+		if diff := cmp.Diff(expectTeam, groups["team"]); diff != "" {
+			t.Fatal(diff)
+		}
+		expectCampaign := Group{
+			Label: "campaign",
+			Nettests: []Nettest{
+				OONIRunV2{
+					AcceptChanges: true,
+					URL:           "https://run.example.org/v2/campaign.json",
+				},
+			},
+			UnattendedOK: false,
+		}
+		if diff := cmp.Diff(expectCampaign, groups["campaign"]); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+	t.Run("warns once about reserved names", func(t *testing.T) {
+		logger := log.Log.(*log.Logger)
+		prev := logger.Handler
+		defer func() { logger.Handler = prev }()
+		handler := memory.New()
+		log.SetHandler(handler)
+		cfg := &config.Config{
+			Groups: map[string]config.Group{
+				"websites": {
+					Label: "Shadowing",
+				},
+			},
+		}
+		first, second := Groups(cfg), Groups(cfg)
+		if diff := cmp.Diff(first, second); diff != "" {
+			t.Fatal(diff)
+		}
+		if len(handler.Entries) != 1 {
+			t.Fatal("expected a single warning, got", len(handler.Entries))
+		}
+	})
+}
@@ -10,8 +10,10 @@ import (
 	"github.com/fatih/color"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	engine "github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitter"
 	"github.com/pkg/errors"
 )
 
@@ -138,6 +140,11 @@ func (c *Controller) Run(builder model.ExperimentBuilder, inputs []string) error
 	var reportID sql.NullString
 	resultID := c.res.ID
 
+	extraSubmitters, err := submitter.NewAll(c.Probe.Config().Sharing.Submitters, log.Log)
+	if err != nil {
+		return errors.Wrap(err, "failed to create extra submitters")
+	}
+
 	log.Debug(color.RedString("status.queued"))
 	log.Debug(color.RedString("status.started"))
 
@@ -202,6 +209,13 @@ func (c *Controller) Run(builder model.ExperimentBuilder, inputs []string) error
 			if err := db.Failed(c.msmts[idx64], err.Error()); err != nil {
 				return errors.Wrap(err, "failed to mark measurement as failed")
 			}
+			// Note: when we exhaust the budget while measuring, the engine
+			// discards the incomplete measurement and returns this error, so
+			// we mark the measurement as failed and we do not submit it.
+			if errors.Is(err, bytecounter.ErrBudgetExceeded) {
+				log.Warn("exceeded the data usage budget")
+				break
+			}
 			// Since https://github.com/ooni/probe-cli/pull/527, the Measure
 			// function returns EITHER a valid measurement OR an error. Before
 			// that, instead, the measurement was valid EVEN in case of an
@@ -229,6 +243,11 @@ func (c *Controller) Run(builder model.ExperimentBuilder, inputs []string) error
 				saveToDisk = false
 			}
 		}
+		for _, subm := range extraSubmitters {
+			if err := subm.Submit(context.Background(), measurement); err != nil {
+				log.WithError(err).Warn("failed to submit measurement using extra submitter")
+			}
+		}
 		// We only save the measurement to disk if we failed to upload the measurement
 		if saveToDisk {
 			if err := exp.SaveMeasurement(measurement, msmt.MeasurementFilePath.String); err != nil {
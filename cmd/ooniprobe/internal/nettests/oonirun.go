@@ -0,0 +1,68 @@
+package nettests
+
+import (
+	"context"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/multierror"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/pkg/errors"
+)
+
+// ErrOONIRunV2Failed indicates that some nettests in an OONI Run v2 descriptor failed.
+var ErrOONIRunV2Failed = errors.New("oonirun: some nettests failed")
+
+// OONIRunV2 runs the nettests inside an OONI Run v2 descriptor.
+type OONIRunV2 struct {
+	// AcceptChanges indicates whether we should run the
+	// descriptor even if it is new or has changed.
+	AcceptChanges bool
+
+	// URL is the URL of the descriptor.
+	URL string
+}
+
+// Run starts the nettest.
+func (n OONIRunV2) Run(ctl *Controller) error {
+	store, err := kvstore.NewFS(utils.EngineDir(ctl.Probe.Home()))
+	if err != nil {
+		return errors.Wrap(err, "creating engine's kvstore")
+	}
+	desc, err := oonirun.V2PullDescriptor(
+		context.Background(), ctl.Session, store, n.URL, n.AcceptChanges)
+	if err != nil {
+		if errors.Is(err, oonirun.ErrNeedToAcceptChanges) {
+			log.Warnf("set oonirun_accept_changes for the group to run %s", n.URL)
+		}
+		return err
+	}
+	if desc == nil {
+		return oonirun.ErrNilDescriptor
+	}
+	union := multierror.New(ErrOONIRunV2Failed)
+	for _, entry := range desc.Nettests {
+		if ctl.Probe.IsTerminated() {
+			break
+		}
+		nt := Custom{
+			Inputs:  entry.Inputs,
+			Name:    entry.TestName,
+			Options: entry.Options,
+		}
+		// Each nettest needs its own controller because the
+		// controller keeps per-experiment state.
+		sub := NewController(nt, ctl.Probe, ctl.res, ctl.Session)
+		sub.RunType = ctl.RunType
+		sub.SetNettestIndex(ctl.ntIndex, ctl.ntCount)
+		if err := nt.Run(sub); err != nil {
+			log.WithError(err).Warnf("cannot run %s", entry.TestName)
+			union.AddWithPrefix(entry.TestName, err)
+		}
+	}
+	if len(union.Children) > 0 {
+		return union
+	}
+	return nil
+}
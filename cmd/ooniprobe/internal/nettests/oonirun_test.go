@@ -0,0 +1,63 @@
+package nettests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestOONIRunV2(t *testing.T) {
+	newController := func(t *testing.T, nt Nettest) *Controller {
+		probe := newOONIProbe(t)
+		sess, err := probe.NewSession(context.Background(), model.RunTypeManual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { sess.Close() })
+		db := probe.DB()
+		network, err := db.CreateNetwork(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := db.CreateResult(probe.Home(), "campaign", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewController(nt, probe, res, sess)
+	}
+
+	t.Run("when we cannot fetch the descriptor", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+		nt := OONIRunV2{AcceptChanges: true, URL: server.URL}
+		if err := nt.Run(newController(t, nt)); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+
+	t.Run("when a nettest fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			descriptor := &oonirun.V2Descriptor{
+				Nettests: []oonirun.V2Nettest{{
+					TestName: "nonexistent",
+				}},
+			}
+			data, err := json.Marshal(descriptor)
+			runtimex.PanicOnError(err, "json.Marshal failed")
+			w.Write(data)
+		}))
+		defer server.Close()
+		nt := OONIRunV2{AcceptChanges: true, URL: server.URL}
+		err := nt.Run(newController(t, nt))
+		if !errors.Is(err, ErrOONIRunV2Failed) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/pkg/errors"
 )
@@ -82,7 +83,7 @@ func RunGroup(config RunGroupConfig) error {
 		return err
 	}
 
-	group, ok := All[config.GroupName]
+	group, ok := Groups(config.Probe.Config())[config.GroupName]
 	if !ok {
 		log.Errorf("No test group named %s", config.GroupName)
 		return errors.New("invalid test group name")
@@ -118,6 +119,9 @@ func RunGroup(config RunGroupConfig) error {
 		if err = nt.Run(ctl); err != nil {
 			log.WithError(err).Errorf("Failed to run %s", group.Label)
 		}
+		if remaining, ok := sess.RemainingDataBudget(); ok {
+			output.DataBudget(float64(remaining) / 1024)
+		}
 	}
 
 	// Remove the directory if it's emtpy, which happens when the corresponding
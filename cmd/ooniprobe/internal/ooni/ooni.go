@@ -9,10 +9,12 @@ import (
 	"os/signal"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/database"
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
@@ -232,17 +234,49 @@ func (p *Probe) NewSession(ctx context.Context, runType model.RunType) (*engine.
 	if runType == model.RunTypeTimed && softwareName == DefaultSoftwareName {
 		softwareName = DefaultSoftwareName + "-unattended"
 	}
+	budget := p.config.Advanced.DataBudget
+	sessionBudget, err := sessionDataBudget(budget, p.db, time.Now())
+	if err != nil {
+		return nil, err
+	}
 	return engine.NewSession(ctx, engine.SessionConfig{
-		KVStore:         kvstore,
-		Logger:          logger,
-		SoftwareName:    softwareName,
-		SoftwareVersion: p.softwareVersion,
-		TempDir:         p.tempDir,
-		TunnelDir:       p.tunnelDir,
-		ProxyURL:        p.proxyURL,
+		DataBudget:           sessionBudget,
+		ExperimentDataBudget: budget.ExperimentMB << 20,
+		KVStore:              kvstore,
+		Logger:               logger,
+		SoftwareName:         softwareName,
+		SoftwareVersion:      p.softwareVersion,
+		TempDir:              p.tempDir,
+		TunnelDir:            p.tunnelDir,
+		ProxyURL:             p.proxyURL,
 	})
 }
 
+// sessionDataBudget returns the data budget in bytes for a new session given
+// the configured budget, the database containing the data usage of previous
+// results, and the current time. A zero return value means no limit. We return
+// an error wrapping [bytecounter.ErrBudgetExceeded] if we've already
+// exhausted today's budget.
+func sessionDataBudget(budget config.DataBudget, db model.ReadableDatabase, now time.Time) (int64, error) {
+	limit := budget.SessionMB << 20
+	if budget.DailyMB <= 0 {
+		return limit, nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	up, down, err := db.DataUsageSince(today)
+	if err != nil {
+		return 0, errors.Wrap(err, "computing today's data usage")
+	}
+	daily := budget.DailyMB<<20 - int64((up+down)*1024)
+	if daily <= 0 {
+		return 0, errors.Wrap(bytecounter.ErrBudgetExceeded, "daily budget")
+	}
+	if limit <= 0 || daily < limit {
+		limit = daily
+	}
+	return limit, nil
+}
+
 // NewProbeEngine creates a new ProbeEngine instance.
 func (p *Probe) NewProbeEngine(ctx context.Context, runType model.RunType) (ProbeEngine, error) {
 	sess, err := p.NewSession(ctx, runType)
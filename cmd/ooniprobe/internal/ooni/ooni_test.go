@@ -1,10 +1,16 @@
 package ooni
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
 )
 
 func TestInit(t *testing.T) {
@@ -27,3 +33,74 @@ func TestInit(t *testing.T) {
 		t.Fatal("config file was not created")
 	}
 }
+
+func TestSessionDataBudget(t *testing.T) {
+	now := time.Date(2023, 5, 17, 14, 30, 0, 0, time.UTC)
+	mocked := errors.New("mocked")
+
+	// newDatabase returns a database where today's usage is the given KiB.
+	newDatabase := func(kib float64, err error) *mocks.Database {
+		return &mocks.Database{
+			MockDataUsageSince: func(since time.Time) (float64, float64, error) {
+				if !since.Equal(time.Date(2023, 5, 17, 0, 0, 0, 0, time.UTC)) {
+					t.Fatal("unexpected since", since)
+				}
+				return kib / 2, kib / 2, err
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		budget    config.DataBudget
+		db        *mocks.Database
+		expect    int64
+		expectErr error
+	}{
+		"without any budget": {
+			budget: config.DataBudget{},
+			db:     nil,
+			expect: 0,
+		},
+		"with only the session budget": {
+			budget: config.DataBudget{SessionMB: 10},
+			db:     nil,
+			expect: 10 << 20,
+		},
+		"with only the daily budget": {
+			budget: config.DataBudget{DailyMB: 10},
+			db:     newDatabase(1024, nil),
+			expect: 9 << 20,
+		},
+		"when the daily budget is the most restrictive": {
+			budget: config.DataBudget{DailyMB: 10, SessionMB: 5},
+			db:     newDatabase(6*1024, nil),
+			expect: 4 << 20,
+		},
+		"when the session budget is the most restrictive": {
+			budget: config.DataBudget{DailyMB: 10, SessionMB: 5},
+			db:     newDatabase(1024, nil),
+			expect: 5 << 20,
+		},
+		"when the daily budget is exhausted": {
+			budget:    config.DataBudget{DailyMB: 10},
+			db:        newDatabase(10*1024, nil),
+			expectErr: bytecounter.ErrBudgetExceeded,
+		},
+		"when we cannot query the database": {
+			budget:    config.DataBudget{DailyMB: 10},
+			db:        newDatabase(0, mocked),
+			expectErr: mocked,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := sessionDataBudget(tt.budget, tt.db, now)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if got != tt.expect {
+				t.Fatal("unexpected budget", got)
+			}
+		})
+	}
+}
@@ -29,6 +29,14 @@ func Progress(key string, perc float64, eta float64, msg string) {
 	}).Info(msg)
 }
 
+// DataBudget logs the remaining data budget in KiB
+func DataBudget(remaining float64) {
+	log.WithFields(log.Fields{
+		"type":      "data_budget",
+		"remaining": remaining,
+	}).Infof("Remaining data budget: %.1f KiB", remaining)
+}
+
 // MeasurementSummaryData contains summary information on the measurement
 type MeasurementSummaryData struct {
 	TotalRuntime       float64
@@ -0,0 +1,196 @@
+package bytecounter
+
+//
+// Data usage budget
+//
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// ErrBudgetExceeded indicates that we have exhausted the data usage budget.
+var ErrBudgetExceeded = errors.New("bytecounter: data usage budget exceeded")
+
+// Budget is a data usage budget, i.e., the maximum number of bytes (sent
+// plus received) we're allowed to exchange as tracked by a [Counter].
+//
+// To enforce a budget, bind the [netxlite.ConnWrapper] returned by
+// [NewBudgetConnWrapper] to the context used for measuring. The returned
+// wrapper counts the bytes exchanged by the conns created by netxlite
+// dialers using such a context and fails I/O once we exhaust the budget.
+type Budget struct {
+	// Counter is the MANDATORY counter tracking the bytes we used. You
+	// SHOULD use a dedicated counter, such that we only count the bytes
+	// exchanged by the conns wrapped using [NewBudgetConnWrapper].
+	Counter *Counter
+
+	// Limit is the MANDATORY maximum number of bytes we can use.
+	Limit int64
+}
+
+// NewBudget creates a new [Budget] using the given counter and limit.
+func NewBudget(counter *Counter, limit int64) *Budget {
+	return &Budget{Counter: counter, Limit: limit}
+}
+
+// Used returns the number of bytes used so far.
+func (b *Budget) Used() int64 {
+	return b.Counter.BytesSent() + b.Counter.BytesReceived()
+}
+
+// Remaining returns the number of bytes we can still use, which is zero
+// when we've exhausted the budget. Note that the bytes used may be larger
+// than the limit, because we only fail new I/O once we reach the limit.
+func (b *Budget) Remaining() int64 {
+	if remaining := b.Limit - b.Used(); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// KibiBytesRemaining is like Remaining but returns KiB.
+func (b *Budget) KibiBytesRemaining() float64 {
+	return float64(b.Remaining()) / 1024
+}
+
+// Check returns [ErrBudgetExceeded] if we have exhausted the budget.
+func (b *Budget) Check() error {
+	if b.Used() >= b.Limit {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// BudgetConnWrapper is the [netxlite.ConnWrapper] returned by [NewBudgetConnWrapper].
+type BudgetConnWrapper struct {
+	budgets  []*Budget
+	exceeded atomic.Bool
+}
+
+var _ netxlite.ConnWrapper = &BudgetConnWrapper{}
+
+// NewBudgetConnWrapper returns a [netxlite.ConnWrapper] that prevents creating new
+// conns and fails I/O once we've exhausted any of the given budgets. The wrapped
+// conns count the bytes they send and receive using the counter of each budget. This
+// function ignores nil budgets and returns nil if all the budgets are nil.
+func NewBudgetConnWrapper(budgets ...*Budget) *BudgetConnWrapper {
+	wrapper := &BudgetConnWrapper{}
+	for _, budget := range budgets {
+		if budget != nil {
+			wrapper.budgets = append(wrapper.budgets, budget)
+		}
+	}
+	if len(wrapper.budgets) <= 0 {
+		return nil
+	}
+	return wrapper
+}
+
+// check returns [ErrBudgetExceeded] if we have exhausted any budget.
+func (w *BudgetConnWrapper) check() error {
+	for _, budget := range w.budgets {
+		if err := budget.Check(); err != nil {
+			w.exceeded.Store(true)
+			return err
+		}
+	}
+	return nil
+}
+
+// Exceeded returns whether we refused to create a conn or failed I/O because
+// we exhausted a budget. When this happens while measuring, the measurement is
+// most likely incomplete and we should not submit it.
+func (w *BudgetConnWrapper) Exceeded() bool {
+	return w.exceeded.Load()
+}
+
+// CheckNewConn implements netxlite.ConnWrapper.
+func (w *BudgetConnWrapper) CheckNewConn() error {
+	return w.check()
+}
+
+// OnDialFailure implements netxlite.ConnWrapper.
+func (w *BudgetConnWrapper) OnDialFailure(network, address string, err error) {
+	// nothing
+}
+
+// WrapNetConn implements netxlite.ConnWrapper.
+func (w *BudgetConnWrapper) WrapNetConn(conn net.Conn) net.Conn {
+	return &budgetConn{Conn: conn, wrapper: w}
+}
+
+// WrapUDPLikeConn implements netxlite.ConnWrapper.
+func (w *BudgetConnWrapper) WrapUDPLikeConn(conn model.UDPLikeConn) model.UDPLikeConn {
+	return &budgetUDPLikeConn{UDPLikeConn: conn, wrapper: w}
+}
+
+// countSent counts the bytes sent using each budget's counter.
+func (w *BudgetConnWrapper) countSent(count int) {
+	for _, budget := range w.budgets {
+		budget.Counter.CountBytesSent(count)
+	}
+}
+
+// countReceived counts the bytes received using each budget's counter.
+func (w *BudgetConnWrapper) countReceived(count int) {
+	for _, budget := range w.budgets {
+		budget.Counter.CountBytesReceived(count)
+	}
+}
+
+// budgetConn is a net.Conn accounting for and enforcing budgets.
+type budgetConn struct {
+	net.Conn
+	wrapper *BudgetConnWrapper
+}
+
+// Read implements net.Conn.Read.
+func (c *budgetConn) Read(p []byte) (int, error) {
+	if err := c.wrapper.check(); err != nil {
+		return 0, err
+	}
+	count, err := c.Conn.Read(p)
+	c.wrapper.countReceived(count)
+	return count, err
+}
+
+// Write implements net.Conn.Write.
+func (c *budgetConn) Write(p []byte) (int, error) {
+	if err := c.wrapper.check(); err != nil {
+		return 0, err
+	}
+	count, err := c.Conn.Write(p)
+	c.wrapper.countSent(count)
+	return count, err
+}
+
+// budgetUDPLikeConn is a model.UDPLikeConn accounting for and enforcing budgets.
+type budgetUDPLikeConn struct {
+	model.UDPLikeConn
+	wrapper *BudgetConnWrapper
+}
+
+// ReadFrom implements model.UDPLikeConn.ReadFrom.
+func (c *budgetUDPLikeConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	if err := c.wrapper.check(); err != nil {
+		return 0, nil, err
+	}
+	count, addr, err := c.UDPLikeConn.ReadFrom(p)
+	c.wrapper.countReceived(count)
+	return count, addr, err
+}
+
+// WriteTo implements model.UDPLikeConn.WriteTo.
+func (c *budgetUDPLikeConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if err := c.wrapper.check(); err != nil {
+		return 0, err
+	}
+	count, err := c.UDPLikeConn.WriteTo(p, addr)
+	c.wrapper.countSent(count)
+	return count, err
+}
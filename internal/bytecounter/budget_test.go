@@ -0,0 +1,123 @@
+package bytecounter
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+func TestBudget(t *testing.T) {
+	counter := New()
+	budget := NewBudget(counter, 2048)
+	if budget.Used() != 0 || budget.Remaining() != 2048 || budget.KibiBytesRemaining() != 2 {
+		t.Fatal("unexpected initial state")
+	}
+	if err := budget.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	counter.CountBytesSent(1000)
+	counter.CountBytesReceived(24)
+	if budget.Used() != 1024 || budget.Remaining() != 1024 || budget.KibiBytesRemaining() != 1 {
+		t.Fatal("unexpected state after using half of the budget")
+	}
+	if err := budget.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	counter.CountBytesReceived(4096)
+	if budget.Used() != 5120 || budget.Remaining() != 0 {
+		t.Fatal("unexpected state after exceeding the budget")
+	}
+	if err := budget.Check(); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestNewBudgetConnWrapper(t *testing.T) {
+	t.Run("with only nil budgets", func(t *testing.T) {
+		if NewBudgetConnWrapper(nil, nil) != nil {
+			t.Fatal("expected nil wrapper")
+		}
+	})
+
+	t.Run("CheckNewConn checks all the budgets", func(t *testing.T) {
+		small, large := NewBudget(New(), 128), NewBudget(New(), 1024)
+		wrapper := NewBudgetConnWrapper(large, nil, small)
+		if err := wrapper.CheckNewConn(); err != nil {
+			t.Fatal(err)
+		}
+		if wrapper.Exceeded() {
+			t.Fatal("did not expect the wrapper to have exceeded the budgets")
+		}
+		small.Counter.CountBytesSent(128)
+		if err := wrapper.CheckNewConn(); !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+		if !wrapper.Exceeded() {
+			t.Fatal("expected the wrapper to remember that we exceeded the budgets")
+		}
+	})
+
+	t.Run("WrapNetConn counts and enforces the budgets", func(t *testing.T) {
+		small, large := NewBudget(New(), 128), NewBudget(New(), 1024)
+		underlying := &mocks.Conn{
+			MockRead: func(b []byte) (int, error) {
+				return len(b), nil
+			},
+			MockWrite: func(b []byte) (int, error) {
+				return len(b), nil
+			},
+		}
+		conn := NewBudgetConnWrapper(small, large).WrapNetConn(underlying)
+		buffer := make([]byte, 64)
+		if _, err := conn.Read(buffer); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write(buffer); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Read(buffer); !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := conn.Write(buffer); !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+		for _, budget := range []*Budget{small, large} {
+			if budget.Counter.BytesReceived() != 64 || budget.Counter.BytesSent() != 64 {
+				t.Fatal("unexpected number of bytes counted")
+			}
+		}
+	})
+
+	t.Run("WrapUDPLikeConn counts and enforces the budgets", func(t *testing.T) {
+		budget := NewBudget(New(), 128)
+		underlying := &mocks.UDPLikeConn{
+			MockReadFrom: func(p []byte) (int, net.Addr, error) {
+				return len(p), &mocks.Addr{}, nil
+			},
+			MockWriteTo: func(p []byte, addr net.Addr) (int, error) {
+				return len(p), nil
+			},
+		}
+		conn := NewBudgetConnWrapper(budget).WrapUDPLikeConn(underlying)
+		buffer := make([]byte, 64)
+		if _, _, err := conn.ReadFrom(buffer); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.WriteTo(buffer, &mocks.Addr{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := conn.ReadFrom(buffer); !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := conn.WriteTo(buffer, &mocks.Addr{}); !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+		if budget.Counter.BytesReceived() != 64 || budget.Counter.BytesSent() != 64 {
+			t.Fatal("unexpected number of bytes counted")
+		}
+	})
+}
@@ -76,6 +76,54 @@ func (r *resolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, erro
 	return out, err
 }
 
+// LookupTXT implements model.Resolver
+func (r *resolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	r.updateCounterBytesSent(domain, 1)
+	out, err := r.Resolver.LookupTXT(ctx, domain)
+	r.updateCounterBytesRecv(err)
+	return out, err
+}
+
+// LookupMX implements model.Resolver
+func (r *resolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	r.updateCounterBytesSent(domain, 1)
+	out, err := r.Resolver.LookupMX(ctx, domain)
+	r.updateCounterBytesRecv(err)
+	return out, err
+}
+
+// LookupCNAME implements model.Resolver
+func (r *resolver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	r.updateCounterBytesSent(domain, 1)
+	out, err := r.Resolver.LookupCNAME(ctx, domain)
+	r.updateCounterBytesRecv(err)
+	return out, err
+}
+
+// LookupSOA implements model.Resolver
+func (r *resolver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	r.updateCounterBytesSent(domain, 1)
+	out, err := r.Resolver.LookupSOA(ctx, domain)
+	r.updateCounterBytesRecv(err)
+	return out, err
+}
+
+// LookupPTR implements model.Resolver
+func (r *resolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	r.updateCounterBytesSent(address, 1)
+	out, err := r.Resolver.LookupPTR(ctx, address)
+	r.updateCounterBytesRecv(err)
+	return out, err
+}
+
+// LookupSRV implements model.Resolver
+func (r *resolver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	r.updateCounterBytesSent(domain, 1)
+	out, err := r.Resolver.LookupSRV(ctx, domain)
+	r.updateCounterBytesRecv(err)
+	return out, err
+}
+
 // Network implements model.Resolver
 func (r *resolver) Network() string {
 	return r.Resolver.Network()
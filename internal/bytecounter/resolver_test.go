@@ -196,6 +196,59 @@ func TestMaybeWrapSystemResolver(t *testing.T) {
 		})
 	})
 
+	t.Run("LookupTXT, LookupMX, LookupCNAME, LookupSOA, LookupPTR, LookupSRV work as intended", func(t *testing.T) {
+		expected := errors.New(netxlite.FailureDNSNXDOMAINError)
+		underlying := &mocks.Resolver{
+			MockLookupTXT: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"v=spf1 -all"}, nil
+			},
+			MockLookupMX: func(ctx context.Context, domain string) ([]*net.MX, error) {
+				return nil, expected
+			},
+			MockLookupCNAME: func(ctx context.Context, domain string) (string, error) {
+				return "www.dns.google.", nil
+			},
+			MockLookupSOA: func(ctx context.Context, domain string) (*model.DNSSOA, error) {
+				return nil, expected
+			},
+			MockLookupPTR: func(ctx context.Context, address string) ([]string, error) {
+				return []string{"dns.google."}, nil
+			},
+			MockLookupSRV: func(ctx context.Context, domain string) ([]*net.SRV, error) {
+				return nil, expected
+			},
+		}
+		counter := New()
+		reso := MaybeWrapSystemResolver(underlying, counter)
+		ctx := context.Background()
+		if _, err := reso.LookupTXT(ctx, "dns.google"); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := reso.LookupMX(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := reso.LookupCNAME(ctx, "dns.google"); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := reso.LookupSOA(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := reso.LookupPTR(ctx, "8.8.8.8"); err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if _, err := reso.LookupSRV(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		// five lookups for "dns.google" and one for "8.8.8.8"
+		if nsent := counter.BytesSent(); nsent != 5*10+7 {
+			t.Fatal("unexpected nsent", nsent)
+		}
+		// three successes and three DNS failures
+		if nrecv := counter.BytesReceived(); nrecv != 3*256+3*128 {
+			t.Fatal("unexpected nrecv", nrecv)
+		}
+	})
+
 	t.Run("LookupHost works as intended", func(t *testing.T) {
 		t.Run("on success", func(t *testing.T) {
 			underlying := &mocks.Resolver{
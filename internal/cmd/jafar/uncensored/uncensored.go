@@ -50,6 +50,36 @@ func (c *Client) LookupNS(ctx context.Context, domain string) ([]*net.NS, error)
 	return nil, errors.New("not implemented")
 }
 
+// LookupTXT implements model.Resolver.LookupTXT.
+func (c *Client) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+// LookupMX implements model.Resolver.LookupMX.
+func (c *Client) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return nil, errors.New("not implemented")
+}
+
+// LookupCNAME implements model.Resolver.LookupCNAME.
+func (c *Client) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+// LookupSOA implements model.Resolver.LookupSOA.
+func (c *Client) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return nil, errors.New("not implemented")
+}
+
+// LookupPTR implements model.Resolver.LookupPTR.
+func (c *Client) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+// LookupSRV implements model.Resolver.LookupSRV.
+func (c *Client) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return nil, errors.New("not implemented")
+}
+
 // Network implements Resolver.Network
 func (c *Client) Network() string {
 	return c.dnsClient.Network()
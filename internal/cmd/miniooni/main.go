@@ -4,9 +4,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime/debug"
 	"strings"
 	"time"
@@ -19,6 +21,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/registry"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/submitter"
 	"github.com/ooni/probe-cli/v3/internal/version"
 	"github.com/spf13/cobra"
 )
@@ -26,20 +29,25 @@ import (
 // Options contains the options you can set from the CLI.
 type Options struct {
 	Annotations         []string
+	CaptureDir          string
 	Emoji               bool
 	ExtraOptions        []string
 	HomeDir             string
 	Inputs              []string
 	InputFilePaths      []string
+	KeyLogFile          string
 	MaxRuntime          int64
 	NoJSON              bool
 	NoCollector         bool
 	ProbeServicesURL    string
 	Proxy               string
+	PTBinary            string
+	PTBridge            string
 	Random              bool
 	RepeatEvery         int64
 	ReportFile          string
 	SnowflakeRendezvous string
+	Submitters          []string
 	TorArgs             []string
 	TorBinary           string
 	Tunnel              string
@@ -56,6 +64,9 @@ func main() {
 		Args:    cobra.NoArgs,
 		Version: version.Version,
 	}
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return checkKeyLogFile(&globalOptions)
+	}
 	rootCmd.SetVersionTemplate("{{ .Version }}\n")
 	flags := rootCmd.PersistentFlags()
 
@@ -67,6 +78,13 @@ func main() {
 		"add KEY=VALUE annotation to the report (can be repeated multiple times)",
 	)
 
+	flags.StringVar(
+		&globalOptions.CaptureDir,
+		"capture-dir",
+		"",
+		"write a pcapng file with the traffic of each measurement into this directory",
+	)
+
 	flags.BoolVar(
 		&globalOptions.Emoji,
 		"emoji",
@@ -111,6 +129,20 @@ func main() {
 		"set proxy URL to communicate with the OONI backend (mutually exclusive with --tunnel)",
 	)
 
+	flags.StringVar(
+		&globalOptions.PTBinary,
+		"pt-binary",
+		"",
+		"external pluggable transport binary for --tunnel=ptx",
+	)
+
+	flags.StringVar(
+		&globalOptions.PTBridge,
+		"pt-bridge",
+		"",
+		"bridge line for --tunnel=ptx, whose backend must be a SOCKS5 proxy unless using snowflake (e.g., \"obfs4 ADDRESS FINGERPRINT cert=CERT iat-mode=0\")",
+	)
+
 	flags.Int64Var(
 		&globalOptions.RepeatEvery,
 		"repeat-every",
@@ -133,6 +165,23 @@ func main() {
 		"rendezvous method for --tunnel=torsf (one of: \"domain_fronting\" and \"amp\")",
 	)
 
+	flags.StringArrayVar(
+		&globalOptions.Submitters,
+		"submitter",
+		[]string{},
+		fmt.Sprintf(
+			"also submit measurements using TYPE=URL (may be specified multiple times; types: %s)",
+			strings.Join(submitter.Types(), ", "),
+		),
+	)
+
+	flags.StringVar(
+		&globalOptions.KeyLogFile,
+		"tls-key-log-file",
+		os.Getenv("SSLKEYLOGFILE"),
+		"append the TLS key log to this file, which defaults to $SSLKEYLOGFILE (never share this file)",
+	)
+
 	flags.StringSliceVar(
 		&globalOptions.TorArgs,
 		"tor-args",
@@ -151,7 +200,7 @@ func main() {
 		&globalOptions.Tunnel,
 		"tunnel",
 		"",
-		"tunnel to use to communicate with the OONI backend (one of: psiphon, tor, torsf)",
+		"tunnel to use to communicate with the OONI backend (one of: psiphon, ptx, tor, torsf)",
 	)
 
 	flags.BoolVarP(
@@ -174,6 +223,7 @@ func main() {
 
 	registerAllExperiments(rootCmd, &globalOptions)
 	registerOONIRun(rootCmd, &globalOptions)
+	registerQueue(rootCmd, &globalOptions)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -290,16 +340,12 @@ func MainWithConfiguration(experimentName string, currentOptions *Options) {
 		currentOptions.Proxy = fmt.Sprintf("%s:///", currentOptions.Tunnel)
 	}
 
-	logHandler := logx.NewHandlerWithDefaultSettings()
-	logHandler.Emoji = currentOptions.Emoji
-	logger := &log.Logger{Level: log.InfoLevel, Handler: logHandler}
-	if currentOptions.Verbose {
-		logger.Level = log.DebugLevel
-	}
+	logger := newLogger(currentOptions)
 	if currentOptions.ReportFile == "" {
 		currentOptions.ReportFile = "report.jsonl"
 	}
-	log.Log = logger
+	// The CLI already reports this error as a usage error.
+	runtimex.PanicOnError(checkKeyLogFile(currentOptions), "invalid TLS key log file")
 	for {
 		mainSingleIteration(logger, experimentName, currentOptions)
 		if currentOptions.RepeatEvery <= 0 {
@@ -311,6 +357,46 @@ func MainWithConfiguration(experimentName string, currentOptions *Options) {
 	}
 }
 
+// errKeyLogFileIsReportFile indicates that the TLS key log file is the report file.
+var errKeyLogFileIsReportFile = errors.New("the TLS key log file cannot be the report file")
+
+// checkKeyLogFile returns an error if the TLS key log file, if any, is the report
+// file, such that we never write the TLS key log into the report file.
+func checkKeyLogFile(currentOptions *Options) error {
+	if currentOptions.KeyLogFile == "" {
+		return nil
+	}
+	reportFile := currentOptions.ReportFile
+	if reportFile == "" {
+		reportFile = "report.jsonl" // like MainWithConfiguration
+	}
+	keyLogPath, err := filepath.Abs(currentOptions.KeyLogFile)
+	if err != nil {
+		return err
+	}
+	reportPath, err := filepath.Abs(reportFile)
+	if err != nil {
+		return err
+	}
+	if keyLogPath == reportPath {
+		return fmt.Errorf("%w: %s", errKeyLogFileIsReportFile, currentOptions.KeyLogFile)
+	}
+	return nil
+}
+
+// newLogger creates the logger depending on the current options and
+// sets it as the default apex/log logger.
+func newLogger(currentOptions *Options) *log.Logger {
+	logHandler := logx.NewHandlerWithDefaultSettings()
+	logHandler.Emoji = currentOptions.Emoji
+	logger := &log.Logger{Level: log.InfoLevel, Handler: logHandler}
+	if currentOptions.Verbose {
+		logger.Level = log.DebugLevel
+	}
+	log.Log = logger
+	return logger
+}
+
 // mainSingleIteration runs a single iteration. There may be multiple iterations
 // when the user specifies the --repeat-every command line flag.
 func mainSingleIteration(logger model.Logger, experimentName string, currentOptions *Options) {
@@ -364,15 +450,22 @@ func mainSingleIteration(logger model.Logger, experimentName string, currentOpti
 	lookupBackendsOrPanic(ctx, sess)
 	lookupLocationOrPanic(ctx, sess)
 
+	// Before measuring, retry submitting the measurements we could not
+	// submit during previous runs whose backoff has expired.
+	queue := newSubmitQueueOrPanic(miniooniDir)
+	if submissionEnabled(currentOptions) {
+		flushSubmitQueue(ctx, sess, queue, currentOptions, false)
+	}
+
 	// We handle the oonirun experiment name specially. The user must specify
 	// `miniooni -i {OONIRunURL} oonirun` to run a OONI Run URL (v1 or v2).
 	if experimentName == "oonirun" {
-		ooniRunMain(ctx, sess, currentOptions, annotations)
+		ooniRunMain(ctx, sess, currentOptions, annotations, queue)
 		return
 	}
 
 	// Otherwise just run OONI experiments as we normally do.
-	runx(ctx, sess, experimentName, annotations, extraOptions, currentOptions)
+	runx(ctx, sess, experimentName, annotations, extraOptions, currentOptions, queue)
 }
 
 func documentationForOptions(name string, factory *registry.Factory) string {
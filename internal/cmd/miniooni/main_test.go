@@ -1,6 +1,11 @@
 package main
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestSimple(t *testing.T) {
 	if testing.Short() {
@@ -10,3 +15,44 @@ func TestSimple(t *testing.T) {
 		Yes: true,
 	})
 }
+
+func TestCheckKeyLogFile(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testcase struct {
+		name    string
+		options *Options
+		expect  error
+	}
+	cases := []testcase{{
+		name:    "without a key log file",
+		options: &Options{},
+		expect:  nil,
+	}, {
+		name:    "with a key log file different from the report file",
+		options: &Options{KeyLogFile: "keylog.txt", ReportFile: "report.jsonl"},
+		expect:  nil,
+	}, {
+		name:    "with the default report file",
+		options: &Options{KeyLogFile: "report.jsonl"},
+		expect:  errKeyLogFileIsReportFile,
+	}, {
+		name:    "with equivalent relative paths",
+		options: &Options{KeyLogFile: "./x/../report.jsonl", ReportFile: "report.jsonl"},
+		expect:  errKeyLogFileIsReportFile,
+	}, {
+		name:    "with an absolute path and a relative path",
+		options: &Options{KeyLogFile: filepath.Join(cwd, "report.jsonl"), ReportFile: "report.jsonl"},
+		expect:  errKeyLogFileIsReportFile,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkKeyLogFile(tc.options)
+			if !errors.Is(err, tc.expect) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
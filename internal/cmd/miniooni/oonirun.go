@@ -12,12 +12,14 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 // ooniRunMain runs the experiments described by the given OONI Run URLs. This
 // function works with both v1 and v2 OONI Run URLs.
 func ooniRunMain(ctx context.Context,
-	sess *engine.Session, currentOptions *Options, annotations map[string]string) {
+	sess *engine.Session, currentOptions *Options, annotations map[string]string,
+	queue *submitqueue.Queue) {
 	logger := sess.Logger()
 	cfg := &oonirun.LinkConfig{
 		AcceptChanges: currentOptions.Yes,
@@ -29,6 +31,8 @@ func ooniRunMain(ctx context.Context,
 		Random:        currentOptions.Random,
 		ReportFile:    currentOptions.ReportFile,
 		Session:       sess,
+		SubmitQueue:   queue,
+		Submitters:    mustParseSubmitterSpecs(currentOptions.Submitters),
 	}
 	for _, URL := range currentOptions.Inputs {
 		r := oonirun.NewLinkRunner(cfg, URL)
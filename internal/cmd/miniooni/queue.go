@@ -0,0 +1,157 @@
+package main
+
+//
+// Offline submission queue
+//
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
+	"github.com/ooni/probe-cli/v3/internal/submitter"
+	"github.com/spf13/cobra"
+)
+
+// registerQueue registers the queue subcommand
+func registerQueue(rootCmd *cobra.Command, globalOptions *Options) {
+	queueCmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Manages the measurements that we could not submit",
+	}
+	rootCmd.AddCommand(queueCmd)
+
+	queueCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Lists the queued measurements",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			queueList(globalOptions)
+		},
+	})
+
+	queueCmd.AddCommand(&cobra.Command{
+		Use:   "flush",
+		Short: "Submits all the queued measurements ignoring the retry backoff",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			queueFlush(globalOptions)
+		},
+	})
+
+	var dropAll bool
+	dropCmd := &cobra.Command{
+		Use:   "drop [UID...]",
+		Short: "Removes the given queued measurements",
+		Run: func(cmd *cobra.Command, args []string) {
+			queueDrop(globalOptions, args, dropAll)
+		},
+	}
+	dropCmd.Flags().BoolVar(
+		&dropAll,
+		"all",
+		false,
+		"remove all the queued measurements",
+	)
+	queueCmd.AddCommand(dropCmd)
+}
+
+// newSubmitQueueOrPanic opens the submit queue or panics on failure
+func newSubmitQueueOrPanic(miniooniDir string) *submitqueue.Queue {
+	return submitqueue.New(newKVStoreOrPanic(miniooniDir))
+}
+
+// submissionEnabled returns whether we should submit measurements
+func submissionEnabled(currentOptions *Options) bool {
+	return !currentOptions.NoCollector || len(currentOptions.Submitters) > 0
+}
+
+// flushSubmitQueue attempts to submit the queued measurements. When force is
+// false, we only submit the measurements whose retry backoff has expired.
+func flushSubmitQueue(ctx context.Context, sess *engine.Session,
+	queue *submitqueue.Queue, currentOptions *Options, force bool) {
+	extra, err := submitter.NewAll(mustParseSubmitterSpecs(currentOptions.Submitters), sess.Logger())
+	runtimex.PanicOnError(err, "cannot create submitters")
+	subm, err := engine.NewSubmitter(ctx, engine.SubmitterConfig{
+		Enabled: !currentOptions.NoCollector,
+		Session: sess,
+		Logger:  sess.Logger(),
+		Extra:   extra,
+	})
+	runtimex.PanicOnError(err, "cannot create submitter")
+	result, err := queue.Flush(ctx, subm, force)
+	runtimex.PanicOnError(err, "cannot flush the submit queue")
+	if len(result.Submitted) > 0 || len(result.Failed) > 0 {
+		log.Infof("submit queue: submitted %d, failed %d, postponed %d",
+			len(result.Submitted), len(result.Failed), len(result.Skipped))
+	}
+}
+
+// queueMiniooniDir returns the miniooni directory for the queue subcommands
+func queueMiniooniDir(currentOptions *Options) string {
+	homeDir := gethomedir(currentOptions.HomeDir)
+	runtimex.Assert(homeDir != "", "home directory is empty")
+	return path.Join(homeDir, ".miniooni")
+}
+
+// queueList implements `miniooni queue list`
+func queueList(currentOptions *Options) {
+	newLogger(currentOptions)
+	queue := newSubmitQueueOrPanic(queueMiniooniDir(currentOptions))
+	entries, err := queue.List()
+	runtimex.PanicOnError(err, "cannot list the submit queue")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "UID\tTEST NAME\tINPUT\tATTEMPTS\tNEXT ATTEMPT\tLAST ERROR")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", e.UID, e.TestName, e.Input,
+			e.Attempts, e.NextAttempt.Local().Format(time.RFC3339), e.LastError)
+	}
+	tw.Flush()
+}
+
+// queueFlush implements `miniooni queue flush`
+func queueFlush(currentOptions *Options) {
+	runtimex.PanicOnError(engine.CheckEmbeddedPsiphonConfig(), "Invalid embedded psiphon config")
+	if currentOptions.Tunnel != "" {
+		currentOptions.Proxy = fmt.Sprintf("%s:///", currentOptions.Tunnel)
+	}
+	runtimex.Assert(submissionEnabled(currentOptions), "no submitter enabled")
+	logger := newLogger(currentOptions)
+	ctx := context.Background()
+	miniooniDir := queueMiniooniDir(currentOptions)
+	queue := newSubmitQueueOrPanic(miniooniDir)
+	sess := newSessionOrPanic(ctx, currentOptions, miniooniDir, logger)
+	defer sess.Close()
+	if !currentOptions.NoCollector {
+		lookupBackendsOrPanic(ctx, sess)
+	}
+	flushSubmitQueue(ctx, sess, queue, currentOptions, true)
+}
+
+// queueDrop implements `miniooni queue drop`
+func queueDrop(currentOptions *Options, uids []string, all bool) {
+	newLogger(currentOptions)
+	queue := newSubmitQueueOrPanic(queueMiniooniDir(currentOptions))
+	if all {
+		entries, err := queue.List()
+		runtimex.PanicOnError(err, "cannot list the submit queue")
+		uids = nil
+		for _, e := range entries {
+			uids = append(uids, e.UID)
+		}
+	}
+	for _, uid := range uids {
+		if err := queue.Drop(uid); err != nil {
+			log.Warnf("cannot drop %s: %s", uid, err.Error())
+			continue
+		}
+		log.Infof("dropped %s", uid)
+	}
+}
@@ -9,11 +9,13 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/oonirun"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
 )
 
 // runx runs the given experiment by name
 func runx(ctx context.Context, sess oonirun.Session, experimentName string,
-	annotations map[string]string, extraOptions map[string]any, currentOptions *Options) {
+	annotations map[string]string, extraOptions map[string]any, currentOptions *Options,
+	queue *submitqueue.Queue) {
 	desc := &oonirun.Experiment{
 		Annotations:    annotations,
 		ExtraOptions:   extraOptions,
@@ -26,6 +28,8 @@ func runx(ctx context.Context, sess oonirun.Session, experimentName string,
 		Random:         currentOptions.Random,
 		ReportFile:     currentOptions.ReportFile,
 		Session:        sess,
+		SubmitQueue:    queue,
+		Submitters:     mustParseSubmitterSpecs(currentOptions.Submitters),
 	}
 	err := desc.Run(ctx)
 	runtimex.PanicOnError(err, "cannot run experiment")
@@ -9,6 +9,7 @@ import (
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/version"
@@ -19,6 +20,14 @@ const (
 	softwareVersion = version.Version
 )
 
+// newKVStoreOrPanic creates the key-value store or panics on failure
+func newKVStoreOrPanic(miniooniDir string) *kvstore.FS {
+	kvstore2dir := filepath.Join(miniooniDir, "kvstore2")
+	kvs, err := kvstore.NewFS(kvstore2dir)
+	runtimex.PanicOnError(err, "cannot create kvstore2 directory")
+	return kvs
+}
+
 // newSessionOrPanic creates and starts a new session or panics on failure
 func newSessionOrPanic(ctx context.Context, currentOptions *Options,
 	miniooniDir string, logger model.Logger) *engine.Session {
@@ -27,23 +36,31 @@ func newSessionOrPanic(ctx context.Context, currentOptions *Options,
 		proxyURL = mustParseURL(currentOptions.Proxy)
 	}
 
-	kvstore2dir := filepath.Join(miniooniDir, "kvstore2")
-	kvstore, err := kvstore.NewFS(kvstore2dir)
-	runtimex.PanicOnError(err, "cannot create kvstore2 directory")
+	kvstore := newKVStoreOrPanic(miniooniDir)
 
 	tunnelDir := filepath.Join(miniooniDir, "tunnel")
-	err = os.MkdirAll(tunnelDir, 0700)
+	err := os.MkdirAll(tunnelDir, 0700)
 	runtimex.PanicOnError(err, "cannot create tunnelDir")
 
+	if currentOptions.CaptureDir != "" {
+		err = os.MkdirAll(currentOptions.CaptureDir, 0700)
+		runtimex.PanicOnError(err, "cannot create captureDir")
+	}
+
 	config := engine.SessionConfig{
+		CaptureDir:          currentOptions.CaptureDir,
+		KeyLogFile:          currentOptions.KeyLogFile,
 		KVStore:             kvstore,
 		Logger:              logger,
 		ProxyURL:            proxyURL,
+		PTBinary:            currentOptions.PTBinary,
+		PTBridge:            currentOptions.PTBridge,
 		SnowflakeRendezvous: currentOptions.SnowflakeRendezvous,
 		SoftwareName:        softwareName,
 		SoftwareVersion:     softwareVersion,
 		TorArgs:             currentOptions.TorArgs,
 		TorBinary:           currentOptions.TorBinary,
+		TraceSubscriber:     measurexlite.NewLoggerSubscriber(logger),
 		TunnelDir:           tunnelDir,
 	}
 	if currentOptions.ProbeServicesURL != "" {
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/internal/submitter"
 )
 
 // splitPair takes in input a string in the form KEY=VALUE and splits it. This
@@ -48,6 +49,16 @@ func mustMakeMapStringAny(input []string) (output map[string]any) {
 	return
 }
 
+// mustParseSubmitterSpecs parses a list of TYPE=URL submitter specs, or panics on error
+func mustParseSubmitterSpecs(input []string) (output []*submitter.Config) {
+	for _, spec := range input {
+		config, err := submitter.ParseSpec(spec)
+		runtimex.PanicOnError(err, "cannot parse submitter spec")
+		output = append(output, config)
+	}
+	return
+}
+
 // mustParseURL parses the given URL or panics
 func mustParseURL(URL string) *url.URL {
 	rv, err := url.Parse(URL)
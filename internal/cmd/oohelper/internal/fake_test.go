@@ -60,6 +60,30 @@ func (c FakeResolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, e
 	return nil, errors.New("not implemented")
 }
 
+func (c FakeResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c FakeResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c FakeResolver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (c FakeResolver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c FakeResolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c FakeResolver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return nil, errors.New("not implemented")
+}
+
 var _ model.Resolver = FakeResolver{}
 
 type FakeTransport struct {
@@ -36,6 +36,10 @@ type dnsConfig struct {
 	// Out is the MANDATORY channel where we publish the results.
 	Out chan ctrlDNSResult
 
+	// Upstreams contains the OPTIONAL upstream resolvers to query in parallel
+	// instead of using NewResolver to create a single resolver.
+	Upstreams []*upstreamResolver
+
 	// Wg is MANDATORY and allows [dnsDo] to synchronize with the caller.
 	Wg *sync.WaitGroup
 }
@@ -50,13 +54,21 @@ func dnsDo(ctx context.Context, config *dnsConfig) {
 	// make sure the caller knows when we're done
 	defer config.Wg.Done()
 
-	// create a temporary resolver for this micro-measurement
-	reso := config.NewResolver(config.Logger)
-	defer reso.CloseIdleConnections()
-
-	// perform and log the actual DNS lookup
+	// perform and log the actual DNS lookup using either the upstreams
+	// or a temporary resolver for this micro-measurement
 	ol := measurexlite.NewOperationLogger(config.Logger, "DNSLookup %s", config.Domain)
-	addrs, err := reso.LookupHost(ctx, config.Domain)
+	var (
+		addrs     []string
+		err       error
+		upstreams []model.THDNSUpstreamResult
+	)
+	if len(config.Upstreams) > 0 {
+		addrs, upstreams, err = dnsLookupUpstreams(ctx, config.Logger, config.Upstreams, config.Domain)
+	} else {
+		reso := config.NewResolver(config.Logger)
+		defer reso.CloseIdleConnections()
+		addrs, err = reso.LookupHost(ctx, config.Domain)
+	}
 	ol.Stop(err)
 
 	// make sure we return an empty slice on failure because this
@@ -72,9 +84,10 @@ func dnsDo(ctx context.Context, config *dnsConfig) {
 	// emit the result; note that the ASNs field is unused by
 	// the TH and is not serialized to JSON.
 	config.Out <- ctrlDNSResult{
-		Failure: failure,
-		Addrs:   addrs,
-		ASNs:    []int64{},
+		Failure:   failure,
+		Addrs:     addrs,
+		ASNs:      []int64{},
+		Upstreams: upstreams,
 	}
 }
 
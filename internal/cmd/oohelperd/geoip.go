@@ -0,0 +1,29 @@
+package main
+
+//
+// Hot-reloading external geoip databases
+//
+
+import (
+	"context"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// geoipReloader calls reload every interval until the context is done. The reload
+// function should reload the external databases whose files have changed.
+func geoipReloader(ctx context.Context, logger model.Logger, interval time.Duration, reload func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := reload(); err != nil {
+				logger.Warnf("geoip: cannot reload databases: %s", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+func TestGeoIPReloader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		calls    = &atomic.Int64{}
+		warnings = &atomic.Int64{}
+	)
+	logger := &mocks.Logger{
+		MockWarnf: func(format string, v ...any) {
+			warnings.Add(1)
+		},
+	}
+	reload := func() error {
+		if calls.Add(1) >= 3 {
+			cancel()
+		}
+		return errors.New("mocked error")
+	}
+	done := make(chan any)
+	go func() {
+		geoipReloader(ctx, logger, time.Millisecond, reload)
+		close(done)
+	}()
+	<-done
+	if calls.Load() < 3 {
+		t.Fatal("unexpected number of calls", calls.Load())
+	}
+	if warnings.Load() != calls.Load() {
+		t.Fatal("expected a warning for each failed reload")
+	}
+}
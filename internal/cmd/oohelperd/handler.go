@@ -52,6 +52,25 @@ type handler struct {
 
 	// NewTLSHandshaker is the MANDATORY factory for creating a new TLS handshaker.
 	NewTLSHandshaker func(model.Logger) model.TLSHandshaker
+
+	// MaxEndpoints is the OPTIONAL maximum number of TCP endpoints that a
+	// request may ask us to measure. Zero or negative means no limit.
+	MaxEndpoints int64
+
+	// RateLimiter is the OPTIONAL per-client rate limiter.
+	RateLimiter *rateLimiter
+
+	// Semaphore is the OPTIONAL semaphore limiting the number of requests
+	// we measure concurrently. When it is full, we return 503.
+	Semaphore chan any
+
+	// TrustXForwardedFor OPTIONALLY indicates that we should identify clients
+	// using the X-Forwarded-For header set by a reverse proxy.
+	TrustXForwardedFor bool
+
+	// Upstreams contains the OPTIONAL upstream resolvers to query in parallel
+	// when measuring DNS. When empty, we use NewResolver.
+	Upstreams []*upstreamResolver
 }
 
 var _ http.Handler = &handler{}
@@ -76,6 +95,26 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// enforce the per-client rate limit
+	if !h.RateLimiter.Allow(clientIPAddress(req, h.TrustXForwardedFor)) {
+		metricRequestsCount.WithLabelValues("429", "rate_limited").Inc()
+		w.WriteHeader(429)
+		return
+	}
+
+	// shed load when we're already measuring too many requests
+	if h.Semaphore != nil {
+		select {
+		case h.Semaphore <- true:
+			defer func() { <-h.Semaphore }()
+		default:
+			metricRequestsCount.WithLabelValues("503", "too_many_concurrent_requests").Inc()
+			w.Header().Add("Retry-After", "1")
+			w.WriteHeader(503)
+			return
+		}
+	}
+
 	// read and parse request body
 	reader := io.LimitReader(req.Body, h.MaxAcceptableBody)
 	data, err := netxlite.ReadAllContext(req.Context(), reader)
@@ -90,6 +129,11 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(400)
 		return
 	}
+	if h.MaxEndpoints > 0 && int64(len(creq.TCPConnect)) > h.MaxEndpoints {
+		metricRequestsCount.WithLabelValues("400", "too_many_endpoints").Inc()
+		w.WriteHeader(400)
+		return
+	}
 
 	// measure the given input
 	started := time.Now()
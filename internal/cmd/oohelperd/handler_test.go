@@ -72,6 +72,9 @@ func TestHandlerWorkingAsIntended(t *testing.T) {
 		measureFn func(
 			ctx context.Context, config *handler, creq *model.THRequest) (*model.THResponse, error)
 
+		// configureFn optionally allows further configuring the handler
+		configureFn func(h *handler)
+
 		// reqBody is the request body to use
 		reqBody io.Reader
 
@@ -114,6 +117,59 @@ func TestHandlerWorkingAsIntended(t *testing.T) {
 		respStatusCode:  400,
 		respContentType: "",
 		parseBody:       false,
+	}, {
+		name: "when the client is rate limited",
+		configureFn: func(h *handler) {
+			h.RateLimiter = newRateLimiter(1e-06, 1)
+			// note: the test request has an empty RemoteAddr
+			h.RateLimiter.Allow("")
+		},
+		reqMethod:       "POST",
+		reqContentType:  "application/json",
+		reqBody:         strings.NewReader(simpleRequestForHandler),
+		respStatusCode:  429,
+		respContentType: "",
+		parseBody:       false,
+	}, {
+		name: "when there are too many concurrent requests",
+		configureFn: func(h *handler) {
+			h.Semaphore = newSemaphore(1)
+			h.Semaphore <- true
+		},
+		reqMethod:       "POST",
+		reqContentType:  "application/json",
+		reqBody:         strings.NewReader(simpleRequestForHandler),
+		respStatusCode:  503,
+		respContentType: "",
+		parseBody:       false,
+	}, {
+		name: "when the request contains too many endpoints",
+		configureFn: func(h *handler) {
+			h.MaxEndpoints = 1
+		},
+		reqMethod:       "POST",
+		reqContentType:  "application/json",
+		reqBody:         strings.NewReader(`{"tcp_connect": ["8.8.8.8:443", "8.8.4.4:443"]}`),
+		respStatusCode:  400,
+		respContentType: "",
+		parseBody:       false,
+	}, {
+		name: "with limits that do not prevent measuring",
+		measureFn: func(ctx context.Context, config *handler, creq *model.THRequest) (*model.THResponse, error) {
+			cresp := &model.THResponse{}
+			return cresp, nil
+		},
+		configureFn: func(h *handler) {
+			h.MaxEndpoints = 1
+			h.RateLimiter = newRateLimiter(1, 1)
+			h.Semaphore = newSemaphore(1)
+		},
+		reqMethod:       "POST",
+		reqContentType:  "application/json",
+		reqBody:         strings.NewReader(simpleRequestForHandler),
+		respStatusCode:  200,
+		respContentType: "application/json",
+		parseBody:       true,
 	}, {
 		name:            "with measurement failure",
 		reqMethod:       "POST",
@@ -155,6 +211,9 @@ func TestHandlerWorkingAsIntended(t *testing.T) {
 			if expect.measureFn != nil {
 				handler.Measure = expect.measureFn
 			}
+			if expect.configureFn != nil {
+				expect.configureFn(handler)
+			}
 
 			// create request
 			req, err := http.NewRequestWithContext(
@@ -10,12 +10,14 @@ import (
 	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
@@ -34,12 +36,40 @@ var (
 	// debug controls whether to enable verbose logging
 	debug = flag.Bool("debug", false, "Toggle debug mode")
 
+	// geoipASNDatabase is the path of an external MMDB database to map IP addresses to ASNs
+	geoipASNDatabase = flag.String("geoip-asn-db", "",
+		"Path of the MMDB database to map IP addresses to ASNs (default: use the embedded database)")
+
+	// geoipReloadInterval is the interval between checks for changes of -geoip-asn-db
+	geoipReloadInterval = flag.Duration("geoip-reload-interval", 10*time.Minute,
+		"Interval between checks for changes of the -geoip-asn-db file (0 disables reloading)")
+
+	// maxConcurrentRequests is the maximum number of requests we measure concurrently
+	maxConcurrentRequests = flag.Int64("max-concurrent-requests", 0,
+		"Maximum number of requests to measure concurrently before returning 503 (0 means no limit)")
+
+	// maxEndpoints is the maximum number of TCP endpoints per request
+	maxEndpoints = flag.Int64("max-endpoints", 0,
+		"Maximum number of TCP endpoints a request may ask to measure (0 means no limit)")
+
 	// pprofEndpoint is the endpoint where we serve pprof info.
 	pprofEndpoint = flag.String("pprof-endpoint", "127.0.0.1:6061", "Pprof endpoint")
 
 	// prometheusEndpoint is the endpoint where we serve prometheus metrics
 	prometheusEndpoint = flag.String("prometheus-endpoint", "127.0.0.1:9091", "Prometheus endpoint")
 
+	// rateLimit is the number of requests per second allowed for each client IP address
+	rateLimit = flag.Float64("rate-limit", 0,
+		"Requests per second allowed for each client IPv4 address or IPv6 /64 before returning 429 (0 means no limit)")
+
+	// rateLimitBurst is the maximum burst of requests allowed for each client IP address
+	rateLimitBurst = flag.Int64("rate-limit-burst", 10,
+		"Maximum burst of requests allowed for each client IP address when using -rate-limit")
+
+	// resolversFile is the file containing the upstream resolvers URLs
+	resolversFile = flag.String("resolvers-file", "",
+		"File containing the URLs of the upstream resolvers to use, one per line")
+
 	// replace runs the commands to replace a running oohelperd.
 	replace = flag.Bool("replace", false, "Replaces a running oohelperd instance")
 
@@ -52,13 +82,52 @@ var (
 	// srvWg is used by tests to know when the server has shut down
 	srvWg = new(sync.WaitGroup)
 
+	// trustXForwardedFor indicates we should use X-Forwarded-For to identify clients
+	trustXForwardedFor = flag.Bool("trust-x-forwarded-for", false,
+		"Use X-Forwarded-For to identify clients (only safe behind a reverse proxy)")
+
+	// upstreams contains the upstream resolvers configured using -resolver
+	// and -resolvers-file, or is empty when we should use the default resolver.
+	upstreams []*upstreamResolver
+
 	// versionFlag indicates we must print the version on stdout
 	versionFlag = flag.Bool("version", false, "Prints version information on the stdout")
 )
 
+func init() {
+	flag.Var(&resolverURLs, "resolver",
+		"URL of an upstream resolver to use (e.g., https://dns.google/dns-query, dot://1.1.1.1, "+
+			"udp://9.9.9.9:53, tcp://8.8.8.8); may be repeated to query several resolvers in parallel")
+}
+
+// resolverURLs contains the upstream resolvers URLs passed using -resolver.
+var resolverURLs stringListFlag
+
+// stringListFlag is a [flag.Value] collecting the values of a repeated flag.
+type stringListFlag []string
+
+var _ flag.Value = &stringListFlag{}
+
+// String implements flag.Value.
+func (sl *stringListFlag) String() string {
+	return strings.Join(*sl, ",")
+}
+
+// Set implements flag.Value.
+func (sl *stringListFlag) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
 // newResolver creates a new [model.Resolver] suitable for serving
 // requests coming from ooniprobe clients.
 func newResolver(logger model.Logger) model.Resolver {
+	// When the operator configured upstream resolvers, the HTTP and HTTP3
+	// clients use all of them, like we do when measuring DNS.
+	if len(upstreams) > 0 {
+		return newUpstreamsResolver(logger, upstreams)
+	}
+
 	// Implementation note: pin to a specific resolver so we don't depend upon the
 	// default resolver configured by the box. Also, use an encrypted transport thus
 	// we're less vulnerable to any policy implemented by the box's provider.
@@ -127,9 +196,23 @@ func newHandler() *handler {
 		NewTLSHandshaker: func(logger model.Logger) model.TLSHandshaker {
 			return netxlite.NewTLSHandshakerStdlib(logger)
 		},
+		MaxEndpoints:       *maxEndpoints,
+		RateLimiter:        newRateLimiter(*rateLimit, *rateLimitBurst),
+		Semaphore:          newSemaphore(*maxConcurrentRequests),
+		TrustXForwardedFor: *trustXForwardedFor,
+		Upstreams:          upstreams,
 	}
 }
 
+// newSemaphore creates the semaphore used by the [handler] to limit the
+// number of concurrent requests, or nil if size is not positive.
+func newSemaphore(size int64) chan any {
+	if size <= 0 {
+		return nil
+	}
+	return make(chan any, size)
+}
+
 func main() {
 	// parse command line options
 	flag.Parse()
@@ -155,6 +238,20 @@ func main() {
 		return
 	}
 
+	// load the upstream resolvers, if any
+	var err error
+	upstreams, err = loadUpstreamResolvers(resolverURLs, *resolversFile)
+	runtimex.PanicOnError(err, "loadUpstreamResolvers failed")
+
+	// use the external geoip database, if any, and reload it when it changes
+	err = geoipx.SetASNDatabase(*geoipASNDatabase)
+	runtimex.PanicOnError(err, "geoipx.SetASNDatabase failed")
+	reloaderCtx, cancelReloader := context.WithCancel(context.Background())
+	defer cancelReloader()
+	if *geoipASNDatabase != "" && *geoipReloadInterval > 0 {
+		go geoipReloader(reloaderCtx, log.Log, *geoipReloadInterval, geoipx.Reload)
+	}
+
 	// create the HTTP server mux
 	mux := http.NewServeMux()
 
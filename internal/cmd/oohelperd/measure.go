@@ -48,6 +48,7 @@ func measure(ctx context.Context, config *handler, creq *ctrlRequest) (*ctrlResp
 			Logger:      logger,
 			NewResolver: config.NewResolver,
 			Out:         dnsch,
+			Upstreams:   config.Upstreams,
 			Wg:          wg,
 		})
 	}
@@ -7,11 +7,41 @@ package main
 //
 
 import (
+	"github.com/ooni/probe-cli/v3/internal/geoipx"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var (
+	// metricDNSUpstreamLookupCount counts the lookups performed by each upstream resolver.
+	metricDNSUpstreamLookupCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oohelperd_dns_upstream_lookups_count",
+		Help: "Total number of DNS lookups performed by each upstream resolver",
+	}, []string{"upstream", "result"})
+
+	// metricDNSUpstreamLookupDurationSeconds tracks the duration of the lookups performed by each upstream resolver.
+	metricDNSUpstreamLookupDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "oohelperd_dns_upstream_lookup_duration_seconds",
+		Help:    "Time to complete a DNS lookup using each upstream resolver (in seconds)",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"upstream"})
+
+	// metricGeoIPReloadsCount counts the successful reloads of external geoip databases.
+	metricGeoIPReloadsCount = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "oohelperd_geoip_reloads_count",
+		Help: "Total number of successful reloads of external geoip databases",
+	}, func() float64 {
+		return float64(geoipx.ReadReloadStats().Reloads)
+	})
+
+	// metricGeoIPReloadFailuresCount counts the failed reloads of external geoip databases.
+	metricGeoIPReloadFailuresCount = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "oohelperd_geoip_reload_failures_count",
+		Help: "Total number of failed reloads of external geoip databases",
+	}, func() float64 {
+		return float64(geoipx.ReadReloadStats().Failures)
+	})
+
 	// metricRequestsCount counts the number of requests we served.
 	metricRequestsCount = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "oohelperd_requests_count",
@@ -89,9 +89,9 @@ func quicDo(ctx context.Context, config *quicConfig) {
 	defer measurexlite.MaybeCloseQUICConn(quicConn)
 	ol.Stop(err)
 
-	out.QUIC = ctrlQUICResult{
-		ServerName: config.URLHostname,
-		Status:     err == nil,
-		Failure:    newfailure(err),
+	var state tls.ConnectionState
+	if err == nil {
+		state = quicConn.ConnectionState().TLS.ConnectionState
 	}
+	out.QUIC = *newCtrlTLSResult(config.URLHostname, state, err)
 }
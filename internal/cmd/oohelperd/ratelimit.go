@@ -0,0 +1,157 @@
+package main
+
+//
+// Abuse protection
+//
+
+import (
+	"container/list"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiterMaxBuckets is the maximum number of buckets. When we reach
+// this limit, we remove the bucket of the least recently seen client, which
+// is the most likely to be full and hence the cheapest to forget.
+const rateLimiterMaxBuckets = 1 << 14
+
+// rateLimiter implements per-client token-bucket rate limiting.
+type rateLimiter struct {
+	// burst is the bucket size.
+	burst float64
+
+	// buckets maps each client to its bucket inside lru.
+	buckets map[string]*list.Element
+
+	// lru contains the buckets from the most to the least recently used.
+	lru *list.List
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// rate is the number of tokens added to a bucket each second.
+	rate float64
+
+	// timeNow allows to mock time.Now in tests.
+	timeNow func() time.Time
+}
+
+// rateLimiterBucket is the token bucket of a client.
+type rateLimiterBucket struct {
+	// client is the client owning the bucket.
+	client string
+
+	// tokens is the number of tokens left after the last update.
+	tokens float64
+
+	// updated is the time of the last update.
+	updated time.Time
+}
+
+// newRateLimiter creates a new rateLimiter allowing each client to
+// perform rate requests per second with the given burst. This function
+// returns nil, which means no rate limiting, when rate is not positive.
+func newRateLimiter(rate float64, burst int64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		burst:   float64(burst),
+		buckets: map[string]*list.Element{},
+		lru:     list.New(),
+		mu:      sync.Mutex{},
+		rate:    rate,
+		timeNow: time.Now,
+	}
+}
+
+// Allow returns whether the client with the given IP address can perform a
+// request now. We use a single bucket for all the IPv6 addresses within the
+// same /64 prefix, because each end user typically has at least a /64.
+// Calling this method on a nil rateLimiter always returns true.
+func (rl *rateLimiter) Allow(client string) bool {
+	if rl == nil {
+		return true
+	}
+	client = rateLimiterKey(client)
+	defer rl.mu.Unlock()
+	rl.mu.Lock()
+	now := rl.timeNow()
+	bucket := rl.bucketLocked(client, now)
+	bucket.tokens = rl.refill(bucket, now)
+	bucket.updated = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// bucketLocked returns the bucket of the given client, creating it
+// if needed, and marks it as the most recently used one. The caller
+// MUST hold the mutex.
+func (rl *rateLimiter) bucketLocked(client string, now time.Time) *rateLimiterBucket {
+	if elem := rl.buckets[client]; elem != nil {
+		rl.lru.MoveToFront(elem)
+		return elem.Value.(*rateLimiterBucket)
+	}
+	if rl.lru.Len() >= rateLimiterMaxBuckets {
+		oldest := rl.lru.Back()
+		rl.lru.Remove(oldest)
+		delete(rl.buckets, oldest.Value.(*rateLimiterBucket).client)
+	}
+	bucket := &rateLimiterBucket{client: client, tokens: rl.burst, updated: now}
+	rl.buckets[client] = rl.lru.PushFront(bucket)
+	return bucket
+}
+
+// refill returns the number of tokens in the bucket at the given time.
+func (rl *rateLimiter) refill(bucket *rateLimiterBucket, now time.Time) float64 {
+	tokens := bucket.tokens + now.Sub(bucket.updated).Seconds()*rl.rate
+	if tokens > rl.burst {
+		tokens = rl.burst
+	}
+	return tokens
+}
+
+// rateLimiterKey returns the key identifying the bucket of the client with
+// the given IP address, which is the /64 prefix for IPv6 addresses and the
+// address itself otherwise (including when it is not a valid address).
+func rateLimiterKey(client string) string {
+	addr, err := netip.ParseAddr(client)
+	if err != nil || !addr.Is6() || addr.Is4In6() {
+		return client
+	}
+	prefix, err := addr.WithZone("").Prefix(64)
+	if err != nil {
+		return client // should not happen
+	}
+	return prefix.String()
+}
+
+// clientIPAddress returns the IP address of the client that sent the
+// request. When trustXForwardedFor is true, which is only safe when we are
+// running behind a reverse proxy, we use the rightmost address in the
+// X-Forwarded-For header, which is the one added by the proxy.
+func clientIPAddress(req *http.Request, trustXForwardedFor bool) string {
+	if trustXForwardedFor {
+		if values := req.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			addrs := strings.Split(values[len(values)-1], ",")
+			if addr := strings.TrimSpace(addrs[len(addrs)-1]); addr != "" {
+				return addr
+			}
+		}
+	}
+	addr, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return addr
+}
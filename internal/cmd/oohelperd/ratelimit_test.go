@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("newRateLimiter returns nil when the rate is not positive", func(t *testing.T) {
+		if rl := newRateLimiter(0, 10); rl != nil {
+			t.Fatal("expected nil")
+		}
+	})
+
+	t.Run("a nil rateLimiter allows all requests", func(t *testing.T) {
+		var rl *rateLimiter
+		for idx := 0; idx < 100; idx++ {
+			if !rl.Allow("1.1.1.1") {
+				t.Fatal("expected true")
+			}
+		}
+	})
+
+	t.Run("we enforce the burst and refill over time", func(t *testing.T) {
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		rl := newRateLimiter(2, 3)
+		rl.timeNow = func() time.Time {
+			return now
+		}
+		for idx := 0; idx < 3; idx++ {
+			if !rl.Allow("1.1.1.1") {
+				t.Fatal("expected true at", idx)
+			}
+		}
+		if rl.Allow("1.1.1.1") {
+			t.Fatal("expected false after exhausting the burst")
+		}
+		if !rl.Allow("8.8.8.8") {
+			t.Fatal("expected distinct clients to have distinct buckets")
+		}
+		now = now.Add(500 * time.Millisecond) // one token at two tokens per second
+		if !rl.Allow("1.1.1.1") {
+			t.Fatal("expected true after refill")
+		}
+		if rl.Allow("1.1.1.1") {
+			t.Fatal("expected false after consuming the refilled token")
+		}
+		now = now.Add(time.Hour) // must not exceed the burst
+		for idx := 0; idx < 3; idx++ {
+			if !rl.Allow("1.1.1.1") {
+				t.Fatal("expected true at", idx)
+			}
+		}
+		if rl.Allow("1.1.1.1") {
+			t.Fatal("expected false after exhausting the burst")
+		}
+	})
+
+	t.Run("we remove the least recently used bucket when there are too many", func(t *testing.T) {
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		rl := newRateLimiter(1, 1)
+		rl.timeNow = func() time.Time {
+			return now
+		}
+		if !rl.Allow("1.1.1.1") {
+			t.Fatal("expected true")
+		}
+		for idx := 1; idx < rateLimiterMaxBuckets; idx++ {
+			rl.Allow(fmt.Sprintf("10.0.%d.%d", idx/256, idx%256))
+		}
+		if rl.Allow("1.1.1.1") {
+			t.Fatal("expected false because we still remember 1.1.1.1")
+		}
+		if !rl.Allow("8.8.8.8") {
+			t.Fatal("expected true")
+		}
+		if len(rl.buckets) != rateLimiterMaxBuckets || rl.lru.Len() != rateLimiterMaxBuckets {
+			t.Fatal("unexpected number of buckets", len(rl.buckets), rl.lru.Len())
+		}
+		if _, found := rl.buckets["10.0.0.1"]; found {
+			t.Fatal("expected the least recently used bucket to be removed")
+		}
+		if _, found := rl.buckets["1.1.1.1"]; !found {
+			t.Fatal("expected a recently used bucket to be kept")
+		}
+	})
+
+	t.Run("IPv6 clients within the same /64 share a bucket", func(t *testing.T) {
+		rl := newRateLimiter(1, 1)
+		if !rl.Allow("2001:db8:1:2::1") {
+			t.Fatal("expected true")
+		}
+		if rl.Allow("2001:db8:1:2:aaaa:bbbb:cccc:dddd") {
+			t.Fatal("expected false for an address within the same /64")
+		}
+		if !rl.Allow("2001:db8:1:3::1") {
+			t.Fatal("expected true for an address within another /64")
+		}
+	})
+}
+
+func TestRateLimiterKey(t *testing.T) {
+	testcases := map[string]string{
+		"130.192.91.211":        "130.192.91.211",
+		"::ffff:130.192.91.211": "::ffff:130.192.91.211",
+		"2001:db8:1:2:3:4:5:6":  "2001:db8:1:2::/64",
+		"fe80::1%eth0":          "fe80::/64",
+		"not an address":        "not an address",
+	}
+	for input, expect := range testcases {
+		if got := rateLimiterKey(input); got != expect {
+			t.Fatal("expected", expect, "got", got, "for", input)
+		}
+	}
+}
+
+func TestClientIPAddress(t *testing.T) {
+	type testcase struct {
+		name               string
+		remoteAddr         string
+		xForwardedFor      []string
+		trustXForwardedFor bool
+		expect             string
+	}
+
+	testcases := []testcase{{
+		name:       "with IPv4 remote address",
+		remoteAddr: "130.192.91.211:54321",
+		expect:     "130.192.91.211",
+	}, {
+		name:       "with IPv6 remote address",
+		remoteAddr: "[::1]:54321",
+		expect:     "::1",
+	}, {
+		name:       "with remote address without port",
+		remoteAddr: "130.192.91.211",
+		expect:     "130.192.91.211",
+	}, {
+		name:          "we ignore X-Forwarded-For by default",
+		remoteAddr:    "127.0.0.1:54321",
+		xForwardedFor: []string{"130.192.91.211"},
+		expect:        "127.0.0.1",
+	}, {
+		name:               "we use the rightmost X-Forwarded-For address when trusted",
+		remoteAddr:         "127.0.0.1:54321",
+		xForwardedFor:      []string{"10.0.0.1", "8.8.8.8, 130.192.91.211"},
+		trustXForwardedFor: true,
+		expect:             "130.192.91.211",
+	}, {
+		name:               "we fall back to the remote address with empty X-Forwarded-For",
+		remoteAddr:         "127.0.0.1:54321",
+		xForwardedFor:      []string{""},
+		trustXForwardedFor: true,
+		expect:             "127.0.0.1",
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &http.Request{
+				Header:     http.Header{},
+				RemoteAddr: tc.remoteAddr,
+			}
+			for _, value := range tc.xForwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIPAddress(req, tc.trustXForwardedFor); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
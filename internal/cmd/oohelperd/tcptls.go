@@ -6,7 +6,9 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"sync"
 	"time"
 
@@ -107,14 +109,43 @@ func tcpTLSDo(ctx context.Context, config *tcpTLSConfig) {
 		ServerName: config.URLHostname,
 	}
 	thx := config.NewTSLHandshaker(config.Logger)
-	tlsConn, _, err := thx.Handshake(ctx, conn, tlsConfig)
+	tlsConn, state, err := thx.Handshake(ctx, conn, tlsConfig)
 	ol.Stop(err)
-	out.TLS = &ctrlTLSResult{
-		ServerName: config.URLHostname,
+	out.TLS = newCtrlTLSResult(config.URLHostname, state, err)
+	measurexlite.MaybeClose(tlsConn)
+}
+
+// newCtrlTLSResult creates a new ctrlTLSResult given the results of a TLS
+// or QUIC handshake. On success, we include information about the negotiated
+// parameters and the certificates sent by the server, which allow the probe
+// to detect whether it saw the same certificate chain.
+func newCtrlTLSResult(serverName string, state tls.ConnectionState, err error) *ctrlTLSResult {
+	out := &ctrlTLSResult{
+		ServerName: serverName,
 		Status:     err == nil,
 		Failure:    newfailure(err),
 	}
-	measurexlite.MaybeClose(tlsConn)
+	if err != nil {
+		return out
+	}
+	for _, cert := range state.PeerCertificates {
+		out.CertificateChainFingerprints = append(
+			out.CertificateChainFingerprints, tlsCertificateFingerprint(cert.Raw))
+	}
+	if len(out.CertificateChainFingerprints) > 0 {
+		out.LeafCertificateFingerprint = out.CertificateChainFingerprints[0]
+	}
+	out.TLSVersion = netxlite.TLSVersionString(state.Version)
+	out.CipherSuite = netxlite.TLSCipherSuiteString(state.CipherSuite)
+	out.NegotiatedProtocol = state.NegotiatedProtocol
+	return out
+}
+
+// tlsCertificateFingerprint returns the hex-encoded SHA-256 of the given
+// DER-encoded certificate.
+func tlsCertificateFingerprint(raw []byte) string {
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
 }
 
 // tcpMapFailure attempts to map netxlite failures to the strings
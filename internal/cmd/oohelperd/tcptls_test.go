@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -38,3 +41,52 @@ func Test_tcpMapFailure(t *testing.T) {
 		})
 	}
 }
+
+func Test_newCtrlTLSResult(t *testing.T) {
+	t.Run("on failure", func(t *testing.T) {
+		state := tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Raw: []byte("leaf")}},
+		}
+		out := newCtrlTLSResult("www.example.com", state, netxlite.NewTopLevelGenericErrWrapper(io.EOF))
+		expect := &ctrlTLSResult{
+			ServerName: "www.example.com",
+			Status:     false,
+			Failure:    out.Failure,
+		}
+		if out.Failure == nil || *out.Failure != netxlite.FailureEOFError {
+			t.Fatal("unexpected failure", out.Failure)
+		}
+		if diff := cmp.Diff(expect, out); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("on success", func(t *testing.T) {
+		state := tls.ConnectionState{
+			Version:            tls.VersionTLS13,
+			CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+			NegotiatedProtocol: "h2",
+			PeerCertificates: []*x509.Certificate{
+				{Raw: []byte("leaf")},
+				{Raw: []byte("intermediate")},
+			},
+		}
+		out := newCtrlTLSResult("www.example.com", state, nil)
+		expect := &ctrlTLSResult{
+			ServerName:                 "www.example.com",
+			Status:                     true,
+			Failure:                    nil,
+			LeafCertificateFingerprint: "9f91161f43433e49a6de6db680d79f60159f2e4ac9172621a12846428158440b",
+			CertificateChainFingerprints: []string{
+				"9f91161f43433e49a6de6db680d79f60159f2e4ac9172621a12846428158440b",
+				tlsCertificateFingerprint([]byte("intermediate")),
+			},
+			TLSVersion:         "TLSv1.3",
+			CipherSuite:        "TLS_AES_128_GCM_SHA256",
+			NegotiatedProtocol: "h2",
+		}
+		if diff := cmp.Diff(expect, out); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
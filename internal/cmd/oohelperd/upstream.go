@@ -0,0 +1,295 @@
+package main
+
+//
+// Upstream DNS resolvers
+//
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// errUnsupportedUpstreamURL indicates that we cannot handle an upstream resolver URL.
+var errUnsupportedUpstreamURL = errors.New("oohelperd: unsupported upstream resolver URL")
+
+// upstreamResolver is an upstream resolver used by the TH.
+type upstreamResolver struct {
+	// NewResolver is the MANDATORY factory to create a resolver using this upstream.
+	NewResolver func(model.Logger) model.Resolver
+
+	// URL is the MANDATORY URL identifying this upstream, which we use to
+	// attribute results to this upstream and as a metrics label.
+	URL string
+}
+
+// newUpstreamResolver creates a new [upstreamResolver] from a URL. We support
+// these URL schemes:
+//
+// - https://dns.google/dns-query for DNS-over-HTTPS;
+//
+// - dot://dns.google for DNS-over-TLS (the default port is 853);
+//
+// - udp://8.8.8.8 for DNS-over-UDP (the default port is 53);
+//
+// - tcp://8.8.8.8 for DNS-over-TCP (the default port is 53).
+func newUpstreamResolver(URL string) (*upstreamResolver, error) {
+	parsed, err := url.Parse(URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedUpstreamURL, err.Error())
+	}
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("%w: %s: missing hostname", errUnsupportedUpstreamURL, URL)
+	}
+	switch parsed.Scheme {
+	case "https":
+		return &upstreamResolver{
+			NewResolver: func(logger model.Logger) model.Resolver {
+				return netxlite.NewParallelDNSOverHTTPSResolver(logger, URL)
+			},
+			URL: URL,
+		}, nil
+
+	case "dot":
+		address := upstreamEndpoint(parsed, "853")
+		return &upstreamResolver{
+			NewResolver: func(logger model.Logger) model.Resolver {
+				dialer := netxlite.NewTLSDialer(
+					netxlite.NewDialerWithStdlibResolver(logger),
+					netxlite.NewTLSHandshakerStdlib(logger),
+				)
+				txp := netxlite.NewUnwrappedDNSOverTLSTransport(dialer.DialTLSContext, address)
+				return upstreamWrapTransport(logger, txp)
+			},
+			URL: URL,
+		}, nil
+
+	case "udp":
+		address := upstreamEndpoint(parsed, "53")
+		return &upstreamResolver{
+			NewResolver: func(logger model.Logger) model.Resolver {
+				dialer := netxlite.NewDialerWithStdlibResolver(logger)
+				return netxlite.NewParallelUDPResolver(logger, dialer, address)
+			},
+			URL: URL,
+		}, nil
+
+	case "tcp":
+		address := upstreamEndpoint(parsed, "53")
+		return &upstreamResolver{
+			NewResolver: func(logger model.Logger) model.Resolver {
+				dialer := netxlite.NewDialerWithStdlibResolver(logger)
+				txp := netxlite.NewUnwrappedDNSOverTCPTransport(dialer.DialContext, address)
+				return upstreamWrapTransport(logger, txp)
+			},
+			URL: URL,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedUpstreamURL, URL)
+	}
+}
+
+// upstreamEndpoint returns the endpoint to use for the given URL, using
+// the given default port when the URL does not contain a port.
+func upstreamEndpoint(URL *url.URL, defaultPort string) string {
+	port := URL.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(URL.Hostname(), port)
+}
+
+// upstreamWrapTransport creates a parallel resolver using the given transport.
+func upstreamWrapTransport(logger model.Logger, txp model.DNSTransport) model.Resolver {
+	return netxlite.WrapResolver(
+		logger, netxlite.NewUnwrappedParallelResolver(netxlite.WrapDNSTransport(txp)))
+}
+
+// loadUpstreamResolvers creates the [upstreamResolver] list using the given URLs
+// and the URLs contained in the given file, if the filename is not empty. The
+// file contains a URL per line and may contain empty lines and lines starting
+// with `#`, which we ignore.
+func loadUpstreamResolvers(URLs []string, filename string) ([]*upstreamResolver, error) {
+	if filename != "" {
+		filep, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer filep.Close()
+		scanner := bufio.NewScanner(filep)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			URLs = append(URLs, line)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	var out []*upstreamResolver
+	for _, URL := range URLs {
+		upstream, err := newUpstreamResolver(URL)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, upstream)
+	}
+	return out, nil
+}
+
+// dnsLookupUpstreams resolves the given domain using all the given upstreams in
+// parallel. This function returns the union of the addresses returned by all the
+// upstreams and the results of each upstream. The returned error is nil if at least
+// one upstream succeeded and otherwise is the error of the first upstream.
+func dnsLookupUpstreams(ctx context.Context, logger model.Logger,
+	upstreams []*upstreamResolver, domain string) ([]string, []model.THDNSUpstreamResult, error) {
+	results := make([]model.THDNSUpstreamResult, len(upstreams))
+	errs := make([]error, len(upstreams))
+	wg := &sync.WaitGroup{}
+	for idx, upstream := range upstreams {
+		wg.Add(1)
+		go func(idx int, upstream *upstreamResolver) {
+			defer wg.Done()
+			reso := upstream.NewResolver(logger)
+			defer reso.CloseIdleConnections()
+			t0 := time.Now()
+			addrs, err := reso.LookupHost(ctx, domain)
+			elapsed := time.Since(t0)
+			outcome := "ok"
+			if err != nil {
+				outcome = "failure"
+			}
+			metricDNSUpstreamLookupCount.WithLabelValues(upstream.URL, outcome).Inc()
+			metricDNSUpstreamLookupDurationSeconds.WithLabelValues(upstream.URL).Observe(elapsed.Seconds())
+			if addrs == nil {
+				addrs = []string{}
+			}
+			results[idx] = model.THDNSUpstreamResult{
+				URL:     upstream.URL,
+				Failure: newfailure(err),
+				Addrs:   addrs,
+			}
+			errs[idx] = err
+		}(idx, upstream)
+	}
+	wg.Wait()
+
+	// compute the union preserving the order in which we see addresses
+	var (
+		addrs   []string
+		success bool
+		seen    = make(map[string]bool)
+	)
+	for idx, result := range results {
+		if errs[idx] != nil {
+			continue
+		}
+		success = true
+		for _, addr := range result.Addrs {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	if !success && len(errs) > 0 {
+		return nil, results, errs[0]
+	}
+	return addrs, results, nil
+}
+
+// upstreamsResolver is a [model.Resolver] resolving domains using all the
+// upstreams in parallel, which we use for the HTTP and HTTP3 clients such
+// that they can use all the addresses we return when measuring DNS.
+type upstreamsResolver struct {
+	// logger is the logger to use.
+	logger model.Logger
+
+	// upstreams contains the upstreams to use.
+	upstreams []*upstreamResolver
+}
+
+// newUpstreamsResolver creates a [model.Resolver] using all the given upstreams.
+func newUpstreamsResolver(logger model.Logger, upstreams []*upstreamResolver) model.Resolver {
+	return netxlite.WrapResolver(logger, &upstreamsResolver{logger: logger, upstreams: upstreams})
+}
+
+var _ model.Resolver = &upstreamsResolver{}
+
+// LookupHost implements model.Resolver.
+func (r *upstreamsResolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
+	addrs, _, err := dnsLookupUpstreams(ctx, r.logger, r.upstreams, hostname)
+	return addrs, err
+}
+
+// Network implements model.Resolver.
+func (r *upstreamsResolver) Network() string {
+	return "upstreams"
+}
+
+// Address implements model.Resolver.
+func (r *upstreamsResolver) Address() string {
+	var URLs []string
+	for _, upstream := range r.upstreams {
+		URLs = append(URLs, upstream.URL)
+	}
+	return strings.Join(URLs, ",")
+}
+
+// CloseIdleConnections implements model.Resolver.
+func (r *upstreamsResolver) CloseIdleConnections() {
+	// nothing to do because dnsLookupUpstreams does not reuse resolvers
+}
+
+// LookupHTTPS implements model.Resolver.
+func (r *upstreamsResolver) LookupHTTPS(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// LookupNS implements model.Resolver.
+func (r *upstreamsResolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// LookupTXT implements model.Resolver.
+func (r *upstreamsResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// LookupMX implements model.Resolver.
+func (r *upstreamsResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// LookupCNAME implements model.Resolver.
+func (r *upstreamsResolver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return "", netxlite.ErrNoDNSTransport
+}
+
+// LookupSOA implements model.Resolver.
+func (r *upstreamsResolver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// LookupPTR implements model.Resolver.
+func (r *upstreamsResolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
+
+// LookupSRV implements model.Resolver.
+func (r *upstreamsResolver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return nil, netxlite.ErrNoDNSTransport
+}
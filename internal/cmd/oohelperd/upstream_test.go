@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestNewUpstreamResolver(t *testing.T) {
+	type testcase struct {
+		name          string
+		URL           string
+		expectErr     error
+		expectNetwork string
+		expectAddress string
+	}
+
+	testcases := []testcase{{
+		name:          "with DNS-over-HTTPS",
+		URL:           "https://dns.google/dns-query",
+		expectNetwork: "doh",
+		expectAddress: "https://dns.google/dns-query",
+	}, {
+		name:          "with DNS-over-TLS and the default port",
+		URL:           "dot://dns.google",
+		expectNetwork: "dot",
+		expectAddress: "dns.google:853",
+	}, {
+		name:          "with DNS-over-UDP and an explicit port",
+		URL:           "udp://[2001:4860:4860::8888]:5353",
+		expectNetwork: "udp",
+		expectAddress: "[2001:4860:4860::8888]:5353",
+	}, {
+		name:          "with DNS-over-TCP and the default port",
+		URL:           "tcp://8.8.8.8",
+		expectNetwork: "tcp",
+		expectAddress: "8.8.8.8:53",
+	}, {
+		name:      "with an unsupported scheme",
+		URL:       "quic://dns.adguard.com",
+		expectErr: errUnsupportedUpstreamURL,
+	}, {
+		name:      "with a missing hostname",
+		URL:       "udp://",
+		expectErr: errUnsupportedUpstreamURL,
+	}, {
+		name:      "with an unparseable URL",
+		URL:       "\t",
+		expectErr: errUnsupportedUpstreamURL,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			upstream, err := newUpstreamResolver(tc.URL)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if err != nil {
+				return
+			}
+			if upstream.URL != tc.URL {
+				t.Fatal("unexpected URL", upstream.URL)
+			}
+			reso := upstream.NewResolver(model.DiscardLogger)
+			defer reso.CloseIdleConnections()
+			if reso.Network() != tc.expectNetwork {
+				t.Fatal("unexpected network", reso.Network())
+			}
+			if reso.Address() != tc.expectAddress {
+				t.Fatal("unexpected address", reso.Address())
+			}
+		})
+	}
+}
+
+func TestLoadUpstreamResolvers(t *testing.T) {
+	t.Run("with URLs and a file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "resolvers.txt")
+		content := []byte("# comment\n\n  dot://1.1.1.1  \nudp://9.9.9.9\n")
+		if err := os.WriteFile(filename, content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		upstreams, err := loadUpstreamResolvers([]string{"https://dns.google/dns-query"}, filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, upstream := range upstreams {
+			got = append(got, upstream.URL)
+		}
+		expect := []string{"https://dns.google/dns-query", "dot://1.1.1.1", "udp://9.9.9.9"}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("without any URL", func(t *testing.T) {
+		upstreams, err := loadUpstreamResolvers(nil, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(upstreams) != 0 {
+			t.Fatal("expected no upstreams")
+		}
+	})
+
+	t.Run("with a nonexistent file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "nonexistent.txt")
+		if _, err := loadUpstreamResolvers(nil, filename); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with an invalid URL", func(t *testing.T) {
+		_, err := loadUpstreamResolvers([]string{"ftp://dns.google"}, "")
+		if !errors.Is(err, errUnsupportedUpstreamURL) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+// newMockedUpstreamResolver creates an [upstreamResolver] using a mocked resolver.
+func newMockedUpstreamResolver(URL string, addrs []string, err error) *upstreamResolver {
+	return &upstreamResolver{
+		NewResolver: func(model.Logger) model.Resolver {
+			return &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return addrs, err
+				},
+				MockCloseIdleConnections: func() {
+					// nothing
+				},
+			}
+		},
+		URL: URL,
+	}
+}
+
+func TestDNSDoWithUpstreams(t *testing.T) {
+	type testcase struct {
+		name            string
+		upstreams       []*upstreamResolver
+		expectFailure   *string
+		expectAddrs     []string
+		expectUpstreams []model.THDNSUpstreamResult
+	}
+
+	nxdomain := errors.New(netxlite.DNSNoSuchHostSuffix)
+
+	testcases := []testcase{{
+		name: "we return the union of the addresses",
+		upstreams: []*upstreamResolver{
+			newMockedUpstreamResolver("udp://8.8.8.8", []string{"8.8.8.8", "8.8.4.4"}, nil),
+			newMockedUpstreamResolver("dot://1.1.1.1", []string{"8.8.4.4", "1.1.1.1"}, nil),
+		},
+		expectFailure: nil,
+		expectAddrs:   []string{"8.8.8.8", "8.8.4.4", "1.1.1.1"},
+		expectUpstreams: []model.THDNSUpstreamResult{{
+			URL:     "udp://8.8.8.8",
+			Failure: nil,
+			Addrs:   []string{"8.8.8.8", "8.8.4.4"},
+		}, {
+			URL:     "dot://1.1.1.1",
+			Failure: nil,
+			Addrs:   []string{"8.8.4.4", "1.1.1.1"},
+		}},
+	}, {
+		name: "we succeed if at least an upstream succeeds",
+		upstreams: []*upstreamResolver{
+			newMockedUpstreamResolver("udp://8.8.8.8", nil, nxdomain),
+			newMockedUpstreamResolver("dot://1.1.1.1", []string{"1.1.1.1"}, nil),
+		},
+		expectFailure: nil,
+		expectAddrs:   []string{"1.1.1.1"},
+		expectUpstreams: []model.THDNSUpstreamResult{{
+			URL:     "udp://8.8.8.8",
+			Failure: stringPointerForString(netxlite.FailureDNSNXDOMAINError),
+			Addrs:   []string{},
+		}, {
+			URL:     "dot://1.1.1.1",
+			Failure: nil,
+			Addrs:   []string{"1.1.1.1"},
+		}},
+	}, {
+		name: "we return the first failure if all upstreams fail",
+		upstreams: []*upstreamResolver{
+			newMockedUpstreamResolver("udp://8.8.8.8", nil, nxdomain),
+			newMockedUpstreamResolver("dot://1.1.1.1", nil, errors.New(netxlite.DNSServerMisbehavingSuffix)),
+		},
+		expectFailure: stringPointerForString(model.THDNSNameError),
+		expectAddrs:   []string{},
+		expectUpstreams: []model.THDNSUpstreamResult{{
+			URL:     "udp://8.8.8.8",
+			Failure: stringPointerForString(netxlite.FailureDNSNXDOMAINError),
+			Addrs:   []string{},
+		}, {
+			URL:     "dot://1.1.1.1",
+			Failure: stringPointerForString(netxlite.FailureDNSServerMisbehaving),
+			Addrs:   []string{},
+		}},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &dnsConfig{
+				Domain: "www.example.com",
+				Logger: model.DiscardLogger,
+				NewResolver: func(model.Logger) model.Resolver {
+					panic("should not be called")
+				},
+				Out:       make(chan model.THDNSResult, 1),
+				Upstreams: tc.upstreams,
+				Wg:        &sync.WaitGroup{},
+			}
+			config.Wg.Add(1)
+			dnsDo(context.Background(), config)
+			config.Wg.Wait()
+			resp := <-config.Out
+			if diff := cmp.Diff(tc.expectFailure, resp.Failure); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.expectAddrs, resp.Addrs); diff != "" {
+				t.Fatal(diff)
+			}
+			if diff := cmp.Diff(tc.expectUpstreams, resp.Upstreams); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestUpstreamsResolver(t *testing.T) {
+	upstreams := []*upstreamResolver{
+		newMockedUpstreamResolver("udp://8.8.8.8", nil, errors.New(netxlite.DNSNoSuchHostSuffix)),
+		newMockedUpstreamResolver("dot://1.1.1.1", []string{"1.1.1.1"}, nil),
+	}
+	reso := newUpstreamsResolver(model.DiscardLogger, upstreams)
+
+	t.Run("LookupHost uses all the upstreams", func(t *testing.T) {
+		addrs, err := reso.LookupHost(context.Background(), "www.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"1.1.1.1"}, addrs); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("Network and Address", func(t *testing.T) {
+		if reso.Network() != "upstreams" {
+			t.Fatal("unexpected network", reso.Network())
+		}
+		if reso.Address() != "udp://8.8.8.8,dot://1.1.1.1" {
+			t.Fatal("unexpected address", reso.Address())
+		}
+	})
+
+	t.Run("other lookups are not supported", func(t *testing.T) {
+		ctx := context.Background()
+		if _, err := reso.LookupHTTPS(ctx, "www.example.com"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := reso.LookupNS(ctx, "www.example.com"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := reso.LookupTXT(ctx, "www.example.com"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := reso.LookupMX(ctx, "www.example.com"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := reso.LookupCNAME(ctx, "www.example.com"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := reso.LookupSOA(ctx, "www.example.com"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := reso.LookupPTR(ctx, "1.1.1.1"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if _, err := reso.LookupSRV(ctx, "www.example.com"); err == nil {
+			t.Fatal("expected an error")
+		}
+		reso.CloseIdleConnections() // should not crash
+	})
+}
@@ -4,6 +4,7 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
 	"net"
 	"sync"
 	"time"
@@ -18,14 +19,18 @@ var (
 	srvCancel   context.CancelFunc
 	srvWg       = new(sync.WaitGroup)
 	srvTestChan = make(chan string, len(TestPorts)) // buffered channel for testing
+	srvTestUDP  = make(chan string, len(TestPorts)) // buffered channel for testing
 	srvTest     bool
+
+	// srvUDPRateLimiter limits the datagrams we echo back to each source address.
+	srvUDPRateLimiter = newUDPRateLimiter(udpMaxDatagramsPerSecond)
 )
 
 func init() {
 	srvCtx, srvCancel = context.WithCancel(context.Background())
 }
 
-func shutdown(ctx context.Context, l net.Listener) {
+func shutdown(ctx context.Context, l io.Closer) {
 	<-ctx.Done()
 	l.Close()
 }
@@ -38,9 +43,9 @@ func handleConnection(ctx context.Context, conn net.Conn) {
 	<-ctx.Done()
 }
 
-func listenTCP(ctx context.Context, port string) {
+func listenTCP(ctx context.Context, address, port string) {
 	defer srvWg.Done()
-	address := net.JoinHostPort("127.0.0.1", port)
+	address = net.JoinHostPort(address, port)
 	listener, err := net.Listen("tcp", address)
 	runtimex.PanicOnError(err, "net.Listen failed")
 	go shutdown(ctx, listener)
@@ -55,11 +60,123 @@ func listenTCP(ctx context.Context, port string) {
 	}
 }
 
+// listenUDP echoes back each datagram received on the given port, which
+// allows the client to tell whether the port is reachable using UDP.
+//
+// To avoid being abused as a reflector, we only echo back datagrams that
+// look like the nonce sent by the client (see portfiltering.IsUDPEchoNonce),
+// hence we never amplify, we drop datagrams coming from the ports used by
+// services that reply to any datagram (see udpDisallowedSourcePort), and we
+// limit the number of datagrams we echo back to each source address.
+func listenUDP(ctx context.Context, address, port string) {
+	defer srvWg.Done()
+	address = net.JoinHostPort(address, port)
+	pconn, err := net.ListenPacket("udp", address)
+	runtimex.PanicOnError(err, "net.ListenPacket failed")
+	go shutdown(ctx, pconn)
+	srvTestUDP <- port // send to channel to imply server will start reading on port
+	buffer := make([]byte, 1<<12)
+	for {
+		count, addr, err := pconn.ReadFrom(buffer)
+		if err != nil {
+			log.Infof("listener unable to read datagrams on port: %s", port)
+			return
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok || udpDisallowedSourcePort(udpAddr.Port) {
+			log.Debugf("dropping datagram from disallowed source: %s", addr)
+			continue
+		}
+		if !portfiltering.IsUDPEchoNonce(buffer[:count]) {
+			log.Debugf("dropping datagram not containing a nonce from: %s", addr)
+			continue
+		}
+		if !srvUDPRateLimiter.allow(udpAddr.IP.String(), time.Now()) {
+			log.Debugf("dropping datagram exceeding the rate limit from: %s", addr)
+			continue
+		}
+		if _, err := pconn.WriteTo(buffer[:count], addr); err != nil {
+			log.Debugf("cannot echo datagram to %s: %s", addr, err.Error())
+		}
+	}
+}
+
+// udpDisallowedSourcePort returns whether we should drop datagrams sent from
+// the given port, which is the case for privileged ports and for well-known
+// ports of services replying to datagrams (e.g., echo, chargen, DNS, NTP,
+// SSDP, mDNS, memcached), which an attacker may want us to bounce off.
+func udpDisallowedSourcePort(port int) bool {
+	switch {
+	case port < 1024:
+		return true
+	case port == 1900, port == 3702, port == 5353, port == 11211:
+		return true
+	default:
+		return false
+	}
+}
+
+// udpMaxDatagramsPerSecond is the maximum number of datagrams per second
+// we echo back to each source address. The client sends a single datagram
+// to each port and waits between datagrams, so this is more than enough.
+const udpMaxDatagramsPerSecond = 32
+
+// udpRateLimiter limits the number of datagrams per second we echo back to
+// each source address. The zero value is invalid; use newUDPRateLimiter.
+type udpRateLimiter struct {
+	// limit is the maximum number of datagrams per second.
+	limit int
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// sources maps each source address to its current window.
+	sources map[string]*udpRateLimiterWindow
+
+	// start is the beginning of the current window.
+	start time.Time
+}
+
+// udpRateLimiterWindow is the number of datagrams seen in the current window.
+type udpRateLimiterWindow struct {
+	count int
+}
+
+// newUDPRateLimiter creates a new udpRateLimiter.
+func newUDPRateLimiter(limit int) *udpRateLimiter {
+	return &udpRateLimiter{
+		limit:   limit,
+		sources: map[string]*udpRateLimiterWindow{},
+	}
+}
+
+// allow returns whether we can echo back a datagram to the given source. We
+// use one-second windows and forget all the sources when a window expires.
+func (rl *udpRateLimiter) allow(source string, now time.Time) bool {
+	defer rl.mu.Unlock()
+	rl.mu.Lock()
+	if now.Sub(rl.start) >= time.Second {
+		rl.start = now
+		rl.sources = map[string]*udpRateLimiterWindow{}
+	}
+	window, found := rl.sources[source]
+	if !found {
+		window = &udpRateLimiterWindow{}
+		rl.sources[source] = window
+	}
+	if window.count >= rl.limit {
+		return false
+	}
+	window.count++
+	return true
+}
+
 func main() {
 	logmap := map[bool]log.Level{
 		true:  log.DebugLevel,
 		false: log.InfoLevel,
 	}
+	address := flag.String("address", "127.0.0.1", "Address where to listen")
 	debug := flag.Bool("debug", false, "Toggle debug mode")
 	flag.Parse()
 	log.SetLevel(logmap[*debug])
@@ -69,10 +186,11 @@ func main() {
 		ports = TestPorts
 	}
 	for _, port := range ports {
-		srvWg.Add(1)
+		srvWg.Add(2)
 		ctx, cancel := context.WithCancel(srvCtx)
 		defer cancel()
-		go listenTCP(ctx, port)
+		go listenTCP(ctx, *address, port)
+		go listenUDP(ctx, *address, port)
 	}
 	<-srvCtx.Done()
 	srvWg.Wait() // wait for listeners on all ports to close
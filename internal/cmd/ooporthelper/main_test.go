@@ -4,13 +4,17 @@ import (
 	"context"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/experiment/portfiltering"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/randx"
 )
 
 var (
-	portsMap = make(map[string]bool)
+	portsMap    = make(map[string]bool)
+	udpPortsMap = make(map[string]bool)
 )
 
 func TestMainWorkingAsIntended(t *testing.T) {
@@ -34,6 +38,30 @@ func TestMainWorkingAsIntended(t *testing.T) {
 		conn.Close()
 		portsMap[port] = true
 	}
+	for i := 0; i < len(TestPorts); i++ {
+		port := <-srvTestUDP
+		addr := net.JoinHostPort("127.0.0.1", port)
+		ctx := context.Background()
+		conn, err := dialer.DialContext(ctx, "udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		nonce := randx.Letters(portfiltering.UDPEchoNonceSize)
+		if _, err := conn.Write([]byte(nonce)); err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 64)
+		count, err := conn.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buffer[:count]) != nonce {
+			t.Fatal("unexpected echo", string(buffer[:count]))
+		}
+		conn.Close()
+		udpPortsMap[port] = true
+	}
 	srvCancel()  // shutdown server
 	srvWg.Wait() // wait for listeners on all ports to close
 	// check if all ports were covered
@@ -41,5 +69,38 @@ func TestMainWorkingAsIntended(t *testing.T) {
 		if !portsMap[port] {
 			t.Fatal("missed port in test", port)
 		}
+		if !udpPortsMap[port] {
+			t.Fatal("missed UDP port in test", port)
+		}
+	}
+}
+
+func TestUDPDisallowedSourcePort(t *testing.T) {
+	for _, port := range []int{0, 7, 19, 53, 123, 1023, 1900, 5353, 11211} {
+		if !udpDisallowedSourcePort(port) {
+			t.Fatal("expected port to be disallowed", port)
+		}
+	}
+	for _, port := range []int{1024, 8080, 54321} {
+		if udpDisallowedSourcePort(port) {
+			t.Fatal("expected port to be allowed", port)
+		}
+	}
+}
+
+func TestUDPRateLimiter(t *testing.T) {
+	rl := newUDPRateLimiter(2)
+	now := time.Now()
+	if !rl.allow("10.0.0.1", now) || !rl.allow("10.0.0.1", now) {
+		t.Fatal("expected to allow the first datagrams")
+	}
+	if rl.allow("10.0.0.1", now.Add(500*time.Millisecond)) {
+		t.Fatal("expected to drop datagrams exceeding the limit")
+	}
+	if !rl.allow("10.0.0.2", now.Add(500*time.Millisecond)) {
+		t.Fatal("expected to allow datagrams from another source")
+	}
+	if !rl.allow("10.0.0.1", now.Add(time.Second)) {
+		t.Fatal("expected to allow datagrams in the next window")
 	}
 }
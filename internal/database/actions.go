@@ -186,6 +186,30 @@ func (d *Database) ListResults() ([]model.DatabaseResultNetwork, []model.Databas
 	return doneResults, incompleteResults, nil
 }
 
+// DataUsageSince implements ReadableDatabase.DataUsageSince
+func (d *Database) DataUsageSince(since time.Time) (float64, float64, error) {
+	var results []model.DatabaseResult
+	req := d.sess.SQL().Select(
+		db.Raw("results.result_start_time"),
+		db.Raw("results.result_data_usage_up"),
+		db.Raw("results.result_data_usage_down"),
+	).From("results")
+	if err := req.All(&results); err != nil {
+		return 0, 0, errors.Wrap(err, "failed to get the data usage")
+	}
+	var up, down float64
+	for _, result := range results {
+		// We filter here rather than in the query because the way in which
+		// the start time is serialized depends on the database driver.
+		if result.StartTime.Before(since) {
+			continue
+		}
+		up += result.DataUsageUp
+		down += result.DataUsageDown
+	}
+	return up, down, nil
+}
+
 // DeleteResult implements WritableDatabase.DeleteResult
 func (d *Database) DeleteResult(resultID int64) error {
 	var result model.DatabaseResult
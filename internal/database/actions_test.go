@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/upper/db/v4"
@@ -423,3 +424,65 @@ func TestGetMeasurementJSON(t *testing.T) {
 		t.Error("inconsistent measurement downloaded")
 	}
 }
+
+func TestDataUsageSince(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	database, err := Open(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location := locationInfo{
+		asn:         0,
+		countryCode: "IT",
+		networkName: "Unknown",
+	}
+	network, err := database.CreateNetwork(&location)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	for idx := 0; idx < 2; idx++ {
+		result, err := database.CreateResult(tmpdir, "websites", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result.DataUsageUp = 10
+		result.DataUsageDown = 100
+		if err := database.Finished(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("we account for results started after the given time", func(t *testing.T) {
+		up, down, err := database.DataUsageSince(before.Add(-time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if up != 20 || down != 200 {
+			t.Fatal("unexpected data usage", up, down)
+		}
+	})
+
+	t.Run("we ignore results started before the given time", func(t *testing.T) {
+		up, down, err := database.DataUsageSince(time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if up != 0 || down != 0 {
+			t.Fatal("unexpected data usage", up, down)
+		}
+	})
+}
@@ -132,7 +132,7 @@ func (f *dnsLookupGetaddrinfoFunc) Apply(
 	ctx context.Context, input *DomainToResolve) *Maybe[*ResolvedAddresses] {
 
 	// create trace
-	trace := measurexlite.NewTrace(input.IDGenerator.Add(1), input.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, input.IDGenerator.Add(1), input.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -195,7 +195,7 @@ func (f *dnsLookupUDPFunc) Apply(
 	ctx context.Context, input *DomainToResolve) *Maybe[*ResolvedAddresses] {
 
 	// create trace
-	trace := measurexlite.NewTrace(input.IDGenerator.Add(1), input.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, input.IDGenerator.Add(1), input.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -242,3 +242,132 @@ func (f *dnsLookupUDPFunc) Apply(
 		State:        state,
 	}
 }
+
+// DNSLookupTCP returns a function that resolves a domain name to
+// IP addresses using the given DNS-over-TCP resolver endpoint (e.g.,
+// `1.1.1.1:53`). The returned observations include the TCP connects.
+func DNSLookupTCP(endpoint string) Func[*DomainToResolve, *Maybe[*ResolvedAddresses]] {
+	return &dnsLookupTransportFunc{
+		Address: endpoint,
+		Network: "tcp",
+		NewResolver: func(trace *measurexlite.Trace, logger model.Logger, address string) model.Resolver {
+			dialer := netxlite.NewDialerWithStdlibResolver(logger)
+			return trace.NewParallelTCPResolver(logger, dialer, address)
+		},
+	}
+}
+
+// DNSLookupTLS returns a function that resolves a domain name to
+// IP addresses using the given DNS-over-TLS resolver endpoint (e.g.,
+// `dns.google:853`). We use the endpoint's hostname as the SNI. The returned
+// observations include the TCP connects and the TLS handshakes.
+func DNSLookupTLS(endpoint string) Func[*DomainToResolve, *Maybe[*ResolvedAddresses]] {
+	return &dnsLookupTransportFunc{
+		Address: endpoint,
+		Network: "dot",
+		NewResolver: func(trace *measurexlite.Trace, logger model.Logger, address string) model.Resolver {
+			dialer := netxlite.NewTLSDialer(
+				netxlite.NewDialerWithStdlibResolver(logger),
+				netxlite.NewTLSHandshakerStdlib(logger),
+			)
+			return trace.NewParallelDNSOverTLSResolver(logger, dialer, address)
+		},
+	}
+}
+
+// DNSLookupHTTPS returns a function that resolves a domain name to
+// IP addresses using the given DNS-over-HTTPS resolver URL (e.g.,
+// `https://dns.google/dns-query`). The returned observations include
+// the TCP connects and the TLS handshakes.
+func DNSLookupHTTPS(URL string) Func[*DomainToResolve, *Maybe[*ResolvedAddresses]] {
+	return &dnsLookupTransportFunc{
+		Address: URL,
+		Network: "doh",
+		NewResolver: func(trace *measurexlite.Trace, logger model.Logger, URL string) model.Resolver {
+			return trace.NewParallelDNSOverHTTPSResolver(logger, URL)
+		},
+	}
+}
+
+// DNSLookupHTTP3 returns a function that resolves a domain name to
+// IP addresses using the given DNS-over-HTTPS resolver URL (e.g.,
+// `https://dns.google/dns-query`) and HTTP3. The returned observations
+// include the QUIC handshakes.
+func DNSLookupHTTP3(URL string) Func[*DomainToResolve, *Maybe[*ResolvedAddresses]] {
+	return &dnsLookupTransportFunc{
+		Address: URL,
+		Network: "doh3",
+		NewResolver: func(trace *measurexlite.Trace, logger model.Logger, URL string) model.Resolver {
+			return trace.NewParallelDNSOverHTTP3Resolver(logger, URL)
+		},
+	}
+}
+
+// dnsLookupTransportFunc is the function returned by DNSLookupTCP,
+// DNSLookupTLS, DNSLookupHTTPS, and DNSLookupHTTP3.
+type dnsLookupTransportFunc struct {
+	// Address is the MANDATORY endpoint or URL of the resolver to use.
+	Address string
+
+	// Network is the MANDATORY network we use for logging.
+	Network string
+
+	// NewResolver is the MANDATORY factory to create a trace-aware resolver.
+	NewResolver func(trace *measurexlite.Trace, logger model.Logger, address string) model.Resolver
+
+	mockResolver model.Resolver // for testing
+}
+
+// Apply implements Func.
+func (f *dnsLookupTransportFunc) Apply(
+	ctx context.Context, input *DomainToResolve) *Maybe[*ResolvedAddresses] {
+
+	// create trace
+	trace := measurexlite.NewTraceWithContext(ctx, input.IDGenerator.Add(1), input.ZeroTime)
+
+	// start the operation logger
+	ol := measurexlite.NewOperationLogger(
+		input.Logger,
+		"[#%d] DNSLookup[%s/%s] %s",
+		trace.Index,
+		f.Address,
+		f.Network,
+		input.Domain,
+	)
+
+	// setup
+	//
+	// Note: we use a larger timeout than for DNSLookupUDP because the
+	// timeout also needs to account for the TCP, TLS or QUIC handshakes.
+	const timeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resolver := f.mockResolver
+	if resolver == nil {
+		resolver = f.NewResolver(trace, input.Logger, f.Address)
+	}
+	defer resolver.CloseIdleConnections()
+
+	// lookup
+	addrs, err := resolver.LookupHost(ctx, input.Domain)
+
+	// stop the operation logger
+	ol.Stop(err)
+
+	state := &ResolvedAddresses{
+		Addresses:   addrs, // maybe empty
+		Domain:      input.Domain,
+		IDGenerator: input.IDGenerator,
+		Logger:      input.Logger,
+		Trace:       trace,
+		ZeroTime:    input.ZeroTime,
+	}
+
+	return &Maybe[*ResolvedAddresses]{
+		Error:        err,
+		Observations: maybeTraceToObservations(trace),
+		Operation:    netxlite.ResolveOperation,
+		State:        state,
+	}
+}
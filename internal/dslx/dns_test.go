@@ -3,12 +3,18 @@ package dslx
 import (
 	"context"
 	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 /*
@@ -217,3 +223,308 @@ func TestLookupUDP(t *testing.T) {
 		})
 	})
 }
+
+/*
+Test cases:
+- Get dnsLookupTransportFunc
+- Apply dnsLookupTransportFunc
+  - with cancelled context
+  - with a TCP server closing the connection
+  - with a TLS server using an unknown authority
+  - with lookup error
+  - with success
+*/
+func TestLookupTransport(t *testing.T) {
+	type constructor struct {
+		name    string
+		fx      Func[*DomainToResolve, *Maybe[*ResolvedAddresses]]
+		network string
+		engine  string
+		address string
+	}
+
+	constructors := []constructor{{
+		name:    "DNSLookupTCP",
+		fx:      DNSLookupTCP("1.1.1.1:53"),
+		network: "tcp",
+		engine:  "tcp",
+		address: "1.1.1.1:53",
+	}, {
+		name:    "DNSLookupTLS",
+		fx:      DNSLookupTLS("1.1.1.1:853"),
+		network: "dot",
+		engine:  "dot",
+		address: "1.1.1.1:853",
+	}, {
+		name:    "DNSLookupHTTPS",
+		fx:      DNSLookupHTTPS("https://1.1.1.1/dns-query"),
+		network: "doh",
+		engine:  "doh",
+		address: "https://1.1.1.1/dns-query",
+	}, {
+		name:    "DNSLookupHTTP3",
+		fx:      DNSLookupHTTP3("https://1.1.1.1/dns-query"),
+		network: "doh3",
+		engine:  "doh",
+		address: "https://1.1.1.1/dns-query",
+	}}
+
+	// checkQueries ensures we have observed the A and AAAA queries
+	// using the expected engine, resolver address, and failure.
+	checkQueries := func(t *testing.T, obs *Observations, engine, address, failure string) {
+		if len(obs.Queries) != 2 {
+			t.Fatal("expected two queries, got", len(obs.Queries))
+		}
+		for _, query := range obs.Queries {
+			if query.Engine != engine {
+				t.Fatal("unexpected engine", query.Engine)
+			}
+			if query.ResolverAddress != address {
+				t.Fatal("unexpected resolver address", query.ResolverAddress)
+			}
+			if query.Hostname != "example.com" {
+				t.Fatal("unexpected hostname", query.Hostname)
+			}
+			if query.Failure == nil || (failure != "" && *query.Failure != failure) {
+				t.Fatal("unexpected failure", query.Failure)
+			}
+		}
+	}
+
+	// checkTCPConnect ensures we have observed the TCP connects
+	// to the given endpoint using the expected failure.
+	checkTCPConnect := func(t *testing.T, obs *Observations, endpoint string, failure *string) {
+		addr, port, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(obs.TCPConnect) <= 0 {
+			t.Fatal("expected TCP connect observations")
+		}
+		for _, entry := range obs.TCPConnect {
+			if entry.IP != addr || strconv.Itoa(entry.Port) != port {
+				t.Fatal("unexpected endpoint", entry.IP, entry.Port)
+			}
+			if diff := cmp.Diff(failure, entry.Status.Failure); diff != "" {
+				t.Fatal(diff)
+			}
+			if entry.Status.Success != (failure == nil) {
+				t.Fatal("unexpected success", entry.Status.Success)
+			}
+		}
+	}
+
+	for _, c := range constructors {
+		t.Run("Get dnsLookupTransportFunc using "+c.name, func(t *testing.T) {
+			f, ok := c.fx.(*dnsLookupTransportFunc)
+			if !ok {
+				t.Fatal("unexpected type, want dnsLookupTransportFunc")
+			}
+			if f.Network != c.network {
+				t.Fatal("unexpected network", f.Network)
+			}
+		})
+
+		t.Run("Apply dnsLookupTransportFunc using "+c.name+" with cancelled context", func(t *testing.T) {
+			domain := NewDomainToResolve(DomainName("example.com"))
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			res := c.fx.Apply(ctx, domain)
+			if res.Error == nil || res.Error.Error() != netxlite.FailureInterrupted {
+				t.Fatal("unexpected error", res.Error)
+			}
+			if len(res.Observations) != 1 {
+				t.Fatal("expected a single observations entry")
+			}
+			obs := res.Observations[0]
+			checkQueries(t, obs, c.engine, c.address, netxlite.FailureInterrupted)
+			if len(obs.TLSHandshakes) != 0 {
+				t.Fatal("expected no TLS handshakes")
+			}
+			switch c.network {
+			case "tcp", "dot":
+				failure := netxlite.FailureInterrupted
+				checkTCPConnect(t, obs, c.address, &failure)
+			case "doh":
+				// the HTTP client fails before dialing
+				if len(obs.TCPConnect) != 0 || len(obs.QUICHandshakes) != 0 {
+					t.Fatal("expected no connect observations")
+				}
+			case "doh3":
+				if len(obs.QUICHandshakes) <= 0 {
+					t.Fatal("expected QUIC handshake observations")
+				}
+				for _, entry := range obs.QUICHandshakes {
+					if entry.Network != "udp" || entry.Address != "1.1.1.1:443" {
+						t.Fatal("unexpected endpoint", entry.Network, entry.Address)
+					}
+					if entry.ServerName != "1.1.1.1" {
+						t.Fatal("unexpected server name", entry.ServerName)
+					}
+					if entry.Failure == nil || *entry.Failure != netxlite.FailureInterrupted {
+						t.Fatal("unexpected failure", entry.Failure)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("Apply dnsLookupTransportFunc with a TCP server closing the connection", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+		address := listener.Addr().String()
+		domain := NewDomainToResolve(DomainName("example.com"))
+		res := DNSLookupTCP(address).Apply(context.Background(), domain)
+		if res.Error == nil {
+			t.Fatal("expected an error here")
+		}
+		if len(res.Observations) != 1 {
+			t.Fatal("expected a single observations entry")
+		}
+		obs := res.Observations[0]
+		checkQueries(t, obs, "tcp", address, "")
+		checkTCPConnect(t, obs, address, nil)
+		if len(obs.TLSHandshakes) != 0 || len(obs.QUICHandshakes) != 0 {
+			t.Fatal("expected no handshakes")
+		}
+	})
+
+	t.Run("Apply dnsLookupTransportFunc with a TLS server using an unknown authority", func(t *testing.T) {
+		srvr := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srvr.Close()
+		address := srvr.Listener.Addr().String()
+
+		tlsCases := []struct {
+			name            string
+			fx              Func[*DomainToResolve, *Maybe[*ResolvedAddresses]]
+			engine          string
+			resolverAddress string
+		}{{
+			name:            "DNSLookupTLS",
+			fx:              DNSLookupTLS(address),
+			engine:          "dot",
+			resolverAddress: address,
+		}, {
+			name:            "DNSLookupHTTPS",
+			fx:              DNSLookupHTTPS(srvr.URL),
+			engine:          "doh",
+			resolverAddress: srvr.URL,
+		}}
+
+		for _, tc := range tlsCases {
+			t.Run(tc.name, func(t *testing.T) {
+				domain := NewDomainToResolve(DomainName("example.com"))
+				res := tc.fx.Apply(context.Background(), domain)
+				if res.Error == nil || res.Error.Error() != netxlite.FailureSSLUnknownAuthority {
+					t.Fatal("unexpected error", res.Error)
+				}
+				if len(res.Observations) != 1 {
+					t.Fatal("expected a single observations entry")
+				}
+				obs := res.Observations[0]
+				checkQueries(t, obs, tc.engine, tc.resolverAddress, netxlite.FailureSSLUnknownAuthority)
+				checkTCPConnect(t, obs, address, nil)
+				if len(obs.TLSHandshakes) <= 0 {
+					t.Fatal("expected TLS handshake observations")
+				}
+				for _, entry := range obs.TLSHandshakes {
+					if entry.Network != "tcp" || entry.Address != address {
+						t.Fatal("unexpected endpoint", entry.Network, entry.Address)
+					}
+					if entry.ServerName != "127.0.0.1" {
+						t.Fatal("unexpected server name", entry.ServerName)
+					}
+					if entry.Failure == nil || *entry.Failure != netxlite.FailureSSLUnknownAuthority {
+						t.Fatal("unexpected failure", entry.Failure)
+					}
+					if len(entry.PeerCertificates) <= 0 {
+						t.Fatal("expected peer certificates")
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("Apply dnsLookupTransportFunc", func(t *testing.T) {
+		domain := &DomainToResolve{
+			Domain:      "example.com",
+			Logger:      model.DiscardLogger,
+			IDGenerator: &atomic.Int64{},
+			ZeroTime:    time.Time{},
+		}
+
+		// checkEmptyObservations ensures the mocked resolver, which
+		// bypasses the trace, did not cause us to observe anything.
+		checkEmptyObservations := func(t *testing.T, observations []*Observations) {
+			if len(observations) != 1 {
+				t.Fatal("expected a single observations entry")
+			}
+			obs := observations[0]
+			if len(obs.Queries) != 0 || len(obs.TCPConnect) != 0 || len(obs.TLSHandshakes) != 0 ||
+				len(obs.QUICHandshakes) != 0 || len(obs.NetworkEvents) != 0 || len(obs.Requests) != 0 {
+				t.Fatal("expected empty observations")
+			}
+		}
+
+		t.Run("with lookup error", func(t *testing.T) {
+			mockedErr := errors.New("mocked")
+			f := DNSLookupTLS("1.1.1.1:853").(*dnsLookupTransportFunc)
+			f.mockResolver = &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return nil, mockedErr
+				},
+				MockCloseIdleConnections: func() {},
+			}
+			res := f.Apply(context.Background(), domain)
+			checkEmptyObservations(t, res.Observations)
+			if res.Error != mockedErr {
+				t.Fatalf("unexpected error type: %s", res.Error)
+			}
+			if res.State == nil {
+				t.Fatal("unexpected nil state")
+			}
+			if res.State.Addresses != nil {
+				t.Fatal("expected empty addresses here")
+			}
+		})
+
+		t.Run("with success", func(t *testing.T) {
+			f := DNSLookupHTTPS("https://1.1.1.1/dns-query").(*dnsLookupTransportFunc)
+			f.mockResolver = &mocks.Resolver{
+				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+					return []string{"93.184.216.34"}, nil
+				},
+				MockCloseIdleConnections: func() {},
+			}
+			res := f.Apply(context.Background(), domain)
+			checkEmptyObservations(t, res.Observations)
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %s", res.Error)
+			}
+			if res.State == nil {
+				t.Fatal("unexpected nil state")
+			}
+			if len(res.State.Addresses) != 1 || res.State.Addresses[0] != "93.184.216.34" {
+				t.Fatal("unexpected addresses")
+			}
+			endpoints := NewAddressSet(res).ToEndpoints(EndpointNetwork("tcp"), EndpointPort(443))
+			if len(endpoints) != 1 || endpoints[0].Address != "93.184.216.34:443" {
+				t.Fatal("unexpected endpoints")
+			}
+		})
+	})
+}
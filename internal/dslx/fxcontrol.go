@@ -0,0 +1,213 @@
+package dslx
+
+//
+// Functional extensions (control flow)
+//
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// Retry returns a Func that applies fx until it succeeds or we have performed
+// the given number of attempts (we'll perform a single attempt if attempts is < 1).
+//
+// Before each retry, we wait for the given backoff, which doubles after each
+// retry until it reaches [RetryMaxBackoff]. We stop retrying early when the
+// context is done.
+//
+// The return value contains the observations collected by all the attempts and
+// the error, operation, and state of the last attempt.
+func Retry[A, B any](attempts int, backoff time.Duration, fx Func[A, *Maybe[B]]) Func[A, *Maybe[B]] {
+	return &retryFunc[A, B]{
+		attempts: attempts,
+		backoff:  backoff,
+		fx:       fx,
+	}
+}
+
+// retryFunc is the type returned by [Retry].
+type retryFunc[A, B any] struct {
+	attempts int
+	backoff  time.Duration
+	fx       Func[A, *Maybe[B]]
+}
+
+// Apply implements Func.
+func (f *retryFunc[A, B]) Apply(ctx context.Context, a A) *Maybe[B] {
+	var (
+		backoff      = f.backoff
+		observations []*Observations
+		mb           *Maybe[B]
+	)
+	for idx := 0; ; idx++ {
+		mb = f.fx.Apply(ctx, a)
+		runtimex.Assert(mb != nil, "f.fx.Apply returned a nil pointer")
+		observations = append(observations, mb.Observations...)
+		if mb.Error == nil || idx+1 >= f.attempts || !retrySleep(ctx, backoff) {
+			break
+		}
+		backoff = retryNextBackoff(backoff)
+	}
+	return &Maybe[B]{
+		Error:        mb.Error,
+		Observations: observations, // merge observations of all attempts
+		Operation:    mb.Operation,
+		State:        mb.State,
+	}
+}
+
+// RetryMaxBackoff is the maximum backoff reached by doubling the
+// backoff passed to [Retry]. A larger initial backoff is not changed.
+const RetryMaxBackoff = time.Minute
+
+// retryNextBackoff doubles the backoff without exceeding RetryMaxBackoff,
+// which also prevents the backoff from overflowing.
+func retryNextBackoff(backoff time.Duration) time.Duration {
+	switch {
+	case backoff >= RetryMaxBackoff:
+		return backoff
+	case backoff >= RetryMaxBackoff/2:
+		return RetryMaxBackoff
+	default:
+		return backoff * 2
+	}
+}
+
+// retrySleep waits for the given backoff and returns false if
+// the context is done before the backoff has expired.
+func retrySleep(ctx context.Context, backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// FirstSuccess returns a Func that applies each function in fs sequentially
+// until one of them succeeds. This is useful to express fallbacks (e.g., try
+// with QUIC first and then fallback to TCP). This function panics if fs is empty.
+//
+// The return value contains the observations collected by all the functions we
+// have applied and the error, operation, and state of the last one.
+func FirstSuccess[A, B any](fs ...Func[A, *Maybe[B]]) Func[A, *Maybe[B]] {
+	runtimex.Assert(len(fs) > 0, "FirstSuccess requires at least one Func")
+	return &firstSuccessFunc[A, B]{fs}
+}
+
+// firstSuccessFunc is the type returned by [FirstSuccess].
+type firstSuccessFunc[A, B any] struct {
+	fs []Func[A, *Maybe[B]]
+}
+
+// Apply implements Func.
+func (f *firstSuccessFunc[A, B]) Apply(ctx context.Context, a A) *Maybe[B] {
+	var (
+		observations []*Observations
+		mb           *Maybe[B]
+	)
+	for _, fx := range f.fs {
+		mb = fx.Apply(ctx, a)
+		runtimex.Assert(mb != nil, "fx.Apply returned a nil pointer")
+		observations = append(observations, mb.Observations...)
+		if mb.Error == nil {
+			break
+		}
+	}
+	return &Maybe[B]{
+		Error:        mb.Error,
+		Observations: observations, // merge observations of all attempts
+		Operation:    mb.Operation,
+		State:        mb.State,
+	}
+}
+
+// Race returns a Func that applies all the functions in fs in parallel and
+// returns the result of the first one that succeeds. As soon as one function
+// succeeds, we cancel the context used by the others and wait for them to
+// terminate. This function panics if fs is empty.
+//
+// The return value contains the observations collected by all the functions,
+// in the same order of fs. When all the functions fail, we return the error,
+// operation, and state of the first function in fs.
+func Race[A, B any](fs ...Func[A, *Maybe[B]]) Func[A, *Maybe[B]] {
+	runtimex.Assert(len(fs) > 0, "Race requires at least one Func")
+	return &raceFunc[A, B]{fs}
+}
+
+// raceFunc is the type returned by [Race].
+type raceFunc[A, B any] struct {
+	fs []Func[A, *Maybe[B]]
+}
+
+// Apply implements Func.
+func (f *raceFunc[A, B]) Apply(ctx context.Context, a A) *Maybe[B] {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		winner = -1
+	)
+	results := make([]*Maybe[B], len(f.fs))
+	wg := &sync.WaitGroup{}
+	for idx, fx := range f.fs {
+		wg.Add(1)
+		go func(idx int, fx Func[A, *Maybe[B]]) {
+			defer wg.Done()
+			mb := fx.Apply(ctx, a)
+			runtimex.Assert(mb != nil, "fx.Apply returned a nil pointer")
+			defer mu.Unlock()
+			mu.Lock()
+			results[idx] = mb
+			if mb.Error == nil && winner < 0 {
+				winner = idx
+				cancel() // stop the other functions
+			}
+		}(idx, fx)
+	}
+	wg.Wait()
+
+	var observations []*Observations
+	for _, mb := range results {
+		observations = append(observations, mb.Observations...)
+	}
+	result := results[0]
+	if winner >= 0 {
+		result = results[winner]
+	}
+	return &Maybe[B]{
+		Error:        result.Error,
+		Observations: observations, // merge observations of all functions
+		Operation:    result.Operation,
+		State:        result.State,
+	}
+}
+
+// WithTimeout returns a Func that applies fx using a context
+// bound to the given timeout.
+func WithTimeout[A, B any](timeout time.Duration, fx Func[A, *Maybe[B]]) Func[A, *Maybe[B]] {
+	return &withTimeoutFunc[A, B]{
+		fx:      fx,
+		timeout: timeout,
+	}
+}
+
+// withTimeoutFunc is the type returned by [WithTimeout].
+type withTimeoutFunc[A, B any] struct {
+	fx      Func[A, *Maybe[B]]
+	timeout time.Duration
+}
+
+// Apply implements Func.
+func (f *withTimeoutFunc[A, B]) Apply(ctx context.Context, a A) *Maybe[B] {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	return f.fx.Apply(ctx, a)
+}
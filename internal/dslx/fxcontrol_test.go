@@ -0,0 +1,248 @@
+package dslx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// flakyFn is a Func that fails the first failures times it's applied.
+type flakyFn struct {
+	count    atomic.Int64
+	failures int64
+	name     string
+}
+
+func (f *flakyFn) Apply(ctx context.Context, i int) *Maybe[int] {
+	var err error
+	if f.count.Add(1) <= f.failures {
+		err = errors.New("mocked")
+	}
+	return &Maybe[int]{
+		Error: err,
+		State: i + 1,
+		Observations: []*Observations{
+			{
+				NetworkEvents: []*model.ArchivalNetworkEvent{{Tags: []string{f.name}}},
+			},
+		},
+		Operation: f.name,
+	}
+}
+
+// blockingFn is a Func that blocks until the context is done.
+type blockingFn struct{}
+
+func (f *blockingFn) Apply(ctx context.Context, i int) *Maybe[int] {
+	<-ctx.Done()
+	return &Maybe[int]{
+		Error: ctx.Err(),
+		State: i,
+		Observations: []*Observations{
+			{
+				NetworkEvents: []*model.ArchivalNetworkEvent{{Tags: []string{"blocking"}}},
+			},
+		},
+		Operation: "blocking",
+	}
+}
+
+/*
+Test cases:
+- Retry:
+  - succeeds at the first attempt
+  - succeeds after retrying
+  - fails after all attempts
+  - stops when the context is done
+*/
+func TestRetry(t *testing.T) {
+	tests := map[string]struct {
+		attempts     int
+		failures     int64
+		expectErr    bool
+		expectCalls  int64
+		expectNumObs int
+	}{
+		"succeeds at the first attempt": {
+			attempts: 3, failures: 0, expectErr: false, expectCalls: 1, expectNumObs: 1,
+		},
+		"succeeds after retrying": {
+			attempts: 3, failures: 2, expectErr: false, expectCalls: 3, expectNumObs: 3,
+		},
+		"fails after all attempts": {
+			attempts: 3, failures: 10, expectErr: true, expectCalls: 3, expectNumObs: 3,
+		},
+		"with zero attempts we try once": {
+			attempts: 0, failures: 10, expectErr: true, expectCalls: 1, expectNumObs: 1,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			fx := &flakyFn{failures: tt.failures, name: "flaky"}
+			r := Retry[int, int](tt.attempts, time.Microsecond, fx).Apply(context.Background(), 42)
+			if (r.Error != nil) != tt.expectErr {
+				t.Fatal("unexpected error", r.Error)
+			}
+			if fx.count.Load() != tt.expectCalls {
+				t.Fatal("unexpected number of calls", fx.count.Load())
+			}
+			if len(r.Observations) != tt.expectNumObs {
+				t.Fatal("unexpected number of observations", len(r.Observations))
+			}
+			if r.State != 43 || r.Operation != "flaky" {
+				t.Fatal("unexpected state or operation")
+			}
+		})
+	}
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		fx := &flakyFn{failures: 10, name: "flaky"}
+		r := Retry[int, int](5, time.Hour, fx).Apply(ctx, 42)
+		if r.Error == nil {
+			t.Fatal("expected an error")
+		}
+		if fx.count.Load() != 1 {
+			t.Fatal("unexpected number of calls", fx.count.Load())
+		}
+	})
+
+	t.Run("the backoff doubling is clamped", func(t *testing.T) {
+		tests := []struct {
+			backoff time.Duration
+			expect  time.Duration
+		}{
+			{backoff: time.Second, expect: 2 * time.Second},
+			{backoff: 40 * time.Second, expect: RetryMaxBackoff},
+			{backoff: RetryMaxBackoff, expect: RetryMaxBackoff},
+			{backoff: math.MaxInt64, expect: math.MaxInt64},
+		}
+		for _, tt := range tests {
+			if got := retryNextBackoff(tt.backoff); got != tt.expect {
+				t.Fatal("unexpected backoff", tt.backoff, got)
+			}
+		}
+	})
+}
+
+/*
+Test cases:
+- FirstSuccess:
+  - the first function succeeds
+  - we fallback to the second function
+  - all functions fail
+*/
+func TestFirstSuccess(t *testing.T) {
+	mockedErr := errors.New("mocked")
+	tests := map[string]struct {
+		fs           []Func[int, *Maybe[int]]
+		expectErr    error
+		expectOp     string
+		expectNumObs int
+	}{
+		"the first function succeeds": {
+			fs:           []Func[int, *Maybe[int]]{getFn(nil, "first"), getFn(nil, "second")},
+			expectErr:    nil,
+			expectOp:     "first",
+			expectNumObs: 1,
+		},
+		"we fallback to the second function": {
+			fs:           []Func[int, *Maybe[int]]{getFn(mockedErr, "first"), getFn(nil, "second")},
+			expectErr:    nil,
+			expectOp:     "second",
+			expectNumObs: 2,
+		},
+		"all functions fail": {
+			fs:           []Func[int, *Maybe[int]]{getFn(mockedErr, "first"), getFn(mockedErr, "second")},
+			expectErr:    mockedErr,
+			expectOp:     "second",
+			expectNumObs: 2,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := FirstSuccess(tt.fs...).Apply(context.Background(), 42)
+			if r.Error != tt.expectErr {
+				t.Fatal("unexpected error", r.Error)
+			}
+			if r.Operation != tt.expectOp {
+				t.Fatal("unexpected operation", r.Operation)
+			}
+			if len(r.Observations) != tt.expectNumObs {
+				t.Fatal("unexpected number of observations", len(r.Observations))
+			}
+		})
+	}
+
+	t.Run("panics without functions", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+		FirstSuccess[int, int]()
+	})
+}
+
+/*
+Test cases:
+- Race:
+  - the successful function wins and cancels the others
+  - all functions fail
+*/
+func TestRace(t *testing.T) {
+	t.Run("the successful function wins and cancels the others", func(t *testing.T) {
+		r := Race[int, int](&blockingFn{}, getFn(nil, "winner")).Apply(context.Background(), 42)
+		if r.Error != nil {
+			t.Fatal(r.Error)
+		}
+		if r.Operation != "winner" || r.State != 43 {
+			t.Fatal("unexpected operation or state")
+		}
+		if len(r.Observations) != 2 {
+			t.Fatal("unexpected number of observations", len(r.Observations))
+		}
+		if r.Observations[0].NetworkEvents[0].Tags[0] != "blocking" {
+			t.Fatal("observations are not in the same order of the functions")
+		}
+	})
+
+	t.Run("all functions fail", func(t *testing.T) {
+		first, second := errors.New("first"), errors.New("second")
+		r := Race(getFn(first, "first"), getFn(second, "second")).Apply(context.Background(), 42)
+		if r.Error != first {
+			t.Fatal("unexpected error", r.Error)
+		}
+		if r.Operation != "first" {
+			t.Fatal("unexpected operation", r.Operation)
+		}
+		if len(r.Observations) != 2 {
+			t.Fatal("unexpected number of observations", len(r.Observations))
+		}
+	})
+
+	t.Run("panics without functions", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected a panic")
+			}
+		}()
+		Race[int, int]()
+	})
+}
+
+func TestWithTimeout(t *testing.T) {
+	r := WithTimeout[int, int](time.Millisecond, &blockingFn{}).Apply(context.Background(), 42)
+	if !errors.Is(r.Error, context.DeadlineExceeded) {
+		t.Fatal("unexpected error", r.Error)
+	}
+	if len(r.Observations) != 1 {
+		t.Fatal("unexpected number of observations", len(r.Observations))
+	}
+}
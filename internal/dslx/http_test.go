@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/model/mocks"
@@ -24,6 +25,7 @@ Test cases:
   - with success (https)
   - with success (http)
   - with header options
+  - with archival observations
 */
 func TestHTTPRequest(t *testing.T) {
 	t.Run("Get httpRequestFunc with options", func(t *testing.T) {
@@ -31,6 +33,7 @@ func TestHTTPRequest(t *testing.T) {
 			HTTPRequestOptionAccept("text/html"),
 			HTTPRequestOptionAcceptLanguage("de"),
 			HTTPRequestOptionHost("host"),
+			HTTPRequestOptionMaxBodySnapshotSize(1024),
 			HTTPRequestOptionMethod("PUT"),
 			HTTPRequestOptionReferer("https://example.com/"),
 			HTTPRequestOptionURLPath("/path/to/example"),
@@ -50,6 +53,9 @@ func TestHTTPRequest(t *testing.T) {
 		if requestFunc.Host != "host" {
 			t.Fatalf("unexpected %s, expected %s, got %s", "Host", "host", requestFunc.Host)
 		}
+		if requestFunc.MaxBodySnapshotSize != 1024 {
+			t.Fatalf("unexpected %s, expected %d, got %d", "MaxBodySnapshotSize", 1024, requestFunc.MaxBodySnapshotSize)
+		}
 		if requestFunc.Method != "PUT" {
 			t.Fatalf("unexpected %s, expected %s, got %s", "Method", "PUT", requestFunc.Method)
 		}
@@ -240,6 +246,73 @@ func TestHTTPRequest(t *testing.T) {
 			}
 		})
 
+		t.Run("with archival observations", func(t *testing.T) {
+			redirectTransport := &mocks.HTTPTransport{
+				MockRoundTrip: func(req *http.Request) (*http.Response, error) {
+					resp := &http.Response{
+						StatusCode: 302,
+						Header: http.Header{
+							"Location": {"/redirected"},
+						},
+						Body:    io.NopCloser(strings.NewReader("0123456789")),
+						Request: req,
+					}
+					return resp, nil
+				},
+				MockNetwork: func() string {
+					return "tcp"
+				},
+			}
+			httpTransport := HTTPTransport{
+				Address:               "1.2.3.4:443",
+				Domain:                "example.com",
+				IDGenerator:           idGen,
+				Logger:                model.DiscardLogger,
+				Network:               "tcp",
+				Scheme:                "https",
+				TLSNegotiatedProtocol: "h2",
+				Trace:                 trace,
+				Transport:             redirectTransport,
+				ZeroTime:              zeroTime,
+			}
+			httpRequest := &httpRequestFunc{
+				MaxBodySnapshotSize: 4,
+			}
+			res := httpRequest.Apply(context.Background(), &httpTransport)
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+			var requests []*model.ArchivalHTTPRequestResult
+			for _, obs := range res.Observations {
+				requests = append(requests, obs.Requests...)
+			}
+			if len(requests) != 1 {
+				t.Fatal("expected exactly one request, got", len(requests))
+			}
+			entry := requests[0]
+			if entry != res.State.HTTPRequestResult {
+				t.Fatal("expected the state to reference the archival result")
+			}
+			if entry.Address != "1.2.3.4:443" || entry.ALPN != "h2" || entry.Network != "tcp" {
+				t.Fatal("unexpected endpoint information")
+			}
+			if entry.Request.URL != "https://example.com/" || entry.Request.Method != "GET" {
+				t.Fatal("unexpected request")
+			}
+			if entry.Response.Code != 302 {
+				t.Fatal("unexpected status code")
+			}
+			if entry.Response.Body.Value != "0123" || !entry.Response.BodyIsTruncated {
+				t.Fatal("unexpected body")
+			}
+			if diff := cmp.Diff([]string{"https://example.com/redirected"}, entry.Response.Locations); diff != "" {
+				t.Fatal(diff)
+			}
+			if entry.TransactionID != trace.Index || entry.T < entry.T0 {
+				t.Fatal("unexpected transaction ID or timing")
+			}
+		})
+
 	})
 }
 
@@ -79,7 +79,15 @@ func HTTPRequestOptionHost(value string) HTTPRequestOption {
 	}
 }
 
-// HTTPRequestOptionHost sets the request method.
+// HTTPRequestOptionMaxBodySnapshotSize sets the maximum response body
+// snapshot size. A zero or negative value means using the default.
+func HTTPRequestOptionMaxBodySnapshotSize(value int64) HTTPRequestOption {
+	return func(hrf *httpRequestFunc) {
+		hrf.MaxBodySnapshotSize = value
+	}
+}
+
+// HTTPRequestOptionMethod sets the request method.
 func HTTPRequestOptionMethod(value string) HTTPRequestOption {
 	return func(hrf *httpRequestFunc) {
 		hrf.Method = value
@@ -127,6 +135,9 @@ type httpRequestFunc struct {
 	// Host is the OPTIONAL host header.
 	Host string
 
+	// MaxBodySnapshotSize is the OPTIONAL maximum body snapshot size.
+	MaxBodySnapshotSize int64
+
 	// Method is the OPTIONAL method.
 	Method string
 
@@ -152,6 +163,7 @@ func (f *httpRequestFunc) Apply(
 		body         []byte
 		observations []*Observations
 		resp         *http.Response
+		result       *model.ArchivalHTTPRequestResult
 	)
 
 	// create HTTP request
@@ -170,7 +182,7 @@ func (f *httpRequestFunc) Apply(
 		)
 
 		// perform HTTP transaction and collect the related observations
-		resp, body, observations, err = f.do(ctx, input, req)
+		resp, body, result, observations, err = f.do(ctx, input, req)
 
 		// stop the operation logger
 		ol.Stop(err)
@@ -181,9 +193,10 @@ func (f *httpRequestFunc) Apply(
 	state := &HTTPResponse{
 		Address:                  input.Address,
 		Domain:                   input.Domain,
-		HTTPRequest:              req,  // possibly nil
-		HTTPResponse:             resp, // possibly nil
-		HTTPResponseBodySnapshot: body, // possibly nil
+		HTTPRequest:              req,    // possibly nil
+		HTTPRequestResult:        result, // possibly nil
+		HTTPResponse:             resp,   // possibly nil
+		HTTPResponseBodySnapshot: body,   // possibly nil
 		IDGenerator:              input.IDGenerator,
 		Logger:                   input.Logger,
 		Network:                  input.Network,
@@ -280,13 +293,24 @@ func (f *httpRequestFunc) urlPath() string {
 	return "/"
 }
 
+// httpDefaultMaxBodySnapshotSize is the default maximum body snapshot size.
+const httpDefaultMaxBodySnapshotSize = 1 << 19
+
+func (f *httpRequestFunc) maxBodySnapshotSize() int64 {
+	if f.MaxBodySnapshotSize > 0 {
+		return f.MaxBodySnapshotSize
+	}
+	return httpDefaultMaxBodySnapshotSize
+}
+
 func (f *httpRequestFunc) do(
 	ctx context.Context,
 	input *HTTPTransport,
 	req *http.Request,
-) (*http.Response, []byte, []*Observations, error) {
-	const maxbody = 1 << 19 // TODO(bassosimone): allow to configure this value?
-	started := input.Trace.TimeSince(input.Trace.ZeroTime)
+) (*http.Response, []byte, *model.ArchivalHTTPRequestResult, []*Observations, error) {
+	maxbody := f.maxBodySnapshotSize()
+	t0 := input.Trace.TimeNow()
+	started := t0.Sub(input.Trace.ZeroTime)
 	observations := []*Observations{{}} // one entry!
 
 	observations[0].NetworkEvents = append(observations[0].NetworkEvents,
@@ -303,7 +327,8 @@ func (f *httpRequestFunc) do(
 		reader := io.LimitReader(resp.Body, maxbody)
 		body, err = netxlite.ReadAllContext(ctx, reader) // TODO: enable streaming and measure speed
 	}
-	finished := input.Trace.TimeSince(input.Trace.ZeroTime)
+	t := input.Trace.TimeNow()
+	finished := t.Sub(input.Trace.ZeroTime)
 
 	observations[0].NetworkEvents = append(observations[0].NetworkEvents,
 		measurexlite.NewAnnotationArchivalNetworkEvent(
@@ -312,23 +337,23 @@ func (f *httpRequestFunc) do(
 			"http_transaction_done",
 		))
 
-	observations[0].Requests = append(observations[0].Requests,
-		measurexlite.NewArchivalHTTPRequestResult(
-			input.Trace.Index,
-			started,
-			input.Network,
-			input.Address,
-			input.TLSNegotiatedProtocol,
-			input.Transport.Network(),
-			req,
-			resp,
-			maxbody,
-			body,
-			err,
-			finished,
-		))
+	// the trace buffers the result, which we'll later collect along with
+	// all the other observations using maybeTraceToObservations
+	result := input.Trace.OnHTTPTransactionDone(
+		t0,
+		input.Network,
+		input.Address,
+		input.TLSNegotiatedProtocol,
+		input.Transport.Network(),
+		req,
+		resp,
+		maxbody,
+		body,
+		err,
+		t,
+	)
 
-	return resp, body, observations, err
+	return resp, body, result, observations, err
 }
 
 // HTTPResponse is the response generated by an HTTP requests. Generally
@@ -343,6 +368,10 @@ type HTTPResponse struct {
 	// HTTPRequest is the possibly-nil HTTP request.
 	HTTPRequest *http.Request
 
+	// HTTPRequestResult is the possibly-nil archival result of the HTTP
+	// transaction, which is also included into the observations.
+	HTTPRequestResult *model.ArchivalHTTPRequestResult
+
 	// HTTPResponse is the HTTP response or nil if Err != nil.
 	HTTPResponse *http.Response
 
@@ -46,7 +46,7 @@ func maybeTraceToObservations(trace *measurexlite.Trace) (out []*Observations) {
 		out = append(out, &Observations{
 			NetworkEvents:  trace.NetworkEvents(),
 			Queries:        trace.DNSLookupsFromRoundTrip(),
-			Requests:       []*model.ArchivalHTTPRequestResult{}, // no extractor inside trace!
+			Requests:       trace.HTTPTransactions(),
 			TCPConnect:     trace.TCPConnects(),
 			TLSHandshakes:  trace.TLSHandshakes(),
 			QUICHandshakes: trace.QUICHandshakes(),
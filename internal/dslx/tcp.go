@@ -32,7 +32,7 @@ func (f *tcpConnectFunc) Apply(
 	ctx context.Context, input *Endpoint) *Maybe[*TCPConnection] {
 
 	// create trace
-	trace := measurexlite.NewTrace(input.IDGenerator.Add(1), input.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, input.IDGenerator.Add(1), input.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -6,20 +6,48 @@ package dslx
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"net"
 	"sync/atomic"
 	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/ech"
 	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	utls "gitlab.com/yawning/utls.git"
 )
 
 // TLSHandshakeOption is an option you can pass to TLSHandshake.
 type TLSHandshakeOption func(*tlsHandshakeFunc)
 
+// TLSHandshakeOptionClientHelloID allows to use uTLS with the given ClientHelloID
+// (e.g., &utls.HelloChrome_Auto) to parrot the ClientHello of a specific client.
+func TLSHandshakeOptionClientHelloID(value *utls.ClientHelloID) TLSHandshakeOption {
+	return func(thf *tlsHandshakeFunc) {
+		thf.ClientHelloID = value
+	}
+}
+
+// TLSHandshakeOptionECHConfigList allows to add an Encrypted ClientHello extension
+// for the first supported config in the given serialized ECHConfigList (e.g., the
+// one published by the HTTPS DNS record). Unless you also set the SNI explicitly,
+// we use the config's public name as the SNI. Passing an empty value causes us to
+// send a GREASE ECH extension. Because ECH requires uTLS, we use the ClientHelloID
+// you configured, if any, or utls.HelloFirefox_Auto otherwise.
+//
+// The ECH extension we send does not encrypt a real inner ClientHello, therefore
+// the server will always complete the handshake using the outer ClientHello, which
+// is enough to determine whether ECH-enabled handshakes are blocked.
+func TLSHandshakeOptionECHConfigList(value []byte) TLSHandshakeOption {
+	return func(thf *tlsHandshakeFunc) {
+		thf.ECH = true
+		thf.ECHConfigList = value
+	}
+}
+
 // TLSHandshakeOptionInsecureSkipVerify controls whether TLS verification is enabled.
 func TLSHandshakeOptionInsecureSkipVerify(value bool) TLSHandshakeOption {
 	return func(thf *tlsHandshakeFunc) {
@@ -55,6 +83,9 @@ func TLSHandshake(pool *ConnPool, options ...TLSHandshakeOption) Func[
 	// why we're using nil to force netxlite to use the cached
 	// default Mozilla cert pool.
 	f := &tlsHandshakeFunc{
+		ClientHelloID:      nil,
+		ECH:                false,
+		ECHConfigList:      nil,
 		InsecureSkipVerify: false,
 		NextProto:          []string{},
 		Pool:               pool,
@@ -69,6 +100,15 @@ func TLSHandshake(pool *ConnPool, options ...TLSHandshakeOption) Func[
 
 // tlsHandshakeFunc performs TLS handshakes.
 type tlsHandshakeFunc struct {
+	// ClientHelloID is the OPTIONAL uTLS ClientHelloID to use.
+	ClientHelloID *utls.ClientHelloID
+
+	// ECH indicates whether to send an ECH extension.
+	ECH bool
+
+	// ECHConfigList is the OPTIONAL ECHConfigList to use when ECH is true.
+	ECHConfigList []byte
+
 	// InsecureSkipVerify allows to skip TLS verification.
 	InsecureSkipVerify bool
 
@@ -98,6 +138,12 @@ func (f *tlsHandshakeFunc) Apply(
 	serverName := f.serverName(input)
 	nextProto := f.nextProto()
 
+	// possibly generate the ECH extension, which may override the SNI
+	extensions, publicName, err := f.extensions()
+	if publicName != "" && f.ServerName == "" {
+		serverName = publicName
+	}
+
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
 		input.Logger,
@@ -109,10 +155,7 @@ func (f *tlsHandshakeFunc) Apply(
 	)
 
 	// setup
-	handshaker := f.handshaker
-	if handshaker == nil {
-		handshaker = trace.NewTLSHandshakerStdlib(input.Logger)
-	}
+	handshaker := f.newHandshaker(trace, input.Logger, extensions)
 	config := &tls.Config{
 		NextProtos:         nextProto,
 		InsecureSkipVerify: f.InsecureSkipVerify,
@@ -124,7 +167,13 @@ func (f *tlsHandshakeFunc) Apply(
 	defer cancel()
 
 	// handshake
-	conn, tlsState, err := handshaker.Handshake(ctx, input.Conn, config)
+	var (
+		conn     net.Conn
+		tlsState tls.ConnectionState
+	)
+	if err == nil {
+		conn, tlsState, err = handshaker.Handshake(ctx, input.Conn, config)
+	}
 
 	// possibly register established conn for late close
 	f.Pool.MaybeTrack(conn)
@@ -157,6 +206,39 @@ func (f *tlsHandshakeFunc) Apply(
 	}
 }
 
+// extensions returns the uTLS extensions to add to the ClientHello and the
+// public name to use as the SNI, which is empty unless we're using ECH.
+func (f *tlsHandshakeFunc) extensions() ([]utls.TLSExtension, string, error) {
+	if !f.ECH {
+		return nil, "", nil
+	}
+	payload, publicName, err := ech.NewExtension(f.ECHConfigList, rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	ext := &utls.GenericExtension{Id: ech.ExtensionType, Data: payload}
+	return []utls.TLSExtension{ext}, publicName, nil
+}
+
+// newHandshaker returns the TLS handshaker to use depending on the options.
+func (f *tlsHandshakeFunc) newHandshaker(
+	trace *measurexlite.Trace, logger model.Logger, extensions []utls.TLSExtension) model.TLSHandshaker {
+	switch {
+	case f.handshaker != nil:
+		return f.handshaker
+	case len(extensions) > 0:
+		id := f.ClientHelloID
+		if id == nil || *id == utls.HelloGolang {
+			id = &utls.HelloFirefox_Auto // we cannot add extensions to HelloGolang
+		}
+		return trace.NewTLSHandshakerUTLSWithExtensions(logger, id, extensions)
+	case f.ClientHelloID != nil:
+		return trace.NewTLSHandshakerUTLS(logger, f.ClientHelloID)
+	default:
+		return trace.NewTLSHandshakerStdlib(logger)
+	}
+}
+
 func (f *tlsHandshakeFunc) serverName(input *TCPConnection) string {
 	if f.ServerName != "" {
 		return f.ServerName
@@ -1,6 +1,7 @@
 package dslx
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -10,9 +11,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cloudflare/circl/hpke"
+	"github.com/ooni/probe-cli/v3/internal/ech"
 	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	utls "gitlab.com/yawning/utls.git"
+	"golang.org/x/crypto/cryptobyte"
 )
 
 /*
@@ -24,6 +29,9 @@ Test cases:
   - with success
   - with sni
   - with options
+  - with ECH GREASE
+  - with ECH config list
+  - with invalid ECH config list
 */
 func TestTLSHandshake(t *testing.T) {
 	t.Run("Get tlsHandshakeFunc with options", func(t *testing.T) {
@@ -36,6 +44,8 @@ func TestTLSHandshake(t *testing.T) {
 			TLSHandshakeOptionNextProto([]string{"h2"}),
 			TLSHandshakeOptionServerName("sni"),
 			TLSHandshakeOptionRootCAs(certpool),
+			TLSHandshakeOptionClientHelloID(&utls.HelloChrome_Auto),
+			TLSHandshakeOptionECHConfigList([]byte{1, 2, 3}),
 		)
 		var handshakeFunc *tlsHandshakeFunc
 		var ok bool
@@ -54,15 +64,23 @@ func TestTLSHandshake(t *testing.T) {
 		if !handshakeFunc.RootCAs.Equal(certpool) {
 			t.Fatalf("unexpected %s, expected %v, got %v", "RootCAs", certpool, handshakeFunc.RootCAs)
 		}
+		if handshakeFunc.ClientHelloID != &utls.HelloChrome_Auto {
+			t.Fatalf("unexpected %s, expected %v, got %v", "ClientHelloID", &utls.HelloChrome_Auto, handshakeFunc.ClientHelloID)
+		}
+		if !handshakeFunc.ECH || !bytes.Equal(handshakeFunc.ECHConfigList, []byte{1, 2, 3}) {
+			t.Fatalf("unexpected %s, got %v", "ECHConfigList", handshakeFunc.ECHConfigList)
+		}
 	})
 
 	t.Run("Apply tlsHandshakeFunc", func(t *testing.T) {
 		wasClosed := false
 
 		type configOptions struct {
-			sni        string
-			address    string
-			nextProtos []string
+			sni           string
+			address       string
+			nextProtos    []string
+			ech           bool
+			echConfigList []byte
 		}
 		tcpConn := mocks.Conn{
 			MockClose: func() error {
@@ -78,8 +96,10 @@ func TestTLSHandshake(t *testing.T) {
 			},
 		}
 
+		var gotSNI string
 		goodHandshaker := &mocks.TLSHandshaker{
 			MockHandshake: func(ctx context.Context, conn net.Conn, config *tls.Config) (net.Conn, tls.ConnectionState, error) {
+				gotSNI = config.ServerName
 				return tlsConn, tls.ConnectionState{}, nil
 			},
 		}
@@ -89,6 +109,7 @@ func TestTLSHandshake(t *testing.T) {
 			handshaker *mocks.TLSHandshaker
 			expectConn net.Conn
 			expectErr  error
+			expectSNI  string
 			closed     bool
 		}{
 			"with EOF": {
@@ -108,6 +129,7 @@ func TestTLSHandshake(t *testing.T) {
 				handshaker: goodHandshaker,
 				expectConn: tlsConn,
 				expectErr:  nil,
+				expectSNI:  "1.2.3.4",
 				closed:     true,
 			},
 			"with sni": {
@@ -115,6 +137,7 @@ func TestTLSHandshake(t *testing.T) {
 				handshaker: goodHandshaker,
 				expectConn: tlsConn,
 				expectErr:  nil,
+				expectSNI:  "sni.com",
 				closed:     true,
 			},
 			"with options": {
@@ -124,16 +147,41 @@ func TestTLSHandshake(t *testing.T) {
 				expectErr:  nil,
 				closed:     true,
 			},
+			"with ECH GREASE": {
+				config:     configOptions{ech: true},
+				handshaker: goodHandshaker,
+				expectConn: tlsConn,
+				expectErr:  nil,
+				expectSNI:  "1.2.3.4",
+				closed:     true,
+			},
+			"with ECH config list": {
+				config:     configOptions{ech: true, echConfigList: newTestECHConfigList(t, "public.example.com")},
+				handshaker: goodHandshaker,
+				expectConn: tlsConn,
+				expectErr:  nil,
+				expectSNI:  "public.example.com",
+				closed:     true,
+			},
+			"with invalid ECH config list": {
+				config:     configOptions{ech: true, echConfigList: []byte{0, 1}},
+				handshaker: goodHandshaker,
+				expectConn: nil,
+				expectErr:  ech.ErrInvalidConfigList,
+				closed:     false,
+			},
 		}
 
 		for name, tt := range tests {
 			t.Run(name, func(t *testing.T) {
 				pool := &ConnPool{}
 				tlsHandshake := &tlsHandshakeFunc{
-					NextProto:  tt.config.nextProtos,
-					Pool:       pool,
-					ServerName: tt.config.sni,
-					handshaker: tt.handshaker,
+					ECH:           tt.config.ech,
+					ECHConfigList: tt.config.echConfigList,
+					NextProto:     tt.config.nextProtos,
+					Pool:          pool,
+					ServerName:    tt.config.sni,
+					handshaker:    tt.handshaker,
 				}
 				idGen := &atomic.Int64{}
 				zeroTime := time.Time{}
@@ -158,12 +206,77 @@ func TestTLSHandshake(t *testing.T) {
 				if res.State.Conn != tt.expectConn {
 					t.Fatalf("unexpected conn %v", res.State.Conn)
 				}
+				if tt.expectSNI != "" && gotSNI != tt.expectSNI {
+					t.Fatalf("unexpected SNI %s", gotSNI)
+				}
 				pool.Close()
 				if wasClosed != tt.closed {
 					t.Fatalf("unexpected connection closed state %v", wasClosed)
 				}
 			})
 			wasClosed = false
+			gotSNI = ""
+		}
+	})
+
+	t.Run("newHandshaker", func(t *testing.T) {
+		type expectation struct {
+			stdlib     bool
+			id         *utls.ClientHelloID
+			extensions bool
+		}
+		tests := map[string]struct {
+			clientHelloID *utls.ClientHelloID
+			extensions    []utls.TLSExtension
+			expect        expectation
+		}{
+			"by default we use the stdlib": {
+				expect: expectation{stdlib: true},
+			},
+			"with a ClientHelloID we use uTLS": {
+				clientHelloID: &utls.HelloChrome_Auto,
+				expect:        expectation{id: &utls.HelloChrome_Auto},
+			},
+			"with extensions and a ClientHelloID": {
+				clientHelloID: &utls.HelloChrome_Auto,
+				extensions:    []utls.TLSExtension{&utls.GenericExtension{}},
+				expect:        expectation{id: &utls.HelloChrome_Auto, extensions: true},
+			},
+			"with extensions and HelloGolang": {
+				clientHelloID: &utls.HelloGolang,
+				extensions:    []utls.TLSExtension{&utls.GenericExtension{}},
+				expect:        expectation{id: &utls.HelloFirefox_Auto, extensions: true},
+			},
+			"with extensions and no ClientHelloID": {
+				extensions: []utls.TLSExtension{&utls.GenericExtension{}},
+				expect:     expectation{id: &utls.HelloFirefox_Auto, extensions: true},
+			},
+		}
+
+		for name, tt := range tests {
+			t.Run(name, func(t *testing.T) {
+				var got expectation
+				trace := measurexlite.NewTrace(0, time.Now())
+				trace.NewTLSHandshakerStdlibFn = func(dl model.DebugLogger) model.TLSHandshaker {
+					got.stdlib = true
+					return &mocks.TLSHandshaker{}
+				}
+				trace.NewTLSHandshakerUTLSFn = func(dl model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker {
+					got.id = id
+					return &mocks.TLSHandshaker{}
+				}
+				trace.NewTLSHandshakerUTLSWithExtensionsFn = func(
+					dl model.DebugLogger, id *utls.ClientHelloID, extensions []utls.TLSExtension) model.TLSHandshaker {
+					got.id = id
+					got.extensions = len(extensions) > 0
+					return &mocks.TLSHandshaker{}
+				}
+				f := &tlsHandshakeFunc{ClientHelloID: tt.clientHelloID}
+				_ = f.newHandshaker(trace, model.DiscardLogger, tt.extensions)
+				if got != tt.expect {
+					t.Fatalf("expected %+v, got %+v", tt.expect, got)
+				}
+			})
 		}
 	})
 }
@@ -235,3 +348,37 @@ func TestServerNameTLS(t *testing.T) {
 		}
 	})
 }
+
+// newTestECHConfigList creates a serialized ECHConfigList containing a
+// single X25519 config using the given public name.
+func newTestECHConfigList(t *testing.T, publicName string) []byte {
+	publicKey, _, err := hpke.KEM_X25519_HKDF_SHA256.Scheme().GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawPublicKey, err := publicKey.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(ech.ConfigVersion)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(7) // config_id
+			b.AddUint16(uint16(hpke.KEM_X25519_HKDF_SHA256))
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(rawPublicKey)
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(uint16(hpke.KDF_HKDF_SHA256))
+				b.AddUint16(uint16(hpke.AEAD_AES128GCM))
+			})
+			b.AddUint8(0) // maximum_name_length
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte(publicName))
+			})
+			b.AddUint16(0) // extensions
+		})
+	})
+	return b.BytesOrPanic()
+}
@@ -0,0 +1,218 @@
+// Package ech contains code to generate the Encrypted ClientHello (ECH)
+// extension, including GREASE ECH extensions, as specified by
+// https://www.ietf.org/archive/id/draft-ietf-tls-esni-14.html.
+//
+// Note that the payload we include into the extension does not encrypt a real
+// ClientHelloInner, therefore the server would always use the outer ClientHello
+// to continue the handshake. This is fine for measuring, since what matters is
+// what the ClientHello we send looks like to a network observer.
+package ech
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/kem"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// ExtensionType is the code point of the ECH extension.
+//
+// See https://www.ietf.org/archive/id/draft-ietf-tls-esni-14.html#section-5.
+const ExtensionType uint16 = 0xfe0d
+
+// ConfigVersion is the only ECHConfig version we know how to parse.
+const ConfigVersion uint16 = 0xfe0d
+
+// clientHelloOuter is the ECHClientHello type for an outer ClientHello.
+const clientHelloOuter uint8 = 0
+
+// encodedClientHelloInnerLen is the size of the fake EncodedClientHelloInner
+// that we encrypt into the payload of the ECH extension.
+//
+// TODO: compute this value using the recommended padding scheme
+// https://www.ietf.org/archive/id/draft-ietf-tls-esni-14.html#section-6.1.3.
+const encodedClientHelloInnerLen = 100
+
+// ErrInvalidConfigList indicates that we cannot parse an ECHConfigList.
+var ErrInvalidConfigList = errors.New("ech: invalid ECH config list")
+
+// ErrNoSupportedConfig indicates that an ECHConfigList does not contain any
+// configuration using a supported version, KEM, KDF, and AEAD.
+var ErrNoSupportedConfig = errors.New("ech: no supported ECH config")
+
+// config is a parsed ECHConfig.
+type config struct {
+	// cipherSuites contains the HPKE cipher suites.
+	cipherSuites []cipherSuite
+
+	// configID is the config ID.
+	configID uint8
+
+	// kemID is the HPKE KEM ID.
+	kemID uint16
+
+	// publicKey is the HPKE public key.
+	publicKey []byte
+
+	// publicName is the public name to use for the outer ClientHello.
+	publicName string
+
+	// raw contains the serialized ECHConfig.
+	raw []byte
+}
+
+// cipherSuite is an HPKE symmetric cipher suite.
+type cipherSuite struct {
+	// aeadID is the HPKE AEAD ID.
+	aeadID uint16
+
+	// kdfID is the HPKE KDF ID.
+	kdfID uint16
+}
+
+// parseConfigList parses a serialized ECHConfigList skipping the
+// configurations using versions we don't know how to parse.
+func parseConfigList(data []byte) ([]*config, error) {
+	input := cryptobyte.String(data)
+	var list cryptobyte.String
+	if !input.ReadUint16LengthPrefixed(&list) || !input.Empty() || list.Empty() {
+		return nil, ErrInvalidConfigList
+	}
+	var out []*config
+	for !list.Empty() {
+		before := list
+		var (
+			version  uint16
+			contents cryptobyte.String
+		)
+		if !list.ReadUint16(&version) || !list.ReadUint16LengthPrefixed(&contents) {
+			return nil, ErrInvalidConfigList
+		}
+		if version != ConfigVersion {
+			continue
+		}
+		cfg := &config{
+			raw: []byte(before[:len(before)-len(list)]),
+		}
+		var (
+			extensions    cryptobyte.String
+			maxNameLength uint8
+			publicKey     cryptobyte.String
+			publicName    cryptobyte.String
+			suites        cryptobyte.String
+		)
+		if !contents.ReadUint8(&cfg.configID) ||
+			!contents.ReadUint16(&cfg.kemID) ||
+			!contents.ReadUint16LengthPrefixed(&publicKey) ||
+			!contents.ReadUint16LengthPrefixed(&suites) ||
+			!contents.ReadUint8(&maxNameLength) ||
+			!contents.ReadUint8LengthPrefixed(&publicName) ||
+			!contents.ReadUint16LengthPrefixed(&extensions) ||
+			!contents.Empty() {
+			return nil, ErrInvalidConfigList
+		}
+		for !suites.Empty() {
+			var suite cipherSuite
+			if !suites.ReadUint16(&suite.kdfID) || !suites.ReadUint16(&suite.aeadID) {
+				return nil, ErrInvalidConfigList
+			}
+			cfg.cipherSuites = append(cfg.cipherSuites, suite)
+		}
+		cfg.publicKey = []byte(publicKey)
+		cfg.publicName = string(publicName)
+		out = append(out, cfg)
+	}
+	return out, nil
+}
+
+// NewExtension returns the payload of the ECH extension to add to the outer
+// ClientHello along with the public name to use as the outer SNI. When the
+// configList is empty, we generate the payload of a GREASE ECH extension (see
+// NewGreaseExtension) and return an empty public name.
+func NewExtension(configList []byte, rand io.Reader) ([]byte, string, error) {
+	if len(configList) <= 0 {
+		payload, err := NewGreaseExtension(rand)
+		if err != nil {
+			return nil, "", err
+		}
+		return payload, "", nil
+	}
+	configs, err := parseConfigList(configList)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, cfg := range configs {
+		kemID := hpke.KEM(cfg.kemID)
+		if !kemID.IsValid() {
+			continue
+		}
+		for _, suite := range cfg.cipherSuites {
+			kdf, aead := hpke.KDF(suite.kdfID), hpke.AEAD(suite.aeadID)
+			if !kdf.IsValid() || !aead.IsValid() {
+				continue
+			}
+			publicKey, err := kemID.Scheme().UnmarshalBinaryPublicKey(cfg.publicKey)
+			if err != nil {
+				return nil, "", fmt.Errorf("%w: %s", ErrInvalidConfigList, err.Error())
+			}
+			info := append([]byte("tls ech\x00"), cfg.raw...)
+			payload, err := newExtensionPayload(
+				rand, hpke.NewSuite(kemID, kdf, aead), publicKey, info, cfg.configID)
+			if err != nil {
+				return nil, "", err
+			}
+			return payload, cfg.publicName, nil
+		}
+	}
+	return nil, "", ErrNoSupportedConfig
+}
+
+// NewGreaseExtension generates the payload of a GREASE ECH extension as
+// specified by https://www.ietf.org/archive/id/draft-ietf-tls-esni-14.html#section-6.2.
+func NewGreaseExtension(rand io.Reader) ([]byte, error) {
+	kemID := hpke.KEM_X25519_HKDF_SHA256
+	publicKey, _, err := kemID.Scheme().GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	var configID [1]byte
+	if _, err := io.ReadFull(rand, configID[:]); err != nil {
+		return nil, err
+	}
+	suite := hpke.NewSuite(kemID, hpke.KDF_HKDF_SHA256, hpke.AEAD_AES128GCM)
+	return newExtensionPayload(rand, suite, publicKey, nil, configID[0])
+}
+
+// newExtensionPayload serializes an outer ECHClientHello for the given suite and
+// public key whose payload contains random data of the expected size.
+func newExtensionPayload(rand io.Reader, suite hpke.Suite,
+	publicKey kem.PublicKey, info []byte, configID uint8) ([]byte, error) {
+	sender, err := suite.NewSender(publicKey, info)
+	if err != nil {
+		return nil, err
+	}
+	enc, _, err := sender.Setup(rand)
+	if err != nil {
+		return nil, err
+	}
+	_, kdf, aead := suite.Params()
+	payload := make([]byte, aead.CipherLen(encodedClientHelloInnerLen))
+	if _, err := io.ReadFull(rand, payload); err != nil {
+		return nil, err
+	}
+	var b cryptobyte.Builder
+	b.AddUint8(clientHelloOuter)
+	b.AddUint16(uint16(kdf))
+	b.AddUint16(uint16(aead))
+	b.AddUint8(configID)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(enc)
+	})
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes(payload)
+	})
+	return b.Bytes()
+}
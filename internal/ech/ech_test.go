@@ -0,0 +1,196 @@
+package ech
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/cloudflare/circl/hpke"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// newTestConfigList creates a serialized ECHConfigList containing a
+// single config using the given version, KEM ID, and public name.
+func newTestConfigList(t *testing.T, version, kemID uint16, publicName string) []byte {
+	publicKey, _, err := hpke.KEM_X25519_HKDF_SHA256.Scheme().GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawPublicKey, err := publicKey.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(version)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint8(7) // config_id
+			b.AddUint16(kemID)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(rawPublicKey)
+			})
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddUint16(uint16(hpke.KDF_HKDF_SHA256))
+				b.AddUint16(uint16(hpke.AEAD_AES128GCM))
+			})
+			b.AddUint8(0) // maximum_name_length
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes([]byte(publicName))
+			})
+			b.AddUint16(0) // extensions
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+// parseTestExtensionPayload parses the payload of an outer ECHClientHello.
+func parseTestExtensionPayload(t *testing.T, data []byte) (kdf, aead uint16, configID uint8) {
+	input := cryptobyte.String(data)
+	var (
+		enc, payload cryptobyte.String
+		chType       uint8
+	)
+	if !input.ReadUint8(&chType) || !input.ReadUint16(&kdf) || !input.ReadUint16(&aead) ||
+		!input.ReadUint8(&configID) || !input.ReadUint16LengthPrefixed(&enc) ||
+		!input.ReadUint16LengthPrefixed(&payload) || !input.Empty() {
+		t.Fatal("cannot parse the ECH extension payload")
+	}
+	if chType != clientHelloOuter {
+		t.Fatal("unexpected ECHClientHello type", chType)
+	}
+	if len(enc) <= 0 {
+		t.Fatal("expected non-empty enc")
+	}
+	expectLen := hpke.AEAD(aead).CipherLen(encodedClientHelloInnerLen)
+	if uint(len(payload)) != expectLen {
+		t.Fatal("unexpected payload length", len(payload))
+	}
+	return
+}
+
+func TestParseConfigList(t *testing.T) {
+	t.Run("with a valid config list", func(t *testing.T) {
+		data := newTestConfigList(t, ConfigVersion, 0x0020, "public.example.com")
+		configs, err := parseConfigList(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(configs) != 1 {
+			t.Fatal("expected a single config")
+		}
+		cfg := configs[0]
+		if cfg.configID != 7 || cfg.kemID != 0x0020 || cfg.publicName != "public.example.com" {
+			t.Fatalf("unexpected config %+v", cfg)
+		}
+		if len(cfg.cipherSuites) != 1 {
+			t.Fatal("expected a single cipher suite")
+		}
+		if !bytes.Equal(cfg.raw, data[2:]) {
+			t.Fatal("unexpected raw config")
+		}
+	})
+
+	t.Run("we skip unknown versions", func(t *testing.T) {
+		data := newTestConfigList(t, 0xfe0a, 0x0020, "public.example.com")
+		configs, err := parseConfigList(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(configs) != 0 {
+			t.Fatal("expected no configs")
+		}
+	})
+
+	t.Run("with invalid input", func(t *testing.T) {
+		inputs := map[string][]byte{
+			"empty list":         {0, 0},
+			"truncated list":     {0, 8, 0xfe, 0x0d},
+			"trailing garbage":   append(newTestConfigList(t, ConfigVersion, 0x0020, "a.com"), 0),
+			"truncated contents": {0, 5, 0xfe, 0x0d, 0, 1, 7},
+		}
+		for name, data := range inputs {
+			t.Run(name, func(t *testing.T) {
+				configs, err := parseConfigList(data)
+				if !errors.Is(err, ErrInvalidConfigList) {
+					t.Fatal("unexpected error", err)
+				}
+				if len(configs) != 0 {
+					t.Fatal("expected no configs")
+				}
+			})
+		}
+	})
+}
+
+func TestNewExtension(t *testing.T) {
+	t.Run("with an empty config list we generate GREASE", func(t *testing.T) {
+		payload, publicName, err := NewExtension(nil, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if publicName != "" {
+			t.Fatal("expected empty public name")
+		}
+		kdf, aead, _ := parseTestExtensionPayload(t, payload)
+		if kdf != uint16(hpke.KDF_HKDF_SHA256) || aead != uint16(hpke.AEAD_AES128GCM) {
+			t.Fatal("unexpected cipher suite")
+		}
+	})
+
+	t.Run("with a valid config list", func(t *testing.T) {
+		data := newTestConfigList(t, ConfigVersion, 0x0020, "public.example.com")
+		payload, publicName, err := NewExtension(data, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if publicName != "public.example.com" {
+			t.Fatal("unexpected public name", publicName)
+		}
+		if _, _, configID := parseTestExtensionPayload(t, payload); configID != 7 {
+			t.Fatal("unexpected config ID", configID)
+		}
+	})
+
+	t.Run("with an unsupported KEM", func(t *testing.T) {
+		data := newTestConfigList(t, ConfigVersion, 0xffff, "public.example.com")
+		payload, _, err := NewExtension(data, rand.Reader)
+		if !errors.Is(err, ErrNoSupportedConfig) {
+			t.Fatal("unexpected error", err)
+		}
+		if payload != nil {
+			t.Fatal("expected nil payload")
+		}
+	})
+
+	t.Run("with a public key not matching the KEM", func(t *testing.T) {
+		data := newTestConfigList(t, ConfigVersion, uint16(hpke.KEM_P256_HKDF_SHA256), "public.example.com")
+		payload, _, err := NewExtension(data, rand.Reader)
+		if !errors.Is(err, ErrInvalidConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+		if payload != nil {
+			t.Fatal("expected nil payload")
+		}
+	})
+
+	t.Run("with an invalid config list", func(t *testing.T) {
+		payload, _, err := NewExtension([]byte{0, 1}, rand.Reader)
+		if !errors.Is(err, ErrInvalidConfigList) {
+			t.Fatal("unexpected error", err)
+		}
+		if payload != nil {
+			t.Fatal("expected nil payload")
+		}
+	})
+
+	t.Run("when the random source fails", func(t *testing.T) {
+		payload, _, err := NewExtension(nil, bytes.NewReader(nil))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if payload != nil {
+			t.Fatal("expected nil payload")
+		}
+	})
+}
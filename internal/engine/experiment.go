@@ -8,13 +8,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/pcapx"
 	"github.com/ooni/probe-cli/v3/internal/probeservices"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 	"github.com/ooni/probe-cli/v3/internal/version"
@@ -28,6 +33,7 @@ func formatTimeNowUTC() string {
 
 // experiment implements Experiment.
 type experiment struct {
+	budget        *bytecounter.Budget
 	byteCounter   *bytecounter.Counter
 	callbacks     model.ExperimentCallbacks
 	measurer      model.ExperimentMeasurer
@@ -40,7 +46,7 @@ type experiment struct {
 
 // newExperiment creates a new experiment given a measurer.
 func newExperiment(sess *Session, measurer model.ExperimentMeasurer) *experiment {
-	return &experiment{
+	exp := &experiment{
 		byteCounter:   bytecounter.New(),
 		callbacks:     model.NewPrinterCallbacks(sess.Logger()),
 		measurer:      measurer,
@@ -49,6 +55,10 @@ func newExperiment(sess *Session, measurer model.ExperimentMeasurer) *experiment
 		testStartTime: formatTimeNowUTC(),
 		testVersion:   measurer.ExperimentVersion(),
 	}
+	if sess.experimentDataBudget > 0 {
+		exp.budget = bytecounter.NewBudget(bytecounter.New(), sess.experimentDataBudget)
+	}
+	return exp
 }
 
 // KibiBytesReceived implements Experiment.KibiBytesReceived.
@@ -116,35 +126,67 @@ func (eaw *experimentAsyncWrapper) RunAsync(
 	return out, nil
 }
 
-// MeasureAsync implements Experiment.MeasureAsync.
+// MeasureAsync implements Experiment.MeasureAsync. We do not emit the measurements
+// collected after exhausting a data usage budget, since they are most likely
+// incomplete, so that the caller does not submit them.
 func (e *experiment) MeasureAsync(
 	ctx context.Context, input string) (<-chan *model.Measurement, error) {
+	out, _, err := e.measureAsync(ctx, input)
+	return out, err
+}
+
+// measureAsync implements MeasureAsync and MeasureWithContext. The second return
+// value is the conn wrapper enforcing the data usage budgets, which tells us
+// whether we exhausted a budget while measuring, or nil if there are no budgets.
+func (e *experiment) measureAsync(ctx context.Context,
+	input string) (<-chan *model.Measurement, *bytecounter.BudgetConnWrapper, error) {
 	err := e.session.MaybeLookupLocationContext(ctx) // this already tracks session bytes
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	ctx = bytecounter.WithSessionByteCounter(ctx, e.session.byteCounter)
 	ctx = bytecounter.WithExperimentByteCounter(ctx, e.byteCounter)
+	ctx = measurexlite.ContextWithTraceConfig(ctx, &measurexlite.TraceConfig{
+		Logger:     e.session.Logger(),
+		Subscriber: e.session.traceSubscriber,
+	})
+	budgetWrapper := bytecounter.NewBudgetConnWrapper(e.budget, e.session.budget)
+	if budgetWrapper != nil {
+		if err := budgetWrapper.CheckNewConn(); err != nil {
+			return nil, nil, err // don't start measuring when we've exhausted a budget
+		}
+		// The netxlite dialers used while measuring will count bytes and fail
+		// I/O once we exhaust the budgets. Note that the session HTTP transport
+		// unbinds the budgets, so we don't account for the backend traffic.
+		ctx = netxlite.ContextWithConnWrapper(ctx, budgetWrapper)
+	}
 	var async model.ExperimentMeasurerAsync
 	if v, okay := e.measurer.(model.ExperimentMeasurerAsync); okay {
 		async = v
 	} else {
 		async = &experimentAsyncWrapper{e}
 	}
-	in, err := async.RunAsync(ctx, e.session, input, e.callbacks)
+	in, pcapngFile, err := e.runAsyncMaybeCapture(ctx, async, input)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	out := make(chan *model.Measurement)
 	go func() {
 		defer close(out) // we need to signal the consumer we're done
 		for tk := range in {
+			if budgetWrapper != nil && budgetWrapper.Exceeded() {
+				e.session.Logger().Warn("discarding measurement truncated by the data usage budget")
+				continue
+			}
 			measurement := e.newMeasurement(input)
 			measurement.Extensions = tk.Extensions
 			measurement.Input = tk.Input
 			measurement.MeasurementRuntime = tk.MeasurementRuntime
 			measurement.TestHelpers = tk.TestHelpers
 			measurement.TestKeys = tk.TestKeys
+			if pcapngFile != "" {
+				measurement.AddAnnotation("pcapng_file", pcapngFile)
+			}
 			if err := model.ScrubMeasurement(measurement, e.session.ProbeIP()); err != nil {
 				// If we fail to scrub the measurement then we are not going to
 				// submit it. Most likely causes of error here are unlikely,
@@ -155,14 +197,56 @@ func (e *experiment) MeasureAsync(
 			out <- measurement
 		}
 	}()
-	return out, nil
+	return out, budgetWrapper, nil
+}
+
+// experimentCaptureTimeFormat is the time format used to name pcapng files.
+const experimentCaptureTimeFormat = "20060102T150405.000000Z"
+
+// runAsyncMaybeCapture calls async.RunAsync. If the session has a capture
+// directory, this function also captures the traffic using pcapx and writes
+// it to a pcapng file inside such a directory, along with the TLS key log
+// of the handshakes performed meanwhile. In such a case, the second return
+// value is the name of the pcapng file relative to the directory.
+//
+// Because we bind the capture to the context used for measuring, we only see
+// the traffic of the conns created by netxlite dialers using such a context,
+// thus excluding other concurrent measurements and the traffic with the OONI
+// backend (see sessionHTTPTransportWithoutConnWrappers). Also, we only write
+// the traffic generated before RunAsync returns, which is all the traffic
+// for sync experiments, and we drop packets once the capture is full.
+func (e *experiment) runAsyncMaybeCapture(ctx context.Context,
+	async model.ExperimentMeasurerAsync, input string) (<-chan *model.ExperimentAsyncTestKeys, string, error) {
+	if e.session.captureDir == "" {
+		in, err := async.RunAsync(ctx, e.session, input, e.callbacks)
+		return in, "", err
+	}
+	capture := pcapx.NewCapture()
+	ctx = netxlite.ContextWithConnWrapper(ctx, capture)
+	ctx = netxlite.ContextWithKeyLogWriter(ctx, capture.KeyLogWriter())
+	in, err := async.RunAsync(ctx, e.session, input, e.callbacks)
+	if err != nil {
+		return nil, "", err
+	}
+	if dropped := capture.Dropped(); dropped > 0 {
+		e.session.Logger().Warnf("the pcapng file lacks %d packets because the capture is full", dropped)
+	}
+	filename := fmt.Sprintf(
+		"%s-%s.pcapng", time.Now().UTC().Format(experimentCaptureTimeFormat), e.testName)
+	if err := capture.WriteFile(filepath.Join(e.session.captureDir, filename)); err != nil {
+		// Not being able to write the capture is not a good reason to
+		// throw away the measurement, so we just warn the user.
+		e.session.Logger().Warnf("can't write pcapng file: %s", err.Error())
+		return in, "", nil
+	}
+	return in, filename, nil
 }
 
 // MeasureWithContext implements Experiment.MeasureWithContext.
 func (e *experiment) MeasureWithContext(
 	ctx context.Context, input string,
 ) (measurement *model.Measurement, err error) {
-	out, err := e.MeasureAsync(ctx, input)
+	out, budgetWrapper, err := e.measureAsync(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +255,10 @@ func (e *experiment) MeasureWithContext(
 			measurement = m // as documented just return the first one
 		}
 	}
-	if measurement == nil {
+	switch {
+	case measurement == nil && budgetWrapper != nil && budgetWrapper.Exceeded():
+		err = fmt.Errorf("%w: discarded the incomplete measurement", bytecounter.ErrBudgetExceeded)
+	case measurement == nil:
 		err = errors.New("experiment returned no measurements")
 	}
 	return
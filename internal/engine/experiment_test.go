@@ -1,10 +1,25 @@
 package engine
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/geolocate"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/pcapx"
 )
 
 func TestExperimentHonoursSharingDefaults(t *testing.T) {
@@ -73,3 +88,307 @@ func TestExperimentHonoursSharingDefaults(t *testing.T) {
 		})
 	}
 }
+
+func TestExperimentHonoursDataBudgets(t *testing.T) {
+	newSession := func(experimentDataBudget int64) *Session {
+		return &Session{
+			byteCounter:          bytecounter.New(),
+			experimentDataBudget: experimentDataBudget,
+			location:             &geolocate.Results{},
+			logger:               model.DiscardLogger,
+		}
+	}
+
+	t.Run("when we have exhausted the session budget", func(t *testing.T) {
+		sess := newSession(0)
+		sess.budget = bytecounter.NewBudget(bytecounter.New(), 1024)
+		sess.budget.Counter.CountBytesReceived(1024)
+		builder, err := sess.NewExperimentBuilder("example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := builder.NewExperiment()
+		meas, err := exp.MeasureWithContext(context.Background(), "")
+		if !errors.Is(err, bytecounter.ErrBudgetExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+		if meas != nil {
+			t.Fatal("expected nil measurement")
+		}
+	})
+
+	t.Run("when we have exhausted the experiment budget", func(t *testing.T) {
+		sess := newSession(1024)
+		builder, err := sess.NewExperimentBuilder("example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := builder.NewExperiment().(*experiment)
+		if exp.budget == nil || exp.budget.Counter == exp.byteCounter {
+			t.Fatal("expected the experiment budget to use a dedicated byte counter")
+		}
+		exp.budget.Counter.CountBytesSent(1024)
+		meas, err := exp.MeasureWithContext(context.Background(), "")
+		if !errors.Is(err, bytecounter.ErrBudgetExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+		if meas != nil {
+			t.Fatal("expected nil measurement")
+		}
+	})
+}
+
+// budgetMeasurer is a measurexlite-based measurer that reads all
+// the data sent by a server, used to test data usage budgets.
+type budgetMeasurer struct {
+	address string
+	err     error
+}
+
+func (m *budgetMeasurer) ExperimentName() string {
+	return "budget"
+}
+
+func (m *budgetMeasurer) ExperimentVersion() string {
+	return "0.1.0"
+}
+
+func (m *budgetMeasurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	trace := measurexlite.NewTraceWithContext(ctx, 0, time.Now())
+	dialer := trace.NewDialerWithoutResolver(model.DiscardLogger)
+	conn, err := dialer.DialContext(ctx, "tcp", m.address)
+	if err != nil {
+		m.err = err
+		return nil
+	}
+	defer conn.Close()
+	_, m.err = io.ReadAll(conn)
+	args.Measurement.TestKeys = map[string]any{"network_events": trace.NetworkEvents()}
+	return nil
+}
+
+func (m *budgetMeasurer) GetSummaryKeys(*model.Measurement) (interface{}, error) {
+	return nil, nil
+}
+
+// newBudgetServer starts a server sending 1 MiB to each client and
+// returns the server's endpoint. The server runs until the test ends.
+func newBudgetServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write(make([]byte, 1<<20))
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// newBudgetSession returns a session whose experiments can use 16 KiB.
+func newBudgetSession() *Session {
+	sess := &Session{
+		byteCounter:          bytecounter.New(),
+		experimentDataBudget: 16384,
+		location:             &geolocate.Results{ProbeIP: "130.192.91.211"},
+		logger:               model.DiscardLogger,
+	}
+	sess.budget = bytecounter.NewBudget(bytecounter.New(), 1<<22)
+	return sess
+}
+
+func TestExperimentEnforcesDataBudgetsWhileMeasuring(t *testing.T) {
+	sess := newBudgetSession()
+	measurer := &budgetMeasurer{address: newBudgetServer(t)}
+	exp := newExperiment(sess, measurer)
+
+	// the measurement is incomplete, so we discard it
+	meas, err := exp.MeasureWithContext(context.Background(), "")
+	if !errors.Is(err, bytecounter.ErrBudgetExceeded) {
+		t.Fatal("unexpected error", err)
+	}
+	if meas != nil {
+		t.Fatal("expected nil measurement")
+	}
+	if !errors.Is(measurer.err, bytecounter.ErrBudgetExceeded) {
+		t.Fatal("unexpected error", measurer.err)
+	}
+	if used := exp.budget.Used(); used < 16384 || used >= 1<<20 {
+		t.Fatal("unexpected experiment budget usage", used)
+	}
+	if sess.budget.Used() != exp.budget.Used() {
+		t.Fatal("the session budget should have counted the same bytes")
+	}
+
+	// we should now refuse to run new measurements
+	meas, err = exp.MeasureWithContext(context.Background(), "")
+	if !errors.Is(err, bytecounter.ErrBudgetExceeded) {
+		t.Fatal("unexpected error", err)
+	}
+	if meas != nil {
+		t.Fatal("expected nil measurement")
+	}
+}
+
+func TestExperimentDoesNotSubmitMeasurementsTruncatedByDataBudgets(t *testing.T) {
+	sess := newBudgetSession()
+	exp := newExperiment(sess, &budgetMeasurer{address: newBudgetServer(t)})
+	saver := &FakeInputProcessorSaver{}
+	submitter := &FakeInputProcessorSubmitter{}
+	ip := &InputProcessor{
+		Experiment: NewInputProcessorExperimentWrapper(exp),
+		Inputs:     []model.OOAPIURLInfo{{URL: ""}},
+		Saver:      NewInputProcessorSaverWrapper(saver),
+		Submitter:  NewInputProcessorSubmitterWrapper(submitter),
+	}
+	if err := ip.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(submitter.M) != 0 || len(saver.M) != 0 {
+		t.Fatal("expected no submitted or saved measurements")
+	}
+	if exp.budget.Remaining() != 0 {
+		t.Fatal("expected the measurement to exhaust the budget")
+	}
+}
+
+func TestExperimentDeliversTraceEventsToSubscriber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("hello"))
+			conn.Close()
+		}
+	}()
+
+	var (
+		events []string
+		mu     sync.Mutex
+	)
+	logger := &mocks.Logger{
+		MockDebugf: func(format string, v ...interface{}) {
+			defer mu.Unlock()
+			mu.Lock()
+			events = append(events, fmt.Sprintf(format, v...))
+		},
+	}
+	sess := &Session{
+		byteCounter:     bytecounter.New(),
+		location:        &geolocate.Results{ProbeIP: "130.192.91.211"},
+		logger:          model.DiscardLogger,
+		traceSubscriber: measurexlite.NewLoggerSubscriber(logger),
+	}
+	measurer := &budgetMeasurer{address: listener.Addr().String()}
+	exp := newExperiment(sess, measurer)
+	if _, err := exp.MeasureWithContext(context.Background(), ""); err != nil {
+		t.Fatal(err)
+	}
+	defer mu.Unlock()
+	mu.Lock()
+	if len(events) <= 0 {
+		t.Fatal("the subscriber did not receive any event")
+	}
+}
+
+func TestExperimentCapturesTraffic(t *testing.T) {
+	newSession := func(captureDir string) *Session {
+		return &Session{
+			byteCounter: bytecounter.New(),
+			captureDir:  captureDir,
+			location:    &geolocate.Results{ProbeIP: "130.192.91.211"},
+			logger:      model.DiscardLogger,
+		}
+	}
+
+	measure := func(t *testing.T, sess *Session) *model.Measurement {
+		builder, err := sess.NewExperimentBuilder("example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		builder.SetOptionAny("SleepTime", 0)
+		meas, err := builder.NewExperiment().MeasureWithContext(context.Background(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return meas
+	}
+
+	t.Run("when the capture directory is set", func(t *testing.T) {
+		dir := t.TempDir()
+		meas := measure(t, newSession(dir))
+		filename := meas.Annotations["pcapng_file"]
+		if !strings.HasSuffix(filename, "-example.pcapng") {
+			t.Fatal("unexpected pcapng file", filename)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) <= 0 {
+			t.Fatal("expected a non-empty pcapng file")
+		}
+	})
+
+	t.Run("when the measurement generates traffic", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("hello"))
+			conn.Close()
+		}()
+		dir := t.TempDir()
+		measurer := &budgetMeasurer{address: listener.Addr().String()}
+		meas, err := newExperiment(newSession(dir), measurer).MeasureWithContext(context.Background(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, meas.Annotations["pcapng_file"]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		empty := &bytes.Buffer{}
+		if err := pcapx.NewCapture().WritePcapng(empty); err != nil {
+			t.Fatal(err)
+		}
+		if len(data) <= empty.Len() {
+			t.Fatal("expected to see the measurement traffic")
+		}
+	})
+
+	t.Run("when the capture directory is not set", func(t *testing.T) {
+		meas := measure(t, newSession(""))
+		if _, found := meas.Annotations["pcapng_file"]; found {
+			t.Fatal("did not expect a pcapng file")
+		}
+	})
+
+	t.Run("when we cannot write the pcapng file", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "nonexistent")
+		meas := measure(t, newSession(dir))
+		if _, found := meas.Annotations["pcapng_file"]; found {
+			t.Fatal("did not expect a pcapng file")
+		}
+	})
+}
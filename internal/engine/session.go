@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -15,6 +16,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/checkincache"
 	"github.com/ooni/probe-cli/v3/internal/geolocate"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 	"github.com/ooni/probe-cli/v3/internal/platform"
@@ -38,10 +40,50 @@ type SessionConfig struct {
 	TorArgs                []string
 	TorBinary              string
 
+	// CaptureDir is the OPTIONAL directory where to write a pcapng file
+	// containing the traffic generated by each measurement, as well as the
+	// TLS key log required to decrypt it. When this field is empty, which
+	// is the default, we do not capture traffic.
+	CaptureDir string
+
+	// DataBudget is the OPTIONAL maximum number of bytes (sent plus received)
+	// that the experiments run by this session may use. Zero means no limit. To
+	// implement a daily budget, use the daily budget minus the bytes already used
+	// today. We count and enforce this budget for the conns that the netxlite dialers
+	// create while measuring, which includes the measurexlite traces. We neither count
+	// nor enforce it when communicating with the OONI backend, so that we can always
+	// submit the measurements we have collected.
+	DataBudget int64
+
+	// ExperimentDataBudget is like DataBudget but applies to each experiment.
+	ExperimentDataBudget int64
+
+	// KeyLogFile is the OPTIONAL file where to append the TLS key log of
+	// the TLS and QUIC handshakes, using the NSS key log format (i.e., the
+	// format used with SSLKEYLOGFILE). When both this field and KeyLogWriter
+	// are empty, which is the default, we do not write the key log.
+	KeyLogFile string
+
+	// KeyLogWriter is like KeyLogFile but writes the TLS key log to the
+	// given io.Writer, e.g., a *netxlite.KeyLogBuffer to keep it in memory.
+	KeyLogWriter io.Writer
+
+	// PTBinary is the OPTIONAL external pluggable transport
+	// binary to be used by the ptx tunnel
+	PTBinary string
+
+	// PTBridge is the bridge line to be used by the ptx tunnel
+	PTBridge string
+
 	// SnowflakeRendezvous is the rendezvous method
 	// to be used by the torsf tunnel
 	SnowflakeRendezvous string
 
+	// TraceSubscriber is the OPTIONAL measurexlite.Subscriber receiving the
+	// events collected by the traces that experiments create while measuring
+	// as soon as they occur (e.g., to show live progress).
+	TraceSubscriber measurexlite.Subscriber
+
 	// TunnelDir is the directory where we should store
 	// the state of persistent tunnels. This field is
 	// optional _unless_ you want to use tunnels. In such
@@ -57,6 +99,7 @@ type SessionConfig struct {
 type Session struct {
 	availableProbeServices   []model.OOAPIService
 	availableTestHelpers     map[string][]model.OOAPIService
+	budget                   *bytecounter.Budget
 	byteCounter              *bytecounter.Counter
 	httpDefaultTransport     model.HTTPTransport
 	kvStore                  model.KeyValueStore
@@ -71,9 +114,24 @@ type Session struct {
 	softwareVersion          string
 	tempDir                  string
 
+	// captureDir is the directory where to write pcapng files or
+	// an empty string if we should not capture traffic.
+	captureDir string
+
 	// closeOnce allows us to call Close just once.
 	closeOnce sync.Once
 
+	// experimentDataBudget is the data usage budget of each experiment
+	// expressed in bytes or zero if there's no such budget.
+	experimentDataBudget int64
+
+	// keyLogFile is the OPTIONAL file containing the TLS key log.
+	keyLogFile *os.File
+
+	// keyLogUnregister contains the functions to unregister the
+	// key log writers we have registered with netxlite.
+	keyLogUnregister []func()
+
 	// mu provides mutual exclusion.
 	mu sync.Mutex
 
@@ -102,6 +160,10 @@ type Session struct {
 	// may need to pass to urlgetter when it uses a tor tunnel.
 	torBinary string
 
+	// traceSubscriber is the OPTIONAL subscriber receiving the events of
+	// the traces that experiments create while measuring.
+	traceSubscriber measurexlite.Subscriber
+
 	// tunnelDir is the directory used by tunnels.
 	tunnelDir string
 
@@ -127,7 +189,7 @@ type sessionProbeServicesClientForCheckIn interface {
 // 3. Create an instance of the session.
 //
 // 4. If the user requested for a proxy that entails a tunnel (at the
-// moment of writing this note, either psiphon or tor), then start the
+// moment of writing this note, psiphon, tor, torsf, or ptx), then start the
 // requested tunnel and configure it as our proxy.
 //
 // 5. Create a compound resolver for the session that will attempt
@@ -139,6 +201,10 @@ type sessionProbeServicesClientForCheckIn interface {
 // we communicate with the OONI backends. This transport will be
 // using the configured proxy, if any.
 //
+// 7. If the user requested for a TLS key log, register the key log
+// file and/or writer with netxlite, such that all the subsequent TLS
+// and QUIC handshakes write their secrets into the key log.
+//
 // If any of these steps fails, then we cannot create a measurement
 // session and we return an error.
 func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
@@ -172,6 +238,8 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 	sess := &Session{
 		availableProbeServices:  config.AvailableProbeServices,
 		byteCounter:             bytecounter.New(),
+		captureDir:              config.CaptureDir,
+		experimentDataBudget:    config.ExperimentDataBudget,
 		kvStore:                 config.KVStore,
 		logger:                  config.Logger,
 		queryProbeServicesCount: &atomic.Int64{},
@@ -180,17 +248,23 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 		tempDir:                 tempDir,
 		torArgs:                 config.TorArgs,
 		torBinary:               config.TorBinary,
+		traceSubscriber:         config.TraceSubscriber,
 		tunnelDir:               config.TunnelDir,
 	}
+	if config.DataBudget > 0 {
+		sess.budget = bytecounter.NewBudget(bytecounter.New(), config.DataBudget)
+	}
 	proxyURL := config.ProxyURL
 	if proxyURL != nil {
 		switch proxyURL.Scheme {
-		case "psiphon", "tor", "torsf", "fake":
+		case "psiphon", "tor", "torsf", "ptx", "fake":
 			config.Logger.Infof(
 				"starting '%s' tunnel; please be patient...", proxyURL.Scheme)
 			tunnel, _, err := tunnel.Start(ctx, &tunnel.Config{
 				Logger:              config.Logger,
 				Name:                proxyURL.Scheme,
+				PTBinary:            config.PTBinary,
+				PTBridge:            config.PTBridge,
 				SnowflakeRendezvous: config.SnowflakeRendezvous,
 				Session:             &sessionTunnelEarlySession{},
 				TorArgs:             config.TorArgs,
@@ -218,10 +292,34 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 		sess.logger, sess.resolver, sess.proxyURL,
 	)
 	txp = bytecounter.WrapHTTPTransport(txp, sess.byteCounter)
-	sess.httpDefaultTransport = txp
+	sess.httpDefaultTransport = &sessionHTTPTransportWithoutConnWrappers{txp}
+	if err := sess.maybeRegisterKeyLog(&config); err != nil {
+		sess.doClose()
+		return nil, err
+	}
 	return sess, nil
 }
 
+// maybeRegisterKeyLog registers the configured key log writer and file, if any,
+// such that they receive the TLS key log. To guard against including the key
+// log into measurements, we do not expose these writers to experiments.
+func (s *Session) maybeRegisterKeyLog(config *SessionConfig) error {
+	if config.KeyLogWriter != nil {
+		s.keyLogUnregister = append(
+			s.keyLogUnregister, netxlite.RegisterKeyLogWriter(config.KeyLogWriter))
+	}
+	if config.KeyLogFile != "" {
+		filep, err := os.OpenFile(config.KeyLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		s.keyLogFile = filep
+		s.keyLogUnregister = append(s.keyLogUnregister, netxlite.RegisterKeyLogWriter(filep))
+		s.logger.Warnf("writing the TLS key log to %s", config.KeyLogFile)
+	}
+	return nil
+}
+
 // TunnelDir returns the persistent directory used by tunnels.
 func (s *Session) TunnelDir() string {
 	return s.tunnelDir
@@ -238,6 +336,34 @@ func (s *Session) KibiBytesSent() float64 {
 	return s.byteCounter.KibiBytesSent()
 }
 
+// sessionHTTPTransportWithoutConnWrappers is the session's default HTTP transport,
+// which we use to communicate with the OONI backend. Because experiments use
+// this transport to talk to test helpers, it may receive contexts carrying a
+// data usage budget or a traffic capture. We unbind them from such contexts such
+// that we can always communicate with the backend (e.g., to submit measurements)
+// and we do not capture the backend traffic and TLS key log.
+type sessionHTTPTransportWithoutConnWrappers struct {
+	model.HTTPTransport
+}
+
+// RoundTrip implements model.HTTPTransport.
+func (txp *sessionHTTPTransportWithoutConnWrappers) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := netxlite.ContextWithoutConnWrapper(req.Context())
+	ctx = netxlite.ContextWithoutKeyLogWriter(ctx)
+	return txp.HTTPTransport.RoundTrip(req.WithContext(ctx))
+}
+
+// RemainingDataBudget returns the bytes that this session's experiments can
+// still use and whether we're using a data usage budget at all. We only
+// account for the bytes exchanged by conns created by netxlite dialers
+// while measuring, thus excluding the traffic with the OONI backend.
+func (s *Session) RemainingDataBudget() (int64, bool) {
+	if s.budget == nil {
+		return 0, false
+	}
+	return s.budget.Remaining(), true
+}
+
 // CheckIn calls the check-in API. The input arguments MUST NOT
 // be nil. Before querying the API, this function will ensure
 // that the config structure does not contain any field that
@@ -346,6 +472,12 @@ func (s *Session) doClose() {
 	if s.tunnel != nil {
 		s.tunnel.Stop()
 	}
+	for _, unregister := range s.keyLogUnregister {
+		unregister()
+	}
+	if s.keyLogFile != nil {
+		s.keyLogFile.Close()
+	}
 	_ = os.RemoveAll(s.tempDir)
 }
 
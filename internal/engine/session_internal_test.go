@@ -1,21 +1,32 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/apex/log"
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/checkincache"
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
 	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivitylte"
 	"github.com/ooni/probe-cli/v3/internal/geolocate"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/pcapx"
 	"github.com/ooni/probe-cli/v3/internal/registry"
 )
 
@@ -401,3 +412,175 @@ func TestSessionNewExperimentBuilder(t *testing.T) {
 		}
 	})
 }
+
+func TestSessionRemainingDataBudget(t *testing.T) {
+	newSession := func(t *testing.T, dataBudget int64) *Session {
+		sess, err := NewSession(context.Background(), SessionConfig{
+			DataBudget:      dataBudget,
+			Logger:          model.DiscardLogger,
+			SoftwareName:    "miniooni",
+			SoftwareVersion: "0.1.0-dev",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sess
+	}
+
+	t.Run("without a data budget", func(t *testing.T) {
+		sess := newSession(t, 0)
+		defer sess.Close()
+		if _, limited := sess.RemainingDataBudget(); limited {
+			t.Fatal("expected no data budget")
+		}
+	})
+
+	t.Run("with a data budget", func(t *testing.T) {
+		sess := newSession(t, 4096)
+		defer sess.Close()
+		sess.budget.Counter.CountBytesSent(1024)
+		sess.byteCounter.CountBytesSent(1024) // backend traffic does not count
+		remaining, limited := sess.RemainingDataBudget()
+		if !limited {
+			t.Fatal("expected a data budget")
+		}
+		if remaining != 3072 {
+			t.Fatal("unexpected remaining budget", remaining)
+		}
+	})
+}
+
+func TestSessionHTTPTransportIgnoresConnWrappers(t *testing.T) {
+	srvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srvr.Close()
+	sess, err := NewSession(context.Background(), SessionConfig{
+		DataBudget:      1024,
+		Logger:          model.DiscardLogger,
+		SoftwareName:    "miniooni",
+		SoftwareVersion: "0.1.0-dev",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	sess.budget.Counter.CountBytesReceived(1024)
+	ctx := netxlite.ContextWithConnWrapper(
+		context.Background(), bytecounter.NewBudgetConnWrapper(sess.budget))
+	capture := pcapx.NewCapture()
+	ctx = netxlite.ContextWithConnWrapper(ctx, capture)
+	req, err := http.NewRequestWithContext(ctx, "GET", srvr.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := sess.DefaultHTTPClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if sess.budget.Used() != 1024 {
+		t.Fatal("the backend traffic should not count", sess.budget.Used())
+	}
+	captured, empty := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := capture.WritePcapng(captured); err != nil {
+		t.Fatal(err)
+	}
+	if err := pcapx.NewCapture().WritePcapng(empty); err != nil {
+		t.Fatal(err)
+	}
+	if captured.Len() != empty.Len() {
+		t.Fatal("we should not capture the backend traffic")
+	}
+}
+
+func TestSessionKeyLog(t *testing.T) {
+	// handshake performs a TLS handshake with a local server using netxlite.
+	handshake := func(t *testing.T) {
+		srvr := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srvr.Close()
+		URL, err := url.Parse(srvr.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := net.Dial("tcp", URL.Host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		handshaker := netxlite.NewTLSHandshakerStdlib(model.DiscardLogger)
+		config := &tls.Config{InsecureSkipVerify: true, ServerName: URL.Hostname()}
+		tlsConn, _, err := handshaker.Handshake(context.Background(), conn, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tlsConn.Close()
+	}
+
+	newSession := func(keyLogFile string, keyLogWriter io.Writer) (*Session, error) {
+		return NewSession(context.Background(), SessionConfig{
+			KeyLogFile:      keyLogFile,
+			KeyLogWriter:    keyLogWriter,
+			Logger:          model.DiscardLogger,
+			SoftwareName:    "ooniprobe-engine",
+			SoftwareVersion: "0.0.1",
+		})
+	}
+
+	t.Run("with a key log writer", func(t *testing.T) {
+		kb := &netxlite.KeyLogBuffer{}
+		sess, err := newSession("", kb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handshake(t)
+		if len(kb.Bytes()) <= 0 {
+			t.Fatal("expected the key log to contain secrets")
+		}
+		sess.Close()
+		length := len(kb.Bytes())
+		handshake(t)
+		if len(kb.Bytes()) != length {
+			t.Fatal("expected Close to unregister the key log writer")
+		}
+	})
+
+	t.Run("with a key log file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "keylog.txt")
+		sess, err := newSession(filename, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handshake(t)
+		sess.Close()
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) <= 0 {
+			t.Fatal("expected the key log file to contain secrets")
+		}
+	})
+
+	t.Run("when we cannot open the key log file", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "nonexistent", "keylog.txt")
+		sess, err := newSession(filename, nil)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if sess != nil {
+			t.Fatal("expected nil session")
+		}
+	})
+
+	t.Run("without a key log", func(t *testing.T) {
+		sess, err := newSession("", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		if len(sess.keyLogUnregister) != 0 || sess.keyLogFile != nil {
+			t.Fatal("expected no key log")
+		}
+	})
+}
@@ -2,13 +2,13 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"sort"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/multierror"
 )
 
-// TODO(bassosimone): maybe keep track of which measurements
-// could not be submitted by a specific submitter?
-
 // Submitter is an alias for model.Submitter
 type Submitter = model.Submitter
 
@@ -28,20 +28,46 @@ type SubmitterConfig struct {
 
 	// Logger is the logger to be used.
 	Logger model.Logger
+
+	// Extra contains OPTIONAL extra submitters (see the submitter package)
+	// to which we submit measurements regardless of Enabled. The key is the
+	// name identifying the submitter in a SubmissionError.
+	Extra map[string]Submitter
 }
 
+// CollectorSubmitterName is the name identifying the OONI collector
+// in a SubmissionError.
+const CollectorSubmitterName = "collector"
+
 // NewSubmitter creates a new submitter instance. Depending on
 // whether submission is enabled or not, the returned submitter
 // instance migh just be a stub implementation.
 func NewSubmitter(ctx context.Context, config SubmitterConfig) (Submitter, error) {
-	if !config.Enabled {
-		return stubSubmitter{}, nil
+	var all multiSubmitter
+	if config.Enabled {
+		subm, err := config.Session.NewSubmitter(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, &namedSubmitter{
+			name: CollectorSubmitterName,
+			subm: realSubmitter{subm: subm, logger: config.Logger},
+		})
 	}
-	subm, err := config.Session.NewSubmitter(ctx)
-	if err != nil {
-		return nil, err
+	var names []string
+	for name := range config.Extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		all = append(all, &namedSubmitter{name: name, subm: config.Extra[name]})
+	}
+	if len(all) <= 0 {
+		return stubSubmitter{}, nil
 	}
-	return realSubmitter{subm: subm, logger: config.Logger}, nil
+	// We return a multiSubmitter even when there is a single submitter, so
+	// that the submitqueue package knows where we delivered a measurement.
+	return all, nil
 }
 
 type stubSubmitter struct{}
@@ -61,3 +87,79 @@ func (rs realSubmitter) Submit(ctx context.Context, m *model.Measurement) error
 	rs.logger.Info("submitting measurement to OONI collector; please be patient...")
 	return rs.subm.Submit(ctx, m)
 }
+
+// namedSubmitter is a submitter with a name.
+type namedSubmitter struct {
+	name string
+	subm Submitter
+}
+
+// multiSubmitter submits to several submitters in sequence.
+type multiSubmitter []*namedSubmitter
+
+// ErrSubmissionFailed indicates that a multiSubmitter failed to
+// submit the measurement using one or more submitters.
+var ErrSubmissionFailed = errors.New("engine: measurement submission failed")
+
+// SubmissionError is the error returned by a submitter using several
+// submitters when one or more of them fail. It tells the caller which
+// submitters received the measurement, such that a retry does not
+// deliver the same measurement twice (see the submitqueue package).
+type SubmissionError struct {
+	// Delivered contains the names of the submitters that received the measurement.
+	Delivered []string
+
+	// Union contains the errors returned by the failed submitters.
+	Union *multierror.Union
+}
+
+// Error implements error.
+func (err *SubmissionError) Error() string {
+	return err.Union.Error()
+}
+
+// Unwrap returns the underlying multierror.Union.
+func (err *SubmissionError) Unwrap() error {
+	return err.Union
+}
+
+// DeliveredTo returns the names of the submitters that received the measurement.
+func (err *SubmissionError) DeliveredTo() []string {
+	return err.Delivered
+}
+
+// Submit implements Submitter. We submit to all the submitters even when
+// some of them fail, and we return a *SubmissionError containing the union
+// of all the errors. Because the OONI collector, if enabled, comes first,
+// extra submitters see the report ID it has assigned to the measurement.
+func (ms multiSubmitter) Submit(ctx context.Context, m *model.Measurement) error {
+	_, err := ms.SubmitSkipping(ctx, m, nil)
+	return err
+}
+
+// SubmitSkipping is like Submit but does not submit to the submitters whose
+// name is in skip and returns the names of the submitters that received the
+// measurement. This method implements submitqueue.SelectiveSubmitter.
+func (ms multiSubmitter) SubmitSkipping(
+	ctx context.Context, m *model.Measurement, skip []string) ([]string, error) {
+	skipped := map[string]bool{}
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	var delivered []string
+	union := multierror.New(ErrSubmissionFailed)
+	for _, ns := range ms {
+		if skipped[ns.name] {
+			continue
+		}
+		if err := ns.subm.Submit(ctx, m); err != nil {
+			union.AddWithPrefix(ns.name, err)
+			continue
+		}
+		delivered = append(delivered, ns.name)
+	}
+	if len(union.Children) > 0 {
+		return delivered, &SubmissionError{Delivered: delivered, Union: union}
+	}
+	return delivered, nil
+}
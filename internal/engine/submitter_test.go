@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -91,3 +92,88 @@ func TestNewSubmitterWithFailedSubmission(t *testing.T) {
 		t.Fatal("unexpected number of calls")
 	}
 }
+
+func TestNewSubmitterWithExtraSubmitters(t *testing.T) {
+	t.Run("with only extra submitters", func(t *testing.T) {
+		extra := &FakeSubmitter{Calls: &atomic.Int64{}}
+		submitter, err := NewSubmitter(context.Background(), SubmitterConfig{
+			Enabled: false,
+			Extra:   map[string]Submitter{"webhook=https://example.com/": extra},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms, ok := submitter.(multiSubmitter)
+		if !ok || len(ms) != 1 || ms[0].subm != extra {
+			t.Fatal("expected to get the extra submitter")
+		}
+	})
+
+	t.Run("we submit to all submitters and union the errors", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		collector := &FakeSubmitter{Calls: &atomic.Int64{}, Error: expected}
+		extras := map[string]Submitter{
+			"webhook=https://example.com/": &FakeSubmitter{Calls: &atomic.Int64{}},
+			"unix=unix:///tmp/ooni.sock":   &FakeSubmitter{Calls: &atomic.Int64{}},
+		}
+		submitter, err := NewSubmitter(context.Background(), SubmitterConfig{
+			Enabled: true,
+			Logger:  log.Log,
+			Session: FakeSubmitterSession{Submitter: collector},
+			Extra:   extras,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = submitter.Submit(context.Background(), new(model.Measurement))
+		if !errors.Is(err, ErrSubmissionFailed) || !errors.Is(err, expected) {
+			t.Fatalf("not the error we expected: %+v", err)
+		}
+		var serr *SubmissionError
+		if !errors.As(err, &serr) {
+			t.Fatal("expected a *SubmissionError")
+		}
+		expectDelivered := []string{"unix=unix:///tmp/ooni.sock", "webhook=https://example.com/"}
+		if diff := cmp.Diff(expectDelivered, serr.DeliveredTo()); diff != "" {
+			t.Fatal(diff)
+		}
+		if collector.Calls.Load() != 1 {
+			t.Fatal("unexpected number of calls")
+		}
+		for _, subm := range extras {
+			if subm.(*FakeSubmitter).Calls.Load() != 1 {
+				t.Fatal("unexpected number of calls")
+			}
+		}
+	})
+
+	t.Run("we return nil when all submitters succeed", func(t *testing.T) {
+		submitter := multiSubmitter{
+			{name: "a", subm: &FakeSubmitter{}},
+			{name: "b", subm: &FakeSubmitter{}},
+		}
+		if err := submitter.Submit(context.Background(), new(model.Measurement)); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("SubmitSkipping skips the given submitters", func(t *testing.T) {
+		first := &FakeSubmitter{Calls: &atomic.Int64{}}
+		second := &FakeSubmitter{Calls: &atomic.Int64{}}
+		submitter := multiSubmitter{
+			{name: CollectorSubmitterName, subm: first},
+			{name: "webhook=https://example.com/", subm: second},
+		}
+		delivered, err := submitter.SubmitSkipping(
+			context.Background(), new(model.Measurement), []string{CollectorSubmitterName})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"webhook=https://example.com/"}, delivered); diff != "" {
+			t.Fatal(diff)
+		}
+		if first.Calls.Load() != 0 || second.Calls.Load() != 1 {
+			t.Fatal("unexpected number of calls")
+		}
+	})
+}
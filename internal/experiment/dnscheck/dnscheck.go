@@ -23,7 +23,7 @@ import (
 
 const (
 	testName      = "dnscheck"
-	testVersion   = "0.9.2"
+	testVersion   = "0.9.3"
 	defaultDomain = "example.org"
 )
 
@@ -155,7 +155,7 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		return fmt.Errorf("%w: %s", ErrInvalidURL, err.Error())
 	}
 	switch URL.Scheme {
-	case "https", "dot", "udp", "tcp":
+	case "https", "dot", "udp", "tcp", "quic":
 		// all good
 	default:
 		return ErrUnsupportedURLScheme
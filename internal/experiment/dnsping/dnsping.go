@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"strings"
 	"sync"
 	"time"
 
@@ -24,11 +23,11 @@ const (
 
 // Config contains the experiment configuration.
 type Config struct {
-	// Delay is the delay between each repetition (in milliseconds).
-	Delay int64 `ooni:"number of milliseconds to wait before sending each ping"`
+	// Delay is the delay between each repetition.
+	Delay time.Duration `ooni:"time to wait before sending each ping (e.g., 500ms)"`
 
-	// Domains is the space-separated list of domains to measure.
-	Domains string `ooni:"space-separated list of domains to measure"`
+	// Domains is the list of domains to measure.
+	Domains []string `ooni:"list of domains to measure"`
 
 	// Repetitions is the number of repetitions for each ping.
 	Repetitions int64 `ooni:"number of times to repeat the measurement"`
@@ -36,7 +35,7 @@ type Config struct {
 
 func (c *Config) delay() time.Duration {
 	if c.Delay > 0 {
-		return time.Duration(c.Delay) * time.Millisecond
+		return c.Delay
 	}
 	return time.Second
 }
@@ -48,11 +47,11 @@ func (c Config) repetitions() int64 {
 	return 10
 }
 
-func (c Config) domains() string {
-	if c.Domains != "" {
+func (c Config) domains() []string {
+	if len(c.Domains) > 0 {
 		return c.Domains
 	}
-	return "edge-chat.instagram.com example.com"
+	return []string{"edge-chat.instagram.com", "example.com"}
 }
 
 // Measurer performs the measurement.
@@ -104,7 +103,7 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	}
 	tk := NewTestKeys()
 	measurement.TestKeys = tk
-	domains := strings.Split(m.config.domains(), " ")
+	domains := m.config.domains()
 	wg := new(sync.WaitGroup)
 	wg.Add(len(domains))
 	for _, domain := range domains {
@@ -135,7 +134,7 @@ func (m *Measurer) dnsRoundTrip(ctx context.Context, index int64, zeroTime time.
 	defer cancel()
 	defer wg.Done()
 	pings := []*SinglePing{}
-	trace := measurexlite.NewTrace(index, zeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
 	ol := measurexlite.NewOperationLogger(logger, "DNSPing #%d %s %s", index, address, domain)
 	// TODO(bassosimone, DecFox): what should we do if the user passes us a resolver with a
 	// domain name in terms of saving its results? Shall we save also the system resolver's lookups?
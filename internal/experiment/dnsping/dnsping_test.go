@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -17,7 +18,7 @@ import (
 
 func TestConfig_domains(t *testing.T) {
 	c := Config{}
-	if c.domains() != "edge-chat.instagram.com example.com" {
+	if diff := cmp.Diff([]string{"edge-chat.instagram.com", "example.com"}, c.domains()); diff != "" {
 		t.Fatal("invalid default domains list")
 	}
 }
@@ -43,8 +44,8 @@ func TestMeasurer_run(t *testing.T) {
 	// runHelper is an helper function to run this set of tests.
 	runHelper := func(input string) (*model.Measurement, model.ExperimentMeasurer, error) {
 		m := NewExperimentMeasurer(Config{
-			Domains:     "example.com",
-			Delay:       1, // millisecond
+			Domains:     []string{"example.com"},
+			Delay:       time.Millisecond,
 			Repetitions: expectedPings,
 		})
 		if m.ExperimentName() != "dnsping" {
@@ -0,0 +1,9 @@
+// Package dslxrunner contains the dslxrunner experiment, which runs a
+// measurement pipeline described using JSON by building and running the
+// corresponding [github.com/ooni/probe-cli/v3/internal/dslx] functions.
+//
+// This experiment allows OONI Run descriptors to ship new measurement
+// recipes (e.g., resolve a domain using DoH and then perform TLS handshakes
+// with a specific ClientHello) without writing a new experiment. See the
+// [Pipeline] type for the structure of the JSON description.
+package dslxrunner
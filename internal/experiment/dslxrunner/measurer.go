@@ -0,0 +1,231 @@
+package dslxrunner
+
+//
+// Measurer implementation
+//
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ooni/probe-cli/v3/internal/dslx"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/tracex"
+)
+
+const (
+	testName    = "dslxrunner"
+	testVersion = "0.1.0"
+)
+
+// Config contains the experiment configuration.
+type Config struct {
+	// Pipeline is the JSON description of the pipeline to run.
+	Pipeline string `ooni:"JSON description of the measurement pipeline"`
+}
+
+// TestKeys contains the experiment results.
+type TestKeys struct {
+	// Observations contains the observations collected by all the steps.
+	*dslx.Observations
+
+	// Failure is the failure that prevented us from measuring any endpoint.
+	Failure *string `json:"failure"`
+
+	// Pipeline is the pipeline we have run.
+	Pipeline *Pipeline `json:"pipeline"`
+
+	// Results contains the result of measuring each endpoint.
+	Results []*EndpointResult `json:"results"`
+}
+
+// EndpointResult is the result of measuring an endpoint.
+type EndpointResult struct {
+	// Address is the endpoint address.
+	Address string `json:"address"`
+
+	// Failure is the failure that occurred or nil.
+	Failure *string `json:"failure"`
+
+	// Network is the endpoint network.
+	Network string `json:"network"`
+
+	// Operation is the operation that failed, if any, or the last operation.
+	Operation string `json:"operation"`
+}
+
+// Measurer performs the measurement.
+type Measurer struct {
+	// allowLoopback allows tests to measure local servers.
+	allowLoopback bool
+
+	config Config
+}
+
+// NewExperimentMeasurer creates a new ExperimentMeasurer.
+func NewExperimentMeasurer(config Config) model.ExperimentMeasurer {
+	return &Measurer{config: config}
+}
+
+// ExperimentName implements model.ExperimentMeasurer.
+func (m *Measurer) ExperimentName() string {
+	return testName
+}
+
+// ExperimentVersion implements model.ExperimentMeasurer.
+func (m *Measurer) ExperimentVersion() string {
+	return testVersion
+}
+
+var (
+	// errNoPipeline indicates that the user did not provide any pipeline.
+	errNoPipeline = errors.New("dslxrunner: no pipeline provided")
+
+	// errNoDomain indicates that we don't know which domain to measure.
+	errNoDomain = errors.New("dslxrunner: neither the pipeline nor the input contain a domain")
+
+	// errNoAddresses indicates that we have no addresses to measure.
+	errNoAddresses = errors.New("dslxrunner: no addresses to measure")
+)
+
+// Run implements model.ExperimentMeasurer.
+func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
+	if m.config.Pipeline == "" {
+		return errNoPipeline
+	}
+	pipeline, err := parsePipeline([]byte(m.config.Pipeline), m.allowLoopback)
+	if err != nil {
+		return err
+	}
+	measurement := args.Measurement
+	domain := pipeline.domain(string(measurement.Input))
+	if domain == "" && len(pipeline.DNS) > 0 {
+		return errNoDomain
+	}
+
+	tk := &TestKeys{
+		Observations: &dslx.Observations{},
+		Failure:      nil,
+		Pipeline:     pipeline,
+		Results:      []*EndpointResult{},
+	}
+	measurement.TestKeys = tk
+
+	logger := args.Session.Logger()
+	idGen := &atomic.Int64{}
+	zeroTime := measurement.MeasurementStartTimeSaved
+	parallelism := dslx.Parallelism(pipeline.Parallelism)
+
+	// resolve the domain using all the DNS steps
+	var funcs []dslx.Func[*dslx.DomainToResolve, *dslx.Maybe[*dslx.ResolvedAddresses]]
+	for _, step := range pipeline.DNS {
+		fx, _ := step.newFunc() // already validated
+		funcs = append(funcs, fx)
+	}
+	dnsInput := dslx.NewDomainToResolve(
+		dslx.DomainName(domain),
+		dslx.DNSLookupOptionIDGenerator(idGen),
+		dslx.DNSLookupOptionLogger(logger),
+		dslx.DNSLookupOptionZeroTime(zeroTime),
+	)
+	dnsResults := dslx.Parallel(ctx, parallelism, dnsInput, funcs...)
+	tk.mergeObservations(dslx.ExtractObservations(dnsResults...)...)
+
+	addresses := dslx.NewAddressSet(dnsResults...).Add(pipeline.Addresses...)
+	pipeline.removeBogons(addresses)
+	if len(addresses.M) <= 0 {
+		err := errNoAddresses
+		if _, dnsErr := dslx.FirstError(dnsResults...); dnsErr != nil {
+			err = dnsErr
+		}
+		tk.Failure = tracex.NewFailure(err)
+		return nil // return nil so we always submit the measurement
+	}
+
+	// measure each address using all the endpoint steps
+	pool := &dslx.ConnPool{}
+	defer pool.Close()
+	for _, step := range pipeline.Endpoints {
+		fx, _ := step.newFunc(pool) // already validated
+		endpoints := addresses.ToEndpoints(
+			dslx.EndpointNetwork(step.Network),
+			dslx.EndpointPort(step.Port),
+			dslx.EndpointOptionDomain(domain),
+			dslx.EndpointOptionIDGenerator(idGen),
+			dslx.EndpointOptionLogger(logger),
+			dslx.EndpointOptionZeroTime(zeroTime),
+		)
+		measure := &measureEndpointFunc{fx}
+		for _, result := range dslx.Collect(dslx.Map[*dslx.Endpoint, *EndpointResult](
+			ctx, parallelism, measure, dslx.StreamList(endpoints...))) {
+			tk.mergeObservations(result.Observations...)
+			tk.Results = append(tk.Results, result.State)
+		}
+	}
+	return nil // return nil so we always submit the measurement
+}
+
+// domain returns the domain to resolve given the input.
+func (p *Pipeline) domain(input string) string {
+	if p.Domain != "" {
+		return p.Domain
+	}
+	if strings.Contains(input, "://") {
+		URL, err := url.Parse(input)
+		if err != nil {
+			return ""
+		}
+		return URL.Hostname()
+	}
+	return input
+}
+
+// mergeObservations appends the given observations to the test keys.
+func (tk *TestKeys) mergeObservations(observations ...*dslx.Observations) {
+	for _, o := range observations {
+		tk.NetworkEvents = append(tk.NetworkEvents, o.NetworkEvents...)
+		tk.Queries = append(tk.Queries, o.Queries...)
+		tk.Requests = append(tk.Requests, o.Requests...)
+		tk.TCPConnect = append(tk.TCPConnect, o.TCPConnect...)
+		tk.TLSHandshakes = append(tk.TLSHandshakes, o.TLSHandshakes...)
+		tk.QUICHandshakes = append(tk.QUICHandshakes, o.QUICHandshakes...)
+	}
+}
+
+// measureEndpointFunc wraps the Func implementing an endpoint
+// step to keep track of the endpoint we have measured.
+type measureEndpointFunc struct {
+	fx dslx.Func[*dslx.Endpoint, *dslx.Maybe[void]]
+}
+
+// Apply implements dslx.Func.
+func (f *measureEndpointFunc) Apply(ctx context.Context, epnt *dslx.Endpoint) *dslx.Maybe[*EndpointResult] {
+	result := f.fx.Apply(ctx, epnt)
+	return &dslx.Maybe[*EndpointResult]{
+		Error:        result.Error,
+		Observations: result.Observations,
+		Operation:    result.Operation,
+		State: &EndpointResult{
+			Address:   epnt.Address,
+			Failure:   tracex.NewFailure(result.Error),
+			Network:   epnt.Network,
+			Operation: result.Operation,
+		},
+	}
+}
+
+// SummaryKeys contains summary keys for this experiment.
+//
+// Note that this structure is part of the ABI contract with ooniprobe
+// therefore we should be careful when changing it.
+type SummaryKeys struct {
+	IsAnomaly bool `json:"-"`
+}
+
+// GetSummaryKeys implements model.ExperimentMeasurer.GetSummaryKeys.
+func (m Measurer) GetSummaryKeys(measurement *model.Measurement) (interface{}, error) {
+	return SummaryKeys{IsAnomaly: false}, nil
+}
@@ -0,0 +1,161 @@
+package dslxrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/legacy/mockable"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestMeasurer(t *testing.T) {
+	// runHelper is an helper function to run this set of tests.
+	runHelper := func(pipeline, input string) (*model.Measurement, model.ExperimentMeasurer, error) {
+		m := NewExperimentMeasurer(Config{Pipeline: pipeline})
+		m.(*Measurer).allowLoopback = true // so we can measure local servers
+		if m.ExperimentName() != "dslxrunner" {
+			t.Fatal("invalid experiment name")
+		}
+		if m.ExperimentVersion() != "0.1.0" {
+			t.Fatal("invalid experiment version")
+		}
+		meas := &model.Measurement{
+			Input: model.MeasurementTarget(input),
+		}
+		sess := &mockable.Session{
+			MockableLogger: model.DiscardLogger,
+		}
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+			Measurement: meas,
+			Session:     sess,
+		}
+		err := m.Run(context.Background(), args)
+		return meas, m, err
+	}
+
+	t.Run("without a pipeline", func(t *testing.T) {
+		_, _, err := runHelper("", "")
+		if !errors.Is(err, errNoPipeline) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with an invalid pipeline", func(t *testing.T) {
+		_, _, err := runHelper("{}", "")
+		if !errors.Is(err, errInvalidPipeline) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with DNS steps and no domain", func(t *testing.T) {
+		pipeline := `{"dns": [{"type": "getaddrinfo"}], "endpoints": [{"network": "tcp", "port": 80}]}`
+		_, _, err := runHelper(pipeline, "")
+		if !errors.Is(err, errNoDomain) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("without any address to measure", func(t *testing.T) {
+		pipeline := `{"endpoints": [{"network": "tcp", "port": 80}]}`
+		meas, _, err := runHelper(pipeline, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := meas.TestKeys.(*TestKeys)
+		if tk.Failure == nil || !strings.HasSuffix(*tk.Failure, errNoAddresses.Error()) {
+			t.Fatal("unexpected failure", tk.Failure)
+		}
+	})
+
+	t.Run("with a bogon address", func(t *testing.T) {
+		pipeline := `{"addresses": ["10.0.0.1"], "endpoints": [{"network": "tcp", "port": 80}]}`
+		_, _, err := runHelper(pipeline, "")
+		if !errors.Is(err, errInvalidPipeline) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("without loopback addresses by default", func(t *testing.T) {
+		m := NewExperimentMeasurer(Config{
+			Pipeline: `{"addresses": ["127.0.0.1"], "endpoints": [{"network": "tcp", "port": 80}]}`,
+		})
+		args := &model.ExperimentArgs{
+			Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+			Measurement: &model.Measurement{},
+			Session:     &mockable.Session{MockableLogger: model.DiscardLogger},
+		}
+		if err := m.Run(context.Background(), args); !errors.Is(err, errInvalidPipeline) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with local servers", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Bonsoir, Elliot!\n"))
+		})
+		cleartext := httptest.NewServer(handler)
+		defer cleartext.Close()
+		secure := httptest.NewTLSServer(handler)
+		defer secure.Close()
+		port := func(URL string) string {
+			parsed, err := url.Parse(URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, port, err := net.SplitHostPort(parsed.Host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return port
+		}
+		pipeline := fmt.Sprintf(`{
+			"addresses": ["127.0.0.1"],
+			"endpoints": [
+				{"network": "tcp", "port": %s, "http": {}},
+				{"network": "tcp", "port": %s, "tls": {"insecure_skip_verify": true}, "http": {}}
+			]
+		}`, port(cleartext.URL), port(secure.URL))
+		meas, m, err := runHelper(pipeline, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := meas.TestKeys.(*TestKeys)
+		if tk.Failure != nil {
+			t.Fatal("unexpected failure", *tk.Failure)
+		}
+		if len(tk.Results) != 2 {
+			t.Fatal("unexpected number of results", len(tk.Results))
+		}
+		for _, result := range tk.Results {
+			if result.Failure != nil {
+				t.Fatal("unexpected failure", *result.Failure)
+			}
+			if result.Network != "tcp" {
+				t.Fatal("unexpected network", result.Network)
+			}
+		}
+		if len(tk.TCPConnect) != 2 || len(tk.TLSHandshakes) != 1 || len(tk.Requests) != 2 {
+			t.Fatal("unexpected number of observations")
+		}
+		for _, request := range tk.Requests {
+			if request.Response.Body.Value != "Bonsoir, Elliot!\n" {
+				t.Fatal("unexpected body")
+			}
+		}
+		ask, err := m.GetSummaryKeys(meas)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ask.(SummaryKeys).IsAnomaly {
+			t.Fatal("expected no anomaly")
+		}
+	})
+}
@@ -0,0 +1,472 @@
+package dslxrunner
+
+//
+// JSON description of a measurement pipeline
+//
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/dslx"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	utls "gitlab.com/yawning/utls.git"
+)
+
+// Pipeline is the JSON description of a measurement pipeline. We resolve
+// Domain using each DNS step, merge the resolved addresses with Addresses,
+// remove bogons, and measure each resulting address using each Endpoints step.
+type Pipeline struct {
+	// Addresses contains OPTIONAL IP addresses to measure in addition
+	// to the ones we resolve using the DNS steps. Bogons are not allowed.
+	Addresses []string `json:"addresses,omitempty"`
+
+	// DNS contains the OPTIONAL DNS lookups to perform.
+	DNS []*DNSStep `json:"dns,omitempty"`
+
+	// Domain is the OPTIONAL domain to resolve. When empty, we use the
+	// experiment input as the domain or, when the input is an URL, its hostname.
+	Domain string `json:"domain,omitempty"`
+
+	// Endpoints contains the MANDATORY steps to perform for each address.
+	Endpoints []*EndpointStep `json:"endpoints"`
+
+	// Parallelism is the OPTIONAL number of goroutines to use for performing
+	// DNS lookups and measuring endpoints (at most maxParallelism).
+	Parallelism int `json:"parallelism,omitempty"`
+
+	// allowLoopback indicates that we should not consider loopback
+	// addresses as bogons, which is only useful for testing.
+	allowLoopback bool
+}
+
+// StepPolicy contains options for controlling how we perform a step.
+type StepPolicy struct {
+	// Attempts is the OPTIONAL number of attempts to perform (at most maxAttempts).
+	// We retry the whole step until it succeeds or we run out of attempts.
+	Attempts int `json:"attempts,omitempty"`
+
+	// BackoffMillis is the OPTIONAL time to wait before the first retry (at most
+	// maxBackoffMillis), which doubles after each retry (default: one second).
+	BackoffMillis int64 `json:"backoff_ms,omitempty"`
+
+	// TimeoutMillis is the OPTIONAL timeout for each attempt (at most maxTimeoutMillis).
+	TimeoutMillis int64 `json:"timeout_ms,omitempty"`
+}
+
+const (
+	// maxAttempts is the maximum value of StepPolicy.Attempts.
+	maxAttempts = 10
+
+	// maxBackoffMillis is the maximum value of StepPolicy.BackoffMillis.
+	maxBackoffMillis = 60 * 1000
+
+	// maxTimeoutMillis is the maximum value of StepPolicy.TimeoutMillis.
+	maxTimeoutMillis = 120 * 1000
+
+	// maxParallelism is the maximum value of Pipeline.Parallelism.
+	maxParallelism = 32
+)
+
+// DNSStep describes a DNS lookup.
+type DNSStep struct {
+	StepPolicy
+
+	// Address is the resolver's endpoint (for "udp", "tcp", and "tls")
+	// or URL (for "https" and "http3"). Ignored for "getaddrinfo". We reject
+	// endpoints and URLs whose host is a bogon IP address.
+	Address string `json:"address,omitempty"`
+
+	// Type is the MANDATORY resolver type: one of "getaddrinfo", "udp",
+	// "tcp", "tls", "https", and "http3".
+	Type string `json:"type"`
+}
+
+// EndpointStep describes what to do with each address.
+type EndpointStep struct {
+	StepPolicy
+
+	// HTTP OPTIONALLY indicates that we should perform an HTTP request.
+	HTTP *HTTPStep `json:"http,omitempty"`
+
+	// Network is the MANDATORY network: either "tcp" or "udp".
+	Network string `json:"network"`
+
+	// Port is the MANDATORY port to use.
+	Port uint16 `json:"port"`
+
+	// QUIC contains the QUIC handshake options, which are MANDATORY when
+	// Network is "udp" and forbidden otherwise.
+	QUIC *QUICStep `json:"quic,omitempty"`
+
+	// TLS contains the OPTIONAL TLS handshake options. When present, we
+	// perform a TLS handshake after connecting. Forbidden with "udp".
+	TLS *TLSStep `json:"tls,omitempty"`
+}
+
+// TLSStep contains the TLS handshake options.
+type TLSStep struct {
+	// ALPN contains the OPTIONAL ALPNs to negotiate.
+	ALPN []string `json:"alpn,omitempty"`
+
+	// ClientHello is the OPTIONAL uTLS ClientHello to parrot: one of
+	// "chrome", "firefox", "ios", and "randomized".
+	ClientHello string `json:"client_hello,omitempty"`
+
+	// ECH OPTIONALLY indicates that we should send an ECH extension.
+	ECH bool `json:"ech,omitempty"`
+
+	// ECHConfigList is the OPTIONAL base64 encoded ECHConfigList to use
+	// when ECH is true. When empty, we send a GREASE ECH extension.
+	ECHConfigList []byte `json:"ech_config_list,omitempty"`
+
+	// InsecureSkipVerify OPTIONALLY disables certificate verification.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+
+	// SNI is the OPTIONAL SNI to use.
+	SNI string `json:"sni,omitempty"`
+}
+
+// QUICStep contains the QUIC handshake options.
+type QUICStep struct {
+	// InsecureSkipVerify OPTIONALLY disables certificate verification.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+
+	// SNI is the OPTIONAL SNI to use.
+	SNI string `json:"sni,omitempty"`
+}
+
+// HTTPStep contains the HTTP request options.
+type HTTPStep struct {
+	// Accept is the OPTIONAL Accept header.
+	Accept string `json:"accept,omitempty"`
+
+	// AcceptLanguage is the OPTIONAL Accept-Language header.
+	AcceptLanguage string `json:"accept_language,omitempty"`
+
+	// Host is the OPTIONAL Host header.
+	Host string `json:"host,omitempty"`
+
+	// MaxBodySnapshotSize is the OPTIONAL maximum body snapshot size.
+	MaxBodySnapshotSize int64 `json:"max_body_snapshot_size,omitempty"`
+
+	// Method is the OPTIONAL request method.
+	Method string `json:"method,omitempty"`
+
+	// Path is the OPTIONAL URL path.
+	Path string `json:"path,omitempty"`
+
+	// Referer is the OPTIONAL Referer header.
+	Referer string `json:"referer,omitempty"`
+
+	// UserAgent is the OPTIONAL User-Agent header.
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// errInvalidPipeline indicates that the pipeline description is invalid.
+var errInvalidPipeline = errors.New("dslxrunner: invalid pipeline")
+
+// clientHelloIDs maps the ClientHello names we support to uTLS ClientHelloIDs.
+var clientHelloIDs = map[string]*utls.ClientHelloID{
+	"chrome":     &utls.HelloChrome_Auto,
+	"firefox":    &utls.HelloFirefox_Auto,
+	"ios":        &utls.HelloIOS_Auto,
+	"randomized": &utls.HelloRandomized,
+}
+
+// ParsePipeline parses and validates the JSON description of a pipeline.
+func ParsePipeline(data []byte) (*Pipeline, error) {
+	return parsePipeline(data, false)
+}
+
+// parsePipeline is like ParsePipeline but allows tests to measure loopback addresses.
+func parsePipeline(data []byte, allowLoopback bool) (*Pipeline, error) {
+	var pipeline Pipeline
+	if err := json.Unmarshal(data, &pipeline); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidPipeline, err.Error())
+	}
+	pipeline.allowLoopback = allowLoopback
+	if err := pipeline.validate(); err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
+
+// validate returns an error if the pipeline is not valid.
+func (p *Pipeline) validate() error {
+	if len(p.Endpoints) <= 0 {
+		return fmt.Errorf("%w: no endpoints steps", errInvalidPipeline)
+	}
+	if p.Parallelism < 0 || p.Parallelism > maxParallelism {
+		return fmt.Errorf("%w: parallelism out of range: %d", errInvalidPipeline, p.Parallelism)
+	}
+	for _, addr := range p.Addresses {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("%w: invalid IP address: %s", errInvalidPipeline, addr)
+		}
+		if p.isBogon(addr) {
+			return fmt.Errorf("%w: bogon IP address: %s", errInvalidPipeline, addr)
+		}
+	}
+	for _, step := range p.DNS {
+		if _, err := step.newFunc(); err != nil {
+			return err
+		}
+		if host := step.host(); net.ParseIP(host) != nil && p.isBogon(host) {
+			return fmt.Errorf("%w: bogon DNS step address: %s", errInvalidPipeline, step.Address)
+		}
+	}
+	for _, step := range p.Endpoints {
+		if _, err := step.newFunc(&dslx.ConnPool{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isBogon returns whether we should not measure the given IP address.
+func (p *Pipeline) isBogon(addr string) bool {
+	if p.allowLoopback {
+		if ip := net.ParseIP(addr); ip != nil && ip.IsLoopback() {
+			return false
+		}
+	}
+	return netxlite.IsBogon(addr)
+}
+
+// removeBogons MUTATES the set to remove the addresses we should not measure.
+func (p *Pipeline) removeBogons(as *dslx.AddressSet) {
+	for addr := range as.M {
+		if p.isBogon(addr) {
+			delete(as.M, addr)
+		}
+	}
+}
+
+// validate returns an error if the policy is not valid.
+func (p *StepPolicy) validate() error {
+	if p.Attempts < 0 || p.Attempts > maxAttempts {
+		return fmt.Errorf("%w: attempts out of range: %d", errInvalidPipeline, p.Attempts)
+	}
+	if p.BackoffMillis < 0 || p.BackoffMillis > maxBackoffMillis {
+		return fmt.Errorf("%w: backoff_ms out of range: %d", errInvalidPipeline, p.BackoffMillis)
+	}
+	if p.TimeoutMillis < 0 || p.TimeoutMillis > maxTimeoutMillis {
+		return fmt.Errorf("%w: timeout_ms out of range: %d", errInvalidPipeline, p.TimeoutMillis)
+	}
+	return nil
+}
+
+// wrap applies the policy to the given Func.
+func wrap[A, B any](policy *StepPolicy, fx dslx.Func[A, *dslx.Maybe[B]]) dslx.Func[A, *dslx.Maybe[B]] {
+	if policy.TimeoutMillis > 0 {
+		fx = dslx.WithTimeout(time.Duration(policy.TimeoutMillis)*time.Millisecond, fx)
+	}
+	if policy.Attempts > 1 {
+		backoff := time.Second
+		if policy.BackoffMillis > 0 {
+			backoff = time.Duration(policy.BackoffMillis) * time.Millisecond
+		}
+		fx = dslx.Retry(policy.Attempts, backoff, fx)
+	}
+	return fx
+}
+
+// newFunc returns the Func implementing this DNS step.
+func (s *DNSStep) newFunc() (dslx.Func[*dslx.DomainToResolve, *dslx.Maybe[*dslx.ResolvedAddresses]], error) {
+	if err := s.StepPolicy.validate(); err != nil {
+		return nil, err
+	}
+	var fx dslx.Func[*dslx.DomainToResolve, *dslx.Maybe[*dslx.ResolvedAddresses]]
+	switch s.Type {
+	case "getaddrinfo":
+		fx = dslx.DNSLookupGetaddrinfo()
+	case "udp":
+		fx = dslx.DNSLookupUDP(s.Address)
+	case "tcp":
+		fx = dslx.DNSLookupTCP(s.Address)
+	case "tls":
+		fx = dslx.DNSLookupTLS(s.Address)
+	case "https":
+		fx = dslx.DNSLookupHTTPS(s.Address)
+	case "http3":
+		fx = dslx.DNSLookupHTTP3(s.Address)
+	default:
+		return nil, fmt.Errorf("%w: unknown DNS step type: %s", errInvalidPipeline, s.Type)
+	}
+	if s.Type != "getaddrinfo" && s.Address == "" {
+		return nil, fmt.Errorf("%w: missing address for DNS step type: %s", errInvalidPipeline, s.Type)
+	}
+	return wrap(&s.StepPolicy, fx), nil
+}
+
+// host returns the host of the resolver's endpoint or URL, which is
+// empty when there is no address (e.g., for "getaddrinfo").
+func (s *DNSStep) host() string {
+	if URL, err := url.Parse(s.Address); err == nil && URL.Scheme != "" && URL.Host != "" {
+		return URL.Hostname()
+	}
+	if host, _, err := net.SplitHostPort(s.Address); err == nil {
+		return host
+	}
+	return s.Address
+}
+
+// newFunc returns the Func implementing this endpoint step.
+func (s *EndpointStep) newFunc(pool *dslx.ConnPool) (dslx.Func[*dslx.Endpoint, *dslx.Maybe[void]], error) {
+	if s.Port == 0 {
+		return nil, fmt.Errorf("%w: missing endpoint port", errInvalidPipeline)
+	}
+	if err := s.StepPolicy.validate(); err != nil {
+		return nil, err
+	}
+	var fx dslx.Func[*dslx.Endpoint, *dslx.Maybe[void]]
+	switch s.Network {
+	case "tcp":
+		if s.QUIC != nil {
+			return nil, fmt.Errorf("%w: cannot use QUIC with tcp", errInvalidPipeline)
+		}
+		tlsOptions, err := s.tlsOptions()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case s.TLS != nil && s.HTTP != nil:
+			fx = dslx.Compose4(
+				dslx.TCPConnect(pool),
+				dslx.TLSHandshake(pool, tlsOptions...),
+				dslx.HTTPRequestOverTLS(s.HTTP.options()...),
+				discard[*dslx.HTTPResponse](),
+			)
+		case s.TLS != nil:
+			fx = dslx.Compose3(
+				dslx.TCPConnect(pool),
+				dslx.TLSHandshake(pool, tlsOptions...),
+				discard[*dslx.TLSConnection](),
+			)
+		case s.HTTP != nil:
+			fx = dslx.Compose3(
+				dslx.TCPConnect(pool),
+				dslx.HTTPRequestOverTCP(s.HTTP.options()...),
+				discard[*dslx.HTTPResponse](),
+			)
+		default:
+			fx = dslx.Compose2(
+				dslx.TCPConnect(pool),
+				discard[*dslx.TCPConnection](),
+			)
+		}
+	case "udp":
+		if s.QUIC == nil || s.TLS != nil {
+			return nil, fmt.Errorf("%w: udp requires QUIC options and no TLS options", errInvalidPipeline)
+		}
+		if s.HTTP != nil {
+			fx = dslx.Compose3(
+				dslx.QUICHandshake(pool, s.QUIC.options()...),
+				dslx.HTTPRequestOverQUIC(s.HTTP.options()...),
+				discard[*dslx.HTTPResponse](),
+			)
+		} else {
+			fx = dslx.Compose2(
+				dslx.QUICHandshake(pool, s.QUIC.options()...),
+				discard[*dslx.QUICConnection](),
+			)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown endpoint network: %s", errInvalidPipeline, s.Network)
+	}
+	return wrap(&s.StepPolicy, fx), nil
+}
+
+// tlsOptions returns the options for dslx.TLSHandshake.
+func (s *EndpointStep) tlsOptions() (out []dslx.TLSHandshakeOption, err error) {
+	if s.TLS == nil {
+		return nil, nil
+	}
+	if len(s.TLS.ALPN) > 0 {
+		out = append(out, dslx.TLSHandshakeOptionNextProto(s.TLS.ALPN))
+	}
+	if s.TLS.ClientHello != "" {
+		id, found := clientHelloIDs[s.TLS.ClientHello]
+		if !found {
+			return nil, fmt.Errorf("%w: unknown ClientHello: %s", errInvalidPipeline, s.TLS.ClientHello)
+		}
+		out = append(out, dslx.TLSHandshakeOptionClientHelloID(id))
+	}
+	if s.TLS.ECH {
+		out = append(out, dslx.TLSHandshakeOptionECHConfigList(s.TLS.ECHConfigList))
+	}
+	if s.TLS.InsecureSkipVerify {
+		out = append(out, dslx.TLSHandshakeOptionInsecureSkipVerify(true))
+	}
+	if s.TLS.SNI != "" {
+		out = append(out, dslx.TLSHandshakeOptionServerName(s.TLS.SNI))
+	}
+	return out, nil
+}
+
+// options returns the options for dslx.QUICHandshake.
+func (s *QUICStep) options() (out []dslx.QUICHandshakeOption) {
+	if s.InsecureSkipVerify {
+		out = append(out, dslx.QUICHandshakeOptionInsecureSkipVerify(true))
+	}
+	if s.SNI != "" {
+		out = append(out, dslx.QUICHandshakeOptionServerName(s.SNI))
+	}
+	return
+}
+
+// options returns the options for dslx.HTTPRequest.
+func (s *HTTPStep) options() (out []dslx.HTTPRequestOption) {
+	if s.Accept != "" {
+		out = append(out, dslx.HTTPRequestOptionAccept(s.Accept))
+	}
+	if s.AcceptLanguage != "" {
+		out = append(out, dslx.HTTPRequestOptionAcceptLanguage(s.AcceptLanguage))
+	}
+	if s.Host != "" {
+		out = append(out, dslx.HTTPRequestOptionHost(s.Host))
+	}
+	if s.MaxBodySnapshotSize > 0 {
+		out = append(out, dslx.HTTPRequestOptionMaxBodySnapshotSize(s.MaxBodySnapshotSize))
+	}
+	if s.Method != "" {
+		out = append(out, dslx.HTTPRequestOptionMethod(s.Method))
+	}
+	if s.Path != "" {
+		out = append(out, dslx.HTTPRequestOptionURLPath(s.Path))
+	}
+	if s.Referer != "" {
+		out = append(out, dslx.HTTPRequestOptionReferer(s.Referer))
+	}
+	if s.UserAgent != "" {
+		out = append(out, dslx.HTTPRequestOptionUserAgent(s.UserAgent))
+	}
+	return
+}
+
+// void is the state produced by a pipeline's last step.
+type void struct{}
+
+// discard returns a Func discarding the state of the previous step.
+func discard[T any]() dslx.Func[T, *dslx.Maybe[void]] {
+	return &discardFunc[T]{}
+}
+
+// discardFunc is the type returned by discard.
+type discardFunc[T any] struct{}
+
+// Apply implements dslx.Func.
+func (f *discardFunc[T]) Apply(ctx context.Context, _ T) *dslx.Maybe[void] {
+	return &dslx.Maybe[void]{
+		Error:        nil,
+		Observations: nil,
+		Operation:    "", // we cannot fail, so no need to store operation name
+		State:        void{},
+	}
+}
@@ -0,0 +1,259 @@
+package dslxrunner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/dslx"
+)
+
+func TestParsePipeline(t *testing.T) {
+	tests := map[string]struct {
+		input     string
+		expectErr bool
+	}{
+		"with invalid JSON": {
+			input:     `{`,
+			expectErr: true,
+		},
+		"with no endpoints": {
+			input:     `{"dns": [{"type": "getaddrinfo"}]}`,
+			expectErr: true,
+		},
+		"with invalid address": {
+			input:     `{"addresses": ["example.com"], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with bogon address": {
+			input:     `{"addresses": ["10.0.0.1"], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with loopback address": {
+			input:     `{"addresses": ["127.0.0.1"], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with bogon DNS step endpoint": {
+			input:     `{"dns": [{"type": "udp", "address": "192.168.1.1:53"}], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with bogon DNS step endpoint URL": {
+			input:     `{"dns": [{"type": "udp", "address": "udp://192.168.1.1:53"}], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with loopback DNS step URL": {
+			input:     `{"dns": [{"type": "https", "address": "https://127.0.0.1/dns-query"}], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with negative parallelism": {
+			input:     `{"parallelism": -1, "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with too much parallelism": {
+			input:     `{"parallelism": 1000000, "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with too many attempts": {
+			input:     `{"endpoints": [{"network": "tcp", "port": 443, "attempts": 1000000}]}`,
+			expectErr: true,
+		},
+		"with negative attempts": {
+			input:     `{"dns": [{"type": "getaddrinfo", "attempts": -1}], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with too large backoff": {
+			input:     `{"dns": [{"type": "getaddrinfo", "attempts": 2, "backoff_ms": 9223372036854775807}], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with negative backoff": {
+			input:     `{"endpoints": [{"network": "tcp", "port": 443, "backoff_ms": -1}]}`,
+			expectErr: true,
+		},
+		"with too large timeout": {
+			input:     `{"endpoints": [{"network": "tcp", "port": 443, "timeout_ms": 86400000}]}`,
+			expectErr: true,
+		},
+		"with negative timeout": {
+			input:     `{"endpoints": [{"network": "tcp", "port": 443, "timeout_ms": -1}]}`,
+			expectErr: true,
+		},
+		"with unknown DNS type": {
+			input:     `{"dns": [{"type": "carrier-pigeon"}], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with DNS step missing address": {
+			input:     `{"dns": [{"type": "udp"}], "endpoints": [{"network": "tcp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with missing port": {
+			input:     `{"endpoints": [{"network": "tcp"}]}`,
+			expectErr: true,
+		},
+		"with unknown network": {
+			input:     `{"endpoints": [{"network": "sctp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with QUIC over tcp": {
+			input:     `{"endpoints": [{"network": "tcp", "port": 443, "quic": {}}]}`,
+			expectErr: true,
+		},
+		"with udp without QUIC": {
+			input:     `{"endpoints": [{"network": "udp", "port": 443}]}`,
+			expectErr: true,
+		},
+		"with udp and TLS": {
+			input:     `{"endpoints": [{"network": "udp", "port": 443, "quic": {}, "tls": {}}]}`,
+			expectErr: true,
+		},
+		"with unknown ClientHello": {
+			input:     `{"endpoints": [{"network": "tcp", "port": 443, "tls": {"client_hello": "netscape"}}]}`,
+			expectErr: true,
+		},
+		"with a complete pipeline": {
+			input: `{
+				"domain": "example.com",
+				"dns": [
+					{"type": "getaddrinfo"},
+					{"type": "udp", "address": "8.8.8.8:53", "attempts": 3, "backoff_ms": 100},
+					{"type": "tcp", "address": "8.8.8.8:53"},
+					{"type": "tls", "address": "8.8.8.8:853"},
+					{"type": "https", "address": "https://dns.google/dns-query", "timeout_ms": 5000},
+					{"type": "http3", "address": "https://dns.google/dns-query"}
+				],
+				"addresses": ["93.184.216.34"],
+				"parallelism": 4,
+				"endpoints": [
+					{"network": "tcp", "port": 80},
+					{"network": "tcp", "port": 80, "http": {"path": "/robots.txt"}},
+					{"network": "tcp", "port": 443, "tls": {"client_hello": "chrome", "ech": true}},
+					{"network": "tcp", "port": 443, "tls": {"alpn": ["http/1.1"], "sni": "example.org"}, "http": {}},
+					{"network": "udp", "port": 443, "quic": {"sni": "example.org"}},
+					{"network": "udp", "port": 443, "quic": {}, "http": {"method": "HEAD"}, "attempts": 2}
+				]
+			}`,
+			expectErr: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pipeline, err := ParsePipeline([]byte(tt.input))
+			if tt.expectErr {
+				if !errors.Is(err, errInvalidPipeline) {
+					t.Fatal("unexpected error", err)
+				}
+				if pipeline != nil {
+					t.Fatal("expected nil pipeline")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pipeline == nil {
+				t.Fatal("expected non-nil pipeline")
+			}
+		})
+	}
+}
+
+func TestPipelineBogons(t *testing.T) {
+	addresses := []string{"93.184.216.34", "10.0.0.1", "127.0.0.1", "::1", "2001:4860:4860::8888"}
+
+	t.Run("by default we remove all bogons", func(t *testing.T) {
+		pipeline, err := ParsePipeline([]byte(`{"endpoints": [{"network": "tcp", "port": 443}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		as := dslx.NewAddressSet().Add(addresses...)
+		pipeline.removeBogons(as)
+		expect := map[string]bool{"93.184.216.34": true, "2001:4860:4860::8888": true}
+		if diff := cmp.Diff(expect, as.M); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("tests may allow loopback addresses", func(t *testing.T) {
+		pipeline, err := parsePipeline([]byte(`{
+			"addresses": ["127.0.0.1"],
+			"endpoints": [{"network": "tcp", "port": 443}]
+		}`), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		as := dslx.NewAddressSet().Add(addresses...)
+		pipeline.removeBogons(as)
+		expect := map[string]bool{
+			"93.184.216.34":        true,
+			"127.0.0.1":            true,
+			"::1":                  true,
+			"2001:4860:4860::8888": true,
+		}
+		if diff := cmp.Diff(expect, as.M); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("tests cannot use other bogons", func(t *testing.T) {
+		_, err := parsePipeline([]byte(`{
+			"addresses": ["10.0.0.1"],
+			"endpoints": [{"network": "tcp", "port": 443}]
+		}`), true)
+		if !errors.Is(err, errInvalidPipeline) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestPipelineDomain(t *testing.T) {
+	tests := map[string]struct {
+		pipeline *Pipeline
+		input    string
+		expect   string
+	}{
+		"the pipeline domain takes precedence": {
+			pipeline: &Pipeline{Domain: "example.com"},
+			input:    "https://example.org/",
+			expect:   "example.com",
+		},
+		"with an URL input": {
+			pipeline: &Pipeline{},
+			input:    "https://example.org:8443/robots.txt",
+			expect:   "example.org",
+		},
+		"with a domain input": {
+			pipeline: &Pipeline{},
+			input:    "example.org",
+			expect:   "example.org",
+		},
+		"with an invalid URL input": {
+			pipeline: &Pipeline{},
+			input:    "\t://",
+			expect:   "",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tt.pipeline.domain(tt.input); got != tt.expect {
+				t.Fatalf("expected %s, got %s", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	t.Run("without a policy we return the same Func", func(t *testing.T) {
+		fx := discard[int]()
+		if wrap(&StepPolicy{}, fx) != fx {
+			t.Fatal("expected the same Func")
+		}
+	})
+
+	t.Run("with a policy we return a different Func", func(t *testing.T) {
+		fx := discard[int]()
+		policy := &StepPolicy{Attempts: 2, TimeoutMillis: int64(time.Second / time.Millisecond)}
+		if wrap(policy, fx) == fx {
+			t.Fatal("expected a different Func")
+		}
+	})
+}
@@ -65,7 +65,7 @@ func (m *Measurer) Run(
 	}
 
 	// 1. perform a DNSLookup
-	trace := measurexlite.NewTrace(0, args.Measurement.MeasurementStartTimeSaved)
+	trace := measurexlite.NewTraceWithContext(ctx, 0, args.Measurement.MeasurementStartTimeSaved)
 	resolver := trace.NewParallelDNSOverHTTPSResolver(args.Session.Logger(), m.config.resolverURL())
 	addrs, err := resolver.LookupHost(ctx, parsed.Host)
 	if err != nil {
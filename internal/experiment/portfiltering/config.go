@@ -8,13 +8,46 @@ import "time"
 
 // Config contains the experiment configuration.
 type Config struct {
-	// Delay is the delay between each repetition (in milliseconds).
-	Delay int64 `ooni:"number of milliseconds to wait before testing each port"`
+	// Delay is the delay between each repetition.
+	Delay time.Duration `ooni:"time to wait before testing each port (e.g., 100ms)"`
+
+	// Mode is the protocol we use to probe ports (default: tcp).
+	Mode string `ooni:"protocol used to probe each port;enum=tcp|udp"`
+
+	// TestHelper is the URL of the test helper (default: http://127.0.0.1). Because
+	// we dial each port without using a resolver, the URL's host must be an IP address.
+	TestHelper string `ooni:"URL of the port-filtering test helper (the host must be an IP address)"`
+
+	// UDPTimeout is the time to wait for the UDP echo.
+	UDPTimeout time.Duration `ooni:"time to wait for each UDP echo (e.g., 3s)"`
 }
 
 func (c *Config) delay() time.Duration {
 	if c.Delay > 0 {
-		return time.Duration(c.Delay) * time.Millisecond
+		return c.Delay
 	}
 	return 100 * time.Millisecond
 }
+
+func (c *Config) mode() string {
+	if c.Mode != "" {
+		return c.Mode
+	}
+	return "tcp"
+}
+
+func (c *Config) testHelper() string {
+	if c.TestHelper != "" {
+		return c.TestHelper
+	}
+	// TODO(DecFox): Replace the localhost deployment with an OONI testhelper
+	// Ensure that we only do this once we have a deployed testhelper
+	return "http://127.0.0.1"
+}
+
+func (c *Config) udpTimeout() time.Duration {
+	if c.UDPTimeout > 0 {
+		return c.UDPTimeout
+	}
+	return 3 * time.Second
+}
@@ -11,3 +11,36 @@ func TestConfig_delay(t *testing.T) {
 		t.Fatal("invalid default delay")
 	}
 }
+
+func TestConfig_mode(t *testing.T) {
+	c := Config{}
+	if c.mode() != "tcp" {
+		t.Fatal("invalid default mode")
+	}
+	c.Mode = "udp"
+	if c.mode() != "udp" {
+		t.Fatal("invalid mode")
+	}
+}
+
+func TestConfig_testHelper(t *testing.T) {
+	c := Config{}
+	if c.testHelper() != "http://127.0.0.1" {
+		t.Fatal("invalid default test helper")
+	}
+	c.TestHelper = "http://192.0.2.1"
+	if c.testHelper() != "http://192.0.2.1" {
+		t.Fatal("invalid test helper")
+	}
+}
+
+func TestConfig_udpTimeout(t *testing.T) {
+	c := Config{}
+	if c.udpTimeout() != 3*time.Second {
+		t.Fatal("invalid default UDP timeout")
+	}
+	c.UDPTimeout = 500 * time.Millisecond
+	if c.udpTimeout() != 500*time.Millisecond {
+		t.Fatal("invalid UDP timeout")
+	}
+}
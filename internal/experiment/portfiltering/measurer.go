@@ -7,6 +7,7 @@ package portfiltering
 import (
 	"context"
 	"errors"
+	"net"
 	"net/url"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -14,7 +15,7 @@ import (
 
 const (
 	testName    = "portfiltering"
-	testVersion = "0.1.0"
+	testVersion = "0.2.0"
 )
 
 // Measurer performs the measurement.
@@ -35,6 +36,9 @@ func (m *Measurer) ExperimentVersion() string {
 var (
 	// errInvalidTestHelper indicates that the given test helper is not an URL
 	errInvalidTestHelper = errors.New("testhelper is not an URL")
+
+	// errTestHelperNotIP indicates that the test helper URL does not contain an IP address
+	errTestHelperNotIP = errors.New("testhelper URL must contain an IP address")
 )
 
 // Run implements ExperimentMeasurer.Run.
@@ -42,19 +46,29 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 	_ = args.Callbacks
 	measurement := args.Measurement
 	sess := args.Session
-	// TODO(DecFox): Replace the localhost deployment with an OONI testhelper
-	// Ensure that we only do this once we have a deployed testhelper
-	testhelper := "http://127.0.0.1"
-	parsed, err := url.Parse(testhelper)
-	if err != nil {
+	parsed, err := url.Parse(m.config.testHelper())
+	if err != nil || parsed.Hostname() == "" {
 		return errInvalidTestHelper
 	}
+	// We dial each port without using a resolver, so we need an IP address
+	if net.ParseIP(parsed.Hostname()) == nil {
+		return errTestHelperNotIP
+	}
 	tk := new(TestKeys)
 	measurement.TestKeys = tk
-	out := make(chan *model.ArchivalTCPConnectResult)
-	go m.tcpConnectLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Host, out)
-	for len(tk.TCPConnect) < len(Ports) {
-		tk.TCPConnect = append(tk.TCPConnect, <-out)
+	switch m.config.mode() {
+	case "udp":
+		out := make(chan *UDPEchoResult)
+		go m.udpEchoLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Hostname(), out)
+		for len(tk.UDPEcho) < len(Ports) {
+			tk.UDPEcho = append(tk.UDPEcho, <-out)
+		}
+	default:
+		out := make(chan *model.ArchivalTCPConnectResult)
+		go m.tcpConnectLoop(ctx, measurement.MeasurementStartTimeSaved, sess.Logger(), parsed.Hostname(), out)
+		for len(tk.TCPConnect) < len(Ports) {
+			tk.TCPConnect = append(tk.TCPConnect, <-out)
+		}
 	}
 	return nil // return nil so we always submit the measurement
 }
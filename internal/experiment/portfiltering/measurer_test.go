@@ -2,7 +2,9 @@ package portfiltering
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/model/mocks"
@@ -13,7 +15,7 @@ func TestMeasurerExperimentNameVersion(t *testing.T) {
 	if measurer.ExperimentName() != "portfiltering" {
 		t.Fatal("unexpected ExperimentName")
 	}
-	if measurer.ExperimentVersion() != "0.1.0" {
+	if measurer.ExperimentVersion() != "0.2.0" {
 		t.Fatal("unexpected ExperimentVersion")
 	}
 }
@@ -51,3 +53,55 @@ func TestMeasurer_run(t *testing.T) {
 		t.Fatal("expected no anomaly")
 	}
 }
+
+func TestMeasurer_runUDP(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+	m := NewExperimentMeasurer(Config{Delay: time.Millisecond, Mode: "udp", UDPTimeout: 500 * time.Millisecond})
+	meas := &model.Measurement{}
+	sess := &mocks.Session{
+		MockLogger: func() model.Logger {
+			return model.DiscardLogger
+		},
+	}
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: meas,
+		Session:     sess,
+	}
+	if err := m.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if len(tk.UDPEcho) != len(Ports) {
+		t.Fatal("unexpected number of ports")
+	}
+	if len(tk.TCPConnect) != 0 {
+		t.Fatal("expected no TCP connect results")
+	}
+}
+
+func TestMeasurer_runWithInvalidTestHelper(t *testing.T) {
+	m := NewExperimentMeasurer(Config{TestHelper: "\t"})
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: &model.Measurement{},
+		Session:     &mocks.Session{},
+	}
+	if err := m.Run(context.Background(), args); !errors.Is(err, errInvalidTestHelper) {
+		t.Fatal("unexpected error", err)
+	}
+}
+
+func TestMeasurer_runWithTestHelperNotIP(t *testing.T) {
+	m := NewExperimentMeasurer(Config{TestHelper: "http://example.com"})
+	args := &model.ExperimentArgs{
+		Callbacks:   model.NewPrinterCallbacks(model.DiscardLogger),
+		Measurement: &model.Measurement{},
+		Session:     &mocks.Session{},
+	}
+	if err := m.Run(context.Background(), args); !errors.Is(err, errTestHelperNotIP) {
+		t.Fatal("unexpected error", err)
+	}
+}
@@ -38,7 +38,7 @@ func (m *Measurer) tcpConnectAsync(ctx context.Context, index int64,
 // tcpConnect performs a TCP connect and returns the result to the caller.
 func (m *Measurer) tcpConnect(ctx context.Context, index int64,
 	zeroTime time.Time, logger model.Logger, address string) *model.ArchivalTCPConnectResult {
-	trace := measurexlite.NewTrace(index, zeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
 	ol := measurexlite.NewOperationLogger(logger, "TCPConnect #%d %s", index, address)
 	dialer := trace.NewDialerWithoutResolver(logger)
 	conn, err := dialer.DialContext(ctx, "tcp", address)
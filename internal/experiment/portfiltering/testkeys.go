@@ -5,4 +5,37 @@ import "github.com/ooni/probe-cli/v3/internal/model"
 // TestKeys contains the experiment results.
 type TestKeys struct {
 	TCPConnect []*model.ArchivalTCPConnectResult `json:"tcp_connect"`
+	UDPEcho    []*UDPEchoResult                  `json:"udp_echo,omitempty"`
 }
+
+// UDPEchoResult contains the result of sending a nonce to
+// a UDP port of the test helper and waiting for its echo.
+type UDPEchoResult struct {
+	IP            string                        `json:"ip"`
+	Port          int                           `json:"port"`
+	Failure       *string                       `json:"failure"`
+	NetworkEvents []*model.ArchivalNetworkEvent `json:"network_events"`
+	Status        string                        `json:"status"`
+	T0            float64                       `json:"t0"`
+	T             float64                       `json:"t"`
+	TransactionID int64                         `json:"transaction_id"`
+}
+
+const (
+	// UDPEchoStatusOK indicates that we received the nonce back.
+	UDPEchoStatusOK = "ok"
+
+	// UDPEchoStatusNoResponse indicates that we did not receive any response.
+	UDPEchoStatusNoResponse = "no_response"
+
+	// UDPEchoStatusUnreachable indicates that we received an ICMP port
+	// unreachable message, which surfaces as connection_refused.
+	UDPEchoStatusUnreachable = "unreachable"
+
+	// UDPEchoStatusEchoMismatch indicates that we received a response
+	// that differs from the nonce we have sent.
+	UDPEchoStatusEchoMismatch = "echo_mismatch"
+
+	// UDPEchoStatusError indicates any other error.
+	UDPEchoStatusError = "error"
+)
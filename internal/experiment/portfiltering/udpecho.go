@@ -0,0 +1,138 @@
+package portfiltering
+
+//
+// UDP echo for portfiltering
+//
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/randx"
+)
+
+// errUDPEchoMismatch indicates that the echo differs from the nonce.
+var errUDPEchoMismatch = errors.New(UDPEchoStatusEchoMismatch)
+
+// UDPEchoNonceSize is the size of the nonce we send to the test helper.
+const UDPEchoNonceSize = 32
+
+// IsUDPEchoNonce returns whether data is a nonce we could have sent to the
+// test helper, i.e., exactly UDPEchoNonceSize ASCII letters. The test helper
+// only echoes back such datagrams, so it cannot reflect arbitrary payloads.
+func IsUDPEchoNonce(data []byte) bool {
+	if len(data) != UDPEchoNonceSize {
+		return false
+	}
+	for _, c := range data {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// udpEchoLoop sends a nonce to all ports and emits the results onto the out channel
+func (m *Measurer) udpEchoLoop(ctx context.Context, zeroTime time.Time,
+	logger model.Logger, address string, out chan<- *UDPEchoResult) {
+	ticker := time.NewTicker(m.config.delay())
+	defer ticker.Stop()
+	rand.Shuffle(len(Ports), func(i, j int) {
+		Ports[i], Ports[j] = Ports[j], Ports[i]
+	})
+	for i, port := range Ports {
+		addr := net.JoinHostPort(address, port)
+		go m.udpEchoAsync(ctx, int64(i), zeroTime, logger, addr, out)
+		<-ticker.C
+	}
+}
+
+// udpEchoAsync performs a UDP echo and emits the result onto the out channel.
+func (m *Measurer) udpEchoAsync(ctx context.Context, index int64,
+	zeroTime time.Time, logger model.Logger, address string, out chan<- *UDPEchoResult) {
+	out <- m.udpEcho(ctx, index, zeroTime, logger, address)
+}
+
+// udpEcho sends a nonce to the given address, waits for the echo,
+// and returns the result to the caller.
+func (m *Measurer) udpEcho(ctx context.Context, index int64,
+	zeroTime time.Time, logger model.Logger, address string) *UDPEchoResult {
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
+	ol := measurexlite.NewOperationLogger(logger, "UDPEcho #%d %s", index, address)
+	started := trace.TimeSince(zeroTime)
+	err := m.udpEchoExchange(ctx, trace, logger, address)
+	finished := trace.TimeSince(zeroTime)
+	ol.Stop(err)
+	ip, port, _ := net.SplitHostPort(address)
+	portnum, _ := strconv.Atoi(port)
+	result := &UDPEchoResult{
+		IP:            ip,
+		Port:          portnum,
+		Failure:       nil,
+		NetworkEvents: trace.NetworkEvents(),
+		Status:        udpEchoStatus(err),
+		T0:            started.Seconds(),
+		T:             finished.Seconds(),
+		TransactionID: index,
+	}
+	switch {
+	case errors.Is(err, errUDPEchoMismatch):
+		failure := UDPEchoStatusEchoMismatch
+		result.Failure = &failure
+	default:
+		result.Failure = measurexlite.NewFailure(err)
+	}
+	return result
+}
+
+// udpEchoExchange sends a nonce to address and reads the echo.
+func (m *Measurer) udpEchoExchange(ctx context.Context,
+	trace *measurexlite.Trace, logger model.Logger, address string) error {
+	dialer := trace.NewDialerWithoutResolver(logger)
+	conn, err := dialer.DialContext(ctx, "udp", address)
+	if err != nil {
+		return err
+	}
+	conn = trace.MaybeWrapNetConn(conn)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(m.config.udpTimeout()))
+	nonce := []byte(randx.Letters(UDPEchoNonceSize))
+	if _, err := conn.Write(nonce); err != nil {
+		return err
+	}
+	buffer := make([]byte, 1<<12)
+	count, err := conn.Read(buffer)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(nonce, buffer[:count]) {
+		return errUDPEchoMismatch
+	}
+	return nil
+}
+
+// udpEchoStatus maps the error returned by udpEchoExchange to a status.
+func udpEchoStatus(err error) string {
+	if err == nil {
+		return UDPEchoStatusOK
+	}
+	if errors.Is(err, errUDPEchoMismatch) {
+		return UDPEchoStatusEchoMismatch
+	}
+	switch *measurexlite.NewFailure(err) {
+	case netxlite.FailureGenericTimeoutError:
+		return UDPEchoStatusNoResponse
+	case netxlite.FailureConnectionRefused:
+		return UDPEchoStatusUnreachable
+	default:
+		return UDPEchoStatusError
+	}
+}
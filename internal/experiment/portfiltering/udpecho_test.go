@@ -0,0 +1,161 @@
+package portfiltering
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// udpEchoNewServer starts a UDP server that replies to each datagram
+// using the given reply function or does not reply if it's nil.
+func udpEchoNewServer(t *testing.T, reply func(data []byte) []byte) net.PacketConn {
+	pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buffer := make([]byte, 1<<12)
+		for {
+			count, addr, err := pconn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if reply != nil {
+				pconn.WriteTo(reply(buffer[:count]), addr)
+			}
+		}
+	}()
+	return pconn
+}
+
+func TestMeasurer_udpEcho(t *testing.T) {
+	tests := map[string]struct {
+		reply   func(data []byte) []byte
+		closed  bool
+		status  string
+		failure string
+	}{
+		"with echo": {
+			reply:   func(data []byte) []byte { return data },
+			status:  UDPEchoStatusOK,
+			failure: "",
+		},
+		"with echo mismatch": {
+			reply:   func(data []byte) []byte { return []byte("antani") },
+			status:  UDPEchoStatusEchoMismatch,
+			failure: UDPEchoStatusEchoMismatch,
+		},
+		"without any response": {
+			reply:   nil,
+			status:  UDPEchoStatusNoResponse,
+			failure: netxlite.FailureGenericTimeoutError,
+		},
+		"with port unreachable": {
+			closed:  true,
+			status:  UDPEchoStatusUnreachable,
+			failure: netxlite.FailureConnectionRefused,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pconn := udpEchoNewServer(t, tt.reply)
+			defer pconn.Close()
+			if tt.closed {
+				pconn.Close() // so we should receive ICMP port unreachable
+			}
+			m := &Measurer{config: Config{UDPTimeout: 250 * time.Millisecond}}
+			address := pconn.LocalAddr().String()
+			result := m.udpEcho(context.Background(), 7, time.Now(), model.DiscardLogger, address)
+			if result.Status != tt.status {
+				t.Fatal("unexpected status", result.Status)
+			}
+			var failure string
+			if result.Failure != nil {
+				failure = *result.Failure
+			}
+			if failure != tt.failure {
+				t.Fatal("unexpected failure", failure)
+			}
+			if result.IP != "127.0.0.1" || result.Port != pconn.LocalAddr().(*net.UDPAddr).Port {
+				t.Fatal("unexpected endpoint", result.IP, result.Port)
+			}
+			if result.TransactionID != 7 {
+				t.Fatal("unexpected transaction ID", result.TransactionID)
+			}
+			if len(result.NetworkEvents) <= 0 {
+				t.Fatal("expected network events")
+			}
+		})
+	}
+}
+
+func TestUDPEchoStatus(t *testing.T) {
+	tests := map[string]struct {
+		err    error
+		expect string
+	}{
+		"with nil error": {
+			err:    nil,
+			expect: UDPEchoStatusOK,
+		},
+		"with echo mismatch": {
+			err:    errUDPEchoMismatch,
+			expect: UDPEchoStatusEchoMismatch,
+		},
+		"with timeout": {
+			err:    &netxlite.ErrWrapper{Failure: netxlite.FailureGenericTimeoutError},
+			expect: UDPEchoStatusNoResponse,
+		},
+		"with connection refused": {
+			err:    &netxlite.ErrWrapper{Failure: netxlite.FailureConnectionRefused},
+			expect: UDPEchoStatusUnreachable,
+		},
+		"with other errors": {
+			err:    errors.New("mocked error"),
+			expect: UDPEchoStatusError,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if status := udpEchoStatus(tt.err); status != tt.expect {
+				t.Fatal("unexpected status", status)
+			}
+		})
+	}
+}
+
+func TestIsUDPEchoNonce(t *testing.T) {
+	tests := map[string]struct {
+		data   string
+		expect bool
+	}{
+		"with a nonce": {
+			data:   "abcdefghijklmnopqrstuvwxyzABCDEF",
+			expect: true,
+		},
+		"with a short nonce": {
+			data:   "abcdef",
+			expect: false,
+		},
+		"with a long nonce": {
+			data:   "abcdefghijklmnopqrstuvwxyzABCDEFG",
+			expect: false,
+		},
+		"with non-letter characters": {
+			data:   "abcdefghijklmnopqrstuvwxyz012345",
+			expect: false,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsUDPEchoNonce([]byte(tt.data)); got != tt.expect {
+				t.Fatal("unexpected result", got)
+			}
+		})
+	}
+}
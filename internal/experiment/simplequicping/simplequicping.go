@@ -169,7 +169,7 @@ func (m *Measurer) quicHandshake(ctx context.Context, index int64,
 	}
 	sni := m.config.sni(address)
 	alpn := strings.Split(m.config.alpn(), " ")
-	trace := measurexlite.NewTrace(index, zeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
 	ol := measurexlite.NewOperationLogger(logger, "SimpleQUICPing #%d %s %s %v", index, address, sni, alpn)
 	listener := netxlite.NewQUICListener()
 	dialer := trace.NewQUICDialerWithoutResolver(listener, logger)
@@ -21,8 +21,8 @@ const (
 
 // Config contains the experiment configuration.
 type Config struct {
-	// Delay is the delay between each repetition (in milliseconds).
-	Delay int64 `ooni:"number of milliseconds to wait before sending each ping"`
+	// Delay is the delay between each repetition.
+	Delay time.Duration `ooni:"time to wait before sending each ping (e.g., 500ms)"`
 
 	// Repetitions is the number of repetitions for each ping.
 	Repetitions int64 `ooni:"number of times to repeat the measurement"`
@@ -30,7 +30,7 @@ type Config struct {
 
 func (c *Config) delay() time.Duration {
 	if c.Delay > 0 {
-		return time.Duration(c.Delay) * time.Millisecond
+		return c.Delay
 	}
 	return time.Second
 }
@@ -132,7 +132,7 @@ func (m *Measurer) tcpConnect(ctx context.Context, index int64,
 	// TODO(bassosimone): make the timeout user-configurable
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
-	trace := measurexlite.NewTrace(index, zeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
 	dialer := trace.NewDialerWithoutResolver(logger)
 	ol := measurexlite.NewOperationLogger(logger, "TCPPing #%d %s", index, address)
 	conn, err := dialer.DialContext(ctx, "tcp", address)
@@ -34,7 +34,7 @@ func TestMeasurer_run(t *testing.T) {
 	// runHelper is an helper function to run this set of tests.
 	runHelper := func(input string) (*model.Measurement, model.ExperimentMeasurer, error) {
 		m := NewExperimentMeasurer(Config{
-			Delay:       1, // millisecond
+			Delay:       time.Millisecond,
 			Repetitions: expectedPings,
 		})
 		if m.ExperimentName() != "tcpping" {
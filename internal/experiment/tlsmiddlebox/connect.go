@@ -15,7 +15,7 @@ import (
 // TCPConnect performs a TCP connect to filter working addresses
 func (m *Measurer) TCPConnect(ctx context.Context, index int64, zeroTime time.Time,
 	logger model.Logger, address string, tk *TestKeys) error {
-	trace := measurexlite.NewTrace(index, zeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
 	dialer := trace.NewDialerWithoutResolver(logger)
 	ol := measurexlite.NewOperationLogger(logger, "TCPConnect #%d %s", index, address)
 	conn, err := dialer.DialContext(ctx, "tcp", address)
@@ -16,7 +16,7 @@ import (
 func (m *Measurer) DNSLookup(ctx context.Context, index int64, zeroTime time.Time,
 	logger model.Logger, domain string, tk *TestKeys) ([]string, error) {
 	url := m.config.resolverURL()
-	trace := measurexlite.NewTrace(index, zeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
 	ol := measurexlite.NewOperationLogger(logger, "DNSLookup #%d, %s, %s", index, url, domain)
 	// TODO(DecFox, bassosimone): We are currently using the DoH resolver, we will
 	// switch to the TRR2 resolver once we have it in measurexlite
@@ -68,7 +68,7 @@ func (m *Measurer) traceWithIncreasingTTLs(ctx context.Context, index int64, zer
 func (m *Measurer) handshakeWithTTL(ctx context.Context, index int64, zeroTime time.Time, logger model.Logger,
 	address string, sni string, ttl int, tr *IterativeTrace, wg *sync.WaitGroup) {
 	defer wg.Done()
-	trace := measurexlite.NewTrace(index, zeroTime)
+	trace := measurexlite.NewTraceWithContext(ctx, index, zeroTime)
 	// 1. Connect to the target IP
 	// TODO(DecFox, bassosimone): Do we need a trace for this TCP connect?
 	d := NewDialerTTLWrapper()
@@ -330,6 +330,40 @@ func TestConfigurerNewConfigurationResolverUDP(t *testing.T) {
 	}
 }
 
+func TestConfigurerNewConfigurationResolverDoQ(t *testing.T) {
+	saver := new(tracex.Saver)
+	configurer := urlgetter.Configurer{
+		Config: urlgetter.Config{
+			ResolverURL: "quic://94.140.14.14:853",
+		},
+		Logger: log.Log,
+		Saver:  saver,
+	}
+	configuration, err := configurer.NewConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer configuration.CloseIdleConnections()
+	sr, ok := configuration.HTTPConfig.BaseResolver.(*netxlite.SerialResolver)
+	if !ok {
+		t.Fatal("not the resolver we expected")
+	}
+	stxp, ok := sr.Txp.(*tracex.DNSTransportSaver)
+	if !ok {
+		t.Fatal("not the DNS transport we expected")
+	}
+	doqtxp, ok := stxp.DNSTransport.(*netxlite.DNSOverQUICTransport)
+	if !ok {
+		t.Fatal("not the DNS transport we expected")
+	}
+	if doqtxp.Address() != "94.140.14.14:853" {
+		t.Fatal("not the DoQ address we expected")
+	}
+	if doqtxp.Network() != "doq" {
+		t.Fatal("not the DoQ network we expected")
+	}
+}
+
 func TestConfigurerNewConfigurationDNSCacheInvalidString(t *testing.T) {
 	saver := new(tracex.Saver)
 	configurer := urlgetter.Configurer{
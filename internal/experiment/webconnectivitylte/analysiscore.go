@@ -33,6 +33,10 @@ const (
 
 	// analysisFlagSuccess indicates we did not detect any blocking.
 	analysisFlagSuccess
+
+	// analysisFlagTLSMITM indicates that a TLS handshake failed and the
+	// certificates we received differ from the ones seen by the TH.
+	analysisFlagTLSMITM
 )
 
 // analysisToplevel is the toplevel function that analyses the results
@@ -80,7 +84,8 @@ const (
 //	+--------------------------------------+----------------+-------------+
 //	| (& TCPIPBlocking) != 0               | "tcp_ip"       | false       |
 //	+--------------------------------------+----------------+-------------+
-//	| (& (TLSBlocking|HTTPBlocking)) != 0  | "http-failure" | false       |
+//	| (& (TLSBlocking|TLSMITM|             | "http-failure" | false       |
+//	|      HTTPBlocking)) != 0             |                |             |
 //	+--------------------------------------+----------------+-------------+
 //	| (& HTTPDiff) != 0                    | "http-diff"    | false       |
 //	+--------------------------------------+----------------+-------------+
@@ -89,7 +94,9 @@ const (
 //	| otherwise                            | null           | null        |
 //	+--------------------------------------+----------------+-------------+
 //
-// It's a very simple rule, that should preserve previous semantics.
+// It's a very simple rule, that should preserve previous semantics. Because
+// the TLSMITM flag maps to "http-failure" like TLSBlocking, consumers should
+// use x_tls_mitm (see TestKeys.TLSMITM) to distinguish a TLS man in the middle.
 //
 // As an improvement over Web Connectivity v0.4, we also attempt to identify
 // special subcases of a null, null result to provide the user with more information.
@@ -121,7 +128,7 @@ func (tk *TestKeys) analysisToplevel(logger model.Logger) {
 			tk.BlockingFlags, tk.Accessible, tk.Blocking,
 		)
 
-	case (tk.BlockingFlags & (analysisFlagTLSBlocking | analysisFlagHTTPBlocking)) != 0:
+	case (tk.BlockingFlags & (analysisFlagTLSBlocking | analysisFlagTLSMITM | analysisFlagHTTPBlocking)) != 0:
 		tk.Blocking = "http-failure"
 		tk.Accessible = false
 		logger.Warnf("ANOMALY: flags=%d, accessible=%+v, blocking=%+v",
@@ -4,12 +4,27 @@ package webconnectivitylte
 // TLS analysis
 //
 
-import "github.com/ooni/probe-cli/v3/internal/model"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
 
 // analysisTLSToplevel is the toplevel analysis function for TLS.
 //
 // This algorithm aims to flag the TLS endpoints that failed unreasonably
 // compared to what the TH has observed for the same endpoints.
+//
+// When the TH includes the fingerprints of the certificate chain it has
+// seen and none of the certificates we received belongs to such a chain, we
+// set the .TLSMITM field (i.e., x_tls_mitm) because this is a signal of a
+// TLS man-in-the-middle attack. We do that regardless of whether the handshake
+// failed, since the probe may trust the attacker's CA. Additionally, when the
+// handshake failed, we set the analysisFlagTLSMITM flag rather than the
+// analysisFlagTLSBlocking flag. We do not set any flag when the handshake
+// succeeded, because a server (e.g., a CDN) may legitimately use different
+// certificates depending on the client location.
 func (tk *TestKeys) analysisTLSToplevel(logger model.Logger) {
 	// if we don't have a control result, do nothing.
 	if tk.Control == nil || len(tk.Control.TLSHandshake) <= 0 {
@@ -18,11 +33,6 @@ func (tk *TestKeys) analysisTLSToplevel(logger model.Logger) {
 
 	// walk the list of probe results and compare with TH results
 	for _, entry := range tk.TLSHandshakes {
-		// skip successful entries
-		failure := entry.Failure
-		if failure == nil {
-			continue // did not fail
-		}
 		epnt := entry.Address
 
 		// TODO(bassosimone,kelmenhorst): if, in the future, we choose to
@@ -39,6 +49,31 @@ func (tk *TestKeys) analysisTLSToplevel(logger model.Logger) {
 			// precise error mapping should be a job for the pipeline.
 			continue
 		}
+		mismatch := analysisTLSCertificatesMismatch(entry.PeerCertificates, ctrl.CertificateChainFingerprints)
+		if mismatch {
+			tk.TLSMITM = true
+		}
+		failure := entry.Failure
+		if failure == nil {
+			if mismatch {
+				logger.Warnf(
+					"TLS: handshake for %s succeeded with certificates not seen by the TH (see #%d)",
+					epnt,
+					entry.TransactionID,
+				)
+			}
+			continue // did not fail
+		}
+		if mismatch {
+			logger.Warnf(
+				"TLS: unexpected failure %s for %s with certificates not seen by the TH (see #%d)",
+				*failure,
+				epnt,
+				entry.TransactionID,
+			)
+			tk.BlockingFlags |= analysisFlagTLSMITM
+			continue
+		}
 		logger.Warnf(
 			"TLS: unexpected failure %s for %s (see #%d)",
 			*failure,
@@ -48,3 +83,26 @@ func (tk *TestKeys) analysisTLSToplevel(logger model.Logger) {
 		tk.BlockingFlags |= analysisFlagTLSBlocking
 	}
 }
+
+// analysisTLSCertificatesMismatch returns true when we have both the certificates
+// received by the probe and the fingerprints of the chain seen by the TH, and none
+// of the probe certificates belongs to the chain seen by the TH. We compare with
+// the whole chain because, on verification errors, the probe may only have saved
+// the certificate that failed verification, which is not always the leaf.
+func analysisTLSCertificatesMismatch(
+	probeCerts []model.ArchivalMaybeBinaryData, thFingerprints []string) bool {
+	if len(probeCerts) <= 0 || len(thFingerprints) <= 0 {
+		return false // cannot say anything
+	}
+	thChain := make(map[string]bool)
+	for _, fp := range thFingerprints {
+		thChain[fp] = true
+	}
+	for _, cert := range probeCerts {
+		sum := sha256.Sum256([]byte(cert.Value))
+		if thChain[hex.EncodeToString(sum[:])] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package webconnectivitylte
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestAnalysisTLSToplevel(t *testing.T) {
+	fingerprint := func(value string) string {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:])
+	}
+	failure := netxlite.FailureSSLUnknownAuthority
+
+	type testcase struct {
+		name         string
+		probeCerts   []string
+		probeFailure *string
+		thChain      []string
+		thFailure    *string
+		expect       int64
+		expectMITM   bool
+	}
+
+	cases := []testcase{{
+		name:         "the TH failed as well",
+		probeCerts:   []string{"mitm"},
+		probeFailure: &failure,
+		thChain:      nil,
+		thFailure:    &failure,
+		expect:       0,
+		expectMITM:   false,
+	}, {
+		name:         "old TH without fingerprints",
+		probeCerts:   []string{"mitm"},
+		probeFailure: &failure,
+		thChain:      nil,
+		expect:       analysisFlagTLSBlocking,
+		expectMITM:   false,
+	}, {
+		name:         "the probe did not receive any certificate",
+		probeCerts:   nil,
+		probeFailure: &failure,
+		thChain:      []string{fingerprint("leaf"), fingerprint("intermediate")},
+		expect:       analysisFlagTLSBlocking,
+		expectMITM:   false,
+	}, {
+		name:         "the probe certificate belongs to the TH chain",
+		probeCerts:   []string{"intermediate"},
+		probeFailure: &failure,
+		thChain:      []string{fingerprint("leaf"), fingerprint("intermediate")},
+		expect:       analysisFlagTLSBlocking,
+		expectMITM:   false,
+	}, {
+		name:         "the probe certificates do not belong to the TH chain",
+		probeCerts:   []string{"mitm", "mitm-ca"},
+		probeFailure: &failure,
+		thChain:      []string{fingerprint("leaf"), fingerprint("intermediate")},
+		expect:       analysisFlagTLSMITM,
+		expectMITM:   true,
+	}, {
+		name:         "successful handshake with the same chain",
+		probeCerts:   []string{"leaf", "intermediate"},
+		probeFailure: nil,
+		thChain:      []string{fingerprint("leaf"), fingerprint("intermediate")},
+		expect:       0,
+		expectMITM:   false,
+	}, {
+		name:         "successful handshake with a different chain",
+		probeCerts:   []string{"mitm", "mitm-ca"},
+		probeFailure: nil,
+		thChain:      []string{fingerprint("leaf"), fingerprint("intermediate")},
+		expect:       0,
+		expectMITM:   true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var probeCerts []model.ArchivalMaybeBinaryData
+			for _, cert := range tc.probeCerts {
+				probeCerts = append(probeCerts, model.ArchivalMaybeBinaryData{Value: cert})
+			}
+			tk := &TestKeys{
+				TLSHandshakes: []*model.ArchivalTLSOrQUICHandshakeResult{{
+					Address:          "93.184.216.34:443",
+					Failure:          tc.probeFailure,
+					PeerCertificates: probeCerts,
+				}},
+				Control: &model.THResponse{
+					TLSHandshake: map[string]model.THTLSHandshakeResult{
+						"93.184.216.34:443": {
+							ServerName:                   "www.example.com",
+							Status:                       tc.thFailure == nil,
+							Failure:                      tc.thFailure,
+							CertificateChainFingerprints: tc.thChain,
+						},
+					},
+				},
+			}
+			tk.analysisTLSToplevel(model.DiscardLogger)
+			if tk.BlockingFlags != tc.expect {
+				t.Fatal("unexpected flags", tk.BlockingFlags, tc.expect)
+			}
+			if tk.TLSMITM != tc.expectMITM {
+				t.Fatal("unexpected x_tls_mitm", tk.TLSMITM, tc.expectMITM)
+			}
+		})
+	}
+}
+
+func TestAnalysisToplevelWithTLSMITM(t *testing.T) {
+	// the probe received a chain different from the one seen by the TH and
+	// the handshake failed because the probe does not trust the MITM CA
+	failure := netxlite.FailureSSLUnknownAuthority
+	thChain := []string{}
+	for _, cert := range []string{"leaf", "intermediate"} {
+		sum := sha256.Sum256([]byte(cert))
+		thChain = append(thChain, hex.EncodeToString(sum[:]))
+	}
+	tk := NewTestKeys()
+	tk.TLSHandshakes = []*model.ArchivalTLSOrQUICHandshakeResult{{
+		Address: "93.184.216.34:443",
+		Failure: &failure,
+		PeerCertificates: []model.ArchivalMaybeBinaryData{
+			{Value: "mitm"},
+			{Value: "mitm-ca"},
+		},
+	}}
+	tk.Control = &model.THResponse{
+		TLSHandshake: map[string]model.THTLSHandshakeResult{
+			"93.184.216.34:443": {
+				ServerName:                   "www.example.com",
+				Status:                       true,
+				CertificateChainFingerprints: thChain,
+			},
+		},
+	}
+	tk.analysisToplevel(model.DiscardLogger)
+	if tk.Blocking != "http-failure" || tk.Accessible != false {
+		t.Fatal("unexpected blocking/accessible", tk.Blocking, tk.Accessible)
+	}
+	if (tk.BlockingFlags & analysisFlagTLSMITM) == 0 {
+		t.Fatal("expected the TLS MITM flag", tk.BlockingFlags)
+	}
+	data, err := json.Marshal(tk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"x_tls_mitm":true`)) {
+		t.Fatal("expected x_tls_mitm to be true", string(data))
+	}
+}
@@ -67,6 +67,14 @@ type CleartextFlow struct {
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
+	// Session is the OPTIONAL session, used for redirects to issue
+	// the control request for the next hop of the redirect chain.
+	Session model.ExperimentSession
+
+	// TestHelpers is the OPTIONAL list of test helpers, used for redirects
+	// to issue the control request for the next hop of the redirect chain.
+	TestHelpers []model.OOAPIService
+
 	// UDPAddress is the OPTIONAL address of the UDP resolver to use. If this
 	// field is not set we use a default one (e.g., `8.8.8.8:53`).
 	UDPAddress string
@@ -96,7 +104,7 @@ func (t *CleartextFlow) Run(parentCtx context.Context, index int64) error {
 	}
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(parentCtx, index, t.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -285,13 +293,13 @@ func (t *CleartextFlow) maybeFollowRedirects(ctx context.Context, resp *http.Res
 			IDGenerator:  t.IDGenerator,
 			Logger:       t.Logger,
 			NumRedirects: t.NumRedirects,
-			TestKeys:     t.TestKeys,
+			TestKeys:     t.TestKeys.newRedirectHop(location, resp.Request.URL.String()),
 			URL:          location,
 			ZeroTime:     t.ZeroTime,
 			WaitGroup:    t.WaitGroup,
 			Referer:      resp.Request.URL.String(),
-			Session:      nil, // no need to issue another control request
-			TestHelpers:  nil, // ditto
+			Session:      t.Session,
+			TestHelpers:  t.TestHelpers,
 			UDPAddress:   t.UDPAddress,
 		}
 		resolvers.Start(ctx)
@@ -61,10 +61,11 @@ type DNSResolvers struct {
 	Referer string
 
 	// Session is the OPTIONAL session. If the session is set, we will use
-	// it to start the task that issues the control request. This request must
-	// only be sent during the first iteration. It would be pointless to
-	// issue such a request for subsequent redirects, because the TH will
-	// always follow the redirect chain caused by the provided URL.
+	// it to start the task that issues the control request. We issue a control
+	// request for the first hop and for each redirect hop whose domain differs
+	// from the one of the previous hop, because the TH resolves and connects to
+	// the domain of the URL we send it. A redirect hop for the same domain uses
+	// the control of the previous hop instead (see RedirectHop).
 	Session model.ExperimentSession
 
 	// TestHelpers is the OPTIONAL list of test helpers. If the list is
@@ -210,7 +211,7 @@ func (t *DNSResolvers) lookupHostSystem(parentCtx context.Context, out chan<- []
 	index := t.IDGenerator.Add(1)
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(parentCtx, index, t.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -237,7 +238,7 @@ func (t *DNSResolvers) lookupHostUDP(parentCtx context.Context, udpAddress strin
 	index := t.IDGenerator.Add(1)
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(parentCtx, index, t.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -375,7 +376,7 @@ func (t *DNSResolvers) lookupHostDNSOverHTTPS(parentCtx context.Context, out cha
 	index := t.IDGenerator.Add(1)
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(parentCtx, index, t.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -445,6 +446,8 @@ func (t *DNSResolvers) startCleartextFlows(
 			HostHeader:      t.URL.Host,
 			PrioSelector:    ps,
 			Referer:         t.Referer,
+			Session:         t.Session,
+			TestHelpers:     t.TestHelpers,
 			UDPAddress:      t.UDPAddress,
 			URLPath:         t.URL.Path,
 			URLRawQuery:     t.URL.RawQuery,
@@ -489,6 +492,8 @@ func (t *DNSResolvers) startSecureFlows(
 			HostHeader:      t.URL.Host,
 			PrioSelector:    ps,
 			Referer:         t.Referer,
+			Session:         t.Session,
+			TestHelpers:     t.TestHelpers,
 			UDPAddress:      t.UDPAddress,
 			URLPath:         t.URL.Path,
 			URLRawQuery:     t.URL.RawQuery,
@@ -497,15 +502,14 @@ func (t *DNSResolvers) startSecureFlows(
 	}
 }
 
-// maybeStartControlFlow starts the control flow iff .Session and .TestHelpers are set.
+// maybeStartControlFlow starts the control flow iff .Session and .TestHelpers are
+// set and this is the first hop or a redirect hop to another domain.
 func (t *DNSResolvers) maybeStartControlFlow(
 	ctx context.Context,
 	ps *prioritySelector,
 	addresses []DNSEntry,
 ) {
-	// note: for subsequent requests we don't set .Session and .TestHelpers hence
-	// we are not going to query the test helper more than once
-	if t.Session != nil && len(t.TestHelpers) > 0 {
+	if t.Session != nil && len(t.TestHelpers) > 0 && redirectHopNeedsControl(t.Referer, t.URL) {
 		var addrs []string
 		for _, addr := range addresses {
 			addrs = append(addrs, addr.Addr)
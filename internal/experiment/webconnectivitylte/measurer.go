@@ -37,7 +37,7 @@ func (m *Measurer) ExperimentName() string {
 
 // ExperimentVersion implements model.ExperimentMeasurer.
 func (m *Measurer) ExperimentVersion() string {
-	return "0.5.23"
+	return "0.5.25"
 }
 
 // Run implements model.ExperimentMeasurer.
@@ -106,7 +106,7 @@ func (m *Measurer) Run(ctx context.Context, args *model.ExperimentArgs) error {
 		IDGenerator:  idGenerator,
 		Logger:       sess.Logger(),
 		NumRedirects: NewNumRedirects(5),
-		TestKeys:     tk,
+		TestKeys:     tk.newRedirectHop(URL, ""),
 		URL:          URL,
 		ZeroTime:     measurement.MeasurementStartTimeSaved,
 		WaitGroup:    wg,
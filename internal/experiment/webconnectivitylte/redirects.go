@@ -1,6 +1,12 @@
 package webconnectivitylte
 
-import "sync/atomic"
+import (
+	"net/url"
+	"sync/atomic"
+
+	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
 
 // NumRedirects counts the number of redirects left.
 type NumRedirects struct {
@@ -21,3 +27,154 @@ func NewNumRedirects(n int64) *NumRedirects {
 func (nr *NumRedirects) CanFollowOneMoreRedirect() bool {
 	return nr.count.Add(-1) > 0
 }
+
+// RedirectHop is a hop of the redirect chain. Each hop is a sub-measurement
+// referencing the DNS, TCP, TLS, and HTTP observations collected when measuring
+// the hop's URL and containing the result of analyzing such observations in
+// isolation. To avoid duplicating observations, we reference them using their
+// indexes inside the toplevel test keys. We issue a TH request for the first hop
+// and for each hop whose domain differs from the one of the previous hop and we
+// analyze each hop using its own control or the control of the previous hop.
+type RedirectHop struct {
+	// Index is the index of this hop in the redirect chain.
+	Index int64 `json:"index"`
+
+	// URL is the URL measured by this hop.
+	URL string `json:"url"`
+
+	// Referer is the URL of the previous hop or empty for the first hop.
+	Referer string `json:"referer"`
+
+	// QueryIndexes contains the indexes of this hop's entries in TestKeys.Queries.
+	QueryIndexes []int64 `json:"query_indexes"`
+
+	// RequestIndexes contains the indexes of this hop's entries in TestKeys.Requests.
+	RequestIndexes []int64 `json:"request_indexes"`
+
+	// TCPConnectIndexes contains the indexes of this hop's entries in TestKeys.TCPConnect.
+	TCPConnectIndexes []int64 `json:"tcp_connect_indexes"`
+
+	// TLSHandshakeIndexes contains the indexes of this hop's entries in TestKeys.TLSHandshakes.
+	TLSHandshakeIndexes []int64 `json:"tls_handshake_indexes"`
+
+	// ControlHop is the index of the hop whose control we used to analyze this hop.
+	ControlHop int64 `json:"control_hop"`
+
+	// ControlRequest is the control request we sent for this hop. It is nil for
+	// the first hop, whose control request is TestKeys.ControlRequest, and for hops
+	// using the control of a previous hop (see ControlHop).
+	ControlRequest *webconnectivity.ControlRequest `json:"control_request"`
+
+	// Control is like ControlRequest but contains the TH's response.
+	Control *webconnectivity.ControlResponse `json:"control"`
+
+	// ControlFailure is like ControlRequest but contains the control failure.
+	ControlFailure *string `json:"control_failure"`
+
+	// DNSFlags is like TestKeys.DNSFlags but for this hop.
+	DNSFlags int64 `json:"dns_flags"`
+
+	// DNSExperimentFailure is like TestKeys.DNSExperimentFailure but for this hop.
+	DNSExperimentFailure *string `json:"dns_experiment_failure"`
+
+	// DNSConsistency is like TestKeys.DNSConsistency but for this hop.
+	DNSConsistency string `json:"dns_consistency"`
+
+	// HTTPExperimentFailure is like TestKeys.HTTPExperimentFailure but for this hop.
+	HTTPExperimentFailure *string `json:"http_experiment_failure"`
+
+	// BlockingFlags is like TestKeys.BlockingFlags but for this hop.
+	BlockingFlags int64 `json:"blocking_flags"`
+
+	// Blocking is like TestKeys.Blocking but for this hop.
+	Blocking any `json:"blocking"`
+
+	// Accessible is like TestKeys.Accessible but for this hop.
+	Accessible any `json:"accessible"`
+
+	// tk contains the test keys collecting this hop's observations.
+	tk *TestKeys
+}
+
+// finalize analyzes the observations collected by this hop. The parent
+// argument contains the toplevel test keys, which we use to fill the DNS
+// lookups of hops whose domain was resolved by previous hops, to obtain the
+// control of previous hops, and to compute the indexes of the observations.
+// The caller MUST finalize the hops in order.
+//
+// Because the TH follows redirects, its HTTP response is the one of the
+// final hop. Therefore, we do not compare the redirect responses that we
+// receive for intermediate hops with the TH's response (see analysisHTTPToplevel).
+func (hop *RedirectHop) finalize(logger model.Logger, parent *TestKeys) {
+	tk := hop.tk
+	if URL, err := url.Parse(hop.URL); err == nil && len(tk.Queries) <= 0 {
+		for _, query := range parent.Queries {
+			if query.Hostname == URL.Hostname() {
+				tk.Queries = append(tk.Queries, query)
+			}
+		}
+	}
+	hop.ControlHop = hop.Index
+	switch {
+	case tk.ControlRequest != nil && hop.Index > 0:
+		hop.ControlRequest = tk.ControlRequest
+		hop.Control = tk.Control
+		hop.ControlFailure = tk.ControlFailure
+	case tk.ControlRequest == nil && hop.Index > 0:
+		prev := parent.RedirectChain[hop.Index-1]
+		hop.ControlHop = prev.ControlHop
+		tk.ControlRequest = prev.tk.ControlRequest
+		tk.Control = prev.tk.Control
+		tk.ControlFailure = prev.tk.ControlFailure
+	}
+	tk.analysisToplevel(logger)
+	hop.QueryIndexes = redirectHopIndexes(tk.Queries, parent.Queries)
+	hop.RequestIndexes = redirectHopIndexes(tk.Requests, parent.Requests)
+	hop.TCPConnectIndexes = redirectHopIndexes(tk.TCPConnect, parent.TCPConnect)
+	hop.TLSHandshakeIndexes = redirectHopIndexes(tk.TLSHandshakes, parent.TLSHandshakes)
+	hop.DNSFlags = tk.DNSFlags
+	hop.DNSExperimentFailure = tk.DNSExperimentFailure
+	hop.DNSConsistency = tk.DNSConsistency
+	hop.HTTPExperimentFailure = tk.HTTPExperimentFailure
+	hop.BlockingFlags = tk.BlockingFlags
+	hop.Blocking = tk.Blocking
+	hop.Accessible = tk.Accessible
+}
+
+// redirectHopIndexes returns the indexes inside all of the entries in hop.
+func redirectHopIndexes[T any](hop []*T, all []*T) []int64 {
+	indexes := map[*T]int64{}
+	for idx, entry := range all {
+		indexes[entry] = int64(idx)
+	}
+	out := []int64{}
+	for _, entry := range hop {
+		if idx, found := indexes[entry]; found {
+			out = append(out, idx)
+		}
+	}
+	return out
+}
+
+// redirectHopNeedsControl returns whether we should issue a control request
+// for the hop measuring URL, given the URL of the previous hop as referer. We do
+// that for the first hop, whose referer is empty, and for hops changing the
+// domain, because the TH resolves and connects to the domain of the URL.
+func redirectHopNeedsControl(referer string, URL *url.URL) bool {
+	prev, err := url.Parse(referer)
+	return referer == "" || err != nil || prev.Hostname() != URL.Hostname()
+}
+
+// analysisHTTPIsRedirect returns whether the given request received
+// a redirect response, which the probe follows at the next hop.
+func analysisHTTPIsRedirect(request *model.ArchivalHTTPRequestResult) bool {
+	if request.Failure != nil {
+		return false
+	}
+	switch request.Response.Code {
+	case 301, 302, 307, 308:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,260 @@
+package webconnectivitylte
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/experiment/webconnectivity"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestNewRedirectHop(t *testing.T) {
+	first, _ := url.Parse("http://example.com/")
+	second, _ := url.Parse("https://www.example.com/")
+	tk := NewTestKeys()
+	hop0 := tk.newRedirectHop(first, "")
+	hop1 := hop0.newRedirectHop(second, first.String())
+
+	t.Run("hops are appended to the toplevel redirect chain", func(t *testing.T) {
+		if len(tk.RedirectChain) != 2 {
+			t.Fatal("unexpected redirect chain length", len(tk.RedirectChain))
+		}
+		for idx, expectURL := range []string{first.String(), second.String()} {
+			hop := tk.RedirectChain[idx]
+			if hop.Index != int64(idx) {
+				t.Fatal("unexpected index", hop.Index)
+			}
+			if hop.URL != expectURL {
+				t.Fatal("unexpected URL", hop.URL)
+			}
+		}
+		if tk.RedirectChain[1].Referer != first.String() {
+			t.Fatal("unexpected referer", tk.RedirectChain[1].Referer)
+		}
+		if hop0.parent != tk || hop1.parent != tk {
+			t.Fatal("hops should point to the toplevel test keys")
+		}
+	})
+
+	t.Run("observations are forwarded to the toplevel test keys", func(t *testing.T) {
+		hop1.AppendQueries(&model.ArchivalDNSLookupResult{Hostname: second.Hostname()})
+		hop1.AppendTCPConnectResults(&model.ArchivalTCPConnectResult{IP: "8.8.8.8"})
+		if len(hop1.Queries) != 1 || len(tk.Queries) != 1 || len(hop0.Queries) != 0 {
+			t.Fatal("unexpected queries")
+		}
+		if len(hop1.TCPConnect) != 1 || len(tk.TCPConnect) != 1 || len(hop0.TCPConnect) != 0 {
+			t.Fatal("unexpected TCP connect results")
+		}
+	})
+
+	t.Run("only the first hop sets the toplevel control", func(t *testing.T) {
+		cresp0 := &webconnectivity.ControlResponse{}
+		cresp1 := &webconnectivity.ControlResponse{}
+		hop0.SetControl(cresp0)
+		hop1.SetControl(cresp1)
+		if tk.Control != cresp0 || hop0.Control != cresp0 || hop1.Control != cresp1 {
+			t.Fatal("unexpected control")
+		}
+		th0 := &model.OOAPIService{Address: "https://0.th.ooni.org"}
+		th1 := &model.OOAPIService{Address: "https://1.th.ooni.org"}
+		hop0.setTestHelper(th0)
+		hop1.setTestHelper(th1)
+		if hop1.getTestHelper() != th0 || tk.getTestHelper() != th0 {
+			t.Fatal("unexpected test helper")
+		}
+	})
+
+	t.Run("hops do not have their own ancillary observations", func(t *testing.T) {
+		hop1.WithTestKeysDoH(func(tkd *TestKeysDoH) {
+			tkd.TCPConnect = append(tkd.TCPConnect, &model.ArchivalTCPConnectResult{})
+		})
+		hop1.SetClientResolver("130.192.91.211")
+		if len(tk.DoH.TCPConnect) != 1 || len(hop1.DoH.TCPConnect) != 0 {
+			t.Fatal("unexpected DoH observations")
+		}
+		if tk.ClientResolver != "130.192.91.211" || hop1.ClientResolver != "" {
+			t.Fatal("unexpected client resolver")
+		}
+	})
+}
+
+func TestFinalizeRedirectChain(t *testing.T) {
+	t.Run("we attribute blocking to the hop where it happens", func(t *testing.T) {
+		first, _ := url.Parse("https://example.com/")
+		second, _ := url.Parse("https://www.example.com/")
+		tk := NewTestKeys()
+		hop0 := tk.newRedirectHop(first, "")
+		hop1 := hop0.newRedirectHop(second, first.String())
+
+		// the second hop has no control, so it uses the one of the first hop
+		hop0.SetControlRequest(&webconnectivity.ControlRequest{HTTPRequest: first.String()})
+		hop0.SetControl(&webconnectivity.ControlResponse{
+			TLSHandshake: map[string]model.THTLSHandshakeResult{
+				"93.184.216.34:443": {ServerName: second.Hostname(), Status: true},
+			},
+		})
+		hop0.AppendQueries(&model.ArchivalDNSLookupResult{Hostname: second.Hostname()})
+
+		// the second hop sees a TLS failure while the TH succeeds
+		failure := netxlite.FailureConnectionReset
+		hop1.AppendTLSHandshakes(&model.ArchivalTLSOrQUICHandshakeResult{
+			Address: "93.184.216.34:443",
+			Failure: &failure,
+		})
+
+		tk.Finalize(model.DiscardLogger)
+
+		if tk.BlockingHop == nil || *tk.BlockingHop != 1 {
+			t.Fatal("expected blocking to be attributed to the second hop")
+		}
+		if tk.RedirectChain[0].BlockingFlags != 0 {
+			t.Fatal("unexpected first hop flags", tk.RedirectChain[0].BlockingFlags)
+		}
+		if (tk.RedirectChain[1].BlockingFlags & analysisFlagTLSBlocking) == 0 {
+			t.Fatal("unexpected second hop flags", tk.RedirectChain[1].BlockingFlags)
+		}
+		if tk.RedirectChain[1].Blocking != "http-failure" {
+			t.Fatal("unexpected second hop blocking", tk.RedirectChain[1].Blocking)
+		}
+		if diff := cmp.Diff([]int64{0}, tk.RedirectChain[1].QueryIndexes); diff != "" {
+			t.Fatal("expected the second hop to reference the cached DNS lookup", diff)
+		}
+		if diff := cmp.Diff([]int64{0}, tk.RedirectChain[1].TLSHandshakeIndexes); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]int64{}, tk.RedirectChain[0].TLSHandshakeIndexes); diff != "" {
+			t.Fatal(diff)
+		}
+		if tk.RedirectChain[1].ControlHop != 0 || tk.RedirectChain[1].Control != nil {
+			t.Fatal("expected the second hop to use the control of the first hop")
+		}
+	})
+
+	t.Run("we analyze a cross-domain hop using its own control", func(t *testing.T) {
+		first, _ := url.Parse("https://example.com/")
+		second, _ := url.Parse("https://www.example.org/")
+		tk := NewTestKeys()
+		hop0 := tk.newRedirectHop(first, "")
+		hop1 := hop0.newRedirectHop(second, first.String())
+
+		hop0.SetControlRequest(&webconnectivity.ControlRequest{HTTPRequest: first.String()})
+		hop0.SetControl(&webconnectivity.ControlResponse{
+			DNS: webconnectivity.ControlDNSResult{Addrs: []string{"93.184.216.34"}},
+		})
+		hop0.AppendQueries(&model.ArchivalDNSLookupResult{
+			Answers:   []model.ArchivalDNSAnswer{{AnswerType: "A", IPv4: "93.184.216.34"}},
+			Engine:    "getaddrinfo",
+			Hostname:  first.Hostname(),
+			QueryType: "ANY",
+		})
+
+		// the TH resolves the second domain while the probe cannot
+		hop1.SetControlRequest(&webconnectivity.ControlRequest{HTTPRequest: second.String()})
+		hop1.SetControl(&webconnectivity.ControlResponse{
+			DNS: webconnectivity.ControlDNSResult{Addrs: []string{"93.184.215.14"}},
+		})
+		failure := netxlite.FailureDNSNXDOMAINError
+		hop1.AppendQueries(&model.ArchivalDNSLookupResult{
+			Engine:    "getaddrinfo",
+			Failure:   &failure,
+			Hostname:  second.Hostname(),
+			QueryType: "ANY",
+		})
+
+		tk.Finalize(model.DiscardLogger)
+
+		if tk.BlockingHop == nil || *tk.BlockingHop != 1 {
+			t.Fatal("expected blocking to be attributed to the second hop")
+		}
+		if (tk.RedirectChain[0].BlockingFlags & analysisFlagDNSBlocking) != 0 {
+			t.Fatal("unexpected first hop flags", tk.RedirectChain[0].BlockingFlags)
+		}
+		hop := tk.RedirectChain[1]
+		if (hop.BlockingFlags & analysisFlagDNSBlocking) == 0 {
+			t.Fatal("unexpected second hop flags", hop.BlockingFlags)
+		}
+		if hop.Blocking != "dns" || hop.DNSConsistency != "inconsistent" {
+			t.Fatal("unexpected second hop analysis", hop.Blocking, hop.DNSConsistency)
+		}
+		if hop.ControlHop != 1 || hop.Control == nil || hop.ControlRequest == nil {
+			t.Fatal("expected the second hop to use its own control")
+		}
+		// the toplevel control is the one of the first hop
+		if tk.ControlRequest.HTTPRequest != first.String() {
+			t.Fatal("unexpected toplevel control request", tk.ControlRequest.HTTPRequest)
+		}
+	})
+
+	t.Run("a 301 followed by a 200 does not cause a blocking hop", func(t *testing.T) {
+		first, _ := url.Parse("http://example.com/")
+		second, _ := url.Parse("http://www.example.com/")
+		body := "<html><title>Example</title></html>"
+		tk := NewTestKeys()
+		hop0 := tk.newRedirectHop(first, "")
+		hop1 := hop0.newRedirectHop(second, first.String())
+
+		// the TH follows the redirect and sees the final 200 response
+		hop0.SetControlRequest(&webconnectivity.ControlRequest{HTTPRequest: first.String()})
+		hop0.SetControl(&webconnectivity.ControlResponse{
+			HTTPRequest: model.THHTTPRequestResult{
+				BodyLength: int64(len(body)),
+				Title:      "Example",
+				StatusCode: 200,
+			},
+		})
+		hop0.AppendRequests(&model.ArchivalHTTPRequestResult{
+			Request: model.ArchivalHTTPRequest{URL: first.String()},
+			Response: model.ArchivalHTTPResponse{
+				Code: 301,
+				Headers: map[string]model.ArchivalMaybeBinaryData{
+					"Location": {Value: second.String()},
+				},
+			},
+		})
+		hop1.AppendRequests(&model.ArchivalHTTPRequestResult{
+			Request: model.ArchivalHTTPRequest{URL: second.String()},
+			Response: model.ArchivalHTTPResponse{
+				Body: model.ArchivalMaybeBinaryData{Value: body},
+				Code: 200,
+			},
+		})
+
+		tk.Finalize(model.DiscardLogger)
+
+		if tk.BlockingHop != nil {
+			t.Fatal("expected no blocking hop", *tk.BlockingHop)
+		}
+		for _, hop := range tk.RedirectChain {
+			if hop.BlockingFlags != analysisFlagSuccess || hop.Blocking != false || hop.Accessible != true {
+				t.Fatal("unexpected hop analysis", hop.Index, hop.BlockingFlags, hop.Blocking, hop.Accessible)
+			}
+		}
+		if tk.Blocking != false || tk.Accessible != true {
+			t.Fatal("unexpected toplevel analysis", tk.Blocking, tk.Accessible)
+		}
+		// the toplevel requests start from the most recent one
+		if diff := cmp.Diff([]int64{1}, tk.RedirectChain[0].RequestIndexes); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]int64{0}, tk.RedirectChain[1].RequestIndexes); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestRedirectHopNeedsControl(t *testing.T) {
+	URL, _ := url.Parse("https://www.example.com/")
+	cases := map[string]bool{
+		"":                             true,
+		"https://example.com/":         true,
+		"http://www.example.com/":      false,
+		"https://www.example.com/path": false,
+	}
+	for referer, expect := range cases {
+		if got := redirectHopNeedsControl(referer, URL); got != expect {
+			t.Fatal("unexpected result for", referer, got)
+		}
+	}
+}
@@ -71,6 +71,14 @@ type SecureFlow struct {
 	// Referer contains the OPTIONAL referer, used for redirects.
 	Referer string
 
+	// Session is the OPTIONAL session, used for redirects to issue
+	// the control request for the next hop of the redirect chain.
+	Session model.ExperimentSession
+
+	// TestHelpers is the OPTIONAL list of test helpers, used for redirects
+	// to issue the control request for the next hop of the redirect chain.
+	TestHelpers []model.OOAPIService
+
 	// SNI is the OPTIONAL SNI to use.
 	SNI string
 
@@ -103,7 +111,7 @@ func (t *SecureFlow) Run(parentCtx context.Context, index int64) error {
 	}
 
 	// create trace
-	trace := measurexlite.NewTrace(index, t.ZeroTime)
+	trace := measurexlite.NewTraceWithContext(parentCtx, index, t.ZeroTime)
 
 	// start the operation logger
 	ol := measurexlite.NewOperationLogger(
@@ -340,13 +348,13 @@ func (t *SecureFlow) maybeFollowRedirects(ctx context.Context, resp *http.Respon
 			IDGenerator:  t.IDGenerator,
 			Logger:       t.Logger,
 			NumRedirects: t.NumRedirects,
-			TestKeys:     t.TestKeys,
+			TestKeys:     t.TestKeys.newRedirectHop(location, resp.Request.URL.String()),
 			URL:          location,
 			ZeroTime:     t.ZeroTime,
 			WaitGroup:    t.WaitGroup,
 			Referer:      resp.Request.URL.String(),
-			Session:      nil, // no need to issue another control request
-			TestHelpers:  nil, // ditto
+			Session:      t.Session,
+			TestHelpers:  t.TestHelpers,
 			UDPAddress:   t.UDPAddress,
 		}
 		resolvers.Start(ctx)
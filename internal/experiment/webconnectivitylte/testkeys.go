@@ -8,6 +8,7 @@ package webconnectivitylte
 //
 
 import (
+	"net/url"
 	"sort"
 	"sync"
 
@@ -90,6 +91,16 @@ type TestKeys struct {
 	// BlockingFlags explains why we think that the website is blocked.
 	BlockingFlags int64 `json:"x_blocking_flags"`
 
+	// TLSMITM is true when, for at least one TLS handshake, none of the
+	// certificates received by the probe belongs to the certificate chain
+	// the TH has seen for the same endpoint, which suggests a TLS man in the
+	// middle. We also set this field when the handshake succeeded, e.g., because
+	// the attacker's CA is trusted by the probe, in which case blocking is
+	// not affected, because some servers (e.g., CDNs) legitimately use distinct
+	// certificates depending on the client location. When the handshake failed,
+	// blocking is "http-failure" and x_blocking_flags contains the TLS MITM flag.
+	TLSMITM bool `json:"x_tls_mitm"`
+
 	// NullNullFlags describes what the algorithm to avoid emitting
 	// blocking = null, accessible = null measurements did
 	NullNullFlags int64 `json:"x_null_null_flags"`
@@ -125,6 +136,14 @@ type TestKeys struct {
 	// values for this field are: nil, true, and false.
 	Accessible any `json:"accessible"`
 
+	// RedirectChain contains a sub-measurement for each hop of the
+	// redirect chain, starting from the input URL.
+	RedirectChain []*RedirectHop `json:"x_redirect_chain"`
+
+	// BlockingHop is the index inside RedirectChain of the first hop
+	// where we detected blocking, or nil if there is no such hop.
+	BlockingHop *int64 `json:"x_blocking_hop"`
+
 	// fundamentalFailure indicates that some fundamental error occurred
 	// in a background task. A fundamental error is something like a programmer
 	// such as a failure to parse a URL that was hardcoded in the codebase. When
@@ -132,9 +151,20 @@ type TestKeys struct {
 	// resulting measurement to the OONI collector.
 	fundamentalFailure error
 
+	// hopIndex is the index of the hop when these are the test keys of
+	// a redirect hop and is otherwise unused.
+	hopIndex int64
+
 	// mu provides mutual exclusion for accessing the test keys.
 	mu *sync.Mutex
 
+	// parent is nil for the toplevel test keys and otherwise points to
+	// the toplevel test keys when these are the test keys of a redirect
+	// hop. The test keys of a redirect hop collect the hop's observations
+	// and forward them to the parent, while the parent collects the
+	// observations that do not pertain to a specific hop.
+	parent *TestKeys
+
 	// testHelper is used to communicate the TH that worked to the main
 	// goroutine such that we can fill measurement.TestHelpers.
 	testHelper *model.OOAPIService
@@ -201,6 +231,9 @@ func (tk *TestKeys) AppendNetworkEvents(v ...*model.ArchivalNetworkEvent) {
 	tk.mu.Lock()
 	tk.NetworkEvents = append(tk.NetworkEvents, v...)
 	tk.mu.Unlock()
+	if tk.parent != nil {
+		tk.parent.AppendNetworkEvents(v...)
+	}
 }
 
 // AppendDNSLateReplies appends to DNSLateReplies.
@@ -208,6 +241,9 @@ func (tk *TestKeys) AppendDNSLateReplies(v ...*model.ArchivalDNSLookupResult) {
 	tk.mu.Lock()
 	tk.DNSDuplicateResponses = append(tk.DNSDuplicateResponses, v...)
 	tk.mu.Unlock()
+	if tk.parent != nil {
+		tk.parent.AppendDNSLateReplies(v...)
+	}
 }
 
 // AppendQueries appends to Queries.
@@ -215,6 +251,9 @@ func (tk *TestKeys) AppendQueries(v ...*model.ArchivalDNSLookupResult) {
 	tk.mu.Lock()
 	tk.Queries = append(tk.Queries, v...)
 	tk.mu.Unlock()
+	if tk.parent != nil {
+		tk.parent.AppendQueries(v...)
+	}
 }
 
 // AppendRequests appends to Requests.
@@ -224,6 +263,9 @@ func (tk *TestKeys) AppendRequests(v ...*model.ArchivalHTTPRequestResult) {
 	// request must be at the beginning of the list.
 	tk.Requests = append(v, tk.Requests...)
 	tk.mu.Unlock()
+	if tk.parent != nil {
+		tk.parent.AppendRequests(v...)
+	}
 }
 
 // AppendTCPConnectResults appends to TCPConnect.
@@ -231,6 +273,9 @@ func (tk *TestKeys) AppendTCPConnectResults(v ...*model.ArchivalTCPConnectResult
 	tk.mu.Lock()
 	tk.TCPConnect = append(tk.TCPConnect, v...)
 	tk.mu.Unlock()
+	if tk.parent != nil {
+		tk.parent.AppendTCPConnectResults(v...)
+	}
 }
 
 // AppendTLSHandshakes appends to TLSHandshakes.
@@ -238,6 +283,9 @@ func (tk *TestKeys) AppendTLSHandshakes(v ...*model.ArchivalTLSOrQUICHandshakeRe
 	tk.mu.Lock()
 	tk.TLSHandshakes = append(tk.TLSHandshakes, v...)
 	tk.mu.Unlock()
+	if tk.parent != nil {
+		tk.parent.AppendTLSHandshakes(v...)
+	}
 }
 
 // SetControlRequest sets the value of controlRequest.
@@ -245,6 +293,9 @@ func (tk *TestKeys) SetControlRequest(v *webconnectivity.ControlRequest) {
 	tk.mu.Lock()
 	tk.ControlRequest = v
 	tk.mu.Unlock()
+	if tk.parent != nil && tk.hopIndex == 0 {
+		tk.parent.SetControlRequest(v)
+	}
 }
 
 // SetControl sets the value of Control.
@@ -252,6 +303,9 @@ func (tk *TestKeys) SetControl(v *webconnectivity.ControlResponse) {
 	tk.mu.Lock()
 	tk.Control = v
 	tk.mu.Unlock()
+	if tk.parent != nil && tk.hopIndex == 0 {
+		tk.parent.SetControl(v)
+	}
 }
 
 // SetControlFailure sets the value of controlFailure.
@@ -259,10 +313,17 @@ func (tk *TestKeys) SetControlFailure(err error) {
 	tk.mu.Lock()
 	tk.ControlFailure = tracex.NewFailure(err)
 	tk.mu.Unlock()
+	if tk.parent != nil && tk.hopIndex == 0 {
+		tk.parent.SetControlFailure(err)
+	}
 }
 
 // SetFundamentalFailure sets the value of fundamentalFailure.
 func (tk *TestKeys) SetFundamentalFailure(err error) {
+	if tk.parent != nil {
+		tk.parent.SetFundamentalFailure(err)
+		return
+	}
 	tk.mu.Lock()
 	tk.fundamentalFailure = err
 	tk.mu.Unlock()
@@ -271,6 +332,10 @@ func (tk *TestKeys) SetFundamentalFailure(err error) {
 // WithTestKeysDoH calls the given function with the mutex locked passing to
 // it as argument the pointer to the DoH field.
 func (tk *TestKeys) WithTestKeysDoH(f func(*TestKeysDoH)) {
+	if tk.parent != nil {
+		tk.parent.WithTestKeysDoH(f)
+		return
+	}
 	tk.mu.Lock()
 	f(tk.DoH)
 	tk.mu.Unlock()
@@ -279,6 +344,10 @@ func (tk *TestKeys) WithTestKeysDoH(f func(*TestKeysDoH)) {
 // WithTestKeysDo53 calls the given function with the mutex locked passing to
 // it as argument the pointer to the Do53 field.
 func (tk *TestKeys) WithTestKeysDo53(f func(*TestKeysDo53)) {
+	if tk.parent != nil {
+		tk.parent.WithTestKeysDo53(f)
+		return
+	}
 	tk.mu.Lock()
 	f(tk.Do53)
 	tk.mu.Unlock()
@@ -287,6 +356,10 @@ func (tk *TestKeys) WithTestKeysDo53(f func(*TestKeysDo53)) {
 // WithDNSWhoami calls the given function with the mutex locked passing to
 // it as argument the pointer to the DNSWhoami field.
 func (tk *TestKeys) WithDNSWhoami(fun func(*DNSWhoamiInfo)) {
+	if tk.parent != nil {
+		tk.parent.WithDNSWhoami(fun)
+		return
+	}
 	tk.mu.Lock()
 	fun(tk.DNSWoami)
 	tk.mu.Unlock()
@@ -294,6 +367,10 @@ func (tk *TestKeys) WithDNSWhoami(fun func(*DNSWhoamiInfo)) {
 
 // SetClientResolver sets the ClientResolver field.
 func (tk *TestKeys) SetClientResolver(value string) {
+	if tk.parent != nil {
+		tk.parent.SetClientResolver(value)
+		return
+	}
 	tk.mu.Lock()
 	tk.ClientResolver = value
 	tk.mu.Unlock()
@@ -301,6 +378,10 @@ func (tk *TestKeys) SetClientResolver(value string) {
 
 // AppendConnPriorityLogEntry appends an entry to ConnPriorityLog.
 func (tk *TestKeys) AppendConnPriorityLogEntry(entry *ConnPriorityLogEntry) {
+	if tk.parent != nil {
+		tk.parent.AppendConnPriorityLogEntry(entry)
+		return
+	}
 	tk.mu.Lock()
 	tk.ConnPriorityLog = append(tk.ConnPriorityLog, entry)
 	tk.mu.Unlock()
@@ -308,6 +389,12 @@ func (tk *TestKeys) AppendConnPriorityLogEntry(entry *ConnPriorityLogEntry) {
 
 // setTestHelper sets .testHelper in a thread safe way
 func (tk *TestKeys) setTestHelper(th *model.OOAPIService) {
+	if tk.parent != nil {
+		if tk.hopIndex == 0 {
+			tk.parent.setTestHelper(th)
+		}
+		return
+	}
 	tk.mu.Lock()
 	tk.testHelper = th
 	tk.mu.Unlock()
@@ -315,6 +402,9 @@ func (tk *TestKeys) setTestHelper(th *model.OOAPIService) {
 
 // getTestHelper gets .testHelper in a thread safe way
 func (tk *TestKeys) getTestHelper() (th *model.OOAPIService) {
+	if tk.parent != nil {
+		return tk.parent.getTestHelper()
+	}
 	tk.mu.Lock()
 	th = tk.testHelper
 	tk.mu.Unlock()
@@ -357,6 +447,7 @@ func NewTestKeys() *TestKeys {
 		DNSConsistency:        "",
 		HTTPExperimentFailure: nil,
 		BlockingFlags:         0,
+		TLSMITM:               false,
 		NullNullFlags:         0,
 		BodyLengthMatch:       nil,
 		HeadersMatch:          nil,
@@ -364,17 +455,54 @@ func NewTestKeys() *TestKeys {
 		TitleMatch:            nil,
 		Blocking:              nil,
 		Accessible:            nil,
+		RedirectChain:         []*RedirectHop{},
+		BlockingHop:           nil,
 		ControlRequest:        nil,
 		fundamentalFailure:    nil,
+		hopIndex:              0,
 		mu:                    &sync.Mutex{},
+		parent:                nil,
 		testHelper:            nil,
 	}
 }
 
+// newRedirectHop creates a new hop of the redirect chain for the given URL
+// and returns the test keys that collect the hop's observations.
+func (tk *TestKeys) newRedirectHop(URL *url.URL, referer string) *TestKeys {
+	if tk.parent != nil {
+		return tk.parent.newRedirectHop(URL, referer)
+	}
+	hop := &RedirectHop{
+		URL:     URL.String(),
+		Referer: referer,
+		tk:      NewTestKeys(),
+	}
+	hop.tk.parent = tk
+	tk.mu.Lock()
+	hop.Index = int64(len(tk.RedirectChain))
+	hop.tk.hopIndex = hop.Index
+	tk.RedirectChain = append(tk.RedirectChain, hop)
+	tk.mu.Unlock()
+	return hop.tk
+}
+
 // Finalize performs any delayed computation on the test keys. This function
 // must be called from the measurer after all the tasks have completed.
 func (tk *TestKeys) Finalize(logger model.Logger) {
 	tk.analysisToplevel(logger)
+	// Note: the analysis of each hop would log the same anomalies that we
+	// have just logged for the toplevel test keys, so we only log a summary.
+	for _, hop := range tk.RedirectChain {
+		hop.finalize(model.DiscardLogger, tk)
+		logger.Infof(
+			"redirect hop #%d: url=%s flags=%d, accessible=%+v, blocking=%+v",
+			hop.Index, hop.URL, hop.BlockingFlags, hop.Accessible, hop.Blocking,
+		)
+		if tk.BlockingHop == nil && (hop.BlockingFlags&^analysisFlagSuccess) != 0 {
+			index := hop.Index
+			tk.BlockingHop = &index
+		}
+	}
 	// Note: sort.SliceStable is WAI when the input slice is nil
 	// as demonstrated by https://go.dev/play/p/znA4MyGFVHC
 	sort.SliceStable(tk.NetworkEvents, func(i, j int) bool {
@@ -0,0 +1,174 @@
+package geoipx
+
+//
+// External, hot-reloadable databases
+//
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/multierror"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// externalDatabase is an MMDB database loaded from a file.
+type externalDatabase struct {
+	// mu serializes reloads.
+	mu sync.Mutex
+
+	// modTime is the modification time of the file we loaded.
+	modTime time.Time
+
+	// path is the path of the file containing the database.
+	path string
+
+	// reader is the reader for the loaded database.
+	reader atomic.Pointer[maxminddb.Reader]
+
+	// size is the size of the file we loaded.
+	size int64
+}
+
+// maybeReload loads the database again if the file changed since we last loaded it
+// and returns whether we reloaded. On failure, we keep using the previous database.
+func (db *externalDatabase) maybeReload() (bool, error) {
+	defer db.mu.Unlock()
+	db.mu.Lock()
+	stat, err := os.Stat(db.path)
+	if err != nil {
+		return false, err
+	}
+	if db.reader.Load() != nil && stat.ModTime().Equal(db.modTime) && stat.Size() == db.size {
+		return false, nil
+	}
+	// Implementation note: we read the whole file in memory rather than using
+	// maxminddb.Open, which uses mmap, because we would otherwise need to know
+	// when no-one is using the previous reader anymore to close it.
+	data, err := os.ReadFile(db.path)
+	if err != nil {
+		return false, err
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return false, err
+	}
+	if err := reader.Verify(); err != nil {
+		return false, err
+	}
+	db.reader.Store(reader)
+	db.modTime, db.size = stat.ModTime(), stat.Size()
+	return true, nil
+}
+
+// databaseSlot contains the external database used for a kind of lookup, if any.
+type databaseSlot struct {
+	db atomic.Pointer[externalDatabase]
+}
+
+// reader returns the reader to use for lookups, which is the external database's
+// reader when we have one and the embedded database's reader otherwise.
+func (s *databaseSlot) reader() *maxminddb.Reader {
+	if db := s.db.Load(); db != nil {
+		return db.reader.Load()
+	}
+	return getEmbeddedReader()
+}
+
+// set configures the slot to use the database at path or the embedded
+// database if path is empty. On failure, we do not modify the slot.
+func (s *databaseSlot) set(path string) error {
+	if path == "" {
+		s.db.Store(nil)
+		return nil
+	}
+	db := &externalDatabase{path: path}
+	if _, err := db.maybeReload(); err != nil {
+		return fmt.Errorf("%w: %s", ErrCannotLoadDatabase, err.Error())
+	}
+	s.db.Store(db)
+	return nil
+}
+
+var (
+	// asnDatabase is the database used by LookupASN.
+	asnDatabase = &databaseSlot{}
+
+	// ccDatabase is the database used by LookupCC.
+	ccDatabase = &databaseSlot{}
+
+	// reloadCount counts the databases we successfully reloaded.
+	reloadCount = &atomic.Int64{}
+
+	// reloadFailureCount counts the failures to reload databases.
+	reloadFailureCount = &atomic.Int64{}
+)
+
+var (
+	// ErrCannotLoadDatabase indicates that we cannot load an external database.
+	ErrCannotLoadDatabase = errors.New("geoipx: cannot load database")
+
+	// ErrReloadFailed indicates that we could not reload some external databases.
+	ErrReloadFailed = errors.New("geoipx: reload failed")
+)
+
+// SetASNDatabase configures LookupASN to use the MMDB database at path (e.g., a
+// GeoLite2-ASN database) instead of the embedded one. An empty path means that
+// we should use the embedded database again.
+func SetASNDatabase(path string) error {
+	return asnDatabase.set(path)
+}
+
+// SetCCDatabase configures LookupCC to use the MMDB database at path (e.g., a
+// GeoLite2-Country database) instead of the embedded one. An empty path means that
+// we should use the embedded database again.
+func SetCCDatabase(path string) error {
+	return ccDatabase.set(path)
+}
+
+// Reload reloads the external databases whose files changed since we last loaded
+// them. When we cannot reload a database, we keep using the previously loaded one,
+// we increment the reload failures counter, and we return an error.
+func Reload() error {
+	union := multierror.New(ErrReloadFailed)
+	for _, slot := range []*databaseSlot{asnDatabase, ccDatabase} {
+		db := slot.db.Load()
+		if db == nil {
+			continue
+		}
+		reloaded, err := db.maybeReload()
+		if err != nil {
+			reloadFailureCount.Add(1)
+			union.AddWithPrefix(db.path, err)
+			continue
+		}
+		if reloaded {
+			reloadCount.Add(1)
+		}
+	}
+	if len(union.Children) > 0 {
+		return union
+	}
+	return nil
+}
+
+// ReloadStats contains statistics about reloading external databases.
+type ReloadStats struct {
+	// Failures is the number of failed reloads.
+	Failures int64
+
+	// Reloads is the number of successful reloads.
+	Reloads int64
+}
+
+// ReadReloadStats returns the current [ReloadStats].
+func ReadReloadStats() ReloadStats {
+	return ReloadStats{
+		Failures: reloadFailureCount.Load(),
+		Reloads:  reloadCount.Load(),
+	}
+}
@@ -0,0 +1,115 @@
+package geoipx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-assets/assets"
+)
+
+// writeTestDatabase writes data to path and sets the file's modification time.
+func writeTestDatabase(t *testing.T, path string, data []byte, modTime time.Time) {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExternalDatabases(t *testing.T) {
+	t.Run("we cannot load a nonexistent file", func(t *testing.T) {
+		err := SetASNDatabase(filepath.Join(t.TempDir(), "nonexistent.mmdb"))
+		if !errors.Is(err, ErrCannotLoadDatabase) {
+			t.Fatal("unexpected error", err)
+		}
+		if asnDatabase.db.Load() != nil {
+			t.Fatal("expected to still use the embedded database")
+		}
+	})
+
+	t.Run("we cannot load an invalid database", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "invalid.mmdb")
+		writeTestDatabase(t, path, []byte("antani"), time.Now())
+		err := SetCCDatabase(path)
+		if !errors.Is(err, ErrCannotLoadDatabase) {
+			t.Fatal("unexpected error", err)
+		}
+		if ccDatabase.db.Load() != nil {
+			t.Fatal("expected to still use the embedded database")
+		}
+	})
+
+	t.Run("we can load, reload, and unload a valid database", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("skip test in short mode") // verifying the database is slow
+		}
+		path := filepath.Join(t.TempDir(), "valid.mmdb")
+		modTime := time.Now().Add(-time.Hour)
+		writeTestDatabase(t, path, assets.OOMMDBDatabaseBytes, modTime)
+		if err := SetASNDatabase(path); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetCCDatabase(path); err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			SetASNDatabase("")
+			SetCCDatabase("")
+			if asnDatabase.reader() != getEmbeddedReader() || ccDatabase.reader() != getEmbeddedReader() {
+				t.Fatal("expected to use the embedded database again")
+			}
+		}()
+		if asnDatabase.reader() == getEmbeddedReader() || ccDatabase.reader() == getEmbeddedReader() {
+			t.Fatal("expected to use the external database")
+		}
+		if asn, _, _ := LookupASN(ipAddr); asn != 15169 {
+			t.Fatal("unexpected ASN", asn)
+		}
+		if cc, _ := LookupCC(ipAddr); cc != "US" {
+			t.Fatal("unexpected CC", cc)
+		}
+
+		// when the file did not change, we do not reload
+		before := ReadReloadStats()
+		reader := asnDatabase.reader()
+		if err := Reload(); err != nil {
+			t.Fatal(err)
+		}
+		if ReadReloadStats() != before || asnDatabase.reader() != reader {
+			t.Fatal("did not expect to reload")
+		}
+
+		// when the file changed, we reload
+		writeTestDatabase(t, path, assets.OOMMDBDatabaseBytes, modTime.Add(time.Minute))
+		if err := Reload(); err != nil {
+			t.Fatal(err)
+		}
+		if stats := ReadReloadStats(); stats.Reloads != before.Reloads+2 || stats.Failures != before.Failures {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		if asnDatabase.reader() == reader {
+			t.Fatal("expected a new reader")
+		}
+
+		// when the new file is invalid, we keep using the previous database
+		reader = asnDatabase.reader()
+		before = ReadReloadStats()
+		writeTestDatabase(t, path, []byte("antani"), modTime.Add(2*time.Minute))
+		if err := Reload(); !errors.Is(err, ErrReloadFailed) {
+			t.Fatal("unexpected error", err)
+		}
+		if stats := ReadReloadStats(); stats.Reloads != before.Reloads || stats.Failures != before.Failures+2 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		if asnDatabase.reader() != reader {
+			t.Fatal("expected to keep using the previous reader")
+		}
+		if asn, _, _ := LookupASN(ipAddr); asn != 15169 {
+			t.Fatal("unexpected ASN", asn)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package geoipx
 
 import (
 	"net"
+	"sync"
 
 	"github.com/ooni/probe-assets/assets"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -10,16 +11,29 @@ import (
 	"github.com/oschwald/maxminddb-golang"
 )
 
-// TODO(bassosimone): this would be more efficient if we'd open just
-// once the database and then reuse it for every address.
+var (
+	// embeddedReaderOnce allows to open the embedded database just once.
+	embeddedReaderOnce sync.Once
+
+	// embeddedReader is the reader for the embedded database.
+	embeddedReader *maxminddb.Reader
+)
+
+// getEmbeddedReader returns the reader for the embedded database, which
+// we open once and then reuse for every lookup.
+func getEmbeddedReader() *maxminddb.Reader {
+	embeddedReaderOnce.Do(func() {
+		db, err := maxminddb.FromBytes(assets.OOMMDBDatabaseBytes)
+		runtimex.PanicOnError(err, "cannot load embedded geoip2 database")
+		embeddedReader = db
+	})
+	return embeddedReader
+}
 
 // LookupASN maps [ip] to an AS number and an AS organization name.
 func LookupASN(ip string) (asn uint, org string, err error) {
 	asn, org = model.DefaultProbeASN, model.DefaultProbeNetworkName
-	db, err := maxminddb.FromBytes(assets.OOMMDBDatabaseBytes)
-	runtimex.PanicOnError(err, "cannot load embedded geoip2 database")
-	defer db.Close()
-	record, err := assets.OOMMDBLooup(db, net.ParseIP(ip))
+	record, err := assets.OOMMDBLooup(asnDatabase.reader(), net.ParseIP(ip))
 	if err != nil {
 		return
 	}
@@ -33,10 +47,7 @@ func LookupASN(ip string) (asn uint, org string, err error) {
 // LookupCC maps [ip] to a country code.
 func LookupCC(ip string) (cc string, err error) {
 	cc = model.DefaultProbeCC
-	db, err := maxminddb.FromBytes(assets.OOMMDBDatabaseBytes)
-	runtimex.PanicOnError(err, "cannot load embedded geoip2 country database")
-	defer db.Close()
-	record, err := assets.OOMMDBLooup(db, net.ParseIP(ip))
+	record, err := assets.OOMMDBLooup(ccDatabase.reader(), net.ParseIP(ip))
 	if err != nil {
 		return
 	}
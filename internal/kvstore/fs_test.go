@@ -65,3 +65,22 @@ func TestFileSystemWithFailure(t *testing.T) {
 		t.Fatal("expected nil here")
 	}
 }
+
+func TestFileSystemDelete(t *testing.T) {
+	kvstore, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Set("antani", []byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Delete("antani"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvstore.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("expected ErrNoSuchKey", err)
+	}
+	if err := kvstore.Delete("antani"); err != nil {
+		t.Fatal("deleting a nonexistent key should not fail", err)
+	}
+}
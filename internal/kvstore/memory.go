@@ -43,3 +43,11 @@ func (kvs *Memory) Set(key string, value []byte) error {
 	kvs.m[key] = value
 	return nil
 }
+
+// Delete removes the specified key. Deleting a nonexistent key is not an error.
+func (kvs *Memory) Delete(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	delete(kvs.m, key)
+	return nil
+}
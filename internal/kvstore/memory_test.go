@@ -29,3 +29,19 @@ func TestExistingKey(t *testing.T) {
 		t.Fatal("not the result we expected")
 	}
 }
+
+func TestDeleteKey(t *testing.T) {
+	kvs := &Memory{}
+	if err := kvs.Delete("antani"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Set("antani", []byte("mascetti")); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Delete("antani"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvs.Get("antani"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("expected ErrNoSuchKey", err)
+	}
+}
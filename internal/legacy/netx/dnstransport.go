@@ -33,6 +33,9 @@ import (
 // - if the URL starts with `udp://`, then we create a client using
 // a resolver that uses the specified UDP endpoint.
 //
+// - if the URL starts with `quic://`, then we create a DNS-over-QUIC
+// client using the specified endpoint (port 853 by default).
+//
 // We return error if the URL does not parse or the URL scheme does not
 // fall into one of the cases described above.
 //
@@ -95,6 +98,16 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 			tlsDialer.DialTLSContext, endpoint)
 		txp = config.Saver.WrapDNSTransport(txp) // safe when config.Saver == nil
 		return netxlite.NewUnwrappedSerialResolver(txp), nil
+	case "quic":
+		quicDialer := NewQUICDialer(config)
+		endpoint, err := makeValidEndpoint(resolverURL)
+		if err != nil {
+			return nil, err
+		}
+		var txp model.DNSTransport = netxlite.NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+			quicDialer, endpoint, config.TLSConfig)
+		txp = config.Saver.WrapDNSTransport(txp) // safe when config.Saver == nil
+		return netxlite.NewUnwrappedSerialResolver(txp), nil
 	case "tcp":
 		dialer := NewDialer(config)
 		endpoint, err := makeValidEndpoint(resolverURL)
@@ -110,11 +123,11 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 	}
 }
 
-// makeValidEndpoint makes a valid endpoint for DoT and Do53 given the
+// makeValidEndpoint makes a valid endpoint for DoT, DoQ and Do53 given the
 // input URL representing such endpoint. Specifically, we are
 // concerned with the case where the port is missing. In such a
 // case, we ensure that we are using the default port 853 for DoT
-// and default port 53 for TCP and UDP.
+// and DoQ and default port 53 for TCP and UDP.
 func makeValidEndpoint(URL *url.URL) (string, error) {
 	// Implementation note: when we're using a quoted IPv6
 	// address, URL.Host contains the quotes but instead the
@@ -152,7 +165,7 @@ func makeValidEndpoint(URL *url.URL) (string, error) {
 	// For this reason we check again whether we can split it using
 	// net.SplitHostPort. If we cannot, we were in case four.
 	host := URL.Host
-	if URL.Scheme == "dot" {
+	if URL.Scheme == "dot" || URL.Scheme == "quic" {
 		host += ":853"
 	} else {
 		host += ":53"
@@ -0,0 +1,102 @@
+package main
+
+//
+// Config
+//
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// config is the Go object corresponding to an OONIConfig, which is created
+// from the JSON passed to OONIEngineNewConfig. A config is not a session: its
+// fields only provide defaults for the settings of the tasks started using it
+// (see mergeTaskSettings) and each task creates its own engine session.
+type config struct {
+	// AssetsDir is the OPTIONAL directory where to store assets.
+	AssetsDir string `json:"assets_dir,omitempty"`
+
+	// LogLevel is the OPTIONAL default log level (e.g., "INFO").
+	LogLevel string `json:"log_level,omitempty"`
+
+	// ProbeServicesURL is the OPTIONAL probe services base URL.
+	ProbeServicesURL string `json:"probe_services_url,omitempty"`
+
+	// Proxy is the OPTIONAL proxy URL (e.g., "socks5://127.0.0.1:9050").
+	Proxy string `json:"proxy,omitempty"`
+
+	// SoftwareName is the MANDATORY name of the application.
+	SoftwareName string `json:"software_name"`
+
+	// SoftwareVersion is the MANDATORY version of the application.
+	SoftwareVersion string `json:"software_version"`
+
+	// StateDir is the MANDATORY directory where to store state.
+	StateDir string `json:"state_dir"`
+
+	// TempDir is the MANDATORY directory where to store temporary files.
+	TempDir string `json:"temp_dir"`
+
+	// TunnelDir is the OPTIONAL directory where to store tunnels state.
+	TunnelDir string `json:"tunnel_dir,omitempty"`
+}
+
+// errConfigMissingField indicates that a MANDATORY field is missing.
+var errConfigMissingField = errors.New("libooniengine: config is missing a mandatory field")
+
+// newConfig creates a new config from the given JSON.
+func newConfig(rawConfig []byte) (*config, error) {
+	var cfg config
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.SoftwareName == "" || cfg.SoftwareVersion == "" ||
+		cfg.StateDir == "" || cfg.TempDir == "" {
+		return nil, errConfigMissingField
+	}
+	return &cfg, nil
+}
+
+// taskABIVersion is the version of the task settings we generate, which
+// must be kept in sync with the one used by pkg/oonimkall.
+const taskABIVersion = 1
+
+// mergeTaskSettings merges the given task settings, which follow the same
+// format used by oonimkall.StartTask, with the config. The values
+// explicitly set by the task settings take precedence.
+func (c *config) mergeTaskSettings(rawSettings []byte) ([]byte, error) {
+	var settings map[string]any
+	if err := json.Unmarshal(rawSettings, &settings); err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = map[string]any{} // the settings were `null`
+	}
+	options, _ := settings["options"].(map[string]any)
+	if options == nil {
+		options = map[string]any{}
+	}
+	settings["options"] = options
+	setDefault(settings, "assets_dir", c.AssetsDir)
+	setDefault(settings, "log_level", c.LogLevel)
+	setDefault(settings, "Proxy", c.Proxy) // oonimkall uses no JSON tag for Proxy
+	setDefault(settings, "state_dir", c.StateDir)
+	setDefault(settings, "temp_dir", c.TempDir)
+	setDefault(settings, "tunnel_dir", c.TunnelDir)
+	setDefault(settings, "version", taskABIVersion)
+	setDefault(options, "probe_services_base_url", c.ProbeServicesURL)
+	setDefault(options, "software_name", c.SoftwareName)
+	setDefault(options, "software_version", c.SoftwareVersion)
+	return json.Marshal(settings)
+}
+
+// setDefault sets m[key] to value if m does not contain key and value is not
+// the zero value of its type (i.e., we do not set empty strings).
+func setDefault[T comparable](m map[string]any, key string, value T) {
+	var zero T
+	if _, found := m[key]; found || value == zero {
+		return
+	}
+	m[key] = value
+}
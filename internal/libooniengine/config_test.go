@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewConfig(t *testing.T) {
+	t.Run("with invalid JSON", func(t *testing.T) {
+		cfg, err := newConfig([]byte("{"))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if cfg != nil {
+			t.Fatal("expected nil config")
+		}
+	})
+
+	t.Run("with missing mandatory fields", func(t *testing.T) {
+		cfg, err := newConfig([]byte(`{"software_name":"x","software_version":"0.1.0"}`))
+		if !errors.Is(err, errConfigMissingField) {
+			t.Fatal("unexpected error", err)
+		}
+		if cfg != nil {
+			t.Fatal("expected nil config")
+		}
+	})
+
+	t.Run("with valid config", func(t *testing.T) {
+		cfg, err := newConfig([]byte(`{"software_name":"x","software_version":"0.1.0","state_dir":"s","temp_dir":"t"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.SoftwareName != "x" || cfg.StateDir != "s" {
+			t.Fatal("unexpected config", cfg)
+		}
+	})
+}
+
+func TestConfigMergeTaskSettings(t *testing.T) {
+	cfg := &config{
+		AssetsDir:        "a",
+		LogLevel:         "INFO",
+		ProbeServicesURL: "https://api.ooni.io/",
+		Proxy:            "psiphon:///",
+		SoftwareName:     "x",
+		SoftwareVersion:  "0.1.0",
+		StateDir:         "s",
+		TempDir:          "t",
+		TunnelDir:        "",
+	}
+
+	t.Run("with invalid JSON", func(t *testing.T) {
+		data, err := cfg.mergeTaskSettings([]byte("{"))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if data != nil {
+			t.Fatal("expected nil data")
+		}
+	})
+
+	type testcase struct {
+		name   string
+		input  string
+		expect map[string]any
+	}
+
+	cases := []testcase{{
+		name:  "with null settings",
+		input: "null",
+		expect: map[string]any{
+			"Proxy":      "psiphon:///",
+			"assets_dir": "a",
+			"log_level":  "INFO",
+			"options": map[string]any{
+				"probe_services_base_url": "https://api.ooni.io/",
+				"software_name":           "x",
+				"software_version":        "0.1.0",
+			},
+			"state_dir": "s",
+			"temp_dir":  "t",
+			"version":   float64(1),
+		},
+	}, {
+		name:  "settings take precedence over the config",
+		input: `{"name":"Example","log_level":"DEBUG","version":2,"options":{"software_name":"y","no_collector":true}}`,
+		expect: map[string]any{
+			"Proxy":      "psiphon:///",
+			"assets_dir": "a",
+			"log_level":  "DEBUG",
+			"name":       "Example",
+			"options": map[string]any{
+				"no_collector":            true,
+				"probe_services_base_url": "https://api.ooni.io/",
+				"software_name":           "y",
+				"software_version":        "0.1.0",
+			},
+			"state_dir": "s",
+			"temp_dir":  "t",
+			"version":   float64(2),
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := cfg.mergeTaskSettings([]byte(tc.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expect, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -7,14 +7,29 @@ package main
 //#include <stdlib.h>
 //
 //#include "engine.h"
+//
+//// cchar_t allows exporting functions taking `const char *` arguments.
+//typedef const char cchar_t;
 import "C"
 
 import (
+	"time"
 	"unsafe"
 
 	"github.com/ooni/probe-cli/v3/internal/version"
 )
 
+// configs contains the configs created by OONIEngineNewConfig.
+var configs = newHandleRegistry()
+
+// tasks contains the tasks created by OONIEngineStartTask.
+var tasks = newHandleRegistry()
+
+// goString converts a `const char *` to a Go string.
+func goString(s *C.cchar_t) string {
+	return C.GoString((*C.char)(unsafe.Pointer(s)))
+}
+
 //export OONIEngineVersion
 func OONIEngineVersion() *C.char {
 	return C.CString(version.Version)
@@ -25,6 +40,69 @@ func OONIEngineFreeMemory(ptr *C.void) {
 	C.free(unsafe.Pointer(ptr))
 }
 
+//export OONIEngineNewConfig
+func OONIEngineNewConfig(rawConfig *C.cchar_t) C.OONIConfig {
+	if rawConfig == nil {
+		return 0
+	}
+	cfg, err := newConfig([]byte(goString(rawConfig)))
+	if err != nil {
+		return 0
+	}
+	return C.OONIConfig(configs.New(cfg))
+}
+
+//export OONIEngineFreeConfig
+func OONIEngineFreeConfig(handle C.OONIConfig) {
+	configs.Delete(uint64(handle))
+}
+
+//export OONIEngineStartTask
+func OONIEngineStartTask(handle C.OONIConfig, settings *C.cchar_t) C.OONITask {
+	cfg, _ := configs.Get(uint64(handle)).(*config)
+	if cfg == nil || settings == nil {
+		return 0
+	}
+	t := startTask(cfg, []byte(goString(settings)))
+	return C.OONITask(tasks.New(t))
+}
+
+//export OONIEngineWaitForNextEvent
+func OONIEngineWaitForNextEvent(handle C.OONITask, timeout C.int32_t) *C.char {
+	t, _ := tasks.Get(uint64(handle)).(*task)
+	if t == nil {
+		return nil
+	}
+	ev, good := t.waitForNextEvent(time.Duration(timeout) * time.Millisecond)
+	if !good {
+		return nil
+	}
+	return C.CString(ev)
+}
+
+//export OONIEngineTaskIsDone
+func OONIEngineTaskIsDone(handle C.OONITask) C.int {
+	t, _ := tasks.Get(uint64(handle)).(*task)
+	if t == nil || t.isDone() {
+		return 1
+	}
+	return 0
+}
+
+//export OONIEngineInterruptTask
+func OONIEngineInterruptTask(handle C.OONITask) {
+	if t, _ := tasks.Get(uint64(handle)).(*task); t != nil {
+		t.interrupt()
+	}
+}
+
+//export OONIEngineFreeTask
+func OONIEngineFreeTask(handle C.OONITask) {
+	if t, _ := tasks.Delete(uint64(handle)).(*task); t != nil {
+		t.free()
+	}
+}
+
 func main() {
 	// do nothing
 }
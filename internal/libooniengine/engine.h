@@ -6,20 +6,108 @@
 ///
 /// C API for using the OONI engine.
 ///
+/// The API is based on JSON messages. You create a config using JSON, then
+/// you start tasks using JSON settings following the same format used by
+/// pkg/oonimkall. Each task emits JSON events (e.g., logs, progress,
+/// measurements) that you poll until the "task_terminated" event.
+///
+/// A config only stores default task settings. It is not an engine session:
+/// each task creates its own engine session, which means that each task
+/// bootstraps on its own (e.g., looks up the probe location) and that tasks
+/// do not share any state except for what is persisted in "state_dir".
+///
+
+#include <stdint.h>
 
 #ifdef __cplusplus
 extern "C" {
 #endif
 
+/// OONIConfig is a handle for a config. Zero is the invalid handle.
+typedef uint64_t OONIConfig;
+
+/// OONITask is a handle for a task. Zero is the invalid handle.
+typedef uint64_t OONITask;
+
 /// OONIEngineVersion return the current engine version.
 ///
-/// @return A char pointer with the current version string.
+/// @return A char pointer with the current version string. You own
+/// this string and must free it using OONIEngineFreeMemory.
 char *OONIEngineVersion(void);
 
 /// OONIEngineFreeMemory frees the memory allocated by the engine.
 ///
 /// @param ptr a void pointer refering to the memory to be freed.
-void OONIENgineFreeMemory(void *ptr);
+void OONIEngineFreeMemory(void *ptr);
+
+/// OONIEngineNewConfig creates a new config.
+///
+/// @param config a JSON string containing the config. The
+/// mandatory fields are "software_name", "software_version", "state_dir"
+/// and "temp_dir". The optional fields are "assets_dir", "log_level",
+/// "probe_services_url", "proxy" and "tunnel_dir". The engine does
+/// not modify nor retain this string.
+///
+/// @return A valid config handle on success, zero on failure (e.g.,
+/// invalid JSON or missing mandatory fields). You must free a valid
+/// config handle using OONIEngineFreeConfig.
+OONIConfig OONIEngineNewConfig(const char *config);
+
+/// OONIEngineFreeConfig frees a config handle. Tasks started using
+/// the config are not affected. Passing an invalid handle is a no-op.
+///
+/// @param config the config to free.
+void OONIEngineFreeConfig(OONIConfig config);
+
+/// OONIEngineStartTask starts a new task, which creates and uses its
+/// own engine session.
+///
+/// @param config a valid config handle.
+///
+/// @param settings a JSON string containing the task settings using the
+/// same format of pkg/oonimkall (e.g., {"name":"WebConnectivity",
+/// "inputs":["https://www.example.com/"]}). Settings missing from this
+/// string are taken from the config. The engine does not modify
+/// nor retain this string.
+///
+/// @return A valid task handle on success, zero if the config handle
+/// or the settings are NULL or invalid. If the settings are not valid
+/// JSON, the task emits a "failure.startup" event and terminates. You
+/// must free a valid task handle using OONIEngineFreeTask.
+OONITask OONIEngineStartTask(OONIConfig config, const char *settings);
+
+/// OONIEngineWaitForNextEvent waits for the next task event.
+///
+/// @param task a valid task handle.
+///
+/// @param timeout the number of milliseconds to wait for. A negative value
+/// means that we should wait until the next event is available.
+///
+/// @return A JSON string containing the next event on success, or NULL if
+/// the timeout expired or the task handle is invalid. You own the returned
+/// string and must free it using OONIEngineFreeMemory. After the task has
+/// terminated, this function keeps returning the "task_terminated" event.
+char *OONIEngineWaitForNextEvent(OONITask task, int32_t timeout);
+
+/// OONIEngineTaskIsDone returns whether the task is done.
+///
+/// @param task a valid task handle.
+///
+/// @return Nonzero if we have already returned the "task_terminated" event
+/// or the handle is invalid, zero otherwise.
+int OONIEngineTaskIsDone(OONITask task);
+
+/// OONIEngineInterruptTask interrupts a running task. You should continue
+/// polling for events until "task_terminated" after calling this function.
+///
+/// @param task a valid task handle.
+void OONIEngineInterruptTask(OONITask task);
+
+/// OONIEngineFreeTask interrupts the task, if needed, and frees the task
+/// handle. Passing an invalid handle is a no-op.
+///
+/// @param task the task to free.
+void OONIEngineFreeTask(OONITask task);
 
 #ifdef __cplusplus
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestCHarness builds libooniengine as a C archive, links it with the
+// C test harness in testdata, and runs the resulting program.
+func TestCHarness(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("skip test on windows")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("skip test because there is no C compiler")
+	}
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "libooniengine.a")
+	runCommand(t, "go", "build", "-buildmode=c-archive", "-o", archive, ".")
+	harness := filepath.Join(dir, "harness")
+	runCommand(t, cc, "-Wall", "-I.", "-o", harness,
+		filepath.Join("testdata", "harness.c"), archive, "-lpthread", "-lm", "-ldl")
+	runCommand(t, harness, t.TempDir(), t.TempDir())
+}
+
+// runCommand runs the given command and fails the test on error.
+func runCommand(t *testing.T, name string, args ...string) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	t.Log(cmd.String())
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+}
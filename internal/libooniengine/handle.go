@@ -0,0 +1,60 @@
+package main
+
+//
+// Handles for passing Go objects to C code
+//
+
+import "sync"
+
+// handleRegistry maps integer handles to Go objects. We cannot pass
+// Go pointers to C code, therefore we give C code integer handles
+// and map them back to the corresponding Go object on each call.
+//
+// Unlike runtime/cgo.Handle, looking up an invalid or already
+// deleted handle does not panic: it just returns nil.
+type handleRegistry struct {
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// next is the next handle value to use.
+	next uint64
+
+	// values maps handles to objects.
+	values map[uint64]any
+}
+
+// newHandleRegistry creates a new handleRegistry.
+func newHandleRegistry() *handleRegistry {
+	return &handleRegistry{
+		mu:     sync.Mutex{},
+		next:   1, // zero is the invalid handle
+		values: map[uint64]any{},
+	}
+}
+
+// New registers value and returns its handle, which is never zero.
+func (hr *handleRegistry) New(value any) uint64 {
+	defer hr.mu.Unlock()
+	hr.mu.Lock()
+	handle := hr.next
+	hr.next++
+	hr.values[handle] = value
+	return handle
+}
+
+// Get returns the value bound to handle or nil.
+func (hr *handleRegistry) Get(handle uint64) any {
+	defer hr.mu.Unlock()
+	hr.mu.Lock()
+	return hr.values[handle]
+}
+
+// Delete removes handle and returns the value that was bound
+// to it, or nil if the handle was not valid.
+func (hr *handleRegistry) Delete(handle uint64) any {
+	defer hr.mu.Unlock()
+	hr.mu.Lock()
+	value := hr.values[handle]
+	delete(hr.values, handle)
+	return value
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestHandleRegistry(t *testing.T) {
+	hr := newHandleRegistry()
+
+	t.Run("Get returns nil for the zero handle", func(t *testing.T) {
+		if hr.Get(0) != nil {
+			t.Fatal("expected nil")
+		}
+	})
+
+	t.Run("New, Get, and Delete work as intended", func(t *testing.T) {
+		h1 := hr.New("antani")
+		h2 := hr.New("mascetti")
+		if h1 == 0 || h2 == 0 || h1 == h2 {
+			t.Fatal("unexpected handles", h1, h2)
+		}
+		if v := hr.Get(h1); v != "antani" {
+			t.Fatal("unexpected value", v)
+		}
+		if v := hr.Delete(h1); v != "antani" {
+			t.Fatal("unexpected value", v)
+		}
+		if hr.Get(h1) != nil {
+			t.Fatal("expected nil after Delete")
+		}
+		if hr.Delete(h1) != nil {
+			t.Fatal("expected nil when deleting twice")
+		}
+		if v := hr.Get(h2); v != "mascetti" {
+			t.Fatal("unexpected value", v)
+		}
+	})
+}
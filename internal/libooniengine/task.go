@@ -0,0 +1,163 @@
+package main
+
+//
+// Task
+//
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+	"github.com/ooni/probe-cli/v3/pkg/oonimkall"
+)
+
+// taskTerminatedEvent is the last event emitted by a task.
+const taskTerminatedEvent = `{"key":"task_terminated","value":{}}` // like oonimkall
+
+// task is the Go object corresponding to an OONITask. It wraps an
+// oonimkall.Task and adds support for waiting for the next event
+// with a timeout, which is convenient for UI event loops.
+type task struct {
+	// done is closed when the task is freed.
+	done chan any
+
+	// events contains the events emitted by the task. The producer
+	// closes this channel after sending taskTerminatedEvent.
+	events chan string
+
+	// interrupt interrupts the underlying task.
+	interrupt func()
+
+	// isdone is nonzero after we delivered taskTerminatedEvent.
+	isdone *atomic.Int64
+
+	// once allows to close done just once.
+	once sync.Once
+}
+
+// startTask starts a new task using the given config and the given JSON
+// settings. The task runs using its own engine session. On failure, this function returns a task that emits a
+// failure.startup event followed by the task_terminated event.
+func startTask(cfg *config, rawSettings []byte) *task {
+	settings, err := cfg.mergeTaskSettings(rawSettings)
+	if err != nil {
+		return newFailedTask(err)
+	}
+	kallTask, err := oonimkall.StartTask(string(settings))
+	if err != nil {
+		return newFailedTask(err)
+	}
+	t := &task{
+		done:      make(chan any),
+		events:    make(chan string),
+		interrupt: kallTask.Interrupt,
+		isdone:    &atomic.Int64{},
+		once:      sync.Once{},
+	}
+	go t.pump(kallTask)
+	return t
+}
+
+// newFailedTask creates a task that failed to start.
+func newFailedTask(err error) *task {
+	t := &task{
+		done:      make(chan any),
+		events:    make(chan string, 2),
+		interrupt: func() {},
+		isdone:    &atomic.Int64{},
+		once:      sync.Once{},
+	}
+	data, err := json.Marshal(map[string]any{
+		"key": "failure.startup",
+		"value": map[string]string{
+			"failure": err.Error(),
+		},
+	})
+	runtimex.PanicOnError(err, "json.Marshal failed")
+	t.events <- string(data)
+	t.events <- taskTerminatedEvent
+	close(t.events)
+	return t
+}
+
+// pump forwards the events emitted by kallTask to t.events until the
+// task terminates or t is freed, whichever happens first.
+func (t *task) pump(kallTask *oonimkall.Task) {
+	defer close(t.events)
+	for {
+		ev := kallTask.WaitForNextEvent()
+		select {
+		case t.events <- ev:
+		case <-t.done:
+			// drain so the interrupted task does not block on emit
+			for !kallTask.IsDone() {
+				_ = kallTask.WaitForNextEvent()
+			}
+			return
+		}
+		if kallTask.IsDone() {
+			return
+		}
+	}
+}
+
+// waitForNextEvent waits for the next event. A negative timeout means that
+// we should block until the next event. The boolean return value is false
+// when the timeout expired without any event being available. Once the task
+// is done, this function keeps returning the task_terminated event.
+func (t *task) waitForNextEvent(timeout time.Duration) (string, bool) {
+	if t.isDone() {
+		return taskTerminatedEvent, true
+	}
+	// Give precedence to events already available (or to the channel being
+	// closed) so that a zero timeout does not race with them.
+	select {
+	case ev, good := <-t.events:
+		return t.onEvent(ev, good), true
+	case <-t.done:
+		t.isdone.Store(1)
+		return taskTerminatedEvent, true
+	default:
+	}
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case ev, good := <-t.events:
+		return t.onEvent(ev, good), true
+	case <-t.done:
+		t.isdone.Store(1)
+		return taskTerminatedEvent, true
+	case <-expired:
+		return "", false
+	}
+}
+
+// onEvent processes an event read from t.events.
+func (t *task) onEvent(ev string, good bool) string {
+	if !good || ev == taskTerminatedEvent {
+		t.isdone.Store(1)
+		return taskTerminatedEvent
+	}
+	return ev
+}
+
+// isDone returns whether the task is done, i.e., whether we have
+// already delivered the task_terminated event.
+func (t *task) isDone() bool {
+	return t.isdone.Load() != 0
+}
+
+// free interrupts the task and releases its resources.
+func (t *task) free() {
+	t.once.Do(func() {
+		t.interrupt()
+		close(t.done)
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collectTaskEvents reads events until task_terminated and returns their keys.
+func collectTaskEvents(t *testing.T, tk *task) []string {
+	var keys []string
+	for !tk.isDone() {
+		ev, good := tk.waitForNextEvent(-1)
+		if !good {
+			t.Fatal("unexpected timeout when blocking")
+		}
+		var decoded struct {
+			Key string `json:"key"`
+		}
+		if err := json.Unmarshal([]byte(ev), &decoded); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, decoded.Key)
+	}
+	return keys
+}
+
+func newTestConfig(t *testing.T) *config {
+	return &config{
+		SoftwareName:    "libooniengine-test",
+		SoftwareVersion: "0.1.0",
+		StateDir:        t.TempDir(),
+		TempDir:         t.TempDir(),
+	}
+}
+
+// newBlockedTask returns a task that never emits any event.
+func newBlockedTask() *task {
+	return &task{
+		done:      make(chan any),
+		events:    make(chan string),
+		interrupt: func() {},
+		isdone:    &atomic.Int64{},
+	}
+}
+
+func TestStartTask(t *testing.T) {
+	t.Run("with invalid settings", func(t *testing.T) {
+		tk := startTask(newTestConfig(t), []byte("{"))
+		defer tk.free()
+		keys := collectTaskEvents(t, tk)
+		if len(keys) != 2 || keys[0] != "failure.startup" || keys[1] != "task_terminated" {
+			t.Fatal("unexpected events", keys)
+		}
+		ev, good := tk.waitForNextEvent(0)
+		if !good || ev != taskTerminatedEvent {
+			t.Fatal("expected task_terminated again", ev, good)
+		}
+	})
+
+	t.Run("with an unknown experiment", func(t *testing.T) {
+		tk := startTask(newTestConfig(t), []byte(`{"name":"Antani","log_level":"DEBUG"}`))
+		defer tk.free()
+		keys := collectTaskEvents(t, tk)
+		var sawStartupFailure bool
+		for _, key := range keys {
+			sawStartupFailure = sawStartupFailure || key == "failure.startup"
+		}
+		if !sawStartupFailure {
+			t.Fatal("expected failure.startup", keys)
+		}
+		if keys[len(keys)-1] != "task_terminated" {
+			t.Fatal("expected task_terminated last", keys)
+		}
+	})
+
+	t.Run("waitForNextEvent with zero timeout does not race with available events", func(t *testing.T) {
+		for idx := 0; idx < 100; idx++ {
+			tk := newFailedTask(errors.New("mocked error"))
+			if ev, good := tk.waitForNextEvent(0); !good || !strings.Contains(ev, "failure.startup") {
+				t.Fatal("expected failure.startup", ev, good)
+			}
+			if ev, good := tk.waitForNextEvent(0); !good || ev != taskTerminatedEvent {
+				t.Fatal("expected task_terminated", ev, good)
+			}
+			for again := 0; again < 10; again++ {
+				if ev, good := tk.waitForNextEvent(0); !good || ev != taskTerminatedEvent {
+					t.Fatal("expected task_terminated again", ev, good)
+				}
+			}
+		}
+	})
+
+	t.Run("waitForNextEvent honours the timeout", func(t *testing.T) {
+		tk := newBlockedTask()
+		ev, good := tk.waitForNextEvent(10 * time.Millisecond)
+		if good || ev != "" {
+			t.Fatal("expected timeout", ev, good)
+		}
+		if tk.isDone() {
+			t.Fatal("should not be done")
+		}
+	})
+
+	t.Run("free is idempotent and terminates the task", func(t *testing.T) {
+		tk := newBlockedTask()
+		var interrupted int
+		tk.interrupt = func() { interrupted++ }
+		tk.free()
+		tk.free()
+		if interrupted != 1 {
+			t.Fatal("expected one interrupt", interrupted)
+		}
+		ev, good := tk.waitForNextEvent(-1)
+		if !good || ev != taskTerminatedEvent || !tk.isDone() {
+			t.Fatal("expected task_terminated", ev, good)
+		}
+	})
+}
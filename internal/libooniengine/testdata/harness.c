@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+//
+// Test harness for the libooniengine C API.
+//
+// Usage: harness <state_dir> <temp_dir>
+//
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "engine.h"
+
+#define FAIL(msg)                                                              \
+  do {                                                                         \
+    fprintf(stderr, "harness: %s\n", msg);                                     \
+    exit(1);                                                                   \
+  } while (0)
+
+int main(int argc, char **argv) {
+  if (argc != 3) {
+    FAIL("usage: harness <state_dir> <temp_dir>");
+  }
+
+  char *version = OONIEngineVersion();
+  if (version == NULL || strlen(version) <= 0) {
+    FAIL("OONIEngineVersion returned an empty version");
+  }
+  OONIEngineFreeMemory(version);
+
+  if (OONIEngineNewConfig("{") != 0) {
+    FAIL("OONIEngineNewConfig accepted invalid JSON");
+  }
+
+  char config[4096];
+  snprintf(config, sizeof(config),
+           "{\"software_name\":\"harness\",\"software_version\":\"0.1.0\","
+           "\"state_dir\":\"%s\",\"temp_dir\":\"%s\",\"log_level\":\"DEBUG\"}",
+           argv[1], argv[2]);
+  OONIConfig cfg = OONIEngineNewConfig(config);
+  if (cfg == 0) {
+    FAIL("OONIEngineNewConfig failed");
+  }
+
+  // Use an invalid experiment name so that we don't need the network
+  OONITask task =
+      OONIEngineStartTask(cfg, "{\"name\":\"Antani\",\"inputs\":[]}");
+  if (task == 0) {
+    FAIL("OONIEngineStartTask failed");
+  }
+  OONIEngineInterruptTask(task);
+
+  int terminated = 0;
+  while (!terminated) {
+    char *ev = OONIEngineWaitForNextEvent(task, 250);
+    if (ev == NULL) {
+      continue; // timeout
+    }
+    printf("%s\n", ev);
+    terminated = strstr(ev, "\"task_terminated\"") != NULL;
+    OONIEngineFreeMemory(ev);
+  }
+  if (!OONIEngineTaskIsDone(task)) {
+    FAIL("OONIEngineTaskIsDone returned false after task_terminated");
+  }
+
+  OONIEngineFreeTask(task);
+  OONIEngineFreeTask(task); // must be idempotent
+  if (OONIEngineWaitForNextEvent(task, 0) != NULL) {
+    FAIL("OONIEngineWaitForNextEvent accepted a freed task");
+  }
+  OONIEngineFreeConfig(cfg);
+  if (OONIEngineStartTask(cfg, "{}") != 0) {
+    FAIL("OONIEngineStartTask accepted a freed config");
+  }
+  return 0;
+}
@@ -46,11 +46,8 @@ func (c *connTrace) Read(b []byte) (int, error) {
 	started := c.tx.TimeSince(c.tx.ZeroTime)
 	count, err := c.Conn.Read(b)
 	finished := c.tx.TimeSince(c.tx.ZeroTime)
-	select {
-	case c.tx.networkEvent <- NewArchivalNetworkEvent(
-		c.tx.Index, started, netxlite.ReadOperation, network, addr, count, err, finished):
-	default: // buffer is full
-	}
+	c.tx.emitNetworkEvent(NewArchivalNetworkEvent(
+		c.tx.Index, started, netxlite.ReadOperation, network, addr, count, err, finished))
 	return count, err
 }
 
@@ -61,11 +58,8 @@ func (c *connTrace) Write(b []byte) (int, error) {
 	started := c.tx.TimeSince(c.tx.ZeroTime)
 	count, err := c.Conn.Write(b)
 	finished := c.tx.TimeSince(c.tx.ZeroTime)
-	select {
-	case c.tx.networkEvent <- NewArchivalNetworkEvent(
-		c.tx.Index, started, netxlite.WriteOperation, network, addr, count, err, finished):
-	default: // buffer is full
-	}
+	c.tx.emitNetworkEvent(NewArchivalNetworkEvent(
+		c.tx.Index, started, netxlite.WriteOperation, network, addr, count, err, finished))
 	return count, err
 }
 
@@ -99,11 +93,8 @@ func (c *udpLikeConnTrace) ReadFrom(b []byte) (int, net.Addr, error) {
 	count, addr, err := c.UDPLikeConn.ReadFrom(b)
 	finished := c.tx.TimeSince(c.tx.ZeroTime)
 	address := addrStringIfNotNil(addr)
-	select {
-	case c.tx.networkEvent <- NewArchivalNetworkEvent(
-		c.tx.Index, started, netxlite.ReadFromOperation, "udp", address, count, err, finished):
-	default: // buffer is full
-	}
+	c.tx.emitNetworkEvent(NewArchivalNetworkEvent(
+		c.tx.Index, started, netxlite.ReadFromOperation, "udp", address, count, err, finished))
 	return count, addr, err
 }
 
@@ -113,11 +104,8 @@ func (c *udpLikeConnTrace) WriteTo(b []byte, addr net.Addr) (int, error) {
 	address := addr.String()
 	count, err := c.UDPLikeConn.WriteTo(b, addr)
 	finished := c.tx.TimeSince(c.tx.ZeroTime)
-	select {
-	case c.tx.networkEvent <- NewArchivalNetworkEvent(
-		c.tx.Index, started, netxlite.WriteToOperation, "udp", address, count, err, finished):
-	default: // buffer is full
-	}
+	c.tx.emitNetworkEvent(NewArchivalNetworkEvent(
+		c.tx.Index, started, netxlite.WriteToOperation, "udp", address, count, err, finished))
 	return count, err
 }
 
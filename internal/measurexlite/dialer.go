@@ -55,21 +55,17 @@ func (tx *Trace) OnConnectDone(
 	case "tcp", "tcp4", "tcp6":
 
 		// insert into the tcpConnect buffer
-		select {
-		case tx.tcpConnect <- NewArchivalTCPConnectResult(
+		tx.emitTCPConnect(NewArchivalTCPConnectResult(
 			tx.Index,
 			started.Sub(tx.ZeroTime),
 			remoteAddr,
 			err,
 			finished.Sub(tx.ZeroTime),
-		):
-		default: // buffer is full
-		}
+		))
 
 		// insert into the networkEvent buffer
 		// see https://github.com/ooni/probe/issues/2254
-		select {
-		case tx.networkEvent <- NewArchivalNetworkEvent(
+		tx.emitNetworkEvent(NewArchivalNetworkEvent(
 			tx.Index,
 			started.Sub(tx.ZeroTime),
 			netxlite.ConnectOperation,
@@ -78,9 +74,7 @@ func (tx *Trace) OnConnectDone(
 			0,
 			err,
 			finished.Sub(tx.ZeroTime),
-		):
-		default: // buffer is full
-		}
+		))
 
 	default:
 		// ignore UDP connect attempts because they cannot fail
@@ -51,22 +51,16 @@ func (r *resolverTrace) CloseIdleConnections() {
 
 // emits the resolve_start event
 func (r *resolverTrace) emitResolveStart() {
-	select {
-	case r.tx.networkEvent <- NewAnnotationArchivalNetworkEvent(
+	r.tx.emitNetworkEvent(NewAnnotationArchivalNetworkEvent(
 		r.tx.Index, r.tx.TimeSince(r.tx.ZeroTime), "resolve_start",
-	):
-	default: // buffer is full
-	}
+	))
 }
 
 // emits the resolve_done event
 func (r *resolverTrace) emiteResolveDone() {
-	select {
-	case r.tx.networkEvent <- NewAnnotationArchivalNetworkEvent(
+	r.tx.emitNetworkEvent(NewAnnotationArchivalNetworkEvent(
 		r.tx.Index, r.tx.TimeSince(r.tx.ZeroTime), "resolve_done",
-	):
-	default: // buffer is full
-	}
+	))
 }
 
 // LookupHost implements model.Resolver.LookupHost
@@ -90,6 +84,48 @@ func (r *resolverTrace) LookupNS(ctx context.Context, domain string) ([]*net.NS,
 	return r.r.LookupNS(netxlite.ContextWithTrace(ctx, r.tx), domain)
 }
 
+// LookupTXT implements model.Resolver.LookupTXT
+func (r *resolverTrace) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	defer r.emiteResolveDone()
+	r.emitResolveStart()
+	return r.r.LookupTXT(netxlite.ContextWithTrace(ctx, r.tx), domain)
+}
+
+// LookupMX implements model.Resolver.LookupMX
+func (r *resolverTrace) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	defer r.emiteResolveDone()
+	r.emitResolveStart()
+	return r.r.LookupMX(netxlite.ContextWithTrace(ctx, r.tx), domain)
+}
+
+// LookupCNAME implements model.Resolver.LookupCNAME
+func (r *resolverTrace) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	defer r.emiteResolveDone()
+	r.emitResolveStart()
+	return r.r.LookupCNAME(netxlite.ContextWithTrace(ctx, r.tx), domain)
+}
+
+// LookupSOA implements model.Resolver.LookupSOA
+func (r *resolverTrace) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	defer r.emiteResolveDone()
+	r.emitResolveStart()
+	return r.r.LookupSOA(netxlite.ContextWithTrace(ctx, r.tx), domain)
+}
+
+// LookupPTR implements model.Resolver.LookupPTR
+func (r *resolverTrace) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	defer r.emiteResolveDone()
+	r.emitResolveStart()
+	return r.r.LookupPTR(netxlite.ContextWithTrace(ctx, r.tx), address)
+}
+
+// LookupSRV implements model.Resolver.LookupSRV
+func (r *resolverTrace) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	defer r.emiteResolveDone()
+	r.emitResolveStart()
+	return r.r.LookupSRV(netxlite.ContextWithTrace(ctx, r.tx), domain)
+}
+
 // NewStdlibResolver returns a trace-ware system resolver
 func (tx *Trace) NewStdlibResolver(logger model.Logger) model.Resolver {
 	return tx.wrapResolver(tx.newStdlibResolver(logger))
@@ -105,12 +141,27 @@ func (tx *Trace) NewParallelDNSOverHTTPSResolver(logger model.Logger, URL string
 	return tx.wrapResolver(tx.newParallelDNSOverHTTPSResolver(logger, URL))
 }
 
+// NewParallelTCPResolver returns a trace-aware parallel DNS-over-TCP resolver
+func (tx *Trace) NewParallelTCPResolver(logger model.Logger, dialer model.Dialer, address string) model.Resolver {
+	return tx.wrapResolver(tx.newParallelTCPResolver(logger, dialer, address))
+}
+
+// NewParallelDNSOverTLSResolver returns a trace-aware parallel DNS-over-TLS resolver
+func (tx *Trace) NewParallelDNSOverTLSResolver(
+	logger model.Logger, dialer model.TLSDialer, address string) model.Resolver {
+	return tx.wrapResolver(tx.newParallelDNSOverTLSResolver(logger, dialer, address))
+}
+
+// NewParallelDNSOverHTTP3Resolver returns a trace-aware parallel DNS-over-HTTP3 resolver
+func (tx *Trace) NewParallelDNSOverHTTP3Resolver(logger model.Logger, URL string) model.Resolver {
+	return tx.wrapResolver(tx.newParallelDNSOverHTTP3Resolver(logger, URL))
+}
+
 // OnDNSRoundTripForLookupHost implements model.Trace.OnDNSRoundTripForLookupHost
 func (tx *Trace) OnDNSRoundTripForLookupHost(started time.Time, reso model.Resolver, query model.DNSQuery,
 	response model.DNSResponse, addrs []string, err error, finished time.Time) {
 	t := finished.Sub(tx.ZeroTime)
-	select {
-	case tx.dnsLookup <- NewArchivalDNSLookupResultFromRoundTrip(
+	tx.emitDNSLookup(NewArchivalDNSLookupResultFromRoundTrip(
 		tx.Index,
 		started.Sub(tx.ZeroTime),
 		reso,
@@ -119,9 +170,23 @@ func (tx *Trace) OnDNSRoundTripForLookupHost(started time.Time, reso model.Resol
 		addrs,
 		err,
 		t,
-	):
-	default:
-	}
+	))
+}
+
+// OnDNSRoundTripForLookupRecords implements model.Trace.OnDNSRoundTripForLookupRecords
+func (tx *Trace) OnDNSRoundTripForLookupRecords(started time.Time, reso model.Resolver, query model.DNSQuery,
+	response model.DNSResponse, err error, finished time.Time) {
+	t := finished.Sub(tx.ZeroTime)
+	tx.emitDNSLookup(NewArchivalDNSLookupResultFromRoundTrip(
+		tx.Index,
+		started.Sub(tx.ZeroTime),
+		reso,
+		query,
+		response,
+		[]string{}, // the answers are extracted from the response
+		err,
+		t,
+	))
 }
 
 // DNSNetworkAddresser is the type of something we just used to perform a DNS
@@ -140,7 +205,10 @@ type DNSNetworkAddresser interface {
 func NewArchivalDNSLookupResultFromRoundTrip(index int64, started time.Duration, reso DNSNetworkAddresser, query model.DNSQuery,
 	response model.DNSResponse, addrs []string, err error, finished time.Duration) *model.ArchivalDNSLookupResult {
 	return &model.ArchivalDNSLookupResult{
-		Answers:          newArchivalDNSAnswers(addrs, response),
+		Answers: append(
+			newArchivalDNSAnswers(addrs, response),
+			newArchivalDNSAnswersForQueryType(query.Type(), response)...,
+		),
 		Engine:           reso.Network(),
 		Failure:          tracex.NewFailure(err),
 		GetaddrinfoError: netxlite.ErrorToGetaddrinfoRetvalOrZero(err),
@@ -236,6 +304,75 @@ func newArchivalDNSAnswers(addrs []string, resp model.DNSResponse) (out []model.
 	return
 }
 
+// newArchivalDNSAnswersForQueryType generates []model.ArchivalDNSAnswer for the
+// record types we only extract when they match the query type (e.g., TXT). We cannot
+// do that in newArchivalDNSAnswers because it does not know the query type.
+func newArchivalDNSAnswersForQueryType(qtype uint16, resp model.DNSResponse) (out []model.ArchivalDNSAnswer) {
+	if resp == nil {
+		return
+	}
+	switch qtype {
+	case dns.TypeNS:
+		values, _ := resp.DecodeNS()
+		for _, value := range values {
+			out = append(out, model.ArchivalDNSAnswer{
+				AnswerType: "NS",
+				Hostname:   value.Host,
+			})
+		}
+	case dns.TypeTXT:
+		values, _ := resp.DecodeTXT()
+		for _, value := range values {
+			out = append(out, model.ArchivalDNSAnswer{
+				AnswerType: "TXT",
+				Text:       value,
+			})
+		}
+	case dns.TypeMX:
+		values, _ := resp.DecodeMX()
+		for _, value := range values {
+			out = append(out, model.ArchivalDNSAnswer{
+				AnswerType: "MX",
+				Hostname:   value.Host,
+				Preference: value.Pref,
+			})
+		}
+	case dns.TypeSOA:
+		if value, err := resp.DecodeSOA(); err == nil {
+			out = append(out, model.ArchivalDNSAnswer{
+				AnswerType:      "SOA",
+				Hostname:        value.NS,
+				ResponsibleName: value.Mbox,
+				SerialNumber:    value.Serial,
+				RefreshInterval: value.Refresh,
+				RetryInterval:   value.Retry,
+				ExpirationLimit: value.Expire,
+				MinimumTTL:      value.MinTTL,
+			})
+		}
+	case dns.TypePTR:
+		values, _ := resp.DecodePTR()
+		for _, value := range values {
+			out = append(out, model.ArchivalDNSAnswer{
+				AnswerType: "PTR",
+				Hostname:   value,
+			})
+		}
+	case dns.TypeSRV:
+		values, _ := resp.DecodeSRV()
+		for _, value := range values {
+			out = append(out, model.ArchivalDNSAnswer{
+				AnswerType: "SRV",
+				Hostname:   value.Target,
+				Port:       value.Port,
+				Priority:   value.Priority,
+				Weight:     value.Weight,
+			})
+		}
+	}
+	return
+}
+
 // DNSLookupsFromRoundTrip drains the network events buffered inside the DNSLookup channel
 func (tx *Trace) DNSLookupsFromRoundTrip() (out []*model.ArchivalDNSLookupResult) {
 	for {
@@ -265,8 +402,7 @@ var ErrDelayedDNSResponseBufferFull = errors.New("buffer full")
 func (tx *Trace) OnDelayedDNSResponse(started time.Time, txp model.DNSTransport, query model.DNSQuery,
 	response model.DNSResponse, addrs []string, err error, finished time.Time) error {
 	t := finished.Sub(tx.ZeroTime)
-	select {
-	case tx.delayedDNSResponse <- NewArchivalDNSLookupResultFromRoundTrip(
+	if !tx.emitDelayedDNSResponse(NewArchivalDNSLookupResultFromRoundTrip(
 		tx.Index,
 		started.Sub(tx.ZeroTime),
 		txp,
@@ -275,11 +411,10 @@ func (tx *Trace) OnDelayedDNSResponse(started time.Time, txp model.DNSTransport,
 		addrs,
 		err,
 		t,
-	):
-		return nil
-	default:
+	)) {
 		return ErrDelayedDNSResponseBufferFull
 	}
+	return nil
 }
 
 // DelayedDNSResponseWithTimeout drains the network events buffered inside
@@ -219,6 +219,133 @@ func TestNewResolver(t *testing.T) {
 		})
 	})
 
+	t.Run("LookupTXT, LookupMX, LookupCNAME, LookupSOA, LookupPTR, LookupSRV are correctly forwarded", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		var calls []string
+		mockResolver := &mocks.Resolver{
+			MockLookupTXT: func(ctx context.Context, domain string) ([]string, error) {
+				calls = append(calls, "TXT "+domain)
+				return []string{"v=spf1 -all"}, nil
+			},
+			MockLookupMX: func(ctx context.Context, domain string) ([]*net.MX, error) {
+				calls = append(calls, "MX "+domain)
+				return []*net.MX{{Host: "mx.example.com.", Pref: 10}}, nil
+			},
+			MockLookupCNAME: func(ctx context.Context, domain string) (string, error) {
+				calls = append(calls, "CNAME "+domain)
+				return "www.example.com.", nil
+			},
+			MockLookupSOA: func(ctx context.Context, domain string) (*model.DNSSOA, error) {
+				calls = append(calls, "SOA "+domain)
+				return &model.DNSSOA{NS: "ns1.example.com."}, nil
+			},
+			MockLookupPTR: func(ctx context.Context, address string) ([]string, error) {
+				calls = append(calls, "PTR "+address)
+				return []string{"dns.google."}, nil
+			},
+			MockLookupSRV: func(ctx context.Context, domain string) ([]*net.SRV, error) {
+				calls = append(calls, "SRV "+domain)
+				return []*net.SRV{{Target: "sip.example.com.", Port: 5060}}, nil
+			},
+		}
+		resolver := trace.wrapResolver(mockResolver)
+		ctx := context.Background()
+		if _, err := resolver.LookupTXT(ctx, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resolver.LookupMX(ctx, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resolver.LookupCNAME(ctx, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resolver.LookupSOA(ctx, "example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resolver.LookupPTR(ctx, "8.8.8.8"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := resolver.LookupSRV(ctx, "_sip._udp.example.com"); err != nil {
+			t.Fatal(err)
+		}
+		expectCalls := []string{
+			"TXT example.com",
+			"MX example.com",
+			"CNAME example.com",
+			"SOA example.com",
+			"PTR 8.8.8.8",
+			"SRV _sip._udp.example.com",
+		}
+		if diff := cmp.Diff(expectCalls, calls); diff != "" {
+			t.Fatal(diff)
+		}
+		events := trace.NetworkEvents()
+		if len(events) != 12 {
+			t.Fatal("unexpected network events length", len(events))
+		}
+	})
+
+	t.Run("LookupMX saves into trace", func(t *testing.T) {
+		zeroTime := time.Now()
+		td := testingx.NewTimeDeterministic(zeroTime)
+		trace := NewTrace(0, zeroTime)
+		trace.TimeNowFn = td.Now
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				response := &mocks.DNSResponse{
+					MockDecodeMX: func() ([]*net.MX, error) {
+						return []*net.MX{{Host: "mx.example.com.", Pref: 10}}, nil
+					},
+					MockDecodeCNAME: func() (string, error) {
+						return "", netxlite.ErrOODNSNoAnswer
+					},
+					MockRcode: func() int {
+						return 0
+					},
+					MockBytes: func() []byte {
+						return []byte{}
+					},
+				}
+				return response, nil
+			},
+			MockRequiresPadding: func() bool {
+				return true
+			},
+			MockNetwork: func() string {
+				return "mocked"
+			},
+			MockAddress: func() string {
+				return "dns.google"
+			},
+		}
+		r := netxlite.NewUnwrappedParallelResolver(txp)
+		resolver := trace.wrapResolver(r)
+		ctx := context.Background()
+		mx, err := resolver.LookupMX(ctx, "example.com")
+		if err != nil {
+			t.Fatal("unexpected err", err)
+		}
+		if len(mx) != 1 {
+			t.Fatal("unexpected array output", mx)
+		}
+		events := trace.DNSLookupsFromRoundTrip()
+		if len(events) != 1 {
+			t.Fatal("unexpected DNS events length")
+		}
+		expect := []model.ArchivalDNSAnswer{{
+			AnswerType: "MX",
+			Hostname:   "mx.example.com.",
+			Preference: 10,
+		}}
+		if diff := cmp.Diff(expect, events[0].Answers); diff != "" {
+			t.Fatal(diff)
+		}
+		if events[0].QueryType != "MX" {
+			t.Fatal("unexpected query type", events[0].QueryType)
+		}
+	})
+
 	t.Run("LookupHost discards events when buffers are full", func(t *testing.T) {
 		zeroTime := time.Now()
 		td := testingx.NewTimeDeterministic(zeroTime)
@@ -318,6 +445,50 @@ func TestNewWrappedResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("NewParallelTCPResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := netxlite.NewDialerWithStdlibResolver(model.DiscardLogger)
+		resolver := trace.NewParallelTCPResolver(model.DiscardLogger, dialer, "1.1.1.1:53")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "tcp" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
+	t.Run("NewParallelDNSOverTLSResolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		dialer := netxlite.NewTLSDialer(
+			netxlite.NewDialerWithStdlibResolver(model.DiscardLogger),
+			netxlite.NewTLSHandshakerStdlib(model.DiscardLogger),
+		)
+		resolver := trace.NewParallelDNSOverTLSResolver(model.DiscardLogger, dialer, "1.1.1.1:853")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "dot" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
+	t.Run("NewParallelDNSOverHTTP3Resolver works as intended", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		resolver := trace.NewParallelDNSOverHTTP3Resolver(model.DiscardLogger, "https://dns.google.com")
+		resolvert := resolver.(*resolverTrace)
+		if resolvert.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if resolver.Network() != "doh" {
+			t.Fatal("unexpected resolver network")
+		}
+	})
+
 	t.Run("NewStdlibResolver works as intended", func(t *testing.T) {
 		zeroTime := time.Now()
 		trace := NewTrace(0, zeroTime)
@@ -721,3 +892,126 @@ func TestNewArchivalDNSAnswers(t *testing.T) {
 		})
 	}
 }
+
+func TestNewArchivalDNSAnswersForQueryType(t *testing.T) {
+	resp := &mocks.DNSResponse{
+		MockDecodeNS: func() ([]*net.NS, error) {
+			return []*net.NS{{Host: "ns1.example.com."}}, nil
+		},
+		MockDecodeTXT: func() ([]string, error) {
+			return []string{"v=spf1 -all"}, nil
+		},
+		MockDecodeMX: func() ([]*net.MX, error) {
+			return []*net.MX{{Host: "mx.example.com.", Pref: 10}}, nil
+		},
+		MockDecodeSOA: func() (*model.DNSSOA, error) {
+			return &model.DNSSOA{
+				NS:      "ns1.example.com.",
+				Mbox:    "hostmaster.example.com.",
+				Serial:  1,
+				Refresh: 2,
+				Retry:   3,
+				Expire:  4,
+				MinTTL:  5,
+			}, nil
+		},
+		MockDecodePTR: func() ([]string, error) {
+			return []string{"dns.google."}, nil
+		},
+		MockDecodeSRV: func() ([]*net.SRV, error) {
+			return []*net.SRV{{Target: "sip.example.com.", Port: 5060, Priority: 10, Weight: 5}}, nil
+		},
+	}
+
+	tests := []struct {
+		name     string
+		qtype    uint16
+		resp     model.DNSResponse
+		expected []model.ArchivalDNSAnswer
+	}{{
+		name:     "with nil response",
+		qtype:    dns.TypeTXT,
+		resp:     nil,
+		expected: nil,
+	}, {
+		name:     "with A query",
+		qtype:    dns.TypeA,
+		resp:     resp,
+		expected: nil,
+	}, {
+		name:  "with NS query",
+		qtype: dns.TypeNS,
+		resp:  resp,
+		expected: []model.ArchivalDNSAnswer{{
+			AnswerType: "NS",
+			Hostname:   "ns1.example.com.",
+		}},
+	}, {
+		name:  "with TXT query",
+		qtype: dns.TypeTXT,
+		resp:  resp,
+		expected: []model.ArchivalDNSAnswer{{
+			AnswerType: "TXT",
+			Text:       "v=spf1 -all",
+		}},
+	}, {
+		name:  "with MX query",
+		qtype: dns.TypeMX,
+		resp:  resp,
+		expected: []model.ArchivalDNSAnswer{{
+			AnswerType: "MX",
+			Hostname:   "mx.example.com.",
+			Preference: 10,
+		}},
+	}, {
+		name:  "with SOA query",
+		qtype: dns.TypeSOA,
+		resp:  resp,
+		expected: []model.ArchivalDNSAnswer{{
+			AnswerType:      "SOA",
+			Hostname:        "ns1.example.com.",
+			ResponsibleName: "hostmaster.example.com.",
+			SerialNumber:    1,
+			RefreshInterval: 2,
+			RetryInterval:   3,
+			ExpirationLimit: 4,
+			MinimumTTL:      5,
+		}},
+	}, {
+		name:  "with PTR query",
+		qtype: dns.TypePTR,
+		resp:  resp,
+		expected: []model.ArchivalDNSAnswer{{
+			AnswerType: "PTR",
+			Hostname:   "dns.google.",
+		}},
+	}, {
+		name:  "with SRV query",
+		qtype: dns.TypeSRV,
+		resp:  resp,
+		expected: []model.ArchivalDNSAnswer{{
+			AnswerType: "SRV",
+			Hostname:   "sip.example.com.",
+			Port:       5060,
+			Priority:   10,
+			Weight:     5,
+		}},
+	}, {
+		name:  "with SOA decode error",
+		qtype: dns.TypeSOA,
+		resp: &mocks.DNSResponse{
+			MockDecodeSOA: func() (*model.DNSSOA, error) {
+				return nil, errors.New("mocked error")
+			},
+		},
+		expected: nil,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newArchivalDNSAnswersForQueryType(tt.qtype, tt.resp)
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
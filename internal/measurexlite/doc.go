@@ -6,5 +6,12 @@
 // This implementation features a Trace that saves events in
 // buffered channels as proposed by df-003-step-by-step.md. We
 // have reasonable default buffers for channels. But, if you
-// are not draining them, eventually we stop collecting events.
+// are not draining them, eventually we stop collecting events
+// and we count the dropped events (see Trace.Overflow). You can
+// also register a Subscriber with a Trace to receive all the
+// events as soon as they occur. Experiments SHOULD create traces
+// using NewTraceWithContext, which applies the TraceConfig that
+// the engine binds to the measurement context, such that the
+// session's Subscriber receives live events and we warn when
+// a Trace drops events.
 package measurexlite
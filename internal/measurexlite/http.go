@@ -74,6 +74,45 @@ func NewArchivalHTTPRequestResult(index int64, started time.Duration, network, a
 	}
 }
 
+// OnHTTPTransactionDone records the result of an HTTP transaction into the
+// trace's buffer and returns the corresponding archival result. Because netxlite
+// does not know when an HTTP transaction is complete (the body is read by the
+// caller), code performing HTTP transactions should call this method after
+// reading the response body. The arguments have the same semantics of the
+// arguments of NewArchivalHTTPRequestResult except that times are absolute.
+func (tx *Trace) OnHTTPTransactionDone(started time.Time, network, address, alpn, transport string,
+	req *http.Request, resp *http.Response, maxRespBodySize int64, body []byte, err error,
+	finished time.Time) *model.ArchivalHTTPRequestResult {
+	ev := NewArchivalHTTPRequestResult(
+		tx.Index,
+		started.Sub(tx.ZeroTime),
+		network,
+		address,
+		alpn,
+		transport,
+		req,
+		resp,
+		maxRespBodySize,
+		body,
+		err,
+		finished.Sub(tx.ZeroTime),
+	)
+	tx.emitHTTPTransaction(ev)
+	return ev
+}
+
+// HTTPTransactions drains the HTTP transactions buffered inside the httpTransaction channel.
+func (tx *Trace) HTTPTransactions() (out []*model.ArchivalHTTPRequestResult) {
+	for {
+		select {
+		case ev := <-tx.httpTransaction:
+			out = append(out, ev)
+		default:
+			return // done
+		}
+	}
+}
+
 // httpRequestMethod returns the HTTP request method or an empty string
 func httpRequestMethod(req *http.Request) (out string) {
 	if req != nil {
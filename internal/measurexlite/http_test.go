@@ -363,3 +363,62 @@ func TestNewArchivalHTTPRequestResult(t *testing.T) {
 		})
 	}
 }
+
+func TestOnHTTPTransactionDone(t *testing.T) {
+	t.Run("we buffer the result and return it", func(t *testing.T) {
+		zeroTime := time.Now()
+		trace := NewTrace(17, zeroTime)
+		req, err := http.NewRequest("GET", "https://dns.google/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := &http.Response{
+			StatusCode: 302,
+			Header: http.Header{
+				"Location": {"/v2/index.html"},
+			},
+			Request: req,
+		}
+		body := []byte("abc")
+		ev := trace.OnHTTPTransactionDone(
+			zeroTime.Add(time.Second),
+			"tcp",
+			"8.8.8.8:443",
+			"h2",
+			"tcp",
+			req,
+			resp,
+			3,
+			body,
+			nil,
+			zeroTime.Add(2*time.Second),
+		)
+		if ev.TransactionID != 17 || ev.T0 != 1 || ev.T != 2 {
+			t.Fatal("unexpected transaction ID or timing")
+		}
+		if ev.Response.Code != 302 || !ev.Response.BodyIsTruncated {
+			t.Fatal("unexpected response")
+		}
+		if diff := cmp.Diff([]string{"https://dns.google/v2/index.html"}, ev.Response.Locations); diff != "" {
+			t.Fatal(diff)
+		}
+		out := trace.HTTPTransactions()
+		if len(out) != 1 || out[0] != ev {
+			t.Fatal("expected to see the buffered result")
+		}
+		if len(trace.HTTPTransactions()) != 0 {
+			t.Fatal("expected the buffer to be drained")
+		}
+	})
+
+	t.Run("we do not block when the buffer is full", func(t *testing.T) {
+		trace := NewTrace(0, time.Now())
+		for idx := 0; idx < 2*HTTPTransactionBufferSize; idx++ {
+			trace.OnHTTPTransactionDone(time.Now(), "tcp", "8.8.8.8:80", "", "tcp",
+				nil, nil, 0, nil, netxlite.ECONNRESET, time.Now())
+		}
+		if len(trace.HTTPTransactions()) != HTTPTransactionBufferSize {
+			t.Fatal("unexpected number of buffered transactions")
+		}
+	})
+}
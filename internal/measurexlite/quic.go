@@ -46,10 +46,7 @@ func (qdx *quicDialerTrace) CloseIdleConnections() {
 // OnQUICHandshakeStart implements model.Trace.OnQUICHandshakeStart
 func (tx *Trace) OnQUICHandshakeStart(now time.Time, remoteAddr string, config *quic.Config) {
 	t := now.Sub(tx.ZeroTime)
-	select {
-	case tx.networkEvent <- NewAnnotationArchivalNetworkEvent(tx.Index, t, "quic_handshake_start"):
-	default:
-	}
+	tx.emitNetworkEvent(NewAnnotationArchivalNetworkEvent(tx.Index, t, "quic_handshake_start"))
 }
 
 // OnQUICHandshakeDone implements model.Trace.OnQUICHandshakeDone
@@ -60,8 +57,7 @@ func (tx *Trace) OnQUICHandshakeDone(started time.Time, remoteAddr string, qconn
 	if qconn != nil {
 		state = qconn.ConnectionState().TLS.ConnectionState
 	}
-	select {
-	case tx.quicHandshake <- NewArchivalTLSOrQUICHandshakeResult(
+	tx.emitQUICHandshake(NewArchivalTLSOrQUICHandshakeResult(
 		tx.Index,
 		started.Sub(tx.ZeroTime),
 		"udp",
@@ -70,13 +66,8 @@ func (tx *Trace) OnQUICHandshakeDone(started time.Time, remoteAddr string, qconn
 		state,
 		err,
 		t,
-	):
-	default: // buffer is full
-	}
-	select {
-	case tx.networkEvent <- NewAnnotationArchivalNetworkEvent(tx.Index, t, "quic_handshake_done"):
-	default: // buffer is full
-	}
+	))
+	tx.emitNetworkEvent(NewAnnotationArchivalNetworkEvent(tx.Index, t, "quic_handshake_done"))
 }
 
 // QUICHandshakes drains the network events buffered inside the QUICHandshake channel.
@@ -0,0 +1,290 @@
+package measurexlite
+
+//
+// Live events subscriber and overflow accounting
+//
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Subscriber receives the events collected by a Trace as soon as they occur,
+// which allows, e.g., to show real-time progress or to tail a measurement.
+//
+// The Trace calls the Subscriber methods synchronously from the goroutine that
+// performed the operation, hence these methods MUST NOT block and MUST be safe
+// to call from multiple goroutines. The Subscriber MUST NOT modify the events.
+type Subscriber interface {
+	// OnNetworkEvent is called for each network event.
+	OnNetworkEvent(ev *model.ArchivalNetworkEvent)
+
+	// OnDNSLookup is called for each DNS lookup.
+	OnDNSLookup(ev *model.ArchivalDNSLookupResult)
+
+	// OnDelayedDNSResponse is called for each delayed DNS response.
+	OnDelayedDNSResponse(ev *model.ArchivalDNSLookupResult)
+
+	// OnTCPConnect is called for each TCP connect.
+	OnTCPConnect(ev *model.ArchivalTCPConnectResult)
+
+	// OnTLSHandshake is called for each TLS handshake.
+	OnTLSHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult)
+
+	// OnQUICHandshake is called for each QUIC handshake.
+	OnQUICHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult)
+
+	// OnHTTPTransaction is called for each HTTP transaction.
+	OnHTTPTransaction(ev *model.ArchivalHTTPRequestResult)
+}
+
+// TraceConfig contains the settings that NewTraceWithContext applies to each
+// Trace it creates. The engine binds a TraceConfig to the context it uses for
+// measuring, such that experiments creating traces using NewTraceWithContext
+// deliver live events to the session's Subscriber and warn about overflows.
+type TraceConfig struct {
+	// Logger is the OPTIONAL logger used to warn about dropped events.
+	Logger model.Logger
+
+	// Subscriber is the OPTIONAL Subscriber receiving all the events.
+	Subscriber Subscriber
+}
+
+// traceConfigKey is the private type used to set/retrieve the context's TraceConfig.
+type traceConfigKey struct{}
+
+// ContextWithTraceConfig returns a new context that binds to the given TraceConfig.
+func ContextWithTraceConfig(ctx context.Context, config *TraceConfig) context.Context {
+	return context.WithValue(ctx, traceConfigKey{}, config)
+}
+
+// NewTraceWithContext is like NewTrace but also applies the TraceConfig
+// bound to the given context using ContextWithTraceConfig, if any.
+func NewTraceWithContext(ctx context.Context, index int64, zeroTime time.Time) *Trace {
+	tx := NewTrace(index, zeroTime)
+	if config, _ := ctx.Value(traceConfigKey{}).(*TraceConfig); config != nil {
+		tx.Logger = config.Logger
+		tx.Subscriber = config.Subscriber
+	}
+	return tx
+}
+
+// TraceOverflow contains the number of events that a Trace has dropped
+// because the corresponding buffered channel was full.
+type TraceOverflow struct {
+	// NetworkEvent is the number of dropped network events.
+	NetworkEvent int64
+
+	// DNSLookup is the number of dropped DNS lookups.
+	DNSLookup int64
+
+	// DelayedDNSResponse is the number of dropped delayed DNS responses.
+	DelayedDNSResponse int64
+
+	// TCPConnect is the number of dropped TCP connects.
+	TCPConnect int64
+
+	// TLSHandshake is the number of dropped TLS handshakes.
+	TLSHandshake int64
+
+	// QUICHandshake is the number of dropped QUIC handshakes.
+	QUICHandshake int64
+
+	// HTTPTransaction is the number of dropped HTTP transactions.
+	HTTPTransaction int64
+}
+
+// Total returns the total number of dropped events.
+func (o TraceOverflow) Total() int64 {
+	return o.NetworkEvent + o.DNSLookup + o.DelayedDNSResponse + o.TCPConnect +
+		o.TLSHandshake + o.QUICHandshake + o.HTTPTransaction
+}
+
+// traceOverflowCounters contains the counters backing TraceOverflow.
+type traceOverflowCounters struct {
+	networkEvent       atomic.Int64
+	dnsLookup          atomic.Int64
+	delayedDNSResponse atomic.Int64
+	tcpConnect         atomic.Int64
+	tlsHandshake       atomic.Int64
+	quicHandshake      atomic.Int64
+	httpTransaction    atomic.Int64
+}
+
+// Overflow returns the number of events dropped so far because the
+// corresponding buffered channel was full. A Subscriber, if any, receives
+// all the events, including the ones counted here.
+func (tx *Trace) Overflow() TraceOverflow {
+	return TraceOverflow{
+		NetworkEvent:       tx.overflow.networkEvent.Load(),
+		DNSLookup:          tx.overflow.dnsLookup.Load(),
+		DelayedDNSResponse: tx.overflow.delayedDNSResponse.Load(),
+		TCPConnect:         tx.overflow.tcpConnect.Load(),
+		TLSHandshake:       tx.overflow.tlsHandshake.Load(),
+		QUICHandshake:      tx.overflow.quicHandshake.Load(),
+		HTTPTransaction:    tx.overflow.httpTransaction.Load(),
+	}
+}
+
+// maybeWarnOverflow warns that we have dropped count events of the given kind
+// so far. To avoid flooding the logs, we only warn when count is a power of two.
+func (tx *Trace) maybeWarnOverflow(kind string, count int64) {
+	if tx.Logger != nil && count > 0 && count&(count-1) == 0 {
+		tx.Logger.Warnf(
+			"measurexlite: trace #%d dropped %d %s because the buffer is full",
+			tx.Index, count, kind)
+	}
+}
+
+// emitNetworkEvent notifies the Subscriber and buffers a network event.
+func (tx *Trace) emitNetworkEvent(ev *model.ArchivalNetworkEvent) {
+	if tx.Subscriber != nil {
+		tx.Subscriber.OnNetworkEvent(ev)
+	}
+	select {
+	case tx.networkEvent <- ev:
+	default: // buffer is full
+		tx.maybeWarnOverflow("network events", tx.overflow.networkEvent.Add(1))
+	}
+}
+
+// emitDNSLookup notifies the Subscriber and buffers a DNS lookup.
+func (tx *Trace) emitDNSLookup(ev *model.ArchivalDNSLookupResult) {
+	if tx.Subscriber != nil {
+		tx.Subscriber.OnDNSLookup(ev)
+	}
+	select {
+	case tx.dnsLookup <- ev:
+	default: // buffer is full
+		tx.maybeWarnOverflow("DNS lookups", tx.overflow.dnsLookup.Add(1))
+	}
+}
+
+// emitDelayedDNSResponse notifies the Subscriber and buffers a delayed DNS
+// response. This function returns false if the buffer was full.
+func (tx *Trace) emitDelayedDNSResponse(ev *model.ArchivalDNSLookupResult) bool {
+	if tx.Subscriber != nil {
+		tx.Subscriber.OnDelayedDNSResponse(ev)
+	}
+	select {
+	case tx.delayedDNSResponse <- ev:
+		return true
+	default: // buffer is full
+		tx.maybeWarnOverflow("delayed DNS responses", tx.overflow.delayedDNSResponse.Add(1))
+		return false
+	}
+}
+
+// emitTCPConnect notifies the Subscriber and buffers a TCP connect.
+func (tx *Trace) emitTCPConnect(ev *model.ArchivalTCPConnectResult) {
+	if tx.Subscriber != nil {
+		tx.Subscriber.OnTCPConnect(ev)
+	}
+	select {
+	case tx.tcpConnect <- ev:
+	default: // buffer is full
+		tx.maybeWarnOverflow("TCP connects", tx.overflow.tcpConnect.Add(1))
+	}
+}
+
+// emitTLSHandshake notifies the Subscriber and buffers a TLS handshake.
+func (tx *Trace) emitTLSHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult) {
+	if tx.Subscriber != nil {
+		tx.Subscriber.OnTLSHandshake(ev)
+	}
+	select {
+	case tx.tlsHandshake <- ev:
+	default: // buffer is full
+		tx.maybeWarnOverflow("TLS handshakes", tx.overflow.tlsHandshake.Add(1))
+	}
+}
+
+// emitQUICHandshake notifies the Subscriber and buffers a QUIC handshake.
+func (tx *Trace) emitQUICHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult) {
+	if tx.Subscriber != nil {
+		tx.Subscriber.OnQUICHandshake(ev)
+	}
+	select {
+	case tx.quicHandshake <- ev:
+	default: // buffer is full
+		tx.maybeWarnOverflow("QUIC handshakes", tx.overflow.quicHandshake.Add(1))
+	}
+}
+
+// emitHTTPTransaction notifies the Subscriber and buffers an HTTP transaction.
+func (tx *Trace) emitHTTPTransaction(ev *model.ArchivalHTTPRequestResult) {
+	if tx.Subscriber != nil {
+		tx.Subscriber.OnHTTPTransaction(ev)
+	}
+	select {
+	case tx.httpTransaction <- ev:
+	default: // buffer is full
+		tx.maybeWarnOverflow("HTTP transactions", tx.overflow.httpTransaction.Add(1))
+	}
+}
+
+// NewLoggerSubscriber returns a Subscriber that emits a debug message for
+// each event. The engine uses it to show the events as soon as they occur.
+func NewLoggerSubscriber(logger model.DebugLogger) Subscriber {
+	return &loggerSubscriber{logger}
+}
+
+// loggerSubscriber is the Subscriber returned by NewLoggerSubscriber.
+type loggerSubscriber struct {
+	logger model.DebugLogger
+}
+
+var _ Subscriber = &loggerSubscriber{}
+
+// OnNetworkEvent implements Subscriber.
+func (s *loggerSubscriber) OnNetworkEvent(ev *model.ArchivalNetworkEvent) {
+	s.logger.Debugf("event #%d: %s %s %s %d bytes: %s", ev.TransactionID,
+		ev.Operation, ev.Proto, ev.Address, ev.NumBytes, loggerSubscriberFailure(ev.Failure))
+}
+
+// OnDNSLookup implements Subscriber.
+func (s *loggerSubscriber) OnDNSLookup(ev *model.ArchivalDNSLookupResult) {
+	s.logger.Debugf("event #%d: dns_lookup %s %s %s %s: %s", ev.TransactionID, ev.Engine,
+		ev.ResolverAddress, ev.QueryType, ev.Hostname, loggerSubscriberFailure(ev.Failure))
+}
+
+// OnDelayedDNSResponse implements Subscriber.
+func (s *loggerSubscriber) OnDelayedDNSResponse(ev *model.ArchivalDNSLookupResult) {
+	s.logger.Debugf("event #%d: delayed_dns_response %s %s %s %s: %s", ev.TransactionID, ev.Engine,
+		ev.ResolverAddress, ev.QueryType, ev.Hostname, loggerSubscriberFailure(ev.Failure))
+}
+
+// OnTCPConnect implements Subscriber.
+func (s *loggerSubscriber) OnTCPConnect(ev *model.ArchivalTCPConnectResult) {
+	s.logger.Debugf("event #%d: tcp_connect %s:%d: %s", ev.TransactionID,
+		ev.IP, ev.Port, loggerSubscriberFailure(ev.Status.Failure))
+}
+
+// OnTLSHandshake implements Subscriber.
+func (s *loggerSubscriber) OnTLSHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult) {
+	s.logger.Debugf("event #%d: tls_handshake %s %s: %s", ev.TransactionID,
+		ev.Address, ev.ServerName, loggerSubscriberFailure(ev.Failure))
+}
+
+// OnQUICHandshake implements Subscriber.
+func (s *loggerSubscriber) OnQUICHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult) {
+	s.logger.Debugf("event #%d: quic_handshake %s %s: %s", ev.TransactionID,
+		ev.Address, ev.ServerName, loggerSubscriberFailure(ev.Failure))
+}
+
+// OnHTTPTransaction implements Subscriber.
+func (s *loggerSubscriber) OnHTTPTransaction(ev *model.ArchivalHTTPRequestResult) {
+	s.logger.Debugf("event #%d: http_transaction %s %s %d: %s", ev.TransactionID,
+		ev.Request.Method, ev.Request.URL, ev.Response.Code, loggerSubscriberFailure(ev.Failure))
+}
+
+// loggerSubscriberFailure returns a string representation of the failure.
+func loggerSubscriberFailure(failure *string) string {
+	if failure == nil {
+		return "success"
+	}
+	return *failure
+}
@@ -0,0 +1,220 @@
+package measurexlite
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+// subscriberRecorder is a Subscriber recording the events it sees.
+type subscriberRecorder struct {
+	events []string
+	mu     sync.Mutex
+}
+
+var _ Subscriber = &subscriberRecorder{}
+
+func (s *subscriberRecorder) record(name string) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	s.events = append(s.events, name)
+}
+
+func (s *subscriberRecorder) OnNetworkEvent(ev *model.ArchivalNetworkEvent) {
+	s.record("network_event")
+}
+
+func (s *subscriberRecorder) OnDNSLookup(ev *model.ArchivalDNSLookupResult) {
+	s.record("dns_lookup")
+}
+
+func (s *subscriberRecorder) OnDelayedDNSResponse(ev *model.ArchivalDNSLookupResult) {
+	s.record("delayed_dns_response")
+}
+
+func (s *subscriberRecorder) OnTCPConnect(ev *model.ArchivalTCPConnectResult) {
+	s.record("tcp_connect")
+}
+
+func (s *subscriberRecorder) OnTLSHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult) {
+	s.record("tls_handshake")
+}
+
+func (s *subscriberRecorder) OnQUICHandshake(ev *model.ArchivalTLSOrQUICHandshakeResult) {
+	s.record("quic_handshake")
+}
+
+func (s *subscriberRecorder) OnHTTPTransaction(ev *model.ArchivalHTTPRequestResult) {
+	s.record("http_transaction")
+}
+
+// subscriberEmitAll emits one event of each kind using the given trace.
+func subscriberEmitAll(tx *Trace) {
+	tx.emitNetworkEvent(&model.ArchivalNetworkEvent{})
+	tx.emitDNSLookup(&model.ArchivalDNSLookupResult{})
+	tx.emitDelayedDNSResponse(&model.ArchivalDNSLookupResult{})
+	tx.emitTCPConnect(&model.ArchivalTCPConnectResult{})
+	tx.emitTLSHandshake(&model.ArchivalTLSOrQUICHandshakeResult{})
+	tx.emitQUICHandshake(&model.ArchivalTLSOrQUICHandshakeResult{})
+	tx.emitHTTPTransaction(&model.ArchivalHTTPRequestResult{})
+}
+
+func TestTraceSubscriber(t *testing.T) {
+	expectEvents := []string{
+		"network_event",
+		"dns_lookup",
+		"delayed_dns_response",
+		"tcp_connect",
+		"tls_handshake",
+		"quic_handshake",
+		"http_transaction",
+	}
+
+	t.Run("when the buffers are not full", func(t *testing.T) {
+		sub := &subscriberRecorder{}
+		tx := NewTrace(0, time.Now())
+		tx.Subscriber = sub
+		subscriberEmitAll(tx)
+		if diff := cmp.Diff(expectEvents, sub.events); diff != "" {
+			t.Fatal(diff)
+		}
+		if overflow := tx.Overflow(); overflow.Total() != 0 {
+			t.Fatal("unexpected overflow", overflow)
+		}
+		if len(tx.NetworkEvents()) != 1 || len(tx.DNSLookupsFromRoundTrip()) != 1 {
+			t.Fatal("expected buffered events")
+		}
+		if len(tx.TCPConnects()) != 1 || len(tx.TLSHandshakes()) != 1 {
+			t.Fatal("expected buffered events")
+		}
+		if len(tx.QUICHandshakes()) != 1 || len(tx.HTTPTransactions()) != 1 {
+			t.Fatal("expected buffered events")
+		}
+	})
+
+	t.Run("when the buffers are full", func(t *testing.T) {
+		sub := &subscriberRecorder{}
+		tx := NewTrace(0, time.Now())
+		tx.Subscriber = sub
+		tx.networkEvent = make(chan *model.ArchivalNetworkEvent)
+		tx.dnsLookup = make(chan *model.ArchivalDNSLookupResult)
+		tx.delayedDNSResponse = make(chan *model.ArchivalDNSLookupResult)
+		tx.tcpConnect = make(chan *model.ArchivalTCPConnectResult)
+		tx.tlsHandshake = make(chan *model.ArchivalTLSOrQUICHandshakeResult)
+		tx.quicHandshake = make(chan *model.ArchivalTLSOrQUICHandshakeResult)
+		tx.httpTransaction = make(chan *model.ArchivalHTTPRequestResult)
+		subscriberEmitAll(tx)
+		subscriberEmitAll(tx)
+		if diff := cmp.Diff(append(expectEvents, expectEvents...), sub.events); diff != "" {
+			t.Fatal(diff)
+		}
+		expectOverflow := TraceOverflow{
+			NetworkEvent:       2,
+			DNSLookup:          2,
+			DelayedDNSResponse: 2,
+			TCPConnect:         2,
+			TLSHandshake:       2,
+			QUICHandshake:      2,
+			HTTPTransaction:    2,
+		}
+		overflow := tx.Overflow()
+		if diff := cmp.Diff(expectOverflow, overflow); diff != "" {
+			t.Fatal(diff)
+		}
+		if overflow.Total() != 14 {
+			t.Fatal("unexpected total", overflow.Total())
+		}
+	})
+
+	t.Run("without a subscriber", func(t *testing.T) {
+		tx := NewTrace(0, time.Now())
+		subscriberEmitAll(tx) // should not crash
+		if overflow := tx.Overflow(); overflow.Total() != 0 {
+			t.Fatal("unexpected overflow", overflow)
+		}
+	})
+
+	t.Run("the subscriber sees events emitted by the trace", func(t *testing.T) {
+		sub := &subscriberRecorder{}
+		zeroTime := time.Now()
+		tx := NewTrace(0, zeroTime)
+		tx.Subscriber = sub
+		tx.OnConnectDone(zeroTime, "tcp", "dns.google", "8.8.8.8:443", nil, time.Now())
+		expect := []string{"tcp_connect", "network_event"}
+		if diff := cmp.Diff(expect, sub.events); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestNewTraceWithContext(t *testing.T) {
+	t.Run("without a config", func(t *testing.T) {
+		tx := NewTraceWithContext(context.Background(), 7, time.Now())
+		if tx.Index != 7 {
+			t.Fatal("unexpected index")
+		}
+		if tx.Logger != nil || tx.Subscriber != nil {
+			t.Fatal("expected nil logger and subscriber")
+		}
+	})
+
+	t.Run("with a config", func(t *testing.T) {
+		config := &TraceConfig{
+			Logger:     model.DiscardLogger,
+			Subscriber: &subscriberRecorder{},
+		}
+		ctx := ContextWithTraceConfig(context.Background(), config)
+		tx := NewTraceWithContext(ctx, 7, time.Now())
+		if tx.Index != 7 {
+			t.Fatal("unexpected index")
+		}
+		if tx.Logger != config.Logger || tx.Subscriber != config.Subscriber {
+			t.Fatal("did not apply the config")
+		}
+	})
+}
+
+func TestTraceWarnsAboutOverflow(t *testing.T) {
+	var warnings []string
+	tx := NewTrace(0, time.Now())
+	tx.Logger = &mocks.Logger{
+		MockWarnf: func(format string, v ...interface{}) {
+			warnings = append(warnings, format)
+		},
+	}
+	const count = 9
+	for idx := 0; idx < TCPConnectBufferSize+count; idx++ {
+		tx.emitTCPConnect(&model.ArchivalTCPConnectResult{})
+	}
+	if tx.Overflow().TCPConnect != count {
+		t.Fatal("unexpected overflow", tx.Overflow())
+	}
+	// we only warn at 1, 2, 4, and 8 dropped events
+	if len(warnings) != 4 {
+		t.Fatal("unexpected number of warnings", len(warnings))
+	}
+}
+
+func TestLoggerSubscriber(t *testing.T) {
+	var count int
+	logger := &mocks.Logger{
+		MockDebugf: func(format string, v ...interface{}) {
+			count++
+		},
+	}
+	tx := NewTrace(0, time.Now())
+	tx.Subscriber = NewLoggerSubscriber(logger)
+	subscriberEmitAll(tx)
+	failure := "generic_timeout_error"
+	tx.emitTCPConnect(&model.ArchivalTCPConnectResult{
+		Status: model.ArchivalTCPConnectStatus{Failure: &failure},
+	})
+	if count != 8 {
+		t.Fatal("unexpected number of debug messages", count)
+	}
+}
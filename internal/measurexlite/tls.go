@@ -43,18 +43,14 @@ func (thx *tlsHandshakerTrace) Handshake(
 // OnTLSHandshakeStart implements model.Trace.OnTLSHandshakeStart.
 func (tx *Trace) OnTLSHandshakeStart(now time.Time, remoteAddr string, config *tls.Config) {
 	t := now.Sub(tx.ZeroTime)
-	select {
-	case tx.networkEvent <- NewAnnotationArchivalNetworkEvent(tx.Index, t, "tls_handshake_start"):
-	default: // buffer is full
-	}
+	tx.emitNetworkEvent(NewAnnotationArchivalNetworkEvent(tx.Index, t, "tls_handshake_start"))
 }
 
 // OnTLSHandshakeDone implements model.Trace.OnTLSHandshakeDone.
 func (tx *Trace) OnTLSHandshakeDone(started time.Time, remoteAddr string, config *tls.Config,
 	state tls.ConnectionState, err error, finished time.Time) {
 	t := finished.Sub(tx.ZeroTime)
-	select {
-	case tx.tlsHandshake <- NewArchivalTLSOrQUICHandshakeResult(
+	tx.emitTLSHandshake(NewArchivalTLSOrQUICHandshakeResult(
 		tx.Index,
 		started.Sub(tx.ZeroTime),
 		"tcp",
@@ -63,13 +59,8 @@ func (tx *Trace) OnTLSHandshakeDone(started time.Time, remoteAddr string, config
 		state,
 		err,
 		t,
-	):
-	default: // buffer is full
-	}
-	select {
-	case tx.networkEvent <- NewAnnotationArchivalNetworkEvent(tx.Index, t, "tls_handshake_done"):
-	default: // buffer is full
-	}
+	))
+	tx.emitNetworkEvent(NewAnnotationArchivalNetworkEvent(tx.Index, t, "tls_handshake_done"))
 }
 
 // NewArchivalTLSOrQUICHandshakeResult generates a model.ArchivalTLSOrQUICHandshakeResult
@@ -29,6 +29,16 @@ import (
 // channels. Otherwise, you could read the channels directly. (In which
 // case, remember to issue nonblocking channel reads because channels are
 // never closed and they're just written when new events occur.)
+//
+// When a buffered channel is full, we drop the event and we increment
+// the corresponding counter returned by the Overflow method.
+//
+// # Subscriber
+//
+// You can set the Subscriber field to receive all the events as soon
+// as they occur, regardless of whether the buffered channels are full.
+// NewTraceWithContext sets the Subscriber and the Logger bound to the
+// context using ContextWithTraceConfig, if any.
 type Trace struct {
 	// Index is the MANDATORY unique index of this trace within the
 	// current measurement. If you don't care about uniquely identifying
@@ -50,6 +60,18 @@ type Trace struct {
 	// calls to the netxlite.NewParallelDNSOverHTTPSUDPResolver factory.
 	NewParallelDNSOverHTTPSResolverFn func(logger model.Logger, URL string) model.Resolver
 
+	// NewParallelTCPResolverFn is OPTIONAL and can be used to overide
+	// calls to the netxlite.NewParallelTCPResolver factory.
+	NewParallelTCPResolverFn func(logger model.Logger, dialer model.Dialer, address string) model.Resolver
+
+	// NewParallelDNSOverTLSResolverFn is OPTIONAL and can be used to overide
+	// calls to the netxlite.NewParallelDNSOverTLSResolver factory.
+	NewParallelDNSOverTLSResolverFn func(logger model.Logger, dialer model.TLSDialer, address string) model.Resolver
+
+	// NewParallelDNSOverHTTP3ResolverFn is OPTIONAL and can be used to overide
+	// calls to the netxlite.NewParallelDNSOverHTTP3Resolver factory.
+	NewParallelDNSOverHTTP3ResolverFn func(logger model.Logger, URL string) model.Resolver
+
 	// NewDialerWithoutResolverFn is OPTIONAL and can be used to override
 	// calls to the netxlite.NewDialerWithoutResolver factory.
 	NewDialerWithoutResolverFn func(dl model.DebugLogger) model.Dialer
@@ -62,6 +84,11 @@ type Trace struct {
 	// calls to the netxlite.NewTLSHandshakerUTLS factory.
 	NewTLSHandshakerUTLSFn func(dl model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker
 
+	// NewTLSHandshakerUTLSWithExtensionsFn is OPTIONAL and can be used to overide
+	// calls to the netxlite.NewTLSHandshakerUTLSWithExtensions factory.
+	NewTLSHandshakerUTLSWithExtensionsFn func(
+		dl model.DebugLogger, id *utls.ClientHelloID, extensions []utls.TLSExtension) model.TLSHandshaker
+
 	// NewDialerWithoutResolverFn is OPTIONAL and can be used to override
 	// calls to the netxlite.NewQUICDialerWithoutResolver factory.
 	NewQUICDialerWithoutResolverFn func(listener model.QUICListener, dl model.DebugLogger) model.QUICDialer
@@ -81,6 +108,21 @@ type Trace struct {
 	// quicHandshake is MANDATORY and buffers QUIC handshake observations.
 	quicHandshake chan *model.ArchivalTLSOrQUICHandshakeResult
 
+	// httpTransaction is MANDATORY and buffers HTTP transaction observations.
+	httpTransaction chan *model.ArchivalHTTPRequestResult
+
+	// Logger is the OPTIONAL logger we use to warn that we're dropping
+	// events because a buffered channel is full.
+	Logger model.Logger
+
+	// overflow counts the events dropped because a buffer was full.
+	overflow traceOverflowCounters
+
+	// Subscriber is the OPTIONAL Subscriber receiving all the events as
+	// soon as they occur. If you set this field, you MUST do that before
+	// starting to use this Trace.
+	Subscriber Subscriber
+
 	// TimeNowFn is OPTIONAL and can be used to override calls to time.Now
 	// to produce deterministic timing when testing.
 	TimeNowFn func() time.Time
@@ -113,6 +155,10 @@ const (
 	// QUICHandshakeBufferSize is the buffer for constructing
 	// the Trace's quicHandshake buffered channel.
 	QUICHandshakeBufferSize = 8
+
+	// HTTPTransactionBufferSize is the buffer for constructing
+	// the Trace's httpTransaction buffered channel.
+	HTTPTransactionBufferSize = 8
 )
 
 // NewTrace creates a new instance of Trace using default settings.
@@ -155,8 +201,13 @@ func NewTrace(index int64, zeroTime time.Time) *Trace {
 			chan *model.ArchivalTLSOrQUICHandshakeResult,
 			QUICHandshakeBufferSize,
 		),
-		TimeNowFn: nil, // use default
-		ZeroTime:  zeroTime,
+		httpTransaction: make(
+			chan *model.ArchivalHTTPRequestResult,
+			HTTPTransactionBufferSize,
+		),
+		Subscriber: nil, // no subscriber
+		TimeNowFn:  nil, // use default
+		ZeroTime:   zeroTime,
 	}
 }
 
@@ -187,6 +238,34 @@ func (tx *Trace) newParallelDNSOverHTTPSResolver(logger model.Logger, URL string
 	return netxlite.NewParallelDNSOverHTTPSResolver(logger, URL)
 }
 
+// newParallelTCPResolver indirectly calls the passed netxlite.NewParallelTCPResolver
+// thus allowing us to mock this function for testing
+func (tx *Trace) newParallelTCPResolver(logger model.Logger, dialer model.Dialer, address string) model.Resolver {
+	if tx.NewParallelTCPResolverFn != nil {
+		return tx.NewParallelTCPResolverFn(logger, dialer, address)
+	}
+	return netxlite.NewParallelTCPResolver(logger, dialer, address)
+}
+
+// newParallelDNSOverTLSResolver indirectly calls the passed netxlite.NewParallelDNSOverTLSResolver
+// thus allowing us to mock this function for testing
+func (tx *Trace) newParallelDNSOverTLSResolver(
+	logger model.Logger, dialer model.TLSDialer, address string) model.Resolver {
+	if tx.NewParallelDNSOverTLSResolverFn != nil {
+		return tx.NewParallelDNSOverTLSResolverFn(logger, dialer, address)
+	}
+	return netxlite.NewParallelDNSOverTLSResolver(logger, dialer, address)
+}
+
+// newParallelDNSOverHTTP3Resolver indirectly calls the passed netxlite.NewParallelDNSOverHTTP3Resolver
+// thus allowing us to mock this function for testing
+func (tx *Trace) newParallelDNSOverHTTP3Resolver(logger model.Logger, URL string) model.Resolver {
+	if tx.NewParallelDNSOverHTTP3ResolverFn != nil {
+		return tx.NewParallelDNSOverHTTP3ResolverFn(logger, URL)
+	}
+	return netxlite.NewParallelDNSOverHTTP3Resolver(logger, URL)
+}
+
 // newDialerWithoutResolver indirectly calls netxlite.NewDialerWithoutResolver
 // thus allowing us to mock this func for testing.
 func (tx *Trace) newDialerWithoutResolver(dl model.DebugLogger) model.Dialer {
@@ -214,6 +293,16 @@ func (tx *Trace) newTLSHandshakerUTLS(dl model.DebugLogger, id *utls.ClientHello
 	return netxlite.NewTLSHandshakerUTLS(dl, id)
 }
 
+// newTLSHandshakerUTLSWithExtensions indirectly calls netxlite.NewTLSHandshakerUTLSWithExtensions
+// thus allowing us to mock this func for testing.
+func (tx *Trace) newTLSHandshakerUTLSWithExtensions(
+	dl model.DebugLogger, id *utls.ClientHelloID, extensions []utls.TLSExtension) model.TLSHandshaker {
+	if tx.NewTLSHandshakerUTLSWithExtensionsFn != nil {
+		return tx.NewTLSHandshakerUTLSWithExtensionsFn(dl, id, extensions)
+	}
+	return netxlite.NewTLSHandshakerUTLSWithExtensions(dl, id, extensions)
+}
+
 // newQUICDialerWithoutResolver indirectly calls netxlite.NewQUICDialerWithoutResolver
 // thus allowing us to mock this func for testing.
 func (tx *Trace) newQUICDialerWithoutResolver(listener model.QUICListener, dl model.DebugLogger) model.QUICDialer {
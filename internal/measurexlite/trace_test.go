@@ -61,6 +61,24 @@ func TestNewTrace(t *testing.T) {
 			}
 		})
 
+		t.Run("NewParallelTCPResolverFn is nil", func(t *testing.T) {
+			if trace.NewParallelTCPResolverFn != nil {
+				t.Fatal("expected nil NewParallelTCPResolverFn")
+			}
+		})
+
+		t.Run("NewParallelDNSOverTLSResolverFn is nil", func(t *testing.T) {
+			if trace.NewParallelDNSOverTLSResolverFn != nil {
+				t.Fatal("expected nil NewParallelDNSOverTLSResolverFn")
+			}
+		})
+
+		t.Run("NewParallelDNSOverHTTP3ResolverFn is nil", func(t *testing.T) {
+			if trace.NewParallelDNSOverHTTP3ResolverFn != nil {
+				t.Fatal("expected nil NewParallelDNSOverHTTP3ResolverFn")
+			}
+		})
+
 		t.Run("NewParallelDNSOverHTTPSResolverFn is nil", func(t *testing.T) {
 			if trace.NewParallelDNSOverHTTPSResolverFn != nil {
 				t.Fatal("expected nil NewParallelDNSOverHTTPSResolverFn")
@@ -85,6 +103,12 @@ func TestNewTrace(t *testing.T) {
 			}
 		})
 
+		t.Run("NewTLSHandshakerUTLSWithExtensionsFn is nil", func(t *testing.T) {
+			if trace.NewTLSHandshakerUTLSWithExtensionsFn != nil {
+				t.Fatal("expected nil NewTLSHandshakerUTLSWithExtensionsFn")
+			}
+		})
+
 		t.Run("NewQUICDialerWithoutResolverFn is nil", func(t *testing.T) {
 			if trace.NewQUICDialerWithoutResolverFn != nil {
 				t.Fatal("expected nil NewQUICDialerQithoutResolverFn")
@@ -186,6 +210,25 @@ func TestNewTrace(t *testing.T) {
 			}
 		})
 
+		t.Run("httpTransaction has the expected buffer size", func(t *testing.T) {
+			ff := &testingx.FakeFiller{}
+			var idx int
+		Loop:
+			for {
+				ev := &model.ArchivalHTTPRequestResult{}
+				ff.Fill(ev)
+				select {
+				case trace.httpTransaction <- ev:
+					idx++
+				default:
+					break Loop
+				}
+			}
+			if idx != HTTPTransactionBufferSize {
+				t.Fatal("invalid httpTransaction channel buffer size")
+			}
+		})
+
 		t.Run("TimeNowFn is nil", func(t *testing.T) {
 			if trace.TimeNowFn != nil {
 				t.Fatal("expected nil TimeNowFn")
@@ -323,6 +366,131 @@ func TestTrace(t *testing.T) {
 		})
 	})
 
+	t.Run("NewParallelTCPResolverFn works as intended", func(t *testing.T) {
+		t.Run("when not nil", func(t *testing.T) {
+			mockedErr := errors.New("mocked")
+			tx := &Trace{
+				NewParallelTCPResolverFn: func(logger model.Logger, dialer model.Dialer, address string) model.Resolver {
+					return &mocks.Resolver{
+						MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+							return []string{}, mockedErr
+						},
+					}
+				},
+			}
+			dialer := &mocks.Dialer{}
+			resolver := tx.newParallelTCPResolver(model.DiscardLogger, dialer, "1.1.1.1:53")
+			addrs, err := resolver.LookupHost(context.Background(), "example.com")
+			if !errors.Is(err, mockedErr) {
+				t.Fatal("unexpected err", err)
+			}
+			if len(addrs) != 0 {
+				t.Fatal("expected array of size 0")
+			}
+		})
+
+		t.Run("when nil", func(t *testing.T) {
+			tx := &Trace{
+				NewParallelTCPResolverFn: nil,
+			}
+			dialer := netxlite.NewDialerWithoutResolver(model.DiscardLogger)
+			resolver := tx.newParallelTCPResolver(model.DiscardLogger, dialer, "1.1.1.1:53")
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			addrs, err := resolver.LookupHost(ctx, "example.com")
+			if err == nil || err.Error() != netxlite.FailureInterrupted {
+				t.Fatal("unexpected err", err)
+			}
+			if len(addrs) != 0 {
+				t.Fatal("expected array of size 0")
+			}
+		})
+	})
+
+	t.Run("NewParallelDNSOverTLSResolverFn works as intended", func(t *testing.T) {
+		t.Run("when not nil", func(t *testing.T) {
+			mockedErr := errors.New("mocked")
+			tx := &Trace{
+				NewParallelDNSOverTLSResolverFn: func(
+					logger model.Logger, dialer model.TLSDialer, address string) model.Resolver {
+					return &mocks.Resolver{
+						MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+							return []string{}, mockedErr
+						},
+					}
+				},
+			}
+			dialer := &mocks.TLSDialer{}
+			resolver := tx.newParallelDNSOverTLSResolver(model.DiscardLogger, dialer, "1.1.1.1:853")
+			addrs, err := resolver.LookupHost(context.Background(), "example.com")
+			if !errors.Is(err, mockedErr) {
+				t.Fatal("unexpected err", err)
+			}
+			if len(addrs) != 0 {
+				t.Fatal("expected array of size 0")
+			}
+		})
+
+		t.Run("when nil", func(t *testing.T) {
+			tx := &Trace{
+				NewParallelDNSOverTLSResolverFn: nil,
+			}
+			dialer := netxlite.NewTLSDialer(
+				netxlite.NewDialerWithoutResolver(model.DiscardLogger),
+				netxlite.NewTLSHandshakerStdlib(model.DiscardLogger),
+			)
+			resolver := tx.newParallelDNSOverTLSResolver(model.DiscardLogger, dialer, "1.1.1.1:853")
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			addrs, err := resolver.LookupHost(ctx, "example.com")
+			if err == nil || err.Error() != netxlite.FailureInterrupted {
+				t.Fatal("unexpected err", err)
+			}
+			if len(addrs) != 0 {
+				t.Fatal("expected array of size 0")
+			}
+		})
+	})
+
+	t.Run("NewParallelDNSOverHTTP3ResolverFn works as intended", func(t *testing.T) {
+		t.Run("when not nil", func(t *testing.T) {
+			mockedErr := errors.New("mocked")
+			tx := &Trace{
+				NewParallelDNSOverHTTP3ResolverFn: func(logger model.Logger, URL string) model.Resolver {
+					return &mocks.Resolver{
+						MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+							return []string{}, mockedErr
+						},
+					}
+				},
+			}
+			resolver := tx.newParallelDNSOverHTTP3Resolver(model.DiscardLogger, "https://dns.google.com")
+			addrs, err := resolver.LookupHost(context.Background(), "example.com")
+			if !errors.Is(err, mockedErr) {
+				t.Fatal("unexpected err", err)
+			}
+			if len(addrs) != 0 {
+				t.Fatal("expected array of size 0")
+			}
+		})
+
+		t.Run("when nil", func(t *testing.T) {
+			tx := &Trace{
+				NewParallelDNSOverHTTP3ResolverFn: nil,
+			}
+			resolver := tx.newParallelDNSOverHTTP3Resolver(model.DiscardLogger, "https://dns.google.com")
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			addrs, err := resolver.LookupHost(ctx, "example.com")
+			if err == nil || err.Error() != netxlite.FailureInterrupted {
+				t.Fatal("unexpected err", err)
+			}
+			if len(addrs) != 0 {
+				t.Fatal("expected array of size 0")
+			}
+		})
+	})
+
 	t.Run("NewDialerWithoutResolverFn works as intended", func(t *testing.T) {
 		t.Run("when not nil", func(t *testing.T) {
 			mockedErr := errors.New("mocked")
@@ -13,3 +13,13 @@ func (tx *Trace) NewTLSHandshakerUTLS(dl model.DebugLogger, id *utls.ClientHello
 		tx:  tx,
 	}
 }
+
+// NewTLSHandshakerUTLSWithExtensions is equivalent to netxlite.NewTLSHandshakerUTLSWithExtensions
+// except that it returns a model.TLSHandshaker that uses this trace.
+func (tx *Trace) NewTLSHandshakerUTLSWithExtensions(
+	dl model.DebugLogger, id *utls.ClientHelloID, extensions []utls.TLSExtension) model.TLSHandshaker {
+	return &tlsHandshakerTrace{
+		thx: tx.newTLSHandshakerUTLSWithExtensions(dl, id, extensions),
+		tx:  tx,
+	}
+}
@@ -27,3 +27,37 @@ func TestNewTLSHandshakerUTLS(t *testing.T) {
 		}
 	})
 }
+
+func TestNewTLSHandshakerUTLSWithExtensions(t *testing.T) {
+	t.Run("NewTLSHandshakerUTLSWithExtensions creates a wrapped TLSHandshaker", func(t *testing.T) {
+		underlying := &mocks.TLSHandshaker{}
+		zeroTime := time.Now()
+		trace := NewTrace(0, zeroTime)
+		extensions := []utls.TLSExtension{&utls.GenericExtension{Id: 0xfe0d}}
+		var gotExtensions []utls.TLSExtension
+		trace.NewTLSHandshakerUTLSWithExtensionsFn = func(
+			dl model.DebugLogger, id *utls.ClientHelloID, extensions []utls.TLSExtension) model.TLSHandshaker {
+			gotExtensions = extensions
+			return underlying
+		}
+		thx := trace.NewTLSHandshakerUTLSWithExtensions(model.DiscardLogger, &utls.HelloFirefox_Auto, extensions)
+		thxt := thx.(*tlsHandshakerTrace)
+		if thxt.thx != underlying {
+			t.Fatal("invalid TLS handshaker")
+		}
+		if thxt.tx != trace {
+			t.Fatal("invalid trace")
+		}
+		if len(gotExtensions) != 1 || gotExtensions[0] != extensions[0] {
+			t.Fatal("invalid extensions")
+		}
+	})
+
+	t.Run("without override we use netxlite", func(t *testing.T) {
+		trace := NewTrace(0, time.Now())
+		thx := trace.NewTLSHandshakerUTLSWithExtensions(model.DiscardLogger, &utls.HelloFirefox_Auto, nil)
+		if thx.(*tlsHandshakerTrace).thx == nil {
+			t.Fatal("expected non-nil handshaker")
+		}
+	})
+}
@@ -129,6 +129,10 @@ type ArchivalDNSLookupResult struct {
 }
 
 // ArchivalDNSAnswer is a DNS answer.
+//
+// The Hostname field contains the CNAME, MX exchange, NS, PTR, SOA primary
+// name server or SRV target. The fields following TTL are only meaningful
+// for the specific answer types mentioned in their documentation.
 type ArchivalDNSAnswer struct {
 	ASN        int64   `json:"asn,omitempty"`
 	ASOrgName  string  `json:"as_org_name,omitempty"`
@@ -137,6 +141,26 @@ type ArchivalDNSAnswer struct {
 	IPv4       string  `json:"ipv4,omitempty"`
 	IPv6       string  `json:"ipv6,omitempty"`
 	TTL        *uint32 `json:"ttl"`
+
+	// Text is the content of a TXT answer.
+	Text string `json:"text,omitempty"`
+
+	// Preference is the preference of an MX answer.
+	Preference uint16 `json:"preference,omitempty"`
+
+	// Port, Priority, and Weight are the fields of an SRV answer.
+	Port     uint16 `json:"port,omitempty"`
+	Priority uint16 `json:"priority,omitempty"`
+	Weight   uint16 `json:"weight,omitempty"`
+
+	// ResponsibleName, SerialNumber, RefreshInterval, RetryInterval,
+	// ExpirationLimit, and MinimumTTL are the fields of an SOA answer.
+	ResponsibleName string `json:"responsible_name,omitempty"`
+	SerialNumber    uint32 `json:"serial_number,omitempty"`
+	RefreshInterval uint32 `json:"refresh_interval,omitempty"`
+	RetryInterval   uint32 `json:"retry_interval,omitempty"`
+	ExpirationLimit uint32 `json:"expiration_limit,omitempty"`
+	MinimumTTL      uint32 `json:"minimum_ttl,omitempty"`
 }
 
 //
@@ -175,6 +175,15 @@ type ReadableDatabase interface {
 	//
 	// Returns the measurement JSON or an error
 	GetMeasurementJSON(msmtID int64) (map[string]interface{}, error)
+
+	// DataUsageSince returns the data usage of the results started since the given time
+	//
+	// Arguments:
+	//
+	// - since is the time since when we should account for data usage
+	//
+	// Returns the data sent and received in KiB or an error
+	DataUsageSince(since time.Time) (float64, float64, error)
 }
 
 // ResultNetwork is used to represent the structure made from the JOIN
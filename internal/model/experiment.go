@@ -288,6 +288,10 @@ type ExperimentOptionInfo struct {
 	// Doc contains the documentation.
 	Doc string
 
+	// Schema contains the option's JSON Schema or nil if
+	// we cannot represent the option's type as JSON.
+	Schema map[string]any
+
 	// Type contains the type.
 	Type string
 }
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -24,6 +25,7 @@ type Database struct {
 	MockListResults        func() ([]model.DatabaseResultNetwork, []model.DatabaseResultNetwork, error)
 	MockListMeasurements   func(resultID int64) ([]model.DatabaseMeasurementURLNetwork, error)
 	MockGetMeasurementJSON func(msmtID int64) (map[string]interface{}, error)
+	MockDataUsageSince     func(since time.Time) (float64, float64, error)
 }
 
 var _ model.WritableDatabase = &Database{}
@@ -105,3 +107,8 @@ func (d *Database) ListMeasurements(resultID int64) ([]model.DatabaseMeasurement
 func (d *Database) GetMeasurementJSON(msmtID int64) (map[string]interface{}, error) {
 	return d.MockGetMeasurementJSON(msmtID)
 }
+
+// DataUsageSince calls MockDataUsageSince
+func (d *Database) DataUsageSince(since time.Time) (float64, float64, error) {
+	return d.MockDataUsageSince(since)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -244,4 +245,20 @@ func TestDatabase(t *testing.T) {
 			t.Fatal("not the error we expected")
 		}
 	})
+
+	t.Run("DataUsageSince", func(t *testing.T) {
+		expected := errors.New("mocked")
+		db := &Database{
+			MockDataUsageSince: func(since time.Time) (float64, float64, error) {
+				return 0, 0, expected
+			},
+		}
+		up, down, err := db.DataUsageSince(time.Now())
+		if up != 0 || down != 0 {
+			t.Fatal("expected zero data usage")
+		}
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected")
+		}
+	})
 }
@@ -19,6 +19,11 @@ type DNSResponse struct {
 	MockDecodeLookupHost func() ([]string, error)
 	MockDecodeNS         func() ([]*net.NS, error)
 	MockDecodeCNAME      func() (string, error)
+	MockDecodeTXT        func() ([]string, error)
+	MockDecodeMX         func() ([]*net.MX, error)
+	MockDecodeSOA        func() (*model.DNSSOA, error)
+	MockDecodePTR        func() ([]string, error)
+	MockDecodeSRV        func() ([]*net.SRV, error)
 }
 
 var _ model.DNSResponse = &DNSResponse{}
@@ -50,3 +55,23 @@ func (r *DNSResponse) DecodeNS() ([]*net.NS, error) {
 func (r *DNSResponse) DecodeCNAME() (string, error) {
 	return r.MockDecodeCNAME()
 }
+
+func (r *DNSResponse) DecodeTXT() ([]string, error) {
+	return r.MockDecodeTXT()
+}
+
+func (r *DNSResponse) DecodeMX() ([]*net.MX, error) {
+	return r.MockDecodeMX()
+}
+
+func (r *DNSResponse) DecodeSOA() (*model.DNSSOA, error) {
+	return r.MockDecodeSOA()
+}
+
+func (r *DNSResponse) DecodePTR() ([]string, error) {
+	return r.MockDecodePTR()
+}
+
+func (r *DNSResponse) DecodeSRV() ([]*net.SRV, error) {
+	return r.MockDecodeSRV()
+}
@@ -15,6 +15,12 @@ type Resolver struct {
 	MockCloseIdleConnections func()
 	MockLookupHTTPS          func(ctx context.Context, domain string) (*model.HTTPSSvc, error)
 	MockLookupNS             func(ctx context.Context, domain string) ([]*net.NS, error)
+	MockLookupTXT            func(ctx context.Context, domain string) ([]string, error)
+	MockLookupMX             func(ctx context.Context, domain string) ([]*net.MX, error)
+	MockLookupCNAME          func(ctx context.Context, domain string) (string, error)
+	MockLookupSOA            func(ctx context.Context, domain string) (*model.DNSSOA, error)
+	MockLookupPTR            func(ctx context.Context, address string) ([]string, error)
+	MockLookupSRV            func(ctx context.Context, domain string) ([]*net.SRV, error)
 }
 
 // LookupHost calls MockLookupHost.
@@ -46,3 +52,33 @@ func (r *Resolver) LookupHTTPS(ctx context.Context, domain string) (*model.HTTPS
 func (r *Resolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, error) {
 	return r.MockLookupNS(ctx, domain)
 }
+
+// LookupTXT calls MockLookupTXT.
+func (r *Resolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return r.MockLookupTXT(ctx, domain)
+}
+
+// LookupMX calls MockLookupMX.
+func (r *Resolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return r.MockLookupMX(ctx, domain)
+}
+
+// LookupCNAME calls MockLookupCNAME.
+func (r *Resolver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return r.MockLookupCNAME(ctx, domain)
+}
+
+// LookupSOA calls MockLookupSOA.
+func (r *Resolver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return r.MockLookupSOA(ctx, domain)
+}
+
+// LookupPTR calls MockLookupPTR.
+func (r *Resolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return r.MockLookupPTR(ctx, address)
+}
+
+// LookupSRV calls MockLookupSRV.
+func (r *Resolver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return r.MockLookupSRV(ctx, domain)
+}
@@ -95,4 +95,106 @@ func TestResolver(t *testing.T) {
 			t.Fatal("expected nil addr")
 		}
 	})
+
+	t.Run("LookupTXT", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &Resolver{
+			MockLookupTXT: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, expected
+			},
+		}
+		ctx := context.Background()
+		out, err := r.LookupTXT(ctx, "dns.google")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != nil {
+			t.Fatal("unexpected out")
+		}
+	})
+
+	t.Run("LookupMX", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &Resolver{
+			MockLookupMX: func(ctx context.Context, domain string) ([]*net.MX, error) {
+				return nil, expected
+			},
+		}
+		ctx := context.Background()
+		out, err := r.LookupMX(ctx, "dns.google")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != nil {
+			t.Fatal("unexpected out")
+		}
+	})
+
+	t.Run("LookupCNAME", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &Resolver{
+			MockLookupCNAME: func(ctx context.Context, domain string) (string, error) {
+				return "", expected
+			},
+		}
+		ctx := context.Background()
+		out, err := r.LookupCNAME(ctx, "dns.google")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != "" {
+			t.Fatal("unexpected out")
+		}
+	})
+
+	t.Run("LookupSOA", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &Resolver{
+			MockLookupSOA: func(ctx context.Context, domain string) (*model.DNSSOA, error) {
+				return nil, expected
+			},
+		}
+		ctx := context.Background()
+		out, err := r.LookupSOA(ctx, "dns.google")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != nil {
+			t.Fatal("unexpected out")
+		}
+	})
+
+	t.Run("LookupPTR", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &Resolver{
+			MockLookupPTR: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, expected
+			},
+		}
+		ctx := context.Background()
+		out, err := r.LookupPTR(ctx, "8.8.8.8")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != nil {
+			t.Fatal("unexpected out")
+		}
+	})
+
+	t.Run("LookupSRV", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		r := &Resolver{
+			MockLookupSRV: func(ctx context.Context, domain string) ([]*net.SRV, error) {
+				return nil, expected
+			},
+		}
+		ctx := context.Background()
+		out, err := r.LookupSRV(ctx, "_xmpp-server._tcp.jabber.org")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != nil {
+			t.Fatal("unexpected out")
+		}
+	})
 }
@@ -24,6 +24,9 @@ type Trace struct {
 	MockOnDNSRoundTripForLookupHost func(started time.Time, reso model.Resolver, query model.DNSQuery,
 		response model.DNSResponse, addrs []string, err error, finished time.Time)
 
+	MockOnDNSRoundTripForLookupRecords func(started time.Time, reso model.Resolver, query model.DNSQuery,
+		response model.DNSResponse, err error, finished time.Time)
+
 	MockOnDelayedDNSResponse func(started time.Time, txp model.DNSTransport, query model.DNSQuery,
 		response model.DNSResponse, addrs []string, err error, finished time.Time) error
 
@@ -60,6 +63,11 @@ func (t *Trace) OnDNSRoundTripForLookupHost(started time.Time, reso model.Resolv
 	t.MockOnDNSRoundTripForLookupHost(started, reso, query, response, addrs, err, finished)
 }
 
+func (t *Trace) OnDNSRoundTripForLookupRecords(started time.Time, reso model.Resolver, query model.DNSQuery,
+	response model.DNSResponse, err error, finished time.Time) {
+	t.MockOnDNSRoundTripForLookupRecords(started, reso, query, response, err, finished)
+}
+
 func (t *Trace) OnDelayedDNSResponse(started time.Time, txp model.DNSTransport, query model.DNSQuery,
 	response model.DNSResponse, addrs []string, err error, finished time.Time) error {
 	return t.MockOnDelayedDNSResponse(started, txp, query, response, addrs, err, finished)
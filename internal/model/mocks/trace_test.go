@@ -71,6 +71,27 @@ func TestTrace(t *testing.T) {
 		}
 	})
 
+	t.Run("OnDNSRoundTripForLookupRecords", func(t *testing.T) {
+		var called bool
+		tx := &Trace{
+			MockOnDNSRoundTripForLookupRecords: func(started time.Time, reso model.Resolver, query model.DNSQuery,
+				response model.DNSResponse, err error, finished time.Time) {
+				called = true
+			},
+		}
+		tx.OnDNSRoundTripForLookupRecords(
+			time.Now(),
+			&Resolver{},
+			&DNSQuery{},
+			&DNSResponse{},
+			nil,
+			time.Now(),
+		)
+		if !called {
+			t.Fatal("not called")
+		}
+	})
+
 	t.Run("OnDelayedDNSResponse", func(t *testing.T) {
 		var called bool
 		tx := &Trace{
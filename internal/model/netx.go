@@ -40,6 +40,22 @@ type DNSResponse interface {
 
 	// DecodeCNAME returns the first CNAME entry in this response.
 	DecodeCNAME() (string, error)
+
+	// DecodeTXT returns all the TXT entries in this response. When a TXT
+	// entry contains several strings, we concatenate them.
+	DecodeTXT() ([]string, error)
+
+	// DecodeMX returns all the MX entries in this response.
+	DecodeMX() ([]*net.MX, error)
+
+	// DecodeSOA returns the first SOA entry in this response.
+	DecodeSOA() (*DNSSOA, error)
+
+	// DecodePTR returns all the PTR entries in this response.
+	DecodePTR() ([]string, error)
+
+	// DecodeSRV returns all the SRV entries in this response.
+	DecodeSRV() ([]*net.SRV, error)
 }
 
 // The DNSDecoder decodes DNS responses.
@@ -181,6 +197,31 @@ type HTTPSSvc struct {
 	IPv6 []string
 }
 
+// DNSSOA is the reply to a SOA DNS query.
+type DNSSOA struct {
+	// NS is the primary name server for the zone.
+	NS string
+
+	// Mbox is the mailbox of the person responsible for the zone.
+	Mbox string
+
+	// Serial is the zone serial number.
+	Serial uint32
+
+	// Refresh is the refresh interval in seconds.
+	Refresh uint32
+
+	// Retry is the retry interval in seconds.
+	Retry uint32
+
+	// Expire is the expiration limit in seconds.
+	Expire uint32
+
+	// MinTTL is the minimum TTL in seconds, which RFC2308 also
+	// uses as the TTL for negative responses.
+	MinTTL uint32
+}
+
 // QUICListener listens for QUIC connections.
 type QUICListener interface {
 	// Listen creates a new listening UDPLikeConn.
@@ -236,7 +277,9 @@ type Resolver interface {
 	//
 	// - doh: is a custom DNS-over-HTTPS resolver;
 	//
-	// - doh3: is a custom DNS-over-HTTP3 resolver.
+	// - doh3: is a custom DNS-over-HTTP3 resolver;
+	//
+	// - doq: is a custom DNS-over-QUIC resolver.
 	//
 	// See https://github.com/ooni/probe/issues/2029#issuecomment-1140805266
 	// for an explanation of why it would not be proper to call "netgo" the
@@ -255,6 +298,26 @@ type Resolver interface {
 
 	// LookupNS issues a NS query for a domain.
 	LookupNS(ctx context.Context, domain string) ([]*net.NS, error)
+
+	// LookupTXT issues a TXT query for a domain.
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+
+	// LookupMX issues a MX query for a domain.
+	LookupMX(ctx context.Context, domain string) ([]*net.MX, error)
+
+	// LookupCNAME issues a CNAME query for a domain.
+	LookupCNAME(ctx context.Context, domain string) (string, error)
+
+	// LookupSOA issues a SOA query for a domain.
+	LookupSOA(ctx context.Context, domain string) (*DNSSOA, error)
+
+	// LookupPTR issues a PTR query for the reverse name of the
+	// given IP address (e.g., 8.8.8.8 => 8.8.8.8.in-addr.arpa).
+	LookupPTR(ctx context.Context, address string) ([]string, error)
+
+	// LookupSRV issues a SRV query for a domain. The domain should
+	// already include the service and proto labels (e.g., _xmpp-server._tcp.jabber.org).
+	LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error)
 }
 
 // TLSDialer is a Dialer dialing TLS connections.
@@ -337,6 +400,25 @@ type Trace interface {
 	OnDNSRoundTripForLookupHost(started time.Time, reso Resolver, query DNSQuery,
 		response DNSResponse, addrs []string, err error, finished time.Time)
 
+	// OnDNSRoundTripForLookupRecords is like OnDNSRoundTripForLookupHost but
+	// is called when we are looking up records other than A and AAAA (e.g., TXT).
+	//
+	// Arguments:
+	//
+	// - started is when we called transport.RoundTrip
+	//
+	// - reso is the parent resolver for the trace;
+	//
+	// - query is the non-nil DNS query we use for the RoundTrip
+	//
+	// - response is either nil or a valid DNS response, obtained after the RoundTrip;
+	//
+	// - err is either the RoundTrip error or the decoding error
+	//
+	// - finished is the time right after the RoundTrip
+	OnDNSRoundTripForLookupRecords(started time.Time, reso Resolver, query DNSQuery,
+		response DNSResponse, err error, finished time.Time)
+
 	// OnDelayedDNSResponse is used with a DNSOverUDPTransport and called
 	// when we get delayed, unexpected DNS responses.
 	//
@@ -32,6 +32,27 @@ type THTLSHandshakeResult struct {
 	ServerName string  `json:"server_name"`
 	Status     bool    `json:"status"`
 	Failure    *string `json:"failure"`
+
+	// The following fields are only set when the handshake succeeds
+	// and are omitted by older test helpers.
+
+	// LeafCertificateFingerprint is the hex-encoded SHA-256 of the
+	// DER encoding of the leaf certificate.
+	LeafCertificateFingerprint string `json:"leaf_certificate_fingerprint,omitempty"`
+
+	// CertificateChainFingerprints contains the hex-encoded SHA-256 of
+	// the DER encoding of each certificate sent by the server, starting
+	// with the leaf certificate.
+	CertificateChainFingerprints []string `json:"certificate_chain_fingerprints,omitempty"`
+
+	// TLSVersion is the negotiated TLS version (e.g., "TLSv1.3").
+	TLSVersion string `json:"tls_version,omitempty"`
+
+	// CipherSuite is the negotiated cipher suite.
+	CipherSuite string `json:"cipher_suite,omitempty"`
+
+	// NegotiatedProtocol is the protocol negotiated using ALPN.
+	NegotiatedProtocol string `json:"negotiated_protocol,omitempty"`
 }
 
 // THHTTPRequestResult is the result of the HTTP request
@@ -56,6 +77,18 @@ type THDNSResult struct {
 	Failure *string  `json:"failure"`
 	Addrs   []string `json:"addrs"`
 	ASNs    []int64  `json:"-"` // not visible from the JSON
+
+	// Upstreams contains the results of each upstream resolver when
+	// the TH is configured to query several upstream resolvers.
+	Upstreams []THDNSUpstreamResult `json:"upstreams,omitempty"`
+}
+
+// THDNSUpstreamResult is the result of the DNS lookup performed
+// by the control vantage point using a given upstream resolver.
+type THDNSUpstreamResult struct {
+	URL     string   `json:"url"`
+	Failure *string  `json:"failure"`
+	Addrs   []string `json:"addrs"`
 }
 
 // THIPInfo contains information about IP addresses resolved either
@@ -65,6 +65,36 @@ func (r *bogonResolver) LookupNS(ctx context.Context, hostname string) ([]*net.N
 	return nil, ErrNoDNSTransport
 }
 
+// LookupTXT implements Resolver.LookupTXT
+func (r *bogonResolver) LookupTXT(ctx context.Context, hostname string) ([]string, error) {
+	return nil, ErrNoDNSTransport
+}
+
+// LookupMX implements Resolver.LookupMX
+func (r *bogonResolver) LookupMX(ctx context.Context, hostname string) ([]*net.MX, error) {
+	return nil, ErrNoDNSTransport
+}
+
+// LookupCNAME implements Resolver.LookupCNAME
+func (r *bogonResolver) LookupCNAME(ctx context.Context, hostname string) (string, error) {
+	return "", ErrNoDNSTransport
+}
+
+// LookupSOA implements Resolver.LookupSOA
+func (r *bogonResolver) LookupSOA(ctx context.Context, hostname string) (*model.DNSSOA, error) {
+	return nil, ErrNoDNSTransport
+}
+
+// LookupPTR implements Resolver.LookupPTR
+func (r *bogonResolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return nil, ErrNoDNSTransport
+}
+
+// LookupSRV implements Resolver.LookupSRV
+func (r *bogonResolver) LookupSRV(ctx context.Context, hostname string) ([]*net.SRV, error) {
+	return nil, ErrNoDNSTransport
+}
+
 // Network implements Resolver.Network
 func (r *bogonResolver) Network() string {
 	return r.Resolver.Network()
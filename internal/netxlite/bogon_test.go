@@ -115,6 +115,20 @@ func TestBogonResolver(t *testing.T) {
 		}
 	})
 
+	for _, rl := range resolverRecordsLookups {
+		t.Run(rl.name, func(t *testing.T) {
+			ctx := context.Background()
+			reso := &bogonResolver{}
+			out, err := rl.lookup(ctx, reso, rl.input)
+			if !errors.Is(err, ErrNoDNSTransport) {
+				t.Fatal("unexpected err", err)
+			}
+			if !resolverRecordsResultIsEmpty(out) {
+				t.Fatal("expected empty result here")
+			}
+		})
+	}
+
 	t.Run("Network", func(t *testing.T) {
 		expected := "antani"
 		reso := &bogonResolver{
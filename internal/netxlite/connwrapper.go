@@ -0,0 +1,123 @@
+package netxlite
+
+//
+// Context-based conn wrapping
+//
+
+import (
+	"context"
+	"net"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// ConnWrapper allows code binding it to a context to account for and to
+// restrict the I/O performed by the conns that netxlite dialers create using
+// such a context. The engine uses it to enforce data usage budgets (see the
+// bytecounter package) regardless of how an experiment creates its dialers.
+//
+// We use this wrapper for all the conns created by dialers using a resolver
+// (which includes NewDialerWithoutResolver) and by QUIC dialers. The engine
+// also uses it to capture the traffic of a measurement (see pcapx).
+type ConnWrapper interface {
+	// CheckNewConn returns an error if we should not create a new conn.
+	CheckNewConn() error
+
+	// OnDialFailure is called when we fail to dial the given address.
+	OnDialFailure(network, address string, err error)
+
+	// WrapNetConn wraps a newly created net.Conn.
+	WrapNetConn(conn net.Conn) net.Conn
+
+	// WrapUDPLikeConn wraps a newly created model.UDPLikeConn.
+	WrapUDPLikeConn(conn model.UDPLikeConn) model.UDPLikeConn
+}
+
+// connWrapperKey is the private type used to set/retrieve the context's ConnWrapper.
+type connWrapperKey struct{}
+
+// ContextWithConnWrapper returns a new context that binds to the given ConnWrapper. If
+// the given wrapper is nil, this function will call panic. If the given context already
+// binds to a ConnWrapper, the returned context uses both wrappers and the given wrapper
+// wraps the conns returned by the wrapper bound to the given context.
+func ContextWithConnWrapper(ctx context.Context, wrapper ConnWrapper) context.Context {
+	runtimex.PanicIfTrue(wrapper == nil, "netxlite.ContextWithConnWrapper passed a nil wrapper")
+	if inner, _ := ctx.Value(connWrapperKey{}).(ConnWrapper); inner != nil {
+		wrapper = &connWrapperChain{inner: inner, outer: wrapper}
+	}
+	return context.WithValue(ctx, connWrapperKey{}, wrapper)
+}
+
+// ContextWithoutConnWrapper returns a new context that does not use the ConnWrappers
+// bound to the given context, if any. For example, the engine uses this function
+// to exclude the traffic with the OONI backend from the data usage budgets.
+func ContextWithoutConnWrapper(ctx context.Context) context.Context {
+	return context.WithValue(ctx, connWrapperKey{}, &connWrapperDefault{})
+}
+
+// contextConnWrapperOrDefault retrieves the ConnWrapper bound to the context or
+// returns a default implementation that does not wrap conns.
+func contextConnWrapperOrDefault(ctx context.Context) ConnWrapper {
+	if wrapper, _ := ctx.Value(connWrapperKey{}).(ConnWrapper); wrapper != nil {
+		return wrapper
+	}
+	return &connWrapperDefault{}
+}
+
+// connWrapperDefault is the default ConnWrapper where each method is a no-op.
+type connWrapperDefault struct{}
+
+var _ ConnWrapper = &connWrapperDefault{}
+
+// CheckNewConn implements ConnWrapper.
+func (*connWrapperDefault) CheckNewConn() error {
+	return nil
+}
+
+// OnDialFailure implements ConnWrapper.
+func (*connWrapperDefault) OnDialFailure(network, address string, err error) {
+	// nothing
+}
+
+// WrapNetConn implements ConnWrapper.
+func (*connWrapperDefault) WrapNetConn(conn net.Conn) net.Conn {
+	return conn
+}
+
+// WrapUDPLikeConn implements ConnWrapper.
+func (*connWrapperDefault) WrapUDPLikeConn(conn model.UDPLikeConn) model.UDPLikeConn {
+	return conn
+}
+
+// connWrapperChain is a ConnWrapper using two ConnWrappers.
+type connWrapperChain struct {
+	inner ConnWrapper
+	outer ConnWrapper
+}
+
+var _ ConnWrapper = &connWrapperChain{}
+
+// CheckNewConn implements ConnWrapper.
+func (w *connWrapperChain) CheckNewConn() error {
+	if err := w.inner.CheckNewConn(); err != nil {
+		return err
+	}
+	return w.outer.CheckNewConn()
+}
+
+// OnDialFailure implements ConnWrapper.
+func (w *connWrapperChain) OnDialFailure(network, address string, err error) {
+	w.inner.OnDialFailure(network, address, err)
+	w.outer.OnDialFailure(network, address, err)
+}
+
+// WrapNetConn implements ConnWrapper.
+func (w *connWrapperChain) WrapNetConn(conn net.Conn) net.Conn {
+	return w.outer.WrapNetConn(w.inner.WrapNetConn(conn))
+}
+
+// WrapUDPLikeConn implements ConnWrapper.
+func (w *connWrapperChain) WrapUDPLikeConn(conn model.UDPLikeConn) model.UDPLikeConn {
+	return w.outer.WrapUDPLikeConn(w.inner.WrapUDPLikeConn(conn))
+}
@@ -0,0 +1,232 @@
+package netxlite
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+// connWrapperForTesting is a ConnWrapper used for testing.
+type connWrapperForTesting struct {
+	err      error
+	failures []string
+}
+
+// connWrapperForTestingConn is the net.Conn returned by connWrapperForTesting.
+type connWrapperForTestingConn struct {
+	net.Conn
+}
+
+// connWrapperForTestingUDPLikeConn is the model.UDPLikeConn returned by connWrapperForTesting.
+type connWrapperForTestingUDPLikeConn struct {
+	model.UDPLikeConn
+}
+
+func (w *connWrapperForTesting) CheckNewConn() error {
+	return w.err
+}
+
+func (w *connWrapperForTesting) OnDialFailure(network, address string, err error) {
+	w.failures = append(w.failures, network+" "+address)
+}
+
+func (w *connWrapperForTesting) WrapNetConn(conn net.Conn) net.Conn {
+	return &connWrapperForTestingConn{conn}
+}
+
+func (w *connWrapperForTesting) WrapUDPLikeConn(conn model.UDPLikeConn) model.UDPLikeConn {
+	return &connWrapperForTestingUDPLikeConn{conn}
+}
+
+func TestContextConnWrapper(t *testing.T) {
+	t.Run("without a wrapper", func(t *testing.T) {
+		wrapper := contextConnWrapperOrDefault(context.Background())
+		if _, good := wrapper.(*connWrapperDefault); !good {
+			t.Fatal("expected the default wrapper")
+		}
+		conn := &mocks.Conn{}
+		if wrapper.CheckNewConn() != nil || wrapper.WrapNetConn(conn) != conn {
+			t.Fatal("the default wrapper should be a no-op")
+		}
+		pconn := &mocks.UDPLikeConn{}
+		if wrapper.WrapUDPLikeConn(pconn) != pconn {
+			t.Fatal("the default wrapper should be a no-op")
+		}
+		wrapper.OnDialFailure("tcp", "8.8.8.8:443", errors.New("mocked error")) // should not crash
+	})
+
+	t.Run("with a wrapper", func(t *testing.T) {
+		expected := &connWrapperForTesting{}
+		ctx := ContextWithConnWrapper(context.Background(), expected)
+		if contextConnWrapperOrDefault(ctx) != expected {
+			t.Fatal("unexpected wrapper")
+		}
+		ctx = ContextWithoutConnWrapper(ctx)
+		if _, good := contextConnWrapperOrDefault(ctx).(*connWrapperDefault); !good {
+			t.Fatal("expected the default wrapper")
+		}
+	})
+
+	t.Run("with two wrappers", func(t *testing.T) {
+		inner, outer := &connWrapperForTesting{}, &connWrapperForTesting{}
+		ctx := ContextWithConnWrapper(context.Background(), inner)
+		ctx = ContextWithConnWrapper(ctx, outer)
+		wrapper := contextConnWrapperOrDefault(ctx)
+		if wrapper.CheckNewConn() != nil {
+			t.Fatal("expected to be able to create new conns")
+		}
+		expected := errors.New("mocked error")
+		inner.err = expected
+		if err := wrapper.CheckNewConn(); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		inner.err, outer.err = nil, expected
+		if err := wrapper.CheckNewConn(); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		wrapper.OnDialFailure("tcp", "8.8.8.8:443", expected)
+		if len(inner.failures) != 1 || len(outer.failures) != 1 {
+			t.Fatal("both wrappers should have seen the failure")
+		}
+		conn := &mocks.Conn{}
+		wrapped := wrapper.WrapNetConn(conn).(*connWrapperForTestingConn)
+		if wrapped.Conn.(*connWrapperForTestingConn).Conn != conn {
+			t.Fatal("unexpected wrapping")
+		}
+		pconn := &mocks.UDPLikeConn{}
+		pwrapped := wrapper.WrapUDPLikeConn(pconn).(*connWrapperForTestingUDPLikeConn)
+		if pwrapped.UDPLikeConn.(*connWrapperForTestingUDPLikeConn).UDPLikeConn != pconn {
+			t.Fatal("unexpected wrapping")
+		}
+	})
+
+	t.Run("with a nil wrapper", func(t *testing.T) {
+		var err error
+		func() {
+			defer func() {
+				err = recover().(error)
+			}()
+			ContextWithConnWrapper(context.Background(), nil)
+		}()
+		if err == nil {
+			t.Fatal("expected a panic")
+		}
+	})
+}
+
+func TestDialerResolverWithTracingUsesConnWrapper(t *testing.T) {
+	newDialer := func(conn net.Conn) *dialerResolverWithTracing {
+		return &dialerResolverWithTracing{
+			Dialer: &mocks.Dialer{
+				MockDialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+					return conn, nil
+				},
+			},
+			Resolver: &NullResolver{},
+		}
+	}
+
+	t.Run("when dialing fails", func(t *testing.T) {
+		wrapper := &connWrapperForTesting{}
+		ctx := ContextWithConnWrapper(context.Background(), wrapper)
+		dialer := &dialerResolverWithTracing{
+			Dialer: &mocks.Dialer{
+				MockDialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+					return nil, errors.New("mocked error")
+				},
+			},
+			Resolver: &NullResolver{},
+		}
+		if _, err := dialer.DialContext(ctx, "tcp", "8.8.8.8:443"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(wrapper.failures) != 1 || wrapper.failures[0] != "tcp 8.8.8.8:443" {
+			t.Fatal("unexpected failures", wrapper.failures)
+		}
+	})
+
+	t.Run("when we cannot create new conns", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		ctx := ContextWithConnWrapper(context.Background(), &connWrapperForTesting{err: expected})
+		conn, err := newDialer(&mocks.Conn{}).DialContext(ctx, "tcp", "8.8.8.8:443")
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("when we can create new conns", func(t *testing.T) {
+		expected := &mocks.Conn{}
+		ctx := ContextWithConnWrapper(context.Background(), &connWrapperForTesting{})
+		conn, err := newDialer(expected).DialContext(ctx, "tcp", "8.8.8.8:443")
+		if err != nil {
+			t.Fatal(err)
+		}
+		wrapped, good := conn.(*connWrapperForTestingConn)
+		if !good {
+			t.Fatal("the conn has not been wrapped")
+		}
+		if wrapped.Conn.(*dialerErrWrapperConn).Conn != expected {
+			t.Fatal("unexpected underlying conn")
+		}
+	})
+}
+
+func TestQUICDialerQUICGoUsesConnWrapper(t *testing.T) {
+	t.Run("when we cannot create new conns", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		dialer := &quicDialerQUICGo{
+			QUICListener: &mocks.QUICListener{
+				MockListen: func(addr *net.UDPAddr) (model.UDPLikeConn, error) {
+					panic("should not be called")
+				},
+			},
+		}
+		ctx := ContextWithConnWrapper(context.Background(), &connWrapperForTesting{err: expected})
+		qconn, err := dialer.DialContext(ctx, "8.8.8.8:443", &tls.Config{}, &quic.Config{})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if qconn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("when we can create new conns", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		var gotPconn net.PacketConn
+		dialer := &quicDialerQUICGo{
+			QUICListener: &mocks.QUICListener{
+				MockListen: func(addr *net.UDPAddr) (model.UDPLikeConn, error) {
+					return &mocks.UDPLikeConn{
+						MockClose: func() error {
+							return nil
+						},
+					}, nil
+				},
+			},
+			mockDialEarlyContext: func(ctx context.Context, pconn net.PacketConn,
+				remoteAddr net.Addr, host string, tlsConfig *tls.Config,
+				quicConfig *quic.Config) (quic.EarlyConnection, error) {
+				gotPconn = pconn
+				return nil, expected
+			},
+		}
+		ctx := ContextWithConnWrapper(context.Background(), &connWrapperForTesting{})
+		_, err := dialer.DialContext(ctx, "8.8.8.8:443", &tls.Config{}, &quic.Config{})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if _, good := gotPconn.(*connWrapperForTestingUDPLikeConn); !good {
+			t.Fatal("the conn has not been wrapped")
+		}
+	})
+}
@@ -187,6 +187,8 @@ var _ model.Dialer = &dialerResolverWithTracing{}
 // 2. cycle through the available IP addresses and try to dial each of them;
 //
 // 3. trace the TCP (or UDP) connect and allow wrapping the returned conn.
+//
+// We also honour the ConnWrapper bound to the context, if any.
 func (d *dialerResolverWithTracing) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
 	// QUIRK: this routine and the related routines in quirks.go cannot
 	// be changed easily until we use events tracing to measure.
@@ -196,6 +198,10 @@ func (d *dialerResolverWithTracing) DialContext(ctx context.Context, network, ad
 	if err != nil {
 		return nil, err
 	}
+	wrapper := contextConnWrapperOrDefault(ctx)
+	if err := wrapper.CheckNewConn(); err != nil {
+		return nil, err
+	}
 	addrs, err := d.lookupHost(ctx, onlyhost)
 	if err != nil {
 		return nil, err
@@ -223,8 +229,9 @@ func (d *dialerResolverWithTracing) DialContext(ctx context.Context, network, ad
 		trace.OnConnectDone(started, network, onlyhost, target, err, finished)
 		if err == nil {
 			conn = &dialerErrWrapperConn{conn}
-			return trace.MaybeWrapNetConn(conn), nil
+			return trace.MaybeWrapNetConn(wrapper.WrapNetConn(conn)), nil
 		}
+		wrapper.OnDialFailure(network, target, err)
 		errorslist = append(errorslist, err)
 	}
 	return nil, quirkReduceErrors(errorslist)
@@ -7,6 +7,7 @@ package netxlite
 import (
 	"errors"
 	"net"
+	"strings"
 
 	"github.com/miekg/dns"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -185,5 +186,109 @@ func (r *dnsResponse) DecodeCNAME() (string, error) {
 	return "", dnsDecoderWrapError(ErrOODNSNoAnswer)
 }
 
+// DecodeTXT implements model.DNSResponse.DecodeTXT.
+func (r *dnsResponse) DecodeTXT() ([]string, error) {
+	if err := r.rcodeToError(); err != nil {
+		return nil, err // error already wrapped
+	}
+	out := []string{}
+	for _, answer := range r.msg.Answer {
+		switch avalue := answer.(type) {
+		case *dns.TXT:
+			out = append(out, strings.Join(avalue.Txt, ""))
+		}
+	}
+	if len(out) < 1 {
+		return nil, dnsDecoderWrapError(ErrOODNSNoAnswer)
+	}
+	return out, nil
+}
+
+// DecodeMX implements model.DNSResponse.DecodeMX.
+func (r *dnsResponse) DecodeMX() ([]*net.MX, error) {
+	if err := r.rcodeToError(); err != nil {
+		return nil, err // error already wrapped
+	}
+	out := []*net.MX{}
+	for _, answer := range r.msg.Answer {
+		switch avalue := answer.(type) {
+		case *dns.MX:
+			out = append(out, &net.MX{Host: avalue.Mx, Pref: avalue.Preference})
+		}
+	}
+	if len(out) < 1 {
+		return nil, dnsDecoderWrapError(ErrOODNSNoAnswer)
+	}
+	return out, nil
+}
+
+// DecodeSOA implements model.DNSResponse.DecodeSOA.
+func (r *dnsResponse) DecodeSOA() (*model.DNSSOA, error) {
+	if err := r.rcodeToError(); err != nil {
+		return nil, err // error already wrapped
+	}
+	for _, answer := range r.msg.Answer {
+		switch avalue := answer.(type) {
+		case *dns.SOA:
+			return newDNSSOA(avalue), nil
+		}
+	}
+	return nil, dnsDecoderWrapError(ErrOODNSNoAnswer)
+}
+
+// newDNSSOA converts a *dns.SOA to a *model.DNSSOA.
+func newDNSSOA(soa *dns.SOA) *model.DNSSOA {
+	return &model.DNSSOA{
+		NS:      soa.Ns,
+		Mbox:    soa.Mbox,
+		Serial:  soa.Serial,
+		Refresh: soa.Refresh,
+		Retry:   soa.Retry,
+		Expire:  soa.Expire,
+		MinTTL:  soa.Minttl,
+	}
+}
+
+// DecodePTR implements model.DNSResponse.DecodePTR.
+func (r *dnsResponse) DecodePTR() ([]string, error) {
+	if err := r.rcodeToError(); err != nil {
+		return nil, err // error already wrapped
+	}
+	out := []string{}
+	for _, answer := range r.msg.Answer {
+		switch avalue := answer.(type) {
+		case *dns.PTR:
+			out = append(out, avalue.Ptr)
+		}
+	}
+	if len(out) < 1 {
+		return nil, dnsDecoderWrapError(ErrOODNSNoAnswer)
+	}
+	return out, nil
+}
+
+// DecodeSRV implements model.DNSResponse.DecodeSRV.
+func (r *dnsResponse) DecodeSRV() ([]*net.SRV, error) {
+	if err := r.rcodeToError(); err != nil {
+		return nil, err // error already wrapped
+	}
+	out := []*net.SRV{}
+	for _, answer := range r.msg.Answer {
+		switch avalue := answer.(type) {
+		case *dns.SRV:
+			out = append(out, &net.SRV{
+				Target:   avalue.Target,
+				Port:     avalue.Port,
+				Priority: avalue.Priority,
+				Weight:   avalue.Weight,
+			})
+		}
+	}
+	if len(out) < 1 {
+		return nil, dnsDecoderWrapError(ErrOODNSNoAnswer)
+	}
+	return out, nil
+}
+
 var _ model.DNSDecoder = &DNSDecoderMiekg{}
 var _ model.DNSResponse = &dnsResponse{}
@@ -3,11 +3,13 @@ package netxlite
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/model/mocks"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
@@ -607,6 +609,217 @@ func TestDNSDecoderMiekg(t *testing.T) {
 				}
 			})
 		})
+
+		// decodeRecordsFuncs maps the query type to a function that invokes the
+		// corresponding Decode method and returns the number of records.
+		decodeRecordsFuncs := map[uint16]func(model.DNSResponse) (int, error){
+			dns.TypeTXT: func(resp model.DNSResponse) (int, error) {
+				out, err := resp.DecodeTXT()
+				return len(out), err
+			},
+			dns.TypeMX: func(resp model.DNSResponse) (int, error) {
+				out, err := resp.DecodeMX()
+				return len(out), err
+			},
+			dns.TypeSOA: func(resp model.DNSResponse) (int, error) {
+				out, err := resp.DecodeSOA()
+				if out == nil {
+					return 0, err
+				}
+				return 1, err
+			},
+			dns.TypePTR: func(resp model.DNSResponse) (int, error) {
+				out, err := resp.DecodePTR()
+				return len(out), err
+			},
+			dns.TypeSRV: func(resp model.DNSResponse) (int, error) {
+				out, err := resp.DecodeSRV()
+				return len(out), err
+			},
+		}
+
+		for qtype, decode := range decodeRecordsFuncs {
+			qtype, decode := qtype, decode
+			t.Run(fmt.Sprintf("dnsResponse.Decode%s", dns.TypeToString[qtype]), func(t *testing.T) {
+				t.Run("with failure", func(t *testing.T) {
+					// Ensure that we're not trying to decode if rcode != 0
+					d := &DNSDecoderMiekg{}
+					queryID := dns.Id()
+					rawQuery := dnsGenQuery(qtype, queryID)
+					rawResponse := dnsGenReplyWithError(rawQuery, dns.RcodeRefused)
+					query := &mocks.DNSQuery{
+						MockID: func() uint16 {
+							return queryID
+						},
+					}
+					resp, err := d.DecodeResponse(rawResponse, query)
+					if err != nil {
+						t.Fatal(err)
+					}
+					count, err := decode(resp)
+					if !errors.Is(err, ErrOODNSRefused) {
+						t.Fatal("unexpected err", err)
+					}
+					if !dnsDecoderErrorIsWrapped(err) {
+						t.Fatal("unwrapped error", err)
+					}
+					if count > 0 {
+						t.Fatal("expected empty result")
+					}
+				})
+
+				t.Run("with empty answer", func(t *testing.T) {
+					d := &DNSDecoderMiekg{}
+					queryID := dns.Id()
+					rawQuery := dnsGenQuery(qtype, queryID)
+					rawResponse := dnsGenRecordsReplySuccess(rawQuery)
+					query := &mocks.DNSQuery{
+						MockID: func() uint16 {
+							return queryID
+						},
+					}
+					resp, err := d.DecodeResponse(rawResponse, query)
+					if err != nil {
+						t.Fatal(err)
+					}
+					count, err := decode(resp)
+					if !errors.Is(err, ErrOODNSNoAnswer) {
+						t.Fatal("unexpected err", err)
+					}
+					if !dnsDecoderErrorIsWrapped(err) {
+						t.Fatal("unwrapped error", err)
+					}
+					if count > 0 {
+						t.Fatal("expected empty result")
+					}
+				})
+			})
+		}
+
+		// dnsDecodeFullAnswer is a helper for decoding a successful response
+		// containing the given answers and returning the model.DNSResponse.
+		dnsDecodeFullAnswer := func(t *testing.T, qtype uint16, answers ...dns.RR) model.DNSResponse {
+			d := &DNSDecoderMiekg{}
+			queryID := dns.Id()
+			rawQuery := dnsGenQuery(qtype, queryID)
+			rawResponse := dnsGenRecordsReplySuccess(rawQuery, answers...)
+			query := &mocks.DNSQuery{
+				MockID: func() uint16 {
+					return queryID
+				},
+			}
+			resp, err := d.DecodeResponse(rawResponse, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return resp
+		}
+
+		// dnsRRHeader returns the header of an answer with the given type.
+		dnsRRHeader := func(qtype uint16) dns.RR_Header {
+			return dns.RR_Header{
+				Name:   dns.Fqdn("x.org"),
+				Rrtype: qtype,
+				Class:  dns.ClassINET,
+				Ttl:    300,
+			}
+		}
+
+		t.Run("dnsResponse.DecodeTXT with full answer", func(t *testing.T) {
+			resp := dnsDecodeFullAnswer(t, dns.TypeTXT, &dns.TXT{
+				Hdr: dnsRRHeader(dns.TypeTXT),
+				Txt: []string{"v=spf1 ", "-all"},
+			}, &dns.TXT{
+				Hdr: dnsRRHeader(dns.TypeTXT),
+				Txt: []string{"google-site-verification=xyz"},
+			})
+			txt, err := resp.DecodeTXT()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := []string{"v=spf1 -all", "google-site-verification=xyz"}
+			if diff := cmp.Diff(expect, txt); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("dnsResponse.DecodeMX with full answer", func(t *testing.T) {
+			resp := dnsDecodeFullAnswer(t, dns.TypeMX, &dns.MX{
+				Hdr:        dnsRRHeader(dns.TypeMX),
+				Preference: 10,
+				Mx:         "mx.x.org.",
+			})
+			mx, err := resp.DecodeMX()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := []*net.MX{{Host: "mx.x.org.", Pref: 10}}
+			if diff := cmp.Diff(expect, mx); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("dnsResponse.DecodeSOA with full answer", func(t *testing.T) {
+			resp := dnsDecodeFullAnswer(t, dns.TypeSOA, &dns.SOA{
+				Hdr:     dnsRRHeader(dns.TypeSOA),
+				Ns:      "ns1.x.org.",
+				Mbox:    "hostmaster.x.org.",
+				Serial:  2023010101,
+				Refresh: 7200,
+				Retry:   3600,
+				Expire:  1209600,
+				Minttl:  300,
+			})
+			soa, err := resp.DecodeSOA()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := &model.DNSSOA{
+				NS:      "ns1.x.org.",
+				Mbox:    "hostmaster.x.org.",
+				Serial:  2023010101,
+				Refresh: 7200,
+				Retry:   3600,
+				Expire:  1209600,
+				MinTTL:  300,
+			}
+			if diff := cmp.Diff(expect, soa); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("dnsResponse.DecodePTR with full answer", func(t *testing.T) {
+			resp := dnsDecodeFullAnswer(t, dns.TypePTR, &dns.PTR{
+				Hdr: dnsRRHeader(dns.TypePTR),
+				Ptr: "dns.google.",
+			})
+			ptr, err := resp.DecodePTR()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := []string{"dns.google."}
+			if diff := cmp.Diff(expect, ptr); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+
+		t.Run("dnsResponse.DecodeSRV with full answer", func(t *testing.T) {
+			resp := dnsDecodeFullAnswer(t, dns.TypeSRV, &dns.SRV{
+				Hdr:      dnsRRHeader(dns.TypeSRV),
+				Priority: 10,
+				Weight:   5,
+				Port:     5060,
+				Target:   "sip.x.org.",
+			})
+			srv, err := resp.DecodeSRV()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expect := []*net.SRV{{Target: "sip.x.org.", Port: 5060, Priority: 10, Weight: 5}}
+			if diff := cmp.Diff(expect, srv); diff != "" {
+				t.Fatal(diff)
+			}
+		})
 	})
 }
 
@@ -782,3 +995,19 @@ func dnsGenNSReplySuccess(rawQuery []byte, names ...string) []byte {
 	runtimex.PanicOnError(err, "reply.Pack failed")
 	return data
 }
+
+// dnsGenRecordsReplySuccess generates a successful reply containing the given answers.
+func dnsGenRecordsReplySuccess(rawQuery []byte, answers ...dns.RR) []byte {
+	query := new(dns.Msg)
+	err := query.Unpack(rawQuery)
+	runtimex.PanicOnError(err, "query.Unpack failed")
+	runtimex.Assert(len(query.Question) == 1, "more than one question")
+	reply := new(dns.Msg)
+	reply.Compress = true
+	reply.MsgHdr.RecursionAvailable = true
+	reply.SetReply(query)
+	reply.Answer = append(reply.Answer, answers...)
+	data, err := reply.Pack()
+	runtimex.PanicOnError(err, "reply.Pack failed")
+	return data
+}
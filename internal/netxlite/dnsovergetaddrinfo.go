@@ -157,3 +157,23 @@ func (r *dnsOverGetaddrinfoResponse) DecodeCNAME() (string, error) {
 	}
 	return r.cname, nil
 }
+
+func (r *dnsOverGetaddrinfoResponse) DecodeTXT() ([]string, error) {
+	return nil, ErrNoDNSTransport
+}
+
+func (r *dnsOverGetaddrinfoResponse) DecodeMX() ([]*net.MX, error) {
+	return nil, ErrNoDNSTransport
+}
+
+func (r *dnsOverGetaddrinfoResponse) DecodeSOA() (*model.DNSSOA, error) {
+	return nil, ErrNoDNSTransport
+}
+
+func (r *dnsOverGetaddrinfoResponse) DecodePTR() ([]string, error) {
+	return nil, ErrNoDNSTransport
+}
+
+func (r *dnsOverGetaddrinfoResponse) DecodeSRV() ([]*net.SRV, error) {
+	return nil, ErrNoDNSTransport
+}
@@ -342,4 +342,79 @@ func TestDNSOverGetaddrinfoResponse(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("DecodeTXT works as intended", func(t *testing.T) {
+		resp := &dnsOverGetaddrinfoResponse{
+			addrs: []string{},
+			cname: "",
+			query: nil,
+		}
+		out, err := resp.DecodeTXT()
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("unexpected err")
+		}
+		if len(out) != 0 {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("DecodeMX works as intended", func(t *testing.T) {
+		resp := &dnsOverGetaddrinfoResponse{
+			addrs: []string{},
+			cname: "",
+			query: nil,
+		}
+		out, err := resp.DecodeMX()
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("unexpected err")
+		}
+		if len(out) != 0 {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("DecodeSOA works as intended", func(t *testing.T) {
+		resp := &dnsOverGetaddrinfoResponse{
+			addrs: []string{},
+			cname: "",
+			query: nil,
+		}
+		out, err := resp.DecodeSOA()
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("unexpected err")
+		}
+		if out != nil {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("DecodePTR works as intended", func(t *testing.T) {
+		resp := &dnsOverGetaddrinfoResponse{
+			addrs: []string{},
+			cname: "",
+			query: nil,
+		}
+		out, err := resp.DecodePTR()
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("unexpected err")
+		}
+		if len(out) != 0 {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("DecodeSRV works as intended", func(t *testing.T) {
+		resp := &dnsOverGetaddrinfoResponse{
+			addrs: []string{},
+			cname: "",
+			query: nil,
+		}
+		out, err := resp.DecodeSRV()
+		if !errors.Is(err, ErrNoDNSTransport) {
+			t.Fatal("unexpected err")
+		}
+		if len(out) != 0 {
+			t.Fatal("unexpected result")
+		}
+	})
 }
@@ -0,0 +1,215 @@
+package netxlite
+
+//
+// DNS-over-QUIC transport
+//
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// DNSOverQUICTransport is a DNS-over-QUIC DNSTransport (see RFC9250).
+//
+// To construct this type, either manually fill the fields marked as MANDATORY
+// or just use the NewUnwrappedDNSOverQUICTransport factory directly.
+//
+// RoundTrip sends each query on a new bidirectional stream, as required by
+// RFC9250, and reuses the same QUIC connection for subsequent queries. The
+// first query creates the connection, which we keep around until either it
+// fails or CloseIdleConnections is called.
+type DNSOverQUICTransport struct {
+	// Decoder is the MANDATORY DNSDecoder to use.
+	Decoder model.DNSDecoder
+
+	// Dialer is the MANDATORY QUIC dialer to use.
+	Dialer model.QUICDialer
+
+	// Endpoint is the MANDATORY server's endpoint (e.g., 94.140.14.14:853).
+	Endpoint string
+
+	// TLSConfig is the OPTIONAL TLS config. If nil, we use an empty config
+	// and the dialer will fill the SNI. In any case, we override the ALPN.
+	TLSConfig *tls.Config
+
+	// conn is the cached QUIC connection.
+	conn quic.EarlyConnection
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+}
+
+// NewUnwrappedDNSOverQUICTransport creates a DNSOverQUICTransport instance
+// that has not been wrapped yet.
+//
+// Arguments:
+//
+// - dialer is any type that implements the QUICDialer interface;
+//
+// - address is the endpoint address (e.g., 94.140.14.14:853).
+func NewUnwrappedDNSOverQUICTransport(dialer model.QUICDialer, address string) *DNSOverQUICTransport {
+	return NewUnwrappedDNSOverQUICTransportWithTLSConfig(dialer, address, nil)
+}
+
+// NewUnwrappedDNSOverQUICTransportWithTLSConfig is like NewUnwrappedDNSOverQUICTransport
+// but additionally allows to specify a TLS config (e.g., to override the SNI).
+func NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+	dialer model.QUICDialer, address string, config *tls.Config) *DNSOverQUICTransport {
+	return &DNSOverQUICTransport{
+		Decoder:   &DNSDecoderMiekg{},
+		Dialer:    dialer,
+		Endpoint:  address,
+		TLSConfig: config,
+		conn:      nil,
+		mu:        sync.Mutex{},
+	}
+}
+
+// RoundTrip sends a query and receives a reply.
+func (t *DNSOverQUICTransport) RoundTrip(
+	ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+	rawQuery, err := query.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(rawQuery) > math.MaxUint16 {
+		return nil, errQueryTooLarge
+	}
+	const opTimeout = 10 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, opTimeout)
+	defer cancel()
+	conn, reused, err := t.getOrDialConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawResponse, err := t.exchange(ctx, conn, rawQuery)
+	if err != nil && reused {
+		// The server may have silently closed the idle connection
+		// so let us retry once with a fresh connection.
+		t.forgetConn(conn)
+		conn, _, err = t.getOrDialConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		rawResponse, err = t.exchange(ctx, conn, rawQuery)
+	}
+	if err != nil {
+		t.forgetConn(conn)
+		return nil, err
+	}
+	// RFC9250 Sect. 4.2.1 requires the message ID to be zero, so we now restore
+	// the original query ID to make the response match the query.
+	if len(rawResponse) >= 2 {
+		rawResponse[0], rawResponse[1] = byte(query.ID()>>8), byte(query.ID())
+	}
+	return t.Decoder.DecodeResponse(rawResponse, query)
+}
+
+// getOrDialConn returns the cached connection, if any, or creates and caches
+// a new connection. The boolean return value indicates whether we are reusing
+// an already existing connection.
+func (t *DNSOverQUICTransport) getOrDialConn(ctx context.Context) (quic.EarlyConnection, bool, error) {
+	defer t.mu.Unlock()
+	t.mu.Lock()
+	if t.conn != nil {
+		if t.conn.Context().Err() == nil {
+			return t.conn, true, nil
+		}
+		t.conn.CloseWithError(0, "") // release resources
+		t.conn = nil
+	}
+	tlsConfig := &tls.Config{}
+	if t.TLSConfig != nil {
+		tlsConfig = t.TLSConfig.Clone()
+	}
+	// See https://www.rfc-editor.org/rfc/rfc9250.html#section-4.1.1
+	tlsConfig.NextProtos = []string{"doq"}
+	conn, err := t.Dialer.DialContext(ctx, t.Endpoint, tlsConfig, &quic.Config{})
+	if err != nil {
+		return nil, false, err
+	}
+	t.conn = conn
+	return conn, false, nil
+}
+
+// forgetConn closes the given conn and removes it from the cache.
+func (t *DNSOverQUICTransport) forgetConn(conn quic.EarlyConnection) {
+	defer t.mu.Unlock()
+	t.mu.Lock()
+	if t.conn == conn {
+		t.conn = nil
+	}
+	conn.CloseWithError(0, "")
+}
+
+// exchange sends the query on a new stream and reads the response.
+func (t *DNSOverQUICTransport) exchange(
+	ctx context.Context, conn quic.EarlyConnection, rawQuery []byte) ([]byte, error) {
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CancelRead(0) // ensure we release the stream
+	if deadline, okay := ctx.Deadline(); okay {
+		stream.SetDeadline(deadline)
+	}
+	// Write request using the same framing of DNS-over-TCP with zero ID
+	buf := []byte{byte(len(rawQuery) >> 8)}
+	buf = append(buf, byte(len(rawQuery)))
+	buf = append(buf, rawQuery...)
+	buf[2], buf[3] = 0, 0
+	if _, err := stream.Write(buf); err != nil {
+		return nil, err
+	}
+	// The client MUST send the STREAM FIN after the query (RFC9250 Sect. 4.2)
+	if err := stream.Close(); err != nil {
+		return nil, err
+	}
+	// Read response
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(stream, header); err != nil {
+		return nil, err
+	}
+	length := int(header[0])<<8 | int(header[1])
+	rawResponse := make([]byte, length)
+	if _, err := io.ReadFull(stream, rawResponse); err != nil {
+		return nil, err
+	}
+	return rawResponse, nil
+}
+
+// RequiresPadding returns true for DoQ according to RFC9250.
+func (t *DNSOverQUICTransport) RequiresPadding() bool {
+	return true
+}
+
+// Network returns the transport network, i.e., "doq".
+func (t *DNSOverQUICTransport) Network() string {
+	return "doq"
+}
+
+// Address returns the upstream server endpoint (e.g., "94.140.14.14:853").
+func (t *DNSOverQUICTransport) Address() string {
+	return t.Endpoint
+}
+
+// CloseIdleConnections closes idle connections, if any.
+func (t *DNSOverQUICTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	conn := t.conn
+	t.conn = nil
+	t.mu.Unlock()
+	if conn != nil {
+		conn.CloseWithError(0, "")
+	}
+	t.Dialer.CloseIdleConnections()
+}
+
+var _ model.DNSTransport = &DNSOverQUICTransport{}
@@ -0,0 +1,377 @@
+package netxlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lucas-clemente/quic-go"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+// dnsOverQUICStream is a fake quic.Stream for testing DNSOverQUICTransport.
+type dnsOverQUICStream struct {
+	quic.Stream
+	input    *bytes.Reader
+	output   bytes.Buffer
+	writeErr error
+	closeErr error
+	closed   bool
+}
+
+func (s *dnsOverQUICStream) Read(b []byte) (int, error) {
+	return s.input.Read(b)
+}
+
+func (s *dnsOverQUICStream) Write(b []byte) (int, error) {
+	if s.writeErr != nil {
+		return 0, s.writeErr
+	}
+	return s.output.Write(b)
+}
+
+func (s *dnsOverQUICStream) Close() error {
+	s.closed = true
+	return s.closeErr
+}
+
+func (s *dnsOverQUICStream) CancelRead(quic.StreamErrorCode) {}
+
+func (s *dnsOverQUICStream) SetDeadline(time.Time) error {
+	return nil
+}
+
+// newDNSOverQUICStream creates a fake stream returning the given DNS response.
+func newDNSOverQUICStream(t *testing.T, reply *dns.Msg) *dnsOverQUICStream {
+	data, err := reply.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	framed := append([]byte{byte(len(data) >> 8), byte(len(data))}, data...)
+	return &dnsOverQUICStream{input: bytes.NewReader(framed)}
+}
+
+// newDNSOverQUICConn creates a fake QUIC conn that returns the given stream.
+func newDNSOverQUICConn(stream quic.Stream, closed *int) *mocks.QUICEarlyConnection {
+	return &mocks.QUICEarlyConnection{
+		MockOpenStreamSync: func(ctx context.Context) (quic.Stream, error) {
+			return stream, nil
+		},
+		MockContext: func() context.Context {
+			return context.Background()
+		},
+		MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+			*closed++
+			return nil
+		},
+	}
+}
+
+func TestDNSOverQUICTransport(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		t.Run("cannot encode query", func(t *testing.T) {
+			expected := errors.New("mocked error")
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return nil, expected
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("query too large", func(t *testing.T) {
+			txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, math.MaxUint16+1), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, errQueryTooLarge) {
+				t.Fatal("unexpected err", err)
+			}
+			if resp != nil {
+				t.Fatal("expected nil response here")
+			}
+		})
+
+		t.Run("dial failure", func(t *testing.T) {
+			mocked := errors.New("mocked error")
+			var gotTLSConfig *tls.Config
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					gotTLSConfig = tlsConfig
+					return nil, mocked
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransportWithTLSConfig(
+				dialer, "9.9.9.9:853", &tls.Config{ServerName: "dns.example.com"})
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if diff := cmp.Diff([]string{"doq"}, gotTLSConfig.NextProtos); diff != "" {
+				t.Fatal(diff)
+			}
+			if gotTLSConfig.ServerName != "dns.example.com" {
+				t.Fatal("did not honour the TLS config")
+			}
+			if txp.TLSConfig.NextProtos != nil {
+				t.Fatal("modified the original TLS config")
+			}
+		})
+
+		t.Run("write failure", func(t *testing.T) {
+			mocked := errors.New("mocked error")
+			var closed int
+			stream := &dnsOverQUICStream{writeErr: mocked}
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					return newDNSOverQUICConn(stream, &closed), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if !errors.Is(err, mocked) {
+				t.Fatal("not the error we expected")
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if closed != 1 {
+				t.Fatal("did not close the connection")
+			}
+			if txp.conn != nil {
+				t.Fatal("did not forget the connection")
+			}
+		})
+
+		t.Run("read failure", func(t *testing.T) {
+			var closed int
+			stream := &dnsOverQUICStream{input: bytes.NewReader(nil)}
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					return newDNSOverQUICConn(stream, &closed), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, "9.9.9.9:853")
+			query := &mocks.DNSQuery{
+				MockBytes: func() ([]byte, error) {
+					return make([]byte, 128), nil
+				},
+			}
+			resp, err := txp.RoundTrip(context.Background(), query)
+			if err == nil {
+				t.Fatal("expected an error here")
+			}
+			if resp != nil {
+				t.Fatal("expected nil resp here")
+			}
+			if !stream.closed {
+				t.Fatal("did not send the STREAM FIN")
+			}
+		})
+
+		t.Run("success with zero message ID and connection reuse", func(t *testing.T) {
+			var dials, closed int
+			var streams []*dnsOverQUICStream
+			reply := &dns.Msg{}
+			reply.SetQuestion("dns.google.", dns.TypeA)
+			reply.Response = true
+			reply.Id = 0 // as mandated by RFC9250
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					dials++
+					return &mocks.QUICEarlyConnection{
+						MockOpenStreamSync: func(ctx context.Context) (quic.Stream, error) {
+							stream := newDNSOverQUICStream(t, reply)
+							streams = append(streams, stream)
+							return stream, nil
+						},
+						MockContext: func() context.Context {
+							return context.Background()
+						},
+						MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+							closed++
+							return nil
+						},
+					}, nil
+				},
+				MockCloseIdleConnections: func() {},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, "9.9.9.9:853")
+			encoder := &DNSEncoderMiekg{}
+			for idx := 0; idx < 2; idx++ {
+				query := encoder.Encode("dns.google", dns.TypeA, txp.RequiresPadding())
+				resp, err := txp.RoundTrip(context.Background(), query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.Query() != query {
+					t.Fatal("unexpected query")
+				}
+				written := streams[idx].output.Bytes()
+				if len(written) < 4 || written[2] != 0 || written[3] != 0 {
+					t.Fatal("the query message ID is not zero")
+				}
+			}
+			if dials != 1 {
+				t.Fatal("expected to reuse the connection")
+			}
+			txp.CloseIdleConnections()
+			if closed != 1 {
+				t.Fatal("did not close the idle connection")
+			}
+		})
+
+		t.Run("we redial when the cached connection fails", func(t *testing.T) {
+			mocked := errors.New("mocked error")
+			var dials, closed int
+			reply := &dns.Msg{}
+			reply.SetQuestion("dns.google.", dns.TypeA)
+			reply.Response = true
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					dials++
+					return &mocks.QUICEarlyConnection{
+						MockOpenStreamSync: func(ctx context.Context) (quic.Stream, error) {
+							return newDNSOverQUICStream(t, reply), nil
+						},
+						MockContext: func() context.Context {
+							return context.Background()
+						},
+						MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+							closed++
+							return nil
+						},
+					}, nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, "9.9.9.9:853")
+			encoder := &DNSEncoderMiekg{}
+			query := encoder.Encode("dns.google", dns.TypeA, txp.RequiresPadding())
+			if _, err := txp.RoundTrip(context.Background(), query); err != nil {
+				t.Fatal(err)
+			}
+			// make the cached connection fail
+			txp.conn = &mocks.QUICEarlyConnection{
+				MockOpenStreamSync: func(ctx context.Context) (quic.Stream, error) {
+					return nil, mocked
+				},
+				MockContext: func() context.Context {
+					return context.Background()
+				},
+				MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+					closed++
+					return nil
+				},
+			}
+			if _, err := txp.RoundTrip(context.Background(), query); err != nil {
+				t.Fatal(err)
+			}
+			if dials != 2 || closed != 1 {
+				t.Fatal("unexpected number of dials or closes", dials, closed)
+			}
+		})
+
+		t.Run("we discard a cached connection that has been closed", func(t *testing.T) {
+			var dials, closed int
+			reply := &dns.Msg{}
+			reply.SetQuestion("dns.google.", dns.TypeA)
+			reply.Response = true
+			dialer := &mocks.QUICDialer{
+				MockDialContext: func(ctx context.Context, address string,
+					tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
+					dials++
+					return newDNSOverQUICConn(newDNSOverQUICStream(t, reply), &closed), nil
+				},
+			}
+			txp := NewUnwrappedDNSOverQUICTransport(dialer, "9.9.9.9:853")
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel() // simulate a connection whose context is done
+			txp.conn = &mocks.QUICEarlyConnection{
+				MockContext: func() context.Context {
+					return ctx
+				},
+				MockCloseWithError: func(code quic.ApplicationErrorCode, reason string) error {
+					closed++
+					return nil
+				},
+			}
+			encoder := &DNSEncoderMiekg{}
+			query := encoder.Encode("dns.google", dns.TypeA, txp.RequiresPadding())
+			if _, err := txp.RoundTrip(context.Background(), query); err != nil {
+				t.Fatal(err)
+			}
+			if dials != 1 || closed != 1 {
+				t.Fatal("unexpected number of dials or closes", dials, closed)
+			}
+		})
+	})
+
+	t.Run("other functions behave correctly", func(t *testing.T) {
+		const address = "9.9.9.9:853"
+		txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{}, address)
+		if txp.RequiresPadding() != true {
+			t.Fatal("invalid RequiresPadding")
+		}
+		if txp.Network() != "doq" {
+			t.Fatal("invalid Network")
+		}
+		if txp.Address() != address {
+			t.Fatal("invalid Address")
+		}
+	})
+
+	t.Run("CloseIdleConnections", func(t *testing.T) {
+		var called bool
+		txp := NewUnwrappedDNSOverQUICTransport(&mocks.QUICDialer{
+			MockCloseIdleConnections: func() {
+				called = true
+			},
+		}, "9.9.9.9:853")
+		txp.CloseIdleConnections()
+		if !called {
+			t.Fatal("not called")
+		}
+	})
+
+	t.Run("implements model.DNSTransport", func(t *testing.T) {
+		var txp model.DNSTransport = NewUnwrappedDNSOverQUICTransport(nil, "")
+		if txp.Network() != "doq" {
+			t.Fatal("unexpected network")
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package netxlite
+
+//
+// TLS key log (aka SSLKEYLOGFILE) support
+//
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	ootls "github.com/ooni/oocrypto/tls"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// keyLogWriters contains the registered key log writers. We use pointers
+// to wrappers as keys because io.Writer values may not be comparable.
+var keyLogWriters = map[*keyLogRegistration]bool{}
+
+// keyLogMu protects keyLogWriters and serializes the writes.
+var keyLogMu sync.Mutex
+
+// keyLogRegistration wraps a registered io.Writer.
+type keyLogRegistration struct {
+	w io.Writer
+}
+
+// RegisterKeyLogWriter registers an io.Writer that will receive the
+// TLS key log, using the NSS key log format (i.e., the one used with
+// SSLKEYLOGFILE), of every subsequent TLS and QUIC handshake performed
+// using netxlite, including the ones performed by DoH and DoT resolvers
+// and by the probe services clients. Because the key log allows anyone to
+// decrypt the captured traffic, there is no writer by default.
+//
+// The returned function unregisters the writer and is idempotent. You
+// MUST call it when you are done, e.g., when closing a session.
+//
+// Like the UnderlyingNetwork overridden by WithCustomTProxy, the writers
+// are process wide, so each registered writer receives the key log of all
+// the handshakes, regardless of which session performed them. Use instead
+// ContextWithKeyLogWriter to only receive the key log of the handshakes
+// performed using a given context (e.g., while measuring).
+//
+// We serialize the writes such that each writer receives complete lines
+// and we ignore the errors returned by the writers, because a failure to
+// write the key log should not cause handshakes to fail.
+func RegisterKeyLogWriter(w io.Writer) (unregister func()) {
+	reg := &keyLogRegistration{w}
+	keyLogMu.Lock()
+	keyLogWriters[reg] = true
+	keyLogMu.Unlock()
+	return func() {
+		defer keyLogMu.Unlock()
+		keyLogMu.Lock()
+		delete(keyLogWriters, reg)
+	}
+}
+
+// keyLogWriterKey is the private type used to set/retrieve the context's key log writer.
+type keyLogWriterKey struct{}
+
+// ContextWithKeyLogWriter returns a new context that binds to the given io.Writer, which
+// receives the TLS key log of the TLS and QUIC handshakes performed by netxlite using
+// such a context, in addition to the writers registered using RegisterKeyLogWriter. If
+// the given writer is nil, this function will call panic.
+func ContextWithKeyLogWriter(ctx context.Context, w io.Writer) context.Context {
+	runtimex.PanicIfTrue(w == nil, "netxlite.ContextWithKeyLogWriter passed a nil writer")
+	return context.WithValue(ctx, keyLogWriterKey{}, &keyLogRegistration{w})
+}
+
+// ContextWithoutKeyLogWriter returns a new context that does not use the io.Writer
+// bound to the given context using ContextWithKeyLogWriter, if any.
+func ContextWithoutKeyLogWriter(ctx context.Context) context.Context {
+	return context.WithValue(ctx, keyLogWriterKey{}, (*keyLogRegistration)(nil))
+}
+
+// keyLogWriterSingleton returns the io.Writer to use as the KeyLogWriter of a
+// tls.Config, or nil if there are no registered writers and the given context
+// does not bind to a writer using ContextWithKeyLogWriter.
+func keyLogWriterSingleton(ctx context.Context) io.Writer {
+	reg, _ := ctx.Value(keyLogWriterKey{}).(*keyLogRegistration)
+	defer keyLogMu.Unlock()
+	keyLogMu.Lock()
+	if len(keyLogWriters) <= 0 && reg == nil {
+		return nil
+	}
+	return &keyLogDispatcher{reg}
+}
+
+// keyLogDispatcher writes to the writer bound to the context, if any, and
+// to all the writers registered when calling Write.
+type keyLogDispatcher struct {
+	reg *keyLogRegistration
+}
+
+// Write implements io.Writer.
+func (d *keyLogDispatcher) Write(data []byte) (int, error) {
+	defer keyLogMu.Unlock()
+	keyLogMu.Lock()
+	if d.reg != nil {
+		_, _ = d.reg.w.Write(data)
+	}
+	for reg := range keyLogWriters {
+		_, _ = reg.w.Write(data)
+	}
+	return len(data), nil
+}
+
+// ErrKeyLogSerialization indicates that you attempted to serialize a KeyLogBuffer.
+var ErrKeyLogSerialization = errors.New("netxlite: refusing to serialize the TLS key log")
+
+// KeyLogBuffer is a goroutine-safe, in-memory TLS key log you can use
+// with RegisterKeyLogWriter. The zero value is ready to use.
+//
+// To guard against accidentally including the key log into a measurement,
+// serializing a KeyLogBuffer to JSON always fails, which in turn prevents
+// the engine from submitting a measurement containing it.
+type KeyLogBuffer struct {
+	buffer bytes.Buffer
+	mu     sync.Mutex
+}
+
+var _ io.Writer = &KeyLogBuffer{}
+
+// Write implements io.Writer.
+func (kb *KeyLogBuffer) Write(data []byte) (int, error) {
+	defer kb.mu.Unlock()
+	kb.mu.Lock()
+	return kb.buffer.Write(data)
+}
+
+// Bytes returns a copy of the key log collected so far.
+func (kb *KeyLogBuffer) Bytes() []byte {
+	defer kb.mu.Unlock()
+	kb.mu.Lock()
+	return append([]byte{}, kb.buffer.Bytes()...)
+}
+
+// MarshalJSON implements json.Marshaler and always fails.
+func (kb *KeyLogBuffer) MarshalJSON() ([]byte, error) {
+	return nil, ErrKeyLogSerialization
+}
+
+// tlsMaybeApplyKeyLogWriter returns a config using the registered key log writers
+// and the one bound to the context, if any, unless the config already contains
+// a KeyLogWriter.
+func tlsMaybeApplyKeyLogWriter(ctx context.Context, config *tls.Config) *tls.Config {
+	if config.KeyLogWriter != nil {
+		return config
+	}
+	w := keyLogWriterSingleton(ctx)
+	if w == nil {
+		return config
+	}
+	config = config.Clone()
+	config.KeyLogWriter = w
+	return config
+}
+
+// tlsNewClientConnStdlib is like ootls.NewClientConnStdlib except that it
+// also honours the KeyLogWriter field, which ootls does not support.
+func tlsNewClientConnStdlib(conn net.Conn, config *tls.Config) (TLSConn, error) {
+	if config.KeyLogWriter == nil {
+		return ootls.NewClientConnStdlib(conn, config)
+	}
+	// Let ootls check whether the other fields are supported. Creating
+	// a client conn does not perform any I/O, so we can discard it.
+	stripped := config.Clone()
+	stripped.KeyLogWriter = nil
+	if _, err := ootls.NewClientConnStdlib(conn, stripped); err != nil {
+		return nil, err
+	}
+	ourConfig := &ootls.Config{
+		DynamicRecordSizingDisabled: config.DynamicRecordSizingDisabled,
+		InsecureSkipVerify:          config.InsecureSkipVerify,
+		KeyLogWriter:                config.KeyLogWriter,
+		MaxVersion:                  config.MaxVersion,
+		MinVersion:                  config.MinVersion,
+		NextProtos:                  config.NextProtos,
+		RootCAs:                     config.RootCAs,
+		ServerName:                  config.ServerName,
+	}
+	return &tlsConnKeyLog{ootls.Client(conn, ourConfig)}, nil
+}
+
+// tlsConnKeyLog adapts an *ootls.Conn to be a TLSConn.
+type tlsConnKeyLog struct {
+	*ootls.Conn
+}
+
+var _ TLSConn = &tlsConnKeyLog{}
+
+// ConnectionState implements TLSConn. We would like to reuse the conversion
+// performed by ootls.ConnStdlib.ConnectionState, but ootls only allows us to
+// construct a ConnStdlib using NewClientConnStdlib, which rejects configs with
+// a KeyLogWriter. So, we mirror such a conversion, and TestTLSConnKeyLog
+// checks that we fill the same fields that ootls fills.
+func (c *tlsConnKeyLog) ConnectionState() tls.ConnectionState {
+	state := c.Conn.ConnectionState()
+	return tls.ConnectionState{
+		Version:                     state.Version,
+		HandshakeComplete:           state.HandshakeComplete,
+		DidResume:                   state.DidResume,
+		CipherSuite:                 state.CipherSuite,
+		NegotiatedProtocol:          state.NegotiatedProtocol,
+		NegotiatedProtocolIsMutual:  state.NegotiatedProtocolIsMutual,
+		ServerName:                  state.ServerName,
+		PeerCertificates:            state.PeerCertificates,
+		VerifiedChains:              state.VerifiedChains,
+		SignedCertificateTimestamps: state.SignedCertificateTimestamps,
+		OCSPResponse:                state.OCSPResponse,
+		TLSUnique:                   state.TLSUnique,
+	}
+}
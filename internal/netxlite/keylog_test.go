@@ -0,0 +1,310 @@
+package netxlite
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lucas-clemente/quic-go"
+	ootls "github.com/ooni/oocrypto/tls"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	utls "gitlab.com/yawning/utls.git"
+)
+
+func TestRegisterKeyLogWriter(t *testing.T) {
+	t.Run("without registered writers", func(t *testing.T) {
+		if w := keyLogWriterSingleton(context.Background()); w != nil {
+			t.Fatal("expected nil writer")
+		}
+	})
+
+	t.Run("with registered writers", func(t *testing.T) {
+		first, second := &KeyLogBuffer{}, &KeyLogBuffer{}
+		unregisterFirst := RegisterKeyLogWriter(first)
+		unregisterSecond := RegisterKeyLogWriter(second)
+		unregisterFailing := RegisterKeyLogWriter(&mocks.Writer{
+			MockWrite: func(b []byte) (int, error) {
+				return 0, errors.New("mocked error")
+			},
+		})
+		defer unregisterFailing()
+		w := keyLogWriterSingleton(context.Background())
+		if w == nil {
+			t.Fatal("expected non-nil writer")
+		}
+		count, err := w.Write([]byte("CLIENT_RANDOM 00 11\n"))
+		if err != nil || count != 20 {
+			t.Fatal("unexpected write result", count, err)
+		}
+		unregisterFirst()
+		unregisterFirst() // should be idempotent
+		w.Write([]byte("CLIENT_RANDOM 22 33\n"))
+		unregisterSecond()
+		if got := string(first.Bytes()); got != "CLIENT_RANDOM 00 11\n" {
+			t.Fatal("unexpected first key log", got)
+		}
+		if got := string(second.Bytes()); got != "CLIENT_RANDOM 00 11\nCLIENT_RANDOM 22 33\n" {
+			t.Fatal("unexpected second key log", got)
+		}
+	})
+}
+
+func TestContextWithKeyLogWriter(t *testing.T) {
+	t.Run("with a writer bound to the context", func(t *testing.T) {
+		registered, bound := &KeyLogBuffer{}, &KeyLogBuffer{}
+		defer RegisterKeyLogWriter(registered)()
+		ctx := ContextWithKeyLogWriter(context.Background(), bound)
+		keyLogWriterSingleton(ctx).Write([]byte("CLIENT_RANDOM 00 11\n"))
+		keyLogWriterSingleton(context.Background()).Write([]byte("CLIENT_RANDOM 22 33\n"))
+		if got := string(bound.Bytes()); got != "CLIENT_RANDOM 00 11\n" {
+			t.Fatal("unexpected bound key log", got)
+		}
+		if got := string(registered.Bytes()); got != "CLIENT_RANDOM 00 11\nCLIENT_RANDOM 22 33\n" {
+			t.Fatal("unexpected registered key log", got)
+		}
+	})
+
+	t.Run("without the writer bound to the context", func(t *testing.T) {
+		ctx := ContextWithKeyLogWriter(context.Background(), &KeyLogBuffer{})
+		if keyLogWriterSingleton(ctx) == nil {
+			t.Fatal("expected non-nil writer")
+		}
+		if w := keyLogWriterSingleton(ContextWithoutKeyLogWriter(ctx)); w != nil {
+			t.Fatal("expected nil writer")
+		}
+	})
+
+	t.Run("with a nil writer", func(t *testing.T) {
+		var err error
+		func() {
+			defer func() {
+				err = recover().(error)
+			}()
+			ContextWithKeyLogWriter(context.Background(), nil)
+		}()
+		if err == nil {
+			t.Fatal("expected a panic")
+		}
+	})
+}
+
+func TestKeyLogBuffer(t *testing.T) {
+	t.Run("we cannot serialize it", func(t *testing.T) {
+		kb := &KeyLogBuffer{}
+		kb.Write([]byte("CLIENT_RANDOM 00 11\n"))
+		data, err := json.Marshal(map[string]any{"keylog": kb})
+		if !errors.Is(err, ErrKeyLogSerialization) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(data) != 0 {
+			t.Fatal("expected no data")
+		}
+	})
+
+	t.Run("Bytes returns a copy", func(t *testing.T) {
+		kb := &KeyLogBuffer{}
+		kb.Write([]byte("abc"))
+		data := kb.Bytes()
+		data[0] = 'x'
+		if string(kb.Bytes()) != "abc" {
+			t.Fatal("the buffer has been modified")
+		}
+	})
+}
+
+func TestTLSMaybeApplyKeyLogWriter(t *testing.T) {
+	t.Run("without registered writers", func(t *testing.T) {
+		config := &tls.Config{}
+		if got := tlsMaybeApplyKeyLogWriter(context.Background(), config); got != config {
+			t.Fatal("expected the same config")
+		}
+	})
+
+	t.Run("with registered writers", func(t *testing.T) {
+		defer RegisterKeyLogWriter(&KeyLogBuffer{})()
+		config := &tls.Config{}
+		got := tlsMaybeApplyKeyLogWriter(context.Background(), config)
+		if got.KeyLogWriter == nil {
+			t.Fatal("expected a key log writer")
+		}
+		if config.KeyLogWriter != nil {
+			t.Fatal("the original config should not have been changed")
+		}
+	})
+
+	t.Run("with a config already using a key log writer", func(t *testing.T) {
+		defer RegisterKeyLogWriter(&KeyLogBuffer{})()
+		kb := &KeyLogBuffer{}
+		config := &tls.Config{KeyLogWriter: kb}
+		if got := tlsMaybeApplyKeyLogWriter(context.Background(), config); got != config || got.KeyLogWriter != kb {
+			t.Fatal("expected the same config")
+		}
+	})
+}
+
+func TestTLSConnKeyLog(t *testing.T) {
+	srvr := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(200)
+	}))
+	defer srvr.Close()
+	URL, err := url.Parse(srvr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// handshake returns the state after a handshake using the given factory.
+	handshake := func(t *testing.T, factory func(net.Conn, *tls.Config) (TLSConn, error),
+		config *tls.Config) tls.ConnectionState {
+		conn, err := net.Dial("tcp", URL.Host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		tlsConn, err := factory(conn, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tlsConn.HandshakeContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return tlsConn.ConnectionState()
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12, // such that TLSUnique is set
+		NextProtos:         []string{"h2", "http/1.1"},
+		ServerName:         URL.Hostname(),
+	}
+	expect := handshake(t, func(conn net.Conn, config *tls.Config) (TLSConn, error) {
+		return ootls.NewClientConnStdlib(conn, config)
+	}, config)
+	config = config.Clone()
+	config.KeyLogWriter = &KeyLogBuffer{}
+	got := handshake(t, tlsNewClientConnStdlib, config)
+
+	// The two states are not equal because they come from distinct handshakes, so
+	// we check that we fill the same fields and that the stable ones are equal.
+	expectValue, gotValue := reflect.ValueOf(expect), reflect.ValueOf(got)
+	for idx := 0; idx < expectValue.NumField(); idx++ {
+		field := expectValue.Type().Field(idx)
+		if !field.IsExported() {
+			continue
+		}
+		if expectValue.Field(idx).IsZero() != gotValue.Field(idx).IsZero() {
+			t.Fatal("we do not fill the field like ootls does:", field.Name)
+		}
+	}
+	if expect.Version != got.Version || expect.CipherSuite != got.CipherSuite ||
+		expect.NegotiatedProtocol != got.NegotiatedProtocol || expect.ServerName != got.ServerName ||
+		!got.HandshakeComplete || len(got.PeerCertificates) != len(expect.PeerCertificates) {
+		t.Fatal("unexpected state", got)
+	}
+}
+
+func TestKeyLogHandshakers(t *testing.T) {
+	handshake := func(t *testing.T, handshaker *tlsHandshakerConfigurable) {
+		srvr := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(200)
+		}))
+		defer srvr.Close()
+		URL, err := url.Parse(srvr.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := net.Dial("tcp", URL.Host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		kb := &KeyLogBuffer{}
+		defer RegisterKeyLogWriter(kb)()
+		config := &tls.Config{InsecureSkipVerify: true, ServerName: URL.Hostname()}
+		tlsConn, _, err := handshaker.Handshake(context.Background(), conn, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tlsConn.Close()
+		if !strings.Contains(string(kb.Bytes()), "CLIENT_HANDSHAKE_TRAFFIC_SECRET") &&
+			!strings.Contains(string(kb.Bytes()), "CLIENT_RANDOM") {
+			t.Fatal("expected the key log to contain secrets", string(kb.Bytes()))
+		}
+	}
+
+	t.Run("with the stdlib handshaker", func(t *testing.T) {
+		handshake(t, &tlsHandshakerConfigurable{})
+	})
+
+	t.Run("with the utls handshaker", func(t *testing.T) {
+		handshake(t, &tlsHandshakerConfigurable{
+			NewConn: newUTLSConnFactory(&utls.HelloFirefox_55),
+		})
+	})
+
+	t.Run("with the QUIC dialer", func(t *testing.T) {
+		defer RegisterKeyLogWriter(&KeyLogBuffer{})()
+		expected := errors.New("mocked error")
+		var gotTLSConfig *tls.Config
+		dialer := &quicDialerQUICGo{
+			QUICListener: &quicListenerStdlib{},
+			mockDialEarlyContext: func(ctx context.Context, pconn net.PacketConn,
+				remoteAddr net.Addr, host string, tlsConfig *tls.Config,
+				quicConfig *quic.Config) (quic.EarlyConnection, error) {
+				gotTLSConfig = tlsConfig
+				return nil, expected
+			},
+		}
+		tlsConfig := &tls.Config{ServerName: "dns.google"}
+		_, err := dialer.DialContext(context.Background(), "8.8.8.8:443", tlsConfig, &quic.Config{})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if gotTLSConfig.KeyLogWriter == nil {
+			t.Fatal("expected a key log writer")
+		}
+		if tlsConfig.KeyLogWriter != nil {
+			t.Fatal("the original config should not have been changed")
+		}
+	})
+}
+
+func TestTLSNewClientConnStdlib(t *testing.T) {
+	t.Run("with a key log writer and an unsupported field", func(t *testing.T) {
+		config := &tls.Config{
+			KeyLogWriter:           &KeyLogBuffer{},
+			SessionTicketsDisabled: true,
+		}
+		tlsConn, err := tlsNewClientConnStdlib(&mocks.Conn{}, config)
+		if err == nil || !strings.HasSuffix(err.Error(), "field SessionTicketsDisabled is nonzero") {
+			t.Fatal("unexpected error", err)
+		}
+		if tlsConn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("with a key log writer and supported fields", func(t *testing.T) {
+		config := &tls.Config{
+			KeyLogWriter: &KeyLogBuffer{},
+			ServerName:   "dns.google",
+		}
+		tlsConn, err := tlsNewClientConnStdlib(&mocks.Conn{}, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, okay := tlsConn.(*tlsConnKeyLog); !okay {
+			t.Fatal("unexpected conn type")
+		}
+		if state := tlsConn.ConnectionState(); state.HandshakeComplete {
+			t.Fatal("expected the handshake not to be complete")
+		}
+	})
+}
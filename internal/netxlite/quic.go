@@ -131,6 +131,8 @@ func ParseUDPAddr(address string) (*net.UDPAddr, error) {
 //
 // 2. if tlsConfig.NextProtos is empty _and_ the port is 443 or 8853,
 // then we configure, respectively, "h3" and "dq".
+//
+// We also honour the ConnWrapper bound to the context, if any.
 func (d *quicDialerQUICGo) DialContext(ctx context.Context,
 	address string, tlsConfig *tls.Config, quicConfig *quic.Config) (
 	quic.EarlyConnection, error) {
@@ -138,13 +140,17 @@ func (d *quicDialerQUICGo) DialContext(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	wrapper := contextConnWrapperOrDefault(ctx)
+	if err := wrapper.CheckNewConn(); err != nil {
+		return nil, err
+	}
 	pconn, err := d.QUICListener.Listen(&net.UDPAddr{IP: net.IPv4zero, Port: 0, Zone: ""})
 	if err != nil {
 		return nil, err
 	}
-	tlsConfig = d.maybeApplyTLSDefaults(tlsConfig, udpAddr.Port)
+	tlsConfig = d.maybeApplyTLSDefaults(ctx, tlsConfig, udpAddr.Port)
 	trace := ContextTraceOrDefault(ctx)
-	pconn = trace.MaybeWrapUDPLikeConn(pconn)
+	pconn = trace.MaybeWrapUDPLikeConn(wrapper.WrapUDPLikeConn(pconn))
 	started := trace.TimeNow()
 	trace.OnQUICHandshakeStart(started, address, quicConfig)
 	qconn, err := d.dialEarlyContext(
@@ -171,13 +177,19 @@ func (d *quicDialerQUICGo) dialEarlyContext(ctx context.Context,
 }
 
 // maybeApplyTLSDefaults ensures that we're using our certificate pool, if
-// needed, and that we use a suitable ALPN, if needed, for h3 and dq.
-func (d *quicDialerQUICGo) maybeApplyTLSDefaults(config *tls.Config, port int) *tls.Config {
+// needed, that we write the key log to the registered writers and to the one
+// bound to the context, if any and if needed, and that we use a suitable
+// ALPN, if needed, for h3 and dq.
+func (d *quicDialerQUICGo) maybeApplyTLSDefaults(
+	ctx context.Context, config *tls.Config, port int) *tls.Config {
 	config = config.Clone()
 	if config.RootCAs == nil {
 		// See https://github.com/ooni/probe/issues/2413 for context
 		config.RootCAs = tproxySingleton().DefaultCertPool()
 	}
+	if config.KeyLogWriter == nil {
+		config.KeyLogWriter = keyLogWriterSingleton(ctx)
+	}
 	if len(config.NextProtos) <= 0 {
 		switch port {
 		case 443:
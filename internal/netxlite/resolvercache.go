@@ -3,25 +3,19 @@ package netxlite
 import (
 	"context"
 	"net"
-	"sync"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 // MaybeWrapWithCachingResolver wraps the provided resolver with a resolver
-// that remembers the result of previous successful resolutions, if the enabled
-// argument is true. Otherwise, we return the unmodified provided resolver.
+// that remembers the result of previous resolutions, if the enabled argument
+// is true. Otherwise, we return the unmodified provided resolver.
 //
-// Bug: the returned resolver only applies caching to LookupHost and any other
-// lookup operation returns ErrNoDNSTransport to the caller.
+// This function is equivalent to calling NewCachingResolver with a nil
+// config, hence it honours the TTLs and uses a bounded cache.
 func MaybeWrapWithCachingResolver(enabled bool, reso model.Resolver) model.Resolver {
 	if enabled {
-		reso = &cacheResolver{
-			cache:    map[string][]string{},
-			mu:       sync.Mutex{},
-			readOnly: false,
-			resolver: reso,
-		}
+		reso = NewCachingResolver(reso, nil)
 	}
 	return reso
 }
@@ -29,31 +23,23 @@ func MaybeWrapWithCachingResolver(enabled bool, reso model.Resolver) model.Resol
 // MaybeWrapWithStaticDNSCache wraps the provided resolver with a resolver that
 // checks the given cache before issuing queries to the underlying DNS resolver.
 //
-// Bug: the returned resolver only applies caching to LookupHost and any other
-// lookup operation returns ErrNoDNSTransport to the caller.
+// The static cache only applies to LookupHost. All the other lookup operations
+// are forwarded to the underlying resolver as-is.
 func MaybeWrapWithStaticDNSCache(cache map[string][]string, reso model.Resolver) model.Resolver {
 	if len(cache) > 0 {
 		reso = &cacheResolver{
 			cache:    cache,
-			mu:       sync.Mutex{},
-			readOnly: true,
 			resolver: reso,
 		}
 	}
 	return reso
 }
 
-// cacheResolver implements CachingResolver and StaticDNSCache.
+// cacheResolver implements StaticDNSCache.
 type cacheResolver struct {
-	// cache is the underlying DNS cache.
+	// cache is the underlying DNS cache, which we never modify.
 	cache map[string][]string
 
-	// mu provides mutual exclusion.
-	mu sync.Mutex
-
-	// readOnly means that we won't cache the result of successful resolutions.
-	readOnly bool
-
 	// resolver is the underlying resolver.
 	resolver model.Resolver
 }
@@ -63,34 +49,10 @@ var _ model.Resolver = &cacheResolver{}
 // LookupHost implements model.Resolver.LookupHost
 func (r *cacheResolver) LookupHost(
 	ctx context.Context, hostname string) ([]string, error) {
-	if entry := r.get(hostname); entry != nil {
+	if entry := r.cache[hostname]; entry != nil {
 		return entry, nil
 	}
-	entry, err := r.resolver.LookupHost(ctx, hostname)
-	if err != nil {
-		return nil, err
-	}
-	if !r.readOnly {
-		r.set(hostname, entry)
-	}
-	return entry, nil
-}
-
-// get gets the currently configured entry for domain, or nil
-func (r *cacheResolver) get(domain string) []string {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	return r.cache[domain]
-}
-
-// set sets a valid inside the cache iff readOnly is false.
-func (r *cacheResolver) set(domain string, addresses []string) {
-	r.mu.Lock()
-	if r.cache == nil {
-		r.cache = make(map[string][]string)
-	}
-	r.cache[domain] = addresses
-	r.mu.Unlock()
+	return r.resolver.LookupHost(ctx, hostname)
 }
 
 // Address implements model.Resolver.Address.
@@ -110,10 +72,40 @@ func (r *cacheResolver) CloseIdleConnections() {
 
 // LookupHTTPS implements model.Resolver.LookupHTTPS.
 func (r *cacheResolver) LookupHTTPS(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
-	return nil, ErrNoDNSTransport
+	return r.resolver.LookupHTTPS(ctx, domain)
 }
 
 // LookupNS implements model.Resolver.LookupNS.
 func (r *cacheResolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, error) {
-	return nil, ErrNoDNSTransport
+	return r.resolver.LookupNS(ctx, domain)
+}
+
+// LookupTXT implements model.Resolver.LookupTXT.
+func (r *cacheResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return r.resolver.LookupTXT(ctx, domain)
+}
+
+// LookupMX implements model.Resolver.LookupMX.
+func (r *cacheResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return r.resolver.LookupMX(ctx, domain)
+}
+
+// LookupCNAME implements model.Resolver.LookupCNAME.
+func (r *cacheResolver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return r.resolver.LookupCNAME(ctx, domain)
+}
+
+// LookupSOA implements model.Resolver.LookupSOA.
+func (r *cacheResolver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return r.resolver.LookupSOA(ctx, domain)
+}
+
+// LookupPTR implements model.Resolver.LookupPTR.
+func (r *cacheResolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return r.resolver.LookupPTR(ctx, address)
+}
+
+// LookupSRV implements model.Resolver.LookupSRV.
+func (r *cacheResolver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return r.resolver.LookupSRV(ctx, domain)
 }
@@ -3,9 +3,11 @@ package netxlite
 import (
 	"context"
 	"errors"
+	"net"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/model/mocks"
 )
 
@@ -13,7 +15,7 @@ func TestMaybeWrapWithCachingResolver(t *testing.T) {
 	t.Run("with enable equal to true", func(t *testing.T) {
 		underlying := &mocks.Resolver{}
 		reso := MaybeWrapWithCachingResolver(true, underlying)
-		cachereso := reso.(*cacheResolver)
+		cachereso := reso.(*ttlCacheResolver)
 		if cachereso.resolver != underlying {
 			t.Fatal("did not wrap correctly")
 		}
@@ -81,9 +83,6 @@ func TestCacheResolver(t *testing.T) {
 			if addrs != nil {
 				t.Fatal("expected nil addrs here")
 			}
-			if cache.get("www.google.com") != nil {
-				t.Fatal("expected empty cache here")
-			}
 		})
 
 		t.Run("cache hit", func(t *testing.T) {
@@ -93,24 +92,10 @@ func TestCacheResolver(t *testing.T) {
 					return nil, expected
 				},
 			}
-			cache := &cacheResolver{resolver: r}
-			cache.set("dns.google.com", []string{"8.8.8.8"})
-			addrs, err := cache.LookupHost(context.Background(), "dns.google.com")
-			if err != nil {
-				t.Fatal(err)
-			}
-			if len(addrs) != 1 || addrs[0] != "8.8.8.8" {
-				t.Fatal("not the result we expected")
-			}
-		})
-
-		t.Run("cache miss and success with readwrite cache", func(t *testing.T) {
-			r := &mocks.Resolver{
-				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
-					return []string{"8.8.8.8"}, nil
-				},
+			cache := &cacheResolver{
+				cache:    map[string][]string{"dns.google.com": {"8.8.8.8"}},
+				resolver: r,
 			}
-			cache := &cacheResolver{resolver: r}
 			addrs, err := cache.LookupHost(context.Background(), "dns.google.com")
 			if err != nil {
 				t.Fatal(err)
@@ -118,18 +103,15 @@ func TestCacheResolver(t *testing.T) {
 			if len(addrs) != 1 || addrs[0] != "8.8.8.8" {
 				t.Fatal("not the result we expected")
 			}
-			if cache.get("dns.google.com")[0] != "8.8.8.8" {
-				t.Fatal("expected full cache here")
-			}
 		})
 
-		t.Run("cache miss and success with readonly cache", func(t *testing.T) {
+		t.Run("cache miss and success", func(t *testing.T) {
 			r := &mocks.Resolver{
 				MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
 					return []string{"8.8.8.8"}, nil
 				},
 			}
-			cache := &cacheResolver{resolver: r, readOnly: true}
+			cache := &cacheResolver{cache: map[string][]string{}, resolver: r}
 			addrs, err := cache.LookupHost(context.Background(), "dns.google.com")
 			if err != nil {
 				t.Fatal(err)
@@ -137,69 +119,98 @@ func TestCacheResolver(t *testing.T) {
 			if len(addrs) != 1 || addrs[0] != "8.8.8.8" {
 				t.Fatal("not the result we expected")
 			}
-			if cache.get("dns.google.com") != nil {
-				t.Fatal("expected empty cache here")
+			if len(cache.cache) != 0 {
+				t.Fatal("expected the static cache not to change")
 			}
 		})
+	})
 
-		t.Run("Address", func(t *testing.T) {
-			underlying := &mocks.Resolver{
-				MockAddress: func() string {
-					return "x"
-				},
-			}
-			reso := &cacheResolver{resolver: underlying}
-			if reso.Address() != "x" {
-				t.Fatal("unexpected result")
-			}
-		})
+	t.Run("Address", func(t *testing.T) {
+		underlying := &mocks.Resolver{
+			MockAddress: func() string {
+				return "x"
+			},
+		}
+		reso := &cacheResolver{resolver: underlying}
+		if reso.Address() != "x" {
+			t.Fatal("unexpected result")
+		}
+	})
 
-		t.Run("Network", func(t *testing.T) {
-			underlying := &mocks.Resolver{
-				MockNetwork: func() string {
-					return "x"
-				},
-			}
-			reso := &cacheResolver{resolver: underlying}
-			if reso.Network() != "x" {
-				t.Fatal("unexpected result")
-			}
-		})
+	t.Run("Network", func(t *testing.T) {
+		underlying := &mocks.Resolver{
+			MockNetwork: func() string {
+				return "x"
+			},
+		}
+		reso := &cacheResolver{resolver: underlying}
+		if reso.Network() != "x" {
+			t.Fatal("unexpected result")
+		}
+	})
 
-		t.Run("CloseIdleConnections", func(t *testing.T) {
-			var called bool
-			underlying := &mocks.Resolver{
-				MockCloseIdleConnections: func() {
-					called = true
-				},
-			}
-			reso := &cacheResolver{resolver: underlying}
-			reso.CloseIdleConnections()
-			if !called {
-				t.Fatal("not called")
-			}
-		})
+	t.Run("CloseIdleConnections", func(t *testing.T) {
+		var called bool
+		underlying := &mocks.Resolver{
+			MockCloseIdleConnections: func() {
+				called = true
+			},
+		}
+		reso := &cacheResolver{resolver: underlying}
+		reso.CloseIdleConnections()
+		if !called {
+			t.Fatal("not called")
+		}
+	})
 
-		t.Run("LookupHTTPS", func(t *testing.T) {
-			reso := &cacheResolver{}
-			https, err := reso.LookupHTTPS(context.Background(), "dns.google")
-			if !errors.Is(err, ErrNoDNSTransport) {
-				t.Fatal("unexpected err", err)
-			}
-			if https != nil {
-				t.Fatal("expected nil")
-			}
-		})
+	t.Run("LookupHTTPS", func(t *testing.T) {
+		expected := &model.HTTPSSvc{ALPN: []string{"h3"}}
+		underlying := &mocks.Resolver{
+			MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+				return expected, nil
+			},
+		}
+		reso := &cacheResolver{resolver: underlying}
+		https, err := reso.LookupHTTPS(context.Background(), "dns.google")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if https != expected {
+			t.Fatal("unexpected result")
+		}
+	})
 
-		t.Run("LookupNS", func(t *testing.T) {
-			reso := &cacheResolver{}
-			ns, err := reso.LookupNS(context.Background(), "dns.google")
-			if !errors.Is(err, ErrNoDNSTransport) {
-				t.Fatal("unexpected err", err)
+	t.Run("LookupNS", func(t *testing.T) {
+		expected := []*net.NS{{Host: "ns1.zdns.google."}}
+		underlying := &mocks.Resolver{
+			MockLookupNS: func(ctx context.Context, domain string) ([]*net.NS, error) {
+				return expected, nil
+			},
+		}
+		reso := &cacheResolver{resolver: underlying}
+		ns, err := reso.LookupNS(context.Background(), "dns.google")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected, ns); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	for _, rl := range resolverRecordsLookups {
+		t.Run(rl.name, func(t *testing.T) {
+			var input string
+			reso := &cacheResolver{resolver: newResolverRecordsMock(&input, nil)}
+			out, err := rl.lookup(context.Background(), reso, rl.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if input != rl.input {
+				t.Fatal("unexpected input", input)
 			}
-			if len(ns) != 0 {
-				t.Fatal("expected zero length slice")
+			if diff := cmp.Diff(rl.expect, out); diff != "" {
+				t.Fatal(diff)
 			}
 		})
-	})
+	}
 }
@@ -91,6 +91,32 @@ func NewParallelUDPResolver(logger model.DebugLogger, dialer model.Dialer,
 	))
 }
 
+// NewParallelTCPResolver is like NewParallelUDPResolver but uses DNS-over-TCP
+// using the given dialer to connect to the given address (e.g., 1.1.1.1:53).
+func NewParallelTCPResolver(logger model.DebugLogger, dialer model.Dialer,
+	address string, wrappers ...model.DNSTransportWrapper) model.Resolver {
+	return WrapResolver(logger, NewUnwrappedParallelResolver(
+		WrapDNSTransport(NewUnwrappedDNSOverTCPTransport(dialer.DialContext, address), wrappers...),
+	))
+}
+
+// NewParallelDNSOverTLSResolver is like NewParallelUDPResolver but uses DNS-over-TLS
+// using the given TLS dialer to connect to the given address (e.g., 1.1.1.1:853).
+func NewParallelDNSOverTLSResolver(logger model.DebugLogger, dialer model.TLSDialer,
+	address string, wrappers ...model.DNSTransportWrapper) model.Resolver {
+	return WrapResolver(logger, NewUnwrappedParallelResolver(
+		WrapDNSTransport(NewUnwrappedDNSOverTLSTransport(dialer.DialTLSContext, address), wrappers...),
+	))
+}
+
+// NewParallelDNSOverHTTP3Resolver is like NewParallelDNSOverHTTPSResolver
+// except that it uses HTTP3 as the underlying HTTP transport.
+func NewParallelDNSOverHTTP3Resolver(logger model.DebugLogger, URL string) model.Resolver {
+	client := &http.Client{Transport: NewHTTP3TransportStdlib(logger)}
+	txp := WrapDNSTransport(NewUnwrappedDNSOverHTTPSTransport(client, URL))
+	return WrapResolver(logger, NewUnwrappedParallelResolver(txp))
+}
+
 // WrapResolver creates a new resolver that wraps an
 // existing resolver to add these properties:
 //
@@ -165,6 +191,38 @@ func (r *resolverSystem) LookupNS(
 	return nil, ErrNoDNSTransport
 }
 
+func (r *resolverSystem) LookupTXT(
+	ctx context.Context, domain string) ([]string, error) {
+	return nil, ErrNoDNSTransport
+}
+
+func (r *resolverSystem) LookupMX(
+	ctx context.Context, domain string) ([]*net.MX, error) {
+	return nil, ErrNoDNSTransport
+}
+
+// LookupCNAME uses getaddrinfo's canonical name, which is the only record
+// other than A and AAAA that we can obtain from the system resolver.
+func (r *resolverSystem) LookupCNAME(
+	ctx context.Context, domain string) (string, error) {
+	return lookupRecords(ctx, r, r.t, domain, dns.TypeANY, model.DNSResponse.DecodeCNAME)
+}
+
+func (r *resolverSystem) LookupSOA(
+	ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return nil, ErrNoDNSTransport
+}
+
+func (r *resolverSystem) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	return nil, ErrNoDNSTransport
+}
+
+func (r *resolverSystem) LookupSRV(
+	ctx context.Context, domain string) ([]*net.SRV, error) {
+	return nil, ErrNoDNSTransport
+}
+
 // resolverLogger is a resolver that emits events
 type resolverLogger struct {
 	Resolver model.Resolver
@@ -232,6 +290,96 @@ func (r *resolverLogger) LookupNS(
 	return ns, nil
 }
 
+func (r *resolverLogger) LookupTXT(
+	ctx context.Context, domain string) ([]string, error) {
+	prefix := fmt.Sprintf("resolve[TXT] %s with %s (%s)", domain, r.Network(), r.Address())
+	r.Logger.Debugf("%s...", prefix)
+	start := time.Now()
+	txt, err := r.Resolver.LookupTXT(ctx, domain)
+	elapsed := time.Since(start)
+	if err != nil {
+		r.Logger.Debugf("%s... %s in %s", prefix, err, elapsed)
+		return nil, err
+	}
+	r.Logger.Debugf("%s... %+v in %s", prefix, txt, elapsed)
+	return txt, nil
+}
+
+func (r *resolverLogger) LookupMX(
+	ctx context.Context, domain string) ([]*net.MX, error) {
+	prefix := fmt.Sprintf("resolve[MX] %s with %s (%s)", domain, r.Network(), r.Address())
+	r.Logger.Debugf("%s...", prefix)
+	start := time.Now()
+	mx, err := r.Resolver.LookupMX(ctx, domain)
+	elapsed := time.Since(start)
+	if err != nil {
+		r.Logger.Debugf("%s... %s in %s", prefix, err, elapsed)
+		return nil, err
+	}
+	r.Logger.Debugf("%s... %+v in %s", prefix, mx, elapsed)
+	return mx, nil
+}
+
+func (r *resolverLogger) LookupCNAME(
+	ctx context.Context, domain string) (string, error) {
+	prefix := fmt.Sprintf("resolve[CNAME] %s with %s (%s)", domain, r.Network(), r.Address())
+	r.Logger.Debugf("%s...", prefix)
+	start := time.Now()
+	cname, err := r.Resolver.LookupCNAME(ctx, domain)
+	elapsed := time.Since(start)
+	if err != nil {
+		r.Logger.Debugf("%s... %s in %s", prefix, err, elapsed)
+		return "", err
+	}
+	r.Logger.Debugf("%s... %s in %s", prefix, cname, elapsed)
+	return cname, nil
+}
+
+func (r *resolverLogger) LookupSOA(
+	ctx context.Context, domain string) (*model.DNSSOA, error) {
+	prefix := fmt.Sprintf("resolve[SOA] %s with %s (%s)", domain, r.Network(), r.Address())
+	r.Logger.Debugf("%s...", prefix)
+	start := time.Now()
+	soa, err := r.Resolver.LookupSOA(ctx, domain)
+	elapsed := time.Since(start)
+	if err != nil {
+		r.Logger.Debugf("%s... %s in %s", prefix, err, elapsed)
+		return nil, err
+	}
+	r.Logger.Debugf("%s... %+v in %s", prefix, soa, elapsed)
+	return soa, nil
+}
+
+func (r *resolverLogger) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	prefix := fmt.Sprintf("resolve[PTR] %s with %s (%s)", address, r.Network(), r.Address())
+	r.Logger.Debugf("%s...", prefix)
+	start := time.Now()
+	names, err := r.Resolver.LookupPTR(ctx, address)
+	elapsed := time.Since(start)
+	if err != nil {
+		r.Logger.Debugf("%s... %s in %s", prefix, err, elapsed)
+		return nil, err
+	}
+	r.Logger.Debugf("%s... %+v in %s", prefix, names, elapsed)
+	return names, nil
+}
+
+func (r *resolverLogger) LookupSRV(
+	ctx context.Context, domain string) ([]*net.SRV, error) {
+	prefix := fmt.Sprintf("resolve[SRV] %s with %s (%s)", domain, r.Network(), r.Address())
+	r.Logger.Debugf("%s...", prefix)
+	start := time.Now()
+	srv, err := r.Resolver.LookupSRV(ctx, domain)
+	elapsed := time.Since(start)
+	if err != nil {
+		r.Logger.Debugf("%s... %s in %s", prefix, err, elapsed)
+		return nil, err
+	}
+	r.Logger.Debugf("%s... %+v in %s", prefix, srv, elapsed)
+	return srv, nil
+}
+
 // resolverIDNA supports resolving Internationalized Domain Names.
 //
 // See RFC3492 for more information.
@@ -279,6 +427,57 @@ func (r *resolverIDNA) LookupNS(
 	return r.Resolver.LookupNS(ctx, host)
 }
 
+func (r *resolverIDNA) LookupTXT(
+	ctx context.Context, domain string) ([]string, error) {
+	host, err := idna.ToASCII(domain)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolver.LookupTXT(ctx, host)
+}
+
+func (r *resolverIDNA) LookupMX(
+	ctx context.Context, domain string) ([]*net.MX, error) {
+	host, err := idna.ToASCII(domain)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolver.LookupMX(ctx, host)
+}
+
+func (r *resolverIDNA) LookupCNAME(
+	ctx context.Context, domain string) (string, error) {
+	host, err := idna.ToASCII(domain)
+	if err != nil {
+		return "", err
+	}
+	return r.Resolver.LookupCNAME(ctx, host)
+}
+
+func (r *resolverIDNA) LookupSOA(
+	ctx context.Context, domain string) (*model.DNSSOA, error) {
+	host, err := idna.ToASCII(domain)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolver.LookupSOA(ctx, host)
+}
+
+// LookupPTR forwards the address as-is because it is not a domain name.
+func (r *resolverIDNA) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	return r.Resolver.LookupPTR(ctx, address)
+}
+
+func (r *resolverIDNA) LookupSRV(
+	ctx context.Context, domain string) ([]*net.SRV, error) {
+	host, err := idna.ToASCII(domain)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolver.LookupSRV(ctx, host)
+}
+
 // resolverShortCircuitIPAddr recognizes when the input hostname is an
 // IP address and returns it immediately to the caller.
 type resolverShortCircuitIPAddr struct {
@@ -323,6 +522,10 @@ func (r *resolverShortCircuitIPAddr) CloseIdleConnections() {
 // function that only works with domain names.
 var ErrDNSIPAddress = errors.New("ooresolver: expected domain, found IP address")
 
+// ErrDNSExpectedIPAddress indicates that you passed a domain name to
+// a DNS function that only works with IP addresses (i.e., LookupPTR).
+var ErrDNSExpectedIPAddress = errors.New("ooresolver: expected IP address, found domain")
+
 func (r *resolverShortCircuitIPAddr) LookupNS(
 	ctx context.Context, hostname string) ([]*net.NS, error) {
 	if net.ParseIP(hostname) != nil {
@@ -331,6 +534,55 @@ func (r *resolverShortCircuitIPAddr) LookupNS(
 	return r.Resolver.LookupNS(ctx, hostname)
 }
 
+func (r *resolverShortCircuitIPAddr) LookupTXT(
+	ctx context.Context, hostname string) ([]string, error) {
+	if net.ParseIP(hostname) != nil {
+		return nil, ErrDNSIPAddress
+	}
+	return r.Resolver.LookupTXT(ctx, hostname)
+}
+
+func (r *resolverShortCircuitIPAddr) LookupMX(
+	ctx context.Context, hostname string) ([]*net.MX, error) {
+	if net.ParseIP(hostname) != nil {
+		return nil, ErrDNSIPAddress
+	}
+	return r.Resolver.LookupMX(ctx, hostname)
+}
+
+func (r *resolverShortCircuitIPAddr) LookupCNAME(
+	ctx context.Context, hostname string) (string, error) {
+	if net.ParseIP(hostname) != nil {
+		return "", ErrDNSIPAddress
+	}
+	return r.Resolver.LookupCNAME(ctx, hostname)
+}
+
+func (r *resolverShortCircuitIPAddr) LookupSOA(
+	ctx context.Context, hostname string) (*model.DNSSOA, error) {
+	if net.ParseIP(hostname) != nil {
+		return nil, ErrDNSIPAddress
+	}
+	return r.Resolver.LookupSOA(ctx, hostname)
+}
+
+// LookupPTR is the only lookup method that requires an IP address.
+func (r *resolverShortCircuitIPAddr) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	if net.ParseIP(address) == nil {
+		return nil, ErrDNSExpectedIPAddress
+	}
+	return r.Resolver.LookupPTR(ctx, address)
+}
+
+func (r *resolverShortCircuitIPAddr) LookupSRV(
+	ctx context.Context, hostname string) ([]*net.SRV, error) {
+	if net.ParseIP(hostname) != nil {
+		return nil, ErrDNSIPAddress
+	}
+	return r.Resolver.LookupSRV(ctx, hostname)
+}
+
 // IsIPv6 returns true if the given candidate is a valid IP address
 // representation and such representation is IPv6.
 func IsIPv6(candidate string) (bool, error) {
@@ -383,6 +635,36 @@ func (r *NullResolver) LookupNS(
 	return nil, ErrNoResolver
 }
 
+func (r *NullResolver) LookupTXT(
+	ctx context.Context, domain string) ([]string, error) {
+	return nil, ErrNoResolver
+}
+
+func (r *NullResolver) LookupMX(
+	ctx context.Context, domain string) ([]*net.MX, error) {
+	return nil, ErrNoResolver
+}
+
+func (r *NullResolver) LookupCNAME(
+	ctx context.Context, domain string) (string, error) {
+	return "", ErrNoResolver
+}
+
+func (r *NullResolver) LookupSOA(
+	ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return nil, ErrNoResolver
+}
+
+func (r *NullResolver) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	return nil, ErrNoResolver
+}
+
+func (r *NullResolver) LookupSRV(
+	ctx context.Context, domain string) ([]*net.SRV, error) {
+	return nil, ErrNoResolver
+}
+
 // resolverErrWrapper is a Resolver that knows about wrapping errors.
 type resolverErrWrapper struct {
 	Resolver model.Resolver
@@ -427,3 +709,57 @@ func (r *resolverErrWrapper) LookupNS(
 	}
 	return out, nil
 }
+
+func (r *resolverErrWrapper) LookupTXT(
+	ctx context.Context, domain string) ([]string, error) {
+	out, err := r.Resolver.LookupTXT(ctx, domain)
+	if err != nil {
+		return nil, NewErrWrapper(ClassifyResolverError, ResolveOperation, err)
+	}
+	return out, nil
+}
+
+func (r *resolverErrWrapper) LookupMX(
+	ctx context.Context, domain string) ([]*net.MX, error) {
+	out, err := r.Resolver.LookupMX(ctx, domain)
+	if err != nil {
+		return nil, NewErrWrapper(ClassifyResolverError, ResolveOperation, err)
+	}
+	return out, nil
+}
+
+func (r *resolverErrWrapper) LookupCNAME(
+	ctx context.Context, domain string) (string, error) {
+	out, err := r.Resolver.LookupCNAME(ctx, domain)
+	if err != nil {
+		return "", NewErrWrapper(ClassifyResolverError, ResolveOperation, err)
+	}
+	return out, nil
+}
+
+func (r *resolverErrWrapper) LookupSOA(
+	ctx context.Context, domain string) (*model.DNSSOA, error) {
+	out, err := r.Resolver.LookupSOA(ctx, domain)
+	if err != nil {
+		return nil, NewErrWrapper(ClassifyResolverError, ResolveOperation, err)
+	}
+	return out, nil
+}
+
+func (r *resolverErrWrapper) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	out, err := r.Resolver.LookupPTR(ctx, address)
+	if err != nil {
+		return nil, NewErrWrapper(ClassifyResolverError, ResolveOperation, err)
+	}
+	return out, nil
+}
+
+func (r *resolverErrWrapper) LookupSRV(
+	ctx context.Context, domain string) ([]*net.SRV, error) {
+	out, err := r.Resolver.LookupSRV(ctx, domain)
+	if err != nil {
+		return nil, NewErrWrapper(ClassifyResolverError, ResolveOperation, err)
+	}
+	return out, nil
+}
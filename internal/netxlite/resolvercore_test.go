@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"net"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -88,6 +89,69 @@ func TestNewParallelDNSOverHTTPSResolver(t *testing.T) {
 	}
 }
 
+func TestNewParallelTCPResolver(t *testing.T) {
+	d := NewDialerWithoutResolver(log.Log)
+	resolver := NewParallelTCPResolver(log.Log, d, "1.1.1.1:53")
+	idna := resolver.(*resolverIDNA)
+	logger := idna.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*resolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverTCPTransport)
+	if dnsTxp.Address() != "1.1.1.1:53" {
+		t.Fatal("invalid address")
+	}
+	if dnsTxp.Network() != "tcp" {
+		t.Fatal("invalid network")
+	}
+}
+
+func TestNewParallelDNSOverTLSResolver(t *testing.T) {
+	d := NewTLSDialer(NewDialerWithoutResolver(log.Log), NewTLSHandshakerStdlib(log.Log))
+	resolver := NewParallelDNSOverTLSResolver(log.Log, d, "1.1.1.1:853")
+	idna := resolver.(*resolverIDNA)
+	logger := idna.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*resolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverTCPTransport)
+	if dnsTxp.Address() != "1.1.1.1:853" {
+		t.Fatal("invalid address")
+	}
+	if dnsTxp.Network() != "dot" {
+		t.Fatal("invalid network")
+	}
+}
+
+func TestNewParallelDNSOverHTTP3Resolver(t *testing.T) {
+	resolver := NewParallelDNSOverHTTP3Resolver(log.Log, "https://1.1.1.1/dns-query")
+	idna := resolver.(*resolverIDNA)
+	logger := idna.Resolver.(*resolverLogger)
+	if logger.Logger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	shortCircuit := logger.Resolver.(*resolverShortCircuitIPAddr)
+	errWrapper := shortCircuit.Resolver.(*resolverErrWrapper)
+	para := errWrapper.Resolver.(*ParallelResolver)
+	txp := para.Transport().(*dnsTransportErrWrapper)
+	dnsTxp := txp.DNSTransport.(*DNSOverHTTPSTransport)
+	if dnsTxp.Address() != "https://1.1.1.1/dns-query" {
+		t.Fatal("invalid address")
+	}
+	client := dnsTxp.Client.(*http.Client)
+	if client.Transport.(model.HTTPTransport).Network() != "udp" {
+		t.Fatal("expected an HTTP3 transport")
+	}
+}
+
 func TestResolverSystem(t *testing.T) {
 	t.Run("Network", func(t *testing.T) {
 		expected := "antani"
@@ -1175,3 +1239,391 @@ func TestResolverErrWrapper(t *testing.T) {
 		})
 	})
 }
+
+// resolverRecordsLookup describes one of the LookupTXT, LookupMX, LookupCNAME,
+// LookupSOA, LookupPTR, and LookupSRV methods of a model.Resolver.
+type resolverRecordsLookup struct {
+	// name is the name of the method
+	name string
+
+	// input is a valid input for the method
+	input string
+
+	// lookup calls the method
+	lookup func(ctx context.Context, reso model.Resolver, input string) (any, error)
+
+	// expect is the value returned by newResolverRecordsMock on success
+	expect any
+}
+
+// resolverRecordsLookups is the list of all the resolverRecordsLookup.
+var resolverRecordsLookups = []resolverRecordsLookup{{
+	name:  "LookupTXT",
+	input: "dns.google",
+	lookup: func(ctx context.Context, reso model.Resolver, input string) (any, error) {
+		return reso.LookupTXT(ctx, input)
+	},
+	expect: []string{"v=spf1 -all"},
+}, {
+	name:  "LookupMX",
+	input: "dns.google",
+	lookup: func(ctx context.Context, reso model.Resolver, input string) (any, error) {
+		return reso.LookupMX(ctx, input)
+	},
+	expect: []*net.MX{{Host: "mx.dns.google.", Pref: 10}},
+}, {
+	name:  "LookupCNAME",
+	input: "dns.google",
+	lookup: func(ctx context.Context, reso model.Resolver, input string) (any, error) {
+		return reso.LookupCNAME(ctx, input)
+	},
+	expect: "www.dns.google.",
+}, {
+	name:  "LookupSOA",
+	input: "dns.google",
+	lookup: func(ctx context.Context, reso model.Resolver, input string) (any, error) {
+		return reso.LookupSOA(ctx, input)
+	},
+	expect: &model.DNSSOA{NS: "ns1.zdns.google.", MinTTL: 300},
+}, {
+	name:  "LookupPTR",
+	input: "8.8.8.8",
+	lookup: func(ctx context.Context, reso model.Resolver, input string) (any, error) {
+		return reso.LookupPTR(ctx, input)
+	},
+	expect: []string{"dns.google."},
+}, {
+	name:  "LookupSRV",
+	input: "_sip._udp.dns.google",
+	lookup: func(ctx context.Context, reso model.Resolver, input string) (any, error) {
+		return reso.LookupSRV(ctx, input)
+	},
+	expect: []*net.SRV{{Target: "sip.dns.google.", Port: 5060}},
+}}
+
+// newResolverRecordsMock returns a mocks.Resolver whose record lookup methods
+// save their input into *input and return either the values inside the
+// resolverRecordsLookups table or the given err, if not nil.
+func newResolverRecordsMock(input *string, err error) *mocks.Resolver {
+	return &mocks.Resolver{
+		MockLookupTXT: func(ctx context.Context, domain string) ([]string, error) {
+			*input = domain
+			if err != nil {
+				return nil, err
+			}
+			return resolverRecordsLookups[0].expect.([]string), nil
+		},
+		MockLookupMX: func(ctx context.Context, domain string) ([]*net.MX, error) {
+			*input = domain
+			if err != nil {
+				return nil, err
+			}
+			return resolverRecordsLookups[1].expect.([]*net.MX), nil
+		},
+		MockLookupCNAME: func(ctx context.Context, domain string) (string, error) {
+			*input = domain
+			if err != nil {
+				return "", err
+			}
+			return resolverRecordsLookups[2].expect.(string), nil
+		},
+		MockLookupSOA: func(ctx context.Context, domain string) (*model.DNSSOA, error) {
+			*input = domain
+			if err != nil {
+				return nil, err
+			}
+			return resolverRecordsLookups[3].expect.(*model.DNSSOA), nil
+		},
+		MockLookupPTR: func(ctx context.Context, address string) ([]string, error) {
+			*input = address
+			if err != nil {
+				return nil, err
+			}
+			return resolverRecordsLookups[4].expect.([]string), nil
+		},
+		MockLookupSRV: func(ctx context.Context, domain string) ([]*net.SRV, error) {
+			*input = domain
+			if err != nil {
+				return nil, err
+			}
+			return resolverRecordsLookups[5].expect.([]*net.SRV), nil
+		},
+		MockNetwork: func() string {
+			return "mocked"
+		},
+		MockAddress: func() string {
+			return ""
+		},
+	}
+}
+
+// resolverRecordsResultIsEmpty returns whether the result of a record lookup is empty.
+func resolverRecordsResultIsEmpty(out any) bool {
+	switch v := out.(type) {
+	case []string:
+		return len(v) <= 0
+	case []*net.MX:
+		return len(v) <= 0
+	case string:
+		return v == ""
+	case *model.DNSSOA:
+		return v == nil
+	case []*net.SRV:
+		return len(v) <= 0
+	default:
+		return false
+	}
+}
+
+func TestResolverLookupRecords(t *testing.T) {
+	t.Run("resolverSystem", func(t *testing.T) {
+		for _, rl := range resolverRecordsLookups {
+			if rl.name == "LookupCNAME" {
+				continue // tested below
+			}
+			t.Run(rl.name, func(t *testing.T) {
+				r := &resolverSystem{}
+				out, err := rl.lookup(context.Background(), r, rl.input)
+				if !errors.Is(err, ErrNoDNSTransport) {
+					t.Fatal("not the error we expected", err)
+				}
+				if !resolverRecordsResultIsEmpty(out) {
+					t.Fatal("expected empty result")
+				}
+			})
+		}
+
+		t.Run("LookupCNAME", func(t *testing.T) {
+			r := &resolverSystem{
+				t: &mocks.DNSTransport{
+					MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+						if query.Type() != dns.TypeANY {
+							return nil, errors.New("unexpected lookup type")
+						}
+						resp := &mocks.DNSResponse{
+							MockDecodeCNAME: func() (string, error) {
+								return "www.dns.google.", nil
+							},
+						}
+						return resp, nil
+					},
+					MockRequiresPadding: func() bool {
+						return false
+					},
+				},
+			}
+			cname, err := r.LookupCNAME(context.Background(), "dns.google")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cname != "www.dns.google." {
+				t.Fatal("unexpected cname", cname)
+			}
+		})
+	})
+
+	t.Run("resolverLogger", func(t *testing.T) {
+		for _, rl := range resolverRecordsLookups {
+			t.Run(rl.name+" with success", func(t *testing.T) {
+				var count int
+				lo := &mocks.Logger{
+					MockDebugf: func(format string, v ...interface{}) {
+						count++
+					},
+				}
+				var input string
+				r := &resolverLogger{
+					Logger:   lo,
+					Resolver: newResolverRecordsMock(&input, nil),
+				}
+				out, err := rl.lookup(context.Background(), r, rl.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(rl.expect, out); diff != "" {
+					t.Fatal(diff)
+				}
+				if count != 2 {
+					t.Fatal("unexpected count")
+				}
+			})
+
+			t.Run(rl.name+" with failure", func(t *testing.T) {
+				var count int
+				lo := &mocks.Logger{
+					MockDebugf: func(format string, v ...interface{}) {
+						count++
+					},
+				}
+				expected := errors.New("mocked error")
+				var input string
+				r := &resolverLogger{
+					Logger:   lo,
+					Resolver: newResolverRecordsMock(&input, expected),
+				}
+				out, err := rl.lookup(context.Background(), r, rl.input)
+				if !errors.Is(err, expected) {
+					t.Fatal("not the error we expected", err)
+				}
+				if !resolverRecordsResultIsEmpty(out) {
+					t.Fatal("expected empty result")
+				}
+				if count != 2 {
+					t.Fatal("unexpected count")
+				}
+			})
+		}
+	})
+
+	t.Run("resolverIDNA", func(t *testing.T) {
+		for _, rl := range resolverRecordsLookups {
+			if rl.name == "LookupPTR" {
+				continue // tested below
+			}
+
+			t.Run(rl.name+" with valid IDNA in input", func(t *testing.T) {
+				var input string
+				r := &resolverIDNA{Resolver: newResolverRecordsMock(&input, nil)}
+				out, err := rl.lookup(context.Background(), r, "яндекс.рф")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if input != "xn--d1acpjx3f.xn--p1ai" {
+					t.Fatal("passed invalid domain", input)
+				}
+				if diff := cmp.Diff(rl.expect, out); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+
+			t.Run(rl.name+" with invalid punycode", func(t *testing.T) {
+				var input string
+				r := &resolverIDNA{Resolver: newResolverRecordsMock(&input, errors.New("should not happen"))}
+				// See https://www.farsightsecurity.com/blog/txt-record/punycode-20180711/
+				out, err := rl.lookup(context.Background(), r, "xn--0000h")
+				if err == nil || !strings.HasPrefix(err.Error(), "idna: invalid label") {
+					t.Fatal("not the error we expected")
+				}
+				if !resolverRecordsResultIsEmpty(out) {
+					t.Fatal("expected empty result")
+				}
+			})
+		}
+
+		t.Run("LookupPTR forwards the address as-is", func(t *testing.T) {
+			var input string
+			r := &resolverIDNA{Resolver: newResolverRecordsMock(&input, nil)}
+			out, err := r.LookupPTR(context.Background(), "2001:4860:4860::8888")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if input != "2001:4860:4860::8888" {
+				t.Fatal("passed invalid address", input)
+			}
+			if diff := cmp.Diff([]string{"dns.google."}, out); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+
+	t.Run("resolverShortCircuitIPAddr", func(t *testing.T) {
+		for _, rl := range resolverRecordsLookups {
+			if rl.name == "LookupPTR" {
+				continue // tested below
+			}
+
+			for _, addr := range []string{"8.8.8.8", "::1"} {
+				t.Run(rl.name+" with "+addr, func(t *testing.T) {
+					var input string
+					r := &resolverShortCircuitIPAddr{Resolver: newResolverRecordsMock(&input, nil)}
+					out, err := rl.lookup(context.Background(), r, addr)
+					if !errors.Is(err, ErrDNSIPAddress) {
+						t.Fatal("unexpected error", err)
+					}
+					if !resolverRecordsResultIsEmpty(out) {
+						t.Fatal("expected empty result")
+					}
+				})
+			}
+
+			t.Run(rl.name+" with domain", func(t *testing.T) {
+				var input string
+				r := &resolverShortCircuitIPAddr{Resolver: newResolverRecordsMock(&input, nil)}
+				out, err := rl.lookup(context.Background(), r, rl.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(rl.expect, out); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		}
+
+		t.Run("LookupPTR with domain", func(t *testing.T) {
+			var input string
+			r := &resolverShortCircuitIPAddr{Resolver: newResolverRecordsMock(&input, nil)}
+			out, err := r.LookupPTR(context.Background(), "dns.google")
+			if !errors.Is(err, ErrDNSExpectedIPAddress) {
+				t.Fatal("unexpected error", err)
+			}
+			if len(out) > 0 {
+				t.Fatal("invalid result")
+			}
+		})
+
+		t.Run("LookupPTR with IP address", func(t *testing.T) {
+			var input string
+			r := &resolverShortCircuitIPAddr{Resolver: newResolverRecordsMock(&input, nil)}
+			out, err := r.LookupPTR(context.Background(), "8.8.8.8")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{"dns.google."}, out); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	})
+
+	t.Run("NullResolver", func(t *testing.T) {
+		for _, rl := range resolverRecordsLookups {
+			t.Run(rl.name, func(t *testing.T) {
+				r := &NullResolver{}
+				out, err := rl.lookup(context.Background(), r, rl.input)
+				if !errors.Is(err, ErrNoResolver) {
+					t.Fatal("not the error we expected", err)
+				}
+				if !resolverRecordsResultIsEmpty(out) {
+					t.Fatal("expected empty result")
+				}
+			})
+		}
+	})
+
+	t.Run("resolverErrWrapper", func(t *testing.T) {
+		for _, rl := range resolverRecordsLookups {
+			t.Run(rl.name+" on success", func(t *testing.T) {
+				var input string
+				reso := &resolverErrWrapper{Resolver: newResolverRecordsMock(&input, nil)}
+				out, err := rl.lookup(context.Background(), reso, rl.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(rl.expect, out); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+
+			t.Run(rl.name+" on failure", func(t *testing.T) {
+				var input string
+				reso := &resolverErrWrapper{Resolver: newResolverRecordsMock(&input, io.EOF)}
+				out, err := rl.lookup(context.Background(), reso, rl.input)
+				if err == nil || err.Error() != FailureEOFError {
+					t.Fatal("unexpected err", err)
+				}
+				if !resolverRecordsResultIsEmpty(out) {
+					t.Fatal("expected empty result")
+				}
+			})
+		}
+	})
+}
@@ -130,3 +130,39 @@ func (r *ParallelResolver) LookupNS(
 	}
 	return response.DecodeNS()
 }
+
+// LookupTXT implements Resolver.LookupTXT.
+func (r *ParallelResolver) LookupTXT(
+	ctx context.Context, domain string) ([]string, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeTXT, model.DNSResponse.DecodeTXT)
+}
+
+// LookupMX implements Resolver.LookupMX.
+func (r *ParallelResolver) LookupMX(
+	ctx context.Context, domain string) ([]*net.MX, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeMX, model.DNSResponse.DecodeMX)
+}
+
+// LookupCNAME implements Resolver.LookupCNAME.
+func (r *ParallelResolver) LookupCNAME(
+	ctx context.Context, domain string) (string, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeCNAME, model.DNSResponse.DecodeCNAME)
+}
+
+// LookupSOA implements Resolver.LookupSOA.
+func (r *ParallelResolver) LookupSOA(
+	ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeSOA, model.DNSResponse.DecodeSOA)
+}
+
+// LookupPTR implements Resolver.LookupPTR.
+func (r *ParallelResolver) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	return lookupPTR(ctx, r, r.Txp, address)
+}
+
+// LookupSRV implements Resolver.LookupSRV.
+func (r *ParallelResolver) LookupSRV(
+	ctx context.Context, domain string) ([]*net.SRV, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeSRV, model.DNSResponse.DecodeSRV)
+}
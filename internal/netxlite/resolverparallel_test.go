@@ -276,6 +276,12 @@ func TestParallelResolver(t *testing.T) {
 		})
 	})
 
+	t.Run("LookupTXT, LookupMX, LookupCNAME, LookupSOA, LookupPTR, LookupSRV", func(t *testing.T) {
+		testResolverLookupRecords(t, func(txp model.DNSTransport) model.Resolver {
+			return NewUnwrappedParallelResolver(txp)
+		})
+	})
+
 	t.Run("uses a context-injected custom trace (success case)", func(t *testing.T) {
 		var (
 			onLookupACalled        bool
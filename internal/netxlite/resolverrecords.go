@@ -0,0 +1,44 @@
+package netxlite
+
+//
+// Generic code to lookup DNS records using a DNSTransport
+//
+
+import (
+	"context"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// lookupRecords sends a query for the given domain and qtype using txp and
+// uses decode to extract the records from the response. The reso argument is
+// the resolver on whose behalf we're performing the lookup, which we need
+// to pass to the trace when notifying about the DNS round trip.
+func lookupRecords[T any](ctx context.Context, reso model.Resolver, txp model.DNSTransport,
+	domain string, qtype uint16, decode func(model.DNSResponse) (T, error)) (T, error) {
+	encoder := &DNSEncoderMiekg{}
+	trace := ContextTraceOrDefault(ctx)
+	query := encoder.Encode(domain, qtype, txp.RequiresPadding())
+	started := trace.TimeNow()
+	response, err := txp.RoundTrip(ctx, query)
+	finished := trace.TimeNow()
+	if err != nil {
+		trace.OnDNSRoundTripForLookupRecords(started, reso, query, response, err, finished)
+		var zero T
+		return zero, err
+	}
+	records, err := decode(response)
+	trace.OnDNSRoundTripForLookupRecords(started, reso, query, response, err, finished)
+	return records, err
+}
+
+// lookupPTR is like lookupRecords but performs a reverse lookup of the given IP address.
+func lookupPTR(ctx context.Context, reso model.Resolver,
+	txp model.DNSTransport, address string) ([]string, error) {
+	domain, err := dns.ReverseAddr(address)
+	if err != nil {
+		return nil, ErrDNSExpectedIPAddress
+	}
+	return lookupRecords(ctx, reso, txp, domain, dns.TypePTR, model.DNSResponse.DecodePTR)
+}
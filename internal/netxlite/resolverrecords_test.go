@@ -0,0 +1,283 @@
+package netxlite
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/testingx"
+)
+
+func TestLookupRecords(t *testing.T) {
+	t.Run("for round-trip error", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		var (
+			called   bool
+			gotErr   error
+			gotQtype uint16
+		)
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				return nil, expected
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		tx := &mocks.Trace{
+			MockTimeNow: testingx.NewTimeDeterministic(time.Now()).Now,
+			MockOnDNSRoundTripForLookupRecords: func(started time.Time, reso model.Resolver, query model.DNSQuery,
+				response model.DNSResponse, err error, finished time.Time) {
+				called = true
+				gotErr = err
+				gotQtype = query.Type()
+			},
+		}
+		ctx := ContextWithTrace(context.Background(), tx)
+		reso := NewUnwrappedParallelResolver(txp)
+		txt, err := lookupRecords(ctx, reso, txp, "example.com", dns.TypeTXT, model.DNSResponse.DecodeTXT)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if txt != nil {
+			t.Fatal("unexpected result")
+		}
+		if !called {
+			t.Fatal("trace not called")
+		}
+		if !errors.Is(gotErr, expected) {
+			t.Fatal("unexpected error in trace", gotErr)
+		}
+		if gotQtype != dns.TypeTXT {
+			t.Fatal("unexpected query type", gotQtype)
+		}
+	})
+
+	t.Run("for decode error", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		var gotErr error
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				response := &mocks.DNSResponse{
+					MockDecodeTXT: func() ([]string, error) {
+						return nil, expected
+					},
+				}
+				return response, nil
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		tx := &mocks.Trace{
+			MockTimeNow: testingx.NewTimeDeterministic(time.Now()).Now,
+			MockOnDNSRoundTripForLookupRecords: func(started time.Time, reso model.Resolver, query model.DNSQuery,
+				response model.DNSResponse, err error, finished time.Time) {
+				gotErr = err
+			},
+		}
+		ctx := ContextWithTrace(context.Background(), tx)
+		reso := NewUnwrappedParallelResolver(txp)
+		txt, err := lookupRecords(ctx, reso, txp, "example.com", dns.TypeTXT, model.DNSResponse.DecodeTXT)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if txt != nil {
+			t.Fatal("unexpected result")
+		}
+		if !errors.Is(gotErr, expected) {
+			t.Fatal("unexpected error in trace", gotErr)
+		}
+	})
+
+	t.Run("for success", func(t *testing.T) {
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				response := &mocks.DNSResponse{
+					MockDecodeCNAME: func() (string, error) {
+						return "www.example.com.", nil
+					},
+				}
+				return response, nil
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		reso := NewUnwrappedParallelResolver(txp)
+		cname, err := lookupRecords(context.Background(), reso, txp,
+			"example.com", dns.TypeCNAME, model.DNSResponse.DecodeCNAME)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cname != "www.example.com." {
+			t.Fatal("unexpected result", cname)
+		}
+	})
+}
+
+func TestLookupPTR(t *testing.T) {
+	t.Run("with a domain name", func(t *testing.T) {
+		txp := &mocks.DNSTransport{}
+		reso := NewUnwrappedParallelResolver(txp)
+		names, err := lookupPTR(context.Background(), reso, txp, "dns.google")
+		if !errors.Is(err, ErrDNSExpectedIPAddress) {
+			t.Fatal("unexpected err", err)
+		}
+		if names != nil {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("with an IP address", func(t *testing.T) {
+		var (
+			gotDomain string
+			gotQtype  uint16
+		)
+		txp := &mocks.DNSTransport{
+			MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+				gotDomain = query.Domain()
+				gotQtype = query.Type()
+				response := &mocks.DNSResponse{
+					MockDecodePTR: func() ([]string, error) {
+						return []string{"dns.google."}, nil
+					},
+				}
+				return response, nil
+			},
+			MockRequiresPadding: func() bool {
+				return false
+			},
+		}
+		reso := NewUnwrappedParallelResolver(txp)
+		names, err := lookupPTR(context.Background(), reso, txp, "8.8.8.8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != 1 || names[0] != "dns.google." {
+			t.Fatal("unexpected result", names)
+		}
+		if gotDomain != "8.8.8.8.in-addr.arpa." {
+			t.Fatal("unexpected domain", gotDomain)
+		}
+		if gotQtype != dns.TypePTR {
+			t.Fatal("unexpected query type", gotQtype)
+		}
+	})
+}
+
+// testResolverLookupRecords checks that the LookupTXT, LookupMX, LookupCNAME,
+// LookupSOA, LookupPTR, and LookupSRV methods of the resolver constructed using
+// newResolver send the correct query type and return the decoded records.
+func testResolverLookupRecords(t *testing.T, newResolver func(txp model.DNSTransport) model.Resolver) {
+	expectedTXT := []string{"v=spf1 -all"}
+	expectedMX := []*net.MX{{Host: "mx.example.com.", Pref: 10}}
+	expectedCNAME := "www.example.com."
+	expectedSOA := &model.DNSSOA{NS: "ns1.example.com.", MinTTL: 300}
+	expectedPTR := []string{"dns.google."}
+	expectedSRV := []*net.SRV{{Target: "sip.example.com.", Port: 5060}}
+
+	var gotQtype uint16
+	txp := &mocks.DNSTransport{
+		MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+			gotQtype = query.Type()
+			response := &mocks.DNSResponse{
+				MockDecodeTXT: func() ([]string, error) {
+					return expectedTXT, nil
+				},
+				MockDecodeMX: func() ([]*net.MX, error) {
+					return expectedMX, nil
+				},
+				MockDecodeCNAME: func() (string, error) {
+					return expectedCNAME, nil
+				},
+				MockDecodeSOA: func() (*model.DNSSOA, error) {
+					return expectedSOA, nil
+				},
+				MockDecodePTR: func() ([]string, error) {
+					return expectedPTR, nil
+				},
+				MockDecodeSRV: func() ([]*net.SRV, error) {
+					return expectedSRV, nil
+				},
+			}
+			return response, nil
+		},
+		MockRequiresPadding: func() bool {
+			return false
+		},
+	}
+	reso := newResolver(txp)
+	ctx := context.Background()
+
+	var testcases = []struct {
+		name   string
+		qtype  uint16
+		lookup func() (any, error)
+		expect any
+	}{{
+		name:  "LookupTXT",
+		qtype: dns.TypeTXT,
+		lookup: func() (any, error) {
+			return reso.LookupTXT(ctx, "example.com")
+		},
+		expect: expectedTXT,
+	}, {
+		name:  "LookupMX",
+		qtype: dns.TypeMX,
+		lookup: func() (any, error) {
+			return reso.LookupMX(ctx, "example.com")
+		},
+		expect: expectedMX,
+	}, {
+		name:  "LookupCNAME",
+		qtype: dns.TypeCNAME,
+		lookup: func() (any, error) {
+			return reso.LookupCNAME(ctx, "example.com")
+		},
+		expect: expectedCNAME,
+	}, {
+		name:  "LookupSOA",
+		qtype: dns.TypeSOA,
+		lookup: func() (any, error) {
+			return reso.LookupSOA(ctx, "example.com")
+		},
+		expect: expectedSOA,
+	}, {
+		name:  "LookupPTR",
+		qtype: dns.TypePTR,
+		lookup: func() (any, error) {
+			return reso.LookupPTR(ctx, "8.8.8.8")
+		},
+		expect: expectedPTR,
+	}, {
+		name:  "LookupSRV",
+		qtype: dns.TypeSRV,
+		lookup: func() (any, error) {
+			return reso.LookupSRV(ctx, "_sip._udp.example.com")
+		},
+		expect: expectedSRV,
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotQtype = 0
+			out, err := tc.lookup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotQtype != tc.qtype {
+				t.Fatal("unexpected query type", gotQtype)
+			}
+			if diff := cmp.Diff(tc.expect, out); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
@@ -146,3 +146,39 @@ func (r *SerialResolver) LookupNS(
 	}
 	return response.DecodeNS()
 }
+
+// LookupTXT implements Resolver.LookupTXT.
+func (r *SerialResolver) LookupTXT(
+	ctx context.Context, domain string) ([]string, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeTXT, model.DNSResponse.DecodeTXT)
+}
+
+// LookupMX implements Resolver.LookupMX.
+func (r *SerialResolver) LookupMX(
+	ctx context.Context, domain string) ([]*net.MX, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeMX, model.DNSResponse.DecodeMX)
+}
+
+// LookupCNAME implements Resolver.LookupCNAME.
+func (r *SerialResolver) LookupCNAME(
+	ctx context.Context, domain string) (string, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeCNAME, model.DNSResponse.DecodeCNAME)
+}
+
+// LookupSOA implements Resolver.LookupSOA.
+func (r *SerialResolver) LookupSOA(
+	ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeSOA, model.DNSResponse.DecodeSOA)
+}
+
+// LookupPTR implements Resolver.LookupPTR.
+func (r *SerialResolver) LookupPTR(
+	ctx context.Context, address string) ([]string, error) {
+	return lookupPTR(ctx, r, r.Txp, address)
+}
+
+// LookupSRV implements Resolver.LookupSRV.
+func (r *SerialResolver) LookupSRV(
+	ctx context.Context, domain string) ([]*net.SRV, error) {
+	return lookupRecords(ctx, r, r.Txp, domain, dns.TypeSRV, model.DNSResponse.DecodeSRV)
+}
@@ -291,4 +291,10 @@ func TestSerialResolver(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("LookupTXT, LookupMX, LookupCNAME, LookupSOA, LookupPTR, LookupSRV", func(t *testing.T) {
+		testResolverLookupRecords(t, func(txp model.DNSTransport) model.Resolver {
+			return NewUnwrappedSerialResolver(txp)
+		})
+	})
 }
@@ -0,0 +1,357 @@
+package netxlite
+
+//
+// TTL-aware and bounded caching resolver
+//
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// CachingResolverConfig contains the configuration for NewCachingResolver. The
+// zero value of this struct is valid and implies using the defaults.
+type CachingResolverConfig struct {
+	// DefaultTTL is the OPTIONAL TTL to use when we cannot obtain a TTL from the
+	// DNS responses (e.g., when using getaddrinfo). If zero, we use one minute.
+	DefaultTTL time.Duration
+
+	// MaxEntries is the OPTIONAL maximum number of entries in the cache. When
+	// the cache is full, we evict the least recently used entry. If zero, we
+	// allow the cache to contain at most 1024 entries.
+	MaxEntries int
+
+	// MaxTTL is the OPTIONAL maximum TTL of a cache entry, which we use to
+	// bound the TTLs we obtain from DNS responses. If zero, we use one hour.
+	MaxTTL time.Duration
+}
+
+const (
+	// cachingResolverDefaultTTL is the default CachingResolverConfig.DefaultTTL.
+	cachingResolverDefaultTTL = time.Minute
+
+	// cachingResolverDefaultMaxEntries is the default CachingResolverConfig.MaxEntries.
+	cachingResolverDefaultMaxEntries = 1024
+
+	// cachingResolverDefaultMaxTTL is the default CachingResolverConfig.MaxTTL.
+	cachingResolverDefaultMaxTTL = time.Hour
+)
+
+// NewCachingResolver wraps the given resolver with a resolver that caches the
+// results of all the lookup operations. The config argument is OPTIONAL and
+// a nil value implies using the default configuration.
+//
+// The returned resolver honours the TTLs of the DNS responses and uses negative
+// caching for NXDOMAIN responses, using the minimum of the SOA record's TTL and
+// MINIMUM fields as the TTL (see RFC2308). To this end, it observes the DNS round
+// trips using the context-based tracing (see ContextWithTrace). Hence, the TTLs
+// are only available when the underlying resolver uses a DNSTransport (e.g., it has
+// been created using NewUnwrappedParallelResolver) and nothing between this
+// resolver and the underlying resolver overrides the context's trace. Otherwise,
+// we use the config's DefaultTTL and we do not cache negative responses.
+func NewCachingResolver(reso model.Resolver, config *CachingResolverConfig) model.Resolver {
+	if config == nil {
+		config = &CachingResolverConfig{}
+	}
+	r := &ttlCacheResolver{
+		defaultTTL: config.DefaultTTL,
+		entries:    map[ttlCacheKey]*list.Element{},
+		lru:        list.New(),
+		maxEntries: config.MaxEntries,
+		maxTTL:     config.MaxTTL,
+		mu:         sync.Mutex{},
+		resolver:   reso,
+		timeNow:    time.Now,
+	}
+	if r.defaultTTL <= 0 {
+		r.defaultTTL = cachingResolverDefaultTTL
+	}
+	if r.maxEntries <= 0 {
+		r.maxEntries = cachingResolverDefaultMaxEntries
+	}
+	if r.maxTTL <= 0 {
+		r.maxTTL = cachingResolverDefaultMaxTTL
+	}
+	return r
+}
+
+// ttlCacheResolver is the resolver returned by NewCachingResolver.
+type ttlCacheResolver struct {
+	// defaultTTL is the TTL to use when we don't know the TTL.
+	defaultTTL time.Duration
+
+	// entries maps a key to the corresponding element of lru.
+	entries map[ttlCacheKey]*list.Element
+
+	// lru contains *ttlCacheEntry sorted from most to least recently used.
+	lru *list.List
+
+	// maxEntries is the maximum number of entries.
+	maxEntries int
+
+	// maxTTL is the maximum TTL.
+	maxTTL time.Duration
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// resolver is the underlying resolver.
+	resolver model.Resolver
+
+	// timeNow allows to mock time.Now in tests.
+	timeNow func() time.Time
+}
+
+// ttlCacheKey is the key of a ttlCacheResolver entry.
+type ttlCacheKey struct {
+	// lookup is the lookup type (e.g., "TXT", "A,AAAA").
+	lookup string
+
+	// name is the domain name or IP address we're resolving.
+	name string
+}
+
+// ttlCacheEntry is an entry of the ttlCacheResolver.
+type ttlCacheEntry struct {
+	// err is the cached error (only set for negative caching).
+	err error
+
+	// expiry is the time when this entry expires.
+	expiry time.Time
+
+	// key is the key of this entry.
+	key ttlCacheKey
+
+	// value is the cached value.
+	value any
+}
+
+var _ model.Resolver = &ttlCacheResolver{}
+
+// get returns the entry for the given key or nil, if the key is not
+// cached or expired. A successful get marks the entry as recently used.
+func (r *ttlCacheResolver) get(key ttlCacheKey) *ttlCacheEntry {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+	elem, found := r.entries[key]
+	if !found {
+		return nil
+	}
+	entry := elem.Value.(*ttlCacheEntry)
+	if !r.timeNow().Before(entry.expiry) {
+		r.lru.Remove(elem)
+		delete(r.entries, key)
+		return nil
+	}
+	r.lru.MoveToFront(elem)
+	return entry
+}
+
+// set adds or replaces the entry for the given key, evicting the least
+// recently used entry if the cache is full. Nonpositive TTLs are ignored.
+func (r *ttlCacheResolver) set(key ttlCacheKey, value any, err error, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if ttl > r.maxTTL {
+		ttl = r.maxTTL
+	}
+	entry := &ttlCacheEntry{
+		err:    err,
+		expiry: r.timeNow().Add(ttl),
+		key:    key,
+		value:  value,
+	}
+	defer r.mu.Unlock()
+	r.mu.Lock()
+	if elem, found := r.entries[key]; found {
+		elem.Value = entry
+		r.lru.MoveToFront(elem)
+		return
+	}
+	r.entries[key] = r.lru.PushFront(entry)
+	for r.lru.Len() > r.maxEntries {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(*ttlCacheEntry).key)
+	}
+}
+
+// ttlCacheLookup implements caching for the lookup function, which is one
+// of the lookup methods of the underlying resolver.
+func ttlCacheLookup[T any](ctx context.Context, r *ttlCacheResolver, lookup string,
+	name string, fx func(ctx context.Context, name string) (T, error)) (T, error) {
+	key := ttlCacheKey{lookup: lookup, name: name}
+	if entry := r.get(key); entry != nil {
+		value, _ := entry.value.(T)
+		return value, entry.err
+	}
+	tracer := &ttlCacheTrace{Trace: ContextTraceOrDefault(ctx)}
+	value, err := fx(ContextWithTrace(ctx, tracer), name)
+	switch {
+	case err == nil:
+		r.set(key, value, nil, tracer.positiveTTL(r.defaultTTL))
+	case err.Error() == FailureDNSNXDOMAINError || errors.Is(err, ErrOODNSNoSuchHost):
+		var zero T
+		r.set(key, zero, err, tracer.negativeTTL())
+	}
+	return value, err
+}
+
+// LookupHost implements model.Resolver.LookupHost.
+func (r *ttlCacheResolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
+	return ttlCacheLookup(ctx, r, "A,AAAA", hostname, r.resolver.LookupHost)
+}
+
+// LookupHTTPS implements model.Resolver.LookupHTTPS.
+func (r *ttlCacheResolver) LookupHTTPS(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+	return ttlCacheLookup(ctx, r, "HTTPS", domain, r.resolver.LookupHTTPS)
+}
+
+// LookupNS implements model.Resolver.LookupNS.
+func (r *ttlCacheResolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, error) {
+	return ttlCacheLookup(ctx, r, "NS", domain, r.resolver.LookupNS)
+}
+
+// LookupTXT implements model.Resolver.LookupTXT.
+func (r *ttlCacheResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return ttlCacheLookup(ctx, r, "TXT", domain, r.resolver.LookupTXT)
+}
+
+// LookupMX implements model.Resolver.LookupMX.
+func (r *ttlCacheResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return ttlCacheLookup(ctx, r, "MX", domain, r.resolver.LookupMX)
+}
+
+// LookupCNAME implements model.Resolver.LookupCNAME.
+func (r *ttlCacheResolver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return ttlCacheLookup(ctx, r, "CNAME", domain, r.resolver.LookupCNAME)
+}
+
+// LookupSOA implements model.Resolver.LookupSOA.
+func (r *ttlCacheResolver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return ttlCacheLookup(ctx, r, "SOA", domain, r.resolver.LookupSOA)
+}
+
+// LookupPTR implements model.Resolver.LookupPTR.
+func (r *ttlCacheResolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return ttlCacheLookup(ctx, r, "PTR", address, r.resolver.LookupPTR)
+}
+
+// LookupSRV implements model.Resolver.LookupSRV.
+func (r *ttlCacheResolver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return ttlCacheLookup(ctx, r, "SRV", domain, r.resolver.LookupSRV)
+}
+
+// Address implements model.Resolver.Address.
+func (r *ttlCacheResolver) Address() string {
+	return r.resolver.Address()
+}
+
+// Network implements model.Resolver.Network.
+func (r *ttlCacheResolver) Network() string {
+	return r.resolver.Network()
+}
+
+// CloseIdleConnections implements model.Resolver.CloseIdleConnections.
+func (r *ttlCacheResolver) CloseIdleConnections() {
+	r.resolver.CloseIdleConnections()
+}
+
+// ttlCacheTrace is a model.Trace that observes the DNS round trips to
+// compute the TTLs and forwards all events to the wrapped model.Trace.
+type ttlCacheTrace struct {
+	model.Trace
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// negative is the negative caching TTL, if any.
+	negative *time.Duration
+
+	// positive is the minimum TTL of the answers, if any.
+	positive *time.Duration
+}
+
+// OnDNSRoundTripForLookupHost implements model.Trace.OnDNSRoundTripForLookupHost.
+func (tx *ttlCacheTrace) OnDNSRoundTripForLookupHost(started time.Time, reso model.Resolver, query model.DNSQuery,
+	response model.DNSResponse, addrs []string, err error, finished time.Time) {
+	tx.observe(response)
+	tx.Trace.OnDNSRoundTripForLookupHost(started, reso, query, response, addrs, err, finished)
+}
+
+// OnDNSRoundTripForLookupRecords implements model.Trace.OnDNSRoundTripForLookupRecords.
+func (tx *ttlCacheTrace) OnDNSRoundTripForLookupRecords(started time.Time, reso model.Resolver,
+	query model.DNSQuery, response model.DNSResponse, err error, finished time.Time) {
+	tx.observe(response)
+	tx.Trace.OnDNSRoundTripForLookupRecords(started, reso, query, response, err, finished)
+}
+
+// observe updates the TTLs using the given response, if possible.
+func (tx *ttlCacheTrace) observe(response model.DNSResponse) {
+	if response == nil {
+		return
+	}
+	rawResponse := response.Bytes()
+	if len(rawResponse) <= 0 {
+		return // e.g., getaddrinfo
+	}
+	msg := &dns.Msg{}
+	if err := msg.Unpack(rawResponse); err != nil {
+		return
+	}
+	defer tx.mu.Unlock()
+	tx.mu.Lock()
+	if msg.Rcode == dns.RcodeNameError {
+		for _, rr := range msg.Ns {
+			if soa, okay := rr.(*dns.SOA); okay {
+				ttl := time.Duration(soa.Hdr.Ttl) * time.Second
+				if minttl := time.Duration(soa.Minttl) * time.Second; minttl < ttl {
+					ttl = minttl
+				}
+				tx.negative = ttlCacheMinDuration(tx.negative, ttl)
+			}
+		}
+		return
+	}
+	for _, rr := range msg.Answer {
+		ttl := time.Duration(rr.Header().Ttl) * time.Second
+		tx.positive = ttlCacheMinDuration(tx.positive, ttl)
+	}
+}
+
+// positiveTTL returns the TTL for caching a successful lookup.
+func (tx *ttlCacheTrace) positiveTTL(defaultTTL time.Duration) time.Duration {
+	defer tx.mu.Unlock()
+	tx.mu.Lock()
+	if tx.positive == nil {
+		return defaultTTL
+	}
+	return *tx.positive
+}
+
+// negativeTTL returns the TTL for caching an NXDOMAIN lookup, which is
+// zero (i.e., do not cache) when we have not seen any SOA record.
+func (tx *ttlCacheTrace) negativeTTL() time.Duration {
+	defer tx.mu.Unlock()
+	tx.mu.Lock()
+	if tx.negative == nil {
+		return 0
+	}
+	return *tx.negative
+}
+
+// ttlCacheMinDuration returns the minimum between *current (if not nil) and value.
+func ttlCacheMinDuration(current *time.Duration, value time.Duration) *time.Duration {
+	if current != nil && *current <= value {
+		return current
+	}
+	return &value
+}
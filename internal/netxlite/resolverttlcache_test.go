@@ -0,0 +1,451 @@
+package netxlite
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// ttlCacheTestTransport returns a DNSTransport that counts the round trips
+// and uses the reply function to generate raw responses for the raw queries.
+func ttlCacheTestTransport(count *int, reply func(query *dns.Msg) *dns.Msg) model.DNSTransport {
+	return &mocks.DNSTransport{
+		MockRoundTrip: func(ctx context.Context, query model.DNSQuery) (model.DNSResponse, error) {
+			*count++
+			rawQuery, err := query.Bytes()
+			runtimex.PanicOnError(err, "query.Bytes failed")
+			msg := &dns.Msg{}
+			err = msg.Unpack(rawQuery)
+			runtimex.PanicOnError(err, "msg.Unpack failed")
+			rawResponse, err := reply(msg).Pack()
+			runtimex.PanicOnError(err, "reply.Pack failed")
+			return (&DNSDecoderMiekg{}).DecodeResponse(rawResponse, query)
+		},
+		MockRequiresPadding: func() bool {
+			return false
+		},
+		MockNetwork: func() string {
+			return "mocked"
+		},
+		MockAddress: func() string {
+			return ""
+		},
+	}
+}
+
+// ttlCacheTestReplyA returns a reply function for ttlCacheTestTransport
+// that responds to A queries with 8.8.8.8 and the given TTL.
+func ttlCacheTestReplyA(ttl uint32) func(query *dns.Msg) *dns.Msg {
+	return func(query *dns.Msg) *dns.Msg {
+		reply := &dns.Msg{}
+		reply.SetReply(query)
+		if query.Question[0].Qtype == dns.TypeA {
+			reply.Answer = append(reply.Answer, &dns.A{
+				Hdr: dns.RR_Header{
+					Name:   query.Question[0].Name,
+					Rrtype: dns.TypeA,
+					Class:  dns.ClassINET,
+					Ttl:    ttl,
+				},
+				A: net.IPv4(8, 8, 8, 8),
+			})
+		}
+		return reply
+	}
+}
+
+// ttlCacheTestReplyNXDOMAIN returns a reply function for ttlCacheTestTransport
+// that responds with NXDOMAIN and, if soa is not nil, includes the SOA record.
+func ttlCacheTestReplyNXDOMAIN(soa *dns.SOA) func(query *dns.Msg) *dns.Msg {
+	return func(query *dns.Msg) *dns.Msg {
+		reply := &dns.Msg{}
+		reply.SetRcode(query, dns.RcodeNameError)
+		if soa != nil {
+			reply.Ns = append(reply.Ns, soa)
+		}
+		return reply
+	}
+}
+
+func TestNewCachingResolver(t *testing.T) {
+	t.Run("with nil config", func(t *testing.T) {
+		underlying := &mocks.Resolver{}
+		reso := NewCachingResolver(underlying, nil).(*ttlCacheResolver)
+		if reso.resolver != underlying {
+			t.Fatal("did not wrap correctly")
+		}
+		if reso.defaultTTL != cachingResolverDefaultTTL {
+			t.Fatal("unexpected defaultTTL")
+		}
+		if reso.maxEntries != cachingResolverDefaultMaxEntries {
+			t.Fatal("unexpected maxEntries")
+		}
+		if reso.maxTTL != cachingResolverDefaultMaxTTL {
+			t.Fatal("unexpected maxTTL")
+		}
+	})
+
+	t.Run("with custom config", func(t *testing.T) {
+		config := &CachingResolverConfig{
+			DefaultTTL: 10 * time.Second,
+			MaxEntries: 7,
+			MaxTTL:     time.Minute,
+		}
+		reso := NewCachingResolver(&mocks.Resolver{}, config).(*ttlCacheResolver)
+		if reso.defaultTTL != config.DefaultTTL {
+			t.Fatal("unexpected defaultTTL")
+		}
+		if reso.maxEntries != config.MaxEntries {
+			t.Fatal("unexpected maxEntries")
+		}
+		if reso.maxTTL != config.MaxTTL {
+			t.Fatal("unexpected maxTTL")
+		}
+	})
+}
+
+func TestTTLCacheResolver(t *testing.T) {
+	t.Run("honours the TTL of the DNS response", func(t *testing.T) {
+		var count int
+		txp := ttlCacheTestTransport(&count, ttlCacheTestReplyA(30))
+		reso := NewCachingResolver(NewUnwrappedParallelResolver(txp), nil).(*ttlCacheResolver)
+		now := time.Now()
+		reso.timeNow = func() time.Time {
+			return now
+		}
+		for i := 0; i < 2; i++ {
+			addrs, err := reso.LookupHost(context.Background(), "dns.google")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{"8.8.8.8"}, addrs); diff != "" {
+				t.Fatal(diff)
+			}
+		}
+		if count != 2 { // one A and one AAAA query
+			t.Fatal("expected the second lookup to hit the cache", count)
+		}
+		now = now.Add(29 * time.Second)
+		if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Fatal("expected the entry not to be expired", count)
+		}
+		now = now.Add(time.Second)
+		if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		if count != 4 {
+			t.Fatal("expected the entry to be expired", count)
+		}
+	})
+
+	t.Run("bounds the TTL using MaxTTL", func(t *testing.T) {
+		var count int
+		txp := ttlCacheTestTransport(&count, ttlCacheTestReplyA(86400))
+		config := &CachingResolverConfig{MaxTTL: time.Minute}
+		reso := NewCachingResolver(NewUnwrappedParallelResolver(txp), config).(*ttlCacheResolver)
+		now := time.Now()
+		reso.timeNow = func() time.Time {
+			return now
+		}
+		if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+		if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		if count != 4 {
+			t.Fatal("expected the entry to be expired", count)
+		}
+	})
+
+	t.Run("does not cache responses with zero TTL", func(t *testing.T) {
+		var count int
+		txp := ttlCacheTestTransport(&count, ttlCacheTestReplyA(0))
+		reso := NewCachingResolver(NewUnwrappedParallelResolver(txp), nil)
+		for i := 0; i < 2; i++ {
+			if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if count != 4 {
+			t.Fatal("expected no caching", count)
+		}
+	})
+
+	t.Run("uses the DefaultTTL without DNS responses", func(t *testing.T) {
+		var count int
+		underlying := &mocks.Resolver{
+			MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+				count++
+				return []string{"8.8.8.8"}, nil
+			},
+		}
+		config := &CachingResolverConfig{DefaultTTL: 10 * time.Second}
+		reso := NewCachingResolver(underlying, config).(*ttlCacheResolver)
+		now := time.Now()
+		reso.timeNow = func() time.Time {
+			return now
+		}
+		if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(9 * time.Second)
+		if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatal("expected the entry not to be expired", count)
+		}
+		now = now.Add(time.Second)
+		if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Fatal("expected the entry to be expired", count)
+		}
+	})
+
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		var domains []string
+		underlying := &mocks.Resolver{
+			MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+				domains = append(domains, domain)
+				return []string{"8.8.8.8"}, nil
+			},
+		}
+		config := &CachingResolverConfig{MaxEntries: 2}
+		reso := NewCachingResolver(underlying, config)
+		ctx := context.Background()
+		for _, domain := range []string{"a.org", "b.org", "a.org", "c.org", "a.org", "b.org"} {
+			if _, err := reso.LookupHost(ctx, domain); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// "b.org" is evicted when we add "c.org" because "a.org" has been used more recently
+		expect := []string{"a.org", "b.org", "c.org", "b.org"}
+		if diff := cmp.Diff(expect, domains); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("uses negative caching for NXDOMAIN with SOA", func(t *testing.T) {
+		var count int
+		soa := &dns.SOA{
+			Hdr: dns.RR_Header{
+				Name:   "google.",
+				Rrtype: dns.TypeSOA,
+				Class:  dns.ClassINET,
+				Ttl:    600,
+			},
+			Ns:     "ns1.zdns.google.",
+			Mbox:   "cloud-dns-hostmaster.google.",
+			Minttl: 30,
+		}
+		txp := ttlCacheTestTransport(&count, ttlCacheTestReplyNXDOMAIN(soa))
+		reso := NewCachingResolver(
+			WrapResolver(model.DiscardLogger, NewUnwrappedParallelResolver(txp)), nil).(*ttlCacheResolver)
+		now := time.Now()
+		reso.timeNow = func() time.Time {
+			return now
+		}
+		for i := 0; i < 2; i++ {
+			txt, err := reso.LookupTXT(context.Background(), "nonexistent.google")
+			if err == nil || err.Error() != FailureDNSNXDOMAINError {
+				t.Fatal("unexpected err", err)
+			}
+			if txt != nil {
+				t.Fatal("expected nil result")
+			}
+		}
+		if count != 1 {
+			t.Fatal("expected the second lookup to hit the cache", count)
+		}
+		now = now.Add(30 * time.Second) // SOA MINIMUM is lower than the SOA TTL
+		if _, err := reso.LookupTXT(context.Background(), "nonexistent.google"); err == nil {
+			t.Fatal("expected an error")
+		}
+		if count != 2 {
+			t.Fatal("expected the entry to be expired", count)
+		}
+	})
+
+	t.Run("does not use negative caching for NXDOMAIN without SOA", func(t *testing.T) {
+		var count int
+		txp := ttlCacheTestTransport(&count, ttlCacheTestReplyNXDOMAIN(nil))
+		reso := NewCachingResolver(WrapResolver(model.DiscardLogger, NewUnwrappedParallelResolver(txp)), nil)
+		for i := 0; i < 2; i++ {
+			if _, err := reso.LookupTXT(context.Background(), "nonexistent.google"); err == nil {
+				t.Fatal("expected an error")
+			}
+		}
+		if count != 2 {
+			t.Fatal("expected no caching", count)
+		}
+	})
+
+	t.Run("does not cache other errors", func(t *testing.T) {
+		var count int
+		expected := errors.New("mocked error")
+		underlying := &mocks.Resolver{
+			MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+				count++
+				return nil, expected
+			},
+		}
+		reso := NewCachingResolver(underlying, nil)
+		for i := 0; i < 2; i++ {
+			if _, err := reso.LookupHost(context.Background(), "dns.google"); !errors.Is(err, expected) {
+				t.Fatal("unexpected err", err)
+			}
+		}
+		if count != 2 {
+			t.Fatal("expected no caching", count)
+		}
+	})
+
+	t.Run("forwards events to the context's trace", func(t *testing.T) {
+		var count, events int
+		txp := ttlCacheTestTransport(&count, ttlCacheTestReplyA(30))
+		reso := NewCachingResolver(NewUnwrappedParallelResolver(txp), nil)
+		tx := &mocks.Trace{
+			MockTimeNow: time.Now,
+			MockOnDNSRoundTripForLookupHost: func(started time.Time, reso model.Resolver, query model.DNSQuery,
+				response model.DNSResponse, addrs []string, err error, finished time.Time) {
+				events++
+			},
+		}
+		ctx := ContextWithTrace(context.Background(), tx)
+		if _, err := reso.LookupHost(ctx, "dns.google"); err != nil {
+			t.Fatal(err)
+		}
+		if events != 2 {
+			t.Fatal("unexpected number of events", events)
+		}
+	})
+
+	t.Run("caches all the lookup types", func(t *testing.T) {
+		for _, rl := range resolverRecordsLookups {
+			t.Run(rl.name, func(t *testing.T) {
+				var input string
+				underlying := newResolverRecordsMock(&input, nil)
+				reso := NewCachingResolver(underlying, nil)
+				out, err := rl.lookup(context.Background(), reso, rl.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(rl.expect, out); diff != "" {
+					t.Fatal(diff)
+				}
+				input = ""
+				out, err = rl.lookup(context.Background(), reso, rl.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if input != "" {
+					t.Fatal("expected a cache hit")
+				}
+				if diff := cmp.Diff(rl.expect, out); diff != "" {
+					t.Fatal(diff)
+				}
+			})
+		}
+
+		t.Run("LookupHTTPS", func(t *testing.T) {
+			var count int
+			expected := &model.HTTPSSvc{ALPN: []string{"h3"}}
+			underlying := &mocks.Resolver{
+				MockLookupHTTPS: func(ctx context.Context, domain string) (*model.HTTPSSvc, error) {
+					count++
+					return expected, nil
+				},
+			}
+			reso := NewCachingResolver(underlying, nil)
+			for i := 0; i < 2; i++ {
+				https, err := reso.LookupHTTPS(context.Background(), "dns.google")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if https != expected {
+					t.Fatal("unexpected result")
+				}
+			}
+			if count != 1 {
+				t.Fatal("expected a cache hit")
+			}
+		})
+
+		t.Run("LookupNS", func(t *testing.T) {
+			var count int
+			expected := []*net.NS{{Host: "ns1.zdns.google."}}
+			underlying := &mocks.Resolver{
+				MockLookupNS: func(ctx context.Context, domain string) ([]*net.NS, error) {
+					count++
+					return expected, nil
+				},
+			}
+			reso := NewCachingResolver(underlying, nil)
+			for i := 0; i < 2; i++ {
+				ns, err := reso.LookupNS(context.Background(), "dns.google")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(expected, ns); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+			if count != 1 {
+				t.Fatal("expected a cache hit")
+			}
+		})
+	})
+
+	t.Run("Address", func(t *testing.T) {
+		underlying := &mocks.Resolver{
+			MockAddress: func() string {
+				return "x"
+			},
+		}
+		reso := NewCachingResolver(underlying, nil)
+		if reso.Address() != "x" {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		underlying := &mocks.Resolver{
+			MockNetwork: func() string {
+				return "x"
+			},
+		}
+		reso := NewCachingResolver(underlying, nil)
+		if reso.Network() != "x" {
+			t.Fatal("unexpected result")
+		}
+	})
+
+	t.Run("CloseIdleConnections", func(t *testing.T) {
+		var called bool
+		underlying := &mocks.Resolver{
+			MockCloseIdleConnections: func() {
+				called = true
+			},
+		}
+		reso := NewCachingResolver(underlying, nil)
+		reso.CloseIdleConnections()
+		if !called {
+			t.Fatal("not called")
+		}
+	})
+}
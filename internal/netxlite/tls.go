@@ -13,7 +13,6 @@ import (
 	"net"
 	"time"
 
-	ootls "github.com/ooni/oocrypto/tls"
 	oohttp "github.com/ooni/oohttp"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
@@ -162,7 +161,11 @@ var _ TLSConn = &tls.Conn{}
 // 2. error wrapping;
 //
 // 3. that we are going to use Mozilla CA if the [tls.Config]
-// RootCAs field is zero initialized.
+// RootCAs field is zero initialized;
+//
+// 4. that we are going to write the TLS key log to the writers
+// registered using RegisterKeyLogWriter, if any, if the [tls.Config]
+// KeyLogWriter field is zero initialized.
 func NewTLSHandshakerStdlib(logger model.DebugLogger) model.TLSHandshaker {
 	return newTLSHandshakerLogger(&tlsHandshakerConfigurable{}, logger)
 }
@@ -219,6 +222,7 @@ func (h *tlsHandshakerConfigurable) Handshake(
 		// See https://github.com/ooni/probe/issues/2413 for context
 		config.RootCAs = tproxySingleton().DefaultCertPool()
 	}
+	config = tlsMaybeApplyKeyLogWriter(ctx, config)
 	tlsconn, err := h.newConn(conn, config)
 	if err != nil {
 		return nil, tls.ConnectionState{}, err
@@ -243,7 +247,7 @@ func (h *tlsHandshakerConfigurable) newConn(conn net.Conn, config *tls.Config) (
 	if h.NewConn != nil {
 		return h.NewConn(conn, config)
 	}
-	return ootls.NewClientConnStdlib(conn, config)
+	return tlsNewClientConnStdlib(conn, config)
 }
 
 // tlsHandshakerLogger is a TLSHandshaker with logging.
@@ -67,6 +67,12 @@ func (*traceDefault) OnDNSRoundTripForLookupHost(started time.Time, reso model.R
 	// nothing
 }
 
+// OnDNSRoundTripForLookupRecords implements model.Trace.OnDNSRoundTripForLookupRecords.
+func (*traceDefault) OnDNSRoundTripForLookupRecords(started time.Time, reso model.Resolver,
+	query model.DNSQuery, response model.DNSResponse, err error, finished time.Time) {
+	// nothing
+}
+
 // OnDelayedDNSResponse implements model.Trace.OnDelayedDNSResponse.
 func (*traceDefault) OnDelayedDNSResponse(started time.Time, txp model.DNSTransport,
 	query model.DNSQuery, response model.DNSResponse, addrs []string, err error, finished time.Time) error {
@@ -28,7 +28,11 @@ import (
 // 2. error wrapping;
 //
 // 3. that we are going to use Mozilla CA if the [tls.Config]
-// RootCAs field is zero initialized.
+// RootCAs field is zero initialized;
+//
+// 4. that we are going to write the TLS key log to the writers
+// registered using RegisterKeyLogWriter, if any, if the [tls.Config]
+// KeyLogWriter field is zero initialized.
 //
 // Passing a nil `id` will make this function panic.
 func NewTLSHandshakerUTLS(logger model.DebugLogger, id *utls.ClientHelloID) model.TLSHandshaker {
@@ -37,6 +41,20 @@ func NewTLSHandshakerUTLS(logger model.DebugLogger, id *utls.ClientHelloID) mode
 	}, logger)
 }
 
+// NewTLSHandshakerUTLSWithExtensions is like NewTLSHandshakerUTLS except that
+// it appends the given extensions to the ClientHello generated using the given id,
+// which allows, e.g., adding an Encrypted ClientHello extension.
+//
+// Passing a nil `id` or a pointer to utls.HelloGolang when extensions is not
+// empty will cause the handshake to fail, since utls does not allow modifying
+// the extensions of the default Go ClientHello.
+func NewTLSHandshakerUTLSWithExtensions(logger model.DebugLogger,
+	id *utls.ClientHelloID, extensions []utls.TLSExtension) model.TLSHandshaker {
+	return newTLSHandshakerLogger(&tlsHandshakerConfigurable{
+		NewConn: newUTLSConnFactoryWithExtensions(id, extensions),
+	}, logger)
+}
+
 // UTLSConn implements TLSConn and uses a utls UConn as its underlying connection
 type UTLSConn struct {
 	// We include the real UConn
@@ -59,6 +77,35 @@ func newUTLSConnFactory(clientHello *utls.ClientHelloID) func(conn net.Conn, con
 	}
 }
 
+// newUTLSConnFactoryWithExtensions is like newUTLSConnFactory but appends the
+// given extensions to the ClientHello before returning the connection.
+func newUTLSConnFactoryWithExtensions(clientHello *utls.ClientHelloID,
+	extensions []utls.TLSExtension) func(conn net.Conn, config *tls.Config) (TLSConn, error) {
+	return func(conn net.Conn, config *tls.Config) (TLSConn, error) {
+		tlsConn, err := NewUTLSConn(conn, config, clientHello)
+		if err != nil {
+			return nil, err
+		}
+		if len(extensions) > 0 {
+			if *clientHello == utls.HelloGolang {
+				return nil, errUTLSExtensionsWithHelloGolang
+			}
+			// Building the handshake state applies the preset to the connection
+			// so that we can append extensions. When handshaking, utls will marshal
+			// the ClientHello again, thus including the extensions we've added.
+			if err := tlsConn.BuildHandshakeState(); err != nil {
+				return nil, err
+			}
+			tlsConn.Extensions = append(tlsConn.Extensions, extensions...)
+		}
+		return tlsConn, nil
+	}
+}
+
+// errUTLSExtensionsWithHelloGolang indicates that you attempted to add
+// extensions to the default Go ClientHello, which utls does not support.
+var errUTLSExtensionsWithHelloGolang = errors.New("utls: cannot add extensions to HelloGolang")
+
 // errUTLSIncompatibleStdlibConfig indicates that the stdlib config you passed to
 // NewUTLSConn contains some fields we don't support.
 var errUTLSIncompatibleStdlibConfig = errors.New("utls: incompatible stdlib config")
@@ -68,6 +115,7 @@ func NewUTLSConn(conn net.Conn, config *tls.Config, cid *utls.ClientHelloID) (*U
 	supportedFields := map[string]bool{
 		"DynamicRecordSizingDisabled": true,
 		"InsecureSkipVerify":          true,
+		"KeyLogWriter":                true,
 		"NextProtos":                  true,
 		"RootCAs":                     true,
 		"ServerName":                  true,
@@ -89,6 +137,7 @@ func NewUTLSConn(conn net.Conn, config *tls.Config, cid *utls.ClientHelloID) (*U
 	uConfig := &utls.Config{
 		DynamicRecordSizingDisabled: config.DynamicRecordSizingDisabled,
 		InsecureSkipVerify:          config.InsecureSkipVerify,
+		KeyLogWriter:                config.KeyLogWriter,
 		RootCAs:                     config.RootCAs,
 		NextProtos:                  config.NextProtos,
 		ServerName:                  config.ServerName,
@@ -26,6 +26,18 @@ func TestNewTLSHandshakerUTLS(t *testing.T) {
 	}
 }
 
+func TestNewTLSHandshakerUTLSWithExtensions(t *testing.T) {
+	th := NewTLSHandshakerUTLSWithExtensions(log.Log, &utls.HelloChrome_83, nil)
+	logger := th.(*tlsHandshakerLogger)
+	if logger.DebugLogger != log.Log {
+		t.Fatal("invalid logger")
+	}
+	configurable := logger.TLSHandshaker.(*tlsHandshakerConfigurable)
+	if configurable.NewConn == nil {
+		t.Fatal("expected non-nil NewConn")
+	}
+}
+
 func TestUTLSConn(t *testing.T) {
 	t.Run("Handshake", func(t *testing.T) {
 		t.Run("not interrupted with success", func(t *testing.T) {
@@ -107,6 +119,44 @@ func TestUTLSConn(t *testing.T) {
 	})
 }
 
+func Test_newUTLSConnFactoryWithExtensions(t *testing.T) {
+	t.Run("we append the extensions", func(t *testing.T) {
+		ext := &utls.GenericExtension{Id: 0xfe0d, Data: []byte{1, 2, 3}}
+		factory := newUTLSConnFactoryWithExtensions(&utls.HelloFirefox_Auto, []utls.TLSExtension{ext})
+		tconn, err := factory(&mocks.Conn{}, &tls.Config{ServerName: "ooni.org"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		uconn := tconn.(*UTLSConn)
+		if len(uconn.Extensions) < 1 || uconn.Extensions[len(uconn.Extensions)-1] != ext {
+			t.Fatal("extension not appended")
+		}
+	})
+
+	t.Run("we fail with HelloGolang", func(t *testing.T) {
+		ext := &utls.GenericExtension{Id: 0xfe0d, Data: []byte{1, 2, 3}}
+		factory := newUTLSConnFactoryWithExtensions(&utls.HelloGolang, []utls.TLSExtension{ext})
+		tconn, err := factory(&mocks.Conn{}, &tls.Config{ServerName: "ooni.org"})
+		if !errors.Is(err, errUTLSExtensionsWithHelloGolang) {
+			t.Fatal("unexpected error", err)
+		}
+		if tconn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+
+	t.Run("we fail with an incompatible config", func(t *testing.T) {
+		factory := newUTLSConnFactoryWithExtensions(&utls.HelloFirefox_Auto, nil)
+		tconn, err := factory(&mocks.Conn{}, &tls.Config{MinVersion: tls.VersionTLS13})
+		if !errors.Is(err, errUTLSIncompatibleStdlibConfig) {
+			t.Fatal("unexpected error", err)
+		}
+		if tconn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+}
+
 func Test_newConnUTLSWithHelloID(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -15,6 +15,8 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/humanize"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
+	"github.com/ooni/probe-cli/v3/internal/submitter"
 )
 
 // experimentShuffledInputs counts how many times we shuffled inputs
@@ -57,6 +59,14 @@ type Experiment struct {
 	// Session is the MANDATORY session.
 	Session Session
 
+	// SubmitQueue is the OPTIONAL queue where we store the measurements
+	// that we could not submit, so that we can retry later.
+	SubmitQueue *submitqueue.Queue
+
+	// Submitters contains OPTIONAL extra submitters to which we
+	// submit measurements besides the OONI collector.
+	Submitters []*submitter.Config
+
 	// newExperimentBuilderFn is OPTIONAL and used for testing.
 	newExperimentBuilderFn func(experimentName string) (model.ExperimentBuilder, error)
 
@@ -156,6 +166,7 @@ func (ed *Experiment) newInputProcessor(experiment model.Experiment,
 		Submitter: &experimentSubmitterWrapper{
 			child:  engine.NewInputProcessorSubmitterWrapper(submitter),
 			logger: ed.Session.Logger(),
+			queue:  ed.SubmitQueue,
 		},
 	}
 }
@@ -178,10 +189,15 @@ func (ed *Experiment) newSubmitter(ctx context.Context) (engine.Submitter, error
 	if ed.newSubmitterFn != nil {
 		return ed.newSubmitterFn(ctx)
 	}
+	extra, err := submitter.NewAll(ed.Submitters, ed.Session.Logger())
+	if err != nil {
+		return nil, err
+	}
 	return engine.NewSubmitter(ctx, engine.SubmitterConfig{
 		Enabled: !ed.NoCollector,
 		Session: ed.Session,
 		Logger:  ed.Session.Logger(),
+		Extra:   extra,
 	})
 }
 
@@ -259,11 +275,21 @@ type experimentSubmitterWrapper struct {
 
 	// logger is the logger to use
 	logger model.Logger
+
+	// queue is the OPTIONAL queue for measurements we could not submit
+	queue *submitqueue.Queue
 }
 
 func (sw *experimentSubmitterWrapper) Submit(ctx context.Context, idx int, m *model.Measurement) error {
 	if err := sw.child.Submit(ctx, idx, m); err != nil {
 		sw.logger.Warnf("submitting measurement failed: %s", err.Error())
+		if sw.queue != nil {
+			if uid, err := sw.queue.Enqueue(m, err); err != nil {
+				sw.logger.Warnf("cannot enqueue measurement for later submission: %s", err.Error())
+			} else {
+				sw.logger.Infof("enqueued measurement %s for later submission", uid)
+			}
+		}
 	}
 	// policy: we do not stop the loop if measurement submission fails
 	return nil
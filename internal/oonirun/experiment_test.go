@@ -9,8 +9,11 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
+	"github.com/ooni/probe-cli/v3/internal/submitter"
 	"github.com/ooni/probe-cli/v3/internal/testingx"
 )
 
@@ -408,3 +411,67 @@ func TestExperimentRun(t *testing.T) {
 		})
 	}
 }
+
+func TestExperimentNewSubmitterWithExtraSubmitters(t *testing.T) {
+	newExperiment := func(configs []*submitter.Config) *Experiment {
+		return &Experiment{
+			NoCollector: true,
+			Session: &mocks.Session{
+				MockLogger: func() model.Logger {
+					return model.DiscardLogger
+				},
+			},
+			Submitters: configs,
+		}
+	}
+
+	t.Run("with an invalid submitter config", func(t *testing.T) {
+		ed := newExperiment([]*submitter.Config{{Type: "antani"}})
+		subm, err := ed.newSubmitter(context.Background())
+		if !errors.Is(err, submitter.ErrUnknownType) {
+			t.Fatal("unexpected error", err)
+		}
+		if subm != nil {
+			t.Fatal("expected nil submitter")
+		}
+	})
+
+	t.Run("with a valid submitter config", func(t *testing.T) {
+		ed := newExperiment([]*submitter.Config{{Type: "webhook", URL: "https://example.com/"}})
+		subm, err := ed.newSubmitter(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if subm == nil {
+			t.Fatal("expected non-nil submitter")
+		}
+	})
+}
+
+func TestExperimentSubmitterWrapperWithQueue(t *testing.T) {
+	expected := errors.New("mocked error")
+	queue := submitqueue.New(&kvstore.Memory{})
+	sw := &experimentSubmitterWrapper{
+		child: engine.NewInputProcessorSubmitterWrapper(&mocks.Submitter{
+			MockSubmit: func(ctx context.Context, m *model.Measurement) error {
+				return expected
+			},
+		}),
+		logger: model.DiscardLogger,
+		queue:  queue,
+	}
+	m := &model.Measurement{TestName: "example"}
+	if err := sw.Submit(context.Background(), 0, m); err != nil {
+		t.Fatal("expected nil error", err)
+	}
+	entries, err := queue.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].UID != submitqueue.MeasurementUID(m) {
+		t.Fatal("expected the measurement to be enqueued", entries)
+	}
+	if entries[0].LastError != expected.Error() {
+		t.Fatal("unexpected last error", entries[0].LastError)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/submitqueue"
+	"github.com/ooni/probe-cli/v3/internal/submitter"
 )
 
 // LinkConfig contains config for an OONI Run link. You MUST fill all the fields that
@@ -44,6 +46,14 @@ type LinkConfig struct {
 
 	// Session is the MANDATORY Session to use.
 	Session Session
+
+	// SubmitQueue is the OPTIONAL queue where we store the measurements
+	// that we could not submit, so that we can retry later.
+	SubmitQueue *submitqueue.Queue
+
+	// Submitters contains OPTIONAL extra submitters to which we
+	// submit measurements besides the OONI collector.
+	Submitters []*submitter.Config
 }
 
 // LinkRunner knows how to run an OONI Run v1 or v2 link.
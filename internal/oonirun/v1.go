@@ -83,6 +83,8 @@ func v1Measure(ctx context.Context, config *LinkConfig, URL string) error {
 		Random:                 config.Random,
 		ReportFile:             config.ReportFile,
 		Session:                config.Session,
+		SubmitQueue:            config.SubmitQueue,
+		Submitters:             config.Submitters,
 		newExperimentBuilderFn: nil,
 		newInputLoaderFn:       nil,
 		newSubmitterFn:         nil,
@@ -187,6 +187,8 @@ func V2MeasureDescriptor(ctx context.Context, config *LinkConfig, desc *V2Descri
 			Random:                 config.Random,
 			ReportFile:             config.ReportFile,
 			Session:                config.Session,
+			SubmitQueue:            config.SubmitQueue,
+			Submitters:             config.Submitters,
 			newExperimentBuilderFn: nil,
 			newInputLoaderFn:       nil,
 			newSubmitterFn:         nil,
@@ -223,35 +225,73 @@ func v2DescriptorDiff(oldValue, newValue *V2Descriptor, URL string) string {
 // v2MeasureHTTPS performs a measurement using an HTTPS v2 OONI Run URL
 // and returns whether performing this measurement failed.
 //
-// This function maintains an on-disk cache that tracks the status of
-// OONI Run v2 links. If there are any changes and the user has not
-// provided config.AcceptChanges, this function will log what has changed
-// and will return with an ErrNeedToAcceptChanges error.
-//
-// In such a case, the caller SHOULD print additional information
-// explaining how to accept changes and then SHOULD exit 1 or similar.
+// See [V2PullDescriptor] for more information on how we handle new
+// or modified OONI Run v2 links.
 func v2MeasureHTTPS(ctx context.Context, config *LinkConfig, URL string) error {
 	logger := config.Session.Logger()
 	logger.Infof("oonirun/v2: running %s", URL)
-	cache, err := v2DescriptorCacheLoad(config.KVStore)
+	desc, err := V2PullDescriptor(ctx, config.Session, config.KVStore, URL, config.AcceptChanges)
 	if err != nil {
 		return err
 	}
-	clnt := config.Session.DefaultHTTPClient()
-	oldValue, newValue, err := cache.PullChangesWithoutSideEffects(ctx, clnt, logger, URL)
+	return V2MeasureDescriptor(ctx, config, desc) // handles nil desc gracefully
+}
+
+// V2PullDescriptor fetches the v2Descriptor at the given HTTPS URL.
+//
+// This function maintains an on-disk cache inside the given store that
+// tracks the status of OONI Run v2 links. If there are any changes and the
+// user has not provided acceptChanges, this function will log what has
+// changed and will return with an ErrNeedToAcceptChanges error.
+//
+// In such a case, the caller SHOULD print additional information
+// explaining how to accept changes and then SHOULD exit 1 or similar.
+func V2PullDescriptor(ctx context.Context, sess Session, store model.KeyValueStore,
+	URL string, acceptChanges bool) (*V2Descriptor, error) {
+	logger := sess.Logger()
+	newValue, diff, err := V2FetchDescriptor(ctx, sess, store, URL)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	diff := v2DescriptorDiff(oldValue, newValue, URL)
-	if !config.AcceptChanges && diff != "" {
+	if !acceptChanges && diff != "" {
 		logger.Warnf("oonirun: %s changed as follows:\n\n%s", URL, diff)
 		logger.Warnf("oonirun: we are not going to run this link until you accept changes")
-		return ErrNeedToAcceptChanges
+		return nil, ErrNeedToAcceptChanges
 	}
 	if diff != "" {
-		if err := cache.Update(config.KVStore, URL, newValue); err != nil {
-			return err
+		if err := V2AcceptDescriptor(store, URL, newValue); err != nil {
+			return nil, err
 		}
 	}
-	return V2MeasureDescriptor(ctx, config, newValue) // handles nil newValue gracefully
+	return newValue, nil
+}
+
+// V2FetchDescriptor fetches the v2Descriptor at the given HTTPS URL and
+// returns it along with a diff describing how it changed since the last
+// time it has been accepted. An empty diff means there are no changes.
+//
+// This function DOES NOT change the cache inside the given store. To
+// accept the changes, the caller SHOULD call [V2AcceptDescriptor].
+func V2FetchDescriptor(ctx context.Context, sess Session, store model.KeyValueStore,
+	URL string) (desc *V2Descriptor, diff string, err error) {
+	cache, err := v2DescriptorCacheLoad(store)
+	if err != nil {
+		return nil, "", err
+	}
+	clnt := sess.DefaultHTTPClient()
+	oldValue, newValue, err := cache.PullChangesWithoutSideEffects(ctx, clnt, sess.Logger(), URL)
+	if err != nil {
+		return nil, "", err
+	}
+	return newValue, v2DescriptorDiff(oldValue, newValue, URL), nil
+}
+
+// V2AcceptDescriptor saves the given v2Descriptor inside the cache such that
+// later calls to [V2FetchDescriptor] will not report any change.
+func V2AcceptDescriptor(store model.KeyValueStore, URL string, desc *V2Descriptor) error {
+	cache, err := v2DescriptorCacheLoad(store)
+	if err != nil {
+		return err
+	}
+	return cache.Update(store, URL, desc)
 }
@@ -335,6 +335,116 @@ func TestV2MeasureHTTPS(t *testing.T) {
 	})
 }
 
+func TestV2PullDescriptor(t *testing.T) {
+	descriptor := &V2Descriptor{
+		Name:        "custom",
+		Description: "",
+		Author:      "",
+		Nettests: []V2Nettest{{
+			Inputs:   []string{},
+			Options:  map[string]any{},
+			TestName: "example",
+		}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(descriptor)
+		runtimex.PanicOnError(err, "json.Marshal failed")
+		w.Write(data)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	store := &kvstore.Memory{}
+	sess := newMinimalFakeSession()
+
+	// the first time we need to accept changes
+	desc, err := V2PullDescriptor(ctx, sess, store, server.URL, false)
+	if !errors.Is(err, ErrNeedToAcceptChanges) {
+		t.Fatal("unexpected err", err)
+	}
+	if desc != nil {
+		t.Fatal("expected nil descriptor")
+	}
+
+	// once we have accepted changes we get the descriptor
+	desc, err = V2PullDescriptor(ctx, sess, store, server.URL, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Name != "custom" || len(desc.Nettests) != 1 {
+		t.Fatal("unexpected descriptor", desc)
+	}
+
+	// without changes we don't need to accept anything
+	desc, err = V2PullDescriptor(ctx, sess, store, server.URL, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Name != "custom" {
+		t.Fatal("unexpected descriptor", desc)
+	}
+}
+
+func TestV2FetchDescriptor(t *testing.T) {
+	descriptor := &V2Descriptor{
+		Name:        "custom",
+		Description: "",
+		Author:      "",
+		Nettests: []V2Nettest{{
+			Inputs:   []string{},
+			Options:  map[string]any{},
+			TestName: "example",
+		}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(descriptor)
+		runtimex.PanicOnError(err, "json.Marshal failed")
+		w.Write(data)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	sess := newMinimalFakeSession()
+
+	t.Run("common case", func(t *testing.T) {
+		store := &kvstore.Memory{}
+
+		// a new descriptor is reported as changed
+		desc, diff, err := V2FetchDescriptor(ctx, sess, store, server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if desc.Name != "custom" || diff == "" {
+			t.Fatal("unexpected result", desc, diff)
+		}
+
+		// fetching does not change the cache
+		if _, diff, _ = V2FetchDescriptor(ctx, sess, store, server.URL); diff == "" {
+			t.Fatal("expected a diff")
+		}
+
+		// once accepted, the descriptor is not changed anymore
+		if err := V2AcceptDescriptor(store, server.URL, desc); err != nil {
+			t.Fatal(err)
+		}
+		if _, diff, _ = V2FetchDescriptor(ctx, sess, store, server.URL); diff != "" {
+			t.Fatal("expected no diff", diff)
+		}
+	})
+
+	t.Run("with a broken cache", func(t *testing.T) {
+		store := &kvstore.Memory{}
+		if err := store.Set(v2DescriptorCacheKey, []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		desc, diff, err := V2FetchDescriptor(ctx, sess, store, server.URL)
+		if err == nil || desc != nil || diff != "" {
+			t.Fatal("unexpected result", desc, diff, err)
+		}
+		if err := V2AcceptDescriptor(store, server.URL, descriptor); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
 func TestV2DescriptorCacheLoad(t *testing.T) {
 	t.Run("cannot unmarshal cache content", func(t *testing.T) {
 		fsstore := &kvstore.Memory{}
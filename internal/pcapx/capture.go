@@ -0,0 +1,403 @@
+// Package pcapx captures the traffic generated using netxlite and
+// exports it as a pcapng file, which is useful to investigate, e.g.,
+// what a middlebox injected during a measurement.
+//
+// Because we wrap the conns created by netxlite dialers, we cannot
+// observe actual packets. Rather, we synthesize packets describing
+// what the socket API told us. For example, we emit a TCP segment
+// with the RST flag when reading from a socket fails with ECONNRESET.
+// Also, we cannot capture the traffic generated by getaddrinfo, which
+// does not use netxlite dialers.
+package pcapx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+const (
+	// CaptureMaxPackets is the maximum number of packets a Capture keeps.
+	CaptureMaxPackets = 1 << 16
+
+	// CaptureMaxBytes is the maximum number of bytes of the packets a Capture keeps.
+	CaptureMaxBytes = 1 << 26
+)
+
+// Capture is a netxlite.ConnWrapper capturing the traffic of the conns created
+// by netxlite dialers using a context bound to it (see netxlite.ContextWithConnWrapper).
+// Use NewCapture to construct. Because the binding is per context, a Capture only
+// sees the traffic of the code using such a context (e.g., a measurement).
+//
+// To bound the memory usage, a Capture keeps at most CaptureMaxPackets packets
+// whose overall size is at most CaptureMaxBytes and drops (and counts) the others.
+type Capture struct {
+	// dropped is the number of packets dropped so far.
+	dropped int64
+
+	// flows is the number of flows created so far.
+	flows int64
+
+	// keylog contains the TLS key log.
+	keylog *bytes.Buffer
+
+	// maxBytes is the maximum size of the packets we keep.
+	maxBytes int64
+
+	// maxPackets is the maximum number of packets we keep.
+	maxPackets int
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// packets contains the captured packets.
+	packets []*pcapngPacket
+
+	// size is the overall size of the captured packets.
+	size int64
+
+	// timeNow is the function returning the current time.
+	timeNow func() time.Time
+}
+
+var _ netxlite.ConnWrapper = &Capture{}
+
+// NewCapture creates a new Capture.
+func NewCapture() *Capture {
+	return &Capture{
+		dropped:    0,
+		flows:      0,
+		keylog:     &bytes.Buffer{},
+		maxBytes:   CaptureMaxBytes,
+		maxPackets: CaptureMaxPackets,
+		mu:         sync.Mutex{},
+		packets:    []*pcapngPacket{},
+		size:       0,
+		timeNow:    time.Now,
+	}
+}
+
+// KeyLogWriter returns an io.Writer that appends to the TLS key log, which
+// uses the NSS key log format and which we include into the pcapng file
+// using a decryption secrets block. You can bind this writer to the context
+// bound to the Capture using netxlite.ContextWithKeyLogWriter.
+func (c *Capture) KeyLogWriter() io.Writer {
+	return &captureKeyLogWriter{c}
+}
+
+// captureKeyLogWriter is the io.Writer returned by KeyLogWriter.
+type captureKeyLogWriter struct {
+	c *Capture
+}
+
+// Write implements io.Writer.
+func (w *captureKeyLogWriter) Write(data []byte) (int, error) {
+	defer w.c.mu.Unlock()
+	w.c.mu.Lock()
+	return w.c.keylog.Write(data)
+}
+
+// Dropped returns the number of packets we have dropped so far because
+// we reached CaptureMaxPackets or CaptureMaxBytes.
+func (c *Capture) Dropped() int64 {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	return c.dropped
+}
+
+// WritePcapng writes the captured traffic to w using the pcapng format.
+func (c *Capture) WritePcapng(w io.Writer) error {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	pw := &pcapngWriter{w}
+	if err := pw.writeHeader(); err != nil {
+		return err
+	}
+	// Write the key log before the packets such that the dissectors
+	// can decrypt the traffic with a single pass.
+	if c.keylog.Len() > 0 {
+		if err := pw.writeKeyLog(c.keylog.Bytes()); err != nil {
+			return err
+		}
+	}
+	for _, packet := range c.packets {
+		if err := pw.writePacket(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile writes the captured traffic to the given file using the pcapng format.
+func (c *Capture) WriteFile(filename string) error {
+	buffer := &bytes.Buffer{}
+	if err := c.WritePcapng(buffer); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buffer.Bytes(), 0600)
+}
+
+// CheckNewConn implements netxlite.ConnWrapper.
+func (c *Capture) CheckNewConn() error {
+	return nil
+}
+
+// OnDialFailure implements netxlite.ConnWrapper.
+func (c *Capture) OnDialFailure(network, address string, err error) {
+	if strings.HasPrefix(network, "tcp") {
+		c.recordTCPDialFailure(address, err)
+	}
+}
+
+// WrapNetConn implements netxlite.ConnWrapper.
+func (c *Capture) WrapNetConn(conn net.Conn) net.Conn {
+	switch conn.LocalAddr().Network() {
+	case "tcp":
+		return c.wrapTCPConn(conn)
+	case "udp":
+		return c.wrapUDPConn(conn)
+	default:
+		return conn
+	}
+}
+
+// WrapUDPLikeConn implements netxlite.ConnWrapper.
+func (c *Capture) WrapUDPLikeConn(pconn model.UDPLikeConn) model.UDPLikeConn {
+	return &captureUDPLikeConn{
+		UDPLikeConn: pconn,
+		c:           c,
+		flow:        c.newFlow(),
+		local:       packetAddrPort(pconn.LocalAddr()),
+	}
+}
+
+// newFlow returns the ID of a new flow.
+func (c *Capture) newFlow() int64 {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	c.flows++
+	return c.flows
+}
+
+// appendPacket appends a packet belonging to the given flow.
+func (c *Capture) appendPacket(flow int64, data []byte) {
+	packet := &pcapngPacket{
+		comment: "flow " + strconv.FormatInt(flow, 10),
+		data:    data,
+		t:       c.timeNow(),
+	}
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	if len(c.packets) >= c.maxPackets || c.size+int64(len(data)) > c.maxBytes {
+		c.dropped++
+		return
+	}
+	c.packets = append(c.packets, packet)
+	c.size += int64(len(data))
+}
+
+// captureMaxSegmentSize is the maximum payload of each synthesized packet.
+const captureMaxSegmentSize = 1 << 14
+
+// recordTCPDialFailure records the SYN segment and, if the connection
+// was refused, the RST segment sent in response by the remote host.
+func (c *Capture) recordTCPDialFailure(address string, err error) {
+	flow := c.newFlow()
+	remote, _ := netip.ParseAddrPort(address)
+	syn := &tcpSegment{dst: remote, flags: tcpFlagSYN}
+	c.appendPacket(flow, newTCPPacket(syn))
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		rst := &tcpSegment{ack: 1, dst: syn.src, flags: tcpFlagRST | tcpFlagACK, src: remote}
+		c.appendPacket(flow, newTCPPacket(rst))
+	}
+}
+
+// wrapTCPConn records the three-way handshake and returns a net.Conn recording the traffic.
+func (c *Capture) wrapTCPConn(conn net.Conn) net.Conn {
+	tc := &captureTCPConn{
+		Conn:   conn,
+		c:      c,
+		flow:   c.newFlow(),
+		local:  packetAddrPort(conn.LocalAddr()),
+		remote: packetAddrPort(conn.RemoteAddr()),
+		rseq:   1,
+		wseq:   1,
+	}
+	c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+		dst: tc.remote, flags: tcpFlagSYN, src: tc.local}))
+	c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+		ack: 1, dst: tc.local, flags: tcpFlagSYN | tcpFlagACK, src: tc.remote}))
+	c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+		ack: 1, dst: tc.remote, flags: tcpFlagACK, seq: 1, src: tc.local}))
+	return tc
+}
+
+// captureTCPConn is a net.Conn recording TCP traffic.
+type captureTCPConn struct {
+	net.Conn
+	c         *Capture
+	closeOnce sync.Once
+	flow      int64
+	local     netip.AddrPort
+	mu        sync.Mutex
+	remote    netip.AddrPort
+	rseq      uint32
+	wseq      uint32
+}
+
+// Read implements net.Conn.
+func (tc *captureTCPConn) Read(data []byte) (int, error) {
+	count, err := tc.Conn.Read(data)
+	tc.recordRead(data[:count], err)
+	return count, err
+}
+
+// recordRead records the segments corresponding to a read.
+func (tc *captureTCPConn) recordRead(data []byte, err error) {
+	defer tc.mu.Unlock()
+	tc.mu.Lock()
+	for _, chunk := range captureChunks(data) {
+		tc.c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+			ack:     tc.wseq,
+			dst:     tc.local,
+			flags:   tcpFlagPSH | tcpFlagACK,
+			payload: chunk,
+			seq:     tc.rseq,
+			src:     tc.remote,
+		}))
+		tc.rseq += uint32(len(chunk))
+	}
+	switch {
+	case errors.Is(err, io.EOF):
+		tc.c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+			ack: tc.wseq, dst: tc.local, flags: tcpFlagFIN | tcpFlagACK, seq: tc.rseq, src: tc.remote}))
+		tc.rseq++
+	case errors.Is(err, syscall.ECONNRESET):
+		tc.c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+			ack: tc.wseq, dst: tc.local, flags: tcpFlagRST | tcpFlagACK, seq: tc.rseq, src: tc.remote}))
+	}
+}
+
+// Write implements net.Conn.
+func (tc *captureTCPConn) Write(data []byte) (int, error) {
+	count, err := tc.Conn.Write(data)
+	tc.recordWrite(data[:count])
+	return count, err
+}
+
+// recordWrite records the segments corresponding to a write.
+func (tc *captureTCPConn) recordWrite(data []byte) {
+	defer tc.mu.Unlock()
+	tc.mu.Lock()
+	for _, chunk := range captureChunks(data) {
+		tc.c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+			ack:     tc.rseq,
+			dst:     tc.remote,
+			flags:   tcpFlagPSH | tcpFlagACK,
+			payload: chunk,
+			seq:     tc.wseq,
+			src:     tc.local,
+		}))
+		tc.wseq += uint32(len(chunk))
+	}
+}
+
+// Close implements net.Conn.
+func (tc *captureTCPConn) Close() error {
+	tc.closeOnce.Do(func() {
+		defer tc.mu.Unlock()
+		tc.mu.Lock()
+		tc.c.appendPacket(tc.flow, newTCPPacket(&tcpSegment{
+			ack: tc.rseq, dst: tc.remote, flags: tcpFlagFIN | tcpFlagACK, seq: tc.wseq, src: tc.local}))
+		tc.wseq++
+	})
+	return tc.Conn.Close()
+}
+
+// wrapUDPConn returns a net.Conn recording UDP traffic.
+func (c *Capture) wrapUDPConn(conn net.Conn) net.Conn {
+	return &captureUDPConn{
+		Conn:   conn,
+		c:      c,
+		flow:   c.newFlow(),
+		local:  packetAddrPort(conn.LocalAddr()),
+		remote: packetAddrPort(conn.RemoteAddr()),
+	}
+}
+
+// captureUDPConn is a connected UDP net.Conn recording traffic.
+type captureUDPConn struct {
+	net.Conn
+	c      *Capture
+	flow   int64
+	local  netip.AddrPort
+	remote netip.AddrPort
+}
+
+// Read implements net.Conn.
+func (uc *captureUDPConn) Read(data []byte) (int, error) {
+	count, err := uc.Conn.Read(data)
+	if count > 0 {
+		uc.c.appendPacket(uc.flow, newUDPPacket(uc.remote, uc.local, data[:count]))
+	}
+	return count, err
+}
+
+// Write implements net.Conn.
+func (uc *captureUDPConn) Write(data []byte) (int, error) {
+	count, err := uc.Conn.Write(data)
+	if count > 0 {
+		uc.c.appendPacket(uc.flow, newUDPPacket(uc.local, uc.remote, data[:count]))
+	}
+	return count, err
+}
+
+// captureUDPLikeConn is a model.UDPLikeConn recording traffic.
+type captureUDPLikeConn struct {
+	model.UDPLikeConn
+	c     *Capture
+	flow  int64
+	local netip.AddrPort
+}
+
+// ReadFrom implements model.UDPLikeConn.
+func (uc *captureUDPLikeConn) ReadFrom(data []byte) (int, net.Addr, error) {
+	count, addr, err := uc.UDPLikeConn.ReadFrom(data)
+	if count > 0 {
+		uc.c.appendPacket(uc.flow, newUDPPacket(packetAddrPort(addr), uc.local, data[:count]))
+	}
+	return count, addr, err
+}
+
+// WriteTo implements model.UDPLikeConn.
+func (uc *captureUDPLikeConn) WriteTo(data []byte, addr net.Addr) (int, error) {
+	count, err := uc.UDPLikeConn.WriteTo(data, addr)
+	if count > 0 {
+		uc.c.appendPacket(uc.flow, newUDPPacket(uc.local, packetAddrPort(addr), data[:count]))
+	}
+	return count, err
+}
+
+// captureChunks splits data in chunks of at most captureMaxSegmentSize bytes.
+func captureChunks(data []byte) (out [][]byte) {
+	for len(data) > 0 {
+		count := len(data)
+		if count > captureMaxSegmentSize {
+			count = captureMaxSegmentSize
+		}
+		out = append(out, data[:count])
+		data = data[count:]
+	}
+	return
+}
@@ -0,0 +1,358 @@
+package pcapx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// captureFlags returns the TCP flags of the captured packets.
+func captureFlags(t *testing.T, c *Capture) (out []uint8) {
+	for _, packet := range c.packets {
+		out = append(out, packetParse(t, packet.data).flags)
+	}
+	return
+}
+
+// captureNewConn returns a mocked net.Conn with the given addresses and read function.
+func captureNewConn(local, remote net.Addr, read func(b []byte) (int, error)) *mocks.Conn {
+	return &mocks.Conn{
+		MockRead: read,
+		MockWrite: func(b []byte) (int, error) {
+			return len(b), nil
+		},
+		MockClose: func() error {
+			return nil
+		},
+		MockLocalAddr: func() net.Addr {
+			return local
+		},
+		MockRemoteAddr: func() net.Addr {
+			return remote
+		},
+	}
+}
+
+func TestCaptureTCP(t *testing.T) {
+	local := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 54321}
+	remote := &net.TCPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 443}
+
+	t.Run("on success", func(t *testing.T) {
+		reads := [][]byte{[]byte("def")}
+		c := NewCapture()
+		conn := c.WrapNetConn(captureNewConn(local, remote, func(b []byte) (int, error) {
+			if len(reads) <= 0 {
+				return 0, io.EOF
+			}
+			count := copy(b, reads[0])
+			reads = reads[1:]
+			return count, nil
+		}))
+		if _, err := conn.Write([]byte("abc")); err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 128)
+		if _, err := conn.Read(buffer); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Read(buffer); !errors.Is(err, io.EOF) {
+			t.Fatal("unexpected error", err)
+		}
+		conn.Close()
+		conn.Close() // we should only see a single FIN
+		expectFlags := []uint8{
+			tcpFlagSYN,
+			tcpFlagSYN | tcpFlagACK,
+			tcpFlagACK,
+			tcpFlagPSH | tcpFlagACK,
+			tcpFlagPSH | tcpFlagACK,
+			tcpFlagFIN | tcpFlagACK,
+			tcpFlagFIN | tcpFlagACK,
+		}
+		if diff := cmp.Diff(expectFlags, captureFlags(t, c)); diff != "" {
+			t.Fatal(diff)
+		}
+		write := packetParse(t, c.packets[3].data)
+		if string(write.payload) != "abc" || write.src.Port() != 54321 {
+			t.Fatal("unexpected write", write)
+		}
+		read := packetParse(t, c.packets[4].data)
+		if string(read.payload) != "def" || read.src.Port() != 443 {
+			t.Fatal("unexpected read", read)
+		}
+		for _, packet := range c.packets {
+			if packet.comment != "flow 1" {
+				t.Fatal("unexpected comment", packet.comment)
+			}
+		}
+	})
+
+	t.Run("when the connection is reset", func(t *testing.T) {
+		c := NewCapture()
+		conn := c.WrapNetConn(captureNewConn(local, remote, func(b []byte) (int, error) {
+			return 0, syscall.ECONNRESET
+		}))
+		if _, err := conn.Read(make([]byte, 128)); !errors.Is(err, syscall.ECONNRESET) {
+			t.Fatal("unexpected error", err)
+		}
+		expectFlags := []uint8{
+			tcpFlagSYN,
+			tcpFlagSYN | tcpFlagACK,
+			tcpFlagACK,
+			tcpFlagRST | tcpFlagACK,
+		}
+		if diff := cmp.Diff(expectFlags, captureFlags(t, c)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the connection is refused", func(t *testing.T) {
+		c := NewCapture()
+		c.OnDialFailure("tcp", "8.8.8.8:443", syscall.ECONNREFUSED)
+		expectFlags := []uint8{tcpFlagSYN, tcpFlagRST | tcpFlagACK}
+		if diff := cmp.Diff(expectFlags, captureFlags(t, c)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when the connect times out", func(t *testing.T) {
+		c := NewCapture()
+		c.OnDialFailure("tcp", "8.8.8.8:443", syscall.ETIMEDOUT)
+		if diff := cmp.Diff([]uint8{tcpFlagSYN}, captureFlags(t, c)); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a real connection", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			io.Copy(conn, conn)
+			conn.Close()
+		}()
+		c := NewCapture()
+		ctx := netxlite.ContextWithConnWrapper(context.Background(), c)
+		dialer := netxlite.NewDialerWithoutResolver(model.DiscardLogger)
+		conn, err := dialer.DialContext(ctx, "tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte("abc")); err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 3)
+		if _, err := io.ReadFull(conn, buffer); err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		if len(c.packets) <= 0 {
+			t.Fatal("expected to see packets")
+		}
+		for _, packet := range c.packets {
+			parsed := packetParse(t, packet.data)
+			if parsed.src.Addr().String() != "127.0.0.1" || parsed.dst.Addr().String() != "127.0.0.1" {
+				t.Fatal("unexpected addresses", parsed.src, parsed.dst)
+			}
+		}
+	})
+}
+
+func TestCaptureUDP(t *testing.T) {
+	local := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 54321}
+	remote := &net.UDPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 53}
+
+	t.Run("with a connected conn", func(t *testing.T) {
+		c := NewCapture()
+		conn := c.WrapNetConn(captureNewConn(local, remote, func(b []byte) (int, error) {
+			return copy(b, "def"), nil
+		}))
+		if _, err := conn.Write([]byte("abc")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Read(make([]byte, 128)); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.packets) != 2 {
+			t.Fatal("unexpected number of packets", len(c.packets))
+		}
+		write := packetParse(t, c.packets[0].data)
+		if write.proto != protoUDP || string(write.payload) != "abc" || write.dst.Port() != 53 {
+			t.Fatal("unexpected write", write)
+		}
+		read := packetParse(t, c.packets[1].data)
+		if read.proto != protoUDP || string(read.payload) != "def" || read.src.Port() != 53 {
+			t.Fatal("unexpected read", read)
+		}
+	})
+
+	t.Run("when dialing fails", func(t *testing.T) {
+		c := NewCapture()
+		c.OnDialFailure("udp", "8.8.8.8:53", errors.New("mocked error"))
+		if len(c.packets) != 0 {
+			t.Fatal("expected no packets")
+		}
+	})
+
+	t.Run("with an UDPLikeConn", func(t *testing.T) {
+		c := NewCapture()
+		pconn := c.WrapUDPLikeConn(&mocks.UDPLikeConn{
+			MockLocalAddr: func() net.Addr {
+				return local
+			},
+			MockReadFrom: func(p []byte) (int, net.Addr, error) {
+				return copy(p, "def"), remote, nil
+			},
+			MockWriteTo: func(p []byte, addr net.Addr) (int, error) {
+				return len(p), nil
+			},
+		})
+		if _, err := pconn.WriteTo([]byte("abc"), remote); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := pconn.ReadFrom(make([]byte, 128)); err != nil {
+			t.Fatal(err)
+		}
+		if len(c.packets) != 2 {
+			t.Fatal("unexpected number of packets", len(c.packets))
+		}
+		write := packetParse(t, c.packets[0].data)
+		if string(write.payload) != "abc" || write.src.Port() != 54321 || write.dst.Port() != 53 {
+			t.Fatal("unexpected write", write)
+		}
+		read := packetParse(t, c.packets[1].data)
+		if string(read.payload) != "def" || read.src.Port() != 53 || read.dst.Port() != 54321 {
+			t.Fatal("unexpected read", read)
+		}
+	})
+}
+
+func TestCaptureWrapNetConnWithOtherNetworks(t *testing.T) {
+	c := NewCapture()
+	addr := &net.UnixAddr{Name: "/tmp/sock", Net: "unix"}
+	conn := captureNewConn(addr, addr, nil)
+	if c.CheckNewConn() != nil {
+		t.Fatal("we should always be able to create new conns")
+	}
+	if c.WrapNetConn(conn) != conn {
+		t.Fatal("expected the same conn")
+	}
+}
+
+func TestCaptureLimits(t *testing.T) {
+	t.Run("with too many packets", func(t *testing.T) {
+		c := NewCapture()
+		c.maxPackets = 1
+		c.OnDialFailure("tcp", "8.8.8.8:443", syscall.ECONNREFUSED)
+		if len(c.packets) != 1 || c.Dropped() != 1 {
+			t.Fatal("unexpected packets", len(c.packets), c.Dropped())
+		}
+	})
+
+	t.Run("with too many bytes", func(t *testing.T) {
+		c := NewCapture()
+		c.OnDialFailure("tcp", "8.8.8.8:443", syscall.ETIMEDOUT)
+		c.maxBytes = c.size + 1
+		c.OnDialFailure("tcp", "8.8.8.8:443", syscall.ECONNREFUSED)
+		if len(c.packets) != 1 || c.Dropped() != 2 {
+			t.Fatal("unexpected packets", len(c.packets), c.Dropped())
+		}
+	})
+}
+
+func TestCaptureWritePcapng(t *testing.T) {
+	newCapture := func() *Capture {
+		c := NewCapture()
+		c.OnDialFailure("tcp", "8.8.8.8:443", syscall.ECONNREFUSED)
+		return c
+	}
+
+	t.Run("without a key log", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		if err := newCapture().WritePcapng(buffer); err != nil {
+			t.Fatal(err)
+		}
+		var types []uint32
+		for _, block := range pcapngParse(t, buffer.Bytes()) {
+			types = append(types, block.blockType)
+		}
+		expect := []uint32{
+			pcapngBlockSectionHeader,
+			pcapngBlockInterfaceDescription,
+			pcapngBlockEnhancedPacket,
+			pcapngBlockEnhancedPacket,
+		}
+		if diff := cmp.Diff(expect, types); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a key log", func(t *testing.T) {
+		c := newCapture()
+		if _, err := c.KeyLogWriter().Write([]byte("CLIENT_RANDOM 00 11\n")); err != nil {
+			t.Fatal(err)
+		}
+		filename := filepath.Join(t.TempDir(), "capture.pcapng")
+		if err := c.WriteFile(filename); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types []uint32
+		for _, block := range pcapngParse(t, data) {
+			types = append(types, block.blockType)
+		}
+		expect := []uint32{
+			pcapngBlockSectionHeader,
+			pcapngBlockInterfaceDescription,
+			pcapngBlockDecryptionSecrets,
+			pcapngBlockEnhancedPacket,
+			pcapngBlockEnhancedPacket,
+		}
+		if diff := cmp.Diff(expect, types); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("when writing fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		err := newCapture().WritePcapng(&mocks.Writer{
+			MockWrite: func(b []byte) (int, error) {
+				return 0, expected
+			},
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
+
+func TestCaptureChunks(t *testing.T) {
+	data := make([]byte, 2*captureMaxSegmentSize+1)
+	chunks := captureChunks(data)
+	if len(chunks) != 3 || len(chunks[2]) != 1 {
+		t.Fatal("unexpected chunks", len(chunks))
+	}
+	if len(captureChunks(nil)) != 0 {
+		t.Fatal("expected no chunks")
+	}
+}
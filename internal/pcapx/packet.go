@@ -0,0 +1,172 @@
+package pcapx
+
+//
+// Synthesizing raw IPv4/IPv6 packets
+//
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+)
+
+// Protocol numbers used in the IPv4 and IPv6 headers.
+const (
+	protoTCP = 6
+	protoUDP = 17
+)
+
+// TCP flags.
+const (
+	tcpFlagFIN = 1 << 0
+	tcpFlagSYN = 1 << 1
+	tcpFlagRST = 1 << 2
+	tcpFlagPSH = 1 << 3
+	tcpFlagACK = 1 << 4
+)
+
+// packetDefaultTTL is the TTL (or hop limit) of synthesized packets.
+const packetDefaultTTL = 64
+
+// packetTCPWindow is the window of synthesized TCP segments.
+const packetTCPWindow = 65535
+
+// tcpSegment describes a TCP segment to synthesize.
+type tcpSegment struct {
+	ack     uint32
+	dst     netip.AddrPort
+	flags   uint8
+	payload []byte
+	seq     uint32
+	src     netip.AddrPort
+}
+
+// newTCPPacket synthesizes a raw IP packet containing the given TCP segment.
+func newTCPPacket(segment *tcpSegment) []byte {
+	header := make([]byte, 20)
+	binary.BigEndian.PutUint16(header[0:], segment.src.Port())
+	binary.BigEndian.PutUint16(header[2:], segment.dst.Port())
+	binary.BigEndian.PutUint32(header[4:], segment.seq)
+	binary.BigEndian.PutUint32(header[8:], segment.ack)
+	header[12] = 5 << 4 // data offset in 32-bit words
+	header[13] = segment.flags
+	binary.BigEndian.PutUint16(header[14:], packetTCPWindow)
+	transport := append(header, segment.payload...)
+	src, dst := packetAddrs(segment.src.Addr(), segment.dst.Addr())
+	csum := packetChecksum(packetPseudoHeader(src, dst, protoTCP, len(transport)), transport)
+	binary.BigEndian.PutUint16(transport[16:], csum)
+	return newIPPacket(src, dst, protoTCP, transport)
+}
+
+// newUDPPacket synthesizes a raw IP packet containing a UDP datagram.
+func newUDPPacket(src, dst netip.AddrPort, payload []byte) []byte {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint16(header[0:], src.Port())
+	binary.BigEndian.PutUint16(header[2:], dst.Port())
+	binary.BigEndian.PutUint16(header[4:], uint16(len(header)+len(payload)))
+	transport := append(header, payload...)
+	srcAddr, dstAddr := packetAddrs(src.Addr(), dst.Addr())
+	csum := packetChecksum(packetPseudoHeader(srcAddr, dstAddr, protoUDP, len(transport)), transport)
+	if csum == 0 {
+		csum = 0xffff // zero means no checksum for UDP
+	}
+	binary.BigEndian.PutUint16(transport[6:], csum)
+	return newIPPacket(srcAddr, dstAddr, protoUDP, transport)
+}
+
+// newIPPacket prepends the IPv4 or IPv6 header to the transport payload.
+func newIPPacket(src, dst netip.Addr, proto uint8, transport []byte) []byte {
+	if src.Is4() {
+		header := make([]byte, 20)
+		header[0] = 4<<4 | 5 // version and header length in 32-bit words
+		binary.BigEndian.PutUint16(header[2:], uint16(len(header)+len(transport)))
+		binary.BigEndian.PutUint16(header[6:], 0x4000) // don't fragment
+		header[8] = packetDefaultTTL
+		header[9] = proto
+		copy(header[12:], src.AsSlice())
+		copy(header[16:], dst.AsSlice())
+		binary.BigEndian.PutUint16(header[10:], packetChecksum(header))
+		return append(header, transport...)
+	}
+	header := make([]byte, 40)
+	header[0] = 6 << 4 // version
+	binary.BigEndian.PutUint16(header[4:], uint16(len(transport)))
+	header[6] = proto
+	header[7] = packetDefaultTTL
+	copy(header[8:], src.AsSlice())
+	copy(header[24:], dst.AsSlice())
+	return append(header, transport...)
+}
+
+// packetAddrs ensures that src and dst belong to the same family, which may
+// not be the case, e.g., when the local address is unspecified.
+func packetAddrs(src, dst netip.Addr) (netip.Addr, netip.Addr) {
+	src, dst = src.Unmap(), dst.Unmap()
+	if !src.IsValid() {
+		src = packetUnspecifiedAddr(dst)
+	}
+	if !dst.IsValid() {
+		dst = packetUnspecifiedAddr(src)
+	}
+	if src.Is4() != dst.Is4() {
+		src, dst = netip.AddrFrom16(src.As16()), netip.AddrFrom16(dst.As16())
+	}
+	return src, dst
+}
+
+// packetUnspecifiedAddr returns the unspecified address of the same family of addr.
+func packetUnspecifiedAddr(addr netip.Addr) netip.Addr {
+	if addr.Is6() {
+		return netip.IPv6Unspecified()
+	}
+	return netip.IPv4Unspecified()
+}
+
+// packetPseudoHeader returns the pseudo header used to compute the TCP and UDP checksums.
+func packetPseudoHeader(src, dst netip.Addr, proto uint8, length int) []byte {
+	var out []byte
+	out = append(out, src.AsSlice()...)
+	out = append(out, dst.AsSlice()...)
+	if src.Is4() {
+		return append(out, 0, proto, byte(length>>8), byte(length))
+	}
+	return append(out, byte(length>>24), byte(length>>16), byte(length>>8), byte(length), 0, 0, 0, proto)
+}
+
+// packetChecksum computes the internet checksum of the concatenation of the given buffers.
+func packetChecksum(buffers ...[]byte) uint16 {
+	var (
+		sum uint32
+		odd bool
+	)
+	for _, buffer := range buffers {
+		for _, b := range buffer {
+			if odd {
+				sum += uint32(b)
+			} else {
+				sum += uint32(b) << 8
+			}
+			odd = !odd
+		}
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+// packetAddrPort converts a net.Addr to a netip.AddrPort, returning
+// the zero value when the conversion is not possible.
+func packetAddrPort(addr net.Addr) netip.AddrPort {
+	if addr == nil {
+		return netip.AddrPort{}
+	}
+	switch v := addr.(type) {
+	case *net.TCPAddr:
+		return v.AddrPort()
+	case *net.UDPAddr:
+		return v.AddrPort()
+	}
+	addrport, _ := netip.ParseAddrPort(addr.String())
+	return addrport
+}
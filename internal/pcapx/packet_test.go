@@ -0,0 +1,196 @@
+package pcapx
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// packetParsed contains the fields of a synthesized packet we check in tests.
+type packetParsed struct {
+	dst     netip.AddrPort
+	flags   uint8
+	payload []byte
+	proto   uint8
+	src     netip.AddrPort
+}
+
+// packetParse parses a packet synthesized by newTCPPacket or newUDPPacket
+// and fails the test if the checksums are not correct.
+func packetParse(t *testing.T, packet []byte) *packetParsed {
+	var (
+		src, dst  netip.Addr
+		proto     uint8
+		transport []byte
+	)
+	switch packet[0] >> 4 {
+	case 4:
+		if packetChecksum(packet[:20]) != 0 {
+			t.Fatal("invalid IPv4 header checksum")
+		}
+		if int(binary.BigEndian.Uint16(packet[2:])) != len(packet) {
+			t.Fatal("invalid IPv4 total length")
+		}
+		src = netip.AddrFrom4(*(*[4]byte)(packet[12:16]))
+		dst = netip.AddrFrom4(*(*[4]byte)(packet[16:20]))
+		proto, transport = packet[9], packet[20:]
+	case 6:
+		if int(binary.BigEndian.Uint16(packet[4:])) != len(packet)-40 {
+			t.Fatal("invalid IPv6 payload length")
+		}
+		src = netip.AddrFrom16(*(*[16]byte)(packet[8:24]))
+		dst = netip.AddrFrom16(*(*[16]byte)(packet[24:40]))
+		proto, transport = packet[6], packet[40:]
+	default:
+		t.Fatal("invalid IP version")
+	}
+	if packetChecksum(packetPseudoHeader(src, dst, proto, len(transport)), transport) != 0 {
+		t.Fatal("invalid transport checksum")
+	}
+	out := &packetParsed{
+		dst:   netip.AddrPortFrom(dst, binary.BigEndian.Uint16(transport[2:])),
+		proto: proto,
+		src:   netip.AddrPortFrom(src, binary.BigEndian.Uint16(transport[0:])),
+	}
+	switch proto {
+	case protoTCP:
+		out.flags = transport[13]
+		out.payload = transport[(transport[12]>>4)*4:]
+	case protoUDP:
+		if int(binary.BigEndian.Uint16(transport[4:])) != len(transport) {
+			t.Fatal("invalid UDP length")
+		}
+		out.payload = transport[8:]
+	default:
+		t.Fatal("invalid protocol")
+	}
+	return out
+}
+
+func TestNewTCPPacket(t *testing.T) {
+	type testcase struct {
+		name   string
+		src    netip.AddrPort
+		dst    netip.AddrPort
+		expect *packetParsed
+	}
+
+	cases := []testcase{{
+		name: "with IPv4 addresses",
+		src:  netip.MustParseAddrPort("10.0.0.1:54321"),
+		dst:  netip.MustParseAddrPort("8.8.8.8:443"),
+		expect: &packetParsed{
+			dst:     netip.MustParseAddrPort("8.8.8.8:443"),
+			flags:   tcpFlagPSH | tcpFlagACK,
+			payload: []byte("abc"),
+			proto:   protoTCP,
+			src:     netip.MustParseAddrPort("10.0.0.1:54321"),
+		},
+	}, {
+		name: "with IPv6 addresses",
+		src:  netip.MustParseAddrPort("[::1]:54321"),
+		dst:  netip.MustParseAddrPort("[2001:4860:4860::8888]:443"),
+		expect: &packetParsed{
+			dst:     netip.MustParseAddrPort("[2001:4860:4860::8888]:443"),
+			flags:   tcpFlagPSH | tcpFlagACK,
+			payload: []byte("abc"),
+			proto:   protoTCP,
+			src:     netip.MustParseAddrPort("[::1]:54321"),
+		},
+	}, {
+		name: "with an invalid source address",
+		src:  netip.AddrPort{},
+		dst:  netip.MustParseAddrPort("8.8.8.8:443"),
+		expect: &packetParsed{
+			dst:     netip.MustParseAddrPort("8.8.8.8:443"),
+			flags:   tcpFlagPSH | tcpFlagACK,
+			payload: []byte("abc"),
+			proto:   protoTCP,
+			src:     netip.MustParseAddrPort("0.0.0.0:0"),
+		},
+	}, {
+		name: "with IPv4-mapped IPv6 addresses",
+		src:  netip.MustParseAddrPort("[::ffff:10.0.0.1]:54321"),
+		dst:  netip.MustParseAddrPort("8.8.8.8:443"),
+		expect: &packetParsed{
+			dst:     netip.MustParseAddrPort("8.8.8.8:443"),
+			flags:   tcpFlagPSH | tcpFlagACK,
+			payload: []byte("abc"),
+			proto:   protoTCP,
+			src:     netip.MustParseAddrPort("10.0.0.1:54321"),
+		},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			packet := newTCPPacket(&tcpSegment{
+				ack:     17,
+				dst:     tc.dst,
+				flags:   tcpFlagPSH | tcpFlagACK,
+				payload: []byte("abc"),
+				seq:     11,
+				src:     tc.src,
+			})
+			got := packetParse(t, packet)
+			if diff := cmp.Diff(tc.expect, got, cmp.AllowUnexported(packetParsed{}),
+				cmp.Comparer(func(a, b netip.AddrPort) bool { return a == b })); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestNewUDPPacket(t *testing.T) {
+	src := netip.MustParseAddrPort("10.0.0.1:54321")
+	dst := netip.MustParseAddrPort("[2001:4860:4860::8888]:53")
+	got := packetParse(t, newUDPPacket(src, dst, []byte("abcde")))
+	if got.proto != protoUDP {
+		t.Fatal("unexpected protocol", got.proto)
+	}
+	if got.src.Port() != 54321 || got.dst != dst {
+		t.Fatal("unexpected addresses", got.src, got.dst)
+	}
+	if !got.src.Addr().Is6() {
+		t.Fatal("expected the source address to be converted to IPv6")
+	}
+	if string(got.payload) != "abcde" {
+		t.Fatal("unexpected payload", string(got.payload))
+	}
+}
+
+func TestPacketAddrPort(t *testing.T) {
+	type testcase struct {
+		name   string
+		addr   net.Addr
+		expect netip.AddrPort
+	}
+
+	cases := []testcase{{
+		name:   "with nil",
+		addr:   nil,
+		expect: netip.AddrPort{},
+	}, {
+		name:   "with a TCP address",
+		addr:   &net.TCPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 443},
+		expect: netip.MustParseAddrPort("[::ffff:8.8.8.8]:443"),
+	}, {
+		name:   "with an UDP address",
+		addr:   &net.UDPAddr{IP: net.IPv6loopback, Port: 53},
+		expect: netip.MustParseAddrPort("[::1]:53"),
+	}, {
+		name:   "with another address",
+		addr:   &net.IPAddr{IP: net.IPv4(8, 8, 8, 8)},
+		expect: netip.AddrPort{},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := packetAddrPort(tc.addr); got != tc.expect {
+				t.Fatal("expected", tc.expect, "got", got)
+			}
+		})
+	}
+}
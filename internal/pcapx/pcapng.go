@@ -0,0 +1,139 @@
+package pcapx
+
+//
+// Writing pcapng files
+//
+// See https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-01.html
+//
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// pcapng block types.
+const (
+	pcapngBlockSectionHeader        = 0x0A0D0D0A
+	pcapngBlockInterfaceDescription = 0x00000001
+	pcapngBlockEnhancedPacket       = 0x00000006
+	pcapngBlockDecryptionSecrets    = 0x0000000A
+)
+
+// pcapngByteOrderMagic is the byte-order magic of the section header block.
+const pcapngByteOrderMagic = 0x1A2B3C4D
+
+// pcapngLinkTypeRaw is LINKTYPE_RAW, i.e., raw IPv4 or IPv6 packets.
+const pcapngLinkTypeRaw = 101
+
+// pcapngSecretsTypeTLSKeyLog is the decryption secrets type for the
+// NSS key log format (i.e., SSLKEYLOGFILE).
+const pcapngSecretsTypeTLSKeyLog = 0x544c534b
+
+// pcapng option codes.
+const (
+	pcapngOptEndOfOpt = 0
+	pcapngOptComment  = 1
+)
+
+// pcapngPacket is a packet to write inside an enhanced packet block.
+type pcapngPacket struct {
+	// comment is an OPTIONAL comment.
+	comment string
+
+	// data contains the raw IP packet.
+	data []byte
+
+	// t is the time when we captured the packet.
+	t time.Time
+}
+
+// pcapngWriter writes a pcapng file containing a single section
+// with a single LINKTYPE_RAW interface.
+type pcapngWriter struct {
+	w io.Writer
+}
+
+// writeHeader writes the section header and the interface description blocks.
+func (pw *pcapngWriter) writeHeader() error {
+	shb := &bytes.Buffer{}
+	pcapngPutUint32(shb, pcapngByteOrderMagic)
+	pcapngPutUint16(shb, 1)                  // major version
+	pcapngPutUint16(shb, 0)                  // minor version
+	pcapngPutUint64(shb, 0xffffffffffffffff) // unknown section length
+	if err := pw.writeBlock(pcapngBlockSectionHeader, shb.Bytes()); err != nil {
+		return err
+	}
+	idb := &bytes.Buffer{}
+	pcapngPutUint16(idb, pcapngLinkTypeRaw)
+	pcapngPutUint16(idb, 0) // reserved
+	pcapngPutUint32(idb, 0) // no snaplen
+	return pw.writeBlock(pcapngBlockInterfaceDescription, idb.Bytes())
+}
+
+// writeKeyLog writes a decryption secrets block containing the given key log.
+func (pw *pcapngWriter) writeKeyLog(keylog []byte) error {
+	dsb := &bytes.Buffer{}
+	pcapngPutUint32(dsb, pcapngSecretsTypeTLSKeyLog)
+	pcapngPutUint32(dsb, uint32(len(keylog)))
+	pcapngPutPadded(dsb, keylog)
+	return pw.writeBlock(pcapngBlockDecryptionSecrets, dsb.Bytes())
+}
+
+// writePacket writes an enhanced packet block containing the given packet.
+func (pw *pcapngWriter) writePacket(packet *pcapngPacket) error {
+	epb := &bytes.Buffer{}
+	ts := uint64(packet.t.UnixMicro()) // the default resolution is microseconds
+	pcapngPutUint32(epb, 0)            // interface ID
+	pcapngPutUint32(epb, uint32(ts>>32))
+	pcapngPutUint32(epb, uint32(ts))
+	pcapngPutUint32(epb, uint32(len(packet.data))) // captured length
+	pcapngPutUint32(epb, uint32(len(packet.data))) // original length
+	pcapngPutPadded(epb, packet.data)
+	if packet.comment != "" {
+		pcapngPutUint16(epb, pcapngOptComment)
+		pcapngPutUint16(epb, uint16(len(packet.comment)))
+		pcapngPutPadded(epb, []byte(packet.comment))
+		pcapngPutUint16(epb, pcapngOptEndOfOpt)
+		pcapngPutUint16(epb, 0)
+	}
+	return pw.writeBlock(pcapngBlockEnhancedPacket, epb.Bytes())
+}
+
+// writeBlock writes a block with the given type and body, which MUST
+// already be padded to a multiple of four bytes.
+func (pw *pcapngWriter) writeBlock(blockType uint32, body []byte) error {
+	block := &bytes.Buffer{}
+	length := uint32(12 + len(body)) // type, two lengths, and body
+	pcapngPutUint32(block, blockType)
+	pcapngPutUint32(block, length)
+	block.Write(body)
+	pcapngPutUint32(block, length)
+	_, err := pw.w.Write(block.Bytes())
+	return err
+}
+
+// pcapngPutUint16 appends a little endian uint16 to the buffer.
+func pcapngPutUint16(buffer *bytes.Buffer, value uint16) {
+	buffer.Write(binary.LittleEndian.AppendUint16(nil, value))
+}
+
+// pcapngPutUint32 appends a little endian uint32 to the buffer.
+func pcapngPutUint32(buffer *bytes.Buffer, value uint32) {
+	buffer.Write(binary.LittleEndian.AppendUint32(nil, value))
+}
+
+// pcapngPutUint64 appends a little endian uint64 to the buffer.
+func pcapngPutUint64(buffer *bytes.Buffer, value uint64) {
+	buffer.Write(binary.LittleEndian.AppendUint64(nil, value))
+}
+
+// pcapngPutPadded appends data to the buffer followed by zero
+// bytes such that the length is a multiple of four bytes.
+func pcapngPutPadded(buffer *bytes.Buffer, data []byte) {
+	buffer.Write(data)
+	if rem := len(data) % 4; rem != 0 {
+		buffer.Write(make([]byte, 4-rem))
+	}
+}
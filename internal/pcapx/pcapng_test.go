@@ -0,0 +1,137 @@
+package pcapx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+// pcapngBlock is a block parsed by pcapngParse.
+type pcapngBlock struct {
+	blockType uint32
+	body      []byte
+}
+
+// pcapngParse parses a pcapng file and fails the test if it's not well formed.
+func pcapngParse(t *testing.T, data []byte) (blocks []*pcapngBlock) {
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatal("truncated block")
+		}
+		length := binary.LittleEndian.Uint32(data[4:])
+		if length%4 != 0 || int(length) > len(data) {
+			t.Fatal("invalid block length", length)
+		}
+		if binary.LittleEndian.Uint32(data[length-4:]) != length {
+			t.Fatal("mismatch between the two block lengths")
+		}
+		blocks = append(blocks, &pcapngBlock{
+			blockType: binary.LittleEndian.Uint32(data),
+			body:      data[8 : length-4],
+		})
+		data = data[length:]
+	}
+	return
+}
+
+// pcapngParseEPB returns the packet and the comment inside an enhanced packet block.
+func pcapngParseEPB(t *testing.T, block *pcapngBlock) ([]byte, string) {
+	if block.blockType != pcapngBlockEnhancedPacket {
+		t.Fatal("not an enhanced packet block")
+	}
+	captured := binary.LittleEndian.Uint32(block.body[12:])
+	packet := block.body[20 : 20+captured]
+	options := block.body[20+(captured+3)/4*4:]
+	var comment string
+	for len(options) >= 4 {
+		code := binary.LittleEndian.Uint16(options)
+		length := binary.LittleEndian.Uint16(options[2:])
+		if code == pcapngOptEndOfOpt {
+			break
+		}
+		if code == pcapngOptComment {
+			comment = string(options[4 : 4+length])
+		}
+		options = options[4+(length+3)/4*4:]
+	}
+	return packet, comment
+}
+
+func TestPcapngWriter(t *testing.T) {
+	t.Run("we write well formed blocks", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		pw := &pcapngWriter{buffer}
+		if err := pw.writeHeader(); err != nil {
+			t.Fatal(err)
+		}
+		if err := pw.writeKeyLog([]byte("CLIENT_RANDOM 00 11\n")); err != nil {
+			t.Fatal(err)
+		}
+		packet := &pcapngPacket{
+			comment: "flow 1",
+			data:    []byte{1, 2, 3, 4, 5},
+			t:       time.Unix(1, 500),
+		}
+		if err := pw.writePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+		if err := pw.writePacket(&pcapngPacket{data: []byte{6, 7, 8, 9}, t: time.Unix(2, 0)}); err != nil {
+			t.Fatal(err)
+		}
+		blocks := pcapngParse(t, buffer.Bytes())
+		if len(blocks) != 5 {
+			t.Fatal("unexpected number of blocks", len(blocks))
+		}
+		if blocks[0].blockType != pcapngBlockSectionHeader {
+			t.Fatal("expected a section header block")
+		}
+		if binary.LittleEndian.Uint32(blocks[0].body) != pcapngByteOrderMagic {
+			t.Fatal("invalid byte order magic")
+		}
+		if blocks[1].blockType != pcapngBlockInterfaceDescription {
+			t.Fatal("expected an interface description block")
+		}
+		if binary.LittleEndian.Uint16(blocks[1].body) != pcapngLinkTypeRaw {
+			t.Fatal("invalid link type")
+		}
+		if blocks[2].blockType != pcapngBlockDecryptionSecrets {
+			t.Fatal("expected a decryption secrets block")
+		}
+		if binary.LittleEndian.Uint32(blocks[2].body) != pcapngSecretsTypeTLSKeyLog {
+			t.Fatal("invalid secrets type")
+		}
+		keylogLength := binary.LittleEndian.Uint32(blocks[2].body[4:])
+		if string(blocks[2].body[8:8+keylogLength]) != "CLIENT_RANDOM 00 11\n" {
+			t.Fatal("invalid key log")
+		}
+		data, comment := pcapngParseEPB(t, blocks[3])
+		if !bytes.Equal(data, packet.data) || comment != "flow 1" {
+			t.Fatal("unexpected packet", data, comment)
+		}
+		ts := uint64(binary.LittleEndian.Uint32(blocks[3].body[4:]))<<32 |
+			uint64(binary.LittleEndian.Uint32(blocks[3].body[8:]))
+		if ts != 1000000 {
+			t.Fatal("unexpected timestamp", ts)
+		}
+		data, comment = pcapngParseEPB(t, blocks[4])
+		if !bytes.Equal(data, []byte{6, 7, 8, 9}) || comment != "" {
+			t.Fatal("unexpected packet", data, comment)
+		}
+	})
+
+	t.Run("we handle write errors", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		pw := &pcapngWriter{&mocks.Writer{
+			MockWrite: func(b []byte) (int, error) {
+				return 0, expected
+			},
+		}}
+		if err := pw.writeHeader(); !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
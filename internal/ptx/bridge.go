@@ -0,0 +1,94 @@
+package ptx
+
+//
+// Parsing tor bridge lines
+//
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BridgeLine is a parsed tor bridge line, i.e., the argument of
+// the Bridge torrc option, which looks like the following:
+//
+//	obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=... iat-mode=0
+type BridgeLine struct {
+	// Address is the bridge address.
+	Address string
+
+	// Fingerprint is the OPTIONAL bridge fingerprint.
+	Fingerprint string
+
+	// Options contains the OPTIONAL transport-specific options.
+	Options map[string]string
+
+	// Transport is the pluggable transport name.
+	Transport string
+}
+
+// ErrInvalidBridgeLine indicates that a bridge line is invalid.
+var ErrInvalidBridgeLine = errors.New("ptx: invalid bridge line")
+
+// bridgeFingerprintRe matches a bridge fingerprint.
+var bridgeFingerprintRe = regexp.MustCompile(`^[0-9A-Fa-f]{40}$`)
+
+// ParseBridgeLine parses a bridge line. We require the line to contain
+// the pluggable transport name, the bridge address, and, optionally, a
+// fingerprint and a list of key=value options.
+func ParseBridgeLine(line string) (*BridgeLine, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBridgeLine, line)
+	}
+	bl := &BridgeLine{
+		Address:     fields[1],
+		Fingerprint: "",
+		Options:     map[string]string{},
+		Transport:   fields[0],
+	}
+	if strings.Contains(bl.Transport, "=") {
+		return nil, fmt.Errorf("%w: missing transport name", ErrInvalidBridgeLine)
+	}
+	if _, _, err := net.SplitHostPort(bl.Address); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBridgeLine, err.Error())
+	}
+	fields = fields[2:]
+	if len(fields) > 0 && bridgeFingerprintRe.MatchString(fields[0]) {
+		bl.Fingerprint = fields[0]
+		fields = fields[1:]
+	}
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("%w: invalid option: %s", ErrInvalidBridgeLine, field)
+		}
+		bl.Options[key] = value
+	}
+	return bl, nil
+}
+
+// String returns the bridge line. Options are sorted by key.
+func (bl *BridgeLine) String() string {
+	v := []string{bl.Transport, bl.Address}
+	if bl.Fingerprint != "" {
+		v = append(v, bl.Fingerprint)
+	}
+	for _, key := range bl.sortedOptionKeys() {
+		v = append(v, key+"="+bl.Options[key])
+	}
+	return strings.Join(v, " ")
+}
+
+// sortedOptionKeys returns the option keys in sorted order.
+func (bl *BridgeLine) sortedOptionKeys() (keys []string) {
+	for key := range bl.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return
+}
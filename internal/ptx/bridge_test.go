@@ -0,0 +1,81 @@
+package ptx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBridgeLine(t *testing.T) {
+	const fingerprint = "0123456789ABCDEF0123456789ABCDEF01234567"
+	tests := map[string]struct {
+		line      string
+		expect    *BridgeLine
+		expectErr error
+	}{
+		"with a full obfs4 bridge line": {
+			line: "obfs4 192.0.2.1:443 " + fingerprint + " cert=xyz iat-mode=0",
+			expect: &BridgeLine{
+				Address:     "192.0.2.1:443",
+				Fingerprint: fingerprint,
+				Options:     map[string]string{"cert": "xyz", "iat-mode": "0"},
+				Transport:   "obfs4",
+			},
+		},
+		"without fingerprint": {
+			line: "meek 192.0.2.1:80 url=https://example.com/",
+			expect: &BridgeLine{
+				Address:   "192.0.2.1:80",
+				Options:   map[string]string{"url": "https://example.com/"},
+				Transport: "meek",
+			},
+		},
+		"with only transport and address": {
+			line: "snowflake 192.0.2.3:80",
+			expect: &BridgeLine{
+				Address:   "192.0.2.3:80",
+				Options:   map[string]string{},
+				Transport: "snowflake",
+			},
+		},
+		"with too few fields": {
+			line:      "obfs4",
+			expectErr: ErrInvalidBridgeLine,
+		},
+		"without transport": {
+			line:      "cert=xyz 192.0.2.1:443",
+			expectErr: ErrInvalidBridgeLine,
+		},
+		"with invalid address": {
+			line:      "obfs4 192.0.2.1",
+			expectErr: ErrInvalidBridgeLine,
+		},
+		"with invalid option": {
+			line:      "obfs4 192.0.2.1:443 cert",
+			expectErr: ErrInvalidBridgeLine,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			bl, err := ParseBridgeLine(tt.line)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tt.expect, bl); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestBridgeLineString(t *testing.T) {
+	const line = "obfs4 192.0.2.1:443 0123456789ABCDEF0123456789ABCDEF01234567 cert=xyz iat-mode=0"
+	bl, err := ParseBridgeLine(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := bl.String(); s != line {
+		t.Fatal("unexpected string", s)
+	}
+}
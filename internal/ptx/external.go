@@ -0,0 +1,252 @@
+package ptx
+
+//
+// External pluggable transports using the managed protocol
+//
+// See https://gitweb.torproject.org/torspec.git/tree/pt-spec.txt
+//
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"golang.org/x/net/proxy"
+	"golang.org/x/sys/execabs"
+)
+
+// ExternalDialer is a dialer using an external pluggable transport binary
+// speaking the pt-spec managed protocol. We start the binary the first time
+// you call DialContext and then reuse it until you call Stop. Make sure you
+// fill all the fields marked as mandatory before using.
+type ExternalDialer struct {
+	// Args contains OPTIONAL arguments for the binary.
+	Args []string
+
+	// Binary is the MANDATORY path of the pluggable transport binary.
+	Binary string
+
+	// Bridge is the MANDATORY bridge to connect to.
+	Bridge *BridgeLine
+
+	// Logger is the OPTIONAL logger. When not set, we will not emit logs.
+	Logger model.Logger
+
+	// StateDir is the MANDATORY directory where the pluggable
+	// transport should store its state.
+	StateDir string
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// proc is the running process or nil.
+	proc *externalProcess
+}
+
+var _ PTDialer = &ExternalDialer{}
+
+// ErrManagedProtocol indicates that the external binary failed
+// to setup the pluggable transport using the managed protocol.
+var ErrManagedProtocol = errors.New("ptx: managed protocol error")
+
+// DialContext establishes a connection with the bridge using the
+// external pluggable transport. The context argument allows to
+// interrupt this operation midway.
+func (d *ExternalDialer) DialContext(ctx context.Context) (net.Conn, error) {
+	addr, err := d.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the code at proxy/socks5.go never fails; see https://git.io/JfJ4g
+	child, _ := proxy.SOCKS5("tcp", addr, externalSOCKSAuth(d.Bridge.Options), &net.Dialer{})
+	return child.(proxy.ContextDialer).DialContext(ctx, "tcp", d.Bridge.Address)
+}
+
+// AsBridgeArgument returns the argument to be passed to
+// the tor command line to declare this bridge.
+func (d *ExternalDialer) AsBridgeArgument() string {
+	return d.Bridge.String()
+}
+
+// Name returns the pluggable transport name.
+func (d *ExternalDialer) Name() string {
+	return d.Bridge.Transport
+}
+
+// Stop stops the external binary, if running. This method is
+// idempotent and safe to call from any goroutine. Calling DialContext
+// after Stop causes the external binary to start again.
+func (d *ExternalDialer) Stop() {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+	if d.proc != nil {
+		d.proc.stop()
+		d.proc = nil
+	}
+}
+
+// logger returns the Logger, if set, or the defaultLogger.
+func (d *ExternalDialer) logger() model.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	return model.DiscardLogger
+}
+
+// start starts the external binary, if needed, and returns
+// the address of the SOCKS5 proxy it's listening at.
+func (d *ExternalDialer) start(ctx context.Context) (string, error) {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+	if d.proc != nil {
+		return d.proc.addr, nil
+	}
+	proc, err := d.newProcess(ctx)
+	if err != nil {
+		return "", err
+	}
+	d.proc = proc
+	return proc.addr, nil
+}
+
+// externalProcess is a running external binary.
+type externalProcess struct {
+	addr  string
+	cmd   *execabs.Cmd
+	once  sync.Once
+	stdin io.Closer
+}
+
+// stop stops the external process.
+func (p *externalProcess) stop() {
+	p.once.Do(func() {
+		p.stdin.Close() // honoured because of TOR_PT_EXIT_ON_STDIN_CLOSE
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+	})
+}
+
+// newProcess starts the external binary and waits for it
+// to complete the managed protocol handshake.
+func (d *ExternalDialer) newProcess(ctx context.Context) (*externalProcess, error) {
+	if err := os.MkdirAll(d.StateDir, 0700); err != nil {
+		return nil, err
+	}
+	cmd := execabs.Command(d.Binary, d.Args...)
+	cmd.Env = append(os.Environ(),
+		"TOR_PT_MANAGED_TRANSPORT_VER=1",
+		"TOR_PT_STATE_LOCATION="+d.StateDir,
+		"TOR_PT_CLIENT_TRANSPORTS="+d.Bridge.Transport,
+		"TOR_PT_EXIT_ON_STDIN_CLOSE=1",
+	)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	d.logger().Infof("ptx: starting %s", d.Binary)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	proc := &externalProcess{cmd: cmd, stdin: stdin}
+	type result struct {
+		addr string
+		err  error
+	}
+	reader := bufio.NewReader(stdout)
+	ch := make(chan *result, 1)
+	go func() {
+		addr, err := externalParseManagedOutput(reader, d.Bridge.Transport, d.logger())
+		ch <- &result{addr, err}
+	}()
+	select {
+	case <-ctx.Done():
+		proc.stop()
+		return nil, ctx.Err()
+	case r := <-ch:
+		if r.err != nil {
+			proc.stop()
+			return nil, r.err
+		}
+		proc.addr = r.addr
+	}
+	// keep reading the output so that the binary does not block
+	go io.Copy(io.Discard, reader)
+	d.logger().Infof("ptx: %s listening at %s", d.Bridge.Transport, proc.addr)
+	return proc, nil
+}
+
+// externalParseManagedOutput parses the output emitted by the external binary
+// until CMETHODS DONE and returns the address of the SOCKS5 proxy for transport.
+func externalParseManagedOutput(reader *bufio.Reader, transport string, logger model.Logger) (string, error) {
+	var addr string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrManagedProtocol, err.Error())
+		}
+		line = strings.TrimSpace(line)
+		logger.Debugf("ptx: %s", line)
+		keyword, args, _ := strings.Cut(line, " ")
+		switch keyword {
+		case "ENV-ERROR", "VERSION-ERROR", "PROXY-ERROR":
+			return "", fmt.Errorf("%w: %s", ErrManagedProtocol, line)
+		case "CMETHOD-ERROR":
+			if name, _, _ := strings.Cut(args, " "); name == transport {
+				return "", fmt.Errorf("%w: %s", ErrManagedProtocol, line)
+			}
+		case "CMETHOD":
+			fields := strings.Fields(args)
+			if len(fields) < 3 {
+				return "", fmt.Errorf("%w: %s", ErrManagedProtocol, line)
+			}
+			if fields[0] != transport {
+				continue
+			}
+			if fields[1] != "socks5" {
+				return "", fmt.Errorf("%w: unsupported proxy type: %s", ErrManagedProtocol, fields[1])
+			}
+			addr = fields[2]
+		case "CMETHODS":
+			if addr == "" {
+				return "", fmt.Errorf("%w: no method for %s", ErrManagedProtocol, transport)
+			}
+			return addr, nil
+		}
+	}
+}
+
+// externalSOCKSAuth encodes the bridge options as SOCKS5 credentials
+// as mandated by the pt-spec or returns nil if there are no options.
+func externalSOCKSAuth(options map[string]string) *proxy.Auth {
+	if len(options) <= 0 {
+		return nil
+	}
+	bl := &BridgeLine{Options: options}
+	var v []string
+	for _, key := range bl.sortedOptionKeys() {
+		v = append(v, externalEscapeArg(key)+"="+externalEscapeArg(options[key]))
+	}
+	encoded := strings.Join(v, ";")
+	const maxLen = 255
+	if len(encoded) <= maxLen {
+		// The password cannot be empty, so we use a single NUL byte
+		return &proxy.Auth{User: encoded, Password: "\x00"}
+	}
+	return &proxy.Auth{User: encoded[:maxLen], Password: encoded[maxLen:]}
+}
+
+// externalEscapeArg escapes a key or a value for externalSOCKSAuth.
+func externalEscapeArg(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, `;`, `\;`).Replace(s)
+}
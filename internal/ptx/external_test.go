@@ -0,0 +1,207 @@
+package ptx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/armon/go-socks5"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// externalHelperEnv is the environment variable telling the test
+// binary to behave like an external pluggable transport.
+const externalHelperEnv = "OONI_PTX_TEST_HELPER_PROCESS"
+
+// externalExpectUserEnv contains the SOCKS5 username we expect.
+const externalExpectUserEnv = "OONI_PTX_TEST_EXPECT_USER"
+
+// externalTestCredentials checks the SOCKS5 credentials.
+type externalTestCredentials struct{}
+
+func (externalTestCredentials) Valid(user, password string) bool {
+	return user == os.Getenv(externalExpectUserEnv) && password == "\x00"
+}
+
+// TestExternalHelperProcess is not a real test. It's the code run by the
+// test binary when we execute it as an external pluggable transport.
+func TestExternalHelperProcess(t *testing.T) {
+	if os.Getenv(externalHelperEnv) != "1" {
+		return
+	}
+	transport := os.Getenv("TOR_PT_CLIENT_TRANSPORTS")
+	if transport == "broken" {
+		fmt.Printf("VERSION 1\nCMETHOD-ERROR %s cannot start\nCMETHODS DONE\n", transport)
+		os.Exit(0)
+	}
+	server, err := socks5.New(&socks5.Config{
+		AuthMethods: []socks5.Authenticator{
+			socks5.UserPassAuthenticator{Credentials: externalTestCredentials{}},
+		},
+	})
+	if err != nil {
+		os.Exit(1)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		os.Exit(1)
+	}
+	go server.Serve(listener)
+	fmt.Printf("VERSION 1\nCMETHOD %s socks5 %s\nCMETHODS DONE\n", transport, listener.Addr())
+	io.Copy(io.Discard, os.Stdin) // exit when stdin is closed
+	os.Exit(0)
+}
+
+// newExternalTestDialer returns an ExternalDialer using the test binary.
+func newExternalTestDialer(t *testing.T, transport, address string) *ExternalDialer {
+	t.Setenv(externalHelperEnv, "1")
+	t.Setenv(externalExpectUserEnv, "cert=a\\=b;iat-mode=0")
+	return &ExternalDialer{
+		Args:   []string{"-test.run=^TestExternalHelperProcess$"},
+		Binary: os.Args[0],
+		Bridge: &BridgeLine{
+			Address:   address,
+			Options:   map[string]string{"cert": "a=b", "iat-mode": "0"},
+			Transport: transport,
+		},
+		Logger:   model.DiscardLogger,
+		StateDir: t.TempDir(),
+	}
+}
+
+func TestExternalDialer(t *testing.T) {
+	t.Run("we can dial through the external binary", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			io.Copy(conn, conn) // echo
+		}()
+		d := newExternalTestDialer(t, "fake", listener.Addr().String())
+		defer d.Stop()
+		if d.Name() != "fake" {
+			t.Fatal("unexpected name", d.Name())
+		}
+		conn, err := d.DialContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte("abc")); err != nil {
+			t.Fatal(err)
+		}
+		buffer := make([]byte, 3)
+		if _, err := io.ReadFull(conn, buffer); err != nil {
+			t.Fatal(err)
+		}
+		if string(buffer) != "abc" {
+			t.Fatal("unexpected echo", string(buffer))
+		}
+		d.Stop()
+		d.Stop() // should be idempotent
+	})
+
+	t.Run("when the external binary cannot start the transport", func(t *testing.T) {
+		d := newExternalTestDialer(t, "broken", "127.0.0.1:1")
+		conn, err := d.DialContext(context.Background())
+		if !errors.Is(err, ErrManagedProtocol) {
+			t.Fatal("unexpected error", err)
+		}
+		if conn != nil {
+			t.Fatal("expected nil conn")
+		}
+	})
+}
+
+func TestExternalParseManagedOutput(t *testing.T) {
+	tests := map[string]struct {
+		output    string
+		expect    string
+		expectErr error
+	}{
+		"common case": {
+			output: "VERSION 1\nCMETHOD other socks4 127.0.0.1:1\nCMETHOD fake socks5 127.0.0.1:5555\nCMETHODS DONE\n",
+			expect: "127.0.0.1:5555",
+		},
+		"with ENV-ERROR": {
+			output:    "ENV-ERROR no TOR_PT_STATE_LOCATION\n",
+			expectErr: ErrManagedProtocol,
+		},
+		"with VERSION-ERROR": {
+			output:    "VERSION-ERROR no-version\n",
+			expectErr: ErrManagedProtocol,
+		},
+		"with CMETHOD-ERROR for another transport": {
+			output: "VERSION 1\nCMETHOD-ERROR other failed\nCMETHOD fake socks5 127.0.0.1:5555\nCMETHODS DONE\n",
+			expect: "127.0.0.1:5555",
+		},
+		"with CMETHOD-ERROR for our transport": {
+			output:    "VERSION 1\nCMETHOD-ERROR fake failed\nCMETHODS DONE\n",
+			expectErr: ErrManagedProtocol,
+		},
+		"with invalid CMETHOD": {
+			output:    "VERSION 1\nCMETHOD fake\n",
+			expectErr: ErrManagedProtocol,
+		},
+		"with unsupported proxy type": {
+			output:    "VERSION 1\nCMETHOD fake socks4 127.0.0.1:5555\n",
+			expectErr: ErrManagedProtocol,
+		},
+		"without any method": {
+			output:    "VERSION 1\nCMETHODS DONE\n",
+			expectErr: ErrManagedProtocol,
+		},
+		"with premature EOF": {
+			output:    "VERSION 1\nCMETHOD fake socks5 127.0.0.1:5555\n",
+			expectErr: ErrManagedProtocol,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			reader := bufio.NewReader(strings.NewReader(tt.output))
+			addr, err := externalParseManagedOutput(reader, "fake", model.DiscardLogger)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if addr != tt.expect {
+				t.Fatal("unexpected addr", addr)
+			}
+		})
+	}
+}
+
+func TestExternalSOCKSAuth(t *testing.T) {
+	t.Run("without options", func(t *testing.T) {
+		if auth := externalSOCKSAuth(nil); auth != nil {
+			t.Fatal("expected nil auth")
+		}
+	})
+
+	t.Run("with short options", func(t *testing.T) {
+		auth := externalSOCKSAuth(map[string]string{"b": "x;y", "a": `\`})
+		if auth.User != `a=\\;b=x\;y` || auth.Password != "\x00" {
+			t.Fatal("unexpected auth", auth)
+		}
+	})
+
+	t.Run("with long options", func(t *testing.T) {
+		value := strings.Repeat("x", 300)
+		auth := externalSOCKSAuth(map[string]string{"k": value})
+		if len(auth.User) != 255 || auth.User+auth.Password != "k="+value {
+			t.Fatal("unexpected auth", auth)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+package registry
+
+//
+// Registers the `dslxrunner' experiment.
+//
+
+import (
+	"github.com/ooni/probe-cli/v3/internal/experiment/dslxrunner"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	AllExperiments["dslxrunner"] = &Factory{
+		build: func(config interface{}) model.ExperimentMeasurer {
+			return dslxrunner.NewExperimentMeasurer(
+				*config.(*dslxrunner.Config),
+			)
+		},
+		config:      &dslxrunner.Config{},
+		inputPolicy: model.InputOptional,
+	}
+}
@@ -5,10 +5,14 @@ package registry
 //
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/iancoleman/strcase"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -59,9 +63,26 @@ var (
 	// ErrCannotSetStringOption means SetOptionAny couldn't set a string option.
 	ErrCannotSetStringOption = errors.New("cannot set string option")
 
+	// ErrCannotSetFloatOption means SetOptionAny couldn't set a float option.
+	ErrCannotSetFloatOption = errors.New("cannot set float option")
+
+	// ErrCannotSetDurationOption means SetOptionAny couldn't set a time.Duration option.
+	ErrCannotSetDurationOption = errors.New("cannot set duration option")
+
+	// ErrCannotSetStringSliceOption means SetOptionAny couldn't set a []string option.
+	ErrCannotSetStringSliceOption = errors.New("cannot set string slice option")
+
+	// ErrCannotSetJSONOption means SetOptionAny couldn't set a struct, map, or
+	// slice option, which we set by round tripping the value through JSON.
+	ErrCannotSetJSONOption = errors.New("cannot set option from JSON")
+
 	// ErrUnsupportedOptionType means we don't support the type passed to
 	// the SetOptionAny method as an opaque any type.
 	ErrUnsupportedOptionType = errors.New("unsupported option type")
+
+	// ErrInvalidOptionValue means the value passed to SetOptionAny does not
+	// satisfy the constraints declared in the field's `ooni` struct tag.
+	ErrInvalidOptionValue = errors.New("invalid option value")
 )
 
 // Options returns the options exposed by this experiment.
@@ -77,9 +98,11 @@ func (b *Factory) Options() (map[string]model.ExperimentOptionInfo, error) {
 	}
 	for i := 0; i < structinfo.NumField(); i++ {
 		field := structinfo.Field(i)
+		tag := parseOptionTag(field.Tag.Get("ooni"))
 		result[field.Name] = model.ExperimentOptionInfo{
-			Doc:  field.Tag.Get("ooni"),
-			Type: field.Type.String(),
+			Doc:    tag.Doc,
+			Schema: optionSchema(field.Type, tag),
+			Type:   field.Type.String(),
 		}
 	}
 	return result, nil
@@ -104,34 +127,105 @@ func (b *Factory) setOptionBool(field reflect.Value, value any) error {
 
 // setOptionInt sets an int option
 func (b *Factory) setOptionInt(field reflect.Value, value any) error {
+	var number int64
 	switch v := value.(type) {
 	case int64:
-		field.SetInt(v)
-		return nil
+		number = v
 	case int32:
-		field.SetInt(int64(v))
-		return nil
+		number = int64(v)
 	case int16:
-		field.SetInt(int64(v))
-		return nil
+		number = int64(v)
 	case int8:
-		field.SetInt(int64(v))
-		return nil
+		number = int64(v)
 	case int:
+		number = int64(v)
+	case float64:
+		// Note: this is how encoding/json represents JSON numbers
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return fmt.Errorf("%w: %v is not an integer", ErrCannotSetIntegerOption, v)
+		}
+		number = int64(v)
+	case string:
+		var err error
+		number, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrCannotSetIntegerOption, err.Error())
+		}
+	default:
+		return fmt.Errorf("%w from a value of type %T", ErrCannotSetIntegerOption, value)
+	}
+	if field.OverflowInt(number) {
+		return fmt.Errorf("%w: %d overflows %s", ErrCannotSetIntegerOption, number, field.Type())
+	}
+	field.SetInt(number)
+	return nil
+}
+
+// setOptionFloat sets a float option
+func (b *Factory) setOptionFloat(field reflect.Value, value any) error {
+	var number float64
+	switch v := value.(type) {
+	case float64:
+		number = v
+	case float32:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	case int:
+		number = float64(v)
+	case string:
+		var err error
+		number, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrCannotSetFloatOption, err.Error())
+		}
+	default:
+		return fmt.Errorf("%w from a value of type %T", ErrCannotSetFloatOption, value)
+	}
+	field.SetFloat(number)
+	return nil
+}
+
+// setOptionDuration sets a time.Duration option. Strings use the time.ParseDuration
+// syntax (e.g., "300ms") while numbers, including strings containing an integer,
+// are milliseconds, which is what the options we migrated to time.Duration used
+// to take (e.g., the Delay of dnsping, which used to be an int64 in milliseconds).
+func (b *Factory) setOptionDuration(field reflect.Value, value any) error {
+	switch v := value.(type) {
+	case time.Duration:
 		field.SetInt(int64(v))
 		return nil
 	case string:
-		number, err := strconv.ParseInt(v, 10, 64)
+		if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return b.setOptionDurationMillis(field, v)
+		}
+		duration, err := time.ParseDuration(v)
 		if err != nil {
-			return fmt.Errorf("%w: %s", ErrCannotSetIntegerOption, err.Error())
+			return fmt.Errorf("%w: %s", ErrCannotSetDurationOption, err.Error())
 		}
-		field.SetInt(number)
+		field.SetInt(int64(duration))
 		return nil
+	case int64, int, float64:
+		return b.setOptionDurationMillis(field, value)
 	default:
-		return fmt.Errorf("%w from a value of type %T", ErrCannotSetIntegerOption, value)
+		return fmt.Errorf("%w from a value of type %T", ErrCannotSetDurationOption, value)
 	}
 }
 
+// setOptionDurationMillis sets a time.Duration option from a number of milliseconds.
+func (b *Factory) setOptionDurationMillis(field reflect.Value, value any) error {
+	if err := b.setOptionInt(field, value); err != nil {
+		return fmt.Errorf("%w: %s", ErrCannotSetDurationOption, err.Error())
+	}
+	millis := field.Int()
+	if millis > math.MaxInt64/int64(time.Millisecond) || millis < math.MinInt64/int64(time.Millisecond) {
+		field.SetInt(0)
+		return fmt.Errorf("%w: %d milliseconds overflows time.Duration", ErrCannotSetDurationOption, millis)
+	}
+	field.SetInt(millis * int64(time.Millisecond))
+	return nil
+}
+
 // setOptionString sets a string option
 func (b *Factory) setOptionString(field reflect.Value, value any) error {
 	switch v := value.(type) {
@@ -143,24 +237,153 @@ func (b *Factory) setOptionString(field reflect.Value, value any) error {
 	}
 }
 
-// SetOptionAny sets an option given any value.
-func (b *Factory) SetOptionAny(key string, value any) error {
-	field, err := b.fieldbyname(b.config, key)
-	if err != nil {
-		return err
+// setOptionStringSlice sets a []string option. A string value is parsed as a JSON
+// array when it starts with "[" and is otherwise split on whitespace, which is
+// compatible with the space-separated lists previously used by experiments.
+func (b *Factory) setOptionStringSlice(field reflect.Value, value any) error {
+	var list []string
+	switch v := value.(type) {
+	case []string:
+		list = append(list, v...)
+	case []any:
+		for _, entry := range v {
+			s, good := entry.(string)
+			if !good {
+				return fmt.Errorf("%w from a list entry of type %T", ErrCannotSetStringSliceOption, entry)
+			}
+			list = append(list, s)
+		}
+	case string:
+		if !strings.HasPrefix(strings.TrimSpace(v), "[") {
+			list = append(list, strings.Fields(v)...)
+			break
+		}
+		if err := json.Unmarshal([]byte(v), &list); err != nil {
+			return fmt.Errorf("%w: %s", ErrCannotSetStringSliceOption, err.Error())
+		}
+	default:
+		return fmt.Errorf("%w from a value of type %T", ErrCannotSetStringSliceOption, value)
+	}
+	field.Set(reflect.ValueOf(list).Convert(field.Type()))
+	return nil
+}
+
+// setOptionJSON sets a struct, map, or slice option by round tripping the value
+// through JSON. A string value is parsed as the JSON serialization of the option.
+//
+// The field MUST contain a deep copy of the current value (see optionDeepCopy). For
+// structs, we decode into such a copy, so the fields missing from the JSON keep their
+// current (i.e., default) value. Maps and slices are instead replaced.
+func (b *Factory) setOptionJSON(field reflect.Value, value any) error {
+	data, good := value.(string)
+	if !good {
+		rawData, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrCannotSetJSONOption, err.Error())
+		}
+		data = string(rawData)
+	}
+	if field.Kind() != reflect.Struct {
+		field.Set(reflect.Zero(field.Type()))
+	}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(field.Addr().Interface()); err != nil {
+		return fmt.Errorf("%w: %s", ErrCannotSetJSONOption, err.Error())
+	}
+	return nil
+}
+
+// optionDeepCopy returns a settable deep copy of value, such that modifying the maps,
+// slices, and pointers inside the copy does not modify the ones inside value.
+func optionDeepCopy(value reflect.Value) reflect.Value {
+	out := reflect.New(value.Type()).Elem()
+	out.Set(value) // also copies the unexported fields of structs
+	switch value.Kind() {
+	case reflect.Struct:
+		for idx := 0; idx < value.NumField(); idx++ {
+			if out.Field(idx).CanSet() {
+				out.Field(idx).Set(optionDeepCopy(value.Field(idx)))
+			}
+		}
+	case reflect.Array:
+		for idx := 0; idx < value.Len(); idx++ {
+			out.Index(idx).Set(optionDeepCopy(value.Index(idx)))
+		}
+	case reflect.Map:
+		if !value.IsNil() {
+			out.Set(reflect.MakeMapWithSize(value.Type(), value.Len()))
+			iter := value.MapRange()
+			for iter.Next() {
+				out.SetMapIndex(iter.Key(), optionDeepCopy(iter.Value()))
+			}
+		}
+	case reflect.Slice:
+		if !value.IsNil() {
+			out.Set(reflect.MakeSlice(value.Type(), value.Len(), value.Len()))
+			for idx := 0; idx < value.Len(); idx++ {
+				out.Index(idx).Set(optionDeepCopy(value.Index(idx)))
+			}
+		}
+	case reflect.Pointer:
+		if !value.IsNil() {
+			out.Set(reflect.New(value.Type().Elem()))
+			out.Elem().Set(optionDeepCopy(value.Elem()))
+		}
+	}
+	return out
+}
+
+// durationType is the reflect.Type of time.Duration.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setOptionValue sets field from value depending on the field's type.
+func (b *Factory) setOptionValue(field reflect.Value, value any) error {
+	if field.Type() == durationType {
+		return b.setOptionDuration(field, value)
 	}
 	switch field.Kind() {
-	case reflect.Int64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return b.setOptionInt(field, value)
+	case reflect.Float32, reflect.Float64:
+		return b.setOptionFloat(field, value)
 	case reflect.Bool:
 		return b.setOptionBool(field, value)
 	case reflect.String:
 		return b.setOptionString(field, value)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			return b.setOptionStringSlice(field, value)
+		}
+		return b.setOptionJSON(field, value)
+	case reflect.Map, reflect.Struct:
+		return b.setOptionJSON(field, value)
 	default:
 		return fmt.Errorf("%w: %T", ErrUnsupportedOptionType, value)
 	}
 }
 
+// SetOptionAny sets an option given any value. We only modify the option
+// when the value satisfies the constraints declared in the `ooni` tag. When
+// setting a struct option, the fields not in value keep their current value.
+func (b *Factory) SetOptionAny(key string, value any) error {
+	field, err := b.fieldbyname(b.config, key)
+	if err != nil {
+		return err
+	}
+	newValue := optionDeepCopy(field)
+	if err := b.setOptionValue(newValue, value); err != nil {
+		return err
+	}
+	structField, _ := reflect.TypeOf(b.config).Elem().FieldByName(key) // cannot fail
+	tag := parseOptionTag(structField.Tag.Get("ooni"))
+	if err := validateOption(key, tag, newValue); err != nil {
+		return err
+	}
+	field.Set(newValue)
+	return nil
+}
+
 // SetOptionsAny calls SetOptionAny for each entry inside [options].
 func (b *Factory) SetOptionsAny(options map[string]any) error {
 	for key, value := range options {
@@ -2,7 +2,10 @@ package registry
 
 import (
 	"errors"
+	"math"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -345,3 +348,345 @@ func TestExperimentBuilderSetOptionsAny(t *testing.T) {
 		}
 	})
 }
+
+type fakeNestedExperimentConfig struct {
+	Name  string `json:"name" ooni:"a name;enum=a|b"`
+	Count int64  `json:"count" ooni:"a count;min=1"`
+}
+
+type fakeTypedExperimentConfig struct {
+	Delay   time.Duration              `ooni:"a delay;min=1ms;max=1s"`
+	Headers map[string]string          `ooni:"some headers;max=2"`
+	List    []string                   `ooni:"a list;enum=h2|http/1.1"`
+	Nested  fakeNestedExperimentConfig `ooni:"a nested struct"`
+	Ratio   float64                    `ooni:"a ratio;min=0;max=1"`
+	Small   int8                       `ooni:"a small number"`
+}
+
+func TestExperimentBuilderSetOptionAnyTyped(t *testing.T) {
+	var inputs = []struct {
+		TestCaseName string
+		FieldName    string
+		FieldValue   any
+		ExpectErr    error
+		ExpectConfig *fakeTypedExperimentConfig
+	}{{
+		TestCaseName: "[duration] for string",
+		FieldName:    "Delay",
+		FieldValue:   "300ms",
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Delay: 300 * time.Millisecond},
+	}, {
+		TestCaseName: "[duration] for time.Duration",
+		FieldName:    "Delay",
+		FieldValue:   time.Second,
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Delay: time.Second},
+	}, {
+		TestCaseName: "[duration] for legacy JSON number of milliseconds",
+		FieldName:    "Delay",
+		FieldValue:   float64(250),
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Delay: 250 * time.Millisecond},
+	}, {
+		TestCaseName: "[duration] for legacy int64 milliseconds",
+		FieldName:    "Delay",
+		FieldValue:   int64(1000),
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Delay: time.Second},
+	}, {
+		TestCaseName: "[duration] for legacy string containing milliseconds",
+		FieldName:    "Delay",
+		FieldValue:   "500",
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Delay: 500 * time.Millisecond},
+	}, {
+		TestCaseName: "[duration] for milliseconds overflowing time.Duration",
+		FieldName:    "Delay",
+		FieldValue:   int64(math.MaxInt64),
+		ExpectErr:    ErrCannotSetDurationOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[duration] for invalid string",
+		FieldName:    "Delay",
+		FieldValue:   "xx",
+		ExpectErr:    ErrCannotSetDurationOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[duration] for non integral JSON number",
+		FieldName:    "Delay",
+		FieldValue:   1.5,
+		ExpectErr:    ErrCannotSetDurationOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[duration] for type we don't know how to convert",
+		FieldName:    "Delay",
+		FieldValue:   true,
+		ExpectErr:    ErrCannotSetDurationOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[duration] for value violating the max constraint",
+		FieldName:    "Delay",
+		FieldValue:   "2s",
+		ExpectErr:    ErrInvalidOptionValue,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[float] for float64",
+		FieldName:    "Ratio",
+		FieldValue:   0.5,
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Ratio: 0.5},
+	}, {
+		TestCaseName: "[float] for int",
+		FieldName:    "Ratio",
+		FieldValue:   1,
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Ratio: 1},
+	}, {
+		TestCaseName: "[float] for string",
+		FieldName:    "Ratio",
+		FieldValue:   "0.25",
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Ratio: 0.25},
+	}, {
+		TestCaseName: "[float] for invalid string",
+		FieldName:    "Ratio",
+		FieldValue:   "xx",
+		ExpectErr:    ErrCannotSetFloatOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[float] for type we don't know how to convert",
+		FieldName:    "Ratio",
+		FieldValue:   true,
+		ExpectErr:    ErrCannotSetFloatOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[float] for value violating the min constraint",
+		FieldName:    "Ratio",
+		FieldValue:   -0.5,
+		ExpectErr:    ErrInvalidOptionValue,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[int] for JSON number",
+		FieldName:    "Small",
+		FieldValue:   float64(17),
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Small: 17},
+	}, {
+		TestCaseName: "[int] for non integral JSON number",
+		FieldName:    "Small",
+		FieldValue:   1.5,
+		ExpectErr:    ErrCannotSetIntegerOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[int] for value overflowing the field",
+		FieldName:    "Small",
+		FieldValue:   1024,
+		ExpectErr:    ErrCannotSetIntegerOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[[]string] for []string",
+		FieldName:    "List",
+		FieldValue:   []string{"h2", "http/1.1"},
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{List: []string{"h2", "http/1.1"}},
+	}, {
+		TestCaseName: "[[]string] for []any",
+		FieldName:    "List",
+		FieldValue:   []any{"h2"},
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{List: []string{"h2"}},
+	}, {
+		TestCaseName: "[[]string] for []any containing a number",
+		FieldName:    "List",
+		FieldValue:   []any{"h2", 11},
+		ExpectErr:    ErrCannotSetStringSliceOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[[]string] for string containing a JSON array",
+		FieldName:    "List",
+		FieldValue:   `["h2", "http/1.1"]`,
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{List: []string{"h2", "http/1.1"}},
+	}, {
+		TestCaseName: "[[]string] for string containing an invalid JSON array",
+		FieldName:    "List",
+		FieldValue:   `["h2", `,
+		ExpectErr:    ErrCannotSetStringSliceOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[[]string] for any other string",
+		FieldName:    "List",
+		FieldValue:   "h2",
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{List: []string{"h2"}},
+	}, {
+		TestCaseName: "[[]string] for space-separated string",
+		FieldName:    "List",
+		FieldValue:   " h2  http/1.1 ",
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{List: []string{"h2", "http/1.1"}},
+	}, {
+		TestCaseName: "[[]string] for type we don't know how to convert",
+		FieldName:    "List",
+		FieldValue:   17,
+		ExpectErr:    ErrCannotSetStringSliceOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[[]string] for value violating the enum constraint",
+		FieldName:    "List",
+		FieldValue:   []string{"h2", "h3"},
+		ExpectErr:    ErrInvalidOptionValue,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[map] for map",
+		FieldName:    "Headers",
+		FieldValue:   map[string]any{"Accept": "*/*"},
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Headers: map[string]string{"Accept": "*/*"}},
+	}, {
+		TestCaseName: "[map] for string containing a JSON object",
+		FieldName:    "Headers",
+		FieldValue:   `{"Accept": "*/*"}`,
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Headers: map[string]string{"Accept": "*/*"}},
+	}, {
+		TestCaseName: "[map] for value with the wrong type",
+		FieldName:    "Headers",
+		FieldValue:   map[string]any{"Accept": 17},
+		ExpectErr:    ErrCannotSetJSONOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[map] for value we cannot serialize",
+		FieldName:    "Headers",
+		FieldValue:   make(chan any),
+		ExpectErr:    ErrCannotSetJSONOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[map] for value violating the max constraint",
+		FieldName:    "Headers",
+		FieldValue:   map[string]any{"A": "a", "B": "b", "C": "c"},
+		ExpectErr:    ErrInvalidOptionValue,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[struct] for map",
+		FieldName:    "Nested",
+		FieldValue:   map[string]any{"name": "a", "count": 4},
+		ExpectErr:    nil,
+		ExpectConfig: &fakeTypedExperimentConfig{Nested: fakeNestedExperimentConfig{Name: "a", Count: 4}},
+	}, {
+		TestCaseName: "[struct] for unknown field",
+		FieldName:    "Nested",
+		FieldValue:   map[string]any{"name": "a", "count": 4, "antani": true},
+		ExpectErr:    ErrCannotSetJSONOption,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[struct] for nested value violating the enum constraint",
+		FieldName:    "Nested",
+		FieldValue:   `{"name": "c", "count": 4}`,
+		ExpectErr:    ErrInvalidOptionValue,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}, {
+		TestCaseName: "[struct] for nested value violating the min constraint",
+		FieldName:    "Nested",
+		FieldValue:   `{"name": "a", "count": 0}`,
+		ExpectErr:    ErrInvalidOptionValue,
+		ExpectConfig: &fakeTypedExperimentConfig{},
+	}}
+
+	for _, input := range inputs {
+		t.Run(input.TestCaseName, func(t *testing.T) {
+			ec := &fakeTypedExperimentConfig{}
+			b := &Factory{config: ec}
+			err := b.SetOptionAny(input.FieldName, input.FieldValue)
+			if !errors.Is(err, input.ExpectErr) {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(input.ExpectConfig, ec); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestExperimentBuilderSetOptionAnyKeepsCurrentValues(t *testing.T) {
+	// newConfig returns a config containing nondefault values.
+	newConfig := func() *fakeTypedExperimentConfig {
+		return &fakeTypedExperimentConfig{
+			Headers: map[string]string{"Accept": "*/*"},
+			List:    []string{"h2", "http/1.1"},
+			Nested:  fakeNestedExperimentConfig{Name: "a", Count: 4},
+		}
+	}
+
+	t.Run("we only override the struct fields inside the value", func(t *testing.T) {
+		ec := newConfig()
+		b := &Factory{config: ec}
+		if err := b.SetOptionAny("Nested", `{"count": 2}`); err != nil {
+			t.Fatal(err)
+		}
+		expect := newConfig()
+		expect.Nested.Count = 2
+		if diff := cmp.Diff(expect, ec); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we replace maps and slices", func(t *testing.T) {
+		ec := newConfig()
+		b := &Factory{config: ec}
+		if err := b.SetOptionAny("Headers", `{"Host": "example.com"}`); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SetOptionAny("List", `["http/1.1"]`); err != nil {
+			t.Fatal(err)
+		}
+		expect := newConfig()
+		expect.Headers = map[string]string{"Host": "example.com"}
+		expect.List = []string{"http/1.1"}
+		if diff := cmp.Diff(expect, ec); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we do not modify the current value on failure", func(t *testing.T) {
+		ec := newConfig()
+		b := &Factory{config: ec}
+		err := b.SetOptionAny("Nested", `{"name": "c"}`)
+		if !errors.Is(err, ErrInvalidOptionValue) {
+			t.Fatal("unexpected error", err)
+		}
+		if diff := cmp.Diff(newConfig(), ec); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
+
+func TestOptionDeepCopy(t *testing.T) {
+	type inner struct {
+		Map   map[string][]string
+		Ptr   *int64
+		Array [1][]string
+	}
+	value := int64(17)
+	orig := &inner{
+		Map:   map[string][]string{"a": {"b"}},
+		Ptr:   &value,
+		Array: [1][]string{{"c"}},
+	}
+	copied := optionDeepCopy(reflect.ValueOf(orig).Elem()).Addr().Interface().(*inner)
+	if diff := cmp.Diff(orig, copied); diff != "" {
+		t.Fatal(diff)
+	}
+	copied.Map["a"][0] = "x"
+	*copied.Ptr = 18
+	copied.Array[0][0] = "y"
+	expect := &inner{
+		Map:   map[string][]string{"a": {"b"}},
+		Ptr:   &value,
+		Array: [1][]string{{"c"}},
+	}
+	if diff := cmp.Diff(expect, orig); diff != "" || value != 17 {
+		t.Fatal("the original value has been modified", diff)
+	}
+}
@@ -0,0 +1,277 @@
+package registry
+
+//
+// Constraints and JSON Schema for experiment options.
+//
+// The `ooni` struct tag contains the option's documentation optionally
+// followed by semicolon-separated constraints. For example:
+//
+//	Repetitions int64 `ooni:"number of repetitions;min=1;max=10"`
+//	Protocol string   `ooni:"protocol to use;enum=tcp|udp"`
+//
+// The min and max constraints bound the value of integer, float, and time.Duration
+// options and the length of string, slice, and map options. The enum constraint lists
+// the values allowed for string options and for the elements of []string options.
+//
+// We use the time.ParseDuration syntax (e.g., "300ms") for time.Duration constraints
+// and values, and we also accept values expressed as integer milliseconds.
+//
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// optionTag is the parsed content of an `ooni` struct tag.
+type optionTag struct {
+	// Doc is the documentation without constraints.
+	Doc string
+
+	// Enum contains the allowed values or is empty.
+	Enum []string
+
+	// Max is the max constraint or empty.
+	Max string
+
+	// Min is the min constraint or empty.
+	Min string
+}
+
+// parseOptionTag parses the content of an `ooni` struct tag. Semicolon-separated
+// parts that are not constraints are considered part of the documentation.
+func parseOptionTag(value string) optionTag {
+	parts := strings.Split(value, ";")
+	tag := optionTag{}
+	doc := []string{parts[0]}
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "enum":
+			tag.Enum = strings.Split(value, "|")
+		case "max":
+			tag.Max = value
+		case "min":
+			tag.Min = value
+		default:
+			doc = append(doc, part)
+		}
+	}
+	tag.Doc = strings.Join(doc, ";")
+	return tag
+}
+
+// validateOption returns an error if the given value does not satisfy the constraints
+// inside the given tag. We recursively validate the fields of struct values.
+func validateOption(name string, tag optionTag, value reflect.Value) error {
+	if value.Type() == durationType {
+		return validateOptionRange(name, tag, time.Duration(value.Int()), parseOptionDuration)
+	}
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return validateOptionRange(name, tag, value.Int(), parseOptionInt)
+	case reflect.Float32, reflect.Float64:
+		return validateOptionRange(name, tag, value.Float(), parseOptionFloat)
+	case reflect.String:
+		if err := validateOptionEnum(name, tag, value.String()); err != nil {
+			return err
+		}
+		return validateOptionRange(name, tag, int64(value.Len()), parseOptionInt)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.String {
+			for idx := 0; idx < value.Len(); idx++ {
+				if err := validateOptionEnum(name, tag, value.Index(idx).String()); err != nil {
+					return err
+				}
+			}
+		}
+		return validateOptionRange(name, tag, int64(value.Len()), parseOptionInt)
+	case reflect.Map:
+		return validateOptionRange(name, tag, int64(value.Len()), parseOptionInt)
+	case reflect.Struct:
+		for idx := 0; idx < value.NumField(); idx++ {
+			field := value.Type().Field(idx)
+			if !field.IsExported() {
+				continue
+			}
+			fieldName := name + "." + field.Name
+			fieldTag := parseOptionTag(field.Tag.Get("ooni"))
+			if err := validateOption(fieldName, fieldTag, value.Field(idx)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+// validateOptionEnum checks whether value is one of the values allowed by the tag.
+func validateOptionEnum(name string, tag optionTag, value string) error {
+	if len(tag.Enum) <= 0 {
+		return nil
+	}
+	for _, allowed := range tag.Enum {
+		if value == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s: %q is not one of %q", ErrInvalidOptionValue, name, value, tag.Enum)
+}
+
+// validateOptionRange checks whether value is within the range declared by the tag.
+func validateOptionRange[T int64 | float64 | time.Duration](
+	name string, tag optionTag, value T, parse func(string) (T, error)) error {
+	if tag.Min != "" {
+		min, err := parse(tag.Min)
+		if err != nil {
+			return fmt.Errorf("%w: %s: invalid min constraint: %s", ErrInvalidOptionValue, name, err.Error())
+		}
+		if value < min {
+			return fmt.Errorf("%w: %s: %v is lower than %v", ErrInvalidOptionValue, name, value, min)
+		}
+	}
+	if tag.Max != "" {
+		max, err := parse(tag.Max)
+		if err != nil {
+			return fmt.Errorf("%w: %s: invalid max constraint: %s", ErrInvalidOptionValue, name, err.Error())
+		}
+		if value > max {
+			return fmt.Errorf("%w: %s: %v is greater than %v", ErrInvalidOptionValue, name, value, max)
+		}
+	}
+	return nil
+}
+
+// parseOptionDuration parses a time.Duration constraint.
+func parseOptionDuration(value string) (time.Duration, error) {
+	return time.ParseDuration(value)
+}
+
+// parseOptionDurationMilliseconds parses a time.Duration constraint as milliseconds.
+func parseOptionDurationMilliseconds(value string) (int64, error) {
+	duration, err := time.ParseDuration(value)
+	return duration.Milliseconds(), err
+}
+
+// optionDurationPattern is the regular expression matching the strings
+// accepted by time.ParseDuration (e.g., "300ms", "1.5h", "2h45m").
+const optionDurationPattern = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$`
+
+// parseOptionInt parses an integer constraint.
+func parseOptionInt(value string) (int64, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// parseOptionFloat parses a float constraint.
+func parseOptionFloat(value string) (float64, error) {
+	return strconv.ParseFloat(value, 64)
+}
+
+// optionSchema returns the JSON Schema describing an option with the given type and
+// tag or nil if we cannot represent values of the given type as JSON.
+func optionSchema(t reflect.Type, tag optionTag) map[string]any {
+	schema := map[string]any{}
+	if tag.Doc != "" {
+		schema["description"] = tag.Doc
+	}
+	if t == durationType {
+		// Note: we cannot use the "duration" format, which is ISO 8601, because
+		// we use the time.ParseDuration syntax. We also accept milliseconds, for
+		// which we can express the min and max constraints.
+		schema["type"] = []string{"string", "integer"}
+		schema["pattern"] = optionDurationPattern
+		optionSchemaRange(schema, tag, "minimum", "maximum", parseOptionDurationMilliseconds)
+		return schema
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		schema["type"] = "integer"
+		optionSchemaRange(schema, tag, "minimum", "maximum", parseOptionInt)
+	case reflect.Float32, reflect.Float64:
+		schema["type"] = "number"
+		optionSchemaRange(schema, tag, "minimum", "maximum", parseOptionFloat)
+	case reflect.String:
+		schema["type"] = "string"
+		if len(tag.Enum) > 0 {
+			schema["enum"] = tag.Enum
+		}
+		optionSchemaRange(schema, tag, "minLength", "maxLength", parseOptionInt)
+	case reflect.Slice:
+		items := optionSchema(t.Elem(), optionTag{Enum: tag.Enum})
+		if items == nil {
+			return nil
+		}
+		schema["type"] = "array"
+		schema["items"] = items
+		optionSchemaRange(schema, tag, "minItems", "maxItems", parseOptionInt)
+	case reflect.Map:
+		values := optionSchema(t.Elem(), optionTag{})
+		if t.Key().Kind() != reflect.String || values == nil {
+			return nil
+		}
+		schema["type"] = "object"
+		schema["additionalProperties"] = values
+		optionSchemaRange(schema, tag, "minProperties", "maxProperties", parseOptionInt)
+	case reflect.Struct:
+		properties := map[string]any{}
+		for idx := 0; idx < t.NumField(); idx++ {
+			field := t.Field(idx)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if property := optionSchema(field.Type, parseOptionTag(field.Tag.Get("ooni"))); property != nil {
+				properties[name] = property
+			}
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["additionalProperties"] = false
+	default:
+		return nil
+	}
+	return schema
+}
+
+// optionSchemaRange adds the min and max constraints inside the tag to the schema
+// using the given keys, ignoring constraints we cannot parse.
+func optionSchemaRange[T int64 | float64](
+	schema map[string]any, tag optionTag, minKey, maxKey string, parse func(string) (T, error)) {
+	if min, err := parse(tag.Min); err == nil {
+		schema[minKey] = min
+	}
+	if max, err := parse(tag.Max); err == nil {
+		schema[maxKey] = max
+	}
+}
+
+// OptionsJSONSchema returns the JSON Schema of the object containing the experiment's
+// options, which is useful to validate OONI Run v2 options. Options whose type we
+// cannot represent as JSON are not part of the schema.
+func (b *Factory) OptionsJSONSchema() (map[string]any, error) {
+	options, err := b.Options()
+	if err != nil {
+		return nil, err
+	}
+	properties := map[string]any{}
+	for name, info := range options {
+		if info.Schema != nil {
+			properties[name] = info.Schema
+		}
+	}
+	schema := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	return schema, nil
+}
@@ -0,0 +1,262 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOptionTag(t *testing.T) {
+	inputs := map[string]optionTag{
+		"": {Doc: ""},
+		"a number": {
+			Doc: "a number",
+		},
+		"a number;min=1;max=10": {
+			Doc: "a number",
+			Min: "1",
+			Max: "10",
+		},
+		"a protocol; enum=tcp|udp": {
+			Doc:  "a protocol",
+			Enum: []string{"tcp", "udp"},
+		},
+		"first part;second part;min=1": {
+			Doc: "first part;second part",
+			Min: "1",
+		},
+		"run over a tunnel, e.g. psiphon": {
+			Doc: "run over a tunnel, e.g. psiphon",
+		},
+	}
+	for input, expect := range inputs {
+		t.Run(input, func(t *testing.T) {
+			if diff := cmp.Diff(expect, parseOptionTag(input)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestValidateOption(t *testing.T) {
+	inputs := map[string]struct {
+		tag       optionTag
+		value     any
+		expectErr error
+	}{
+		"int within range": {
+			tag:       optionTag{Min: "1", Max: "10"},
+			value:     int64(5),
+			expectErr: nil,
+		},
+		"int below min": {
+			tag:       optionTag{Min: "1"},
+			value:     0,
+			expectErr: ErrInvalidOptionValue,
+		},
+		"int above max": {
+			tag:       optionTag{Max: "10"},
+			value:     11,
+			expectErr: ErrInvalidOptionValue,
+		},
+		"invalid min constraint": {
+			tag:       optionTag{Min: "xx"},
+			value:     11,
+			expectErr: ErrInvalidOptionValue,
+		},
+		"invalid max constraint": {
+			tag:       optionTag{Max: "xx"},
+			value:     11,
+			expectErr: ErrInvalidOptionValue,
+		},
+		"duration within range": {
+			tag:       optionTag{Min: "1s", Max: "1m"},
+			value:     10 * time.Second,
+			expectErr: nil,
+		},
+		"duration above max": {
+			tag:       optionTag{Max: "1m"},
+			value:     time.Hour,
+			expectErr: ErrInvalidOptionValue,
+		},
+		"string in enum": {
+			tag:       optionTag{Enum: []string{"tcp", "udp"}},
+			value:     "tcp",
+			expectErr: nil,
+		},
+		"string not in enum": {
+			tag:       optionTag{Enum: []string{"tcp", "udp"}},
+			value:     "quic",
+			expectErr: ErrInvalidOptionValue,
+		},
+		"string too long": {
+			tag:       optionTag{Max: "3"},
+			value:     "quic",
+			expectErr: ErrInvalidOptionValue,
+		},
+		"slice too short": {
+			tag:       optionTag{Min: "1"},
+			value:     []string{},
+			expectErr: ErrInvalidOptionValue,
+		},
+		"map within range": {
+			tag:       optionTag{Max: "1"},
+			value:     map[string]int{"a": 1},
+			expectErr: nil,
+		},
+		"unconstrained type": {
+			tag:       optionTag{Min: "1"},
+			value:     true,
+			expectErr: nil,
+		},
+	}
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			err := validateOption("Field", input.tag, reflect.ValueOf(input.value))
+			if !errors.Is(err, input.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
+
+func TestOptionsJSONSchema(t *testing.T) {
+	t.Run("when config is not a pointer", func(t *testing.T) {
+		b := &Factory{config: 17}
+		schema, err := b.OptionsJSONSchema()
+		if !errors.Is(err, ErrConfigIsNotAStructPointer) {
+			t.Fatal("unexpected error", err)
+		}
+		if schema != nil {
+			t.Fatal("expected nil schema")
+		}
+	})
+
+	t.Run("when config is a pointer to struct", func(t *testing.T) {
+		b := &Factory{config: &fakeTypedExperimentConfig{}}
+		schema, err := b.OptionsJSONSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		expect := map[string]any{
+			"$schema":              "https://json-schema.org/draft/2020-12/schema",
+			"type":                 "object",
+			"additionalProperties": false,
+			"properties": map[string]any{
+				"Delay": map[string]any{
+					"description": "a delay",
+					"type":        []any{"string", "integer"},
+					"pattern":     optionDurationPattern,
+					"minimum":     float64(1),
+					"maximum":     float64(1000),
+				},
+				"Headers": map[string]any{
+					"description":          "some headers",
+					"type":                 "object",
+					"additionalProperties": map[string]any{"type": "string"},
+					"maxProperties":        float64(2),
+				},
+				"List": map[string]any{
+					"description": "a list",
+					"type":        "array",
+					"items": map[string]any{
+						"type": "string",
+						"enum": []any{"h2", "http/1.1"},
+					},
+				},
+				"Nested": map[string]any{
+					"description":          "a nested struct",
+					"type":                 "object",
+					"additionalProperties": false,
+					"properties": map[string]any{
+						"name": map[string]any{
+							"description": "a name",
+							"type":        "string",
+							"enum":        []any{"a", "b"},
+						},
+						"count": map[string]any{
+							"description": "a count",
+							"type":        "integer",
+							"minimum":     float64(1),
+						},
+					},
+				},
+				"Ratio": map[string]any{
+					"description": "a ratio",
+					"type":        "number",
+					"minimum":     float64(0),
+					"maximum":     float64(1),
+				},
+				"Small": map[string]any{
+					"description": "a small number",
+					"type":        "integer",
+				},
+			},
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("we skip options we cannot represent as JSON", func(t *testing.T) {
+		b := &Factory{config: &fakeExperimentConfig{}}
+		schema, err := b.OptionsJSONSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		properties := schema["properties"].(map[string]any)
+		if _, found := properties["Chan"]; found {
+			t.Fatal("did not expect to see the Chan option")
+		}
+		if len(properties) != 3 {
+			t.Fatal("unexpected number of properties", len(properties))
+		}
+	})
+}
+
+func TestOptionDurationPattern(t *testing.T) {
+	re := regexp.MustCompile(optionDurationPattern)
+	inputs := []string{
+		"", "0", "300ms", "-1.5h", "+2h45m", "1us", "1µs", "1μs", "10", "1d", "PT1S", "ms", "1.s", ".5s", ".s", "1h.m",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			_, err := time.ParseDuration(input)
+			if (err == nil) != re.MatchString(input) {
+				t.Fatal("the pattern does not match time.ParseDuration", err)
+			}
+		})
+	}
+}
+
+func TestAllExperimentsOptions(t *testing.T) {
+	for name, factory := range AllExperiments {
+		t.Run(name, func(t *testing.T) {
+			schema, err := factory.OptionsJSONSchema()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := json.Marshal(schema); err != nil {
+				t.Fatal(err)
+			}
+			// the default config must satisfy the declared constraints
+			config := reflect.ValueOf(factory.config).Elem()
+			if err := validateOption(name, optionTag{}, config); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -13,10 +13,10 @@ func init() {
 	AllExperiments["telegram"] = &Factory{
 		build: func(config any) model.ExperimentMeasurer {
 			return telegram.NewExperimentMeasurer(
-				config.(telegram.Config),
+				*config.(*telegram.Config),
 			)
 		},
-		config:        telegram.Config{},
+		config:        &telegram.Config{},
 		interruptible: false,
 		inputPolicy:   model.InputNone,
 	}
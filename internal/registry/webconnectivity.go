@@ -13,10 +13,10 @@ func init() {
 	AllExperiments["web_connectivity"] = &Factory{
 		build: func(config any) model.ExperimentMeasurer {
 			return webconnectivity.NewExperimentMeasurer(
-				config.(webconnectivity.Config),
+				*config.(*webconnectivity.Config),
 			)
 		},
-		config:        webconnectivity.Config{},
+		config:        &webconnectivity.Config{},
 		interruptible: false,
 		inputPolicy:   model.InputOrQueryBackend,
 	}
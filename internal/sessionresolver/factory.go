@@ -2,6 +2,7 @@ package sessionresolver
 
 import (
 	"errors"
+	"net"
 	"net/url"
 
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
@@ -14,8 +15,12 @@ import (
 // child resolver using HTTP/3 with a proxy URL.
 var errCannotUseHTTP3WithAProxyURL = errors.New("cannot use HTTP/3 with a proxy URL")
 
+// errCannotUseDoQWithAProxyURL means we cannot construct a new
+// child resolver using DNS-over-QUIC with a proxy URL.
+var errCannotUseDoQWithAProxyURL = errors.New("cannot use DNS-over-QUIC with a proxy URL")
+
 // errUnsupportedResolverScheme means we don't support the
-// given resolver scheme. We only support https, http and system.
+// given resolver scheme. We only support https, http, quic and system.
 var errUnsupportedResolverScheme = errors.New("unsupported resolver scheme")
 
 // newChildResolver constructs a new child resolver.
@@ -24,7 +29,7 @@ var errUnsupportedResolverScheme = errors.New("unsupported resolver scheme")
 //
 // - logger is the MANDATORY logger;
 //
-// - URL is the MANDATORY URL to use (a DoH URL or system:///);
+// - URL is the MANDATORY URL to use (a DoH URL, a DoQ URL or system:///);
 //
 // - http3Enabled indicates whether to use HTTP/3;
 //
@@ -32,8 +37,8 @@ var errUnsupportedResolverScheme = errors.New("unsupported resolver scheme")
 //
 // - proxyURL is the OPTIONAL proxy URL.
 //
-// Using a proxy URL is incompatible with using HTTP/3 and this
-// factory will return an error if that happens.
+// Using a proxy URL is incompatible with using HTTP/3 or DNS-over-QUIC
+// and this factory will return an error if that happens.
 //
 // This function returns a model.Resolver or an error.
 func newChildResolver(
@@ -56,6 +61,11 @@ func newChildResolver(
 	switch parsed.Scheme {
 	case "http", "https": // http is here for testing
 		reso = newChildResolverHTTPS(logger, URL, http3Enabled, counter, proxyURL)
+	case "quic":
+		if proxyURL != nil {
+			return nil, errCannotUseDoQWithAProxyURL
+		}
+		reso = newChildResolverQUIC(logger, parsed.Hostname(), parsed.Port())
 	case "system":
 		reso = bytecounter.MaybeWrapSystemResolver(
 			netxlite.NewStdlibResolver(logger),
@@ -96,3 +106,22 @@ func newChildResolverHTTPS(
 	wrapped := netxlite.WrapResolver(logger, underlying)
 	return wrapped
 }
+
+// newChildResolverQUIC is like newChildResolver but assumes that we
+// already know that the URL scheme is quic and there is no proxy. The
+// hostname MUST NOT be enclosed in brackets (i.e., use [url.URL.Hostname]).
+//
+// TODO(https://github.com/ooni/probe/issues/2121#issuecomment-1147424810): we
+// should count the bytes consumed by this QUIC dialer.
+func newChildResolverQUIC(logger model.Logger, hostname, port string) model.Resolver {
+	if port == "" {
+		port = "853" // RFC9250 default port
+	}
+	endpoint := net.JoinHostPort(hostname, port)
+	dialer := netxlite.NewQUICDialerWithResolver(
+		netxlite.NewQUICListener(), logger, netxlite.NewStdlibResolver(logger))
+	dnstxp := netxlite.WrapDNSTransport(netxlite.NewUnwrappedDNSOverQUICTransport(dialer, endpoint))
+	underlying := netxlite.NewUnwrappedParallelResolver(dnstxp)
+	wrapped := netxlite.WrapResolver(logger, underlying)
+	return wrapped
+}
@@ -117,6 +117,84 @@ func Test_newChildResolver(t *testing.T) {
 		}
 	})
 
+	t.Run("we return an error when using DoQ with a proxy URL", func(t *testing.T) {
+		reso, err := newChildResolver(
+			model.DiscardLogger,
+			"quic://94.140.14.14:853",
+			false,
+			bytecounter.New(),
+			&url.URL{}, // even an empty URL is enough
+		)
+		if !errors.Is(err, errCannotUseDoQWithAProxyURL) {
+			t.Fatal("unexpected error", err)
+		}
+		if reso != nil {
+			t.Fatal("expected nil resolver here")
+		}
+	})
+
+	t.Run("for DoQ resolvers", func(t *testing.T) {
+		t.Run("we add the default port if it is missing", func(t *testing.T) {
+			reso, err := newChildResolver(
+				model.DiscardLogger,
+				"quic://94.140.14.14",
+				false,
+				bytecounter.New(),
+				nil,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reso.CloseIdleConnections()
+			if reso.Network() != "doq" {
+				t.Fatal("unexpected network", reso.Network())
+			}
+			if reso.Address() != "94.140.14.14:853" {
+				t.Fatal("unexpected address", reso.Address())
+			}
+		})
+
+		t.Run("we honour the port if present", func(t *testing.T) {
+			reso, err := newChildResolver(
+				model.DiscardLogger,
+				"quic://94.140.14.14:8853",
+				false,
+				bytecounter.New(),
+				nil,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reso.CloseIdleConnections()
+			if reso.Address() != "94.140.14.14:8853" {
+				t.Fatal("unexpected address", reso.Address())
+			}
+		})
+
+		t.Run("we correctly handle IPv6 addresses", func(t *testing.T) {
+			expectations := map[string]string{
+				"quic://[2001:db8::1]":      "[2001:db8::1]:853",
+				"quic://[2001:db8::1]:8853": "[2001:db8::1]:8853",
+			}
+			for URL, expect := range expectations {
+				reso, err := newChildResolver(
+					model.DiscardLogger,
+					URL,
+					false,
+					bytecounter.New(),
+					nil,
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if reso.Address() != expect {
+					t.Fatal("unexpected address", reso.Address())
+				}
+				reso.CloseIdleConnections()
+			}
+		})
+	})
+
 	t.Run("for HTTPS resolvers", func(t *testing.T) {
 
 		t.Run("the returned resolver wraps errors", func(t *testing.T) {
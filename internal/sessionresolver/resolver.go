@@ -93,6 +93,36 @@ func (r *Resolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, erro
 	return nil, errLookupNotImplemented
 }
 
+// LookupTXT implements Resolver.LookupTXT.
+func (r *Resolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return nil, errLookupNotImplemented
+}
+
+// LookupMX implements Resolver.LookupMX.
+func (r *Resolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return nil, errLookupNotImplemented
+}
+
+// LookupCNAME implements Resolver.LookupCNAME.
+func (r *Resolver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return "", errLookupNotImplemented
+}
+
+// LookupSOA implements Resolver.LookupSOA.
+func (r *Resolver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return nil, errLookupNotImplemented
+}
+
+// LookupPTR implements Resolver.LookupPTR.
+func (r *Resolver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return nil, errLookupNotImplemented
+}
+
+// LookupSRV implements Resolver.LookupSRV.
+func (r *Resolver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return nil, errLookupNotImplemented
+}
+
 // ErrLookupHost indicates that LookupHost failed.
 var ErrLookupHost = errors.New("sessionresolver: LookupHost failed")
 
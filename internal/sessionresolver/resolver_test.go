@@ -385,6 +385,72 @@ func TestUnimplementedFunctions(t *testing.T) {
 			t.Fatal("expected empty result")
 		}
 	})
+
+	t.Run("LookupTXT", func(t *testing.T) {
+		r := &Resolver{}
+		out, err := r.LookupTXT(context.Background(), "dns.google")
+		if !errors.Is(err, errLookupNotImplemented) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(out) > 0 {
+			t.Fatal("expected empty result")
+		}
+	})
+
+	t.Run("LookupMX", func(t *testing.T) {
+		r := &Resolver{}
+		out, err := r.LookupMX(context.Background(), "dns.google")
+		if !errors.Is(err, errLookupNotImplemented) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(out) > 0 {
+			t.Fatal("expected empty result")
+		}
+	})
+
+	t.Run("LookupCNAME", func(t *testing.T) {
+		r := &Resolver{}
+		out, err := r.LookupCNAME(context.Background(), "dns.google")
+		if !errors.Is(err, errLookupNotImplemented) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != "" {
+			t.Fatal("expected empty result")
+		}
+	})
+
+	t.Run("LookupSOA", func(t *testing.T) {
+		r := &Resolver{}
+		out, err := r.LookupSOA(context.Background(), "dns.google")
+		if !errors.Is(err, errLookupNotImplemented) {
+			t.Fatal("unexpected error", err)
+		}
+		if out != nil {
+			t.Fatal("expected empty result")
+		}
+	})
+
+	t.Run("LookupPTR", func(t *testing.T) {
+		r := &Resolver{}
+		out, err := r.LookupPTR(context.Background(), "8.8.8.8")
+		if !errors.Is(err, errLookupNotImplemented) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(out) > 0 {
+			t.Fatal("expected empty result")
+		}
+	})
+
+	t.Run("LookupSRV", func(t *testing.T) {
+		r := &Resolver{}
+		out, err := r.LookupSRV(context.Background(), "dns.google")
+		if !errors.Is(err, errLookupNotImplemented) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(out) > 0 {
+			t.Fatal("expected empty result")
+		}
+	})
 }
 
 func TestResolverWorkingAsIntendedWithMocks(t *testing.T) {
@@ -0,0 +1,349 @@
+// Package submitqueue implements a persistent queue of measurements that
+// we could not submit, which we retry with exponential backoff.
+package submitqueue
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+const (
+	// indexKey is the key containing the queue index.
+	indexKey = "submitqueue.index"
+
+	// measurementKeyPrefix is the prefix of the keys containing measurements.
+	measurementKeyPrefix = "submitqueue.measurement."
+
+	// defaultInitialBackoff is the default value of Queue.InitialBackoff.
+	defaultInitialBackoff = 5 * time.Minute
+
+	// defaultMaxBackoff is the default value of Queue.MaxBackoff.
+	defaultMaxBackoff = 24 * time.Hour
+)
+
+// Entry describes a queued measurement.
+type Entry struct {
+	// UID is the measurement UID (see MeasurementUID).
+	UID string `json:"uid"`
+
+	// TestName is the measurement test name.
+	TestName string `json:"test_name"`
+
+	// Input is the measurement input.
+	Input string `json:"input,omitempty"`
+
+	// Enqueued is when we first enqueued the measurement.
+	Enqueued time.Time `json:"enqueued"`
+
+	// Attempts is the number of failed submission attempts.
+	Attempts int64 `json:"attempts"`
+
+	// NextAttempt is the time after which we should retry.
+	NextAttempt time.Time `json:"next_attempt"`
+
+	// LastError is the error that occurred during the last attempt.
+	LastError string `json:"last_error,omitempty"`
+
+	// Delivered contains the names of the submitters that already
+	// received the measurement (see SelectiveSubmitter).
+	Delivered []string `json:"delivered,omitempty"`
+}
+
+// SelectiveSubmitter is the OPTIONAL interface implemented by submitters
+// delivering measurements to several destinations, such as the one returned
+// by engine.NewSubmitter when using extra submitters. We use it to avoid
+// delivering a measurement twice to the same destination.
+type SelectiveSubmitter interface {
+	// SubmitSkipping is like Submit but skips the submitters whose name
+	// is in skip and returns the names of the submitters that received
+	// the measurement, including when it returns an error.
+	SubmitSkipping(ctx context.Context, m *model.Measurement, skip []string) ([]string, error)
+}
+
+// deliveryError is the OPTIONAL interface implemented by errors returned by
+// submitters delivering measurements to several destinations, such as the
+// engine.SubmissionError, to tell us which submitters received the measurement.
+type deliveryError interface {
+	error
+	DeliveredTo() []string
+}
+
+// Queue is a persistent queue of measurements built on a model.KeyValueStore
+// such as kvstore.FS. The zero value is invalid; use New.
+type Queue struct {
+	// InitialBackoff is the delay before the first retry, which doubles at
+	// each subsequent failed attempt. New sets it to a default value.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between retries. New sets it to
+	// a default value.
+	MaxBackoff time.Duration
+
+	// kvs is the underlying key-value store.
+	kvs model.KeyValueStore
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// timeNow allows to mock time.Now in tests.
+	timeNow func() time.Time
+}
+
+// New creates a new Queue using the given key-value store.
+func New(kvs model.KeyValueStore) *Queue {
+	return &Queue{
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		kvs:            kvs,
+		mu:             sync.Mutex{},
+		timeNow:        time.Now,
+	}
+}
+
+// MeasurementUID returns the UID of the given measurement. We use the ID
+// field when it is set. Otherwise, we hash the measurement ignoring the
+// report ID, which changes every time we resubmit a measurement.
+func MeasurementUID(m *model.Measurement) string {
+	if m.ID != "" {
+		return m.ID
+	}
+	clone := *m
+	clone.ReportID = ""
+	data, err := json.Marshal(&clone)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Enqueue adds the given measurement to the queue, unless a measurement with
+// the same UID is already queued, and returns the measurement UID. The cause
+// argument is the OPTIONAL error that prevented submitting the measurement. If
+// such an error tells us that some submitters received the measurement (see
+// engine.SubmissionError), we will not submit to them again when flushing.
+func (q *Queue) Enqueue(m *model.Measurement, cause error) (string, error) {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	uid := MeasurementUID(m)
+	index, err := q.readIndex()
+	if err != nil {
+		return "", err
+	}
+	if _, found := index[uid]; found {
+		return uid, nil
+	}
+	data, err := json.Marshal(m)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	if err := q.kvs.Set(measurementKeyPrefix+uid, data); err != nil {
+		return "", err
+	}
+	now := q.timeNow()
+	entry := &Entry{
+		UID:         uid,
+		TestName:    m.TestName,
+		Input:       string(m.Input),
+		Enqueued:    now,
+		Attempts:    0,
+		NextAttempt: now,
+	}
+	if cause != nil {
+		entry.LastError = cause.Error()
+		var derr deliveryError
+		if errors.As(cause, &derr) {
+			entry.Delivered = append(entry.Delivered, derr.DeliveredTo()...)
+		}
+	}
+	index[uid] = entry
+	return uid, q.writeIndex(index)
+}
+
+// List returns the queued entries sorted by enqueue time.
+func (q *Queue) List() ([]*Entry, error) {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	index, err := q.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	return sortedEntries(index), nil
+}
+
+// ErrNoSuchEntry indicates that there is no queued measurement with a given UID.
+var ErrNoSuchEntry = errors.New("submitqueue: no such entry")
+
+// Drop removes the measurement with the given UID from the queue.
+func (q *Queue) Drop(uid string) error {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	index, err := q.readIndex()
+	if err != nil {
+		return err
+	}
+	if _, found := index[uid]; !found {
+		return fmt.Errorf("%w: %s", ErrNoSuchEntry, uid)
+	}
+	return q.remove(index, uid)
+}
+
+// FlushResult contains the results of Flush.
+type FlushResult struct {
+	// Submitted contains the UIDs of the submitted measurements.
+	Submitted []string
+
+	// Failed contains the UIDs of the measurements we failed to submit.
+	Failed []string
+
+	// Skipped contains the UIDs of the measurements whose backoff has not expired yet.
+	Skipped []string
+}
+
+// Flush attempts to submit the queued measurements whose backoff has expired
+// using the given submitter, removing them from the queue on success and
+// scheduling the next attempt on failure. When force is true, we ignore the
+// backoff and attempt to submit all the queued measurements. If subm is also
+// a SelectiveSubmitter, we only submit to the submitters that did not receive
+// the measurement yet, so a failing extra submitter does not cause us to submit
+// duplicate measurements to the OONI collector.
+func (q *Queue) Flush(ctx context.Context, subm model.Submitter, force bool) (*FlushResult, error) {
+	defer q.mu.Unlock()
+	q.mu.Lock()
+	index, err := q.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	result := &FlushResult{}
+	for _, entry := range sortedEntries(index) {
+		if ctx.Err() != nil {
+			break
+		}
+		if !force && q.timeNow().Before(entry.NextAttempt) {
+			result.Skipped = append(result.Skipped, entry.UID)
+			continue
+		}
+		if err := q.submit(ctx, subm, entry); err != nil {
+			entry.Attempts++
+			entry.LastError = err.Error()
+			entry.NextAttempt = q.timeNow().Add(q.backoff(entry.Attempts))
+			result.Failed = append(result.Failed, entry.UID)
+			if err := q.writeIndex(index); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := q.remove(index, entry.UID); err != nil {
+			return nil, err
+		}
+		result.Submitted = append(result.Submitted, entry.UID)
+	}
+	return result, nil
+}
+
+// submit loads and submits the measurement of the given entry. When using
+// a SelectiveSubmitter, we update entry.Delivered and, on partial failure, we
+// store the measurement again such that the next attempt sees the changes made
+// by the submitters that succeeded (e.g., the report ID). The caller MUST hold
+// the mutex and is responsible for writing the index.
+func (q *Queue) submit(ctx context.Context, subm model.Submitter, entry *Entry) error {
+	data, err := q.kvs.Get(measurementKeyPrefix + entry.UID)
+	if err != nil {
+		return err
+	}
+	var m model.Measurement
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	ss, ok := subm.(SelectiveSubmitter)
+	if !ok {
+		return subm.Submit(ctx, &m)
+	}
+	delivered, err := ss.SubmitSkipping(ctx, &m, entry.Delivered)
+	entry.Delivered = append(entry.Delivered, delivered...)
+	if err != nil && len(delivered) > 0 {
+		data, jsonErr := json.Marshal(&m)
+		runtimex.PanicOnError(jsonErr, "json.Marshal unexpectedly failed")
+		if err := q.kvs.Set(measurementKeyPrefix+entry.UID, data); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// backoff returns the backoff after the given number of failed attempts.
+func (q *Queue) backoff(attempts int64) time.Duration {
+	backoff := q.InitialBackoff
+	for i := int64(1); i < attempts && backoff < q.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > q.MaxBackoff {
+		backoff = q.MaxBackoff
+	}
+	return backoff
+}
+
+// deleter is the optional interface implemented by key-value stores
+// that support deleting keys, such as kvstore.FS.
+type deleter interface {
+	Delete(key string) error
+}
+
+// remove removes uid from the index and deletes the measurement. The
+// caller MUST hold the mutex.
+func (q *Queue) remove(index map[string]*Entry, uid string) error {
+	delete(index, uid)
+	if err := q.writeIndex(index); err != nil {
+		return err
+	}
+	if d, ok := q.kvs.(deleter); ok {
+		return d.Delete(measurementKeyPrefix + uid)
+	}
+	return q.kvs.Set(measurementKeyPrefix+uid, nil)
+}
+
+// readIndex reads the index. A missing index is an empty index. The
+// caller MUST hold the mutex.
+func (q *Queue) readIndex() (map[string]*Entry, error) {
+	index := map[string]*Entry{}
+	data, err := q.kvs.Get(indexKey)
+	if errors.Is(err, kvstore.ErrNoSuchKey) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// writeIndex writes the index. The caller MUST hold the mutex.
+func (q *Queue) writeIndex(index map[string]*Entry) error {
+	data, err := json.Marshal(index)
+	runtimex.PanicOnError(err, "json.Marshal unexpectedly failed")
+	return q.kvs.Set(indexKey, data)
+}
+
+// sortedEntries returns the entries sorted by enqueue time.
+func sortedEntries(index map[string]*Entry) []*Entry {
+	var out []*Entry
+	for _, entry := range index {
+		out = append(out, entry)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Enqueued.Equal(out[j].Enqueued) {
+			return out[i].UID < out[j].UID
+		}
+		return out[i].Enqueued.Before(out[j].Enqueued)
+	})
+	return out
+}
@@ -0,0 +1,428 @@
+package submitqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+// newQueueWithClock creates a queue with a mockable clock.
+func newQueueWithClock(kvs model.KeyValueStore) (*Queue, *time.Time) {
+	now := time.Date(2023, 5, 10, 11, 0, 0, 0, time.UTC)
+	q := New(kvs)
+	q.timeNow = func() time.Time {
+		return now
+	}
+	return q, &now
+}
+
+func TestMeasurementUID(t *testing.T) {
+	t.Run("we use the ID when set", func(t *testing.T) {
+		if uid := MeasurementUID(&model.Measurement{ID: "antani"}); uid != "antani" {
+			t.Fatal("unexpected UID", uid)
+		}
+	})
+
+	t.Run("the UID does not depend on the report ID", func(t *testing.T) {
+		m1 := &model.Measurement{TestName: "example", ReportID: "a"}
+		m2 := &model.Measurement{TestName: "example", ReportID: "b"}
+		if MeasurementUID(m1) != MeasurementUID(m2) {
+			t.Fatal("expected the same UID")
+		}
+		if m1.ReportID != "a" {
+			t.Fatal("MeasurementUID modified the measurement")
+		}
+	})
+
+	t.Run("different measurements have different UIDs", func(t *testing.T) {
+		m1 := &model.Measurement{TestName: "example", Input: "a"}
+		m2 := &model.Measurement{TestName: "example", Input: "b"}
+		if MeasurementUID(m1) == MeasurementUID(m2) {
+			t.Fatal("expected different UIDs")
+		}
+	})
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("Enqueue deduplicates by UID", func(t *testing.T) {
+		q, _ := newQueueWithClock(&kvstore.Memory{})
+		m := &model.Measurement{TestName: "example", Input: "https://x.org/"}
+		uid1, err := q.Enqueue(m, errors.New("mocked error"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.ReportID = "20230510T110000Z_example_IT_30722_n1_xxx"
+		uid2, err := q.Enqueue(m, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if uid1 != uid2 {
+			t.Fatal("expected the same UID")
+		}
+		entries, err := q.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatal("expected a single entry")
+		}
+		expect := &Entry{
+			UID:         uid1,
+			TestName:    "example",
+			Input:       "https://x.org/",
+			Enqueued:    q.timeNow(),
+			Attempts:    0,
+			NextAttempt: q.timeNow(),
+			LastError:   "mocked error",
+		}
+		if diff := cmp.Diff(expect, entries[0]); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("the queue persists on kvstore.FS", func(t *testing.T) {
+		dir := t.TempDir()
+		kvs, err := kvstore.NewFS(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := New(kvs).Enqueue(&model.Measurement{TestName: "example"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		kvs2, err := kvstore.NewFS(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := New(kvs2).List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].TestName != "example" {
+			t.Fatal("unexpected entries", entries)
+		}
+	})
+
+	t.Run("List fails with a corrupt index", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		if err := kvs.Set(indexKey, []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := New(kvs).List()
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if len(entries) != 0 {
+			t.Fatal("expected no entries")
+		}
+	})
+
+	t.Run("List fails when the kvstore fails", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		kvs := &mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, expected
+			},
+		}
+		entries, err := New(kvs).List()
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(entries) != 0 {
+			t.Fatal("expected no entries")
+		}
+	})
+
+	t.Run("Drop removes the entry and the measurement", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		q := New(kvs)
+		uid, err := q.Enqueue(&model.Measurement{TestName: "example"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := q.Drop(uid); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kvs.Get(measurementKeyPrefix + uid); !errors.Is(err, kvstore.ErrNoSuchKey) {
+			t.Fatal("expected the measurement to be deleted", err)
+		}
+		if err := q.Drop(uid); !errors.Is(err, ErrNoSuchEntry) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("Flush submits measurements and retries with backoff", func(t *testing.T) {
+		q, now := newQueueWithClock(&kvstore.Memory{})
+		q.InitialBackoff = time.Minute
+		q.MaxBackoff = 3 * time.Minute
+		good, err := q.Enqueue(&model.Measurement{TestName: "good"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bad, err := q.Enqueue(&model.Measurement{TestName: "bad"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := errors.New("mocked error")
+		var submitted []string
+		subm := &mocks.Submitter{
+			MockSubmit: func(ctx context.Context, m *model.Measurement) error {
+				if m.TestName == "bad" {
+					return expected
+				}
+				submitted = append(submitted, m.TestName)
+				return nil
+			},
+		}
+
+		result, err := q.Flush(context.Background(), subm, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FlushResult{Submitted: []string{good}, Failed: []string{bad}}, result); diff != "" {
+			t.Fatal(diff)
+		}
+		if diff := cmp.Diff([]string{"good"}, submitted); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// the backoff should prevent retrying immediately
+		result, err = q.Flush(context.Background(), subm, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FlushResult{Skipped: []string{bad}}, result); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// the backoff should double at each attempt until the maximum
+		for _, expect := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+			*now = now.Add(time.Hour)
+			if _, err := q.Flush(context.Background(), subm, false); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := q.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := entries[0].NextAttempt.Sub(*now); got != expect {
+				t.Fatal("unexpected backoff", got, expect)
+			}
+			if entries[0].LastError != "mocked error" {
+				t.Fatal("unexpected last error", entries[0].LastError)
+			}
+		}
+
+		// force ignores the backoff
+		result, err = q.Flush(context.Background(), subm, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FlushResult{Failed: []string{bad}}, result); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("Flush does not submit again to the submitters that succeeded", func(t *testing.T) {
+		q := New(&kvstore.Memory{})
+		m := &model.Measurement{TestName: "example"}
+		cause := &fakeDeliveryError{delivered: []string{"collector"}}
+		uid, err := q.Enqueue(m, cause)
+		if err != nil {
+			t.Fatal(err)
+		}
+		subm := &fakeSelectiveSubmitter{failing: map[string]bool{"webhook": true}}
+		result, err := q.Flush(context.Background(), subm, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FlushResult{Failed: []string{uid}}, result); diff != "" {
+			t.Fatal(diff)
+		}
+		entries, err := q.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"collector", "unix"}, entries[0].Delivered); diff != "" {
+			t.Fatal(diff)
+		}
+
+		// the webhook recovers and we only submit to it
+		subm.failing = nil
+		result, err = q.Flush(context.Background(), subm, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FlushResult{Submitted: []string{uid}}, result); diff != "" {
+			t.Fatal(diff)
+		}
+		expectCalls := []string{"unix", "webhook", "webhook"}
+		if diff := cmp.Diff(expectCalls, subm.calls); diff != "" {
+			t.Fatal(diff)
+		}
+		// the last attempt should have seen the changes made by the unix submitter
+		if subm.lastReportID != "unix" {
+			t.Fatal("unexpected report ID", subm.lastReportID)
+		}
+	})
+
+	t.Run("Flush with engine.NewSubmitter does not resubmit after partial success", func(t *testing.T) {
+		var collectorCalls, webhookCalls int
+		collector := &mocks.Submitter{
+			MockSubmit: func(ctx context.Context, m *model.Measurement) error {
+				collectorCalls++
+				return nil
+			},
+		}
+		webhook := &mocks.Submitter{
+			MockSubmit: func(ctx context.Context, m *model.Measurement) error {
+				webhookCalls++
+				return errors.New("mocked error")
+			},
+		}
+		subm, err := engine.NewSubmitter(context.Background(), engine.SubmitterConfig{
+			Enabled: true,
+			Logger:  model.DiscardLogger,
+			Session: &fakeSubmitterSession{subm: collector},
+			Extra:   map[string]engine.Submitter{"webhook": webhook},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := New(&kvstore.Memory{})
+		m := &model.Measurement{TestName: "example"}
+		cause := subm.Submit(context.Background(), m)
+		if cause == nil {
+			t.Fatal("expected an error")
+		}
+		uid, err := q.Enqueue(m, cause)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the user removes the webhook and we retry using just the collector
+		subm, err = engine.NewSubmitter(context.Background(), engine.SubmitterConfig{
+			Enabled: true,
+			Logger:  model.DiscardLogger,
+			Session: &fakeSubmitterSession{subm: collector},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := q.Flush(context.Background(), subm, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FlushResult{Submitted: []string{uid}}, result); diff != "" {
+			t.Fatal(diff)
+		}
+		if collectorCalls != 1 || webhookCalls != 1 {
+			t.Fatal("unexpected number of calls", collectorCalls, webhookCalls)
+		}
+	})
+
+	t.Run("Flush stops when the context is done", func(t *testing.T) {
+		q := New(&kvstore.Memory{})
+		if _, err := q.Enqueue(&model.Measurement{TestName: "example"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result, err := q.Flush(ctx, &mocks.Submitter{}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(&FlushResult{}, result); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("remove works with stores that cannot delete keys", func(t *testing.T) {
+		kvs := &mocks.KeyValueStore{
+			MockGet: func(key string) ([]byte, error) {
+				return nil, kvstore.ErrNoSuchKey
+			},
+			MockSet: func(key string, value []byte) error {
+				return nil
+			},
+		}
+		q := New(kvs)
+		if err := q.remove(map[string]*Entry{}, "antani"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// fakeDeliveryError is an error implementing deliveryError.
+type fakeDeliveryError struct {
+	delivered []string
+}
+
+func (err *fakeDeliveryError) Error() string {
+	return "mocked error"
+}
+
+func (err *fakeDeliveryError) DeliveredTo() []string {
+	return err.delivered
+}
+
+// fakeSubmitterSession is an engine.SubmitterSession returning subm.
+type fakeSubmitterSession struct {
+	subm model.Submitter
+}
+
+var _ engine.SubmitterSession = &fakeSubmitterSession{}
+
+func (fss *fakeSubmitterSession) NewSubmitter(ctx context.Context) (engine.Submitter, error) {
+	return fss.subm, nil
+}
+
+// fakeSelectiveSubmitter is a SelectiveSubmitter delivering to the
+// collector, unix, and webhook submitters. The unix submitter sets the
+// report ID to emulate changes made by the first successful submitter.
+type fakeSelectiveSubmitter struct {
+	calls        []string
+	failing      map[string]bool
+	lastReportID string
+}
+
+var _ SelectiveSubmitter = &fakeSelectiveSubmitter{}
+
+func (fs *fakeSelectiveSubmitter) Submit(ctx context.Context, m *model.Measurement) error {
+	_, err := fs.SubmitSkipping(ctx, m, nil)
+	return err
+}
+
+func (fs *fakeSelectiveSubmitter) SubmitSkipping(
+	ctx context.Context, m *model.Measurement, skip []string) ([]string, error) {
+	var (
+		delivered []string
+		err       error
+	)
+	skipped := map[string]bool{}
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	for _, name := range []string{"collector", "unix", "webhook"} {
+		if skipped[name] {
+			continue
+		}
+		fs.calls = append(fs.calls, name)
+		fs.lastReportID = m.ReportID
+		if fs.failing[name] {
+			err = errors.New("mocked error")
+			continue
+		}
+		if name == "unix" {
+			m.ReportID = "unix"
+		}
+		delivered = append(delivered, name)
+	}
+	return delivered, err
+}
@@ -0,0 +1,4 @@
+// Package submitter contains a registry of measurement submitters that
+// deliver measurements to destinations other than the OONI collector
+// (e.g., an HTTP webhook, an S3-compatible bucket, or a Unix socket).
+package submitter
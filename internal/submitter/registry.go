@@ -0,0 +1,121 @@
+package submitter
+
+//
+// Registry of submitter factories.
+//
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Config contains the config of a submitter.
+type Config struct {
+	// Type is the MANDATORY submitter type (one of the values returned by Types).
+	Type string `json:"type"`
+
+	// URL is the MANDATORY destination URL, whose meaning depends on the Type.
+	URL string `json:"url"`
+
+	// Headers contains OPTIONAL extra headers for HTTP based submitters.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// MaxRetries is the OPTIONAL number of times the webhook submitter
+	// retries a failed submission. When zero, we use a default value. When
+	// negative, we do not retry failed submissions.
+	MaxRetries int64 `json:"max_retries,omitempty"`
+
+	// AccessKeyID is the OPTIONAL access key for the s3 submitter.
+	AccessKeyID string `json:"access_key_id,omitempty"`
+
+	// SecretAccessKey is the OPTIONAL secret key for the s3 submitter.
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+
+	// Region is the OPTIONAL region for the s3 submitter (default: "us-east-1").
+	Region string `json:"region,omitempty"`
+}
+
+// Name returns the name identifying the submitter, i.e., its TYPE=URL spec.
+func (c *Config) Name() string {
+	return c.Type + "=" + c.URL
+}
+
+// Factory constructs a submitter given its config.
+type Factory func(config *Config, logger model.Logger) (model.Submitter, error)
+
+var (
+	// allFactories contains all the registered factories.
+	allFactories = map[string]Factory{}
+
+	// allFactoriesMu protects allFactories.
+	allFactoriesMu sync.Mutex
+)
+
+// Register registers a factory for the given submitter type. This function
+// panics if there is already a factory for such a type.
+func Register(typeName string, factory Factory) {
+	defer allFactoriesMu.Unlock()
+	allFactoriesMu.Lock()
+	if _, found := allFactories[typeName]; found {
+		panic(fmt.Sprintf("submitter: type already registered: %s", typeName))
+	}
+	allFactories[typeName] = factory
+}
+
+// Types returns the sorted list of registered submitter types.
+func Types() []string {
+	defer allFactoriesMu.Unlock()
+	allFactoriesMu.Lock()
+	var out []string
+	for name := range allFactories {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ErrUnknownType indicates that there is no factory for the given type.
+var ErrUnknownType = errors.New("submitter: unknown type")
+
+// New creates a new submitter using the given config.
+func New(config *Config, logger model.Logger) (model.Submitter, error) {
+	allFactoriesMu.Lock()
+	factory := allFactories[config.Type]
+	allFactoriesMu.Unlock()
+	if factory == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, config.Type)
+	}
+	return factory(config, logger)
+}
+
+// NewAll creates a new submitter for each of the given configs and returns
+// a map from each config's Name to the corresponding submitter.
+func NewAll(configs []*Config, logger model.Logger) (map[string]model.Submitter, error) {
+	out := map[string]model.Submitter{}
+	for _, config := range configs {
+		subm, err := New(config, logger)
+		if err != nil {
+			return nil, err
+		}
+		out[config.Name()] = subm
+	}
+	return out, nil
+}
+
+// ErrInvalidSpec indicates that a spec passed to ParseSpec is invalid.
+var ErrInvalidSpec = errors.New("submitter: expected TYPE=URL spec")
+
+// ParseSpec parses a TYPE=URL spec (e.g., "webhook=https://example.com/")
+// as used by command line tools into a Config with default settings.
+func ParseSpec(spec string) (*Config, error) {
+	typeName, URL, found := strings.Cut(spec, "=")
+	if !found || typeName == "" || URL == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSpec, spec)
+	}
+	return &Config{Type: typeName, URL: URL}, nil
+}
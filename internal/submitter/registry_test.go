@@ -0,0 +1,126 @@
+package submitter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestTypes(t *testing.T) {
+	expect := []string{"s3", "unix", "webhook"}
+	if diff := cmp.Diff(expect, Types()); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("we cannot register the same type twice", func(t *testing.T) {
+		var panicked bool
+		func() {
+			defer func() {
+				panicked = recover() != nil
+			}()
+			Register("webhook", newWebhookSubmitter)
+		}()
+		if !panicked {
+			t.Fatal("expected a panic")
+		}
+	})
+
+	t.Run("New uses the registered factory", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		Register("antani", func(config *Config, logger model.Logger) (model.Submitter, error) {
+			return nil, expected
+		})
+		defer func() {
+			allFactoriesMu.Lock()
+			delete(allFactories, "antani")
+			allFactoriesMu.Unlock()
+		}()
+		subm, err := New(&Config{Type: "antani"}, log.Log)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+		if subm != nil {
+			t.Fatal("expected nil submitter")
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("with unknown type", func(t *testing.T) {
+		subm, err := New(&Config{Type: "mascetti"}, log.Log)
+		if !errors.Is(err, ErrUnknownType) {
+			t.Fatal("unexpected error", err)
+		}
+		if subm != nil {
+			t.Fatal("expected nil submitter")
+		}
+	})
+}
+
+func TestNewAll(t *testing.T) {
+	t.Run("on success", func(t *testing.T) {
+		subms, err := NewAll([]*Config{
+			{Type: "webhook", URL: "https://example.com/"},
+			{Type: "unix", URL: "unix:///tmp/ooni.sock"},
+		}, log.Log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(subms) != 2 {
+			t.Fatal("expected two submitters")
+		}
+		if subms["webhook=https://example.com/"] == nil || subms["unix=unix:///tmp/ooni.sock"] == nil {
+			t.Fatal("unexpected submitter names", subms)
+		}
+	})
+
+	t.Run("on failure", func(t *testing.T) {
+		subms, err := NewAll([]*Config{
+			{Type: "webhook", URL: "https://example.com/"},
+			{Type: "webhook", URL: "ftp://example.com/"},
+		}, log.Log)
+		if !errors.Is(err, ErrInvalidURL) {
+			t.Fatal("unexpected error", err)
+		}
+		if len(subms) != 0 {
+			t.Fatal("expected no submitters")
+		}
+	})
+}
+
+func TestParseSpec(t *testing.T) {
+	type testcase struct {
+		spec   string
+		expect *Config
+		err    error
+	}
+	cases := []testcase{{
+		spec:   "webhook=https://example.com/hook?a=b",
+		expect: &Config{Type: "webhook", URL: "https://example.com/hook?a=b"},
+	}, {
+		spec: "webhook",
+		err:  ErrInvalidSpec,
+	}, {
+		spec: "=https://example.com/",
+		err:  ErrInvalidSpec,
+	}, {
+		spec: "unix=",
+		err:  ErrInvalidSpec,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.spec, func(t *testing.T) {
+			config, err := ParseSpec(tc.spec)
+			if !errors.Is(err, tc.err) {
+				t.Fatal("unexpected error", err)
+			}
+			if diff := cmp.Diff(tc.expect, config); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
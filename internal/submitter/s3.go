@@ -0,0 +1,205 @@
+package submitter
+
+//
+// S3-compatible PUT submitter.
+//
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func init() {
+	Register("s3", newS3Submitter)
+}
+
+// s3DefaultRegion is the default value of Config.Region.
+const s3DefaultRegion = "us-east-1"
+
+// s3Submitter uploads each measurement to an S3-compatible bucket using
+// a path-style PUT request signed with AWS Signature Version 4.
+type s3Submitter struct {
+	// accessKeyID is the OPTIONAL access key. When empty, we do not sign requests.
+	accessKeyID string
+
+	// baseURL is the URL of the bucket including the OPTIONAL key prefix.
+	baseURL *url.URL
+
+	// client is the HTTP client to use.
+	client model.HTTPClient
+
+	// headers contains extra headers.
+	headers map[string]string
+
+	// region is the bucket region.
+	region string
+
+	// secretAccessKey is the OPTIONAL secret key.
+	secretAccessKey string
+
+	// timeNow allows to mock time.Now in tests.
+	timeNow func() time.Time
+}
+
+// newS3Submitter is the Factory for the s3 submitter. The URL has the
+// https://host/bucket/prefix form. Credentials not set in the config are
+// read from the URL userinfo, if present, and the region from the "region"
+// query string parameter, if present.
+func newS3Submitter(config *Config, logger model.Logger) (model.Submitter, error) {
+	URL, err := url.Parse(config.URL)
+	if err != nil || (URL.Scheme != "http" && URL.Scheme != "https") ||
+		URL.Host == "" || strings.Trim(URL.Path, "/") == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, config.URL)
+	}
+	subm := &s3Submitter{
+		accessKeyID:     config.AccessKeyID,
+		baseURL:         URL,
+		client:          netxlite.NewHTTPClientStdlib(logger),
+		headers:         config.Headers,
+		region:          config.Region,
+		secretAccessKey: config.SecretAccessKey,
+		timeNow:         time.Now,
+	}
+	if URL.User != nil && subm.accessKeyID == "" {
+		subm.accessKeyID = URL.User.Username()
+		subm.secretAccessKey, _ = URL.User.Password()
+	}
+	if subm.region == "" {
+		subm.region = URL.Query().Get("region")
+	}
+	if subm.region == "" {
+		subm.region = s3DefaultRegion
+	}
+	URL.User, URL.RawQuery = nil, ""
+	return subm, nil
+}
+
+// Submit implements model.Submitter.
+func (ss *s3Submitter) Submit(ctx context.Context, m *model.Measurement) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	payloadHash := sha256.Sum256(body)
+	URL := ss.baseURL.JoinPath(s3ObjectKey(m, payloadHash[:]))
+	req, err := http.NewRequestWithContext(ctx, "PUT", URL.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, value := range ss.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ss.accessKeyID != "" {
+		req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+		s3SignV4(req, ss.accessKeyID, ss.secretAccessKey, ss.region, ss.timeNow())
+	}
+	resp, err := ss.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%w: %s", ErrHTTPRequestFailed, resp.Status)
+	}
+	return nil
+}
+
+// s3DateFormat is the format of Measurement.MeasurementStartTime.
+const s3DateFormat = "2006-01-02 15:04:05"
+
+// s3ObjectKey returns the object key for the given measurement. Because the key
+// depends on the measurement content, uploading the same measurement twice
+// overwrites the same object rather than creating a duplicate.
+func s3ObjectKey(m *model.Measurement, payloadHash []byte) string {
+	testName := m.TestName
+	if testName == "" {
+		testName = "unknown"
+	}
+	day := "00000000"
+	if t, err := time.Parse(s3DateFormat, m.MeasurementStartTime); err == nil {
+		day = t.Format("20060102")
+	}
+	return fmt.Sprintf("%s/%s/%s.json", testName, day, hex.EncodeToString(payloadHash[:16]))
+}
+
+// s3SignV4 adds the AWS Signature Version 4 Authorization header to req, signing the
+// Host header and all the headers already set. The caller MUST set the
+// X-Amz-Content-Sha256 header to the hex encoded SHA256 of the body.
+func s3SignV4(req *http.Request, accessKeyID, secretAccessKey, region string, now time.Time) {
+	const service = "s3"
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	// 1. canonical headers, which must be sorted and lowercase
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// 2. canonical request
+	canonicalURI := req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	// 3. string to sign
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	// 4. signature
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = s3HMAC(key, part)
+	}
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// s3HMAC computes the HMAC-SHA256 of data using key.
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,97 @@
+package submitter
+
+//
+// Unix socket streaming submitter.
+//
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	Register("unix", newUnixSocketSubmitter)
+}
+
+// unixSocketSubmitter streams measurements to a Unix domain socket
+// using newline-delimited JSON, i.e., one measurement per line.
+type unixSocketSubmitter struct {
+	// conn is the connection, which is nil when we're not connected.
+	conn net.Conn
+
+	// dialer is the dialer to use.
+	dialer *net.Dialer
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// path is the socket path.
+	path string
+}
+
+// newUnixSocketSubmitter is the Factory for the unix submitter. The URL is
+// either a unix:///path/to/socket URL or the socket path.
+func newUnixSocketSubmitter(config *Config, logger model.Logger) (model.Submitter, error) {
+	path := config.URL
+	if URL, err := url.Parse(config.URL); err == nil && URL.Scheme == "unix" {
+		path = URL.Path
+	}
+	if path == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, config.URL)
+	}
+	subm := &unixSocketSubmitter{
+		conn:   nil,
+		dialer: &net.Dialer{},
+		mu:     sync.Mutex{},
+		path:   path,
+	}
+	return subm, nil
+}
+
+// Submit implements model.Submitter. We keep the connection open across
+// submissions and we reconnect once when writing fails, which typically
+// happens after the consumer restarted.
+func (us *unixSocketSubmitter) Submit(ctx context.Context, m *model.Measurement) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	defer us.mu.Unlock()
+	us.mu.Lock()
+	if us.conn != nil {
+		if err := us.write(ctx, data); err == nil {
+			return nil
+		}
+	}
+	conn, err := us.dialer.DialContext(ctx, "unix", us.path)
+	if err != nil {
+		return err
+	}
+	us.conn = conn
+	return us.write(ctx, data)
+}
+
+// write writes data to the current connection and closes the
+// connection on failure. The caller MUST hold the mutex.
+//
+// We bound the write using the context deadline, if any, and we clear the
+// deadline afterwards, since the connection outlives the context.
+func (us *unixSocketSubmitter) write(ctx context.Context, data []byte) error {
+	deadline, _ := ctx.Deadline() // the zero value means no deadline
+	us.conn.SetWriteDeadline(deadline)
+	if _, err := us.conn.Write(data); err != nil {
+		us.conn.Close()
+		us.conn = nil
+		return err
+	}
+	us.conn.SetWriteDeadline(time.Time{})
+	return nil
+}
@@ -0,0 +1,145 @@
+package submitter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestUnixSocketSubmitter(t *testing.T) {
+	t.Run("newUnixSocketSubmitter parses the URL", func(t *testing.T) {
+		for URL, expect := range map[string]string{
+			"unix:///tmp/ooni.sock": "/tmp/ooni.sock",
+			"/tmp/ooni.sock":        "/tmp/ooni.sock",
+		} {
+			subm, err := newUnixSocketSubmitter(&Config{URL: URL}, log.Log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path := subm.(*unixSocketSubmitter).path; path != expect {
+				t.Fatal("unexpected path", path)
+			}
+		}
+	})
+
+	t.Run("newUnixSocketSubmitter rejects empty paths", func(t *testing.T) {
+		subm, err := newUnixSocketSubmitter(&Config{URL: "unix://"}, log.Log)
+		if !errors.Is(err, ErrInvalidURL) {
+			t.Fatal("unexpected error", err)
+		}
+		if subm != nil {
+			t.Fatal("expected nil submitter")
+		}
+	})
+
+	t.Run("when we cannot connect", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nonexistent.sock")
+		subm, err := newUnixSocketSubmitter(&Config{URL: path}, log.Log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := subm.Submit(context.Background(), &model.Measurement{}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("we stream measurements and reconnect", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ooni.sock")
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		lines := make(chan string, 8)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					defer conn.Close()
+					scanner := bufio.NewScanner(conn)
+					scanner.Buffer(nil, 1<<20)
+					for scanner.Scan() {
+						lines <- scanner.Text()
+					}
+				}()
+			}
+		}()
+		subm, err := newUnixSocketSubmitter(&Config{URL: "unix://" + path}, log.Log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		us := subm.(*unixSocketSubmitter)
+		for idx, name := range []string{"first", "second", "third"} {
+			if idx == 2 {
+				us.conn.Close() // force the submitter to reconnect
+			}
+			if err := subm.Submit(context.Background(), &model.Measurement{TestName: name}); err != nil {
+				t.Fatal(err)
+			}
+			var m model.Measurement
+			if err := json.Unmarshal([]byte(<-lines), &m); err != nil {
+				t.Fatal(err)
+			}
+			if m.TestName != name {
+				t.Fatal("unexpected measurement", m.TestName)
+			}
+		}
+	})
+	t.Run("the write deadline does not outlive the context", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ooni.sock")
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		var accepted atomic.Int64
+		lines := make(chan string, 8)
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				accepted.Add(1)
+				go func() {
+					defer conn.Close()
+					scanner := bufio.NewScanner(conn)
+					for scanner.Scan() {
+						lines <- scanner.Text()
+					}
+				}()
+			}
+		}()
+		subm, err := newUnixSocketSubmitter(&Config{URL: path}, log.Log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const timeout = 50 * time.Millisecond
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := subm.Submit(ctx, &model.Measurement{TestName: "first"}); err != nil {
+			t.Fatal(err)
+		}
+		<-lines
+		time.Sleep(2 * timeout) // the first deadline is now in the past
+		if err := subm.Submit(context.Background(), &model.Measurement{TestName: "second"}); err != nil {
+			t.Fatal(err)
+		}
+		<-lines
+		if count := accepted.Load(); count != 1 {
+			t.Fatal("expected to reuse the connection, got", count, "connections")
+		}
+	})
+}
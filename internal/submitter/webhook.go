@@ -0,0 +1,143 @@
+package submitter
+
+//
+// HTTP POST webhook submitter.
+//
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func init() {
+	Register("webhook", newWebhookSubmitter)
+}
+
+// webhookDefaultMaxRetries is the default value of Config.MaxRetries.
+const webhookDefaultMaxRetries = 3
+
+// webhookSubmitter POSTs each measurement as JSON to an HTTP(S) URL.
+type webhookSubmitter struct {
+	// backoff is the delay before the first retry, which doubles at each retry.
+	backoff time.Duration
+
+	// client is the HTTP client to use.
+	client model.HTTPClient
+
+	// headers contains extra headers.
+	headers map[string]string
+
+	// logger is the logger to use.
+	logger model.Logger
+
+	// maxRetries is the maximum number of retries.
+	maxRetries int64
+
+	// url is the webhook URL.
+	url string
+}
+
+// ErrInvalidURL indicates that a submitter URL is not valid.
+var ErrInvalidURL = errors.New("submitter: invalid URL")
+
+// newWebhookSubmitter is the Factory for the webhook submitter.
+func newWebhookSubmitter(config *Config, logger model.Logger) (model.Submitter, error) {
+	URL, err := url.Parse(config.URL)
+	if err != nil || (URL.Scheme != "http" && URL.Scheme != "https") || URL.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, config.URL)
+	}
+	maxRetries := config.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = webhookDefaultMaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+	subm := &webhookSubmitter{
+		backoff:    time.Second,
+		client:     netxlite.NewHTTPClientStdlib(logger),
+		headers:    config.Headers,
+		logger:     logger,
+		maxRetries: maxRetries,
+		url:        config.URL,
+	}
+	return subm, nil
+}
+
+// errWebhookPermanent wraps errors that we should not retry.
+type errWebhookPermanent struct {
+	err error
+}
+
+// Error implements error.
+func (err *errWebhookPermanent) Error() string {
+	return err.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (err *errWebhookPermanent) Unwrap() error {
+	return err.err
+}
+
+// ErrHTTPRequestFailed indicates that the server returned a non-2xx status code.
+var ErrHTTPRequestFailed = errors.New("submitter: HTTP request failed")
+
+// Submit implements model.Submitter.
+func (ws *webhookSubmitter) Submit(ctx context.Context, m *model.Measurement) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	backoff := ws.backoff
+	for attempt := int64(0); ; attempt++ {
+		err = ws.post(ctx, body)
+		var permanent *errWebhookPermanent
+		if err == nil || errors.As(err, &permanent) || attempt >= ws.maxRetries {
+			return err
+		}
+		ws.logger.Warnf("submitter: webhook: %s (retrying in %s)", err.Error(), backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// post performs a single POST request.
+func (ws *webhookSubmitter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", ws.url, bytes.NewReader(body))
+	if err != nil {
+		return &errWebhookPermanent{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range ws.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("%w: %s", ErrHTTPRequestFailed, resp.Status)
+	// retry on server errors and when we're asked to slow down
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	return &errWebhookPermanent{err}
+}
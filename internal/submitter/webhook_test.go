@@ -0,0 +1,151 @@
+package submitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// newTestWebhookSubmitter creates a webhook submitter with a tiny backoff.
+func newTestWebhookSubmitter(t *testing.T, config *Config) *webhookSubmitter {
+	subm, err := newWebhookSubmitter(config, log.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := subm.(*webhookSubmitter)
+	ws.backoff = time.Millisecond
+	return ws
+}
+
+func TestWebhookSubmitter(t *testing.T) {
+	t.Run("newWebhookSubmitter rejects invalid URLs", func(t *testing.T) {
+		for _, URL := range []string{"", "\t", "ftp://example.com/", "https:///"} {
+			subm, err := newWebhookSubmitter(&Config{URL: URL}, log.Log)
+			if !errors.Is(err, ErrInvalidURL) {
+				t.Fatal("unexpected error", URL, err)
+			}
+			if subm != nil {
+				t.Fatal("expected nil submitter")
+			}
+		}
+	})
+
+	t.Run("newWebhookSubmitter handles MaxRetries", func(t *testing.T) {
+		expect := map[int64]int64{0: webhookDefaultMaxRetries, -1: 0, 7: 7}
+		for value, expected := range expect {
+			ws := newTestWebhookSubmitter(t, &Config{URL: "https://example.com/", MaxRetries: value})
+			if ws.maxRetries != expected {
+				t.Fatal("unexpected maxRetries", value, ws.maxRetries)
+			}
+		}
+	})
+
+	t.Run("on success", func(t *testing.T) {
+		var got model.Measurement
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(400)
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer xo" {
+				w.WriteHeader(401)
+				return
+			}
+			data, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(data, &got); err != nil {
+				w.WriteHeader(400)
+				return
+			}
+			w.WriteHeader(204)
+		}))
+		defer srv.Close()
+		ws := newTestWebhookSubmitter(t, &Config{
+			URL:     srv.URL,
+			Headers: map[string]string{"Authorization": "Bearer xo"},
+		})
+		m := &model.Measurement{TestName: "example"}
+		if err := ws.Submit(context.Background(), m); err != nil {
+			t.Fatal(err)
+		}
+		if got.TestName != "example" {
+			t.Fatal("unexpected measurement", got)
+		}
+	})
+
+	t.Run("we retry on server errors", func(t *testing.T) {
+		calls := &atomic.Int64{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) < 3 {
+				w.WriteHeader(503)
+				return
+			}
+			w.WriteHeader(200)
+		}))
+		defer srv.Close()
+		ws := newTestWebhookSubmitter(t, &Config{URL: srv.URL})
+		if err := ws.Submit(context.Background(), &model.Measurement{}); err != nil {
+			t.Fatal(err)
+		}
+		if calls.Load() != 3 {
+			t.Fatal("unexpected number of calls", calls.Load())
+		}
+	})
+
+	t.Run("we give up after MaxRetries", func(t *testing.T) {
+		calls := &atomic.Int64{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(429)
+		}))
+		defer srv.Close()
+		ws := newTestWebhookSubmitter(t, &Config{URL: srv.URL, MaxRetries: 2})
+		err := ws.Submit(context.Background(), &model.Measurement{})
+		if !errors.Is(err, ErrHTTPRequestFailed) {
+			t.Fatal("unexpected error", err)
+		}
+		if calls.Load() != 3 {
+			t.Fatal("unexpected number of calls", calls.Load())
+		}
+	})
+
+	t.Run("we do not retry on client errors", func(t *testing.T) {
+		calls := &atomic.Int64{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(403)
+		}))
+		defer srv.Close()
+		ws := newTestWebhookSubmitter(t, &Config{URL: srv.URL})
+		err := ws.Submit(context.Background(), &model.Measurement{})
+		if !errors.Is(err, ErrHTTPRequestFailed) {
+			t.Fatal("unexpected error", err)
+		}
+		if calls.Load() != 1 {
+			t.Fatal("unexpected number of calls", calls.Load())
+		}
+	})
+
+	t.Run("we stop retrying when the context is done", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(500)
+		}))
+		defer srv.Close()
+		ws := newTestWebhookSubmitter(t, &Config{URL: srv.URL, MaxRetries: 100})
+		ws.backoff = time.Hour
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := ws.Submit(ctx, &model.Measurement{})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
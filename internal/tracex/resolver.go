@@ -97,6 +97,34 @@ func (r *ResolverSaver) LookupNS(ctx context.Context, domain string) ([]*net.NS,
 	return r.Resolver.LookupNS(ctx, domain)
 }
 
+// The following lookups are not saved because the events emitted by
+// LookupHost only describe A and AAAA answers, so the archival format
+// would misrepresent TXT, MX, CNAME, SOA, PTR, and SRV answers.
+
+func (r *ResolverSaver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	return r.Resolver.LookupTXT(ctx, domain)
+}
+
+func (r *ResolverSaver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	return r.Resolver.LookupMX(ctx, domain)
+}
+
+func (r *ResolverSaver) LookupCNAME(ctx context.Context, domain string) (string, error) {
+	return r.Resolver.LookupCNAME(ctx, domain)
+}
+
+func (r *ResolverSaver) LookupSOA(ctx context.Context, domain string) (*model.DNSSOA, error) {
+	return r.Resolver.LookupSOA(ctx, domain)
+}
+
+func (r *ResolverSaver) LookupPTR(ctx context.Context, address string) ([]string, error) {
+	return r.Resolver.LookupPTR(ctx, address)
+}
+
+func (r *ResolverSaver) LookupSRV(ctx context.Context, domain string) ([]*net.SRV, error) {
+	return r.Resolver.LookupSRV(ctx, domain)
+}
+
 // DNSTransportSaver is a DNS transport that saves events.
 type DNSTransportSaver struct {
 	// DNSTransport is the underlying DNS transport.
@@ -214,6 +214,51 @@ func TestResolverSaver(t *testing.T) {
 		}
 	})
 
+	t.Run("LookupTXT, LookupMX, LookupCNAME, LookupSOA, LookupPTR, LookupSRV", func(t *testing.T) {
+		expected := errors.New("mocked")
+		saver := &Saver{}
+		child := &mocks.Resolver{
+			MockLookupTXT: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, expected
+			},
+			MockLookupMX: func(ctx context.Context, domain string) ([]*net.MX, error) {
+				return nil, expected
+			},
+			MockLookupCNAME: func(ctx context.Context, domain string) (string, error) {
+				return "", expected
+			},
+			MockLookupSOA: func(ctx context.Context, domain string) (*model.DNSSOA, error) {
+				return nil, expected
+			},
+			MockLookupPTR: func(ctx context.Context, address string) ([]string, error) {
+				return nil, expected
+			},
+			MockLookupSRV: func(ctx context.Context, domain string) ([]*net.SRV, error) {
+				return nil, expected
+			},
+		}
+		reso := saver.WrapResolver(child)
+		ctx := context.Background()
+		if _, err := reso.LookupTXT(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if _, err := reso.LookupMX(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if _, err := reso.LookupCNAME(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if _, err := reso.LookupSOA(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if _, err := reso.LookupPTR(ctx, "8.8.8.8"); !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+		if _, err := reso.LookupSRV(ctx, "dns.google"); !errors.Is(err, expected) {
+			t.Fatal("unexpected err", err)
+		}
+	})
+
 	t.Run("CloseIdleConnections", func(t *testing.T) {
 		var called bool
 		saver := &Saver{}
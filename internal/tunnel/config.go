@@ -19,11 +19,28 @@ import (
 // structure while in use, because that may lead to data races.
 type Config struct {
 	// Name is the MANDATORY name of the tunnel. We support
-	// "tor", "psiphon", and "fake" tunnels. You SHOULD
+	// "tor", "torsf", "psiphon", "ptx", and "fake" tunnels. You SHOULD
 	// use "fake" tunnels only for testing: they don't provide
 	// any real tunneling, just a socks5 proxy.
 	Name string
 
+	// PTBinary is the OPTIONAL path of an external pluggable transport
+	// binary speaking the pt-spec managed protocol that the "ptx"
+	// tunnel should use to implement the transport in PTBridge.
+	PTBinary string
+
+	// PTBridge is the OPTIONAL bridge line used by the "ptx" tunnel
+	// when PTDialer is not set (e.g., "obfs4 192.0.2.1:443 FINGERPRINT
+	// cert=... iat-mode=0"). Without PTBinary, we only support the
+	// obfs4 and snowflake transports. Except for snowflake, where we
+	// run tor, the backend of the bridge MUST be a SOCKS5 proxy, hence
+	// other standard tor bridge lines do not work.
+	PTBridge string
+
+	// PTDialer is the OPTIONAL pluggable transport dialer used by
+	// the "ptx" tunnel. When set, PTBridge and PTBinary are ignored.
+	PTDialer ptx.PTDialer
+
 	// Session is the MANDATORY measurement session, or a suitable
 	// mock of the required functionality. That is, the possibility
 	// of obtaining a valid psiphon configuration.
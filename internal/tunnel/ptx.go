@@ -0,0 +1,275 @@
+package tunnel
+
+//
+// ptx: pluggable transport tunnel not requiring tor
+//
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/armon/go-socks5"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+	"golang.org/x/net/proxy"
+)
+
+// ErrUnsupportedPluggableTransport indicates that we cannot
+// create a dialer for the pluggable transport you requested.
+var ErrUnsupportedPluggableTransport = errors.New("tunnel: unsupported pluggable transport")
+
+// ErrPTXBackendNotSOCKS5 indicates that the backend of the bridge used
+// by the ptx tunnel does not speak SOCKS5, which most likely means that
+// we're using a standard tor bridge line, where the backend is tor.
+var ErrPTXBackendNotSOCKS5 = errors.New("tunnel: the ptx bridge backend is not a SOCKS5 proxy")
+
+// ptxSOCKSCheckTimeout is the maximum time to wait for the bridge
+// backend to reply to our SOCKS5 greeting during the bootstrap.
+const ptxSOCKSCheckTimeout = 30 * time.Second
+
+// ptxStart starts the ptx tunnel. This tunnel uses config.PTDialer or, if
+// not set, the dialer described by config.PTBridge and config.PTBinary.
+//
+// The tunnel exposes a local SOCKS5 proxy. For each SOCKS5 connection, we
+// dial the bridge using the pluggable transport and speak SOCKS5 with the
+// backend of the bridge, which MUST therefore be a SOCKS5 proxy. Domain
+// names are resolved by the backend, so we don't leak DNS queries.
+//
+// We require a SOCKS5 backend because, without tor, nothing in this tunnel
+// speaks the tor protocol with a standard tor bridge, and SOCKS5 is the
+// simplest protocol allowing us to ask the backend to connect to arbitrary
+// destinations. Snowflake is the exception: its bridges are tor bridges, and
+// there is no way to reach a non-tor backend through them. So, when using
+// snowflake, we bootstrap tor on top of the [ptx.SnowflakeDialer] like the
+// torsf tunnel does and we expose the SOCKS5 proxy provided by tor.
+//
+// Otherwise, before returning, we dial the bridge once and check whether the
+// backend speaks SOCKS5, failing with [ErrPTXBackendNotSOCKS5] if it does not.
+// We use the time required to perform these operations as the bootstrap time.
+func ptxStart(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
+	debugInfo := DebugInfo{
+		LogFilePath: "",
+		Name:        "ptx",
+		Version:     "",
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, debugInfo, err
+	}
+	if config.TunnelDir == "" {
+		return nil, debugInfo, ErrEmptyTunnelDir
+	}
+	if err := config.mkdirAll(config.TunnelDir, 0700); err != nil {
+		return nil, debugInfo, err
+	}
+	dialer, err := newPTXDialer(config)
+	if err != nil {
+		return nil, debugInfo, err
+	}
+	if sfdialer, ok := dialer.(*ptx.SnowflakeDialer); ok {
+		config.logger().Infof("tunnel: starting tor using the snowflake pluggable transport")
+		tunnel, torDebugInfo, err := torsfStartWithDialer(ctx, config, sfdialer)
+		torDebugInfo.Name = debugInfo.Name
+		return tunnel, torDebugInfo, err
+	}
+	config.logger().Infof("tunnel: starting %s pluggable transport", dialer.Name())
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx)
+	if err != nil {
+		ptxMaybeStopDialer(dialer)
+		return nil, debugInfo, err
+	}
+	err = ptxCheckSOCKS5Backend(ctx, conn)
+	conn.Close()
+	if err != nil {
+		ptxMaybeStopDialer(dialer)
+		return nil, debugInfo, err
+	}
+	bootstrapTime := time.Since(start)
+	server, err := config.socks5New(&socks5.Config{
+		Dial:     (&ptxSOCKSDialer{dialer}).DialContext,
+		Logger:   log.New(io.Discard, "", 0),
+		Resolver: &ptxRemoteResolver{},
+	})
+	if err != nil {
+		ptxMaybeStopDialer(dialer)
+		return nil, debugInfo, err
+	}
+	listener, err := config.netListen("tcp", "127.0.0.1:0")
+	if err != nil {
+		ptxMaybeStopDialer(dialer)
+		return nil, debugInfo, err
+	}
+	go server.Serve(listener)
+	return &ptxTunnel{
+		addr:          listener.Addr(),
+		bootstrapTime: bootstrapTime,
+		dialer:        dialer,
+		listener:      listener,
+	}, debugInfo, nil
+}
+
+// newPTXDialer creates the ptx.PTDialer to use according to the config.
+func newPTXDialer(config *Config) (ptx.PTDialer, error) {
+	if config.PTDialer != nil {
+		return config.PTDialer, nil
+	}
+	if config.PTBridge == "" {
+		return nil, fmt.Errorf("%w: PTBridge is empty", ErrUnsupportedPluggableTransport)
+	}
+	bridge, err := ptx.ParseBridgeLine(config.PTBridge)
+	if err != nil {
+		return nil, err
+	}
+	stateDir := filepath.Join(config.TunnelDir, "ptx")
+	switch {
+	case config.PTBinary != "":
+		return &ptx.ExternalDialer{
+			Args:     nil,
+			Binary:   config.PTBinary,
+			Bridge:   bridge,
+			Logger:   config.logger(),
+			StateDir: stateDir,
+		}, nil
+	case bridge.Transport == "obfs4":
+		return &ptx.OBFS4Dialer{
+			Address:     bridge.Address,
+			Cert:        bridge.Options["cert"],
+			DataDir:     stateDir,
+			Fingerprint: bridge.Fingerprint,
+			IATMode:     bridge.Options["iat-mode"],
+		}, nil
+	case bridge.Transport == "snowflake":
+		// Note: the snowflake dialer knows which bridge to use, so we only
+		// need the bridge line to select snowflake as the transport.
+		sfdialer, err := newSnowflakeDialer(config)
+		if err != nil {
+			return nil, err
+		}
+		return sfdialer, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPluggableTransport, bridge.Transport)
+	}
+}
+
+// ptxCheckSOCKS5Backend sends a SOCKS5 greeting offering no authentication
+// to the bridge backend using conn and checks whether the backend accepts it.
+func ptxCheckSOCKS5Backend(ctx context.Context, conn net.Conn) error {
+	deadline := time.Now().Add(ptxSOCKSCheckTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+	if _, err := conn.Write([]byte{5, 1, 0}); err != nil {
+		return fmt.Errorf("%w: %s", ErrPTXBackendNotSOCKS5, err.Error())
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("%w: %s", ErrPTXBackendNotSOCKS5, err.Error())
+	}
+	if reply[0] != 5 || reply[1] != 0 {
+		return fmt.Errorf("%w: unexpected reply: %v", ErrPTXBackendNotSOCKS5, reply)
+	}
+	return nil
+}
+
+// ptxMaybeStopDialer stops the dialer if it's stoppable.
+func ptxMaybeStopDialer(dialer ptx.PTDialer) {
+	if stopper, ok := dialer.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
+}
+
+// ptxSOCKSDialer dials connections using SOCKS5 over the pluggable transport.
+type ptxSOCKSDialer struct {
+	dialer ptx.PTDialer
+}
+
+// DialContext establishes a connection with address using the SOCKS5
+// proxy behind the bridge we connect to with the pluggable transport.
+func (d *ptxSOCKSDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	// the code at proxy/socks5.go never fails; see https://git.io/JfJ4g
+	child, _ := proxy.SOCKS5("tcp", d.dialer.Name(), nil, &ptxForwardDialer{d.dialer})
+	conn, err := child.(proxy.ContextDialer).DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &ptxConn{conn}, nil
+}
+
+// ptxForwardDialer adapts a ptx.PTDialer to be used by proxy.SOCKS5.
+type ptxForwardDialer struct {
+	dialer ptx.PTDialer
+}
+
+// Dial implements proxy.Dialer.
+func (d *ptxForwardDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext implements proxy.ContextDialer.
+func (d *ptxForwardDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.dialer.DialContext(ctx) // the address is implied by the dialer
+}
+
+// ptxConn is the net.Conn returned by ptxSOCKSDialer.
+type ptxConn struct {
+	net.Conn
+}
+
+// LocalAddr implements net.Conn.LocalAddr. We need to always return
+// a *net.TCPAddr because otherwise the socks5 server panics.
+func (c *ptxConn) LocalAddr() net.Addr {
+	if addr, ok := c.Conn.LocalAddr().(*net.TCPAddr); ok {
+		return addr
+	}
+	return &net.TCPAddr{IP: net.IPv4zero, Port: 0}
+}
+
+// ptxRemoteResolver is a socks5.NameResolver that does not resolve
+// domain names, so that the remote proxy resolves them.
+type ptxRemoteResolver struct{}
+
+// Resolve implements socks5.NameResolver.
+func (r *ptxRemoteResolver) Resolve(ctx context.Context, name string) (context.Context, net.IP, error) {
+	return ctx, nil, nil
+}
+
+// ptxTunnel is the tunnel returned by ptxStart.
+type ptxTunnel struct {
+	addr          net.Addr
+	bootstrapTime time.Duration
+	dialer        ptx.PTDialer
+	listener      net.Listener
+	once          sync.Once
+}
+
+var _ Tunnel = &ptxTunnel{}
+
+// BootstrapTime implements Tunnel.
+func (t *ptxTunnel) BootstrapTime() time.Duration {
+	return t.bootstrapTime
+}
+
+// SOCKS5ProxyURL implements Tunnel.
+func (t *ptxTunnel) SOCKS5ProxyURL() *url.URL {
+	return &url.URL{
+		Scheme: "socks5",
+		Host:   t.addr.String(),
+	}
+}
+
+// Stop implements Tunnel.
+func (t *ptxTunnel) Stop() {
+	t.once.Do(func() {
+		t.listener.Close()
+		ptxMaybeStopDialer(t.dialer)
+	})
+}
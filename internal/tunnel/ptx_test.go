@@ -0,0 +1,370 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/armon/go-socks5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+	"github.com/ooni/probe-cli/v3/internal/ptx"
+)
+
+// ptxNewBackend starts a SOCKS5 proxy acting as the bridge backend.
+func ptxNewBackend(t *testing.T) net.Listener {
+	server, err := socks5.New(&socks5.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	return listener
+}
+
+func TestPTXStart(t *testing.T) {
+	t.Run("common case", func(t *testing.T) {
+		srvr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		defer srvr.Close()
+		backend := ptxNewBackend(t)
+		defer backend.Close()
+		tunnel, debugInfo, err := Start(context.Background(), &Config{
+			Name:      "ptx",
+			PTDialer:  &ptx.FakeDialer{Address: backend.Addr().String()},
+			TunnelDir: t.TempDir(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tunnel.Stop()
+		if debugInfo.Name != "ptx" {
+			t.Fatal("unexpected debug info name", debugInfo.Name)
+		}
+		if tunnel.BootstrapTime() <= 0 {
+			t.Fatal("expected positive bootstrap time")
+		}
+		clnt := &http.Client{Transport: &http.Transport{
+			Proxy: http.ProxyURL(tunnel.SOCKS5ProxyURL()),
+		}}
+		defer clnt.CloseIdleConnections()
+		resp, err := clnt.Get(srvr.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "ok" {
+			t.Fatal("unexpected body", string(data))
+		}
+		tunnel.Stop()
+		tunnel.Stop() // should be idempotent
+	})
+
+	t.Run("with a snowflake bridge line", func(t *testing.T) {
+		var torArgs []string
+		tunnel, debugInfo, err := ptxStart(context.Background(), &Config{
+			Logger:    model.DiscardLogger,
+			PTBridge:  "snowflake 192.0.2.3:80",
+			TunnelDir: t.TempDir(),
+			testSfTorStart: func(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
+				torArgs = config.TorArgs
+				tun := &fakeTunnel{
+					addr: &mocks.Addr{
+						MockString: func() string {
+							return "127.0.0.1:5555"
+						},
+					},
+					bootstrapTime: 123,
+					listener: &mocks.Listener{
+						MockClose: func() error {
+							return nil
+						},
+					},
+				}
+				return tun, DebugInfo{Name: "tor", LogFilePath: "tor.log"}, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tunnel.Stop()
+		expectDebugInfo := DebugInfo{Name: "ptx", LogFilePath: "tor.log"}
+		if diff := cmp.Diff(expectDebugInfo, debugInfo); diff != "" {
+			t.Fatal(diff)
+		}
+		expectBridge := ptx.NewSnowflakeDialer().AsBridgeArgument()
+		if len(torArgs) != 6 || torArgs[4] != "Bridge" || torArgs[5] != expectBridge {
+			t.Fatal("unexpected tor args", torArgs)
+		}
+		if tunnel.SOCKS5ProxyURL().String() != "socks5://127.0.0.1:5555" {
+			t.Fatal("invalid socks5 proxy URL")
+		}
+	})
+
+	t.Run("with cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // immediately fail
+		tunnel, _, err := ptxStart(ctx, &Config{TunnelDir: t.TempDir()})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("with empty tunnel dir", func(t *testing.T) {
+		tunnel, _, err := ptxStart(context.Background(), &Config{})
+		if !errors.Is(err, ErrEmptyTunnelDir) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("with failing mkdirAll", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		tunnel, _, err := ptxStart(context.Background(), &Config{
+			TunnelDir: t.TempDir(),
+			testMkdirAll: func(path string, perm os.FileMode) error {
+				return expected
+			},
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("without any pluggable transport", func(t *testing.T) {
+		tunnel, _, err := ptxStart(context.Background(), &Config{TunnelDir: t.TempDir()})
+		if !errors.Is(err, ErrUnsupportedPluggableTransport) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("when we cannot dial the bridge", func(t *testing.T) {
+		backend := ptxNewBackend(t)
+		backend.Close() // so we cannot connect
+		tunnel, _, err := ptxStart(context.Background(), &Config{
+			PTDialer:  &ptx.FakeDialer{Address: backend.Addr().String()},
+			TunnelDir: t.TempDir(),
+		})
+		if err == nil {
+			t.Fatal("expected an error here")
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("when the bridge backend is not a SOCKS5 proxy", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+			conn.Close()
+		}()
+		tunnel, _, err := ptxStart(context.Background(), &Config{
+			PTDialer:  &ptx.FakeDialer{Address: listener.Addr().String()},
+			TunnelDir: t.TempDir(),
+		})
+		if !errors.Is(err, ErrPTXBackendNotSOCKS5) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("when the bridge backend closes the connection", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}()
+		tunnel, _, err := ptxStart(context.Background(), &Config{
+			PTDialer:  &ptx.FakeDialer{Address: listener.Addr().String()},
+			TunnelDir: t.TempDir(),
+		})
+		if !errors.Is(err, ErrPTXBackendNotSOCKS5) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("with failing socks5New", func(t *testing.T) {
+		backend := ptxNewBackend(t)
+		defer backend.Close()
+		expected := errors.New("mocked error")
+		tunnel, _, err := ptxStart(context.Background(), &Config{
+			PTDialer:  &ptx.FakeDialer{Address: backend.Addr().String()},
+			TunnelDir: t.TempDir(),
+			testSocks5New: func(conf *socks5.Config) (*socks5.Server, error) {
+				return nil, expected
+			},
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+
+	t.Run("with failing netListen", func(t *testing.T) {
+		backend := ptxNewBackend(t)
+		defer backend.Close()
+		expected := errors.New("mocked error")
+		tunnel, _, err := ptxStart(context.Background(), &Config{
+			PTDialer:  &ptx.FakeDialer{Address: backend.Addr().String()},
+			TunnelDir: t.TempDir(),
+			testNetListen: func(network, address string) (net.Listener, error) {
+				return nil, expected
+			},
+		})
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tunnel != nil {
+			t.Fatal("expected nil tunnel here")
+		}
+	})
+}
+
+func TestNewPTXDialer(t *testing.T) {
+	const (
+		fingerprint = "0123456789ABCDEF0123456789ABCDEF01234567"
+		tunnelDir   = "testdata"
+	)
+	stateDir := filepath.Join(tunnelDir, "ptx")
+	fake := &ptx.FakeDialer{Address: "127.0.0.1:5555"}
+	tests := map[string]struct {
+		config    *Config
+		expect    ptx.PTDialer
+		expectErr error
+	}{
+		"with PTDialer": {
+			config: &Config{PTDialer: fake, PTBridge: "obfs4 127.0.0.1:443"},
+			expect: fake,
+		},
+		"with an obfs4 bridge line": {
+			config: &Config{
+				PTBridge:  "obfs4 127.0.0.1:443 " + fingerprint + " cert=xyz iat-mode=1",
+				TunnelDir: tunnelDir,
+			},
+			expect: &ptx.OBFS4Dialer{
+				Address:     "127.0.0.1:443",
+				Cert:        "xyz",
+				DataDir:     stateDir,
+				Fingerprint: fingerprint,
+				IATMode:     "1",
+			},
+		},
+		"with a snowflake bridge line": {
+			config: &Config{PTBridge: "snowflake 192.0.2.3:80", TunnelDir: tunnelDir},
+			expect: ptx.NewSnowflakeDialerWithRendezvousMethod(
+				ptx.NewSnowflakeRendezvousMethodDomainFronting()),
+		},
+		"with a snowflake bridge line and the AMP rendezvous method": {
+			config: &Config{
+				PTBridge:            "snowflake 192.0.2.3:80",
+				SnowflakeRendezvous: "amp",
+				TunnelDir:           tunnelDir,
+			},
+			expect: ptx.NewSnowflakeDialerWithRendezvousMethod(
+				ptx.NewSnowflakeRendezvousMethodAMP()),
+		},
+		"with a snowflake bridge line and an invalid rendezvous method": {
+			config: &Config{
+				PTBridge:            "snowflake 192.0.2.3:80",
+				SnowflakeRendezvous: "antani",
+				TunnelDir:           tunnelDir,
+			},
+			expectErr: ptx.ErrSnowflakeNoSuchRendezvousMethod,
+		},
+		"with an external binary": {
+			config: &Config{
+				PTBinary:  "/usr/bin/lyrebird",
+				PTBridge:  "webtunnel 127.0.0.1:443 url=https://example.com/",
+				TunnelDir: tunnelDir,
+			},
+			expect: &ptx.ExternalDialer{
+				Binary: "/usr/bin/lyrebird",
+				Bridge: &ptx.BridgeLine{
+					Address:   "127.0.0.1:443",
+					Options:   map[string]string{"url": "https://example.com/"},
+					Transport: "webtunnel",
+				},
+				StateDir: stateDir,
+			},
+		},
+		"with empty bridge line": {
+			config:    &Config{},
+			expectErr: ErrUnsupportedPluggableTransport,
+		},
+		"with invalid bridge line": {
+			config:    &Config{PTBridge: "obfs4"},
+			expectErr: ptx.ErrInvalidBridgeLine,
+		},
+		"with unsupported transport": {
+			config:    &Config{PTBridge: "webtunnel 127.0.0.1:443"},
+			expectErr: ErrUnsupportedPluggableTransport,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dialer, err := newPTXDialer(tt.config)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatal("unexpected error", err)
+			}
+			if external, ok := dialer.(*ptx.ExternalDialer); ok {
+				external.Logger = nil // cannot compare
+			}
+			opts := []cmp.Option{
+				cmpopts.IgnoreUnexported(ptx.ExternalDialer{}, ptx.SnowflakeDialer{}),
+			}
+			if diff := cmp.Diff(tt.expect, dialer, opts...); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
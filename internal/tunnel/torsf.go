@@ -19,12 +19,16 @@ func torsfStart(ctx context.Context, config *Config) (Tunnel, DebugInfo, error)
 	if err := ctx.Err(); err != nil {
 		return nil, DebugInfo{}, err
 	}
-
-	// 1. start a listener using snowflake
 	sfdialer, err := newSnowflakeDialer(config)
 	if err != nil {
 		return nil, DebugInfo{}, err
 	}
+	return torsfStartWithDialer(ctx, config, sfdialer)
+}
+
+// torsfStartWithDialer starts the torsf tunnel using the given snowflake dialer.
+func torsfStartWithDialer(ctx context.Context, config *Config, sfdialer *ptx.SnowflakeDialer) (Tunnel, DebugInfo, error) {
+	// 1. start a listener using snowflake
 	ptl := config.sfNewPTXListener(ctx, sfdialer)
 	if err := ptl.Start(); err != nil {
 		return nil, DebugInfo{}, err
@@ -107,6 +107,18 @@ type DebugInfo struct {
 // case, fetching the Psiphon configuration from the backend may
 // fail when the backend is not reachable.
 //
+// The "torsf" tunnel is like "tor" but uses snowflake. You can use
+// config.SnowflakeRendezvous to select the rendezvous method.
+//
+// The "ptx" tunnel uses a pluggable transport without tor. You can
+// either set config.PTDialer or provide a config.PTBridge line and,
+// optionally, a config.PTBinary implementing the pluggable transport
+// using the managed protocol. The bridge backend MUST be a SOCKS5
+// proxy: we'll speak SOCKS5 with it over the pluggable transport. Thus,
+// the "ptx" tunnel does not work with standard tor bridges. The only
+// exception is snowflake, for which we run tor like "torsf" does, using
+// config.SnowflakeRendezvous to select the rendezvous method.
+//
 // The "fake" tunnel is a fake tunnel that just exposes a
 // SOCKS5 proxy and then connects directly to server. We use
 // this special kind of tunnel to implement tests.
@@ -124,6 +136,8 @@ func Start(ctx context.Context, config *Config) (Tunnel, DebugInfo, error) {
 		return fakeStart(ctx, config)
 	case "psiphon":
 		return psiphonStart(ctx, config)
+	case "ptx":
+		return ptxStart(ctx, config)
 	case "torsf":
 		return torsfStart(ctx, config)
 	case "tor":
@@ -49,4 +49,12 @@
 //
 // The basic tenet of the session API is that you create an instance
 // of `Session` and use it to perform the operations you need.
+//
+// # OONI Run v2 API
+//
+// You use `Session.OONIRunV2Fetch` to fetch an OONI Run v2 descriptor and
+// to know whether it changed since the user last accepted it, and
+// `Session.OONIRunV2Accept` to record that the user accepted it. Then,
+// `StartOONIRunV2Task` runs the descriptor's nettests emitting the same
+// events emitted by the task API for each nettest.
 package oonimkall
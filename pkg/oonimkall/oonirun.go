@@ -0,0 +1,183 @@
+package oonimkall
+
+//
+// OONI Run v2 API
+//
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+// OONIRunV2Descriptor is an OONI Run v2 descriptor. You obtain
+// a descriptor by calling Session.OONIRunV2Fetch. You should show
+// the descriptor to the user when it has changed and, if the user
+// accepts it, call Session.OONIRunV2Accept. Then, you can run the
+// descriptor's nettests using StartOONIRunV2Task.
+type OONIRunV2Descriptor struct {
+	// Author contains the author's name.
+	Author string
+
+	// Changed indicates whether this descriptor is new or has changed
+	// since the last time the user accepted it.
+	Changed bool
+
+	// Description contains a long description.
+	Description string
+
+	// Diff describes how the descriptor has changed since the last
+	// time the user accepted it. It is empty when Changed is false.
+	Diff string
+
+	// Name is the name of this descriptor.
+	Name string
+
+	// URL is the URL from which we fetched this descriptor.
+	URL string
+
+	// desc is the underlying descriptor.
+	desc *oonirun.V2Descriptor
+}
+
+// newOONIRunV2Descriptor creates a new OONIRunV2Descriptor instance.
+func newOONIRunV2Descriptor(URL string, desc *oonirun.V2Descriptor, diff string) *OONIRunV2Descriptor {
+	return &OONIRunV2Descriptor{
+		Author:      desc.Author,
+		Changed:     diff != "",
+		Description: desc.Description,
+		Diff:        diff,
+		Name:        desc.Name,
+		URL:         URL,
+		desc:        desc,
+	}
+}
+
+// NettestsSize returns the number of nettests inside the descriptor.
+func (d *OONIRunV2Descriptor) NettestsSize() int64 {
+	return int64(len(d.desc.Nettests))
+}
+
+// NettestAt returns the nettest at index idx. Note that this function will
+// return nil/null if the index is out of bounds.
+func (d *OONIRunV2Descriptor) NettestAt(idx int64) *OONIRunV2Nettest {
+	if idx < 0 || int(idx) >= len(d.desc.Nettests) {
+		return nil
+	}
+	nettest := d.desc.Nettests[idx]
+	options, err := json.Marshal(nettest.Options)
+	runtimex.PanicOnError(err, "json.Marshal failed")
+	return &OONIRunV2Nettest{
+		OptionsJSON: string(options),
+		TestName:    nettest.TestName,
+		inputs:      nettest.Inputs,
+	}
+}
+
+// OONIRunV2Nettest is a nettest inside an OONIRunV2Descriptor.
+type OONIRunV2Nettest struct {
+	// OptionsJSON contains the nettest options serialized as JSON.
+	OptionsJSON string
+
+	// TestName contains the nettest name.
+	TestName string
+
+	// inputs contains the nettest inputs.
+	inputs []string
+}
+
+// InputsSize returns the number of inputs of the nettest.
+func (n *OONIRunV2Nettest) InputsSize() int64 {
+	return int64(len(n.inputs))
+}
+
+// InputAt returns the input at index idx. Note that this function will
+// return an empty string if the index is out of bounds.
+func (n *OONIRunV2Nettest) InputAt(idx int64) string {
+	if idx < 0 || int(idx) >= len(n.inputs) {
+		return ""
+	}
+	return n.inputs[idx]
+}
+
+// OONIRunV2Fetch fetches the OONI Run v2 descriptor at the given URL and
+// tells you whether it changed since the last time you accepted it.
+//
+// This function locks the session until it's done. That is, no other operation
+// can be performed as long as this function is pending.
+func (sess *Session) OONIRunV2Fetch(ctx *Context, URL string) (*OONIRunV2Descriptor, error) {
+	sess.mtx.Lock()
+	defer sess.mtx.Unlock()
+	desc, diff, err := oonirun.V2FetchDescriptor(ctx.ctx, sess.sessp, sess.sessp.KeyValueStore(), URL)
+	if err != nil {
+		return nil, err
+	}
+	return newOONIRunV2Descriptor(URL, desc, diff), nil
+}
+
+// OONIRunV2Accept records that the user has accepted the given descriptor,
+// such that the next OONIRunV2Fetch will report no changes unless the
+// descriptor changes again. The descriptor MUST NOT be nil.
+//
+// This function locks the session until it's done. That is, no other operation
+// can be performed as long as this function is pending.
+func (sess *Session) OONIRunV2Accept(desc *OONIRunV2Descriptor) error {
+	sess.mtx.Lock()
+	defer sess.mtx.Unlock()
+	return oonirun.V2AcceptDescriptor(sess.sessp.KeyValueStore(), desc.URL, desc.desc)
+}
+
+// errNilDescriptor indicates that the descriptor passed to
+// StartOONIRunV2Task is nil.
+var errNilDescriptor = errors.New("oonimkall: descriptor is nil")
+
+// StartOONIRunV2Task starts an asynchronous task running all the nettests inside
+// the given descriptor, one after the other. The input argument is the same
+// serialized JSON you would pass to StartTask, except that we ignore the name,
+// inputs, and extra options and we use the ones in the descriptor instead.
+//
+// For each nettest, the task emits the same events emitted by a task started
+// using StartTask, from status.queued until status.end. It is up to the caller
+// to check whether the descriptor has changed and the user has accepted it.
+//
+// Unlike StartTask, when a nettest requiring input has no inputs, we load
+// them like oonirun.V2MeasureDescriptor does (e.g., for web_connectivity we
+// call the check-in API to obtain the URLs to measure).
+func StartOONIRunV2Task(input string, desc *OONIRunV2Descriptor) (*Task, error) {
+	if desc == nil {
+		return nil, errNilDescriptor
+	}
+	var settings settings
+	if err := json.Unmarshal([]byte(input), &settings); err != nil {
+		return nil, err
+	}
+	all := ooniRunV2TaskSettings(&settings, desc.desc)
+	return startTask(func(ctx context.Context, emitter taskEmitter) {
+		for _, settings := range all {
+			if ctx.Err() != nil {
+				break
+			}
+			newRunner(settings, emitter).Run(ctx)
+		}
+	}), nil
+}
+
+// ooniRunV2TaskSettings returns the settings for running each nettest inside
+// the given descriptor, using the given settings as the template.
+func ooniRunV2TaskSettings(template *settings, desc *oonirun.V2Descriptor) (out []*settings) {
+	for _, nettest := range desc.Nettests {
+		if nettest.TestName == "" {
+			continue // like oonirun.V2MeasureDescriptor
+		}
+		settings := *template // shallow copy
+		settings.ExtraOptions = nettest.Options
+		settings.Inputs = append([]string{}, nettest.Inputs...)
+		settings.Name = nettest.TestName
+		settings.loadInputs = true // like oonirun.V2MeasureDescriptor
+		out = append(out, &settings)
+	}
+	return
+}
@@ -0,0 +1,205 @@
+package oonimkall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/oonirun"
+	"github.com/ooni/probe-cli/v3/internal/runtimex"
+)
+
+func TestOONIRunV2Descriptor(t *testing.T) {
+	desc := newOONIRunV2Descriptor("https://example.com/", &oonirun.V2Descriptor{
+		Name:        "name",
+		Description: "description",
+		Author:      "author",
+		Nettests: []oonirun.V2Nettest{{
+			Inputs:   []string{"https://www.example.com/"},
+			Options:  map[string]any{"SleepTime": 10},
+			TestName: "example",
+		}},
+	}, "")
+	if desc.Author != "author" || desc.Description != "description" || desc.Name != "name" {
+		t.Fatal("unexpected descriptor", desc)
+	}
+	if desc.Changed || desc.URL != "https://example.com/" {
+		t.Fatal("unexpected descriptor", desc)
+	}
+	if desc.NettestsSize() != 1 {
+		t.Fatal("unexpected number of nettests")
+	}
+	if desc.NettestAt(-1) != nil || desc.NettestAt(1) != nil {
+		t.Fatal("expected nil nettest when out of bounds")
+	}
+	nettest := desc.NettestAt(0)
+	if nettest.TestName != "example" || nettest.OptionsJSON != `{"SleepTime":10}` {
+		t.Fatal("unexpected nettest", nettest)
+	}
+	if nettest.InputsSize() != 1 || nettest.InputAt(0) != "https://www.example.com/" {
+		t.Fatal("unexpected inputs")
+	}
+	if nettest.InputAt(-1) != "" || nettest.InputAt(1) != "" {
+		t.Fatal("expected empty input when out of bounds")
+	}
+}
+
+func TestSessionOONIRunV2(t *testing.T) {
+	descriptor := &oonirun.V2Descriptor{
+		Name: "custom",
+		Nettests: []oonirun.V2Nettest{{
+			TestName: "example",
+		}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(descriptor)
+		runtimex.PanicOnError(err, "json.Marshal failed")
+		w.Write(data)
+	}))
+	defer server.Close()
+	sess, err := newSessionWithContext(context.Background(), &SessionConfig{
+		SoftwareName:    "oonimkall-test",
+		SoftwareVersion: "0.1.0",
+		StateDir:        t.TempDir(),
+		TempDir:         t.TempDir(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := sess.NewContext()
+	defer ctx.Cancel()
+
+	// the first time the descriptor is new
+	desc, err := sess.OONIRunV2Fetch(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !desc.Changed || desc.Diff == "" || desc.Name != "custom" {
+		t.Fatal("unexpected descriptor", desc)
+	}
+
+	// once accepted, the descriptor does not change anymore
+	if err := sess.OONIRunV2Accept(desc); err != nil {
+		t.Fatal(err)
+	}
+	desc, err = sess.OONIRunV2Fetch(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Changed || desc.Diff != "" {
+		t.Fatal("unexpected descriptor", desc)
+	}
+
+	// we notice when the descriptor changes again
+	descriptor.Name = "modified"
+	desc, err = sess.OONIRunV2Fetch(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !desc.Changed || desc.Name != "modified" {
+		t.Fatal("unexpected descriptor", desc)
+	}
+
+	// we handle errors
+	server.Close()
+	desc, err = sess.OONIRunV2Fetch(ctx, server.URL)
+	if err == nil || desc != nil {
+		t.Fatal("expected an error and a nil descriptor")
+	}
+}
+
+func TestStartOONIRunV2Task(t *testing.T) {
+	t.Run("with nil descriptor", func(t *testing.T) {
+		task, err := StartOONIRunV2Task("{}", nil)
+		if !errors.Is(err, errNilDescriptor) {
+			t.Fatal("unexpected error", err)
+		}
+		if task != nil {
+			t.Fatal("expected nil task")
+		}
+	})
+
+	t.Run("with invalid JSON", func(t *testing.T) {
+		desc := newOONIRunV2Descriptor("", &oonirun.V2Descriptor{}, "")
+		task, err := StartOONIRunV2Task("{", desc)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if task != nil {
+			t.Fatal("expected nil task")
+		}
+	})
+
+	t.Run("we run each nettest", func(t *testing.T) {
+		desc := newOONIRunV2Descriptor("", &oonirun.V2Descriptor{
+			Nettests: []oonirun.V2Nettest{{
+				TestName: "antani",
+			}, {
+				TestName: "mascetti",
+			}},
+		}, "")
+		// Version zero causes each nettest to immediately fail
+		task, err := StartOONIRunV2Task(`{"log_level":"DEBUG"}`, desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count int
+		for !task.IsDone() {
+			var ev event
+			if err := json.Unmarshal([]byte(task.WaitForNextEvent()), &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Key == eventTypeFailureStartup {
+				count++
+			}
+		}
+		if count != 2 {
+			t.Fatal("unexpected number of failure.startup events", count)
+		}
+	})
+}
+
+func TestOONIRunV2TaskSettings(t *testing.T) {
+	template := &settings{
+		Annotations: map[string]string{"x": "y"},
+		Inputs:      []string{"https://www.example.com/"},
+		Name:        "Example",
+		StateDir:    "state",
+		Version:     1,
+	}
+	desc := &oonirun.V2Descriptor{
+		Nettests: []oonirun.V2Nettest{{
+			Inputs:   []string{"https://www.example.org/"},
+			Options:  map[string]any{"SleepTime": 10},
+			TestName: "web_connectivity",
+		}, {
+			TestName: "", // should be skipped
+		}, {
+			TestName: "dnscheck",
+		}},
+	}
+	expect := []*settings{{
+		Annotations:  map[string]string{"x": "y"},
+		ExtraOptions: map[string]any{"SleepTime": 10},
+		Inputs:       []string{"https://www.example.org/"},
+		Name:         "web_connectivity",
+		StateDir:     "state",
+		Version:      1,
+		loadInputs:   true,
+	}, {
+		Annotations: map[string]string{"x": "y"},
+		Inputs:      []string{},
+		Name:        "dnscheck",
+		StateDir:    "state",
+		Version:     1,
+		loadInputs:  true,
+	}}
+	got := ooniRunV2TaskSettings(template, desc)
+	if diff := cmp.Diff(expect, got, cmp.AllowUnexported(settings{})); diff != "" {
+		t.Fatal(diff)
+	}
+}
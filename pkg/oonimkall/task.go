@@ -34,6 +34,13 @@ func StartTask(input string) (*Task, error) {
 	if err := json.Unmarshal([]byte(input), &settings); err != nil {
 		return nil, err
 	}
+	return startTask(func(ctx context.Context, emitter taskEmitter) {
+		newRunner(&settings, emitter).Run(ctx)
+	}), nil
+}
+
+// startTask starts a task using the given function to run it.
+func startTask(run func(ctx context.Context, emitter taskEmitter)) *Task {
 	const bufsiz = 128 // common case: we don't want runner to block
 	ctx, cancel := context.WithCancel(context.Background())
 	task := &Task{
@@ -46,13 +53,12 @@ func StartTask(input string) (*Task, error) {
 	go func() {
 		close(task.isstarted)
 		emitter := newTaskEmitterUsingChan(task.out)
-		r := newRunner(&settings, emitter)
-		r.Run(ctx)
+		run(ctx, emitter)
 		task.out <- nil // signal that we're done w/o closing the channel
 		emitter.Close()
 		close(task.isstopped)
 	}()
-	return task, nil
+	return task
 }
 
 // WaitForNextEvent blocks until the next event occurs. The returned
@@ -83,6 +83,9 @@ type MockableTaskRunnerDependencies struct {
 	MockResolverASNString          func() string
 	MockResolverIP                 func() string
 	MockResolverNetworkName        func() string
+	MockRemainingDataBudget        func() (int64, bool)
+	MockCheckIn                    func(ctx context.Context,
+		config *model.OOAPICheckInConfig) (*model.OOAPICheckInResultNettests, error)
 
 	// taskExperimentBuilder:
 
@@ -90,6 +93,7 @@ type MockableTaskRunnerDependencies struct {
 	MockableInputPolicy           func() model.InputPolicy
 	MockableNewExperimentInstance func() taskExperiment
 	MockableInterruptible         func() bool
+	MockableSetOptionsAny         func(options map[string]any) error
 
 	// taskExperiment:
 
@@ -165,6 +169,18 @@ func (dep *MockableTaskRunnerDependencies) ResolverNetworkName() string {
 	return dep.MockResolverNetworkName()
 }
 
+func (dep *MockableTaskRunnerDependencies) RemainingDataBudget() (int64, bool) {
+	if f := dep.MockRemainingDataBudget; f != nil {
+		return f()
+	}
+	return 0, false
+}
+
+func (dep *MockableTaskRunnerDependencies) CheckIn(ctx context.Context,
+	config *model.OOAPICheckInConfig) (*model.OOAPICheckInResultNettests, error) {
+	return dep.MockCheckIn(ctx, config)
+}
+
 func (dep *MockableTaskRunnerDependencies) SetCallbacks(callbacks model.ExperimentCallbacks) {
 	dep.MockableSetCallbacks(callbacks)
 }
@@ -184,6 +200,13 @@ func (dep *MockableTaskRunnerDependencies) Interruptible() bool {
 	return dep.MockableInterruptible()
 }
 
+func (dep *MockableTaskRunnerDependencies) SetOptionsAny(options map[string]any) error {
+	if f := dep.MockableSetOptionsAny; f != nil {
+		return f(options)
+	}
+	return nil
+}
+
 func (dep *MockableTaskRunnerDependencies) KibiBytesReceived() float64 {
 	return dep.MockableKibiBytesReceived()
 }
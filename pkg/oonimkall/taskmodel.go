@@ -107,16 +107,18 @@ type eventLog struct {
 }
 
 type eventMeasurementGeneric struct {
-	Failure string `json:"failure,omitempty"`
-	Idx     int64  `json:"idx"`
-	Input   string `json:"input"`
-	JSONStr string `json:"json_str,omitempty"`
+	Failure           string   `json:"failure,omitempty"`
+	Idx               int64    `json:"idx"`
+	Input             string   `json:"input"`
+	JSONStr           string   `json:"json_str,omitempty"`
+	RemainingBudgetKB *float64 `json:"remaining_budget_kb,omitempty"`
 }
 
 type eventStatusEnd struct {
-	DownloadedKB float64 `json:"downloaded_kb"`
-	Failure      string  `json:"failure"`
-	UploadedKB   float64 `json:"uploaded_kb"`
+	DownloadedKB      float64  `json:"downloaded_kb"`
+	Failure           string   `json:"failure"`
+	RemainingBudgetKB *float64 `json:"remaining_budget_kb,omitempty"`
+	UploadedKB        float64  `json:"uploaded_kb"`
 }
 
 type eventStatusGeoIPLookup struct {
@@ -219,6 +221,15 @@ type taskSession interface {
 	// ResolverNetworkName must be called after MaybeLookupLocationContext
 	// and returns the resolved resolver's network name.
 	ResolverNetworkName() string
+
+	// RemainingDataBudget returns the bytes we can still use and
+	// whether we're using a data usage budget at all.
+	RemainingDataBudget() (int64, bool)
+
+	// CheckIn calls the check-in API, which we use to load the inputs
+	// of experiments using the InputOrQueryBackend policy.
+	CheckIn(ctx context.Context,
+		config *model.OOAPICheckInConfig) (*model.OOAPICheckInResultNettests, error)
 }
 
 // taskExperimentBuilder builds a taskExperiment.
@@ -234,6 +245,9 @@ type taskExperimentBuilder interface {
 
 	// Interruptible returns whether this experiment is interruptible.
 	Interruptible() bool
+
+	// SetOptionsAny sets the experiment options.
+	SetOptionsAny(options map[string]any) error
 }
 
 // taskExperiment is a runnable experiment.
@@ -300,6 +314,11 @@ type settings struct {
 	// code was ignoring it on 2021-12-01.
 	DisabledEvents []string `json:"disabled_events,omitempty"`
 
+	// ExtraOptions contains experiment-specific options, which
+	// we set using the ExperimentBuilder's SetOptionsAny method
+	// before running the experiment. Added since 3.18.0.
+	ExtraOptions map[string]any `json:"extra_options,omitempty"`
+
 	// Inputs contains the inputs. The task will fail if it
 	// requires input and you provide no input.
 	Inputs []string `json:"inputs,omitempty"`
@@ -347,10 +366,24 @@ type settings struct {
 
 	// Version indicates the version of this structure.
 	Version int64 `json:"version"`
+
+	// loadInputs indicates whether we should load the inputs (e.g., by
+	// calling the check-in API) when Inputs is empty and the experiment
+	// requires input. We only set this field when running OONI Run v2
+	// descriptors, since the apps otherwise load the inputs themselves.
+	loadInputs bool
 }
 
 // settingsOptions contains the settings options
 type settingsOptions struct {
+	// DataBudgetKB is the maximum number of KiB (sent plus received) that
+	// the experiment may use. When we exhaust this budget, we stop measuring
+	// and we skip the remaining inputs. A zero or negative value means there
+	// is no limit. To implement a daily budget, pass the daily budget minus
+	// the KiB already used today (see the downloaded_kb and uploaded_kb fields
+	// of the status.end event). Added since 3.18.0.
+	DataBudgetKB int64 `json:"data_budget_kb,omitempty"`
+
 	// MaxRuntime is the maximum runtime expressed in seconds. A negative
 	// value for this field disables the maximum runtime. Using
 	// a zero value will also mean disabled. This is not the
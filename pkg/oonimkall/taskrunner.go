@@ -3,11 +3,14 @@ package oonimkall
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/measurexlite"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/runtimex"
 )
@@ -59,12 +62,14 @@ func (r *runnerForTask) newsession(ctx context.Context, logger model.Logger) (ta
 	}
 
 	config := engine.SessionConfig{
+		DataBudget:      r.settings.Options.DataBudgetKB * 1024,
 		KVStore:         kvstore,
 		Logger:          logger,
 		ProxyURL:        proxyURL,
 		SoftwareName:    r.settings.Options.SoftwareName,
 		SoftwareVersion: r.settings.Options.SoftwareVersion,
 		TempDir:         r.settings.TempDir,
+		TraceSubscriber: measurexlite.NewLoggerSubscriber(logger),
 		TunnelDir:       r.settings.TunnelDir,
 	}
 	if r.settings.Options.ProbeServicesBaseURL != "" {
@@ -127,6 +132,7 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 	}
 	endEvent := new(eventStatusEnd)
 	defer func() {
+		endEvent.RemainingBudgetKB = remainingBudgetKB(sess)
 		sess.Close()
 		r.emitter.Emit(eventTypeStatusEnd, endEvent)
 	}()
@@ -136,6 +142,10 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 		r.emitter.EmitFailureStartup(err.Error())
 		return
 	}
+	if err := builder.SetOptionsAny(r.settings.ExtraOptions); err != nil {
+		r.emitter.EmitFailureStartup(err.Error())
+		return
+	}
 
 	logger.Info("Looking up OONI backends... please, be patient")
 	if err := sess.MaybeLookupBackendsContext(rootCtx); err != nil {
@@ -185,6 +195,14 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 	// responsibility to load the inputs, not oonimkall's.
 	switch builder.InputPolicy() {
 	case model.InputOrQueryBackend, model.InputStrictlyRequired:
+		if len(r.settings.Inputs) <= 0 && r.settings.loadInputs {
+			inputs, err := r.loadInputs(rootCtx, sess, logger, builder.InputPolicy())
+			if err != nil {
+				r.emitter.EmitFailureStartup(err.Error())
+				return
+			}
+			r.settings.Inputs = inputs
+		}
 		if len(r.settings.Inputs) <= 0 {
 			r.emitter.EmitFailureStartup("no input provided")
 			return
@@ -297,6 +315,13 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 				Idx:     int64(idx),
 				Input:   input,
 			})
+			// Note: when we exhaust the budget while measuring, the engine
+			// discards the incomplete measurement and returns this error, so
+			// we emit a measurement failure and we do not submit anything.
+			if errors.Is(err, bytecounter.ErrBudgetExceeded) {
+				logger.Warn("exhausted the data usage budget; skipping the remaining inputs")
+				break
+			}
 			// Historical note: here we used to fallthrough but, since we have
 			// implemented async measurements, the case where there is an error
 			// and we also have a valid measurement cant't happen anymore. So,
@@ -323,12 +348,51 @@ func (r *runnerForTask) Run(rootCtx context.Context) {
 			})
 		}
 		r.emitter.Emit(eventTypeStatusMeasurementDone, eventMeasurementGeneric{
-			Idx:   int64(idx),
-			Input: input,
+			Idx:               int64(idx),
+			Input:             input,
+			RemainingBudgetKB: remainingBudgetKB(sess),
 		})
 	}
 }
 
+// loadInputs loads the inputs using the engine.InputLoader, which calls the
+// check-in API for experiments using the InputOrQueryBackend policy, like
+// oonirun.V2MeasureDescriptor does when the nettest inputs are empty.
+func (r *runnerForTask) loadInputs(ctx context.Context, sess taskSession,
+	logger model.Logger, inputPolicy model.InputPolicy) ([]string, error) {
+	loader := &engine.InputLoader{
+		CheckInConfig: &model.OOAPICheckInConfig{
+			RunType:  model.RunTypeManual,
+			OnWiFi:   true, // meaning: not on 4G
+			Charging: true,
+		},
+		ExperimentName: r.settings.Name,
+		InputPolicy:    inputPolicy,
+		Logger:         logger,
+		Session:        sess,
+	}
+	infos, err := loader.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []string
+	for _, info := range infos {
+		inputs = append(inputs, info.URL)
+	}
+	return inputs, nil
+}
+
+// remainingBudgetKB returns the KiB we can still use or nil if
+// we're not using a data usage budget.
+func remainingBudgetKB(sess taskSession) *float64 {
+	remaining, limited := sess.RemainingDataBudget()
+	if !limited {
+		return nil
+	}
+	kb := float64(remaining) / 1024
+	return &kb
+}
+
 func warnOnFailure(logger model.Logger, message string, err error) {
 	if err != nil {
 		logger.Warnf("%s: %s (%+v)", message, err.Error(), err)
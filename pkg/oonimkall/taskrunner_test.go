@@ -3,10 +3,12 @@ package oonimkall
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	engine "github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -267,6 +269,30 @@ func TestTaskRunnerRun(t *testing.T) {
 		assertReducedEventsLike(t, expect, reduced)
 	})
 
+	t.Run("with invalid extra options", func(t *testing.T) {
+		runner, emitter := newRunnerForTesting()
+		runner.settings.ExtraOptions = map[string]any{"Antani": true}
+		fake := fakeSuccessfulRun()
+		var options map[string]any
+		fake.MockableSetOptionsAny = func(value map[string]any) error {
+			options = value
+			return errors.New("mocked error")
+		}
+		runner.sessionBuilder = fake
+		events := runAndCollect(runner, emitter)
+		reduced := reduceEventsKeysIgnoreLog(events)
+		expect := []eventKeyCount{
+			{Key: eventTypeStatusQueued, Count: 1},
+			{Key: eventTypeStatusStarted, Count: 1},
+			{Key: eventTypeFailureStartup, Count: 1},
+			{Key: eventTypeStatusEnd, Count: 1},
+		}
+		assertReducedEventsLike(t, expect, reduced)
+		if diff := cmp.Diff(runner.settings.ExtraOptions, options); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
 	t.Run("with error during backends lookup", func(t *testing.T) {
 		runner, emitter := newRunnerForTesting()
 		fake := fakeSuccessfulRun()
@@ -328,6 +354,90 @@ func TestTaskRunnerRun(t *testing.T) {
 		assertReducedEventsLike(t, expect, reduced)
 	})
 
+	t.Run("with missing input, InputOrQueryBackend policy, and OONI Run v2", func(t *testing.T) {
+		runner, emitter := newRunnerForTesting()
+		runner.settings.Name = "web_connectivity"
+		runner.settings.loadInputs = true
+		fake := fakeSuccessfulRun()
+		fake.MockableInputPolicy = func() model.InputPolicy {
+			return model.InputOrQueryBackend
+		}
+		fake.MockCheckIn = func(ctx context.Context,
+			config *model.OOAPICheckInConfig) (*model.OOAPICheckInResultNettests, error) {
+			return &model.OOAPICheckInResultNettests{
+				WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+					URLs: []model.OOAPIURLInfo{{
+						URL: "https://www.example.com/",
+					}, {
+						URL: "https://www.example.org/",
+					}},
+				},
+			}, nil
+		}
+		var inputs []string
+		fake.MockableMeasureWithContext = func(ctx context.Context, input string) (*model.Measurement, error) {
+			inputs = append(inputs, input)
+			return &model.Measurement{}, nil
+		}
+		runner.sessionBuilder = fake
+		events := runAndCollect(runner, emitter)
+		reduced := reduceEventsKeysIgnoreLog(events)
+		expect := []eventKeyCount{
+			{Key: eventTypeStatusQueued, Count: 1},
+			{Key: eventTypeStatusStarted, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 3},
+			{Key: eventTypeStatusGeoIPLookup, Count: 1},
+			{Key: eventTypeStatusResolverLookup, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeStatusReportCreate, Count: 1},
+			//
+			{Key: eventTypeStatusMeasurementStart, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeMeasurement, Count: 1},
+			{Key: eventTypeStatusMeasurementSubmission, Count: 1},
+			{Key: eventTypeStatusMeasurementDone, Count: 1},
+			//
+			{Key: eventTypeStatusMeasurementStart, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeMeasurement, Count: 1},
+			{Key: eventTypeStatusMeasurementSubmission, Count: 1},
+			{Key: eventTypeStatusMeasurementDone, Count: 1},
+			//
+			{Key: eventTypeStatusEnd, Count: 1},
+		}
+		assertReducedEventsLike(t, expect, reduced)
+		if diff := cmp.Diff([]string{"https://www.example.com/", "https://www.example.org/"}, inputs); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with missing input, InputOrQueryBackend policy, OONI Run v2, and check-in failure", func(t *testing.T) {
+		runner, emitter := newRunnerForTesting()
+		runner.settings.Name = "web_connectivity"
+		runner.settings.loadInputs = true
+		fake := fakeSuccessfulRun()
+		fake.MockableInputPolicy = func() model.InputPolicy {
+			return model.InputOrQueryBackend
+		}
+		fake.MockCheckIn = func(ctx context.Context,
+			config *model.OOAPICheckInConfig) (*model.OOAPICheckInResultNettests, error) {
+			return nil, errors.New("mocked error")
+		}
+		runner.sessionBuilder = fake
+		events := runAndCollect(runner, emitter)
+		reduced := reduceEventsKeysIgnoreLog(events)
+		expect := []eventKeyCount{
+			{Key: eventTypeStatusQueued, Count: 1},
+			{Key: eventTypeStatusStarted, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 3},
+			{Key: eventTypeStatusGeoIPLookup, Count: 1},
+			{Key: eventTypeStatusResolverLookup, Count: 1},
+			{Key: eventTypeFailureStartup, Count: 1},
+			{Key: eventTypeStatusEnd, Count: 1},
+		}
+		assertReducedEventsLike(t, expect, reduced)
+	})
+
 	t.Run("with missing input and InputStrictlyRequired policy", func(t *testing.T) {
 		runner, emitter := newRunnerForTesting()
 		fake := fakeSuccessfulRun()
@@ -502,6 +612,119 @@ func TestTaskRunnerRun(t *testing.T) {
 		assertReducedEventsLike(t, expect, reduced)
 	})
 
+	t.Run("with data usage budget exhausted while measuring", func(t *testing.T) {
+		runner, emitter := newRunnerForTesting()
+		runner.settings.Inputs = []string{"a", "b", "c", "d"}
+		fake := fakeSuccessfulRun()
+		fake.MockableInputPolicy = func() model.InputPolicy {
+			return model.InputStrictlyRequired
+		}
+		var remaining int64 = 2048
+		fake.MockRemainingDataBudget = func() (int64, bool) {
+			return remaining, true
+		}
+		fake.MockableMeasureWithContext = func(ctx context.Context, input string) (*model.Measurement, error) {
+			if remaining <= 0 {
+				return nil, bytecounter.ErrBudgetExceeded
+			}
+			remaining -= 2048
+			return &model.Measurement{}, nil
+		}
+		runner.sessionBuilder = fake
+		events := runAndCollect(runner, emitter)
+		reduced := reduceEventsKeysIgnoreLog(events)
+		expect := []eventKeyCount{
+			{Key: eventTypeStatusQueued, Count: 1},
+			{Key: eventTypeStatusStarted, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 3},
+			{Key: eventTypeStatusGeoIPLookup, Count: 1},
+			{Key: eventTypeStatusResolverLookup, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeStatusReportCreate, Count: 1},
+			//
+			{Key: eventTypeStatusMeasurementStart, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeMeasurement, Count: 1},
+			{Key: eventTypeStatusMeasurementSubmission, Count: 1},
+			{Key: eventTypeStatusMeasurementDone, Count: 1},
+			//
+			{Key: eventTypeStatusMeasurementStart, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeFailureMeasurement, Count: 1},
+			//
+			{Key: eventTypeStatusEnd, Count: 1},
+		}
+		assertReducedEventsLike(t, expect, reduced)
+		for _, ev := range events {
+			switch ev.Key {
+			case eventTypeStatusMeasurementDone:
+				value := ev.Value.(eventMeasurementGeneric)
+				if value.RemainingBudgetKB == nil || *value.RemainingBudgetKB != 0 {
+					t.Fatal("unexpected remaining budget")
+				}
+			case eventTypeStatusEnd:
+				value := ev.Value.(*eventStatusEnd)
+				if value.RemainingBudgetKB == nil || *value.RemainingBudgetKB != 0 {
+					t.Fatal("unexpected remaining budget")
+				}
+			}
+		}
+	})
+
+	t.Run("with data usage budget running out in the middle of a measurement", func(t *testing.T) {
+		runner, emitter := newRunnerForTesting()
+		runner.settings.Inputs = []string{"a", "b"}
+		fake := fakeSuccessfulRun()
+		fake.MockableInputPolicy = func() model.InputPolicy {
+			return model.InputStrictlyRequired
+		}
+		fake.MockRemainingDataBudget = func() (int64, bool) {
+			return 0, true
+		}
+		var submissions int
+		fake.MockableSubmitAndUpdateMeasurementContext = func(ctx context.Context, m *model.Measurement) error {
+			submissions++
+			return nil
+		}
+		fake.MockableMeasureWithContext = func(ctx context.Context, input string) (*model.Measurement, error) {
+			// this is what the engine returns when it discards a truncated measurement
+			return nil, fmt.Errorf("%w: discarded the incomplete measurement", bytecounter.ErrBudgetExceeded)
+		}
+		runner.sessionBuilder = fake
+		events := runAndCollect(runner, emitter)
+		reduced := reduceEventsKeysIgnoreLog(events)
+		expect := []eventKeyCount{
+			{Key: eventTypeStatusQueued, Count: 1},
+			{Key: eventTypeStatusStarted, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 3},
+			{Key: eventTypeStatusGeoIPLookup, Count: 1},
+			{Key: eventTypeStatusResolverLookup, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeStatusReportCreate, Count: 1},
+			//
+			{Key: eventTypeStatusMeasurementStart, Count: 1},
+			{Key: eventTypeStatusProgress, Count: 1},
+			{Key: eventTypeFailureMeasurement, Count: 1},
+			//
+			{Key: eventTypeStatusEnd, Count: 1},
+		}
+		assertReducedEventsLike(t, expect, reduced)
+		if submissions != 0 {
+			t.Fatal("expected no submissions", submissions)
+		}
+	})
+
+	t.Run("without data usage budget", func(t *testing.T) {
+		runner, emitter := newRunnerForTesting()
+		runner.sessionBuilder = fakeSuccessfulRun()
+		events := runAndCollect(runner, emitter)
+		for _, ev := range events {
+			if ev.Key == eventTypeStatusEnd && ev.Value.(*eventStatusEnd).RemainingBudgetKB != nil {
+				t.Fatal("expected no remaining budget")
+			}
+		}
+	})
+
 	t.Run("with success and InputStrictlyRequired", func(t *testing.T) {
 		runner, emitter := newRunnerForTesting()
 		runner.settings.Inputs = []string{"a", "b", "c", "d"}